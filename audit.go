@@ -0,0 +1,235 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// AuditRecord is a row of the history table of an audited table, the tables
+// are audited by the tag "audited" on any of their fields
+type AuditRecord struct {
+	Id        int64
+	EntityId  string    `xorm:"varchar(255) notnull index"` // the values of the primary key, comma separated
+	Operation string    `xorm:"varchar(10) notnull"`
+	Actor     string    `xorm:"varchar(255)"`
+	Before    string    `xorm:"text"` // the JSON of the columns before an update or a delete
+	After     string    `xorm:"text"` // the JSON of the columns after an insert or an update
+	Created   time.Time `xorm:"created"`
+}
+
+// Values decodes the columns before and after the change
+func (record *AuditRecord) Values() (before, after map[string]interface{}, err error) {
+	if record.Before != "" {
+		if err = json.Unmarshal([]byte(record.Before), &before); err != nil {
+			return nil, nil, err
+		}
+	}
+	if record.After != "" {
+		if err = json.Unmarshal([]byte(record.After), &after); err != nil {
+			return nil, nil, err
+		}
+	}
+	return before, after, nil
+}
+
+// AuditTableName returns the name of the history table of an audited table
+func AuditTableName(tableName string) string {
+	return tableName + "_history"
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor returns a context recording actor as who writes in the
+// audit records of the sessions using the context
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActor returns the actor of ctx set by WithAuditActor
+func AuditActor(ctx context.Context) string {
+	actor, _ := ctx.Value(auditActorKey{}).(string)
+	return actor
+}
+
+type auditRegistry struct {
+	mutex  sync.RWMutex
+	tables map[string]bool
+}
+
+func (r *auditRegistry) add(tableName string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.tables == nil {
+		r.tables = make(map[string]bool)
+	}
+	r.tables[tableName] = true
+}
+
+func (r *auditRegistry) has(tableName string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.tables[tableName]
+}
+
+// AuditedTagHandler describes audited tag handler
+func AuditedTagHandler(ctx *tagContext) error {
+	ctx.hasAuditedTag = true
+	return nil
+}
+
+func (engine *Engine) isAudited(table *core.Table) bool {
+	return table != nil && engine.audits.has(table.Name)
+}
+
+// historyTableName returns the name of the history table of the statement,
+// the tenant is applied to it like to the table
+func (statement *Statement) historyTableName() string {
+	if statement.AltTableName != "" {
+		return AuditTableName(statement.AltTableName)
+	}
+	return AuditTableName(statement.tableName)
+}
+
+// syncHistory creates the history table of table when it's audited and it
+// doesn't exist in tables
+func (session *Session) syncHistory(table *core.Table, tables []*core.Table) error {
+	if !session.Engine.isAudited(table) {
+		return nil
+	}
+	history, err := session.Engine.autoMapType(reflect.ValueOf(AuditRecord{}))
+	if err != nil {
+		return err
+	}
+
+	name := AuditTableName(session.tbNameNoSchema(table))
+	return session.runHook(func() error {
+		session.Statement.RefTable = history
+		session.Statement.AltTableName = name
+		for _, tb := range tables {
+			if strings.EqualFold(tb.Name, session.Statement.TableName()) {
+				return nil
+			}
+		}
+
+		sqls := append([]string{session.Statement.genCreateTableSQL()}, session.Statement.genIndexSQL()...)
+		for _, sqlStr := range sqls {
+			if _, err := session.exec(sqlStr); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// beginAudit begins a transaction for a write of table when it's audited
+// and the session is in autocommit mode, so the write and its audit records
+// are committed together. The returned end commits the transaction, or
+// rolls it back when the write failed, puts the session back in autocommit
+// mode and returns the error of the write or of the commit.
+func (session *Session) beginAudit(table *core.Table) (end func(err error) error, err error) {
+	if !session.IsAutoCommit || table == nil || session.Statement.noCapture || !session.Engine.isAudited(table) {
+		return func(err error) error { return err }, nil
+	}
+	if err := session.Begin(); err != nil {
+		return nil, err
+	}
+	return func(err error) error {
+		if err == nil {
+			err = session.Commit()
+		} else {
+			session.Rollback()
+		}
+		session.IsAutoCommit = true
+		session.Tx = nil
+		return err
+	}, nil
+}
+
+// writeAudit inserts the audit records of events in the history table, in
+// the transaction of the write, see beginAudit
+func (session *Session) writeAudit(events []ChangeEvent) error {
+	actor := AuditActor(session.Ctx())
+	name := session.Statement.historyTableName()
+	records := make([]AuditRecord, 0, len(events))
+	for _, event := range events {
+		record := AuditRecord{Operation: event.Op.String(), Actor: actor}
+		if event.PK != nil {
+			record.EntityId = auditEntityID(event.PK)
+		}
+		if event.Before != nil {
+			data, err := json.Marshal(event.Before)
+			if err != nil {
+				return err
+			}
+			record.Before = string(data)
+		}
+		if event.After != nil {
+			data, err := json.Marshal(event.After)
+			if err != nil {
+				return err
+			}
+			record.After = string(data)
+		}
+		records = append(records, record)
+	}
+
+	return session.runHook(func() error {
+		session.Statement.noCapture = true
+		_, err := session.Table(name).Insert(&records)
+		return err
+	})
+}
+
+// History returns the audit records of the entity of bean, identified by its
+// primary key, from the oldest
+func (session *Session) History(bean interface{}) ([]AuditRecord, error) {
+	if err := session.enterOperation(); err != nil {
+		return nil, err
+	}
+	defer session.leaveOperation()
+
+	v := rValue(bean)
+	if err := session.Statement.setRefValue(v); err != nil {
+		return nil, err
+	}
+	pk, err := session.Engine.idOfV(v)
+	name := session.Statement.historyTableName()
+	session.resetStatement()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []AuditRecord
+	err = session.Table(name).
+		Asc(session.Engine.ColumnMapper.Obj2Table("Id")).
+		Find(&records, &AuditRecord{EntityId: auditEntityID(pk)})
+	return records, err
+}
+
+// auditEntityID returns the entity id of the history records of pk
+func auditEntityID(pk core.PK) string {
+	ids := make([]string, len(pk))
+	for i, id := range pk {
+		ids[i] = fmt.Sprint(id)
+	}
+	return strings.Join(ids, ",")
+}
+
+// History returns the audit records of the entity of bean, see
+// Session.History
+func (engine *Engine) History(bean interface{}) ([]AuditRecord, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.History(bean)
+}
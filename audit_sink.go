@@ -0,0 +1,98 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"time"
+)
+
+// StatementAudit is a write statement executed by an engine, as received by
+// the audit sink
+type StatementAudit struct {
+	// Principal is the actor of the context of the session, set by
+	// WithAuditActor
+	Principal string
+	SQL       string
+	Args      []interface{}
+	// RowsAffected is -1 when it's unknown, as for the failed statements
+	RowsAffected int64
+	Err          error
+	Time         time.Time
+	Duration     time.Duration
+	Session      uint64 // the id of the session
+	Tx           bool   // true when the statement is in a transaction
+}
+
+// AuditSink receives every insert, update and delete executed by an engine,
+// failed or not, to log the data modifications centrally. Write is called
+// synchronously after the statement, a slow sink should queue the entries.
+type AuditSink interface {
+	Write(audit StatementAudit) error
+}
+
+// AuditSinkFunc is an AuditSink as a function
+type AuditSinkFunc func(audit StatementAudit) error
+
+// Write implements AuditSink
+func (f AuditSinkFunc) Write(audit StatementAudit) error {
+	return f(audit)
+}
+
+// SetAuditSink sets the sink of the write statements of the engine, nil
+// disables it. The errors of the sink are logged.
+func (engine *Engine) SetAuditSink(sink AuditSink) {
+	engine.auditSink = sink
+}
+
+// auditSinkHook is the SQL hook sending the write statements to the audit
+// sink of an engine
+type auditSinkHook struct {
+	sink AuditSink
+}
+
+func (h auditSinkHook) BeforeSQL(event *SQLEvent) {}
+
+func (h auditSinkHook) AfterSQL(event *SQLEvent) {
+	if !isWriteSQL(event.SQL) {
+		return
+	}
+
+	session := event.Session
+	audit := StatementAudit{
+		Principal:    AuditActor(session.Ctx()),
+		SQL:          event.SQL,
+		Args:         event.Args,
+		RowsAffected: -1,
+		Err:          event.Err,
+		Time:         event.Start,
+		Duration:     event.Duration,
+		Session:      session.SessionID(),
+		Tx:           !session.IsAutoCommit,
+	}
+	if event.Err == nil && event.Result != nil {
+		if n, err := event.Result.RowsAffected(); err == nil {
+			audit.RowsAffected = n
+		}
+	}
+	if err := h.sink.Write(audit); err != nil {
+		session.getLogger().Errorf("audit sink failed: %v", err)
+	}
+}
+
+// the verbs of the statements modifying the data
+var writeVerbs = []string{"INSERT", "UPDATE", "DELETE", "REPLACE", "MERGE"}
+
+// isWriteSQL returns true if sqlStr modifies the data, the inserts
+// returning the ids are queries but are writes
+func isWriteSQL(sqlStr string) bool {
+	sqlStr = strings.TrimLeft(sqlStr, " \t\r\n(")
+	for _, verb := range writeVerbs {
+		if len(sqlStr) >= len(verb) && strings.EqualFold(sqlStr[:len(verb)], verb) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type SinkedUser struct {
+	Id   int64
+	Name string
+}
+
+func TestIsWriteSQL(t *testing.T) {
+	assert.True(t, isWriteSQL("INSERT INTO user VALUES (?)"))
+	assert.True(t, isWriteSQL(" update user SET name=?"))
+	assert.True(t, isWriteSQL("DELETE FROM user"))
+	assert.False(t, isWriteSQL("SELECT * FROM user"))
+	assert.False(t, isWriteSQL("UP"))
+}
+
+func TestAuditSink(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(SinkedUser)))
+
+	var audits []StatementAudit
+	testEngine.SetAuditSink(AuditSinkFunc(func(audit StatementAudit) error {
+		audits = append(audits, audit)
+		return nil
+	}))
+	defer testEngine.SetAuditSink(nil)
+
+	ctx := WithAuditActor(context.Background(), "lunny")
+	user := SinkedUser{Name: "lunny"}
+	_, err := testEngine.Context(ctx).Insert(&user)
+	assert.NoError(t, err)
+	_, err = testEngine.Context(ctx).Id(user.Id).Update(&SinkedUser{Name: "xlw"})
+	assert.NoError(t, err)
+	var users []SinkedUser
+	assert.NoError(t, testEngine.Find(&users))
+
+	session := testEngine.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+	_, err = session.Id(user.Id).Delete(new(SinkedUser))
+	assert.NoError(t, err)
+	assert.NoError(t, session.Commit())
+
+	if assert.EqualValues(t, 3, len(audits)) {
+		assert.EqualValues(t, "lunny", audits[0].Principal)
+		assert.True(t, strings.HasPrefix(strings.ToUpper(audits[0].SQL), "INSERT"))
+		assert.EqualValues(t, []interface{}{"lunny"}, audits[0].Args)
+		assert.False(t, audits[0].Tx)
+		assert.False(t, audits[0].Time.IsZero())
+
+		assert.True(t, strings.HasPrefix(strings.ToUpper(audits[1].SQL), "UPDATE"))
+		assert.EqualValues(t, 1, audits[1].RowsAffected)
+		assert.NoError(t, audits[1].Err)
+
+		assert.EqualValues(t, "", audits[2].Principal)
+		assert.True(t, strings.HasPrefix(strings.ToUpper(audits[2].SQL), "DELETE"))
+		assert.True(t, audits[2].Tx)
+		assert.EqualValues(t, session.SessionID(), audits[2].Session)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type AuditedUser struct {
+	Id   int64 `xorm:"pk autoincr audited"`
+	Name string
+}
+
+func TestAudit(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(AuditedUser)))
+	// the history table is created once
+	assert.NoError(t, testEngine.Sync2(new(AuditedUser)))
+
+	tableName := testEngine.TableMapper.Obj2Table("AuditedUser")
+	nameCol := testEngine.ColumnMapper.Obj2Table("Name")
+	exist, err := testEngine.IsTableExist(AuditTableName(tableName))
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	user := AuditedUser{Name: "lunny"}
+	_, err = testEngine.Insert(&user)
+	assert.NoError(t, err)
+
+	session := testEngine.NewSession()
+	defer session.Close()
+	session.Context(WithAuditActor(context.Background(), "admin"))
+	assert.NoError(t, session.Begin())
+	_, err = session.Id(user.Id).Update(&AuditedUser{Name: "xlw"})
+	assert.NoError(t, err)
+	_, err = session.Id(user.Id).Delete(new(AuditedUser))
+	assert.NoError(t, err)
+	assert.NoError(t, session.Commit())
+
+	records, err := testEngine.History(&user)
+	assert.NoError(t, err)
+	if assert.EqualValues(t, 3, len(records)) {
+		assert.EqualValues(t, "insert", records[0].Operation)
+		assert.EqualValues(t, "", records[0].Actor)
+		assert.EqualValues(t, "update", records[1].Operation)
+		assert.EqualValues(t, "admin", records[1].Actor)
+		assert.EqualValues(t, "delete", records[2].Operation)
+		assert.False(t, records[2].Created.IsZero())
+
+		before, after, err := records[1].Values()
+		assert.NoError(t, err)
+		assert.EqualValues(t, "lunny", before[nameCol])
+		assert.EqualValues(t, "xlw", after[nameCol])
+
+		before, after, err = records[2].Values()
+		assert.NoError(t, err)
+		assert.EqualValues(t, "xlw", before[nameCol])
+		assert.Nil(t, after)
+	}
+
+	// the history is rolled back with the write
+	user2 := AuditedUser{Name: "lunny"}
+	session2 := testEngine.NewSession()
+	defer session2.Close()
+	assert.NoError(t, session2.Begin())
+	_, err = session2.Insert(&user2)
+	assert.NoError(t, err)
+	assert.NoError(t, session2.Rollback())
+
+	records, err = testEngine.History(&user2)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, len(records))
+}
+
+func TestAuditAutoCommit(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(AuditedUser)))
+
+	session := testEngine.NewSession()
+	defer session.Close()
+	user := AuditedUser{Name: "lunny"}
+	_, err := session.Insert(&user)
+	assert.NoError(t, err)
+	// the write and its history are committed, the session is still in
+	// autocommit mode
+	assert.True(t, session.IsAutoCommit)
+	records, err := testEngine.History(&user)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, len(records))
+
+	// the write is rolled back when its history can't be inserted
+	tableName := testEngine.TableMapper.Obj2Table("AuditedUser")
+	assert.NoError(t, testEngine.DropTables(AuditTableName(tableName)))
+	defer testEngine.Sync2(new(AuditedUser))
+
+	_, err = testEngine.Id(user.Id).Update(&AuditedUser{Name: "xlw"})
+	assert.Error(t, err)
+
+	var user2 AuditedUser
+	has, err := testEngine.Id(user.Id).Get(&user2)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", user2.Name)
+}
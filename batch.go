@@ -0,0 +1,181 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+)
+
+// Batch queues the inserts, the updates, the deletes and the execs of a
+// session and flushes them at once, in a single transaction and with the
+// consecutive inserts of the beans of a type merged into multi-values
+// inserts, to save the round trips of the writes.
+//
+// The merged inserts don't set the autoincrement ids of their beans, as
+// InsertMulti.
+type Batch struct {
+	engine  *Engine
+	session *Session // nil for the batches of the engine
+	ops     []batchOp
+}
+
+// batchOp is a queued operation, the beans are the ones of consecutive
+// inserts of a type which are merged
+type batchOp struct {
+	beans []interface{}
+	run   func(*Session) (int64, error)
+}
+
+// Batch returns a batch of the operations of the session, flushed in the
+// transaction of the session if any
+func (session *Session) Batch() *Batch {
+	return &Batch{engine: session.Engine, session: session}
+}
+
+// Batch returns a batch of operations, flushed in their own transactions
+func (engine *Engine) Batch() *Batch {
+	return &Batch{engine: engine}
+}
+
+// Len returns the number of the queued operations, the merged inserts are
+// counted per bean
+func (b *Batch) Len() int {
+	var n int
+	for _, op := range b.ops {
+		if op.beans != nil {
+			n += len(op.beans)
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// Insert queues the inserts of beans, the pointers to the structs are
+// merged with the previous ones of the same type
+func (b *Batch) Insert(beans ...interface{}) *Batch {
+	for _, bean := range beans {
+		bean := bean
+		t := reflect.TypeOf(bean)
+		if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+			b.ops = append(b.ops, batchOp{run: func(session *Session) (int64, error) {
+				return session.Insert(bean)
+			}})
+			continue
+		}
+
+		if n := len(b.ops); n > 0 && b.ops[n-1].beans != nil &&
+			reflect.TypeOf(b.ops[n-1].beans[0]) == t {
+			b.ops[n-1].beans = append(b.ops[n-1].beans, bean)
+			continue
+		}
+		b.ops = append(b.ops, batchOp{beans: []interface{}{bean}})
+	}
+	return b
+}
+
+// Update queues an update, as Session.Update
+func (b *Batch) Update(bean interface{}, condiBean ...interface{}) *Batch {
+	return b.Do(func(session *Session) (int64, error) {
+		return session.Update(bean, condiBean...)
+	})
+}
+
+// Delete queues a delete, as Session.Delete
+func (b *Batch) Delete(bean interface{}) *Batch {
+	return b.Do(func(session *Session) (int64, error) {
+		return session.Delete(bean)
+	})
+}
+
+// Exec queues a raw SQL
+func (b *Batch) Exec(sqlStr string, args ...interface{}) *Batch {
+	return b.Do(func(session *Session) (int64, error) {
+		res, err := session.Exec(sqlStr, args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	})
+}
+
+// Do queues an operation of the conditions of a chain, as
+//
+//	b.Do(func(session *Session) (int64, error) {
+//		return session.Id(id).Cols("name").Update(&user)
+//	})
+func (b *Batch) Do(op func(*Session) (int64, error)) *Batch {
+	b.ops = append(b.ops, batchOp{run: op})
+	return b
+}
+
+// Flush runs the queued operations in a transaction and returns the number
+// of the records they affected. The queue is emptied even on an error, its
+// own transaction is rolled back but the one of the session is left to the
+// caller, with the number of the records affected before the error.
+func (b *Batch) Flush() (int64, error) {
+	ops := b.ops
+	b.ops = nil
+	if len(ops) == 0 {
+		return 0, nil
+	}
+
+	if b.session != nil && !b.session.IsAutoCommit {
+		// the operations don't close the session of the caller
+		if b.session.IsAutoClose {
+			b.session.IsAutoClose = false
+			defer func() { b.session.IsAutoClose = true }()
+		}
+		return runBatchOps(b.session, ops)
+	}
+
+	session := b.engine.NewSession()
+	defer session.Close()
+	if b.session != nil {
+		session.Context(b.session.Ctx())
+	}
+
+	if err := session.Begin(); err != nil {
+		return 0, err
+	}
+	affected, err := runBatchOps(session, ops)
+	if err != nil {
+		session.Rollback()
+		return 0, err
+	}
+	return affected, session.Commit()
+}
+
+func runBatchOps(session *Session, ops []batchOp) (int64, error) {
+	var affected int64
+	for _, op := range ops {
+		var n int64
+		var err error
+		if op.beans != nil {
+			n, err = insertBatchBeans(session, op.beans)
+		} else {
+			n, err = op.run(session)
+		}
+		if err != nil {
+			return affected, err
+		}
+		affected += n
+	}
+	return affected, nil
+}
+
+// insertBatchBeans inserts the beans of a type by InsertMulti, which splits
+// them by the limits of the database, a single bean is inserted with its id
+func insertBatchBeans(session *Session, beans []interface{}) (int64, error) {
+	if len(beans) == 1 {
+		return session.Insert(beans[0])
+	}
+
+	slice := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(beans[0])), 0, len(beans))
+	for _, bean := range beans {
+		slice = reflect.Append(slice, reflect.ValueOf(bean))
+	}
+	return session.InsertMulti(slice.Interface())
+}
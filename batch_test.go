@@ -0,0 +1,101 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type BatchUser struct {
+	Id   int64
+	Name string `xorm:"unique"`
+	Age  int
+}
+
+type BatchOrder struct {
+	Id     int64
+	UserId int64
+}
+
+func TestBatch(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(BatchUser), new(BatchOrder))
+
+	var users = make([]*BatchUser, 300)
+	for i := range users {
+		users[i] = &BatchUser{Name: "user" + string(rune('a'+i%26)) + string(rune('a'+i/26)), Age: i}
+	}
+
+	batch := testEngine.Batch()
+	for _, user := range users {
+		batch.Insert(user)
+	}
+	batch.Insert(&BatchOrder{UserId: 1}, &BatchOrder{UserId: 2})
+	batch.Update(&BatchUser{Age: 1000}, &BatchUser{Name: "userab"})
+	batch.Do(func(session *Session) (int64, error) {
+		return session.Where("age < ?", 10).Delete(new(BatchUser))
+	})
+	assert.EqualValues(t, 304, batch.Len())
+
+	affected, err := batch.Flush()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 300+2+1+10, affected)
+	assert.EqualValues(t, 0, batch.Len())
+
+	cnt, err := testEngine.Count(new(BatchUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 290, cnt)
+	cnt, err = testEngine.Count(new(BatchOrder))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cnt)
+
+	var user BatchUser
+	has, err := testEngine.Where("name = ?", "userab").Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 1000, user.Age)
+
+	// a failed operation rolls back the batch
+	_, err = testEngine.Batch().
+		Insert(&BatchOrder{UserId: 3}).
+		Insert(&BatchUser{Name: "userab"}).
+		Flush()
+	assert.Error(t, err)
+	cnt, err = testEngine.Count(new(BatchOrder))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cnt)
+}
+
+func TestBatchSessionTx(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(BatchOrder))
+
+	session := testEngine.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+
+	affected, err := session.Batch().
+		Insert(&BatchOrder{UserId: 1}).
+		Exec("UPDATE "+testEngine.Quote(testEngine.TableMapper.Obj2Table("BatchOrder"))+" SET "+testEngine.Quote(testEngine.ColumnMapper.Obj2Table("UserId"))+" = ?", 2).
+		Do(func(session *Session) (int64, error) {
+			return 0, errors.New("stop")
+		}).
+		Flush()
+	assert.EqualError(t, err, "stop")
+	assert.EqualValues(t, 2, affected)
+
+	// the operations are in the transaction of the session
+	cnt, err := session.Count(new(BatchOrder))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	assert.NoError(t, session.Rollback())
+
+	cnt, err = testEngine.Count(new(BatchOrder))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+}
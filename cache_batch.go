@@ -0,0 +1,92 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+)
+
+type inParam struct {
+	column string
+	args   []interface{}
+	cond   builder.Cond
+}
+
+// values returns the values of the In, a single slice arg is expanded
+func (in *inParam) values() []interface{} {
+	if len(in.args) != 1 {
+		return in.args
+	}
+	v := reflect.ValueOf(in.args[0])
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return in.args
+	}
+	var values = make([]interface{}, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+	return values
+}
+
+// pkInIds returns the ids queried when the only condition of the statement
+// is an In of the primary key, so Find gets them from the bean cache
+// without querying the ids. The statements with mandatory conditions,
+// tenant or query filters, or on soft deleted tables query their ids.
+func (statement *Statement) pkInIds(table *core.Table) ([]core.PK, bool) {
+	in := statement.lastIn
+	if in == nil || len(table.PrimaryKeys) != 1 ||
+		!strings.EqualFold(strings.Trim(in.column, "`\"[]"), table.PrimaryKeys[0]) ||
+		statement.LimitN > 0 || statement.OrderStr != "" ||
+		statement.tenantColumn() != nil || statement.filterCond().IsValid() ||
+		table.DeletedColumn() != nil {
+		return nil, false
+	}
+
+	// the In must be the whole condition
+	condSQL, condArgs, err := builder.ToSQL(statement.cond)
+	if err != nil {
+		return nil, false
+	}
+	inSQL, inArgs, err := builder.ToSQL(in.cond)
+	if err != nil || condSQL != inSQL || len(condArgs) != len(inArgs) {
+		return nil, false
+	}
+
+	var ids []core.PK
+	var seen = make(map[string]bool)
+	for _, v := range in.values() {
+		pk, ok := cachePK(table, core.PK{v})
+		if !ok {
+			return nil, false
+		}
+		sid, err := pk.ToString()
+		if err != nil {
+			return nil, false
+		}
+		if !seen[sid] {
+			seen[sid] = true
+			ids = append(ids, pk)
+		}
+	}
+	return ids, true
+}
+
+// cacheLoadSession returns a new session loading the beans missing from the
+// cache with the context, the tenant, the table and the query filters of
+// session
+func (session *Session) cacheLoadSession() *Session {
+	newSession := session.Engine.NewSession()
+	newSession.ctx = session.ctx
+	newSession.bypassFilters = session.bypassFilters
+	newSession.Statement.tenant = session.Statement.tenant
+	newSession.Statement.AltTableName = session.Statement.AltTableName
+	newSession.Statement.tableSuffix = session.Statement.tableSuffix
+	newSession.Statement.unscoped = session.Statement.unscoped
+	return newSession
+}
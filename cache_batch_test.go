@@ -0,0 +1,98 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type BatchCacheUser struct {
+	Id   int64
+	Name string
+}
+
+func TestCacheFindIn(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(BatchCacheUser)))
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.MapCacher(new(BatchCacheUser), cacher)
+	defer testEngine.MapCacher(new(BatchCacheUser), nil)
+
+	for _, name := range []string{"lunny", "xlw", "xiangxiao"} {
+		_, err := testEngine.Insert(&BatchCacheUser{Name: name})
+		assert.NoError(t, err)
+	}
+
+	var user BatchCacheUser
+	has, err := testEngine.Id(2).Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	tableName := testEngine.TableMapper.Obj2Table("BatchCacheUser")
+	before := testEngine.CacheStats()[tableName]
+
+	var users []BatchCacheUser
+	assert.NoError(t, testEngine.In("id", []int64{1, 2, 3, 4, 2}).Find(&users))
+	assert.EqualValues(t, 3, len(users))
+
+	// the bean 2 hits the cache, only 1 and 3 are loaded
+	stats := testEngine.CacheStats()[tableName]
+	assert.EqualValues(t, 1, stats.Hits-before.Hits)
+	assert.EqualValues(t, 3, stats.Misses-before.Misses)
+	for _, id := range []int64{1, 3} {
+		pk := core.PK{id}
+		sid, err := pk.ToString()
+		assert.NoError(t, err)
+		assert.NotNil(t, cacher.GetBean(tableName, sid))
+	}
+
+	// the ids are queried when the In is not the only condition
+	users = nil
+	assert.NoError(t, testEngine.In("id", 1, 2, 3).Where("name = ?", "xlw").Find(&users))
+	assert.EqualValues(t, 1, len(users))
+	assert.EqualValues(t, "xlw", users[0].Name)
+}
+
+func TestCacheFindInFiltered(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(BatchCacheUser)))
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.MapCacher(new(BatchCacheUser), cacher)
+	defer testEngine.MapCacher(new(BatchCacheUser), nil)
+
+	for _, name := range []string{"lunny", "xlw", "xiangxiao"} {
+		_, err := testEngine.Insert(&BatchCacheUser{Name: name})
+		assert.NoError(t, err)
+	}
+
+	// the bean 3 is cached by an unfiltered session
+	var user BatchCacheUser
+	has, err := testEngine.Id(3).Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	nameCol := testEngine.ColumnMapper.Obj2Table("Name")
+	testEngine.AddQueryFilter(func(table *core.Table, session *Session) builder.Cond {
+		if table.GetColumn(nameCol) == nil {
+			return nil
+		}
+		return builder.Neq{nameCol: "xiangxiao"}
+	})
+	defer func() { testEngine.queryFilters = nil }()
+
+	var users []BatchCacheUser
+	assert.NoError(t, testEngine.In("id", 1, 2, 3).Find(&users))
+	assert.EqualValues(t, []BatchCacheUser{{1, "lunny"}, {2, "xlw"}}, users)
+
+	users = nil
+	assert.NoError(t, testEngine.In("id", 1, 2, 3).BypassQueryFilters().Find(&users))
+	assert.EqualValues(t, 3, len(users))
+}
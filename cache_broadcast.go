@@ -0,0 +1,350 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// CacheInvalidation is a deletion from the cache of an instance, broadcast
+// to the other instances of the application
+type CacheInvalidation struct {
+	Origin string // the cacher which deleted the entry
+	Kind   string // "ids" or "bean"
+	Table  string
+	Key    string // the sql of the ids or the id of the bean, empty when the table is cleared
+}
+
+// InvalidationBus broadcasts the invalidations between the instances
+type InvalidationBus interface {
+	Publish(inv CacheInvalidation) error
+	// Subscribe calls handler with the invalidations published by all the
+	// instances, including the subscriber
+	Subscribe(handler func(CacheInvalidation)) error
+	Close() error
+}
+
+// BroadcastCacher wraps the in process cacher of an instance, the entries it
+// deletes or clears are broadcast so the other instances delete them from
+// their own cacher without waiting for them to expire. The writes of the
+// engine delete or clear the entries they change, so the instances share
+// the invalidations of each other's writes.
+// The invalidations published while an instance is disconnected from the
+// bus are lost, the entries should have a TTL as a safety net.
+// An entry loaded before a write of another instance may be put after its
+// invalidation was received, the puts of the keys missed before the last
+// invalidation of their table are dropped. The puts of the keys not seen
+// missing are dropped for BroadcastRaceWindow after the invalidation.
+type BroadcastCacher struct {
+	core.Cacher
+	bus    InvalidationBus
+	origin string
+
+	mutex       sync.Mutex
+	versions    map[string]uint64    // the invalidations received by table
+	invalidated map[string]time.Time // the time of the last invalidation received by table
+	misses      map[string]uint64    // the versions of the tables when the keys were missed
+}
+
+var (
+	// BroadcastRaceWindow is how long after an invalidation of a table the
+	// puts of the keys not seen missing are dropped
+	BroadcastRaceWindow = 5 * time.Second
+	// BroadcastMaxMisses is the number of missed keys remembered, the puts
+	// of the keys forgotten fall back to BroadcastRaceWindow
+	BroadcastMaxMisses = 10000
+)
+
+// NewBroadcastCacher creates a cacher broadcasting the invalidations of
+// cacher on bus
+func NewBroadcastCacher(cacher core.Cacher, bus InvalidationBus) (*BroadcastCacher, error) {
+	var id = make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	c := &BroadcastCacher{
+		Cacher:      cacher,
+		bus:         bus,
+		origin:      hex.EncodeToString(id),
+		versions:    make(map[string]uint64),
+		invalidated: make(map[string]time.Time),
+		misses:      make(map[string]uint64),
+	}
+	if err := bus.Subscribe(c.apply); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// apply deletes the entry invalidated by another instance
+func (c *BroadcastCacher) apply(inv CacheInvalidation) {
+	if inv.Origin == c.origin {
+		return
+	}
+
+	c.mutex.Lock()
+	c.versions[inv.Table]++
+	c.invalidated[inv.Table] = time.Now()
+	c.mutex.Unlock()
+
+	switch {
+	case inv.Kind == "ids" && inv.Key == "":
+		c.Cacher.ClearIds(inv.Table)
+	case inv.Kind == "ids":
+		c.Cacher.DelIds(inv.Table, inv.Key)
+	case inv.Kind == "bean" && inv.Key == "":
+		c.Cacher.ClearBeans(inv.Table)
+	case inv.Kind == "bean":
+		c.Cacher.DelBean(inv.Table, inv.Key)
+	}
+}
+
+func missKey(kind, tableName, key string) string {
+	return kind + "\x00" + tableName + "\x00" + key
+}
+
+// miss records the version of the table when key is missed, the first miss
+// is kept so a concurrent load doesn't hide an invalidation
+func (c *BroadcastCacher) miss(kind, tableName, key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	k := missKey(kind, tableName, key)
+	if _, ok := c.misses[k]; ok {
+		return
+	}
+	if len(c.misses) >= BroadcastMaxMisses {
+		c.misses = make(map[string]uint64)
+	}
+	c.misses[k] = c.versions[tableName]
+}
+
+// fresh returns false when the put of key may have raced an invalidation
+// of its table received from another instance
+func (c *BroadcastCacher) fresh(kind, tableName, key string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	k := missKey(kind, tableName, key)
+	if version, ok := c.misses[k]; ok {
+		delete(c.misses, k)
+		return version == c.versions[tableName]
+	}
+	last, ok := c.invalidated[tableName]
+	return !ok || time.Since(last) >= BroadcastRaceWindow
+}
+
+// GetIds implements core.Cacher
+func (c *BroadcastCacher) GetIds(tableName, sql string) interface{} {
+	v := c.Cacher.GetIds(tableName, sql)
+	if v == nil {
+		c.miss("ids", tableName, sql)
+	}
+	return v
+}
+
+// GetBean implements core.Cacher
+func (c *BroadcastCacher) GetBean(tableName string, id string) interface{} {
+	v := c.Cacher.GetBean(tableName, id)
+	if v == nil {
+		c.miss("bean", tableName, id)
+	}
+	return v
+}
+
+// PutIds implements core.Cacher, the ids loaded before an invalidation of
+// the table are dropped
+func (c *BroadcastCacher) PutIds(tableName, sql string, ids interface{}) {
+	if c.fresh("ids", tableName, sql) {
+		c.Cacher.PutIds(tableName, sql, ids)
+	}
+}
+
+// PutBean implements core.Cacher, the bean loaded before an invalidation of
+// the table is dropped
+func (c *BroadcastCacher) PutBean(tableName string, id string, obj interface{}) {
+	if c.fresh("bean", tableName, id) {
+		c.Cacher.PutBean(tableName, id, obj)
+	}
+}
+
+func (c *BroadcastCacher) publish(kind, tableName, key string) {
+	c.bus.Publish(CacheInvalidation{c.origin, kind, tableName, key})
+}
+
+// DelIds implements core.Cacher
+func (c *BroadcastCacher) DelIds(tableName, sql string) {
+	c.Cacher.DelIds(tableName, sql)
+	c.publish("ids", tableName, sql)
+}
+
+// DelBean implements core.Cacher
+func (c *BroadcastCacher) DelBean(tableName string, id string) {
+	c.Cacher.DelBean(tableName, id)
+	c.publish("bean", tableName, id)
+}
+
+// ClearIds implements core.Cacher
+func (c *BroadcastCacher) ClearIds(tableName string) {
+	c.Cacher.ClearIds(tableName)
+	c.publish("ids", tableName, "")
+}
+
+// ClearBeans implements core.Cacher
+func (c *BroadcastCacher) ClearBeans(tableName string) {
+	c.Cacher.ClearBeans(tableName)
+	c.publish("bean", tableName, "")
+}
+
+// Close closes the bus
+func (c *BroadcastCacher) Close() error {
+	return c.bus.Close()
+}
+
+// RedisInvalidationBus is an InvalidationBus on a redis pub/sub channel
+type RedisInvalidationBus struct {
+	pool    *redisPool
+	channel string
+
+	mutex  sync.Mutex
+	conns  []*redisConn // the subscribed connections
+	closed bool
+}
+
+// NewRedisInvalidationBus creates a bus on the channel of a redis server,
+// the channel is prefixed by opts.Prefix
+func NewRedisInvalidationBus(opts RedisOptions, channel string) *RedisInvalidationBus {
+	if opts.Prefix == "" {
+		opts.Prefix = "xorm"
+	}
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = 8
+	}
+	return &RedisInvalidationBus{
+		pool:    &redisPool{opts: &opts},
+		channel: opts.Prefix + ":" + channel,
+	}
+}
+
+// Publish implements InvalidationBus
+func (b *RedisInvalidationBus) Publish(inv CacheInvalidation) error {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+	_, err = b.pool.do("PUBLISH", b.channel, data)
+	return err
+}
+
+// Subscribe implements InvalidationBus, the subscription is renewed when
+// the connection is broken
+func (b *RedisInvalidationBus) Subscribe(handler func(CacheInvalidation)) error {
+	c, err := b.subscribe()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			b.receive(c, handler)
+
+			// reconnect until the bus is closed
+			for {
+				b.mutex.Lock()
+				closed := b.closed
+				b.mutex.Unlock()
+				if closed {
+					return
+				}
+				if c, err = b.subscribe(); err == nil {
+					break
+				}
+				time.Sleep(time.Second)
+			}
+		}
+	}()
+	return nil
+}
+
+func (b *RedisInvalidationBus) subscribe() (*redisConn, error) {
+	c, err := b.pool.get()
+	if err != nil {
+		return nil, err
+	}
+	// no read timeout, the connection waits for the messages
+	c.conn.SetReadDeadline(time.Time{})
+	if err = writeRedisCommand(c.w, "SUBSCRIBE", b.channel); err == nil {
+		err = c.w.Flush()
+	}
+	if err == nil {
+		_, err = readRedisReply(c.r)
+	}
+	if err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		c.conn.Close()
+		return nil, errors.New("redis invalidation bus is closed")
+	}
+	b.conns = append(b.conns, c)
+	return c, nil
+}
+
+// receive handles the messages until the connection is broken or closed
+func (b *RedisInvalidationBus) receive(c *redisConn, handler func(CacheInvalidation)) {
+	defer func() {
+		c.conn.Close()
+		b.mutex.Lock()
+		for i := range b.conns {
+			if b.conns[i] == c {
+				b.conns = append(b.conns[:i], b.conns[i+1:]...)
+				break
+			}
+		}
+		b.mutex.Unlock()
+	}()
+
+	for {
+		reply, err := readRedisReply(c.r)
+		if err != nil {
+			return
+		}
+		msg, ok := reply.([]interface{})
+		if !ok || len(msg) != 3 {
+			continue
+		}
+		data, ok := msg[2].([]byte)
+		if kind, _ := msg[0].([]byte); !ok || string(kind) != "message" {
+			continue
+		}
+
+		var inv CacheInvalidation
+		if err := json.Unmarshal(data, &inv); err == nil {
+			handler(inv)
+		}
+	}
+}
+
+// Close implements InvalidationBus
+func (b *RedisInvalidationBus) Close() error {
+	b.mutex.Lock()
+	b.closed = true
+	for _, c := range b.conns {
+		c.conn.Close()
+	}
+	b.conns = nil
+	b.mutex.Unlock()
+	return b.pool.close()
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryBus is an InvalidationBus delivering the invalidations synchronously
+type memoryBus struct {
+	mutex    sync.Mutex
+	handlers []func(CacheInvalidation)
+}
+
+func (b *memoryBus) Publish(inv CacheInvalidation) error {
+	b.mutex.Lock()
+	handlers := b.handlers
+	b.mutex.Unlock()
+	for _, handler := range handlers {
+		handler(inv)
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(handler func(CacheInvalidation)) error {
+	b.mutex.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mutex.Unlock()
+	return nil
+}
+
+func (b *memoryBus) Close() error {
+	return nil
+}
+
+func TestBroadcastCacher(t *testing.T) {
+	bus := new(memoryBus)
+	a, err := NewBroadcastCacher(NewShardedCacher(ShardedCacherOptions{}), bus)
+	assert.NoError(t, err)
+	b, err := NewBroadcastCacher(NewShardedCacher(ShardedCacherOptions{}), bus)
+	assert.NoError(t, err)
+
+	for _, c := range []*BroadcastCacher{a, b} {
+		c.PutBean("user", "1", 1)
+		c.PutBean("user", "2", 2)
+		c.PutIds("user", "SELECT id FROM user", 1)
+	}
+
+	// the puts are not broadcast
+	a.PutBean("user", "1", 3)
+	assert.EqualValues(t, 1, b.GetBean("user", "1"))
+
+	a.DelBean("user", "1")
+	assert.Nil(t, a.GetBean("user", "1"))
+	assert.Nil(t, b.GetBean("user", "1"))
+	assert.EqualValues(t, 2, b.GetBean("user", "2"))
+
+	b.ClearIds("user")
+	assert.Nil(t, a.GetIds("user", "SELECT id FROM user"))
+
+	b.ClearBeans("user")
+	assert.Nil(t, a.GetBean("user", "2"))
+}
+
+func TestBroadcastCacherStalePut(t *testing.T) {
+	bus := new(memoryBus)
+	a, err := NewBroadcastCacher(NewShardedCacher(ShardedCacherOptions{}), bus)
+	assert.NoError(t, err)
+	b, err := NewBroadcastCacher(NewShardedCacher(ShardedCacherOptions{}), bus)
+	assert.NoError(t, err)
+
+	// b misses the bean and loads it while a writes it
+	assert.Nil(t, b.GetBean("user", "1"))
+	a.DelBean("user", "1")
+	b.PutBean("user", "1", 1)
+	assert.Nil(t, b.GetBean("user", "1"))
+
+	// a load started after the invalidation is put
+	b.PutBean("user", "1", 2)
+	assert.EqualValues(t, 2, b.GetBean("user", "1"))
+
+	// the keys not seen missing are dropped within the race window
+	a.ClearIds("user")
+	b.PutIds("user", "SELECT id FROM user", 1)
+	assert.Nil(t, b.GetIds("user", "SELECT id FROM user"))
+
+	window := BroadcastRaceWindow
+	BroadcastRaceWindow = 0
+	defer func() { BroadcastRaceWindow = window }()
+	a.ClearBeans("user")
+	b.PutBean("user", "2", 2)
+	assert.EqualValues(t, 2, b.GetBean("user", "2"))
+}
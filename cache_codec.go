@@ -0,0 +1,194 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/go-xorm/core"
+)
+
+// CacheCodec serializes the ids and beans stored by out of process cachers,
+// see GobCodec and MarshalCodec
+type CacheCodec interface {
+	Encode(v interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+// GobCodec serializes values with encoding/gob, the types of the values are
+// registered to gob once, when they are mapped or first encoded
+type GobCodec struct{}
+
+// the errors of the types registered to gob, nil when registered
+var gobRegistered sync.Map
+
+// gobRegister registers the type of v to gob once, a type whose name is
+// registered for another type is reported as an error instead of panicking
+func gobRegister(v interface{}) (err error) {
+	t := reflect.TypeOf(v)
+	if res, ok := gobRegistered.Load(t); ok {
+		if res != nil {
+			return res.(error)
+		}
+		return nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("xorm: register %v to gob: %v", t, r)
+			gobRegistered.Store(t, err)
+		}
+	}()
+	gob.Register(v)
+	gobRegistered.Store(t, nil)
+	return nil
+}
+
+// Encode implements CacheCodec
+func (GobCodec) Encode(v interface{}) ([]byte, error) {
+	if err := gobRegister(v); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode implements CacheCodec
+func (GobCodec) Decode(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// CodecCacher is implemented by the cachers serializing the ids and beans
+type CodecCacher interface {
+	core.Cacher
+	SetCodec(codec CacheCodec)
+}
+
+var (
+	_ CodecCacher = &RedisCacher{}
+	_ CodecCacher = &MemcachedCacher{}
+)
+
+// MarshalCodec serializes the beans with a pair of marshal and unmarshal
+// functions, e.g. the ones of msgpack, so any serialization library can be
+// plugged without xorm depending on it. The ids are small and hold interface
+// values whose types only gob keeps, so they are still encoded by gob.
+//
+// As the unmarshal function needs a typed value, the encoded data starts
+// with the name of the type, which must be registered before decoding. The
+// types are registered automatically when they are encoded, the processes
+// only reading the cache have to call Register.
+type MarshalCodec struct {
+	marshal   func(v interface{}) ([]byte, error)
+	unmarshal func(data []byte, v interface{}) error
+
+	mutex sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// NewMarshalCodec creates a codec from marshal and unmarshal functions
+func NewMarshalCodec(marshal func(v interface{}) ([]byte, error), unmarshal func(data []byte, v interface{}) error) *MarshalCodec {
+	return &MarshalCodec{
+		marshal:   marshal,
+		unmarshal: unmarshal,
+		types:     make(map[string]reflect.Type),
+	}
+}
+
+// NewJSONCodec creates a codec serializing the beans with encoding/json
+func NewJSONCodec() *MarshalCodec {
+	return NewMarshalCodec(json.Marshal, json.Unmarshal)
+}
+
+// Register registers the types of the values, so they can be decoded
+func (c *MarshalCodec) Register(values ...interface{}) {
+	c.mutex.Lock()
+	for _, v := range values {
+		t := reflect.TypeOf(v)
+		c.types[codecTypeName(t)] = t
+	}
+	c.mutex.Unlock()
+}
+
+// codecTypeName returns the name identifying t in the encoded data
+func codecTypeName(t reflect.Type) string {
+	var prefix string
+	for t.Kind() == reflect.Ptr {
+		prefix += "*"
+		t = t.Elem()
+	}
+	if t.Name() == "" || t.PkgPath() == "" {
+		return prefix + t.String()
+	}
+	return prefix + t.PkgPath() + "." + t.Name()
+}
+
+// Encode implements CacheCodec
+func (c *MarshalCodec) Encode(v interface{}) ([]byte, error) {
+	var name string
+	var data []byte
+	var err error
+	switch v.(type) {
+	case []core.PK, core.PK:
+		data, err = GobCodec{}.Encode(v)
+	default:
+		t := reflect.TypeOf(v)
+		name = codecTypeName(t)
+		c.mutex.RLock()
+		_, ok := c.types[name]
+		c.mutex.RUnlock()
+		if !ok {
+			c.Register(v)
+		}
+		data, err = c.marshal(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var buf = make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(name)+len(data))
+	buf = append(buf[:binary.PutUvarint(buf, uint64(len(name)))], name...)
+	return append(buf, data...), nil
+}
+
+// Decode implements CacheCodec
+func (c *MarshalCodec) Decode(data []byte) (interface{}, error) {
+	l, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < l {
+		return nil, errors.New("xorm: malformed cache data")
+	}
+	name, data := string(data[n:n+int(l)]), data[n+int(l):]
+	if name == "" {
+		return GobCodec{}.Decode(data)
+	}
+
+	c.mutex.RLock()
+	t, ok := c.types[name]
+	c.mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("xorm: type %s is not registered to the codec", name)
+	}
+
+	v := reflect.New(t)
+	if err := c.unmarshal(data, v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type CodecUser struct {
+	Id   int64
+	Name string
+}
+
+func TestJSONCodec(t *testing.T) {
+	codec := NewJSONCodec()
+
+	data, err := codec.Encode(&CodecUser{1, "lunny"})
+	assert.NoError(t, err)
+	v, err := codec.Decode(data)
+	assert.NoError(t, err)
+	assert.EqualValues(t, &CodecUser{1, "lunny"}, v)
+
+	// the ids keep the types of their values
+	var ids = []core.PK{{int64(1)}, {int64(2)}}
+	data, err = codec.Encode(ids)
+	assert.NoError(t, err)
+	v, err = codec.Decode(data)
+	assert.NoError(t, err)
+	assert.EqualValues(t, ids, v)
+
+	// a reader has to register the types
+	reader := NewJSONCodec()
+	data, err = codec.Encode([]CodecUser{{1, "lunny"}})
+	assert.NoError(t, err)
+	_, err = reader.Decode(data)
+	assert.Error(t, err)
+
+	reader.Register([]CodecUser{})
+	v, err = reader.Decode(data)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []CodecUser{{1, "lunny"}}, v)
+
+	_, err = reader.Decode([]byte{0x80})
+	assert.Error(t, err)
+}
+
+func TestGobCodecConflict(t *testing.T) {
+	// two types registered to gob with the same name
+	first := func() interface{} {
+		type GobConflict struct{ A int }
+		return GobConflict{1}
+	}()
+	second := func() interface{} {
+		type GobConflict struct{ B string }
+		return GobConflict{"a"}
+	}()
+
+	data, err := GobCodec{}.Encode(first)
+	assert.NoError(t, err)
+	v, err := GobCodec{}.Decode(data)
+	assert.NoError(t, err)
+	assert.EqualValues(t, first, v)
+
+	_, err = GobCodec{}.Encode(second)
+	assert.Error(t, err)
+	_, err = GobCodec{}.Encode(second)
+	assert.Error(t, err)
+}
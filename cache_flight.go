@@ -0,0 +1,94 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-xorm/builder"
+)
+
+// flightGroup runs one load per key at a time, the callers loading a key
+// which is being loaded wait for the running load and share its result, so
+// an expired hot key doesn't send all its readers to the database.
+type flightGroup struct {
+	mutex sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+// do runs fn unless a load of key is running, shared reports whether the
+// result was loaded by another caller
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (val interface{}, err error, shared bool) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mutex.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := new(flightCall)
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mutex.Unlock()
+
+	defer func() {
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		c.wg.Done()
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err, false
+}
+
+// detachedContext keeps the values of a context but not its cancellation, so
+// a load shared by several sessions isn't canceled with the session running it
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// flightKey returns the key of a load of the session, the sessions of other
+// tenants or whose query filters have other conditions don't share it
+func (session *Session) flightKey(key string) string {
+	condSQL, condArgs, err := builder.ToSQL(session.Statement.mandatoryCond())
+	if err != nil {
+		return fmt.Sprintf("%s|%p", key, session)
+	}
+	return fmt.Sprintf("%s|%s|%s-%v", key, session.Statement.tenant, condSQL, condArgs)
+}
+
+// loadOnce runs fn as the load of key shared with the sessions in the same
+// state, with the context of the session detached from its cancellation
+func (session *Session) loadOnce(key string, fn func() (interface{}, error)) (interface{}, error, bool) {
+	return session.Engine.cacheLoads.do(session.flightKey(key), func() (interface{}, error) {
+		ctx := session.ctx
+		if ctx != nil {
+			session.ctx = detachedContext{ctx}
+			defer func() {
+				session.ctx = ctx
+			}()
+		}
+		return fn()
+	})
+}
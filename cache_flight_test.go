@@ -0,0 +1,109 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlightGroup(t *testing.T) {
+	var g flightGroup
+	var loads int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, _ := g.do("key", func() (interface{}, error) {
+				atomic.AddInt32(&loads, 1)
+				time.Sleep(50 * time.Millisecond)
+				return 1, nil
+			})
+			assert.NoError(t, err)
+			assert.EqualValues(t, 1, v)
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, atomic.LoadInt32(&loads))
+
+	// the key is loaded again once the load is done
+	_, err, shared := g.do("key", func() (interface{}, error) {
+		return nil, errors.New("failed")
+	})
+	assert.Error(t, err)
+	assert.False(t, shared)
+}
+
+type FlightCacheUser struct {
+	Id   int64
+	Name string
+}
+
+func TestCacheGetSingleFlight(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(FlightCacheUser)))
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.MapCacher(new(FlightCacheUser), cacher)
+	defer testEngine.MapCacher(new(FlightCacheUser), nil)
+
+	_, err := testEngine.Insert(&FlightCacheUser{Name: "lunny"})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var user FlightCacheUser
+			has, err := testEngine.Id(1).Get(&user)
+			assert.NoError(t, err)
+			assert.True(t, has)
+			assert.EqualValues(t, "lunny", user.Name)
+		}()
+	}
+	wg.Wait()
+}
+
+type flightKeyCtx struct{}
+
+func TestLoadOnceState(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	testEngine.SetTenantStrategy(ColumnTenantStrategy("name"))
+	defer testEngine.SetTenantStrategy(nil)
+
+	newSession := func(tenant string) *Session {
+		session := testEngine.NewSession()
+		assert.NoError(t, session.Statement.setRefValue(rValue(new(FlightCacheUser))))
+		return session.Tenant(tenant)
+	}
+	sess1, sess2, sess3 := newSession("a"), newSession("b"), newSession("a")
+	defer sess1.Close()
+	defer sess2.Close()
+	defer sess3.Close()
+
+	// the loads of other tenants are not shared
+	assert.NotEqual(t, sess1.flightKey("key"), sess2.flightKey("key"))
+	assert.EqualValues(t, sess1.flightKey("key"), sess3.flightKey("key"))
+
+	// the load is not canceled with the context of the session
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), flightKeyCtx{}, "v"))
+	cancel()
+	sess1.Context(ctx)
+	_, err, _ := sess1.loadOnce("key", func() (interface{}, error) {
+		assert.EqualValues(t, "v", sess1.Ctx().Value(flightKeyCtx{}))
+		return nil, sess1.Ctx().Err()
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, ctx, sess1.Ctx())
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+var writeTablesRegexp = regexp.MustCompile("(?i)\\b(?:INSERT\\s+(?:IGNORE\\s+)?INTO|REPLACE\\s+INTO|UPDATE(?:\\s+IGNORE)?|DELETE\\s+FROM|TRUNCATE(?:\\s+TABLE)?|DROP\\s+TABLE(?:\\s+IF\\s+EXISTS)?|ALTER\\s+TABLE)\\s+([`\"\\[\\]\\w.]+)")
+
+// writeTables returns the tables written by the sql statements
+func writeTables(sqlStr string) []string {
+	var tables []string
+	for _, match := range writeTablesRegexp.FindAllStringSubmatch(sqlStr, -1) {
+		parts := strings.Split(match[1], ".")
+		for i, part := range parts {
+			parts[i] = strings.Trim(part, "`\"[]")
+		}
+		if name := strings.Join(parts, "."); name != "" && !strings.EqualFold(name, "SET") {
+			tables = append(tables, name)
+		}
+	}
+	return tables
+}
+
+// namedCacher is a cacher and the name its entries of a table are keyed by
+type namedCacher struct {
+	name   string
+	cacher core.Cacher
+}
+
+// tableCachers returns the cachers of the mapped tables named tableName with
+// the names of the tables, or the default cacher when no table is mapped.
+// The schema and the case of tableName are ignored when matching.
+func (engine *Engine) tableCachers(tableName string) []namedCacher {
+	name := tableName
+	if idx := strings.LastIndex(name, "."); idx > -1 {
+		name = name[idx+1:]
+	}
+
+	var cachers []namedCacher
+	var mapped bool
+	engine.mutex.RLock()
+	for _, table := range engine.Tables {
+		if !strings.EqualFold(table.Name, name) {
+			continue
+		}
+		mapped = true
+		if cacher := engine.getCacher2(table); cacher != nil {
+			cachers = appendCacher(cachers, namedCacher{table.Name, cacher})
+		}
+	}
+	engine.mutex.RUnlock()
+
+	if !mapped && engine.Cacher != nil {
+		cachers = append(cachers, namedCacher{tableName, engine.Cacher})
+	}
+	return cachers
+}
+
+func appendCacher(cachers []namedCacher, cacher namedCacher) []namedCacher {
+	for _, c := range cachers {
+		if c == cacher {
+			return cachers
+		}
+	}
+	return append(cachers, cacher)
+}
+
+func containsCacher(cachers []core.Cacher, cacher core.Cacher) bool {
+	for _, c := range cachers {
+		if c == cacher {
+			return true
+		}
+	}
+	return false
+}
+
+// ClearCacheTables clears the cached ids and beans of the tables
+func (engine *Engine) ClearCacheTables(tables ...string) {
+	for _, tableName := range tables {
+		for _, c := range engine.tableCachers(tableName) {
+			engine.logger.Debug("[cache] clear sql:", c.name)
+			c.cacher.ClearIds(c.name)
+			c.cacher.ClearBeans(c.name)
+		}
+	}
+}
+
+// InvalidateTables declares the tables written by the next Exec, their
+// cache is cleared when the Exec succeeds. The tables of INSERT, UPDATE,
+// DELETE, REPLACE, TRUNCATE, DROP TABLE and ALTER TABLE statements are
+// found automatically, this is for the writes which can't be parsed, e.g.
+// calling a stored procedure.
+func (session *Session) InvalidateTables(tables ...string) *Session {
+	session.Statement.invalidTables = append(session.Statement.invalidTables, tables...)
+	return session
+}
+
+// InvalidateTables declares the tables written by the next Exec
+func (engine *Engine) InvalidateTables(tables ...string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.InvalidateTables(tables...)
+}
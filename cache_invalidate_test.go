@@ -0,0 +1,115 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTables(t *testing.T) {
+	var kases = []struct {
+		sql    string
+		tables []string
+	}{
+		{"UPDATE `user` SET name=? WHERE id=?", []string{"user"}},
+		{"update user set name=?", []string{"user"}},
+		{"INSERT INTO \"public\".\"user\" (name) VALUES (?)", []string{"public.user"}},
+		{"DELETE FROM [user] WHERE id=1", []string{"user"}},
+		{"REPLACE INTO user (id) VALUES (1)", []string{"user"}},
+		{"TRUNCATE TABLE user", []string{"user"}},
+		{"DROP TABLE IF EXISTS user", []string{"user"}},
+		{"ALTER TABLE user ADD COLUMN age INT", []string{"user"}},
+		{"UPDATE user SET a=1; DELETE FROM `group`", []string{"user", "group"}},
+		{"SELECT * FROM user", nil},
+		{"CALL update_users()", nil},
+	}
+
+	for _, kase := range kases {
+		assert.EqualValues(t, kase.tables, writeTables(kase.sql), kase.sql)
+	}
+}
+
+type ExecCacheUser struct {
+	Id   int64
+	Name string
+}
+
+func TestExecInvalidateCache(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(ExecCacheUser)))
+
+	cacher := NewLRUCacher2(NewMemoryStore(), time.Hour, 10000)
+	testEngine.MapCacher(new(ExecCacheUser), cacher)
+	defer testEngine.MapCacher(new(ExecCacheUser), nil)
+
+	_, err := testEngine.Insert(&ExecCacheUser{Name: "lunny"})
+	assert.NoError(t, err)
+
+	var user ExecCacheUser
+	has, err := testEngine.Id(1).Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", user.Name)
+
+	tableName := testEngine.TableMapper.Obj2Table("ExecCacheUser")
+	_, err = testEngine.Exec("UPDATE "+testEngine.Quote(tableName)+" SET name = ? WHERE id = ?", "xlw", 1)
+	assert.NoError(t, err)
+
+	user = ExecCacheUser{}
+	has, err = testEngine.Id(1).Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "xlw", user.Name)
+}
+
+func TestInvalidateTables(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.SetDefaultCacher(cacher)
+	defer testEngine.SetDefaultCacher(nil)
+
+	cacher.PutBean("not_mapped", "1", &ExecCacheUser{1, "lunny"})
+	cacher.PutBean("other", "1", &ExecCacheUser{1, "lunny"})
+
+	_, err := testEngine.InvalidateTables("not_mapped").Exec("SELECT 1")
+	assert.NoError(t, err)
+	assert.Nil(t, cacher.GetBean("not_mapped", "1"))
+	assert.NotNil(t, cacher.GetBean("other", "1"))
+
+	// the declared tables are not kept for the next statement
+	sess := testEngine.NewSession()
+	defer sess.Close()
+	_, err = sess.InvalidateTables("other").Exec("SELECT 1")
+	assert.NoError(t, err)
+	assert.Nil(t, cacher.GetBean("other", "1"))
+
+	cacher.PutBean("other", "1", &ExecCacheUser{1, "lunny"})
+	_, err = sess.Exec("SELECT 1")
+	assert.NoError(t, err)
+	assert.NotNil(t, cacher.GetBean("other", "1"))
+}
+
+func TestClearCacheTablesName(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(ExecCacheUser)))
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.MapCacher(new(ExecCacheUser), cacher)
+	defer testEngine.MapCacher(new(ExecCacheUser), nil)
+
+	// the entries are cleared by the name of the mapped table
+	tableName := testEngine.TableMapper.Obj2Table("ExecCacheUser")
+	for _, name := range []string{"public." + tableName, strings.ToUpper(tableName)} {
+		cacher.PutBean(tableName, "1", &ExecCacheUser{1, "lunny"})
+		assert.NotNil(t, cacher.GetBean(tableName, "1"))
+		testEngine.ClearCacheTables(name)
+		assert.Nil(t, cacher.GetBean(tableName, "1"), name)
+	}
+}
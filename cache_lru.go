@@ -25,6 +25,9 @@ type LRUCacher struct {
 	MaxElementSize int
 	Expired        time.Duration
 	GcInterval     time.Duration
+	onEvict        func(tableName string)
+	gcTimer        *time.Timer
+	stopped        bool
 }
 
 // NewLRUCacher creates a cacher
@@ -44,14 +47,30 @@ func NewLRUCacher2(store core.CacheStore, expired time.Duration, maxElementSize
 	return cacher
 }
 
-// RunGC run once every m.GcInterval
+// RunGC run once every m.GcInterval until Stop is called
 func (m *LRUCacher) RunGC() {
-	time.AfterFunc(m.GcInterval, func() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.stopped {
+		return
+	}
+	m.gcTimer = time.AfterFunc(m.GcInterval, func() {
 		m.RunGC()
 		m.GC()
 	})
 }
 
+// Stop stops the GC of the cacher, a cacher no longer used should be
+// stopped so it can be garbage collected
+func (m *LRUCacher) Stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stopped = true
+	if m.gcTimer != nil {
+		m.gcTimer.Stop()
+	}
+}
+
 // GC check ids lit and sql list to remove all element expired
 func (m *LRUCacher) GC() {
 	//fmt.Println("begin gc ...")
@@ -91,6 +110,13 @@ func (m *LRUCacher) GC() {
 	}
 }
 
+// SetEvictHandler implements EvictNotifier
+func (m *LRUCacher) SetEvictHandler(handler func(tableName string)) {
+	m.mutex.Lock()
+	m.onEvict = handler
+	m.mutex.Unlock()
+}
+
 // GetIds returns all bean's ids according to sql and parameter from cache
 func (m *LRUCacher) GetIds(tableName, sql string) interface{} {
 	m.mutex.Lock()
@@ -206,6 +232,9 @@ func (m *LRUCacher) PutIds(tableName, sql string, ids interface{}) {
 		e := m.sqlList.Front()
 		node := e.Value.(*sqlNode)
 		m.delIds(node.tbName, node.sql)
+		if m.onEvict != nil {
+			m.onEvict(node.tbName)
+		}
 	}
 }
 
@@ -228,6 +257,9 @@ func (m *LRUCacher) PutBean(tableName string, id string, obj interface{}) {
 		e := m.idList.Front()
 		node := e.Value.(*idNode)
 		m.delBean(node.tbName, node.id)
+		if m.onEvict != nil {
+			m.onEvict(node.tbName)
+		}
 	}
 }
 
@@ -0,0 +1,463 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMemcachedMaxValueSize is the default max size of a cached value,
+// it's the default item size limit of memcached
+const DefaultMemcachedMaxValueSize = 1024 * 1024
+
+// the number of points of a node on the hash ring
+const memcachedReplicas = 160
+
+var errMemcachedMiss = errors.New("memcached: cache miss")
+
+// MemcachedOptions configures a MemcachedCacher
+type MemcachedOptions struct {
+	Addrs []string // host:port of the memcached nodes
+
+	// Prefix namespaces the keys, engines sharing memcached nodes should
+	// use different prefixes. Default is "xorm".
+	Prefix string
+	// Expired is the TTL of the cached ids and beans, 0 means no TTL
+	Expired time.Duration
+	// MaxValueSize is the max size of an encoded value, larger values are
+	// not cached. Default is DefaultMemcachedMaxValueSize.
+	MaxValueSize int
+
+	MaxIdle int // max idle connections per node, default is 8
+	Timeout time.Duration
+}
+
+var _ TTLCacher = &MemcachedCacher{}
+
+// MemcachedCacher is a core.Cacher storing ids and beans in memcached. The
+// keys are distributed to the nodes by consistent hashing, so adding or
+// removing a node only moves a small part of the keys. As RedisCacher,
+// clearing a table increases the table's generation.
+type MemcachedCacher struct {
+	opts   MemcachedOptions
+	nodes  map[string]*memcachedNode
+	ring   []uint32
+	points map[uint32]string
+	codec  CacheCodec
+}
+
+// NewMemcachedCacher creates a memcached cacher, connections are created lazily
+func NewMemcachedCacher(opts MemcachedOptions) *MemcachedCacher {
+	if opts.Prefix == "" {
+		opts.Prefix = "xorm"
+	}
+	if opts.MaxValueSize <= 0 {
+		opts.MaxValueSize = DefaultMemcachedMaxValueSize
+	}
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = 8
+	}
+
+	c := &MemcachedCacher{
+		opts:   opts,
+		nodes:  make(map[string]*memcachedNode),
+		points: make(map[uint32]string),
+		codec:  GobCodec{},
+	}
+	for _, addr := range opts.Addrs {
+		c.nodes[addr] = &memcachedNode{addr: addr, opts: &c.opts}
+		for i := 0; i < memcachedReplicas; i++ {
+			point := crc32.ChecksumIEEE([]byte(addr + "#" + strconv.Itoa(i)))
+			c.points[point] = addr
+			c.ring = append(c.ring, point)
+		}
+	}
+	sort.Sort(uint32Slice(c.ring))
+	return c
+}
+
+type uint32Slice []uint32
+
+func (s uint32Slice) Len() int           { return len(s) }
+func (s uint32Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s uint32Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// SetCodec set the codec serializing ids and beans, default is GobCodec
+func (c *MemcachedCacher) SetCodec(codec CacheCodec) {
+	c.codec = codec
+}
+
+// Close closes all the idle connections
+func (c *MemcachedCacher) Close() error {
+	for _, node := range c.nodes {
+		node.close()
+	}
+	return nil
+}
+
+// node returns the node storing the key
+func (c *MemcachedCacher) node(key string) *memcachedNode {
+	if len(c.ring) == 0 {
+		return nil
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(c.ring), func(i int) bool { return c.ring[i] >= h })
+	if i == len(c.ring) {
+		i = 0
+	}
+	return c.nodes[c.points[c.ring[i]]]
+}
+
+func (c *MemcachedCacher) genKey(kind, tableName string) string {
+	return fmt.Sprintf("%s:gen:%s:%s", c.opts.Prefix, kind, tableName)
+}
+
+// key returns the key of id in the current generation of the table
+func (c *MemcachedCacher) key(kind, tableName, id string) (string, error) {
+	genKey := c.genKey(kind, tableName)
+	node := c.node(genKey)
+	if node == nil {
+		return "", errors.New("memcached: no node")
+	}
+	var gen = "0"
+	data, err := node.get(genKey)
+	if err == nil {
+		gen = strings.TrimSpace(string(data))
+	} else if err != errMemcachedMiss {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s:%s:%s:%s", c.opts.Prefix, kind, tableName, gen, id), nil
+}
+
+// memcachedMaxRelativeTTL is the longest expiration memcached reads as
+// seconds from now, the longer ones are read as unix timestamps
+const memcachedMaxRelativeTTL = 30 * 24 * 3600
+
+// expiration returns the expiration of the entries of ttl, 0 never expires
+func (c *MemcachedCacher) expiration(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	secs := int64(ttl / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	if secs > memcachedMaxRelativeTTL {
+		return time.Now().Add(ttl).Unix()
+	}
+	return secs
+}
+
+func (c *MemcachedCacher) get(kind, tableName, id string) interface{} {
+	key, err := c.key(kind, tableName, id)
+	if err != nil {
+		return nil
+	}
+	data, err := c.node(key).get(key)
+	if err != nil {
+		return nil
+	}
+	v, err := c.codec.Decode(data)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+func (c *MemcachedCacher) put(kind, tableName, id string, v interface{}, ttl time.Duration) {
+	key, err := c.key(kind, tableName, id)
+	if err != nil {
+		return
+	}
+	data, err := c.codec.Encode(v)
+	if err != nil || len(data) > c.opts.MaxValueSize {
+		return
+	}
+	c.node(key).store("set", key, data, c.expiration(ttl))
+}
+
+func (c *MemcachedCacher) del(kind, tableName, id string) {
+	key, err := c.key(kind, tableName, id)
+	if err != nil {
+		return
+	}
+	c.node(key).delete(key)
+}
+
+func (c *MemcachedCacher) clear(kind, tableName string) {
+	key := c.genKey(kind, tableName)
+	node := c.node(key)
+	if node == nil {
+		return
+	}
+	if err := node.incr(key); err == errMemcachedMiss {
+		// the generation key is created by the first clear
+		if err = node.store("add", key, []byte("1"), 0); err == errMemcachedMiss {
+			node.incr(key)
+		}
+	}
+}
+
+// memcachedKey makes sure the key is valid for memcached, the keys longer
+// than 250 bytes are replaced by their hash
+func memcachedKey(key string) string {
+	if len(key) > 250 || strings.ContainsAny(key, " \r\n\t") {
+		return sqlHash(key)
+	}
+	return key
+}
+
+// GetIds implements core.Cacher
+func (c *MemcachedCacher) GetIds(tableName, sql string) interface{} {
+	return c.get("ids", tableName, sqlHash(sql))
+}
+
+// GetBean implements core.Cacher
+func (c *MemcachedCacher) GetBean(tableName string, id string) interface{} {
+	return c.get("bean", tableName, id)
+}
+
+// PutIds implements core.Cacher
+func (c *MemcachedCacher) PutIds(tableName, sql string, ids interface{}) {
+	c.put("ids", tableName, sqlHash(sql), ids, c.opts.Expired)
+}
+
+// PutIdsTTL implements TTLCacher
+func (c *MemcachedCacher) PutIdsTTL(tableName, sql string, ids interface{}, ttl time.Duration) {
+	c.put("ids", tableName, sqlHash(sql), ids, ttl)
+}
+
+// PutBean implements core.Cacher
+func (c *MemcachedCacher) PutBean(tableName string, id string, obj interface{}) {
+	c.put("bean", tableName, id, obj, c.opts.Expired)
+}
+
+// PutBeanTTL implements TTLCacher
+func (c *MemcachedCacher) PutBeanTTL(tableName string, id string, obj interface{}, ttl time.Duration) {
+	c.put("bean", tableName, id, obj, ttl)
+}
+
+// DelIds implements core.Cacher
+func (c *MemcachedCacher) DelIds(tableName, sql string) {
+	c.del("ids", tableName, sqlHash(sql))
+}
+
+// DelBean implements core.Cacher
+func (c *MemcachedCacher) DelBean(tableName string, id string) {
+	c.del("bean", tableName, id)
+}
+
+// ClearIds implements core.Cacher
+func (c *MemcachedCacher) ClearIds(tableName string) {
+	c.clear("ids", tableName)
+}
+
+// ClearBeans implements core.Cacher
+func (c *MemcachedCacher) ClearBeans(tableName string) {
+	c.clear("bean", tableName)
+}
+
+type memcachedConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// memcachedNode keeps the idle connections to a memcached node and speaks
+// the text protocol
+type memcachedNode struct {
+	addr   string
+	opts   *MemcachedOptions
+	mutex  sync.Mutex
+	idle   []*memcachedConn
+	closed bool
+}
+
+func (n *memcachedNode) getConn() (*memcachedConn, error) {
+	n.mutex.Lock()
+	if n.closed {
+		n.mutex.Unlock()
+		return nil, errors.New("memcached: node is closed")
+	}
+	if l := len(n.idle); l > 0 {
+		c := n.idle[l-1]
+		n.idle = n.idle[:l-1]
+		n.mutex.Unlock()
+		return c, nil
+	}
+	n.mutex.Unlock()
+
+	conn, err := net.DialTimeout("tcp", n.addr, n.opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &memcachedConn{conn, bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))}, nil
+}
+
+func (n *memcachedNode) putConn(c *memcachedConn) {
+	n.mutex.Lock()
+	if !n.closed && len(n.idle) < n.opts.MaxIdle {
+		n.idle = append(n.idle, c)
+		c = nil
+	}
+	n.mutex.Unlock()
+
+	if c != nil {
+		c.conn.Close()
+	}
+}
+
+func (n *memcachedNode) close() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.closed = true
+	for _, c := range n.idle {
+		c.conn.Close()
+	}
+	n.idle = nil
+}
+
+// do runs f on a pooled connection, broken connections are discarded
+func (n *memcachedNode) do(f func(rw *bufio.ReadWriter) error) error {
+	c, err := n.getConn()
+	if err != nil {
+		return err
+	}
+	if n.opts.Timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(n.opts.Timeout))
+	}
+
+	err = f(c.rw)
+	if err != nil && err != errMemcachedMiss {
+		// the connection is broken
+		c.conn.Close()
+		return err
+	}
+	n.putConn(c)
+	return err
+}
+
+func readMemcachedLine(r *bufio.ReadWriter) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (n *memcachedNode) get(key string) ([]byte, error) {
+	var data []byte
+	err := n.do(func(rw *bufio.ReadWriter) error {
+		fmt.Fprintf(rw, "get %s\r\n", memcachedKey(key))
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		line, err := readMemcachedLine(rw)
+		if err != nil {
+			return err
+		}
+		if line == "END" {
+			return errMemcachedMiss
+		}
+
+		// VALUE <key> <flags> <bytes>
+		fields := strings.Fields(line)
+		if len(fields) != 4 || fields[0] != "VALUE" {
+			return fmt.Errorf("memcached: unexpected reply %q", line)
+		}
+		size, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return err
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(rw, buf); err != nil {
+			return err
+		}
+		data = buf[:size]
+
+		line, err = readMemcachedLine(rw)
+		if err != nil {
+			return err
+		}
+		if line != "END" {
+			return fmt.Errorf("memcached: unexpected reply %q", line)
+		}
+		return nil
+	})
+	return data, err
+}
+
+// store executes set or add, errMemcachedMiss is returned when the value
+// is not stored
+func (n *memcachedNode) store(command, key string, data []byte, exptime int64) error {
+	return n.do(func(rw *bufio.ReadWriter) error {
+		fmt.Fprintf(rw, "%s %s 0 %d %d\r\n", command, memcachedKey(key), exptime, len(data))
+		rw.Write(data)
+		rw.WriteString("\r\n")
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		line, err := readMemcachedLine(rw)
+		if err != nil {
+			return err
+		}
+		switch line {
+		case "STORED":
+			return nil
+		case "NOT_STORED":
+			return errMemcachedMiss
+		}
+		return fmt.Errorf("memcached: unexpected reply %q", line)
+	})
+}
+
+func (n *memcachedNode) delete(key string) error {
+	return n.do(func(rw *bufio.ReadWriter) error {
+		fmt.Fprintf(rw, "delete %s\r\n", memcachedKey(key))
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		line, err := readMemcachedLine(rw)
+		if err != nil {
+			return err
+		}
+		switch line {
+		case "DELETED":
+			return nil
+		case "NOT_FOUND":
+			return errMemcachedMiss
+		}
+		return fmt.Errorf("memcached: unexpected reply %q", line)
+	})
+}
+
+func (n *memcachedNode) incr(key string) error {
+	return n.do(func(rw *bufio.ReadWriter) error {
+		fmt.Fprintf(rw, "incr %s 1\r\n", memcachedKey(key))
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		line, err := readMemcachedLine(rw)
+		if err != nil {
+			return err
+		}
+		if line == "NOT_FOUND" {
+			return errMemcachedMiss
+		}
+		if _, err = strconv.ParseUint(line, 10, 64); err != nil {
+			return fmt.Errorf("memcached: unexpected reply %q", line)
+		}
+		return nil
+	})
+}
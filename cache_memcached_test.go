@@ -0,0 +1,249 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMemcached is a minimal in memory memcached node for the tests
+type fakeMemcached struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	data     map[string][]byte
+}
+
+func newFakeMemcached(t *testing.T) *fakeMemcached {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	s := &fakeMemcached{
+		listener: l,
+		data:     make(map[string][]byte),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeMemcached) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeMemcached) Close() {
+	s.listener.Close()
+}
+
+func (s *fakeMemcached) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.data)
+}
+
+func (s *fakeMemcached) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return
+		}
+
+		var reply string
+		s.mutex.Lock()
+		switch fields[0] {
+		case "get":
+			if v, ok := s.data[fields[1]]; ok {
+				reply = fmt.Sprintf("VALUE %s 0 %d\r\n%s\r\n", fields[1], len(v), v)
+			}
+			reply += "END\r\n"
+		case "set", "add":
+			size, _ := strconv.Atoi(fields[4])
+			buf := make([]byte, size+2)
+			io.ReadFull(r, buf)
+			if _, ok := s.data[fields[1]]; ok && fields[0] == "add" {
+				reply = "NOT_STORED\r\n"
+			} else {
+				s.data[fields[1]] = buf[:size]
+				reply = "STORED\r\n"
+			}
+		case "delete":
+			if _, ok := s.data[fields[1]]; ok {
+				delete(s.data, fields[1])
+				reply = "DELETED\r\n"
+			} else {
+				reply = "NOT_FOUND\r\n"
+			}
+		case "incr":
+			if v, ok := s.data[fields[1]]; ok {
+				n, _ := strconv.ParseUint(string(v), 10, 64)
+				n++
+				s.data[fields[1]] = []byte(strconv.FormatUint(n, 10))
+				reply = fmt.Sprintf("%d\r\n", n)
+			} else {
+				reply = "NOT_FOUND\r\n"
+			}
+		default:
+			reply = "ERROR\r\n"
+		}
+		s.mutex.Unlock()
+
+		if _, err = conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+type MemcachedCacheUser struct {
+	Id   int64
+	Name string
+}
+
+func TestMemcachedCacher(t *testing.T) {
+	server := newFakeMemcached(t)
+	defer server.Close()
+
+	cacher := NewMemcachedCacher(MemcachedOptions{Addrs: []string{server.Addr()}, Expired: time.Hour})
+	defer cacher.Close()
+
+	assert.Nil(t, cacher.GetBean("user", "1"))
+
+	cacher.PutBean("user", "1", &MemcachedCacheUser{1, "lunny"})
+	assert.EqualValues(t, &MemcachedCacheUser{1, "lunny"}, cacher.GetBean("user", "1"))
+
+	var ids = []core.PK{{int64(1)}, {int64(2)}}
+	cacher.PutIds("user", "SELECT id FROM user", ids)
+	assert.EqualValues(t, ids, cacher.GetIds("user", "SELECT id FROM user"))
+
+	cacher.DelIds("user", "SELECT id FROM user")
+	assert.Nil(t, cacher.GetIds("user", "SELECT id FROM user"))
+
+	cacher.DelBean("user", "1")
+	assert.Nil(t, cacher.GetBean("user", "1"))
+
+	cacher.PutBean("user", "1", &MemcachedCacheUser{1, "lunny"})
+	cacher.PutBean("group", "1", &MemcachedCacheUser{1, "admin"})
+	cacher.ClearBeans("user")
+	assert.Nil(t, cacher.GetBean("user", "1"))
+	assert.NotNil(t, cacher.GetBean("group", "1"))
+
+	cacher.PutBean("user", "1", &MemcachedCacheUser{1, "lunny"})
+	assert.NotNil(t, cacher.GetBean("user", "1"))
+	cacher.ClearBeans("user")
+	assert.Nil(t, cacher.GetBean("user", "1"))
+}
+
+func TestMemcachedExpiration(t *testing.T) {
+	var c MemcachedCacher
+	assert.EqualValues(t, 0, c.expiration(0))
+	assert.EqualValues(t, 1, c.expiration(time.Millisecond))
+	assert.EqualValues(t, 3600, c.expiration(time.Hour))
+	assert.EqualValues(t, 30*24*3600, c.expiration(30*24*time.Hour))
+
+	// the longer ones are unix timestamps
+	ttl := 60 * 24 * time.Hour
+	exp := c.expiration(ttl)
+	assert.True(t, exp >= time.Now().Add(ttl).Unix()-1 && exp <= time.Now().Add(ttl).Unix())
+}
+
+func TestMemcachedCacherMaxValueSize(t *testing.T) {
+	server := newFakeMemcached(t)
+	defer server.Close()
+
+	cacher := NewMemcachedCacher(MemcachedOptions{Addrs: []string{server.Addr()}, MaxValueSize: 256})
+	defer cacher.Close()
+
+	cacher.PutBean("user", "1", &MemcachedCacheUser{1, strings.Repeat("x", 512)})
+	assert.Nil(t, cacher.GetBean("user", "1"))
+	assert.EqualValues(t, 0, server.Len())
+
+	cacher.PutBean("user", "2", &MemcachedCacheUser{2, "lunny"})
+	assert.NotNil(t, cacher.GetBean("user", "2"))
+}
+
+func TestMemcachedCacherNodes(t *testing.T) {
+	var servers []*fakeMemcached
+	var addrs []string
+	for i := 0; i < 3; i++ {
+		server := newFakeMemcached(t)
+		defer server.Close()
+		servers = append(servers, server)
+		addrs = append(addrs, server.Addr())
+	}
+
+	cacher := NewMemcachedCacher(MemcachedOptions{Addrs: addrs})
+	defer cacher.Close()
+
+	for i := 0; i < 100; i++ {
+		cacher.PutBean("user", strconv.Itoa(i), &MemcachedCacheUser{int64(i), "lunny"})
+	}
+	for i := 0; i < 100; i++ {
+		assert.NotNil(t, cacher.GetBean("user", strconv.Itoa(i)))
+	}
+	for _, server := range servers {
+		assert.True(t, server.Len() > 0)
+	}
+
+	// removing a node only moves the keys of the node
+	var moved int
+	cacher2 := NewMemcachedCacher(MemcachedOptions{Addrs: addrs[:2]})
+	defer cacher2.Close()
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("xorm:bean:user:0:%d", i)
+		if cacher.node(key).addr != cacher2.node(key).addr {
+			assert.EqualValues(t, addrs[2], cacher.node(key).addr)
+			moved++
+		}
+	}
+	assert.True(t, moved < 100)
+}
+
+func TestMemcachedCacherGet(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	server := newFakeMemcached(t)
+	defer server.Close()
+
+	cacher := NewMemcachedCacher(MemcachedOptions{Addrs: []string{server.Addr()}})
+	defer cacher.Close()
+
+	assert.NoError(t, testEngine.Sync2(new(MemcachedCacheUser)))
+	testEngine.MapCacher(new(MemcachedCacheUser), cacher)
+	defer testEngine.MapCacher(new(MemcachedCacheUser), nil)
+
+	_, err := testEngine.Insert(&MemcachedCacheUser{Name: "lunny"})
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		var user MemcachedCacheUser
+		has, err := testEngine.Id(1).Get(&user)
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.EqualValues(t, "lunny", user.Name)
+	}
+	assert.True(t, server.Len() > 0)
+}
@@ -0,0 +1,131 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// CacheOptions overrides the caching policy of a table
+type CacheOptions struct {
+	// Disabled stops caching the table
+	Disabled bool
+	// TTL is the TTL of the ids and beans of the table, it's only applied
+	// by the cachers implementing TTLCacher and by the cacher of MaxEntries
+	TTL time.Duration
+	// MaxEntries gives the table its own in process LRU cacher holding at
+	// most MaxEntries ids and beans, so the table can't evict the entries
+	// of the other tables
+	MaxEntries int
+}
+
+type tableCacheOptions struct {
+	opts   CacheOptions
+	cacher core.Cacher // the own cacher when MaxEntries is set
+}
+
+type cacheOptionsRegistry struct {
+	mutex  sync.RWMutex
+	tables map[string]*tableCacheOptions
+}
+
+// SetCacherOptions overrides the caching policy of the mapped table, it can
+// be changed at runtime. The zero CacheOptions restores the default policy.
+// The entries of the table cached before the change are cleared, as the
+// writes of a disabled table don't clear them.
+func (engine *Engine) SetCacherOptions(tableName string, opts CacheOptions) {
+	r := &engine.cacheOptions
+	r.mutex.Lock()
+	old := r.tables[tableName]
+	var t *tableCacheOptions
+	if opts == (CacheOptions{}) {
+		delete(r.tables, tableName)
+	} else {
+		if r.tables == nil {
+			r.tables = make(map[string]*tableCacheOptions)
+		}
+		t = &tableCacheOptions{opts: opts}
+		if opts.MaxEntries > 0 && !opts.Disabled {
+			// the own cacher of the same size and TTL is kept
+			if old != nil && old.cacher != nil && old.opts.MaxEntries == opts.MaxEntries && old.opts.TTL == opts.TTL {
+				t.cacher = old.cacher
+			} else {
+				var expired = opts.TTL
+				if expired <= 0 {
+					expired = 3600 * time.Second
+				}
+				t.cacher = NewLRUCacher2(NewMemoryStore(), expired, opts.MaxEntries)
+				engine.cacheMonitor.watch(t.cacher)
+			}
+		}
+		r.tables[tableName] = t
+	}
+	r.mutex.Unlock()
+
+	if old != nil && old.cacher != nil && (t == nil || t.cacher != old.cacher) {
+		if c, ok := old.cacher.(interface {
+			Stop()
+		}); ok {
+			c.Stop()
+		}
+	}
+	for _, cacher := range engine.mappedCachers(tableName) {
+		cacher.ClearIds(tableName)
+		cacher.ClearBeans(tableName)
+	}
+}
+
+// mappedCachers returns the cachers the mapped tables named tableName have
+// without options
+func (engine *Engine) mappedCachers(tableName string) []core.Cacher {
+	var cachers []core.Cacher
+	engine.mutex.RLock()
+	defer engine.mutex.RUnlock()
+	for _, table := range engine.Tables {
+		if table.Name == tableName && table.Cacher != nil && !containsCacher(cachers, table.Cacher) {
+			cachers = append(cachers, table.Cacher)
+		}
+	}
+	return cachers
+}
+
+// CacherOptions returns the caching policy of the table set by
+// SetCacherOptions
+func (engine *Engine) CacherOptions(tableName string) CacheOptions {
+	r := &engine.cacheOptions
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if t, ok := r.tables[tableName]; ok {
+		return t.opts
+	}
+	return CacheOptions{}
+}
+
+// tableCacher applies the options of the table to its cacher
+func (engine *Engine) tableCacher(table *core.Table) core.Cacher {
+	r := &engine.cacheOptions
+	r.mutex.RLock()
+	t, ok := r.tables[table.Name]
+	r.mutex.RUnlock()
+	if !ok {
+		return table.Cacher
+	}
+
+	switch {
+	case t.opts.Disabled:
+		return nil
+	case t.cacher != nil:
+		return t.cacher
+	case table.Cacher == nil:
+		return nil
+	}
+	if c, ok := table.Cacher.(TTLCacher); ok && t.opts.TTL > 0 {
+		return ttlCacher{c, t.opts.TTL}
+	}
+	return table.Cacher
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type OptionsCacheUser struct {
+	Id   int64
+	Name string
+}
+
+func TestCacherOptions(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(OptionsCacheUser)))
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.MapCacher(new(OptionsCacheUser), cacher)
+	defer testEngine.MapCacher(new(OptionsCacheUser), nil)
+
+	tableName := testEngine.TableMapper.Obj2Table("OptionsCacheUser")
+	defer testEngine.SetCacherOptions(tableName, CacheOptions{})
+
+	for _, name := range []string{"lunny", "xlw", "xiangxiao"} {
+		_, err := testEngine.Insert(&OptionsCacheUser{Name: name})
+		assert.NoError(t, err)
+	}
+
+	getAll := func() {
+		for id := int64(1); id <= 3; id++ {
+			var user OptionsCacheUser
+			has, err := testEngine.Id(id).Get(&user)
+			assert.NoError(t, err)
+			assert.True(t, has)
+		}
+	}
+
+	testEngine.SetCacherOptions(tableName, CacheOptions{Disabled: true})
+	assert.True(t, testEngine.CacherOptions(tableName).Disabled)
+	getAll()
+	assert.EqualValues(t, 0, cacher.Len())
+
+	// the table has its own cacher of at most 2 entries
+	testEngine.SetCacherOptions(tableName, CacheOptions{MaxEntries: 2})
+	getAll()
+	assert.EqualValues(t, 0, cacher.Len())
+	own := testEngine.cacheOptions.tables[tableName].cacher.(*LRUCacher)
+	assert.EqualValues(t, 2, own.idList.Len())
+
+	testEngine.SetCacherOptions(tableName, CacheOptions{})
+	assert.EqualValues(t, CacheOptions{}, testEngine.CacherOptions(tableName))
+	getAll()
+	assert.True(t, cacher.Len() > 0)
+}
+
+func TestCacherOptionsChange(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(OptionsCacheUser)))
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.MapCacher(new(OptionsCacheUser), cacher)
+	defer testEngine.MapCacher(new(OptionsCacheUser), nil)
+
+	tableName := testEngine.TableMapper.Obj2Table("OptionsCacheUser")
+	defer testEngine.SetCacherOptions(tableName, CacheOptions{})
+
+	user := OptionsCacheUser{Name: "lunny"}
+	_, err := testEngine.Insert(&user)
+	assert.NoError(t, err)
+	var got OptionsCacheUser
+	_, err = testEngine.Id(user.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, cacher.Len() > 0)
+
+	// the writes while the table is disabled don't make its cache stale
+	testEngine.SetCacherOptions(tableName, CacheOptions{Disabled: true})
+	_, err = testEngine.Id(user.Id).Update(&OptionsCacheUser{Name: "xlw"})
+	assert.NoError(t, err)
+	testEngine.SetCacherOptions(tableName, CacheOptions{})
+	got = OptionsCacheUser{}
+	_, err = testEngine.Id(user.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "xlw", got.Name)
+
+	// the own cacher is kept by the same options and stopped by others
+	testEngine.SetCacherOptions(tableName, CacheOptions{MaxEntries: 2})
+	own := testEngine.cacheOptions.tables[tableName].cacher.(*LRUCacher)
+	testEngine.SetCacherOptions(tableName, CacheOptions{MaxEntries: 2})
+	assert.True(t, own == testEngine.cacheOptions.tables[tableName].cacher)
+	testEngine.SetCacherOptions(tableName, CacheOptions{MaxEntries: 3})
+	assert.True(t, own.stopped)
+}
@@ -0,0 +1,275 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"crypto/sha1"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// the table name the query results are stored under in the cacher
+const queryCacheTable = "xorm_query_cache"
+
+var readTablesRegexp = regexp.MustCompile("(?i)\\b(?:FROM|JOIN)\\s+([`\"\\[\\]\\w.]+)")
+
+// readTables returns the tables read by the sql statement
+func readTables(sqlStr string) []string {
+	var tables []string
+	for _, match := range readTablesRegexp.FindAllStringSubmatch(sqlStr, -1) {
+		parts := strings.Split(match[1], ".")
+		for i, part := range parts {
+			parts[i] = strings.Trim(part, "`\"[]")
+		}
+		if name := strings.Join(parts, "."); name != "" {
+			tables = append(tables, name)
+		}
+	}
+	return tables
+}
+
+// QueryCache caches the results of Find keyed by the SQL and its args. A
+// result is tagged with the tables its query reads and a write to any of
+// them invalidates it, including raw Exec. The tags are versioned in
+// process, so the writes of other processes are not seen until the results
+// expire.
+type QueryCache struct {
+	cacher core.Cacher
+	codec  CacheCodec
+	ttl    time.Duration
+
+	mutex sync.RWMutex
+	gens  map[string]uint64
+}
+
+type queryCacheEntry struct {
+	Tables []string
+	Gens   []uint64
+	Data   []byte
+}
+
+// NewQueryCache creates a query cache storing the results in cacher, the
+// results are encoded so they are never shared with the callers. The
+// optional ttl is used when the cacher is a TTLCacher.
+func NewQueryCache(cacher core.Cacher, ttl ...time.Duration) *QueryCache {
+	qc := &QueryCache{
+		cacher: cacher,
+		codec:  GobCodec{},
+		gens:   make(map[string]uint64),
+	}
+	if len(ttl) > 0 {
+		qc.ttl = ttl[0]
+	}
+	return qc
+}
+
+// SetCodec set the codec encoding the results, default is GobCodec
+func (qc *QueryCache) SetCodec(codec CacheCodec) {
+	qc.codec = codec
+}
+
+// queryCacheTag returns the tag of the results reading table, the schema
+// is ignored as for the cachers of the tables
+func queryCacheTag(table string) string {
+	if idx := strings.LastIndex(table, "."); idx > -1 {
+		table = table[idx+1:]
+	}
+	return strings.ToLower(table)
+}
+
+// Invalidate invalidates the results which read the tables
+func (qc *QueryCache) Invalidate(tables ...string) {
+	if len(tables) == 0 {
+		return
+	}
+	qc.mutex.Lock()
+	for _, table := range tables {
+		qc.gens[queryCacheTag(table)]++
+	}
+	qc.mutex.Unlock()
+}
+
+func (qc *QueryCache) currentGens(tables []string) []uint64 {
+	var gens = make([]uint64, len(tables))
+	qc.mutex.RLock()
+	for i, table := range tables {
+		gens[i] = qc.gens[table]
+	}
+	qc.mutex.RUnlock()
+	return gens
+}
+
+// queryCacheArg returns the value bound for arg, the pointers are
+// dereferenced and the driver.Valuers valued
+func queryCacheArg(arg interface{}) (interface{}, error) {
+	for {
+		v := reflect.ValueOf(arg)
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, nil
+		}
+		if valuer, ok := arg.(driver.Valuer); ok {
+			return valuer.Value()
+		}
+		if v.Kind() != reflect.Ptr {
+			if t, ok := arg.(time.Time); ok {
+				// without the monotonic clock reading
+				return t.Round(0), nil
+			}
+			return arg, nil
+		}
+		arg = v.Elem().Interface()
+	}
+}
+
+// queryCacheKey returns the key of the result of the query, false when an
+// arg could not be valued
+func queryCacheKey(sqlStr string, args []interface{}, tp reflect.Type) (string, bool) {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\x00%s", tp.String(), strings.Join(strings.Fields(sqlStr), " "))
+	for _, arg := range args {
+		value, err := queryCacheArg(arg)
+		if err != nil {
+			return "", false
+		}
+		fmt.Fprintf(h, "\x00%T:%v", value, value)
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// load fills container with the cached result, it returns false when the
+// result is not cached or stale
+func (qc *QueryCache) load(key string, container reflect.Value) bool {
+	entry, ok := qc.cacher.GetBean(queryCacheTable, key).(*queryCacheEntry)
+	if !ok {
+		return false
+	}
+	gens := qc.currentGens(entry.Tables)
+	for i := range gens {
+		if gens[i] != entry.Gens[i] {
+			return false
+		}
+	}
+
+	v, err := qc.codec.Decode(entry.Data)
+	if err != nil {
+		return false
+	}
+	result := reflect.ValueOf(v)
+	if result.Type() != container.Type() {
+		return false
+	}
+
+	if container.Kind() == reflect.Map {
+		if container.IsNil() {
+			container.Set(reflect.MakeMap(container.Type()))
+		}
+		for _, k := range result.MapKeys() {
+			container.SetMapIndex(k, result.MapIndex(k))
+		}
+	} else {
+		container.Set(reflect.AppendSlice(container, result))
+	}
+	return true
+}
+
+func (qc *QueryCache) store(key string, tables []string, gens []uint64, container reflect.Value, ttl time.Duration) {
+	data, err := qc.codec.Encode(container.Interface())
+	if err != nil {
+		return
+	}
+
+	entry := &queryCacheEntry{tables, gens, data}
+	if c, ok := qc.cacher.(TTLCacher); ok && ttl > 0 {
+		c.PutBeanTTL(queryCacheTable, key, entry, ttl)
+		return
+	}
+	qc.cacher.PutBean(queryCacheTable, key, entry)
+}
+
+// find loads the result of the query from the cache or stores the result
+// of doFind into the cache
+func (qc *QueryCache) find(session *Session, container reflect.Value, sqlStr string, args []interface{}, doFind func() error) error {
+	monitor := session.Engine.cacheMonitor
+	key, ok := queryCacheKey(sqlStr, args, container.Type())
+	if !ok {
+		return doFind()
+	}
+	if qc.load(key, container) {
+		monitor.record(queryCacheTable, CacheHit, 0)
+		session.getLogger().Debug("[queryCache] cache hit sql:", sqlStr, args)
+		return nil
+	}
+	monitor.record(queryCacheTable, CacheMiss, 0)
+
+	var ttl = qc.ttl
+	if session.Statement.cacheTTL > 0 {
+		ttl = session.Statement.cacheTTL
+	}
+	_, err, shared := session.loadOnce("query:"+key, func() (interface{}, error) {
+		var tables = readTables(sqlStr)
+		for i := range tables {
+			tables[i] = queryCacheTag(tables[i])
+		}
+		// the generations are taken before querying, so the result of a
+		// query racing with a write is stale at once
+		gens := qc.currentGens(tables)
+		defer monitor.loaded(queryCacheTable, time.Now())
+		if err := doFind(); err != nil {
+			return nil, err
+		}
+		qc.store(key, tables, gens, container, ttl)
+		return nil, nil
+	})
+	if !shared {
+		return err
+	}
+	// the result loaded by another session is taken from the cache, the
+	// session queries by itself when it could not be cached
+	if err == nil && qc.load(key, container) {
+		return nil
+	}
+	return doFind()
+}
+
+// SetQueryCache set the cache of the Find results, nil disables it
+func (engine *Engine) SetQueryCache(qc *QueryCache) {
+	engine.queryCache = qc
+}
+
+// queryCache returns the query cache when the result of a Find into
+// container could be cached
+func (session *Session) queryCache(container reflect.Value) *QueryCache {
+	qc := session.Engine.queryCache
+	if qc == nil || !session.IsAutoCommit || !session.Statement.UseCache ||
+		session.Statement.IsForUpdate || container.Len() > 0 {
+		return nil
+	}
+	return qc
+}
+
+// invalidateQueryCache invalidates the results reading the tables written
+// by sqlStr, again after commit when the session is in a transaction
+func (session *Session) invalidateQueryCache(sqlStr string) {
+	qc := session.Engine.queryCache
+	if qc == nil {
+		return
+	}
+
+	tables := append(writeTables(sqlStr), session.Statement.invalidTables...)
+	qc.Invalidate(tables...)
+	if !session.IsAutoCommit {
+		session.OnCommit(func() {
+			qc.Invalidate(tables...)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadTables(t *testing.T) {
+	var kases = []struct {
+		sql    string
+		tables []string
+	}{
+		{"SELECT * FROM `user` WHERE id=?", []string{"user"}},
+		{"select a.id from \"public\".\"user\" a join [group] g on a.gid = g.id", []string{"public.user", "group"}},
+		{"SELECT 1", nil},
+	}
+
+	for _, kase := range kases {
+		assert.EqualValues(t, kase.tables, readTables(kase.sql), kase.sql)
+	}
+}
+
+func TestQueryCacheKey(t *testing.T) {
+	tp := reflect.TypeOf([]QueryCacheUser{})
+	key := func(args ...interface{}) string {
+		k, ok := queryCacheKey("SELECT * FROM user WHERE name = ?", args, tp)
+		assert.True(t, ok)
+		return k
+	}
+
+	// the pointer args are keyed by the values they point to
+	name1, name2 := "lunny", "xlw"
+	assert.NotEqual(t, key(&name1), key(&name2))
+	assert.EqualValues(t, key("lunny"), key(&name1))
+	name2 = "lunny"
+	assert.EqualValues(t, key(&name1), key(&name2))
+
+	// and the valuers by their values
+	assert.EqualValues(t, key("lunny"), key(sql.NullString{String: "lunny", Valid: true}))
+	assert.NotEqual(t, key(&sql.NullString{String: "lunny", Valid: true}), key(&sql.NullString{String: "xlw", Valid: true}))
+	assert.EqualValues(t, key(nil), key((*sql.NullString)(nil)))
+}
+
+func TestQueryCacheSchema(t *testing.T) {
+	qc := NewQueryCache(NewShardedCacher(ShardedCacherOptions{}))
+	gens := qc.currentGens([]string{queryCacheTag("public.user")})
+
+	// the write of user invalidates the results reading public.user
+	qc.Invalidate("user")
+	assert.NotEqual(t, gens, qc.currentGens([]string{queryCacheTag("public.user")}))
+
+	gens = qc.currentGens([]string{queryCacheTag("user")})
+	qc.Invalidate("public.User")
+	assert.NotEqual(t, gens, qc.currentGens([]string{queryCacheTag("user")}))
+}
+
+type QueryCacheUser struct {
+	Id   int64
+	Name string
+}
+
+func TestQueryCache(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(QueryCacheUser)))
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.SetQueryCache(NewQueryCache(cacher))
+	defer testEngine.SetQueryCache(nil)
+
+	_, err := testEngine.Insert(&QueryCacheUser{Name: "lunny"})
+	assert.NoError(t, err)
+
+	var users []QueryCacheUser
+	assert.NoError(t, testEngine.Where("name = ?", "lunny").Find(&users))
+	assert.EqualValues(t, 1, len(users))
+	assert.True(t, cacher.Len() > 0)
+
+	// a raw write not seen by the query cache is hidden by the cached result
+	tableName := testEngine.TableMapper.Obj2Table("QueryCacheUser")
+	_, err = testEngine.DB().Exec("INSERT INTO "+testEngine.Quote(tableName)+" (name) VALUES (?)", "lunny")
+	assert.NoError(t, err)
+
+	users = nil
+	assert.NoError(t, testEngine.Where("name = ?", "lunny").Find(&users))
+	assert.EqualValues(t, 1, len(users))
+
+	// NoCache skips the query cache
+	users = nil
+	assert.NoError(t, testEngine.NoCache().Where("name = ?", "lunny").Find(&users))
+	assert.EqualValues(t, 2, len(users))
+
+	// a write to the table invalidates the result
+	_, err = testEngine.Insert(&QueryCacheUser{Name: "lunny"})
+	assert.NoError(t, err)
+
+	users = nil
+	assert.NoError(t, testEngine.Where("name = ?", "lunny").Find(&users))
+	assert.EqualValues(t, 3, len(users))
+
+	// and so does an invalidation by table
+	_, err = testEngine.DB().Exec("DELETE FROM "+testEngine.Quote(tableName)+" WHERE id = ?", 1)
+	assert.NoError(t, err)
+	testEngine.queryCache.Invalidate(tableName)
+
+	var userMap = make(map[int64]QueryCacheUser)
+	assert.NoError(t, testEngine.Where("name = ?", "lunny").Find(&userMap))
+	assert.EqualValues(t, 2, len(userMap))
+}
@@ -0,0 +1,421 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisOptions configures a RedisCacher
+type RedisOptions struct {
+	Addr     string // host:port of the redis server
+	Password string
+	DB       int
+
+	// Prefix namespaces the keys, engines sharing a redis server should use
+	// different prefixes. Default is "xorm".
+	Prefix string
+	// Expired is the TTL of the cached ids and beans, default is
+	// DefaultRedisExpired
+	Expired time.Duration
+
+	MaxIdle      int // max idle connections in pool, default is 8
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// DefaultRedisExpired is the TTL of the ids and beans of a RedisCacher whose
+// Expired is not set. The keys always expire, as the keys of the cleared
+// generations are only removed by their TTL.
+const DefaultRedisExpired = 24 * time.Hour
+
+var _ TTLCacher = &RedisCacher{}
+
+// RedisCacher is a core.Cacher storing ids and beans in redis, so the cache
+// is shared by all the instances of an application. Clearing the ids or
+// beans of a table increases the table's generation stored in redis, the
+// keys of the old generation are left to expire.
+type RedisCacher struct {
+	opts  RedisOptions
+	pool  *redisPool
+	codec CacheCodec
+}
+
+// NewRedisCacher creates a redis cacher, connections are created lazily
+func NewRedisCacher(opts RedisOptions) *RedisCacher {
+	if opts.Prefix == "" {
+		opts.Prefix = "xorm"
+	}
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = 8
+	}
+	if opts.Expired <= 0 {
+		opts.Expired = DefaultRedisExpired
+	}
+	return &RedisCacher{
+		opts:  opts,
+		pool:  &redisPool{opts: &opts},
+		codec: GobCodec{},
+	}
+}
+
+// SetCodec set the codec serializing ids and beans, default is GobCodec
+func (c *RedisCacher) SetCodec(codec CacheCodec) {
+	c.codec = codec
+}
+
+// Close closes all the idle connections
+func (c *RedisCacher) Close() error {
+	return c.pool.close()
+}
+
+func (c *RedisCacher) genKey(kind, tableName string) string {
+	return fmt.Sprintf("%s:gen:%s:%s", c.opts.Prefix, kind, tableName)
+}
+
+// key returns the key of id in the current generation of the table
+func (c *RedisCacher) key(kind, tableName, id string) (string, error) {
+	prefix, err := c.keyPrefix(kind, tableName)
+	if err != nil {
+		return "", err
+	}
+	return prefix + id, nil
+}
+
+// keyPrefix returns the prefix of the keys of the current generation of the
+// table
+func (c *RedisCacher) keyPrefix(kind, tableName string) (string, error) {
+	reply, err := c.pool.do("GET", c.genKey(kind, tableName))
+	if err != nil {
+		return "", err
+	}
+	var gen = "0"
+	if reply != nil {
+		gen = string(reply.([]byte))
+	}
+	return fmt.Sprintf("%s:%s:%s:%s:", c.opts.Prefix, kind, tableName, gen), nil
+}
+
+func (c *RedisCacher) get(kind, tableName, id string) interface{} {
+	key, err := c.key(kind, tableName, id)
+	if err != nil {
+		return nil
+	}
+	reply, err := c.pool.do("GET", key)
+	if err != nil || reply == nil {
+		return nil
+	}
+	v, err := c.codec.Decode(reply.([]byte))
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+func (c *RedisCacher) put(kind, tableName, id string, v interface{}, ttl time.Duration) {
+	key, err := c.key(kind, tableName, id)
+	if err != nil {
+		return
+	}
+	data, err := c.codec.Encode(v)
+	if err != nil {
+		return
+	}
+	if ttl <= 0 {
+		ttl = c.opts.Expired
+	}
+	c.pool.do("SET", key, data, "PX", int64(ttl/time.Millisecond))
+}
+
+func (c *RedisCacher) del(kind, tableName, id string) {
+	key, err := c.key(kind, tableName, id)
+	if err != nil {
+		return
+	}
+	c.pool.do("DEL", key)
+}
+
+func (c *RedisCacher) clear(kind, tableName string) {
+	c.pool.do("INCR", c.genKey(kind, tableName))
+}
+
+func sqlHash(sql string) string {
+	sum := sha1.Sum([]byte(sql))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetIds implements core.Cacher
+func (c *RedisCacher) GetIds(tableName, sql string) interface{} {
+	return c.get("ids", tableName, sqlHash(sql))
+}
+
+// GetBean implements core.Cacher
+func (c *RedisCacher) GetBean(tableName string, id string) interface{} {
+	return c.get("bean", tableName, id)
+}
+
+// GetBeans implements MultiGetCacher by a MGET
+func (c *RedisCacher) GetBeans(tableName string, ids []string) []interface{} {
+	var beans = make([]interface{}, len(ids))
+	prefix, err := c.keyPrefix("bean", tableName)
+	if err != nil || len(ids) == 0 {
+		return beans
+	}
+	var args = make([]interface{}, 0, len(ids)+1)
+	args = append(args, "MGET")
+	for _, id := range ids {
+		args = append(args, prefix+id)
+	}
+	reply, err := c.pool.do(args...)
+	if err != nil {
+		return beans
+	}
+	replies, _ := reply.([]interface{})
+	for i, r := range replies {
+		if data, ok := r.([]byte); ok && i < len(beans) {
+			if v, err := c.codec.Decode(data); err == nil {
+				beans[i] = v
+			}
+		}
+	}
+	return beans
+}
+
+// PutIds implements core.Cacher
+func (c *RedisCacher) PutIds(tableName, sql string, ids interface{}) {
+	c.put("ids", tableName, sqlHash(sql), ids, c.opts.Expired)
+}
+
+// PutIdsTTL implements TTLCacher
+func (c *RedisCacher) PutIdsTTL(tableName, sql string, ids interface{}, ttl time.Duration) {
+	c.put("ids", tableName, sqlHash(sql), ids, ttl)
+}
+
+// PutBean implements core.Cacher
+func (c *RedisCacher) PutBean(tableName string, id string, obj interface{}) {
+	c.put("bean", tableName, id, obj, c.opts.Expired)
+}
+
+// PutBeanTTL implements TTLCacher
+func (c *RedisCacher) PutBeanTTL(tableName string, id string, obj interface{}, ttl time.Duration) {
+	c.put("bean", tableName, id, obj, ttl)
+}
+
+// DelIds implements core.Cacher
+func (c *RedisCacher) DelIds(tableName, sql string) {
+	c.del("ids", tableName, sqlHash(sql))
+}
+
+// DelBean implements core.Cacher
+func (c *RedisCacher) DelBean(tableName string, id string) {
+	c.del("bean", tableName, id)
+}
+
+// ClearIds implements core.Cacher
+func (c *RedisCacher) ClearIds(tableName string) {
+	c.clear("ids", tableName)
+}
+
+// ClearBeans implements core.Cacher
+func (c *RedisCacher) ClearBeans(tableName string) {
+	c.clear("bean", tableName)
+}
+
+// redisError is an error replied by the redis server
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// redisPool keeps the idle connections to a redis server
+type redisPool struct {
+	opts   *RedisOptions
+	mutex  sync.Mutex
+	idle   []*redisConn
+	closed bool
+}
+
+func (p *redisPool) get() (*redisConn, error) {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil, errors.New("redis pool is closed")
+	}
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mutex.Unlock()
+		return c, nil
+	}
+	p.mutex.Unlock()
+
+	conn, err := net.DialTimeout("tcp", p.opts.Addr, p.opts.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn, bufio.NewReader(conn), bufio.NewWriter(conn)}
+	if p.opts.Password != "" {
+		if _, err = p.exec(c, "AUTH", p.opts.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if p.opts.DB != 0 {
+		if _, err = p.exec(c, "SELECT", p.opts.DB); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (p *redisPool) put(c *redisConn) {
+	p.mutex.Lock()
+	if !p.closed && len(p.idle) < p.opts.MaxIdle {
+		p.idle = append(p.idle, c)
+		c = nil
+	}
+	p.mutex.Unlock()
+
+	if c != nil {
+		c.conn.Close()
+	}
+}
+
+func (p *redisPool) close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+	for _, c := range p.idle {
+		c.conn.Close()
+	}
+	p.idle = nil
+	return nil
+}
+
+// do executes a command on a pooled connection
+func (p *redisPool) do(args ...interface{}) (interface{}, error) {
+	c, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := p.exec(c, args...)
+	if _, ok := err.(redisError); err != nil && !ok {
+		// the connection is broken
+		c.conn.Close()
+		return nil, err
+	}
+	p.put(c)
+	return reply, err
+}
+
+func (p *redisPool) exec(c *redisConn, args ...interface{}) (interface{}, error) {
+	if p.opts.WriteTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(p.opts.WriteTimeout))
+	}
+	if err := writeRedisCommand(c.w, args...); err != nil {
+		return nil, err
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	if p.opts.ReadTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(p.opts.ReadTimeout))
+	}
+	return readRedisReply(c.r)
+}
+
+func writeRedisCommand(w *bufio.Writer, args ...interface{}) error {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var data []byte
+		switch v := arg.(type) {
+		case []byte:
+			data = v
+		case string:
+			data = []byte(v)
+		case int:
+			data = []byte(strconv.Itoa(v))
+		case int64:
+			data = []byte(strconv.FormatInt(v, 10))
+		default:
+			return fmt.Errorf("Unsupported redis argument type %T", arg)
+		}
+		fmt.Fprintf(w, "$%d\r\n", len(data))
+		w.Write(data)
+		w.WriteString("\r\n")
+	}
+	return nil
+}
+
+// readRedisReply reads a reply, bulk strings are returned as []byte,
+// integers as int64, arrays as []interface{} and nil replies as nil
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("malformed redis reply")
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err = io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		replies := make([]interface{}, n)
+		for i := 0; i < n; i++ {
+			if replies[i], err = readRedisReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return replies, nil
+	}
+	return nil, fmt.Errorf("unknown redis reply %q", line)
+}
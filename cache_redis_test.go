@@ -0,0 +1,290 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis is a minimal in memory redis server for the tests
+type fakeRedis struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	data     map[string][]byte
+	expires  map[string]time.Time
+	commands []string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	s := &fakeRedis{
+		listener: l,
+		data:     make(map[string][]byte),
+		expires:  make(map[string]time.Time),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRedis) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedis) Close() {
+	s.listener.Close()
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		reply, err := readRedisReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = string(item.([]byte))
+		}
+		if _, err = conn.Write([]byte(s.handle(args))); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeRedis) handle(args []string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(args) == 0 {
+		return "-ERR empty command\r\n"
+	}
+	s.commands = append(s.commands, strings.ToUpper(args[0]))
+	if exp, ok := s.expires[args[len(args)-1]]; ok && time.Now().After(exp) {
+		delete(s.data, args[len(args)-1])
+		delete(s.expires, args[len(args)-1])
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "AUTH", "SELECT", "PING":
+		return "+OK\r\n"
+	case "GET":
+		if exp, ok := s.expires[args[1]]; ok && time.Now().After(exp) {
+			delete(s.data, args[1])
+		}
+		v, ok := s.data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	case "MGET":
+		var reply = fmt.Sprintf("*%d\r\n", len(args)-1)
+		for _, key := range args[1:] {
+			if v, ok := s.data[key]; ok {
+				reply += fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			} else {
+				reply += "$-1\r\n"
+			}
+		}
+		return reply
+	case "SET":
+		s.data[args[1]] = []byte(args[2])
+		delete(s.expires, args[1])
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			s.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := s.data[args[1]]
+		delete(s.data, args[1])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "INCR":
+		n, _ := strconv.ParseInt(string(s.data[args[1]]), 10, 64)
+		n++
+		s.data[args[1]] = []byte(strconv.FormatInt(n, 10))
+		return fmt.Sprintf(":%d\r\n", n)
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func (s *fakeRedis) count(command string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var n int
+	for _, c := range s.commands {
+		if c == command {
+			n++
+		}
+	}
+	return n
+}
+
+type RedisCacheUser struct {
+	Id   int64
+	Name string
+}
+
+func TestRedisCacher(t *testing.T) {
+	server := newFakeRedis(t)
+	defer server.Close()
+
+	cacher := NewRedisCacher(RedisOptions{
+		Addr:     server.Addr(),
+		Password: "pass",
+		DB:       1,
+		Prefix:   "test",
+	})
+	defer cacher.Close()
+
+	assert.Nil(t, cacher.GetBean("user", "1"))
+
+	cacher.PutBean("user", "1", &RedisCacheUser{1, "lunny"})
+	bean := cacher.GetBean("user", "1")
+	assert.EqualValues(t, &RedisCacheUser{1, "lunny"}, bean)
+
+	var ids = []core.PK{{int64(1)}, {int64(2)}}
+	cacher.PutIds("user", "SELECT id FROM user", ids)
+	assert.EqualValues(t, ids, cacher.GetIds("user", "SELECT id FROM user"))
+	assert.Nil(t, cacher.GetIds("user", "SELECT id FROM user WHERE id=1"))
+
+	cacher.DelIds("user", "SELECT id FROM user")
+	assert.Nil(t, cacher.GetIds("user", "SELECT id FROM user"))
+
+	cacher.PutBean("user", "2", &RedisCacheUser{2, "xlw"})
+	assert.EqualValues(t, []interface{}{&RedisCacheUser{2, "xlw"}, nil, &RedisCacheUser{1, "lunny"}},
+		cacher.GetBeans("user", []string{"2", "3", "1"}))
+
+	cacher.DelBean("user", "1")
+	assert.Nil(t, cacher.GetBean("user", "1"))
+
+	// clearing a table doesn't affect the other tables
+	cacher.PutBean("user", "1", &RedisCacheUser{1, "lunny"})
+	cacher.PutBean("group", "1", &RedisCacheUser{1, "admin"})
+	cacher.ClearBeans("user")
+	assert.Nil(t, cacher.GetBean("user", "1"))
+	assert.NotNil(t, cacher.GetBean("group", "1"))
+
+	// the connections are reused
+	assert.EqualValues(t, 1, server.count("AUTH"))
+	assert.EqualValues(t, 1, server.count("SELECT"))
+}
+
+func TestRedisCacherNamespace(t *testing.T) {
+	server := newFakeRedis(t)
+	defer server.Close()
+
+	cacher1 := NewRedisCacher(RedisOptions{Addr: server.Addr(), Prefix: "engine1"})
+	defer cacher1.Close()
+	cacher2 := NewRedisCacher(RedisOptions{Addr: server.Addr(), Prefix: "engine2"})
+	defer cacher2.Close()
+
+	cacher1.PutBean("user", "1", &RedisCacheUser{1, "lunny"})
+	assert.NotNil(t, cacher1.GetBean("user", "1"))
+	assert.Nil(t, cacher2.GetBean("user", "1"))
+}
+
+func TestRedisCacherExpired(t *testing.T) {
+	server := newFakeRedis(t)
+	defer server.Close()
+
+	cacher := NewRedisCacher(RedisOptions{Addr: server.Addr(), Expired: 50 * time.Millisecond})
+	defer cacher.Close()
+
+	cacher.PutBean("user", "1", &RedisCacheUser{1, "lunny"})
+	assert.NotNil(t, cacher.GetBean("user", "1"))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, cacher.GetBean("user", "1"))
+}
+
+func TestRedisCacherDefaultExpired(t *testing.T) {
+	server := newFakeRedis(t)
+	defer server.Close()
+
+	cacher := NewRedisCacher(RedisOptions{Addr: server.Addr()})
+	defer cacher.Close()
+
+	// the keys of the cleared generations expire
+	cacher.PutBean("user", "1", &RedisCacheUser{1, "lunny"})
+	cacher.PutIdsTTL("user", "SELECT id FROM user", []core.PK{{int64(1)}}, 0)
+	cacher.ClearBeans("user")
+	cacher.ClearIds("user")
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	var keys int
+	for key := range server.data {
+		if strings.Contains(key, ":gen:") {
+			continue
+		}
+		keys++
+		exp, ok := server.expires[key]
+		assert.True(t, ok, key)
+		assert.True(t, exp.After(time.Now().Add(DefaultRedisExpired-time.Minute)), key)
+	}
+	assert.EqualValues(t, 2, keys)
+}
+
+func TestRedisCacherDown(t *testing.T) {
+	server := newFakeRedis(t)
+	cacher := NewRedisCacher(RedisOptions{Addr: server.Addr(), DialTimeout: time.Second})
+	defer cacher.Close()
+	server.Close()
+
+	// errors are cache misses
+	cacher.PutBean("user", "1", &RedisCacheUser{1, "lunny"})
+	assert.Nil(t, cacher.GetBean("user", "1"))
+}
+
+func TestRedisCacherGet(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	server := newFakeRedis(t)
+	defer server.Close()
+
+	cacher := NewRedisCacher(RedisOptions{Addr: server.Addr()})
+	defer cacher.Close()
+
+	assert.NoError(t, testEngine.Sync2(new(RedisCacheUser)))
+	testEngine.MapCacher(new(RedisCacheUser), cacher)
+	defer testEngine.MapCacher(new(RedisCacheUser), nil)
+
+	_, err := testEngine.Insert(&RedisCacheUser{Name: "lunny"})
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		var user RedisCacheUser
+		has, err := testEngine.Id(1).Get(&user)
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.EqualValues(t, "lunny", user.Name)
+	}
+	assert.True(t, server.count("SET") > 0)
+}
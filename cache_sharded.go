@@ -0,0 +1,410 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"container/list"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// default options of ShardedCacher
+const (
+	DefaultCacheShards   = 16
+	DefaultCacheMaxBytes = 64 * 1024 * 1024
+)
+
+// ShardedCacherOptions configures a ShardedCacher
+type ShardedCacherOptions struct {
+	// Shards is the number of shards, it's rounded up to a power of 2.
+	// Default is DefaultCacheShards.
+	Shards int
+	// MaxBytes is the max estimated size of all the cached values. Default
+	// is DefaultCacheMaxBytes.
+	MaxBytes int64
+	// Expired is the TTL of the cached ids and beans, 0 means no TTL
+	Expired time.Duration
+	// Sizer returns the size of a cached value, the default one estimates
+	// the size by reflection
+	Sizer func(v interface{}) int64
+}
+
+var _ TTLCacher = &ShardedCacher{}
+
+// ShardedCacher is an in process cacher for hot lookups at high QPS. The keys
+// are distributed to shards which have their own lock and LRU list, and the
+// cached values are bounded by their size instead of their number.
+// When a shard is full, a new value is only admitted when it's accessed
+// more often than the value it would evict, so one-off queries don't evict
+// the hot rows.
+type ShardedCacher struct {
+	shards  []*cacheShard
+	mask    uint32
+	expired time.Duration
+	sizer   func(v interface{}) int64
+
+	genMutex sync.RWMutex
+	gens     map[string]uint64
+}
+
+// NewShardedCacher creates a sharded cacher
+func NewShardedCacher(opts ShardedCacherOptions) *ShardedCacher {
+	var n = 1
+	for n < opts.Shards {
+		n <<= 1
+	}
+	if opts.Shards <= 0 {
+		n = DefaultCacheShards
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultCacheMaxBytes
+	}
+	if opts.Sizer == nil {
+		opts.Sizer = estimateSize
+	}
+
+	c := &ShardedCacher{
+		shards:  make([]*cacheShard, n),
+		mask:    uint32(n - 1),
+		expired: opts.Expired,
+		sizer:   opts.Sizer,
+		gens:    make(map[string]uint64),
+	}
+	for i := range c.shards {
+		c.shards[i] = newCacheShard(opts.MaxBytes / int64(n))
+	}
+	return c
+}
+
+// Len returns the number of the cached ids and beans
+func (c *ShardedCacher) Len() int {
+	var n int
+	for _, shard := range c.shards {
+		shard.mutex.Lock()
+		n += len(shard.items)
+		shard.mutex.Unlock()
+	}
+	return n
+}
+
+// Size returns the estimated size of the cached ids and beans
+func (c *ShardedCacher) Size() int64 {
+	var n int64
+	for _, shard := range c.shards {
+		shard.mutex.Lock()
+		n += shard.size
+		shard.mutex.Unlock()
+	}
+	return n
+}
+
+// SetEvictHandler implements EvictNotifier
+func (c *ShardedCacher) SetEvictHandler(handler func(tableName string)) {
+	for _, shard := range c.shards {
+		shard.mutex.Lock()
+		shard.onEvict = handler
+		shard.mutex.Unlock()
+	}
+}
+
+// key returns the key of id in the current generation of the table, the
+// generation is increased when the table is cleared
+func (c *ShardedCacher) key(kind, tableName, id string) string {
+	c.genMutex.RLock()
+	gen := c.gens[kind+tableName]
+	c.genMutex.RUnlock()
+	return kind + ":" + tableName + ":" + strconv.FormatUint(gen, 10) + ":" + id
+}
+
+func (c *ShardedCacher) shard(key string) *cacheShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return c.shards[h.Sum32()&c.mask]
+}
+
+func (c *ShardedCacher) get(kind, tableName, id string) interface{} {
+	key := c.key(kind, tableName, id)
+	return c.shard(key).get(key)
+}
+
+func (c *ShardedCacher) put(kind, tableName, id string, v interface{}, ttl time.Duration) {
+	key := c.key(kind, tableName, id)
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+	c.shard(key).put(key, tableName, v, int64(len(key))+c.sizer(v), expireAt)
+}
+
+func (c *ShardedCacher) del(kind, tableName, id string) {
+	key := c.key(kind, tableName, id)
+	c.shard(key).del(key)
+}
+
+func (c *ShardedCacher) clear(kind, tableName string) {
+	c.genMutex.Lock()
+	c.gens[kind+tableName]++
+	c.genMutex.Unlock()
+}
+
+// GetIds implements core.Cacher
+func (c *ShardedCacher) GetIds(tableName, sql string) interface{} {
+	return c.get("ids", tableName, sql)
+}
+
+// GetBean implements core.Cacher
+func (c *ShardedCacher) GetBean(tableName string, id string) interface{} {
+	return c.get("bean", tableName, id)
+}
+
+// PutIds implements core.Cacher
+func (c *ShardedCacher) PutIds(tableName, sql string, ids interface{}) {
+	c.put("ids", tableName, sql, ids, c.expired)
+}
+
+// PutIdsTTL implements TTLCacher
+func (c *ShardedCacher) PutIdsTTL(tableName, sql string, ids interface{}, ttl time.Duration) {
+	c.put("ids", tableName, sql, ids, ttl)
+}
+
+// PutBean implements core.Cacher
+func (c *ShardedCacher) PutBean(tableName string, id string, obj interface{}) {
+	c.put("bean", tableName, id, obj, c.expired)
+}
+
+// PutBeanTTL implements TTLCacher
+func (c *ShardedCacher) PutBeanTTL(tableName string, id string, obj interface{}, ttl time.Duration) {
+	c.put("bean", tableName, id, obj, ttl)
+}
+
+// DelIds implements core.Cacher
+func (c *ShardedCacher) DelIds(tableName, sql string) {
+	c.del("ids", tableName, sql)
+}
+
+// DelBean implements core.Cacher
+func (c *ShardedCacher) DelBean(tableName string, id string) {
+	c.del("bean", tableName, id)
+}
+
+// ClearIds implements core.Cacher, the ids of the old generation are left
+// to be evicted
+func (c *ShardedCacher) ClearIds(tableName string) {
+	c.clear("ids", tableName)
+}
+
+// ClearBeans implements core.Cacher, the beans of the old generation are
+// left to be evicted
+func (c *ShardedCacher) ClearBeans(tableName string) {
+	c.clear("bean", tableName)
+}
+
+type cacheItem struct {
+	key      string
+	table    string
+	value    interface{}
+	size     int64
+	expireAt time.Time
+}
+
+type cacheShard struct {
+	mutex    sync.Mutex
+	items    map[string]*list.Element
+	lru      *list.List
+	size     int64
+	maxBytes int64
+	sketch   *cacheSketch
+	onEvict  func(tableName string)
+}
+
+func newCacheShard(maxBytes int64) *cacheShard {
+	return &cacheShard{
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+		maxBytes: maxBytes,
+		sketch:   newCacheSketch(1024),
+	}
+}
+
+func (s *cacheShard) get(key string) interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sketch.increment(key)
+	e, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+	item := e.Value.(*cacheItem)
+	if !item.expireAt.IsZero() && time.Now().After(item.expireAt) {
+		s.remove(e)
+		return nil
+	}
+	s.lru.MoveToFront(e)
+	return item.value
+}
+
+func (s *cacheShard) put(key, tableName string, v interface{}, size int64, expireAt time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sketch.increment(key)
+	if e, ok := s.items[key]; ok {
+		s.remove(e)
+	}
+	if size > s.maxBytes {
+		return
+	}
+
+	// admission, the new value should be more frequent than the victim
+	if s.size+size > s.maxBytes {
+		if back := s.lru.Back(); back != nil {
+			victim := back.Value.(*cacheItem)
+			if s.sketch.estimate(key) < s.sketch.estimate(victim.key) {
+				return
+			}
+		}
+	}
+	for s.size+size > s.maxBytes {
+		victim := s.remove(s.lru.Back())
+		if s.onEvict != nil {
+			s.onEvict(victim.table)
+		}
+	}
+
+	s.items[key] = s.lru.PushFront(&cacheItem{key, tableName, v, size, expireAt})
+	s.size += size
+}
+
+func (s *cacheShard) del(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if e, ok := s.items[key]; ok {
+		s.remove(e)
+	}
+}
+
+func (s *cacheShard) remove(e *list.Element) *cacheItem {
+	item := s.lru.Remove(e).(*cacheItem)
+	delete(s.items, item.key)
+	s.size -= item.size
+	return item
+}
+
+// cacheSketch is a count-min sketch estimating the access frequencies of
+// the keys, the counters are halved periodically so old accesses fade
+type cacheSketch struct {
+	rows      [4][]uint8
+	mask      uint32
+	additions int
+	resetAt   int
+}
+
+func newCacheSketch(width int) *cacheSketch {
+	s := &cacheSketch{mask: uint32(width - 1), resetAt: width * 10}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *cacheSketch) indexes(key string) [4]uint32 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := uint32(sum), uint32(sum>>32)
+
+	var idx [4]uint32
+	for i := range idx {
+		idx[i] = (h1 + uint32(i)*h2) & s.mask
+	}
+	return idx
+}
+
+func (s *cacheSketch) increment(key string) {
+	for i, idx := range s.indexes(key) {
+		if s.rows[i][idx] < 255 {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.resetAt {
+		for i := range s.rows {
+			for j := range s.rows[i] {
+				s.rows[i][j] >>= 1
+			}
+		}
+		s.additions /= 2
+	}
+}
+
+func (s *cacheSketch) estimate(key string) uint8 {
+	var min uint8 = 255
+	for i, idx := range s.indexes(key) {
+		if s.rows[i][idx] < min {
+			min = s.rows[i][idx]
+		}
+	}
+	return min
+}
+
+// estimateSize estimates the memory used by v
+func estimateSize(v interface{}) int64 {
+	return estimateValueSize(reflect.ValueOf(v), 0)
+}
+
+func estimateValueSize(v reflect.Value, depth int) int64 {
+	if !v.IsValid() {
+		return 0
+	}
+	if depth > 8 {
+		return int64(v.Type().Size())
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return int64(v.Type().Size())
+		}
+		return int64(v.Type().Size()) + estimateValueSize(v.Elem(), depth+1)
+	case reflect.String:
+		return int64(v.Type().Size()) + int64(v.Len())
+	case reflect.Slice:
+		size := int64(v.Type().Size())
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return size + int64(v.Cap())
+		}
+		for i := 0; i < v.Len(); i++ {
+			size += estimateValueSize(v.Index(i), depth+1)
+		}
+		return size
+	case reflect.Array:
+		var size int64
+		for i := 0; i < v.Len(); i++ {
+			size += estimateValueSize(v.Index(i), depth+1)
+		}
+		return size
+	case reflect.Map:
+		size := int64(v.Type().Size())
+		for _, k := range v.MapKeys() {
+			size += estimateValueSize(k, depth+1) + estimateValueSize(v.MapIndex(k), depth+1)
+		}
+		return size
+	case reflect.Struct:
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += estimateValueSize(v.Field(i), depth+1)
+		}
+		if size < int64(v.Type().Size()) {
+			size = int64(v.Type().Size())
+		}
+		return size
+	}
+	return int64(v.Type().Size())
+}
@@ -0,0 +1,154 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type ShardedCacheUser struct {
+	Id   int64
+	Name string
+}
+
+func TestShardedCacher(t *testing.T) {
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	assert.EqualValues(t, DefaultCacheShards, len(cacher.shards))
+
+	assert.Nil(t, cacher.GetBean("user", "1"))
+
+	var user = &ShardedCacheUser{1, "lunny"}
+	cacher.PutBean("user", "1", user)
+	assert.True(t, user == cacher.GetBean("user", "1"))
+
+	var ids = []core.PK{{int64(1)}}
+	cacher.PutIds("user", "SELECT id FROM user", ids)
+	assert.EqualValues(t, ids, cacher.GetIds("user", "SELECT id FROM user"))
+	assert.EqualValues(t, 2, cacher.Len())
+	assert.True(t, cacher.Size() > 0)
+
+	cacher.DelIds("user", "SELECT id FROM user")
+	assert.Nil(t, cacher.GetIds("user", "SELECT id FROM user"))
+
+	cacher.DelBean("user", "1")
+	assert.Nil(t, cacher.GetBean("user", "1"))
+	assert.EqualValues(t, 0, cacher.Len())
+	assert.EqualValues(t, 0, cacher.Size())
+
+	cacher.PutBean("user", "1", user)
+	cacher.PutBean("group", "1", user)
+	cacher.ClearBeans("user")
+	assert.Nil(t, cacher.GetBean("user", "1"))
+	assert.NotNil(t, cacher.GetBean("group", "1"))
+}
+
+func TestShardedCacherMaxBytes(t *testing.T) {
+	cacher := NewShardedCacher(ShardedCacherOptions{
+		Shards:   1,
+		MaxBytes: 1000,
+		Sizer:    func(interface{}) int64 { return 100 },
+	})
+
+	for i := 0; i < 20; i++ {
+		cacher.PutBean("user", strconv.Itoa(i), &ShardedCacheUser{Id: int64(i)})
+		assert.True(t, cacher.Size() <= 1000)
+	}
+	assert.True(t, cacher.Len() < 20)
+
+	// too large to be cached
+	cacher = NewShardedCacher(ShardedCacherOptions{Shards: 1, MaxBytes: 10})
+	cacher.PutBean("user", "1", &ShardedCacheUser{1, "lunny"})
+	assert.Nil(t, cacher.GetBean("user", "1"))
+}
+
+func TestShardedCacherAdmission(t *testing.T) {
+	cacher := NewShardedCacher(ShardedCacherOptions{
+		Shards:   1,
+		MaxBytes: 1000,
+		Sizer:    func(interface{}) int64 { return 100 },
+	})
+
+	// hot beans which are accessed many times
+	for i := 0; i < 5; i++ {
+		cacher.PutBean("user", strconv.Itoa(i), &ShardedCacheUser{Id: int64(i)})
+		for n := 0; n < 10; n++ {
+			cacher.GetBean("user", strconv.Itoa(i))
+		}
+	}
+
+	// a scan of one-off beans doesn't evict the hot ones
+	for i := 100; i < 200; i++ {
+		cacher.PutBean("user", strconv.Itoa(i), &ShardedCacheUser{Id: int64(i)})
+	}
+	for i := 0; i < 5; i++ {
+		assert.NotNil(t, cacher.GetBean("user", strconv.Itoa(i)))
+	}
+}
+
+func TestShardedCacherExpired(t *testing.T) {
+	cacher := NewShardedCacher(ShardedCacherOptions{Expired: 50 * time.Millisecond})
+
+	cacher.PutBean("user", "1", &ShardedCacheUser{1, "lunny"})
+	assert.NotNil(t, cacher.GetBean("user", "1"))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, cacher.GetBean("user", "1"))
+	assert.EqualValues(t, 0, cacher.Len())
+}
+
+func TestShardedCacherConcurrent(t *testing.T) {
+	cacher := NewShardedCacher(ShardedCacherOptions{MaxBytes: 64 * 1024})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				id := strconv.Itoa(i % 100)
+				cacher.PutBean("user", id, &ShardedCacheUser{Id: int64(i)})
+				cacher.GetBean("user", id)
+				if i%100 == 0 {
+					cacher.ClearBeans("user")
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.True(t, cacher.Size() <= 64*1024)
+}
+
+func TestEstimateSize(t *testing.T) {
+	assert.True(t, estimateSize(&ShardedCacheUser{1, "lunny"}) > estimateSize(&ShardedCacheUser{1, ""}))
+	assert.True(t, estimateSize([]core.PK{{int64(1)}, {int64(2)}}) > estimateSize([]core.PK{{int64(1)}}))
+	assert.EqualValues(t, 0, estimateSize(nil))
+}
+
+func TestShardedCacherGet(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	assert.NoError(t, testEngine.Sync2(new(ShardedCacheUser)))
+	testEngine.MapCacher(new(ShardedCacheUser), cacher)
+	defer testEngine.MapCacher(new(ShardedCacheUser), nil)
+
+	_, err := testEngine.Insert(&ShardedCacheUser{Name: "lunny"})
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		var user ShardedCacheUser
+		has, err := testEngine.Id(1).Get(&user)
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.EqualValues(t, "lunny", user.Name)
+	}
+	assert.True(t, cacher.Len() > 0)
+}
@@ -0,0 +1,170 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// CacheEventKind is the kind of a CacheEvent
+type CacheEventKind int
+
+// the kinds of CacheEvent
+const (
+	CacheHit CacheEventKind = iota
+	CacheMiss
+	CacheEvict
+	CacheLoad
+)
+
+// CacheEvent is an access to the cache of a table. Duration is the time
+// spent loading the missed ids or bean from the database for a CacheLoad.
+type CacheEvent struct {
+	Table    string
+	Kind     CacheEventKind
+	Duration time.Duration
+}
+
+// CacheStats is the statistics of the cache of a table
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64 // only reported by the cachers implementing EvictNotifier
+	Loads     int64
+	LoadTime  time.Duration // the total time of the loads
+}
+
+// HitRatio returns the ratio of the lookups which hit the cache
+func (stats CacheStats) HitRatio() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+// EvictNotifier is implemented by the cachers which report the ids and
+// beans evicted to make room for new ones. The handler is called with the
+// cacher locked, so it should be fast.
+type EvictNotifier interface {
+	SetEvictHandler(handler func(tableName string))
+}
+
+var (
+	_ EvictNotifier = &ShardedCacher{}
+	_ EvictNotifier = &LRUCacher{}
+)
+
+type cacheCounters struct {
+	hits      int64
+	misses    int64
+	evictions int64
+	loads     int64
+	loadTime  int64
+}
+
+type cacheMonitor struct {
+	mutex    sync.RWMutex
+	tables   map[string]*cacheCounters
+	observer func(CacheEvent)
+}
+
+func (m *cacheMonitor) counters(tableName string) *cacheCounters {
+	m.mutex.RLock()
+	c, ok := m.tables[tableName]
+	m.mutex.RUnlock()
+	if ok {
+		return c
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.tables == nil {
+		m.tables = make(map[string]*cacheCounters)
+	}
+	if c, ok = m.tables[tableName]; !ok {
+		c = new(cacheCounters)
+		m.tables[tableName] = c
+	}
+	return c
+}
+
+func (m *cacheMonitor) record(tableName string, kind CacheEventKind, d time.Duration) {
+	c := m.counters(tableName)
+	switch kind {
+	case CacheHit:
+		atomic.AddInt64(&c.hits, 1)
+	case CacheMiss:
+		atomic.AddInt64(&c.misses, 1)
+	case CacheEvict:
+		atomic.AddInt64(&c.evictions, 1)
+	case CacheLoad:
+		atomic.AddInt64(&c.loads, 1)
+		atomic.AddInt64(&c.loadTime, int64(d))
+	}
+
+	m.mutex.RLock()
+	observer := m.observer
+	m.mutex.RUnlock()
+	if observer != nil {
+		observer(CacheEvent{tableName, kind, d})
+	}
+}
+
+// lookup records a hit or a miss
+func (m *cacheMonitor) lookup(tableName string, hit bool) {
+	if hit {
+		m.record(tableName, CacheHit, 0)
+	} else {
+		m.record(tableName, CacheMiss, 0)
+	}
+}
+
+// loaded records a load started at start
+func (m *cacheMonitor) loaded(tableName string, start time.Time) {
+	m.record(tableName, CacheLoad, time.Since(start))
+}
+
+func (m *cacheMonitor) evicted(tableName string) {
+	m.record(tableName, CacheEvict, 0)
+}
+
+// watch reports the evictions of cacher to the monitor
+func (m *cacheMonitor) watch(cacher core.Cacher) {
+	if n, ok := cacher.(EvictNotifier); ok {
+		n.SetEvictHandler(m.evicted)
+	}
+}
+
+// CacheStats returns the statistics of the cache by table, the results of
+// the query cache are under the table xorm_query_cache
+func (engine *Engine) CacheStats() map[string]CacheStats {
+	m := engine.cacheMonitor
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var stats = make(map[string]CacheStats, len(m.tables))
+	for tableName, c := range m.tables {
+		stats[tableName] = CacheStats{
+			Hits:      atomic.LoadInt64(&c.hits),
+			Misses:    atomic.LoadInt64(&c.misses),
+			Evictions: atomic.LoadInt64(&c.evictions),
+			Loads:     atomic.LoadInt64(&c.loads),
+			LoadTime:  time.Duration(atomic.LoadInt64(&c.loadTime)),
+		}
+	}
+	return stats
+}
+
+// SetCacheObserver sets a function called on every cache event, e.g. to
+// export the statistics to a metrics system, nil removes it
+func (engine *Engine) SetCacheObserver(observer func(CacheEvent)) {
+	engine.cacheMonitor.mutex.Lock()
+	engine.cacheMonitor.observer = observer
+	engine.cacheMonitor.mutex.Unlock()
+}
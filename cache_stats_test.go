@@ -0,0 +1,77 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type StatsCacheUser struct {
+	Id   int64
+	Name string
+}
+
+func TestCacheStats(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(StatsCacheUser)))
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.MapCacher(new(StatsCacheUser), cacher)
+	defer testEngine.MapCacher(new(StatsCacheUser), nil)
+
+	var mutex sync.Mutex
+	var events []CacheEvent
+	testEngine.SetCacheObserver(func(event CacheEvent) {
+		mutex.Lock()
+		events = append(events, event)
+		mutex.Unlock()
+	})
+	defer testEngine.SetCacheObserver(nil)
+
+	_, err := testEngine.Insert(&StatsCacheUser{Name: "lunny"})
+	assert.NoError(t, err)
+
+	tableName := testEngine.TableMapper.Obj2Table("StatsCacheUser")
+	before := testEngine.CacheStats()[tableName]
+
+	for i := 0; i < 2; i++ {
+		var user StatsCacheUser
+		has, err := testEngine.Id(1).Get(&user)
+		assert.NoError(t, err)
+		assert.True(t, has)
+	}
+
+	stats := testEngine.CacheStats()[tableName]
+	// the ids and the bean miss the first time and hit the second time
+	assert.EqualValues(t, 2, stats.Misses-before.Misses)
+	assert.EqualValues(t, 2, stats.Hits-before.Hits)
+	assert.EqualValues(t, 2, stats.Loads-before.Loads)
+	assert.True(t, stats.HitRatio() > 0)
+
+	mutex.Lock()
+	assert.EqualValues(t, 6, len(events))
+	mutex.Unlock()
+}
+
+func TestShardedCacherEvictHandler(t *testing.T) {
+	cacher := NewShardedCacher(ShardedCacherOptions{
+		Shards:   1,
+		MaxBytes: 60,
+		Sizer:    func(interface{}) int64 { return 30 },
+	})
+	var evicted []string
+	cacher.SetEvictHandler(func(tableName string) {
+		evicted = append(evicted, tableName)
+	})
+
+	cacher.PutBean("user", "1", 1)
+	cacher.GetBean("user", "2")
+	cacher.GetBean("user", "2")
+	cacher.PutBean("user", "2", 2)
+	assert.EqualValues(t, []string{"user"}, evicted)
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"reflect"
+
+	"github.com/go-xorm/builder"
+)
+
+// WarmCache loads the rows of the table of bean matching conds into the
+// cacher of the table, so the first reads after a start or a deploy don't
+// all miss the cache. As Rows, the non-empty fields of bean are conditions
+// too. It returns the number of cached beans, nothing is loaded when the
+// table has no cacher.
+func (session *Session) WarmCache(bean interface{}, conds ...builder.Cond) (int, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
+	if err := session.Statement.setRefValue(rValue(bean)); err != nil {
+		return 0, err
+	}
+	table := session.Statement.RefTable
+	tableName := session.Statement.TableName()
+	cacher := session.Engine.getCacher2(table)
+	if cacher == nil {
+		session.resetStatement()
+		return 0, nil
+	}
+
+	for _, cond := range conds {
+		session.Statement.cond = session.Statement.cond.And(cond)
+	}
+	session.Statement.UseCache = false
+	rows, err := session.Rows(bean)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	beanType := reflect.Indirect(reflect.ValueOf(bean)).Type()
+	var n int
+	for rows.Next() {
+		b := reflect.New(beanType)
+		if err := rows.Scan(b.Interface()); err != nil {
+			return n, err
+		}
+		pk, err := session.Engine.idOfV(b)
+		if err != nil {
+			return n, err
+		}
+		pk, ok := cachePK(table, pk)
+		if !ok {
+			return n, ErrCacheFailed
+		}
+		sid, err := pk.ToString()
+		if err != nil {
+			return n, err
+		}
+
+		session.getLogger().Debug("[WarmCache] cache bean:", tableName, pk, b.Interface())
+		cacher.PutBean(tableName, sid, b.Interface())
+		n++
+	}
+	// the Rows end with sql.ErrNoRows
+	if err := rows.Err(); err != nil && err != sql.ErrNoRows {
+		return n, err
+	}
+	return n, nil
+}
+
+// WarmCache loads the rows of the table of bean matching conds into the
+// cacher of the table, see Session.WarmCache
+func (engine *Engine) WarmCache(bean interface{}, conds ...builder.Cond) (int, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.WarmCache(bean, conds...)
+}
@@ -0,0 +1,49 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type WarmCacheUser struct {
+	Id   int64
+	Name string
+}
+
+func TestWarmCache(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(WarmCacheUser)))
+
+	for _, name := range []string{"lunny", "xlw", "xiangxiao"} {
+		_, err := testEngine.Insert(&WarmCacheUser{Name: name})
+		assert.NoError(t, err)
+	}
+
+	// no cacher
+	n, err := testEngine.WarmCache(new(WarmCacheUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.MapCacher(new(WarmCacheUser), cacher)
+	defer testEngine.MapCacher(new(WarmCacheUser), nil)
+
+	n, err = testEngine.WarmCache(new(WarmCacheUser), builder.Neq{"name": "xlw"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	tableName := testEngine.TableMapper.Obj2Table("WarmCacheUser")
+	for id, cached := range map[int64]bool{1: true, 2: false, 3: true} {
+		pk := core.PK{id}
+		sid, err := pk.ToString()
+		assert.NoError(t, err)
+		assert.EqualValues(t, cached, cacher.GetBean(tableName, sid) != nil, id)
+	}
+}
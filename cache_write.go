@@ -0,0 +1,209 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// CacheWriteMode is how the writes update the cached beans
+type CacheWriteMode int
+
+// the cache write modes
+const (
+	// CacheInvalidate clears the cached beans of the written tables
+	CacheInvalidate CacheWriteMode = iota
+	// CacheWriteThrough reloads the inserted bean, or the bean updated by
+	// Id, into the cache once the write is committed, the others beans of
+	// the table stay cached. The other writes are invalidated.
+	CacheWriteThrough
+)
+
+// SetCacheWriteMode set how the writes update the cached beans, default is
+// CacheInvalidate
+func (engine *Engine) SetCacheWriteMode(mode CacheWriteMode) {
+	engine.cacheWriteMode = mode
+}
+
+// cachePK converts the values of pk to the types of the ids cached by Get
+// and Find, so they have the same key
+func cachePK(table *core.Table, pk core.PK) (core.PK, bool) {
+	if len(pk) == 0 || len(pk) != len(table.PrimaryKeys) {
+		return nil, false
+	}
+
+	var res = make(core.PK, len(pk))
+	for i, col := range table.PKColumns() {
+		v := reflect.Indirect(reflect.ValueOf(pk[i]))
+		switch {
+		case col.SQLType.IsNumeric():
+			switch v.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				res[i] = v.Int()
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				res[i] = int64(v.Uint())
+			default:
+				return nil, false
+			}
+		case col.SQLType.IsText() && v.Kind() == reflect.String:
+			res[i] = v.String()
+		default:
+			return nil, false
+		}
+	}
+	return res, true
+}
+
+// writeThrough reloads the row of pk into the cache after the write is
+// committed, it returns false when the cache should be cleared instead
+func (session *Session) writeThrough(table *core.Table, pk core.PK) bool {
+	if session.Engine.cacheWriteMode != CacheWriteThrough || table == nil {
+		return false
+	}
+	pk, ok := cachePK(table, pk)
+	if !ok {
+		return false
+	}
+	sid, err := pk.ToString()
+	if err != nil {
+		return false
+	}
+
+	engine := session.Engine
+	cacher := engine.getCacher2(table)
+	tableName := session.Statement.TableName()
+	cacher.ClearIds(tableName)
+	cacher.DelBean(tableName, sid)
+	session.OnCommit(func() {
+		bean := reflect.New(table.Type).Interface()
+		has, err := engine.NoCache().Table(tableName).Id(pk).Get(bean)
+		if err != nil {
+			engine.logger.Error("[writeThrough]", tableName, pk, err)
+			return
+		}
+		if has {
+			engine.logger.Debug("[writeThrough] cache bean:", tableName, pk, bean)
+			cacher.PutBean(tableName, sid, bean)
+		}
+	})
+	return true
+}
+
+// CounterBuffer buffers the increments of a counter column and writes them
+// in batches, one UPDATE per row, so a hot counter doesn't write the
+// database on every hit. The increments not flushed yet are lost when the
+// process exits without calling Close.
+type CounterBuffer struct {
+	engine *Engine
+	bean   interface{}
+	column string
+
+	mutex   sync.Mutex
+	pending map[string]*counterDelta
+	stop    chan bool
+	done    chan bool
+}
+
+type counterDelta struct {
+	id    interface{}
+	delta int64
+}
+
+// NewCounterBuffer creates a buffer of the increments of column in the table
+// of bean, flushed every interval when interval is positive
+func (engine *Engine) NewCounterBuffer(bean interface{}, column string, interval time.Duration) *CounterBuffer {
+	b := &CounterBuffer{
+		engine:  engine,
+		bean:    bean,
+		column:  column,
+		pending: make(map[string]*counterDelta),
+	}
+	if interval > 0 {
+		b.stop = make(chan bool)
+		b.done = make(chan bool)
+		go func() {
+			defer close(b.done)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := b.Flush(); err != nil {
+						engine.logger.Error("[CounterBuffer]", err)
+					}
+				case <-b.stop:
+					return
+				}
+			}
+		}()
+	}
+	return b
+}
+
+// Incr adds delta to the counter of the row of id
+func (b *CounterBuffer) Incr(id interface{}, delta int64) {
+	key := fmt.Sprintf("%#v", id)
+	b.mutex.Lock()
+	if d, ok := b.pending[key]; ok {
+		d.delta += delta
+	} else {
+		b.pending[key] = &counterDelta{id, delta}
+	}
+	b.mutex.Unlock()
+}
+
+// Flush writes the buffered increments in one transaction, they are
+// buffered again when it fails
+func (b *CounterBuffer) Flush() error {
+	b.mutex.Lock()
+	pending := b.pending
+	b.pending = make(map[string]*counterDelta)
+	b.mutex.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	session := b.engine.NewSession()
+	defer session.Close()
+
+	err := session.Begin()
+	if err == nil {
+		beanType := reflect.Indirect(reflect.ValueOf(b.bean)).Type()
+		for _, d := range pending {
+			if d.delta == 0 {
+				continue
+			}
+			bean := reflect.New(beanType).Interface()
+			if _, err = session.Id(d.id).Incr(b.column, d.delta).Update(bean); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = session.Commit()
+		}
+	}
+	if err != nil {
+		session.Rollback()
+		for _, d := range pending {
+			b.Incr(d.id, d.delta)
+		}
+	}
+	return err
+}
+
+// Close stops the periodic flushes and flushes the buffered increments
+func (b *CounterBuffer) Close() error {
+	if b.stop != nil {
+		close(b.stop)
+		<-b.done
+		b.stop = nil
+	}
+	return b.Flush()
+}
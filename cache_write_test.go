@@ -0,0 +1,86 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type WriteThroughUser struct {
+	Id    int64
+	Name  string
+	Views int
+}
+
+func TestCacheWriteThrough(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(WriteThroughUser)))
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.MapCacher(new(WriteThroughUser), cacher)
+	testEngine.SetCacheWriteMode(CacheWriteThrough)
+	defer func() {
+		testEngine.SetCacheWriteMode(CacheInvalidate)
+		testEngine.MapCacher(new(WriteThroughUser), nil)
+	}()
+
+	tableName := testEngine.TableMapper.Obj2Table("WriteThroughUser")
+	pk := core.PK{int64(1)}
+	sid, err := pk.ToString()
+	assert.NoError(t, err)
+
+	_, err = testEngine.Insert(&WriteThroughUser{Name: "lunny"})
+	assert.NoError(t, err)
+	bean, ok := cacher.GetBean(tableName, sid).(*WriteThroughUser)
+	assert.True(t, ok)
+	assert.EqualValues(t, "lunny", bean.Name)
+
+	_, err = testEngine.Id(1).Update(&WriteThroughUser{Name: "xlw"})
+	assert.NoError(t, err)
+	bean, ok = cacher.GetBean(tableName, sid).(*WriteThroughUser)
+	assert.True(t, ok)
+	assert.EqualValues(t, "xlw", bean.Name)
+
+	// the bean is cached once the transaction is committed
+	sess := testEngine.NewSession()
+	defer sess.Close()
+	assert.NoError(t, sess.Begin())
+	_, err = sess.Id(1).Update(&WriteThroughUser{Name: "lunny"})
+	assert.NoError(t, err)
+	assert.Nil(t, cacher.GetBean(tableName, sid))
+	assert.NoError(t, sess.Commit())
+	bean, ok = cacher.GetBean(tableName, sid).(*WriteThroughUser)
+	assert.True(t, ok)
+	assert.EqualValues(t, "lunny", bean.Name)
+}
+
+func TestCounterBuffer(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(WriteThroughUser)))
+
+	_, err := testEngine.Insert(&WriteThroughUser{Name: "lunny"})
+	assert.NoError(t, err)
+
+	counter := testEngine.NewCounterBuffer(new(WriteThroughUser), "views", 0)
+	for i := 0; i < 10; i++ {
+		counter.Incr(int64(1), 1)
+	}
+
+	var user WriteThroughUser
+	has, err := testEngine.Id(1).Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 0, user.Views)
+
+	assert.NoError(t, counter.Close())
+	user = WriteThroughUser{}
+	has, err = testEngine.Id(1).Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 10, user.Views)
+}
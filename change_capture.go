@@ -0,0 +1,216 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// ChangeOperation is the kind of write of a ChangeEvent
+type ChangeOperation int
+
+// the operations of the changes
+const (
+	ChangeInsert ChangeOperation = iota
+	ChangeUpdate
+	ChangeDelete
+)
+
+func (op ChangeOperation) String() string {
+	switch op {
+	case ChangeInsert:
+		return "insert"
+	case ChangeUpdate:
+		return "update"
+	}
+	return "delete"
+}
+
+// ChangeEvent is a row written by Insert, Update or Delete
+type ChangeEvent struct {
+	Table string
+	Op    ChangeOperation
+	// PK is nil when the written rows are not known, as the writes of the
+	// tables without primary key
+	PK core.PK
+	// Before is the values of the columns before an update or a delete
+	Before map[string]interface{}
+	// After is the values of the columns after an insert or an update
+	After map[string]interface{}
+	// Time is when the change is committed
+	Time time.Time
+}
+
+// ChangeConsumer receives the change events of an engine. The events of a
+// transaction are consumed after the commit, in the order of the writes.
+type ChangeConsumer interface {
+	Consume(event ChangeEvent)
+}
+
+// ChangeConsumerFunc is a ChangeConsumer as a function
+type ChangeConsumerFunc func(event ChangeEvent)
+
+// Consume implements ChangeConsumer
+func (f ChangeConsumerFunc) Consume(event ChangeEvent) {
+	f(event)
+}
+
+// ChangeChannel returns a consumer sending the events to ch, the writes wait
+// when ch is full
+func ChangeChannel(ch chan<- ChangeEvent) ChangeConsumer {
+	return ChangeConsumerFunc(func(event ChangeEvent) {
+		ch <- event
+	})
+}
+
+// AddChangeConsumer captures the changes of the ORM writes of the engine to
+// consumers, the raw SQLs are not captured. Once a consumer is added, the
+// updates and the deletes load the rows they write before, and the updates
+// reload them after.
+func (engine *Engine) AddChangeConsumer(consumers ...ChangeConsumer) {
+	engine.changeConsumers = append(engine.changeConsumers, consumers...)
+}
+
+// capturesChanges returns true when the changes of table are consumed or
+// audited
+func (session *Session) capturesChanges(table *core.Table) bool {
+	if session.Statement.noCapture {
+		return false
+	}
+	return len(session.Engine.changeConsumers) > 0 || session.Engine.isAudited(table)
+}
+
+// emitChanges writes the audit records of events when table is audited, and
+// sends events to the consumers once they are committed
+func (session *Session) emitChanges(table *core.Table, events []ChangeEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if session.Engine.isAudited(table) {
+		if err := session.writeAudit(events); err != nil {
+			return err
+		}
+	}
+
+	consumers := session.Engine.changeConsumers
+	if len(consumers) == 0 {
+		return nil
+	}
+	session.OnCommit(func() {
+		now := session.Engine.now()
+		for _, event := range events {
+			event.Time = now
+			for _, consumer := range consumers {
+				consumer.Consume(event)
+			}
+		}
+	})
+	return nil
+}
+
+// beanColumns returns the values of the columns of bean
+func beanColumns(table *core.Table, bean interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(table.ColumnsSeq()))
+	for _, col := range table.Columns() {
+		v, err := col.ValueOf(bean)
+		if err != nil || v == nil || !v.IsValid() {
+			continue
+		}
+		values[col.Name] = v.Interface()
+	}
+	return values
+}
+
+// changePK returns the primary key of bean, nil when it has none or it's not
+// set yet
+func (session *Session) changePK(table *core.Table, bean interface{}) core.PK {
+	if len(table.PrimaryKeys) == 0 {
+		return nil
+	}
+	pk, err := session.Engine.idOfV(reflect.ValueOf(bean))
+	if err != nil || isPKZero(pk) {
+		return nil
+	}
+	return pk
+}
+
+// captureInsert emits the changes of the inserted beans
+func (session *Session) captureInsert(table *core.Table, beans ...interface{}) error {
+	if table == nil || !session.capturesChanges(table) {
+		return nil
+	}
+	events := make([]ChangeEvent, 0, len(beans))
+	for _, bean := range beans {
+		events = append(events, ChangeEvent{
+			Table: session.Statement.TableName(),
+			Op:    ChangeInsert,
+			PK:    session.changePK(table, bean),
+			After: beanColumns(table, bean),
+		})
+	}
+	return session.emitChanges(table, events)
+}
+
+// beforeChange loads the rows selected by sqlStr, the rows an update or a
+// delete is going to write
+func (session *Session) beforeChange(table *core.Table, sqlStr string, args []interface{}) ([]interface{}, error) {
+	if table == nil || len(table.PrimaryKeys) == 0 || !session.capturesChanges(table) {
+		return nil, nil
+	}
+	rows := reflect.New(reflect.SliceOf(reflect.PtrTo(table.Type)))
+	err := session.runHook(func() error {
+		return session.ForceMaster().NoCache().SQL(sqlStr, args...).Find(rows.Interface())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	before := make([]interface{}, rows.Elem().Len())
+	for i := range before {
+		before[i] = rows.Elem().Index(i).Interface()
+	}
+	return before, nil
+}
+
+// captureChange emits the changes of an update or a delete of the rows loaded
+// by beforeChange, the updated rows are reloaded one by one
+func (session *Session) captureChange(op ChangeOperation, table *core.Table, before []interface{}) error {
+	if !session.capturesChanges(table) {
+		return nil
+	}
+	tableName := session.Statement.TableName()
+	if table == nil || len(table.PrimaryKeys) == 0 {
+		return session.emitChanges(table, []ChangeEvent{{Table: tableName, Op: op}})
+	}
+
+	events := make([]ChangeEvent, 0, len(before))
+	for _, bean := range before {
+		event := ChangeEvent{
+			Table:  tableName,
+			Op:     op,
+			PK:     session.changePK(table, bean),
+			Before: beanColumns(table, bean),
+		}
+		if op == ChangeUpdate && event.PK != nil {
+			after := reflect.New(table.Type).Interface()
+			var has bool
+			err := session.runHook(func() (err error) {
+				has, err = session.ForceMaster().NoCache().Unscoped().Table(tableName).Id(event.PK).Get(after)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if has {
+				event.After = beanColumns(table, after)
+			}
+		}
+		events = append(events, event)
+	}
+	return session.emitChanges(table, events)
+}
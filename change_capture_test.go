@@ -0,0 +1,104 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ChangeCaptureUser struct {
+	Id   int64
+	Name string
+	Age  int
+}
+
+func TestChangeCapture(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(ChangeCaptureUser)))
+
+	events := make(chan ChangeEvent, 10)
+	testEngine.AddChangeConsumer(ChangeChannel(events))
+	defer func() { testEngine.changeConsumers = nil }()
+	tableName := testEngine.TableMapper.Obj2Table("ChangeCaptureUser")
+	nameCol := testEngine.ColumnMapper.Obj2Table("Name")
+	ageCol := testEngine.ColumnMapper.Obj2Table("Age")
+
+	user := ChangeCaptureUser{Name: "lunny", Age: 20}
+	_, err := testEngine.Insert(&user)
+	assert.NoError(t, err)
+	event := <-events
+	assert.EqualValues(t, tableName, event.Table)
+	assert.EqualValues(t, ChangeInsert, event.Op)
+	assert.EqualValues(t, []interface{}{user.Id}, event.PK)
+	assert.Nil(t, event.Before)
+	assert.EqualValues(t, "lunny", event.After[nameCol])
+	assert.False(t, event.Time.IsZero())
+
+	_, err = testEngine.Id(user.Id).Update(&ChangeCaptureUser{Name: "xlw"})
+	assert.NoError(t, err)
+	event = <-events
+	assert.EqualValues(t, ChangeUpdate, event.Op)
+	assert.EqualValues(t, "lunny", event.Before[nameCol])
+	assert.EqualValues(t, "xlw", event.After[nameCol])
+	assert.EqualValues(t, 20, event.After[ageCol])
+
+	// nothing is written, nothing is captured
+	_, err = testEngine.Id(user.Id + 1).Update(&ChangeCaptureUser{Name: "xlw"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, len(events))
+
+	_, err = testEngine.Id(user.Id).Delete(new(ChangeCaptureUser))
+	assert.NoError(t, err)
+	event = <-events
+	assert.EqualValues(t, ChangeDelete, event.Op)
+	assert.EqualValues(t, []interface{}{user.Id}, event.PK)
+	assert.EqualValues(t, "xlw", event.Before[nameCol])
+	assert.Nil(t, event.After)
+}
+
+func TestChangeCaptureTx(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(ChangeCaptureUser)))
+
+	ageCol := testEngine.ColumnMapper.Obj2Table("Age")
+	var events []ChangeEvent
+	testEngine.AddChangeConsumer(ChangeConsumerFunc(func(event ChangeEvent) {
+		events = append(events, event)
+	}))
+	defer func() { testEngine.changeConsumers = nil }()
+
+	session := testEngine.NewSession()
+	defer session.Close()
+
+	assert.NoError(t, session.Begin())
+	_, err := session.Insert([]ChangeCaptureUser{{Name: "lunny", Age: 1}, {Name: "xlw", Age: 1}})
+	assert.NoError(t, err)
+	_, err = session.Where("age = ?", 1).Update(&ChangeCaptureUser{Age: 2})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, len(events))
+	assert.NoError(t, session.Commit())
+
+	if assert.EqualValues(t, 4, len(events)) {
+		assert.EqualValues(t, ChangeInsert, events[0].Op)
+		assert.EqualValues(t, ChangeInsert, events[1].Op)
+		for _, event := range events[2:] {
+			assert.EqualValues(t, ChangeUpdate, event.Op)
+			assert.EqualValues(t, 1, event.Before[ageCol])
+			assert.EqualValues(t, 2, event.After[ageCol])
+		}
+	}
+
+	// the changes rolled back are not consumed
+	events = nil
+	session2 := testEngine.NewSession()
+	defer session2.Close()
+	assert.NoError(t, session2.Begin())
+	_, err = session2.Where("age = ?", 2).Delete(new(ChangeCaptureUser))
+	assert.NoError(t, err)
+	assert.NoError(t, session2.Rollback())
+	assert.EqualValues(t, 0, len(events))
+}
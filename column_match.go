@@ -0,0 +1,53 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// SetFieldNameMatch matches the columns of the results to the fields of
+// their names before the column names given by the mappers, so the aliases
+// of "SELECT user_name AS UserName" and the columns of the views are
+// scanned into their fields. The names are matched case-insensitively, as
+// the column names, for the databases folding the case of the identifiers.
+func (engine *Engine) SetFieldNameMatch(match bool) {
+	engine.fieldNameMatch = match
+	engine.resetScanPlans()
+}
+
+// resultColumn returns the column of the idx-th column named key of the
+// results
+func (session *Session) resultColumn(table *core.Table, key string, idx int) *core.Column {
+	if session.Engine.fieldNameMatch {
+		if cols := session.Engine.fieldColumns(table)[strings.ToLower(key)]; idx < len(cols) {
+			return cols[idx]
+		}
+	}
+	return table.GetColumnIdx(key, idx)
+}
+
+// fieldColumns returns the columns of table by the lower case names of
+// their fields, the names of the fields of the extends fields are the
+// names of their own fields
+func (engine *Engine) fieldColumns(table *core.Table) map[string][]*core.Column {
+	if cols, ok := engine.fieldColumnMaps.Load(table); ok {
+		return cols.(map[string][]*core.Column)
+	}
+
+	var cols = make(map[string][]*core.Column)
+	for _, col := range table.Columns() {
+		name := col.FieldName
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name = name[i+1:]
+		}
+		name = strings.ToLower(name)
+		cols[name] = append(cols[name], col)
+	}
+	engine.fieldColumnMaps.Store(table, cols)
+	return cols
+}
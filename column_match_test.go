@@ -0,0 +1,50 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type FieldMatchUser struct {
+	Id       int64
+	UserName string `xorm:"'login'"`
+}
+
+func TestSetFieldNameMatch(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(FieldMatchUser))
+
+	_, err := testEngine.Insert(&FieldMatchUser{UserName: "alice"})
+	assert.NoError(t, err)
+
+	tableName := testEngine.TableInfo(new(FieldMatchUser)).Name
+	sqlStr := fmt.Sprintf("SELECT %v, %v AS %v FROM %v", testEngine.Quote("id"),
+		testEngine.Quote("login"), testEngine.Quote("USERNAME"), testEngine.Quote(tableName))
+
+	var users []FieldMatchUser
+	assert.NoError(t, testEngine.SQL(sqlStr).Find(&users))
+	assert.EqualValues(t, 1, len(users))
+	assert.EqualValues(t, "", users[0].UserName)
+
+	testEngine.SetFieldNameMatch(true)
+	defer testEngine.SetFieldNameMatch(false)
+
+	users = nil
+	assert.NoError(t, testEngine.SQL(sqlStr).Find(&users))
+	assert.EqualValues(t, 1, len(users))
+	assert.EqualValues(t, "alice", users[0].UserName)
+	assert.True(t, users[0].Id > 0)
+
+	// the column names still match
+	var user FieldMatchUser
+	has, err := testEngine.Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "alice", user.UserName)
+}
@@ -0,0 +1,203 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/go-xorm/core"
+)
+
+// Converter binds and scans the values of a type, as core.Conversion does
+// for the types which can't implement it, as the ones of other packages.
+type Converter interface {
+	// SQLType is the type of the columns of the fields of the type, the
+	// type of the tag wins
+	SQLType() core.SQLType
+	// ToDB returns the value bound for v, a value of the type
+	ToDB(v interface{}) (interface{}, error)
+	// FromDB returns the value of the type for the value of a column, the
+	// NULLs aren't passed to FromDB
+	FromDB(src interface{}) (interface{}, error)
+}
+
+// ConverterFuncs is a Converter of functions
+type ConverterFuncs struct {
+	Type core.SQLType
+	To   func(v interface{}) (interface{}, error)
+	From func(src interface{}) (interface{}, error)
+}
+
+// SQLType implements Converter
+func (c ConverterFuncs) SQLType() core.SQLType {
+	return c.Type
+}
+
+// ToDB implements Converter
+func (c ConverterFuncs) ToDB(v interface{}) (interface{}, error) {
+	return c.To(v)
+}
+
+// FromDB implements Converter
+func (c ConverterFuncs) FromDB(src interface{}) (interface{}, error) {
+	return c.From(src)
+}
+
+// RegisterConverter converts the fields, the pointer fields and the args
+// of type t with converter, it wins over the conversions of the type. The
+// converters should be registered before the tables are mapped.
+func (engine *Engine) RegisterConverter(t reflect.Type, converter Converter) {
+	if engine.converters == nil {
+		engine.converters = make(map[reflect.Type]Converter)
+	}
+	engine.converters[t] = converter
+	engine.resetScanPlans()
+}
+
+// converter returns the converter of the values of a column or of an arg,
+// col is nil for an arg, and the type of the values it converts: t or the
+// type pointed by t
+func (engine *Engine) converter(col *core.Column, t reflect.Type) (Converter, reflect.Type, bool) {
+	if col != nil {
+		if converter, ok := engine.columnConverters.Load(col); ok {
+			if t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			return converter.(Converter), t, true
+		}
+	}
+	if converter, ok := engine.converters[t]; ok {
+		return converter, t, true
+	}
+	if t.Kind() == reflect.Ptr {
+		if converter, ok := engine.converters[t.Elem()]; ok {
+			return converter, t.Elem(), true
+		}
+	}
+	if converter, ok := engine.builtinConverter(col, t); ok {
+		return converter, t, true
+	}
+	if t.Kind() == reflect.Ptr {
+		if converter, ok := engine.builtinConverter(col, t.Elem()); ok {
+			return converter, t.Elem(), true
+		}
+	}
+	return nil, nil, false
+}
+
+// builtinConverters are the converters of the types of the standard library
+// which have a column type, as the network addresses
+var builtinConverters = make(map[reflect.Type]func(engine *Engine, col *core.Column) Converter)
+
+// builtinConverter returns the converter of the types of builtinConverters
+// and of the uuids
+func (engine *Engine) builtinConverter(col *core.Column, t reflect.Type) (Converter, bool) {
+	if newConverter, ok := builtinConverters[t]; ok {
+		return newConverter(engine, col), true
+	}
+	if isUUIDType(t) {
+		return engine.uuidConverter(col), true
+	}
+	return nil, false
+}
+
+// setColumnConverter converts the values of col with converter, as set by
+// the tags of the column
+func (engine *Engine) setColumnConverter(col *core.Column, converter Converter) {
+	engine.columnConverters.Store(col, converter)
+}
+
+// fieldSQLType returns the SQL type of the columns of the fields of type t,
+// the nullable types of a converted type have its type
+func (engine *Engine) fieldSQLType(t reflect.Type) core.SQLType {
+	if converter, _, ok := engine.converter(nil, t); ok {
+		return converter.SQLType()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if vt, ok := nullableValueType(t); ok {
+		return engine.fieldSQLType(vt)
+	}
+	return type2SQLType(t)
+}
+
+// convertToDB returns the value bound for a field of col having a
+// converter, nil for a nil pointer
+func (engine *Engine) convertToDB(col *core.Column, fieldValue reflect.Value) (interface{}, bool, error) {
+	converter, valueType, ok := engine.converter(col, fieldValue.Type())
+	if !ok {
+		return nil, false, nil
+	}
+	if valueType != fieldValue.Type() {
+		if fieldValue.IsNil() {
+			return nil, true, nil
+		}
+		fieldValue = fieldValue.Elem()
+	}
+	v, err := converter.ToDB(fieldValue.Interface())
+	return v, true, err
+}
+
+// convertFromDB sets a field of col having a converter from the not NULL
+// value of the column
+func (engine *Engine) convertFromDB(col *core.Column, fieldValue *reflect.Value, src interface{}) (bool, error) {
+	converter, valueType, ok := engine.converter(col, fieldValue.Type())
+	if !ok {
+		return false, nil
+	}
+	return true, setConverted(converter, valueType, fieldValue, src)
+}
+
+// setConverted sets fieldValue, of type valueType or pointing it, to the
+// value converter returns for src
+func setConverted(converter Converter, valueType reflect.Type, fieldValue *reflect.Value, src interface{}) error {
+	v, err := converter.FromDB(src)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || !rv.Type().ConvertibleTo(valueType) {
+		return fmt.Errorf("converter of %v returned %T", valueType, v)
+	}
+	rv = rv.Convert(valueType)
+	if valueType != fieldValue.Type() {
+		ptr := reflect.New(valueType)
+		ptr.Elem().Set(rv)
+		rv = ptr
+	}
+	fieldValue.Set(rv)
+	return nil
+}
+
+// convertArgs converts the args of the types having a converter and the
+// uuids. The times are bound as they are, only the times of the mapped
+// columns are bound in the timezone of the database.
+func (engine *Engine) convertArgs(args []interface{}) ([]interface{}, error) {
+	var converted []interface{}
+	for i, arg := range args {
+		if arg == nil {
+			continue
+		}
+		v, ok, err := engine.convertToDB(nil, reflect.ValueOf(arg))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if converted == nil {
+			converted = make([]interface{}, len(args))
+			copy(converted, args)
+		}
+		converted[i] = v
+	}
+	if converted == nil {
+		return args, nil
+	}
+	return converted, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+var urlConverter = ConverterFuncs{
+	Type: core.SQLType{Name: core.Varchar, DefaultLength: 255},
+	To: func(v interface{}) (interface{}, error) {
+		u := v.(url.URL)
+		return u.String(), nil
+	},
+	From: func(src interface{}) (interface{}, error) {
+		var s string
+		switch v := src.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		default:
+			return nil, errors.New("url is not a string")
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		return *u, nil
+	},
+}
+
+type ConverterSite struct {
+	Id       int64
+	Name     string
+	Home     url.URL
+	Feed     *url.URL
+	Previous *url.URL
+}
+
+func TestConverter(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	testEngine.RegisterConverter(reflect.TypeOf(url.URL{}), urlConverter)
+	defer func() {
+		testEngine.converters = nil
+	}()
+
+	assertSync(t, new(ConverterSite))
+
+	table := testEngine.TableInfo(new(ConverterSite))
+	col := table.GetColumn(testEngine.ColumnMapper.Obj2Table("Home"))
+	assert.NotNil(t, col)
+	assert.EqualValues(t, core.Varchar, col.SQLType.Name)
+
+	home, _ := url.Parse("https://xorm.io/docs?lang=en")
+	feed, _ := url.Parse("https://xorm.io/feed")
+	site := ConverterSite{Name: "xorm", Home: *home, Feed: feed}
+	cnt, err := testEngine.Insert(&site)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var got ConverterSite
+	has, err := testEngine.Id(site.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, home.String(), got.Home.String())
+	assert.NotNil(t, got.Feed)
+	assert.EqualValues(t, feed.String(), got.Feed.String())
+	assert.Nil(t, got.Previous)
+
+	// the args of the converted types are converted too
+	var sites []ConverterSite
+	err = testEngine.Where(testEngine.Quote(testEngine.ColumnMapper.Obj2Table("Home"))+" = ?", *home).Find(&sites)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, len(sites))
+
+	// the converted fields are conditions
+	has, err = testEngine.Get(&ConverterSite{Home: *home})
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	newHome, _ := url.Parse("https://gitea.io")
+	cnt, err = testEngine.Id(site.Id).Update(&ConverterSite{Home: *newHome})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var updated ConverterSite
+	has, err = testEngine.Id(site.Id).Get(&updated)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, newHome.String(), updated.Home.String())
+	assert.EqualValues(t, feed.String(), updated.Feed.String())
+}
@@ -0,0 +1,127 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// CountOption is an option of Count
+type CountOption interface {
+	countOption()
+}
+
+// EstimatedCount is the option of Count returned by Estimated
+type EstimatedCount struct {
+	// Approximate is set by Count, true if the count is the estimate of
+	// the statistics of the database, false if the records were counted
+	Approximate bool
+}
+
+func (*EstimatedCount) countOption() {}
+
+// Estimated returns an option of Count reading the number of the records of
+// the table from the statistics of the database, pg_class.reltuples,
+// information_schema.TABLES, sys.partitions, USER_TABLES or sqlite_stat1,
+// instead of counting them, as
+//
+//	est := xorm.Estimated()
+//	cnt, err := engine.Count(new(Log), est)
+//	// est.Approximate is true if cnt is an estimate
+//
+// The counts with conditions, joins, groups or distincts and the tables
+// without statistics are counted exactly.
+func Estimated() *EstimatedCount {
+	return new(EstimatedCount)
+}
+
+func estimatedOption(options []CountOption) *EstimatedCount {
+	for _, option := range options {
+		if est, ok := option.(*EstimatedCount); ok {
+			return est
+		}
+	}
+	return nil
+}
+
+// estimateCount returns the estimate of the number of the records of the
+// table of bean, false if the count has conditions or the table has no
+// statistics
+func (session *Session) estimateCount(bean interface{}) (int64, bool) {
+	statement := session.Statement
+	if statement.RawSQL != "" || statement.JoinStr != "" || statement.GroupByStr != "" ||
+		statement.HavingStr != "" || statement.IsDistinct || statement.selectStr != "" {
+		return 0, false
+	}
+	if err := statement.setRefValue(rValue(bean)); err != nil {
+		return 0, false
+	}
+
+	// the conditions are generated again by the exact count
+	cond := statement.cond
+	condSQL, _, err := statement.genConds(bean)
+	statement.cond = cond
+	if err != nil || condSQL != "" {
+		return 0, false
+	}
+
+	sqlStr, args := session.estimateSQL(statement.TableName())
+	if sqlStr == "" {
+		return 0, false
+	}
+	session.queryPreprocess(&sqlStr, args...)
+
+	var estimate sql.NullString
+	err = session.queryRow(sqlStr, args, func(row *core.Row) error {
+		return row.Scan(&estimate)
+	})
+	if err != nil || !estimate.Valid {
+		if err != nil {
+			session.getLogger().Debugf("[estimate] %v: %v", sqlStr, err)
+		}
+		return 0, false
+	}
+
+	// the stats of sqlite are the rows followed by the rows per key
+	fields := strings.Fields(estimate.String)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	total, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || total < 0 {
+		// -1 is a table never analyzed by PostgreSQL
+		return 0, false
+	}
+	return int64(total), true
+}
+
+// estimateSQL returns the query of the estimate of the number of the
+// records of tableName by the dialect, empty when it has none
+func (session *Session) estimateSQL(tableName string) (string, []interface{}) {
+	uri := session.Engine.dialect.URI()
+	switch session.Engine.dialect.DBType() {
+	case core.POSTGRES:
+		return "SELECT reltuples FROM pg_class WHERE oid = to_regclass(?)",
+			[]interface{}{session.Engine.Quote(tableName)}
+	case core.MYSQL:
+		return "SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+			[]interface{}{uri.DbName, tableName}
+	case core.MSSQL:
+		return "SELECT SUM(rows) FROM sys.partitions WHERE object_id = OBJECT_ID(?) AND index_id IN (0, 1)",
+			[]interface{}{tableName}
+	case core.ORACLE:
+		return "SELECT NUM_ROWS FROM USER_TABLES WHERE TABLE_NAME = ?",
+			[]interface{}{tableName}
+	case core.SQLITE:
+		// the table exists after an ANALYZE only
+		return "SELECT stat FROM sqlite_stat1 WHERE tbl = ? ORDER BY idx IS NOT NULL LIMIT 1",
+			[]interface{}{tableName}
+	}
+	return "", nil
+}
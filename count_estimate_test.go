@@ -0,0 +1,73 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type EstimatedLog struct {
+	Id    int64
+	Level string `xorm:"index"`
+}
+
+func TestCountEstimated(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(EstimatedLog))
+
+	var logs = make([]EstimatedLog, 20)
+	for i := range logs {
+		logs[i].Level = fmt.Sprintf("level%d", i%2)
+	}
+	_, err := testEngine.Insert(&logs)
+	assert.NoError(t, err)
+
+	// the conditions are counted exactly
+	est := Estimated()
+	cnt, err := testEngine.Where("level = ?", "level0").Count(new(EstimatedLog), est)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 10, cnt)
+	assert.False(t, est.Approximate)
+
+	est = Estimated()
+	cnt, err = testEngine.Count(&EstimatedLog{Level: "level1"}, est)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 10, cnt)
+	assert.False(t, est.Approximate)
+
+	if testEngine.Dialect().DBType() != core.SQLITE {
+		return
+	}
+
+	// no statistics before an analyze
+	est = Estimated()
+	cnt, err = testEngine.Count(new(EstimatedLog), est)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 20, cnt)
+	assert.False(t, est.Approximate)
+
+	_, err = testEngine.Exec("ANALYZE")
+	assert.NoError(t, err)
+
+	est = Estimated()
+	cnt, err = testEngine.Count(new(EstimatedLog), est)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 20, cnt)
+	assert.True(t, est.Approximate)
+
+	// the statistics are stale until the next analyze
+	_, err = testEngine.Insert(&EstimatedLog{Level: "level0"})
+	assert.NoError(t, err)
+	cnt, err = testEngine.Count(new(EstimatedLog), Estimated())
+	assert.NoError(t, err)
+	assert.EqualValues(t, 20, cnt)
+	cnt, err = testEngine.Count(new(EstimatedLog))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 21, cnt)
+}
@@ -0,0 +1,67 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package decimaladapter maps the decimals of github.com/shopspring/decimal
+// to the DECIMAL columns, exactly: the decimals are bound as strings and
+// are scanned from the text of the columns, never through a float64.
+package decimaladapter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/go-xorm/core"
+	"github.com/go-xorm/xorm"
+	"github.com/shopspring/decimal"
+)
+
+// Converter converts the decimals, the columns are DECIMAL(precision,
+// scale) unless the tag of the field has its own type, as
+// `xorm:"decimal(12,2)"` or `xorm:"numeric(30,10)"`
+type Converter struct {
+	Precision int
+	Scale     int
+}
+
+var _ xorm.Converter = Converter{}
+
+// Register converts the decimal.Decimal fields, the pointers to them and
+// the decimal args of engine, decimal.NullDecimal scans and binds itself
+// and has the same column type. It should be called before the tables are
+// mapped.
+func Register(engine *xorm.Engine, precision, scale int) {
+	engine.RegisterConverter(reflect.TypeOf(decimal.Decimal{}), Converter{precision, scale})
+}
+
+// SQLType implements xorm.Converter
+func (c Converter) SQLType() core.SQLType {
+	return core.SQLType{Name: core.Decimal, DefaultLength: c.Precision, DefaultLength2: c.Scale}
+}
+
+// ToDB implements xorm.Converter
+func (c Converter) ToDB(v interface{}) (interface{}, error) {
+	d, ok := v.(decimal.Decimal)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a decimal", v)
+	}
+	return d.String(), nil
+}
+
+// FromDB implements xorm.Converter
+func (c Converter) FromDB(src interface{}) (interface{}, error) {
+	switch v := src.(type) {
+	case []byte:
+		return decimal.NewFromString(string(v))
+	case string:
+		return decimal.NewFromString(v)
+	case int64:
+		return decimal.New(v, 0), nil
+	case float64:
+		// a database without decimals, as sqlite, may return a float, its
+		// shortest representation is the decimal stored
+		return decimal.NewFromString(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return nil, fmt.Errorf("unsupported decimal %T", src)
+}
@@ -0,0 +1,42 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimaladapter
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConverter(t *testing.T) {
+	c := Converter{Precision: 20, Scale: 4}
+	assert.EqualValues(t, core.Decimal, c.SQLType().Name)
+	assert.EqualValues(t, 20, c.SQLType().DefaultLength)
+	assert.EqualValues(t, 4, c.SQLType().DefaultLength2)
+
+	d := decimal.RequireFromString("12345678901234.5678")
+	v, err := c.ToDB(d)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "12345678901234.5678", v)
+
+	for _, src := range []interface{}{[]byte("12345678901234.5678"), "12345678901234.5678"} {
+		got, err := c.FromDB(src)
+		assert.NoError(t, err)
+		assert.True(t, d.Equal(got.(decimal.Decimal)))
+	}
+
+	got, err := c.FromDB(0.1)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "0.1", got.(decimal.Decimal).String())
+
+	got, err = c.FromDB(int64(42))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "42", got.(decimal.Decimal).String())
+
+	_, err = c.FromDB(true)
+	assert.Error(t, err)
+}
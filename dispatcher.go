@@ -0,0 +1,162 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"sync"
+	"time"
+)
+
+// the dispatcher created by an engine when none was set
+const (
+	defaultDispatchWorkers   = 4
+	defaultDispatchQueueSize = 1024
+)
+
+// Dispatcher delivers events asynchronously, as the events published after
+// a transaction is committed, so the slow deliveries don't block the
+// callers. A failed delivery is retried by the worker according to the
+// retry policy, every error is retried when the policy has no Retryable.
+// The queued events are in memory, they are lost if the process exits
+// before Close.
+type Dispatcher struct {
+	// OnFailure is called with the error of a delivery given up after its
+	// last attempt, it should be set before the dispatcher is used
+	OnFailure func(err error)
+
+	policy RetryPolicy
+	queue  chan func() error
+	mutex  sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher starts a dispatcher of workers goroutines. Up to queueSize
+// deliveries wait for a worker, Dispatch blocks when the queue is full. A
+// nil policy makes a single attempt.
+func NewDispatcher(workers, queueSize int, policy *RetryPolicy) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	d := &Dispatcher{
+		queue: make(chan func() error, queueSize),
+	}
+	if policy != nil {
+		d.policy = *policy
+	}
+
+	d.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// Dispatch queues the delivery deliver
+func (d *Dispatcher) Dispatch(deliver func() error) error {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+	d.queue <- deliver
+	return nil
+}
+
+// Close stops accepting deliveries and waits for the queued ones to finish
+func (d *Dispatcher) Close() error {
+	d.mutex.Lock()
+	if d.closed {
+		d.mutex.Unlock()
+		return nil
+	}
+	d.closed = true
+	close(d.queue)
+	d.mutex.Unlock()
+
+	d.wg.Wait()
+	return nil
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for deliver := range d.queue {
+		d.deliver(deliver)
+	}
+}
+
+// deliver runs deliver until it succeeds or the policy gives up
+func (d *Dispatcher) deliver(deliver func() error) {
+	for attempt := 1; ; attempt++ {
+		err := deliver()
+		if err == nil {
+			return
+		}
+		if attempt >= d.policy.MaxAttempts || (d.policy.Retryable != nil && !d.policy.Retryable(err)) {
+			if d.OnFailure != nil {
+				d.OnFailure(err)
+			}
+			return
+		}
+		if d.policy.Backoff != nil {
+			time.Sleep(d.policy.Backoff(attempt))
+		}
+	}
+}
+
+// SetDispatcher sets the dispatcher of the deliveries registered by
+// OnCommitAsync, the engine logs the failures when it has no OnFailure.
+// The engine closes it on Close.
+func (engine *Engine) SetDispatcher(dispatcher *Dispatcher) {
+	if dispatcher != nil && dispatcher.OnFailure == nil {
+		dispatcher.OnFailure = func(err error) {
+			engine.logger.Errorf("async dispatch failed: %v", err)
+		}
+	}
+	engine.mutex.Lock()
+	engine.dispatcher = dispatcher
+	engine.mutex.Unlock()
+}
+
+// getDispatcher returns the dispatcher of the engine, a default one is
+// created on first use
+func (engine *Engine) getDispatcher() *Dispatcher {
+	engine.mutex.RLock()
+	dispatcher := engine.dispatcher
+	engine.mutex.RUnlock()
+	if dispatcher != nil {
+		return dispatcher
+	}
+
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	if engine.dispatcher == nil {
+		engine.dispatcher = NewDispatcher(defaultDispatchWorkers, defaultDispatchQueueSize, &RetryPolicy{
+			MaxAttempts: 5,
+			Backoff:     ExponentialBackoff(100*time.Millisecond, 10*time.Second),
+		})
+		engine.dispatcher.OnFailure = func(err error) {
+			engine.logger.Errorf("async dispatch failed: %v", err)
+		}
+	}
+	return engine.dispatcher
+}
+
+// OnCommitAsync registers deliver to be dispatched asynchronously by the
+// engine's dispatcher after the transaction has been committed
+// successfully, as publishing an event of the changes to a message broker.
+// It's retried when it returns an error. If the session is not in a
+// transaction, it's dispatched immediately.
+func (session *Session) OnCommitAsync(deliver func() error) *Session {
+	if deliver == nil {
+		return session
+	}
+	engine := session.Engine
+	return session.OnCommit(func() {
+		if err := engine.getDispatcher().Dispatch(deliver); err != nil {
+			engine.logger.Errorf("async dispatch failed: %v", err)
+		}
+	})
+}
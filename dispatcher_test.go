@@ -0,0 +1,93 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type DispatchedUser struct {
+	Id   int64
+	Name string
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	var failures []error
+	d := NewDispatcher(2, 10, &RetryPolicy{MaxAttempts: 3})
+	d.OnFailure = func(err error) {
+		failures = append(failures, err)
+	}
+
+	var mutex sync.Mutex
+	attempts := make(map[string]int)
+	deliver := func(name string, fails int) func() error {
+		return func() error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			attempts[name]++
+			if attempts[name] <= fails {
+				return errors.New(name + " failed")
+			}
+			return nil
+		}
+	}
+	assert.NoError(t, d.Dispatch(deliver("ok", 0)))
+	assert.NoError(t, d.Dispatch(deliver("retried", 2)))
+	assert.NoError(t, d.Dispatch(deliver("failed", 5)))
+	assert.NoError(t, d.Close())
+
+	assert.EqualValues(t, map[string]int{"ok": 1, "retried": 3, "failed": 3}, attempts)
+	if assert.EqualValues(t, 1, len(failures)) {
+		assert.EqualValues(t, "failed failed", failures[0].Error())
+	}
+	assert.EqualValues(t, ErrDispatcherClosed, d.Dispatch(deliver("ok", 0)))
+}
+
+func TestOnCommitAsync(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(DispatchedUser)))
+
+	var mutex sync.Mutex
+	var published []string
+	publish := func(name string) func() error {
+		return func() error {
+			mutex.Lock()
+			published = append(published, name)
+			mutex.Unlock()
+			return nil
+		}
+	}
+
+	d := NewDispatcher(1, 10, nil)
+	testEngine.SetDispatcher(d)
+	defer testEngine.SetDispatcher(nil)
+
+	session := testEngine.NewSession()
+	defer session.Close()
+
+	assert.NoError(t, session.Begin())
+	_, err := session.Insert(&DispatchedUser{Name: "lunny"})
+	assert.NoError(t, err)
+	session.OnCommitAsync(publish("lunny"))
+	assert.NoError(t, session.Rollback())
+
+	session2 := testEngine.NewSession()
+	defer session2.Close()
+	assert.NoError(t, session2.Begin())
+	_, err = session2.Insert(&DispatchedUser{Name: "xlw"})
+	assert.NoError(t, err)
+	session2.OnCommitAsync(publish("xlw"))
+	assert.NoError(t, session2.Commit())
+
+	// outside of a transaction it's dispatched at once
+	testEngine.NewSession().OnCommitAsync(publish("auto")).Close()
+
+	assert.NoError(t, d.Close())
+	assert.EqualValues(t, []string{"xlw", "auto"}, published)
+}
@@ -0,0 +1,198 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// Interval is the SQL type of the interval columns of PostgreSQL
+const Interval = "INTERVAL"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// the units of the duration tag
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// DurationTagHandler stores a time.Duration field in a BIGINT of a unit,
+// `xorm:"duration(ms)"` stores milliseconds. The unit is ns, us, ms, s, m
+// or h, the nanoseconds by default as the fields without the tag. The
+// durations are truncated to the unit.
+func DurationTagHandler(ctx *tagContext) error {
+	if !isDurationType(ctx.fieldValue.Type()) {
+		return fmt.Errorf("duration tag of the field %v which isn't a time.Duration", ctx.col.FieldName)
+	}
+
+	unit := time.Nanosecond
+	if len(ctx.params) > 0 {
+		var ok bool
+		if unit, ok = durationUnits[strings.ToLower(strings.TrimSpace(ctx.params[0]))]; !ok {
+			return fmt.Errorf("unknown duration unit %v of the field %v", ctx.params[0], ctx.col.FieldName)
+		}
+	}
+	converter := durationConverter{unit: unit}
+	ctx.col.SQLType = converter.SQLType()
+	ctx.engine.setColumnConverter(ctx.col, converter)
+	return nil
+}
+
+// IntervalTagHandler stores a time.Duration field in an INTERVAL of
+// PostgreSQL, to the microsecond
+func IntervalTagHandler(ctx *tagContext) error {
+	if !isDurationType(ctx.fieldValue.Type()) {
+		return fmt.Errorf("interval tag of the field %v which isn't a time.Duration", ctx.col.FieldName)
+	}
+	if ctx.engine.dialect.DBType() != core.POSTGRES {
+		return fmt.Errorf("interval column %v isn't supported by %v, use the duration tag", ctx.col.FieldName, ctx.engine.dialect.DBType())
+	}
+
+	converter := durationConverter{interval: true}
+	ctx.col.SQLType = converter.SQLType()
+	ctx.engine.setColumnConverter(ctx.col, converter)
+	return nil
+}
+
+func isDurationType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == durationType
+}
+
+// durationConverter converts the durations to a number of units or to an
+// interval
+type durationConverter struct {
+	unit     time.Duration
+	interval bool
+}
+
+func (c durationConverter) SQLType() core.SQLType {
+	if c.interval {
+		return core.SQLType{Name: Interval}
+	}
+	return core.SQLType{Name: core.BigInt}
+}
+
+func (c durationConverter) ToDB(v interface{}) (interface{}, error) {
+	d := time.Duration(reflect.ValueOf(v).Int())
+	if c.interval {
+		return strconv.FormatInt(int64(d/time.Microsecond), 10) + " microseconds", nil
+	}
+	return int64(d / c.unit), nil
+}
+
+func (c durationConverter) FromDB(src interface{}) (interface{}, error) {
+	if c.interval {
+		switch v := src.(type) {
+		case []byte:
+			return parseInterval(string(v))
+		case string:
+			return parseInterval(v)
+		}
+		return nil, fmt.Errorf("unsupported interval %T", src)
+	}
+
+	var n int64
+	switch v := src.(type) {
+	case int64:
+		n = v
+	case float64:
+		n = int64(v)
+	case []byte:
+		i, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		n = i
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		n = i
+	default:
+		return nil, fmt.Errorf("unsupported duration %T", src)
+	}
+	return time.Duration(n) * c.unit, nil
+}
+
+// the lengths of the units of the intervals of PostgreSQL, a month is 30
+// days and a year 365.25 days as for extract(epoch from interval)
+var intervalUnits = map[string]time.Duration{
+	"year": time.Duration(365.25 * 24 * float64(time.Hour)),
+	"mon":  30 * 24 * time.Hour,
+	"day":  24 * time.Hour,
+}
+
+// parseInterval parses an interval of the default style of PostgreSQL, as
+// "1 year 2 mons 3 days -04:05:06.789"
+func parseInterval(s string) (time.Duration, error) {
+	var d time.Duration
+	fields := strings.Fields(s)
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if strings.Contains(f, ":") {
+			t, err := parseIntervalTime(f)
+			if err != nil {
+				return 0, err
+			}
+			d += t
+			continue
+		}
+
+		n, err := strconv.ParseInt(f, 10, 64)
+		if err != nil || i+1 >= len(fields) {
+			return 0, fmt.Errorf("invalid interval %q", s)
+		}
+		i++
+		unit, ok := intervalUnits[strings.TrimSuffix(fields[i], "s")]
+		if !ok {
+			return 0, fmt.Errorf("invalid interval %q", s)
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
+
+// parseIntervalTime parses the [-]hh:mm:ss[.ffffff] part of an interval
+func parseIntervalTime(s string) (time.Duration, error) {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "+-")
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid interval time %q", s)
+	}
+	h, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	m, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	sec, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, err
+	}
+	d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(sec*float64(time.Second)+0.5)
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInterval(t *testing.T) {
+	var kases = []struct {
+		interval string
+		expected time.Duration
+	}{
+		{"00:00:00", 0},
+		{"01:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"-00:00:01.5", -1500 * time.Millisecond},
+		{"00:00:00.000001", time.Microsecond},
+		{"3 days 04:00:00", 76 * time.Hour},
+		{"1 day", 24 * time.Hour},
+		{"-2 days +01:00:00", -47 * time.Hour},
+		{"1 mon 1 day", 31 * 24 * time.Hour},
+	}
+
+	for _, kase := range kases {
+		d, err := parseInterval(kase.interval)
+		assert.NoError(t, err)
+		assert.EqualValues(t, kase.expected, d, kase.interval)
+	}
+
+	_, err := parseInterval("3 fortnights")
+	assert.Error(t, err)
+}
+
+type DurationStruct struct {
+	Id      int64
+	Timeout time.Duration
+	Ttl     time.Duration  `xorm:"duration(ms)"`
+	Retry   *time.Duration `xorm:"duration(s)"`
+}
+
+func TestDurationColumns(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(DurationStruct))
+
+	table := testEngine.TableInfo(new(DurationStruct))
+	col := table.GetColumn(testEngine.ColumnMapper.Obj2Table("Ttl"))
+	assert.NotNil(t, col)
+	assert.EqualValues(t, core.BigInt, col.SQLType.Name)
+
+	retry := 30 * time.Second
+	d := DurationStruct{
+		Timeout: 1500 * time.Millisecond,
+		Ttl:     90*time.Second + 1500*time.Microsecond,
+		Retry:   &retry,
+	}
+	cnt, err := testEngine.Insert(&d)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	// the durations are stored in their unit
+	var ttl int64
+	has, err := testEngine.Table(new(DurationStruct)).Id(d.Id).
+		Cols(testEngine.ColumnMapper.Obj2Table("Ttl")).Get(&ttl)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 90001, ttl)
+
+	var got DurationStruct
+	has, err = testEngine.Id(d.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 1500*time.Millisecond, got.Timeout)
+	assert.EqualValues(t, 90001*time.Millisecond, got.Ttl)
+	assert.NotNil(t, got.Retry)
+	assert.EqualValues(t, retry, *got.Retry)
+
+	// the conditions are in the unit of the column too
+	has, err = testEngine.Get(&DurationStruct{Ttl: 90001 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.True(t, has)
+}
+
+type IntervalStruct struct {
+	Id      int64
+	Timeout time.Duration `xorm:"interval"`
+}
+
+func TestIntervalColumns(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	if testEngine.Dialect().DBType() != core.POSTGRES {
+		assert.Error(t, testEngine.Sync2(new(IntervalStruct)))
+		return
+	}
+
+	assertSync(t, new(IntervalStruct))
+
+	d := IntervalStruct{Timeout: 26*time.Hour + 1500*time.Millisecond}
+	_, err := testEngine.Insert(&d)
+	assert.NoError(t, err)
+
+	var got IntervalStruct
+	has, err := testEngine.Id(d.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, d.Timeout, got.Timeout)
+}
@@ -5,10 +5,9 @@
 package xorm
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"database/sql"
-	"encoding/gob"
 	"errors"
 	"fmt"
 	"io"
@@ -44,6 +43,52 @@ type Engine struct {
 	DatabaseTZ *time.Location // The timezone of the database
 
 	disableGlobalCache bool
+	detectSessionRace  bool
+
+	group *EngineGroup // not nil when the engine is the master of a group
+
+	tenantStrategy TenantStrategy
+
+	retryPolicy    *RetryPolicy
+	sessionPool    *sync.Pool   // nil when sessions are not pooled
+	pool           *poolMonitor // created on first use by getPoolMonitor
+	poolOnce       sync.Once
+	stmtCounters   *stmtCacheCounters
+	queryCache     *QueryCache
+	cacheLoads     flightGroup // the loads of the missed cache keys
+	cacheMonitor   *cacheMonitor
+	cacheWriteMode CacheWriteMode
+	cacheOptions   cacheOptionsRegistry
+	forcedCaches   sync.Map // the NOCACHE *core.Table cached by Session.Cache
+	interceptors   []Interceptor
+
+	changeConsumers  []ChangeConsumer
+	audits           auditRegistry
+	validator        StructValidator
+	sqlHooks         []SQLHook
+	dispatcher       *Dispatcher // created on first use, guarded by mutex
+	queryFilters     []QueryFilter
+	slowQuery        slowQueryLog
+	structuredLogger StructuredLogger // the SQLs are logged by a hook when set
+	queryStats       *queryStats      // nil when the statistics are disabled
+	auditSink        AuditSink
+	converters       map[reflect.Type]Converter
+	columnConverters sync.Map // *core.Column to the Converter set by its tags
+	serializer       string   // the serializer of the slices and the maps, json if empty
+	blobChunkSize    int      // the chunks of the blobs, 1MB if 0
+	typeMappers      map[reflect.Type]core.IMapper
+	pkgMappers       map[string]core.IMapper
+	fieldNameMatch   bool
+	fieldColumnMaps  sync.Map // *core.Table to its columns by field name
+	columnGroups     sync.Map // *core.Column to its groups of Scope
+	columnIDGens     sync.Map // *core.Column to its IDGenerator
+	scanPlans        sync.Map // scanPlanKey to its *scanPlan
+	lazyColumns      sync.Map // *core.Column with the LAZY tag
+	versionConflict  VersionConflictMode
+	sqlCache         *sqlCache
+	insertStrategy   InsertMultiStrategy
+	clock            func() time.Time
+	sqlTemplates     sync.Map // the names to the templates of LoadSQLMap
 
 	tagHandlers map[string]tagHandler
 }
@@ -85,6 +130,15 @@ func (engine *Engine) SetDisableGlobalCache(disable bool) {
 	}
 }
 
+// SetSessionRaceDetect enables or disables the detection of using one session
+// from multiple goroutines concurrently. When enabled, operations on a session
+// already running an operation in another goroutine will return
+// ErrConcurrentSessionUse. It has some overhead, so it's designed for
+// debugging and testing.
+func (engine *Engine) SetSessionRaceDetect(detect bool) {
+	engine.detectSessionRace = detect
+}
+
 // DriverName return the current sql driver's name
 func (engine *Engine) DriverName() string {
 	return engine.dialect.DriverName()
@@ -198,6 +252,14 @@ func (engine *Engine) SetMaxIdleConns(conns int) {
 // SetDefaultCacher set the default cacher. Xorm's default not enable cacher.
 func (engine *Engine) SetDefaultCacher(cacher core.Cacher) {
 	engine.Cacher = cacher
+	engine.cacheMonitor.watch(cacher)
+}
+
+// Cache caches the result of the next Get or Find, see Session.Cache
+func (engine *Engine) Cache(ttl ...time.Duration) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Cache(ttl...)
 }
 
 // NoCache If you has set default cacher, and you want temporilly stop use cache,
@@ -208,6 +270,47 @@ func (engine *Engine) NoCache() *Session {
 	return session.NoCache()
 }
 
+// SetTenantStrategy set how the tenant of sessions is applied
+func (engine *Engine) SetTenantStrategy(strategy TenantStrategy) {
+	engine.tenantStrategy = strategy
+}
+
+// Tenant applies the tenant to all the statements of the session
+func (engine *Engine) Tenant(tenant string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Tenant(tenant)
+}
+
+// Context set the context passed to the processors of the beans
+func (engine *Engine) Context(ctx context.Context) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Context(ctx)
+}
+
+// ForceMaster sends the query to the master of the engine group
+func (engine *Engine) ForceMaster() *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.ForceMaster()
+}
+
+// PreferReplica sends the query to a slave of the engine group
+func (engine *Engine) PreferReplica() *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.PreferReplica()
+}
+
+// UseMaster forces the reads of the session to go to the master when the
+// engine is the master of an engine group
+func (engine *Engine) UseMaster() *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.UseMaster()
+}
+
 // NoCascade If you do not want to auto cascade load object
 func (engine *Engine) NoCascade() *Session {
 	session := engine.NewSession()
@@ -224,6 +327,7 @@ func (engine *Engine) MapCacher(bean interface{}, cacher core.Cacher) error {
 	}
 
 	tb.Cacher = cacher
+	engine.cacheMonitor.watch(cacher)
 	return nil
 }
 
@@ -244,13 +348,28 @@ func (engine *Engine) Dialect() core.Dialect {
 
 // NewSession New a session
 func (engine *Engine) NewSession() *Session {
-	session := &Session{Engine: engine}
-	session.Init()
+	var session *Session
+	if engine.sessionPool != nil {
+		session = engine.getPooledSession()
+	} else {
+		session = &Session{Engine: engine}
+		session.Init()
+	}
+	session.id = nextSessionID()
+	if engine.detectSessionRace {
+		session.guard = new(sessionGuard)
+	}
 	return session
 }
 
 // Close the engine
 func (engine *Engine) Close() error {
+	engine.mutex.RLock()
+	dispatcher := engine.dispatcher
+	engine.mutex.RUnlock()
+	if dispatcher != nil {
+		dispatcher.Close()
+	}
 	return engine.db.Close()
 }
 
@@ -264,7 +383,7 @@ func (engine *Engine) Ping() error {
 
 // logging sql
 func (engine *Engine) logSQL(sqlStr string, sqlArgs ...interface{}) {
-	if engine.showSQL && !engine.showExecTime {
+	if engine.showSQL && !engine.showExecTime && engine.structuredLogger == nil {
 		if len(sqlArgs) > 0 {
 			engine.logger.Infof("[SQL] %v %v", sqlStr, sqlArgs)
 		} else {
@@ -273,36 +392,6 @@ func (engine *Engine) logSQL(sqlStr string, sqlArgs ...interface{}) {
 	}
 }
 
-func (engine *Engine) logSQLQueryTime(sqlStr string, args []interface{}, executionBlock func() (*core.Stmt, *core.Rows, error)) (*core.Stmt, *core.Rows, error) {
-	if engine.showSQL && engine.showExecTime {
-		b4ExecTime := time.Now()
-		stmt, res, err := executionBlock()
-		execDuration := time.Since(b4ExecTime)
-		if len(args) > 0 {
-			engine.logger.Infof("[SQL] %s %v - took: %v", sqlStr, args, execDuration)
-		} else {
-			engine.logger.Infof("[SQL] %s - took: %v", sqlStr, execDuration)
-		}
-		return stmt, res, err
-	}
-	return executionBlock()
-}
-
-func (engine *Engine) logSQLExecutionTime(sqlStr string, args []interface{}, executionBlock func() (sql.Result, error)) (sql.Result, error) {
-	if engine.showSQL && engine.showExecTime {
-		b4ExecTime := time.Now()
-		res, err := executionBlock()
-		execDuration := time.Since(b4ExecTime)
-		if len(args) > 0 {
-			engine.logger.Infof("[sql] %s [args] %v - took: %v", sqlStr, args, execDuration)
-		} else {
-			engine.logger.Infof("[sql] %s - took: %v", sqlStr, execDuration)
-		}
-		return res, err
-	}
-	return executionBlock()
-}
-
 // Sql provides raw sql input parameter. When you have a complex SQL statement
 // and cannot use Where, Id, In and etc. Methods to describe, you can use SQL.
 //
@@ -563,7 +652,8 @@ func (engine *Engine) tbName(v reflect.Value) string {
 			return tb.TableName()
 		}
 	}
-	return engine.TableMapper.Obj2Table(reflect.Indirect(v).Type().Name())
+	tableMapper, _ := engine.mappers(reflect.Indirect(v).Type())
+	return tableMapper.Obj2Table(reflect.Indirect(v).Type().Name())
 }
 
 // Cascade use cascade or not
@@ -622,6 +712,50 @@ func (engine *Engine) StoreEngine(storeEngine string) *Session {
 	return session.StoreEngine(storeEngine)
 }
 
+// OnConflict makes Upsert update the record conflicting by the unique index
+// of columns
+func (engine *Engine) OnConflict(columns ...string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.OnConflict(columns...)
+}
+
+// OnConstraint makes Upsert update the record conflicting by the unique
+// constraint name, PostgreSQL only
+func (engine *Engine) OnConstraint(name string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.OnConstraint(name)
+}
+
+// DoUpdate makes Upsert only update the columns of a conflicting record
+func (engine *Engine) DoUpdate(columns ...string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.DoUpdate(columns...)
+}
+
+// Keep makes Upsert keep the columns of a conflicting record as is
+func (engine *Engine) Keep(columns ...string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Keep(columns...)
+}
+
+// Temporary makes CreateTable create a temporary table
+func (engine *Engine) Temporary() *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Temporary()
+}
+
+// Unlogged makes CreateTable create an unlogged table, PostgreSQL only
+func (engine *Engine) Unlogged() *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Unlogged()
+}
+
 // Distinct use for distinct columns. Caution: when you are using cache,
 // distinct will not be cached because cache system need id,
 // but distinct will not provide id
@@ -659,6 +793,75 @@ func (engine *Engine) MustCols(columns ...string) *Session {
 	return session.MustCols(columns...)
 }
 
+// Fields only updates the columns of the fields of mask, even if they are
+// empty
+func (engine *Engine) Fields(mask FieldMask) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Fields(mask)
+}
+
+// Scope only selects the primary keys and the columns of the groups
+func (engine *Engine) Scope(groups ...string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Scope(groups...)
+}
+
+// Eager selects the lazy columns too
+func (engine *Engine) Eager() *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Eager()
+}
+
+// SelectExpr selects the expression expr, as "count(c.id) AS comment_count",
+// scanned into the field of its alias which isn't a column
+func (engine *Engine) SelectExpr(expr string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.SelectExpr(expr)
+}
+
+// SelectFields only selects the columns of the fields of paths, the unknown
+// fields are errors
+func (engine *Engine) SelectFields(paths []string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.SelectFields(paths)
+}
+
+// Nested makes Find fetch the records of the has-many field of its beans,
+// whose column foreignKey references their primary key, in the same query
+func (engine *Engine) Nested(field, foreignKey string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Nested(field, foreignKey)
+}
+
+// LoadColumn loads the columns, the lazy ones when none is given, of the
+// record of the primary key of bean into its fields
+func (engine *Engine) LoadColumn(bean interface{}, columns ...string) error {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.LoadColumn(bean, columns...)
+}
+
+// Cursor makes Rows and Iterate read the records of PostgreSQL by batches
+// of size from a server-side cursor
+func (engine *Engine) Cursor(size int) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.Cursor(size)
+}
+
+// TableSuffix appends suffix to the table of the next statement
+func (engine *Engine) TableSuffix(suffix string) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.TableSuffix(suffix)
+}
+
 // UseBool xorm automatically retrieve condition according struct, but
 // if struct has bool field, it will ignore them. So use UseBool
 // to tell system to do not ignore them.
@@ -712,6 +915,13 @@ func (engine *Engine) SetExpr(column string, expression string) *Session {
 	return session.SetExpr(column, expression)
 }
 
+// SetJSON provides a update string like "column = JSON_SET(column, path, ?)"
+func (engine *Engine) SetJSON(column, path string, arg interface{}) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.SetJSON(column, path, arg)
+}
+
 // Table temporarily change the Get, Find, Update's table
 func (engine *Engine) Table(tableNameOrBean interface{}) *Session {
 	session := engine.NewSession()
@@ -810,9 +1020,12 @@ func (engine *Engine) autoMapType(v reflect.Value) (*core.Table, error) {
 	return table, nil
 }
 
-// GobRegister register one struct to gob for cache use
+// GobRegister register one struct to gob for cache use, a struct whose name
+// is registered for another type is logged and not registered
 func (engine *Engine) GobRegister(v interface{}) *Engine {
-	gob.Register(v)
+	if err := gobRegister(v); err != nil {
+		engine.logger.Error(err)
+	}
 	return engine
 }
 
@@ -869,6 +1082,7 @@ var (
 
 func (engine *Engine) mapType(v reflect.Value) (*core.Table, error) {
 	t := v.Type()
+	tableMapper, columnMapper := engine.mappers(t)
 	table := engine.newTable()
 	if tb, ok := v.Interface().(TableName); ok {
 		table.Name = tb.TableName()
@@ -879,14 +1093,14 @@ func (engine *Engine) mapType(v reflect.Value) (*core.Table, error) {
 			}
 		}
 		if table.Name == "" {
-			table.Name = engine.TableMapper.Obj2Table(t.Name())
+			table.Name = tableMapper.Obj2Table(t.Name())
 		}
 	}
 
 	table.Type = t
 
 	var idFieldColName string
-	var hasCacheTag, hasNoCacheTag bool
+	var hasCacheTag, hasNoCacheTag, hasAuditedTag bool
 
 	for i := 0; i < t.NumField(); i++ {
 		tag := t.Field(i).Tag
@@ -971,10 +1185,16 @@ func (engine *Engine) mapType(v reflect.Value) (*core.Table, error) {
 					if ctx.hasNoCacheTag {
 						hasNoCacheTag = true
 					}
+					if ctx.hasAuditedTag {
+						hasAuditedTag = true
+					}
 				}
 
+				if sqlType, ok := engine.serializeByDefault(col, fieldType); ok && col.SQLType.Name == "" {
+					col.SQLType = sqlType
+				}
 				if col.SQLType.Name == "" {
-					col.SQLType = core.Type2SQLType(fieldType)
+					col.SQLType = engine.fieldSQLType(fieldType)
 				}
 				engine.dialect.SqlType(col)
 				if col.Length == 0 {
@@ -984,7 +1204,7 @@ func (engine *Engine) mapType(v reflect.Value) (*core.Table, error) {
 					col.Length2 = col.SQLType.DefaultLength2
 				}
 				if col.Name == "" {
-					col.Name = engine.ColumnMapper.Obj2Table(t.Field(i).Name)
+					col.Name = columnMapper.Obj2Table(t.Field(i).Name)
 				}
 
 				if ctx.isUnique {
@@ -1007,11 +1227,15 @@ func (engine *Engine) mapType(v reflect.Value) (*core.Table, error) {
 			if _, ok := fieldValue.Interface().(core.Conversion); ok {
 				sqlType = core.SQLType{Name: core.Text}
 			} else {
-				sqlType = core.Type2SQLType(fieldType)
+				sqlType = engine.fieldSQLType(fieldType)
 			}
-			col = core.NewColumn(engine.ColumnMapper.Obj2Table(t.Field(i).Name),
+			col = core.NewColumn(columnMapper.Obj2Table(t.Field(i).Name),
 				t.Field(i).Name, sqlType, sqlType.DefaultLength,
 				sqlType.DefaultLength2, true)
+			if sqlType, ok := engine.serializeByDefault(col, fieldType); ok {
+				col.SQLType = sqlType
+				col.Length, col.Length2 = sqlType.DefaultLength, sqlType.DefaultLength2
+			}
 
 			if fieldType.Kind() == reflect.Int64 && (strings.ToUpper(col.FieldName) == "ID" || strings.HasSuffix(strings.ToUpper(col.FieldName), ".ID")) {
 				idFieldColName = col.Name
@@ -1020,6 +1244,9 @@ func (engine *Engine) mapType(v reflect.Value) (*core.Table, error) {
 		if col.IsAutoIncrement {
 			col.Nullable = false
 		}
+		if col.SQLType.Name == core.Enum && len(col.EnumOptions) == 0 {
+			col.EnumOptions = engine.enumOptions(fieldType)
+		}
 
 		table.AddColumn(col)
 
@@ -1047,6 +1274,9 @@ func (engine *Engine) mapType(v reflect.Value) (*core.Table, error) {
 		engine.logger.Info("no cache on table:", table.Name)
 		table.Cacher = nil
 	}
+	if hasAuditedTag {
+		engine.audits.add(table.Name)
+	}
 
 	return table, nil
 }
@@ -1111,6 +1341,8 @@ func (engine *Engine) idOfV(rv reflect.Value) (core.PK, error) {
 			pk[i] = pkField.Int()
 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 			pk[i] = pkField.Uint()
+		case reflect.Array:
+			pk[i] = pkField.Interface()
 		}
 	}
 	return core.PK(pk), nil
@@ -1130,8 +1362,16 @@ func (engine *Engine) CreateUniques(bean interface{}) error {
 	return session.CreateUniques(bean)
 }
 
+// getCacher2 returns the cacher of table cleared by the writes, the default
+// cacher of a NOCACHE table once it's cached by Session.Cache
 func (engine *Engine) getCacher2(table *core.Table) core.Cacher {
-	return table.Cacher
+	if cacher := engine.tableCacher(table); cacher != nil {
+		return cacher
+	}
+	if _, ok := engine.forcedCaches.Load(table); ok {
+		return engine.Cacher
+	}
+	return nil
 }
 
 // ClearCacheBean if enabled cache, clear the cache bean
@@ -1405,6 +1645,13 @@ func (engine *Engine) Get(bean interface{}) (bool, error) {
 	return session.Get(bean)
 }
 
+// Exist returns true if a record matches the conditions
+func (engine *Engine) Exist(bean ...interface{}) (bool, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.Exist(bean...)
+}
+
 // Find retrieve records from table, condiBeans's non-empty fields
 // are conditions. beans could be []Struct, []*Struct, map[int64]Struct
 // map[int64]*Struct
@@ -1430,10 +1677,10 @@ func (engine *Engine) Rows(bean interface{}) (*Rows, error) {
 }
 
 // Count counts the records. bean's non-empty fields are conditions.
-func (engine *Engine) Count(bean interface{}) (int64, error) {
+func (engine *Engine) Count(bean interface{}, options ...CountOption) (int64, error) {
 	session := engine.NewSession()
 	defer session.Close()
-	return session.Count(bean)
+	return session.Count(bean, options...)
 }
 
 // Sum sum the records by some column. bean's non-empty fields are conditions.
@@ -1459,55 +1706,17 @@ func (engine *Engine) SumsInt(bean interface{}, colNames ...string) ([]int64, er
 
 // ImportFile SQL DDL file
 func (engine *Engine) ImportFile(ddlPath string) ([]sql.Result, error) {
-	file, err := os.Open(ddlPath)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	return engine.Import(file)
+	return engine.ImportFileWith(ddlPath, ImportOptions{})
 }
 
 // Import SQL DDL from io.Reader
 func (engine *Engine) Import(r io.Reader) ([]sql.Result, error) {
-	var results []sql.Result
-	var lastError error
-	scanner := bufio.NewScanner(r)
-
-	semiColSpliter := func(data []byte, atEOF bool) (advance int, token []byte, err error) {
-		if atEOF && len(data) == 0 {
-			return 0, nil, nil
-		}
-		if i := bytes.IndexByte(data, ';'); i >= 0 {
-			return i + 1, data[0:i], nil
-		}
-		// If we're at EOF, we have a final, non-terminated line. Return it.
-		if atEOF {
-			return len(data), data, nil
-		}
-		// Request more data.
-		return 0, nil, nil
-	}
-
-	scanner.Split(semiColSpliter)
-
-	for scanner.Scan() {
-		query := strings.Trim(scanner.Text(), " \t\n\r")
-		if len(query) > 0 {
-			engine.logSQL(query)
-			result, err := engine.DB().Exec(query)
-			results = append(results, result)
-			if err != nil {
-				return nil, err
-			}
-		}
-	}
-
-	return results, lastError
+	return engine.ImportWith(r, ImportOptions{})
 }
 
 // NowTime2 return current time
 func (engine *Engine) NowTime2(sqlTypeName string) (interface{}, time.Time) {
-	t := time.Now()
+	t := engine.now()
 	return engine.formatTime(sqlTypeName, t.In(engine.DatabaseTZ)), t.In(engine.TZLocation)
 }
 
@@ -1519,10 +1728,7 @@ func (engine *Engine) formatColTime(col *core.Column, t time.Time) (v interface{
 		return ""
 	}
 
-	if col.TimeZone != nil {
-		return engine.formatTime(col.SQLType.Name, t.In(col.TimeZone))
-	}
-	return engine.formatTime(col.SQLType.Name, t.In(engine.DatabaseTZ))
+	return engine.formatTime(col.SQLType.Name, t.In(engine.columnTZ(col)))
 }
 
 // formatTime format time as column type
@@ -0,0 +1,338 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// EngineGroup defines an engine group which has one master and zero or more
+// slaves. Writes and transactions always go to the master, autocommit reads
+// are distributed to the slaves by the group's policy.
+type EngineGroup struct {
+	*Engine
+	slaves []*Engine
+	policy GroupPolicy
+
+	mutex           sync.RWMutex
+	healthySlaves   []*Engine
+	noFallback      bool
+	stickyWindow    time.Duration
+	stopHealthCheck chan bool
+}
+
+// NewEngineGroup creates a new engine group. args1 and args2 could be a
+// driver name and a list of data source names whose first element is the
+// master, or a master *Engine and a []*Engine of slaves.
+// The default policy is RoundRobinPolicy.
+func NewEngineGroup(args1 interface{}, args2 interface{}, policies ...GroupPolicy) (*EngineGroup, error) {
+	var eg EngineGroup
+	if len(policies) > 0 {
+		eg.policy = policies[0]
+	} else {
+		eg.policy = RoundRobinPolicy()
+	}
+
+	driverName, ok1 := args1.(string)
+	conns, ok2 := args2.([]string)
+	if ok1 && ok2 {
+		if len(conns) == 0 {
+			return nil, errors.New("At least one data source name is required")
+		}
+		engines := make([]*Engine, len(conns))
+		for i, conn := range conns {
+			engine, err := NewEngine(driverName, conn)
+			if err != nil {
+				for _, e := range engines[:i] {
+					e.Close()
+				}
+				return nil, err
+			}
+			engines[i] = engine
+		}
+
+		eg.Engine = engines[0]
+		eg.slaves = engines[1:]
+		eg.healthySlaves = eg.slaves
+		eg.Engine.group = &eg
+		return &eg, nil
+	}
+
+	master, ok3 := args1.(*Engine)
+	slaves, ok4 := args2.([]*Engine)
+	if ok3 && ok4 {
+		eg.Engine = master
+		eg.slaves = slaves
+		eg.healthySlaves = slaves
+		eg.Engine.group = &eg
+		return &eg, nil
+	}
+	return nil, errors.New("Unsupported arguments for NewEngineGroup")
+}
+
+// Close closes the master and all the slaves
+func (eg *EngineGroup) Close() error {
+	eg.StopHealthCheck()
+
+	err := eg.Engine.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, slave := range eg.slaves {
+		err = slave.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Master returns the master engine. Sessions created from it still read
+// from the slaves unless UseMaster is called.
+func (eg *EngineGroup) Master() *Engine {
+	return eg.Engine
+}
+
+// Ping tests if the master and all the slaves are alive
+func (eg *EngineGroup) Ping() error {
+	if err := eg.Engine.Ping(); err != nil {
+		return err
+	}
+
+	for _, slave := range eg.slaves {
+		if err := slave.Ping(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetColumnMapper set the column name mapping rule
+func (eg *EngineGroup) SetColumnMapper(mapper core.IMapper) {
+	eg.Engine.SetColumnMapper(mapper)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetColumnMapper(mapper)
+	}
+}
+
+// SetDefaultCacher set the default cacher
+func (eg *EngineGroup) SetDefaultCacher(cacher core.Cacher) {
+	eg.Engine.SetDefaultCacher(cacher)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetDefaultCacher(cacher)
+	}
+}
+
+// SetLogger set the new logger
+func (eg *EngineGroup) SetLogger(logger core.ILogger) {
+	eg.Engine.SetLogger(logger)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetLogger(logger)
+	}
+}
+
+// SetMapper set the name mapping rules
+func (eg *EngineGroup) SetMapper(mapper core.IMapper) {
+	eg.Engine.SetMapper(mapper)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetMapper(mapper)
+	}
+}
+
+// SetMaxIdleConns set the max idle connections on pool, default is 2
+func (eg *EngineGroup) SetMaxIdleConns(conns int) {
+	eg.Engine.SetMaxIdleConns(conns)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetMaxIdleConns(conns)
+	}
+}
+
+// SetMaxOpenConns is only available for go 1.2+
+func (eg *EngineGroup) SetMaxOpenConns(conns int) {
+	eg.Engine.SetMaxOpenConns(conns)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetMaxOpenConns(conns)
+	}
+}
+
+// SetConnMaxLifetime sets the maximum amount of time a connection may be reused
+func (eg *EngineGroup) SetConnMaxLifetime(d time.Duration) {
+	eg.Engine.SetConnMaxLifetime(d)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetConnMaxLifetime(d)
+	}
+}
+
+// SetConnMaxIdleTime sets the maximum amount of time a connection may be idle
+func (eg *EngineGroup) SetConnMaxIdleTime(d time.Duration) {
+	eg.Engine.SetConnMaxIdleTime(d)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetConnMaxIdleTime(d)
+	}
+}
+
+// SetStickyWindow enables read-your-writes, the reads of a session go to the
+// master within the window after a write of the session. It's disabled when
+// window is 0.
+func (eg *EngineGroup) SetStickyWindow(window time.Duration) {
+	eg.stickyWindow = window
+}
+
+// SetPolicy set the group policy
+func (eg *EngineGroup) SetPolicy(policy GroupPolicy) *EngineGroup {
+	eg.policy = policy
+	return eg
+}
+
+// SetTableMapper set the table name mapping rule
+func (eg *EngineGroup) SetTableMapper(mapper core.IMapper) {
+	eg.Engine.SetTableMapper(mapper)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetTableMapper(mapper)
+	}
+}
+
+// SetTableMapperFor sets the mapper of the names of the struct t, see
+// Engine.SetTableMapperFor
+func (eg *EngineGroup) SetTableMapperFor(t reflect.Type, mapper core.IMapper) {
+	eg.Engine.SetTableMapperFor(t, mapper)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetTableMapperFor(t, mapper)
+	}
+}
+
+// SetTableMapperForPackage sets the mapper of the names of the structs of
+// the package pkgPath, see Engine.SetTableMapperForPackage
+func (eg *EngineGroup) SetTableMapperForPackage(pkgPath string, mapper core.IMapper) {
+	eg.Engine.SetTableMapperForPackage(pkgPath, mapper)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetTableMapperForPackage(pkgPath, mapper)
+	}
+}
+
+// ShowExecTime show SQL statement and execute time or not on logger if log level is great than INFO
+func (eg *EngineGroup) ShowExecTime(show ...bool) {
+	eg.Engine.ShowExecTime(show...)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].ShowExecTime(show...)
+	}
+}
+
+// ShowSQL show SQL statement or not on logger if log level is great than INFO
+func (eg *EngineGroup) ShowSQL(show ...bool) {
+	eg.Engine.ShowSQL(show...)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].ShowSQL(show...)
+	}
+}
+
+// Slave returns one of the healthy slaves chosen by the policy. The master
+// is returned when the group has no slave, or when all the slaves are down
+// unless SetFallbackToMaster(false) is called, then nil is returned.
+func (eg *EngineGroup) Slave() *Engine {
+	if len(eg.slaves) == 0 {
+		return eg.Engine
+	}
+
+	// the policy chooses among the same healthy slaves
+	eg.mutex.RLock()
+	slaves, noFallback := eg.healthySlaves, eg.noFallback
+	eg.mutex.RUnlock()
+	switch len(slaves) {
+	case 0:
+		if noFallback {
+			return nil
+		}
+		return eg.Engine
+	case 1:
+		return slaves[0]
+	}
+	return eg.policy.Slave(eg, slaves)
+}
+
+// Slaves returns the healthy slaves, it's all the slaves when health
+// checking is not started
+func (eg *EngineGroup) Slaves() []*Engine {
+	eg.mutex.RLock()
+	defer eg.mutex.RUnlock()
+	return eg.healthySlaves
+}
+
+// AllSlaves returns all the slaves including the unhealthy ones
+func (eg *EngineGroup) AllSlaves() []*Engine {
+	return eg.slaves
+}
+
+// SetFallbackToMaster set if reads go to the master when all the slaves are
+// down, default is true. Otherwise the reads fail with ErrNoHealthySlave.
+func (eg *EngineGroup) SetFallbackToMaster(fallback bool) {
+	eg.mutex.Lock()
+	eg.noFallback = !fallback
+	eg.mutex.Unlock()
+}
+
+// CheckHealth pings all the slaves, the unhealthy slaves are removed from
+// the read slaves and the recovered ones are added back
+func (eg *EngineGroup) CheckHealth() {
+	var healthy = make([]*Engine, 0, len(eg.slaves))
+	for _, slave := range eg.slaves {
+		if err := slave.Ping(); err != nil {
+			eg.Engine.logger.Warnf("slave %s is unhealthy: %v", slave.DataSourceName(), err)
+			continue
+		}
+		healthy = append(healthy, slave)
+	}
+
+	eg.mutex.Lock()
+	if len(healthy) != len(eg.healthySlaves) {
+		eg.Engine.logger.Infof("%d of %d slaves are healthy", len(healthy), len(eg.slaves))
+	}
+	eg.healthySlaves = healthy
+	eg.mutex.Unlock()
+}
+
+// StartHealthCheck checks the health of the slaves every interval until
+// StopHealthCheck or Close is called
+func (eg *EngineGroup) StartHealthCheck(interval time.Duration) {
+	stop := make(chan bool)
+	eg.mutex.Lock()
+	old := eg.stopHealthCheck
+	eg.stopHealthCheck = stop
+	eg.mutex.Unlock()
+	if old != nil {
+		close(old)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				eg.CheckHealth()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopHealthCheck stops the health checking started by StartHealthCheck
+func (eg *EngineGroup) StopHealthCheck() {
+	eg.mutex.Lock()
+	stop := eg.stopHealthCheck
+	eg.stopHealthCheck = nil
+	eg.mutex.Unlock()
+	// closed out of the lock, the check running meanwhile takes it
+	if stop != nil {
+		close(stop)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type routeHint int
+
+const (
+	routeDefault routeHint = iota
+	routeMaster
+	routeReplica
+)
+
+// GroupPolicy is used to choose the current slave from slaves, the healthy
+// slaves of the group when it's called, of which there are at least two
+type GroupPolicy interface {
+	Slave(eg *EngineGroup, slaves []*Engine) *Engine
+}
+
+// GroupPolicyHandler should be used when a function is a GroupPolicy
+type GroupPolicyHandler func(eg *EngineGroup, slaves []*Engine) *Engine
+
+// Slave implements GroupPolicy
+func (h GroupPolicyHandler) Slave(eg *EngineGroup, slaves []*Engine) *Engine {
+	return h(eg, slaves)
+}
+
+// RandomPolicy randomly chooses a slave
+func RandomPolicy() GroupPolicyHandler {
+	var r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	var mutex sync.Mutex
+	return func(g *EngineGroup, slaves []*Engine) *Engine {
+		mutex.Lock()
+		idx := r.Intn(len(slaves))
+		mutex.Unlock()
+		return slaves[idx]
+	}
+}
+
+// WeightRandomPolicy randomly chooses a slave, a slave with a bigger
+// weight is more likely to be chosen. The weights are the ones of the
+// slaves of the group by position, the unhealthy slaves are skipped. It's
+// RandomPolicy when the weights are empty, all zero or any negative, or
+// when the healthy slaves have no weight.
+func WeightRandomPolicy(weights []int) GroupPolicyHandler {
+	var random = RandomPolicy()
+	if !validWeights(weights) {
+		return random
+	}
+	var r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	var mutex sync.Mutex
+
+	return func(g *EngineGroup, slaves []*Engine) *Engine {
+		ws, total := slaveWeights(g, slaves, weights)
+		if total == 0 {
+			return random(g, slaves)
+		}
+		mutex.Lock()
+		n := r.Intn(total)
+		mutex.Unlock()
+		for i, w := range ws {
+			if n < w {
+				return slaves[i]
+			}
+			n -= w
+		}
+		return slaves[len(slaves)-1]
+	}
+}
+
+// slaveWeights returns the weights of slaves, the healthy slaves of g, by
+// their positions in the group and the total of the weights
+func slaveWeights(g *EngineGroup, slaves []*Engine, weights []int) ([]int, int) {
+	var ws = make([]int, len(slaves))
+	var total int
+	for i, slave := range slaves {
+		for pos, s := range g.slaves {
+			if s == slave {
+				if pos < len(weights) {
+					ws[i] = weights[pos]
+				}
+				break
+			}
+		}
+		total += ws[i]
+	}
+	return ws, total
+}
+
+// RoundRobinPolicy chooses the slaves one by one
+func RoundRobinPolicy() GroupPolicyHandler {
+	var pos = -1
+	var lock sync.Mutex
+	return func(g *EngineGroup, slaves []*Engine) *Engine {
+		lock.Lock()
+		defer lock.Unlock()
+		pos++
+		if pos >= len(slaves) {
+			pos = 0
+		}
+
+		return slaves[pos]
+	}
+}
+
+// WeightRoundRobinPolicy chooses the slaves one by one, each slave is
+// chosen as many times in a row as its weight. The weights are the ones of
+// the slaves of the group by position, the unhealthy slaves are skipped.
+// It's RoundRobinPolicy when the weights are empty, all zero or any
+// negative, or when the healthy slaves have no weight.
+func WeightRoundRobinPolicy(weights []int) GroupPolicyHandler {
+	var roundRobin = RoundRobinPolicy()
+	if !validWeights(weights) {
+		return roundRobin
+	}
+	var rands = make([]int, 0, len(weights))
+	for i := 0; i < len(weights); i++ {
+		for n := 0; n < weights[i]; n++ {
+			rands = append(rands, i)
+		}
+	}
+	var pos = -1
+	var lock sync.Mutex
+
+	return func(g *EngineGroup, slaves []*Engine) *Engine {
+		lock.Lock()
+		defer lock.Unlock()
+		for range rands {
+			pos++
+			if pos >= len(rands) {
+				pos = 0
+			}
+
+			if idx := rands[pos]; idx < len(g.slaves) && containsEngine(slaves, g.slaves[idx]) {
+				return g.slaves[idx]
+			}
+		}
+		return roundRobin(g, slaves)
+	}
+}
+
+func containsEngine(engines []*Engine, engine *Engine) bool {
+	for _, e := range engines {
+		if e == engine {
+			return true
+		}
+	}
+	return false
+}
+
+// validWeights returns true if weights has a positive weight and no
+// negative one
+func validWeights(weights []int) bool {
+	var total int
+	for _, weight := range weights {
+		if weight < 0 {
+			return false
+		}
+		total += weight
+	}
+	return total > 0
+}
+
+// LeastConnPolicy chooses the slave which has the least open connections
+func LeastConnPolicy() GroupPolicyHandler {
+	return func(g *EngineGroup, slaves []*Engine) *Engine {
+		connections := 0
+		idx := 0
+		for i := 0; i < len(slaves); i++ {
+			openConnections := slaves[i].DB().Stats().OpenConnections
+			if i == 0 {
+				connections = openConnections
+				idx = i
+			} else if openConnections <= connections {
+				connections = openConnections
+				idx = i
+			}
+		}
+		return slaves[idx]
+	}
+}
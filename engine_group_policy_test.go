@@ -0,0 +1,118 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGroup(policy GroupPolicy, n int) *EngineGroup {
+	var slaves = make([]*Engine, n)
+	for i := 0; i < n; i++ {
+		slaves[i] = new(Engine)
+	}
+	return &EngineGroup{
+		Engine:        new(Engine),
+		slaves:        slaves,
+		healthySlaves: slaves,
+		policy:        policy,
+	}
+}
+
+func TestRoundRobinPolicy(t *testing.T) {
+	eg := newTestGroup(RoundRobinPolicy(), 3)
+	for i := 0; i < 6; i++ {
+		assert.True(t, eg.Slave() == eg.slaves[i%3])
+	}
+}
+
+func TestWeightRoundRobinPolicy(t *testing.T) {
+	eg := newTestGroup(WeightRoundRobinPolicy([]int{2, 1}), 2)
+	var expected = []int{0, 0, 1, 0, 0, 1}
+	for _, idx := range expected {
+		assert.True(t, eg.Slave() == eg.slaves[idx])
+	}
+}
+
+func TestRandomPolicy(t *testing.T) {
+	eg := newTestGroup(RandomPolicy(), 3)
+	for i := 0; i < 10; i++ {
+		slave := eg.Slave()
+		assert.True(t, slave == eg.slaves[0] || slave == eg.slaves[1] || slave == eg.slaves[2])
+	}
+}
+
+func TestWeightRandomPolicy(t *testing.T) {
+	eg := newTestGroup(WeightRandomPolicy([]int{0, 1}), 2)
+	for i := 0; i < 10; i++ {
+		assert.True(t, eg.Slave() == eg.slaves[1])
+	}
+}
+
+func TestInvalidWeightPolicies(t *testing.T) {
+	for _, weights := range [][]int{nil, {0, 0}, {2, -1}} {
+		eg := newTestGroup(WeightRandomPolicy(weights), 2)
+		for i := 0; i < 4; i++ {
+			slave := eg.Slave()
+			assert.True(t, slave == eg.slaves[0] || slave == eg.slaves[1])
+		}
+
+		eg = newTestGroup(WeightRoundRobinPolicy(weights), 2)
+		for i := 0; i < 4; i++ {
+			assert.True(t, eg.Slave() == eg.slaves[i%2])
+		}
+	}
+}
+
+func TestWeightPoliciesSlaveDown(t *testing.T) {
+	// the weights are the ones of the slaves of the group, the first is down
+	eg := newTestGroup(WeightRandomPolicy([]int{1, 0, 3}), 3)
+	eg.healthySlaves = eg.slaves[1:]
+	for i := 0; i < 10; i++ {
+		assert.True(t, eg.Slave() == eg.slaves[2])
+	}
+
+	eg = newTestGroup(WeightRoundRobinPolicy([]int{2, 1, 1}), 3)
+	eg.healthySlaves = []*Engine{eg.slaves[0], eg.slaves[2]}
+	var expected = []int{0, 0, 2, 0, 0, 2}
+	for _, idx := range expected {
+		assert.True(t, eg.Slave() == eg.slaves[idx])
+	}
+
+	// the healthy slaves have no weight
+	eg = newTestGroup(WeightRoundRobinPolicy([]int{1, 0, 0}), 3)
+	eg.healthySlaves = eg.slaves[1:]
+	for i := 0; i < 4; i++ {
+		slave := eg.Slave()
+		assert.True(t, slave == eg.slaves[1] || slave == eg.slaves[2])
+	}
+}
+
+func TestPolicySlavesSnapshot(t *testing.T) {
+	// the slaves removed by a health check meanwhile are still chosen from
+	policy := RandomPolicy()
+	eg := newTestGroup(GroupPolicyHandler(func(g *EngineGroup, slaves []*Engine) *Engine {
+		g.mutex.Lock()
+		g.healthySlaves = nil
+		g.mutex.Unlock()
+		return policy(g, slaves)
+	}), 2)
+	slave := eg.Slave()
+	assert.True(t, slave == eg.slaves[0] || slave == eg.slaves[1])
+	assert.True(t, eg.Slave() == eg.Master())
+}
+
+func TestLeastConnPolicy(t *testing.T) {
+	slaves := []*Engine{testEngine, testEngine}
+	eg := &EngineGroup{
+		Engine:        testEngine,
+		slaves:        slaves,
+		healthySlaves: slaves,
+		policy:        LeastConnPolicy(),
+	}
+	assert.True(t, eg.Slave() == testEngine)
+}
@@ -0,0 +1,227 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineGroup(t *testing.T) {
+	if dbType != "sqlite3" {
+		t.Skip("engine group test needs two separated sqlite3 databases")
+	}
+
+	defer os.Remove("./test_group_master.db")
+	defer os.Remove("./test_group_slave.db")
+
+	eg, err := NewEngineGroup("sqlite3", []string{
+		"./test_group_master.db",
+		"./test_group_slave.db",
+	})
+	assert.NoError(t, err)
+	defer eg.Close()
+
+	assert.NoError(t, eg.Ping())
+	assert.EqualValues(t, 1, len(eg.Slaves()))
+	assert.True(t, eg.Slave() == eg.Slaves()[0])
+
+	type GroupUser struct {
+		Id   int64
+		Name string
+	}
+
+	assert.NoError(t, eg.Sync2(new(GroupUser)))
+	assert.NoError(t, eg.Slave().Sync2(new(GroupUser)))
+
+	cnt, err := eg.Insert(&GroupUser{Name: "master"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	// reads go to the slave which has no data
+	total, err := eg.Count(new(GroupUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, total)
+
+	var users []GroupUser
+	assert.NoError(t, eg.Find(&users))
+	assert.EqualValues(t, 0, len(users))
+
+	has, err := eg.Get(new(GroupUser))
+	assert.NoError(t, err)
+	assert.False(t, has)
+
+	results, err := eg.QueryString("select * from group_user")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, len(results))
+
+	// UseMaster forces reads to the master
+	total, err = eg.UseMaster().Count(new(GroupUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+
+	sess := eg.NewSession()
+	defer sess.Close()
+
+	sess.UseMaster()
+	has, err = sess.Get(new(GroupUser))
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	users = make([]GroupUser, 0)
+	assert.NoError(t, sess.Find(&users))
+	assert.EqualValues(t, 1, len(users))
+
+	// transactions always go to the master
+	sess2 := eg.NewSession()
+	defer sess2.Close()
+
+	assert.NoError(t, sess2.Begin())
+	total, err = sess2.Count(new(GroupUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.NoError(t, sess2.Commit())
+}
+
+func TestNewEngineGroupArgs(t *testing.T) {
+	_, err := NewEngineGroup("sqlite3", []string{})
+	assert.Error(t, err)
+
+	_, err = NewEngineGroup(1, 2)
+	assert.Error(t, err)
+
+	eg, err := NewEngineGroup(testEngine, []*Engine{})
+	assert.NoError(t, err)
+	defer func() {
+		testEngine.group = nil
+	}()
+
+	assert.True(t, eg.Master() == testEngine)
+	assert.True(t, eg.Slave() == testEngine)
+}
+
+func TestEngineGroupHealthCheck(t *testing.T) {
+	if dbType != "sqlite3" {
+		t.Skip("engine group test needs separated sqlite3 databases")
+	}
+
+	defer os.Remove("./test_health_master.db")
+	defer os.Remove("./test_health_slave1.db")
+	defer os.Remove("./test_health_slave2.db")
+
+	eg, err := NewEngineGroup("sqlite3", []string{
+		"./test_health_master.db",
+		"./test_health_slave1.db",
+		"./test_health_slave2.db",
+	})
+	assert.NoError(t, err)
+	defer eg.Master().Close()
+
+	eg.StartHealthCheck(time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.EqualValues(t, 2, len(eg.Slaves()))
+
+	assert.NoError(t, eg.AllSlaves()[1].Close())
+	eg.CheckHealth()
+	assert.EqualValues(t, 1, len(eg.Slaves()))
+	assert.True(t, eg.Slave() == eg.AllSlaves()[0])
+
+	assert.NoError(t, eg.AllSlaves()[0].Close())
+	eg.StopHealthCheck()
+	eg.CheckHealth()
+	assert.EqualValues(t, 0, len(eg.Slaves()))
+	assert.EqualValues(t, 2, len(eg.AllSlaves()))
+
+	// all slaves are down, reads fall back to master
+	assert.True(t, eg.Slave() == eg.Master())
+	_, err = eg.QueryString("select 1")
+	assert.NoError(t, err)
+
+	eg.SetFallbackToMaster(false)
+	assert.Nil(t, eg.Slave())
+	_, err = eg.QueryString("select 1")
+	assert.EqualValues(t, ErrNoHealthySlave, err)
+
+	_, err = eg.UseMaster().QueryString("select 1")
+	assert.NoError(t, err)
+}
+
+func TestEngineGroupRouting(t *testing.T) {
+	if dbType != "sqlite3" {
+		t.Skip("engine group test needs separated sqlite3 databases")
+	}
+
+	defer os.Remove("./test_route_master.db")
+	defer os.Remove("./test_route_slave.db")
+
+	eg, err := NewEngineGroup("sqlite3", []string{
+		"./test_route_master.db",
+		"./test_route_slave.db",
+	})
+	assert.NoError(t, err)
+	defer eg.Close()
+
+	type RouteUser struct {
+		Id   int64
+		Name string
+	}
+	assert.NoError(t, eg.Sync2(new(RouteUser)))
+	assert.NoError(t, eg.Slave().Sync2(new(RouteUser)))
+
+	_, err = eg.Insert(&RouteUser{Name: "master"})
+	assert.NoError(t, err)
+
+	// hints only apply to the next query
+	sess := eg.NewSession()
+	defer sess.Close()
+
+	total, err := sess.ForceMaster().Count(new(RouteUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	total, err = sess.Count(new(RouteUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, total)
+
+	sess.UseMaster()
+	total, err = sess.PreferReplica().Count(new(RouteUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, total)
+	total, err = sess.Count(new(RouteUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+
+	// read-your-writes after a write of the session
+	eg.SetStickyWindow(time.Hour)
+	sess2 := eg.NewSession()
+	defer sess2.Close()
+
+	total, err = sess2.Count(new(RouteUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, total)
+
+	_, err = sess2.Insert(&RouteUser{Name: "sticky"})
+	assert.NoError(t, err)
+	total, err = sess2.Count(new(RouteUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+
+	// other sessions are not pinned
+	total, err = eg.Count(new(RouteUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, total)
+
+	eg.SetStickyWindow(time.Nanosecond)
+	sess3 := eg.NewSession()
+	defer sess3.Close()
+	_, err = sess3.Insert(&RouteUser{Name: "expired"})
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	total, err = sess3.Count(new(RouteUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, total)
+}
@@ -0,0 +1,88 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// PoolStats is the statistics of the connection pool of an engine
+type PoolStats struct {
+	sql.DBStats
+
+	// ExhaustedCount is the number of statements and transactions of the
+	// engine which were started when all the connections were in use, and
+	// so had to wait for a connection
+	ExhaustedCount int64
+}
+
+type poolMonitor struct {
+	exhausted int64 // keep first for the alignment of atomic operations
+
+	mutex   sync.RWMutex
+	handler func(PoolStats)
+}
+
+// getPoolMonitor returns the pool monitor of the engine, created on first
+// use so the engines not created by NewEngine have one too
+func (engine *Engine) getPoolMonitor() *poolMonitor {
+	engine.poolOnce.Do(func() {
+		engine.pool = new(poolMonitor)
+	})
+	return engine.pool
+}
+
+// SetConnMaxLifetime sets the maximum amount of time a connection may be reused
+func (engine *Engine) SetConnMaxLifetime(d time.Duration) {
+	engine.db.SetConnMaxLifetime(d)
+}
+
+// SetConnMaxIdleTime sets the maximum amount of time a connection may be idle
+func (engine *Engine) SetConnMaxIdleTime(d time.Duration) {
+	engine.db.SetConnMaxIdleTime(d)
+}
+
+// SetPoolExhaustedHandler sets a handler called with the pool statistics
+// when a statement or transaction is started while all the connections
+// are in use, the statistics are the ones of the exhausted pool, which is a
+// slave's for the reads of an engine group. It only works for the pools
+// whose max open connections is set by SetMaxOpenConns.
+func (engine *Engine) SetPoolExhaustedHandler(handler func(PoolStats)) {
+	pool := engine.getPoolMonitor()
+	pool.mutex.Lock()
+	pool.handler = handler
+	pool.mutex.Unlock()
+}
+
+// PoolStats returns the statistics of the connection pool
+func (engine *Engine) PoolStats() PoolStats {
+	return PoolStats{
+		DBStats:        engine.db.Stats(),
+		ExhaustedCount: atomic.LoadInt64(&engine.getPoolMonitor().exhausted),
+	}
+}
+
+// checkPool counts and reports the pool exhaustion before a connection is
+// acquired from db, the database of the engine or of a slave of its group
+func (engine *Engine) checkPool(db *core.DB) {
+	stats := db.Stats()
+	if stats.MaxOpenConnections <= 0 || stats.InUse < stats.MaxOpenConnections {
+		return
+	}
+
+	pool := engine.getPoolMonitor()
+	exhausted := atomic.AddInt64(&pool.exhausted, 1)
+	pool.mutex.RLock()
+	handler := pool.handler
+	pool.mutex.RUnlock()
+	if handler != nil {
+		handler(PoolStats{stats, exhausted})
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolStats(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	type PoolUser struct {
+		Id   int64
+		Name string
+	}
+	assert.NoError(t, testEngine.Sync2(new(PoolUser)))
+
+	testEngine.SetConnMaxLifetime(time.Hour)
+	testEngine.SetConnMaxIdleTime(time.Hour)
+	testEngine.SetMaxOpenConns(1)
+	defer testEngine.SetMaxOpenConns(0)
+
+	var exhausted = make(chan PoolStats, 1)
+	testEngine.SetPoolExhaustedHandler(func(stats PoolStats) {
+		exhausted <- stats
+	})
+	defer testEngine.SetPoolExhaustedHandler(nil)
+
+	stats := testEngine.PoolStats()
+	assert.EqualValues(t, 1, stats.MaxOpenConnections)
+	before := stats.ExhaustedCount
+
+	sess := testEngine.NewSession()
+	defer sess.Close()
+	assert.NoError(t, sess.Begin())
+
+	var done = make(chan error)
+	go func() {
+		_, err := testEngine.Count(new(PoolUser))
+		done <- err
+	}()
+
+	select {
+	case stats = <-exhausted:
+		assert.EqualValues(t, 1, stats.InUse)
+	case <-time.After(5 * time.Second):
+		t.Error("pool exhausted handler is not called")
+	}
+
+	assert.NoError(t, sess.Commit())
+	assert.NoError(t, <-done)
+	assert.EqualValues(t, before+1, testEngine.PoolStats().ExhaustedCount)
+}
+
+func TestPoolMonitorSlave(t *testing.T) {
+	if dbType != "sqlite3" {
+		t.Skip("engine group test needs two separated sqlite3 databases")
+	}
+
+	defer os.Remove("./test_pool_master.db")
+	defer os.Remove("./test_pool_slave.db")
+
+	eg, err := NewEngineGroup("sqlite3", []string{
+		"./test_pool_master.db",
+		"./test_pool_slave.db",
+	})
+	assert.NoError(t, err)
+	defer eg.Close()
+
+	type PoolSlaveUser struct {
+		Id   int64
+		Name string
+	}
+	assert.NoError(t, eg.Slave().Sync2(new(PoolSlaveUser)))
+
+	// only the pool of the slave is limited and in use
+	eg.Slave().SetMaxOpenConns(1)
+	conn, err := eg.Slave().DB().Conn(context.Background())
+	assert.NoError(t, err)
+
+	var exhausted = make(chan PoolStats, 1)
+	eg.SetPoolExhaustedHandler(func(stats PoolStats) {
+		exhausted <- stats
+	})
+
+	var done = make(chan error)
+	go func() {
+		_, err := eg.Count(new(PoolSlaveUser))
+		done <- err
+	}()
+
+	select {
+	case stats := <-exhausted:
+		assert.EqualValues(t, 1, stats.MaxOpenConnections)
+		assert.EqualValues(t, 1, stats.InUse)
+	case <-time.After(5 * time.Second):
+		t.Error("pool exhausted handler is not called for the slave")
+	}
+
+	assert.NoError(t, conn.Close())
+	assert.NoError(t, <-done)
+}
+
+func TestPoolMonitorLazy(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	// an engine not created by NewEngine
+	engine := &Engine{db: testEngine.DB()}
+	engine.SetPoolExhaustedHandler(func(PoolStats) {})
+	engine.checkPool(engine.DB())
+	assert.EqualValues(t, 0, engine.PoolStats().ExhaustedCount)
+}
+
+func TestPoolMonitorConcurrent(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	defer testEngine.SetMaxOpenConns(0)
+	defer testEngine.SetPoolExhaustedHandler(nil)
+
+	// run with -race, the settings change while the pool is checked
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				testEngine.SetMaxOpenConns(i + j%2)
+				testEngine.SetPoolExhaustedHandler(func(PoolStats) {})
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				testEngine.checkPool(testEngine.DB())
+			}
+		}()
+	}
+	wg.Wait()
+}
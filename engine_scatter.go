@@ -0,0 +1,156 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ScatterFind queries the table of the beans of rowsSlicePtr on all the
+// engines concurrently, at most workers at once or all of them when 0, and
+// merges the results as ShardEngine.Find. The engines should hold distinct
+// records, as the databases of the partitions of a table, unlike the
+// replicas of an EngineGroup.
+func ScatterFind(ctx context.Context, engines []*Engine, workers int, rowsSlicePtr interface{}, query ShardQuery, condiBean ...interface{}) error {
+	if len(engines) == 0 {
+		return errors.New("At least one engine is required")
+	}
+	if _, err := sliceElemBean(rowsSlicePtr); err != nil {
+		return err
+	}
+	sessions := make([]*Session, len(engines))
+	for i, engine := range engines {
+		sessions[i] = engine.NewSession()
+	}
+	return scatterFind(ctx, workers, sessions, rowsSlicePtr, query, condiBean)
+}
+
+// sliceElemBean returns a new bean of the elements of the slice of
+// rowsSlicePtr
+func sliceElemBean(rowsSlicePtr interface{}) (interface{}, error) {
+	ptrValue := reflect.ValueOf(rowsSlicePtr)
+	if ptrValue.Kind() != reflect.Ptr || ptrValue.Elem().Kind() != reflect.Slice {
+		return nil, errors.New("needs a pointer to a slice")
+	}
+	elemType := ptrValue.Elem().Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	return reflect.New(elemType).Interface(), nil
+}
+
+// scatterFind runs the Find of query on the sessions concurrently, closes
+// them and merges their results into rowsSlicePtr: the orders and the
+// limit of the first session are applied on the merged results
+func scatterFind(ctx context.Context, workers int, sessions []*Session, rowsSlicePtr interface{}, query ShardQuery, condiBean []interface{}) error {
+	defer func() {
+		for _, session := range sessions {
+			session.Close()
+		}
+	}()
+
+	sliceValue := reflect.Indirect(reflect.ValueOf(rowsSlicePtr))
+	bean, err := sliceElemBean(rowsSlicePtr)
+	if err != nil {
+		return err
+	}
+	table, err := sessions[0].Engine.autoMapType(reflect.Indirect(reflect.ValueOf(bean)))
+	if err != nil {
+		return err
+	}
+
+	var orders []shardOrder
+	var start, limit int
+	for i, session := range sessions {
+		if query != nil {
+			query(session)
+		}
+		if i == 0 {
+			start, limit = session.Statement.Start, session.Statement.LimitN
+			orders, err = parseShardOrders(table, session.Statement.OrderStr)
+			if err != nil {
+				return err
+			}
+		}
+		// every session should return enough records for the merged page
+		if limit > 0 {
+			session.Limit(start+limit, 0)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if workers <= 0 || workers > len(sessions) {
+		workers = len(sessions)
+	}
+
+	var (
+		partials = make([]reflect.Value, len(sessions))
+		next     = make(chan int)
+		wg       sync.WaitGroup
+		errMutex sync.Mutex
+		firstErr error
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				partial := reflect.New(sliceValue.Type())
+				if err := sessions[i].Context(ctx).Find(partial.Interface(), condiBean...); err != nil {
+					errMutex.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errMutex.Unlock()
+					cancel()
+					continue
+				}
+				partials[i] = partial.Elem()
+			}
+		}()
+	}
+feed:
+	for i := range sessions {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	var isPointer = sliceValue.Type().Elem().Kind() == reflect.Ptr
+	var results = reflect.MakeSlice(sliceValue.Type(), 0, 0)
+	for _, partial := range partials {
+		if !partial.IsValid() {
+			// not queried before the end of ctx
+			return ctx.Err()
+		}
+		results = reflect.AppendSlice(results, partial)
+	}
+	if len(orders) > 0 {
+		sort.Stable(&shardSorter{results, orders, isPointer})
+	}
+
+	if start > results.Len() {
+		start = results.Len()
+	}
+	end := results.Len()
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+	sliceValue.Set(reflect.AppendSlice(sliceValue, results.Slice(start, end)))
+	return nil
+}
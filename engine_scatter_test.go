@@ -0,0 +1,98 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ScatterOrder struct {
+	Id     int64
+	UserId int64 `xorm:"index"`
+	Amount int
+}
+
+func TestShardFindContext(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	se, err := NewShardEngine(testEngine)
+	assert.NoError(t, err)
+	assert.NoError(t, se.SetShardRule(new(ScatterOrder), "user_id", ModShardResolver(1, 4)))
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, testEngine.Table("scatter_order_"+string(rune('0'+i))).DropTable(new(ScatterOrder)))
+	}
+	assert.NoError(t, se.CreateTables(new(ScatterOrder)))
+
+	var orders []ScatterOrder
+	for i := 1; i <= 8; i++ {
+		orders = append(orders, ScatterOrder{UserId: int64(i), Amount: i * 10})
+	}
+	_, err = se.Insert(orders)
+	assert.NoError(t, err)
+
+	se.SetScatterWorkers(2)
+	var found []*ScatterOrder
+	err = se.FindContext(context.Background(), &found, func(session *Session) *Session {
+		return session.Where("amount > ?", 20).Desc("amount").Limit(3, 1)
+	})
+	assert.NoError(t, err)
+	if assert.EqualValues(t, 3, len(found)) {
+		assert.EqualValues(t, 70, found[0].Amount)
+		assert.EqualValues(t, 60, found[1].Amount)
+		assert.EqualValues(t, 50, found[2].Amount)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	found = nil
+	assert.Error(t, se.FindContext(ctx, &found, nil))
+	assert.EqualValues(t, 0, len(found))
+
+	// the first error is returned
+	err = se.FindContext(context.Background(), &found, func(session *Session) *Session {
+		return session.Where("unknown = ?", 1)
+	})
+	assert.Error(t, err)
+}
+
+func TestScatterFind(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(ScatterOrder))
+
+	_, err := testEngine.Insert(&ScatterOrder{UserId: 1, Amount: 10}, &ScatterOrder{UserId: 2, Amount: 20})
+	assert.NoError(t, err)
+
+	// the same database twice, its records are merged twice
+	var orders []ScatterOrder
+	err = ScatterFind(context.Background(), []*Engine{testEngine, testEngine}, 0, &orders, func(session *Session) *Session {
+		return session.Asc("amount")
+	})
+	assert.NoError(t, err)
+	if assert.EqualValues(t, 4, len(orders)) {
+		assert.EqualValues(t, 10, orders[0].Amount)
+		assert.EqualValues(t, 10, orders[1].Amount)
+		assert.EqualValues(t, 20, orders[3].Amount)
+	}
+
+	assert.Error(t, ScatterFind(context.Background(), nil, 0, &orders, nil))
+	assert.Error(t, ScatterFind(context.Background(), []*Engine{testEngine}, 0, orders, nil))
+}
+
+func TestScatterQueryContext(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(ScatterOrder)))
+
+	// the queries of the shards are run with their context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var orders []ScatterOrder
+	assert.Error(t, testEngine.Context(ctx).Find(&orders))
+
+	_, err := testEngine.Context(ctx).QueryString("SELECT 1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,467 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// ShardQuery is applied on the session of every shard before a scatter
+// query, it could be used to add conditions, orders and limits.
+type ShardQuery func(*Session) *Session
+
+type shardRule struct {
+	column   string
+	resolver ShardResolver
+}
+
+// ShardEngine routes the beans of sharded tables to one of its engines and
+// table suffixes according to the value of the bean's shard key. Tables
+// without shard rule are not supported.
+type ShardEngine struct {
+	engines        []*Engine
+	rules          map[reflect.Type]*shardRule
+	mutex          sync.RWMutex
+	scatterWorkers int
+}
+
+// NewShardEngine creates a shard engine on the engines, the index of an
+// engine is the Engine of the Shard returned by resolvers
+func NewShardEngine(engines ...*Engine) (*ShardEngine, error) {
+	if len(engines) == 0 {
+		return nil, errors.New("At least one engine is required")
+	}
+	return &ShardEngine{
+		engines: engines,
+		rules:   make(map[reflect.Type]*shardRule),
+	}, nil
+}
+
+// Engines returns all the engines of shards
+func (se *ShardEngine) Engines() []*Engine {
+	return se.engines
+}
+
+// SetShardRule declares bean's table is sharded by the column, and the
+// resolver maps a column value to a shard
+func (se *ShardEngine) SetShardRule(bean interface{}, column string, resolver ShardResolver) error {
+	v := rValue(bean)
+	table, err := se.engines[0].autoMapType(v)
+	if err != nil {
+		return err
+	}
+	if table.GetColumn(column) == nil {
+		return fmt.Errorf("Unknown shard column %s of table %s", column, table.Name)
+	}
+
+	se.mutex.Lock()
+	se.rules[v.Type()] = &shardRule{column, resolver}
+	se.mutex.Unlock()
+	return nil
+}
+
+func (se *ShardEngine) rule(v reflect.Value) (*shardRule, error) {
+	se.mutex.RLock()
+	rule, ok := se.rules[v.Type()]
+	se.mutex.RUnlock()
+	if !ok {
+		return nil, ErrShardRuleNotFound
+	}
+	return rule, nil
+}
+
+// shardOf returns the shard of a bean's shard key, hasKey is false when the
+// bean's shard key is zero
+func (se *ShardEngine) shardOf(bean interface{}) (shard Shard, hasKey bool, err error) {
+	v := rValue(bean)
+	rule, err := se.rule(v)
+	if err != nil {
+		return shard, false, err
+	}
+
+	table, err := se.engines[0].autoMapType(v)
+	if err != nil {
+		return shard, false, err
+	}
+	fieldValue, err := table.GetColumn(rule.column).ValueOf(bean)
+	if err != nil {
+		return shard, false, err
+	}
+	key := fieldValue.Interface()
+	if isZero(key) {
+		return shard, false, nil
+	}
+
+	shard, err = rule.resolver.Resolve(key)
+	if err != nil {
+		return shard, true, err
+	}
+	if shard.Engine < 0 || shard.Engine >= len(se.engines) {
+		return shard, true, fmt.Errorf("Shard engine index %d is out of range", shard.Engine)
+	}
+	return shard, true, nil
+}
+
+// shards returns all the shards of a bean's table
+func (se *ShardEngine) shards(bean interface{}) ([]Shard, error) {
+	rule, err := se.rule(rValue(bean))
+	if err != nil {
+		return nil, err
+	}
+	return rule.resolver.Shards(), nil
+}
+
+// newSession returns a session working on the shard's table
+func (se *ShardEngine) newSession(bean interface{}, shard Shard) *Session {
+	engine := se.engines[shard.Engine]
+	session := engine.NewSession()
+	session.Table(engine.tbName(rValue(bean)) + shard.Suffix)
+	return session
+}
+
+// CreateTables creates the tables, indexes and uniques of beans on all the
+// shards if the tables don't exist
+func (se *ShardEngine) CreateTables(beans ...interface{}) error {
+	for _, bean := range beans {
+		shards, err := se.shards(bean)
+		if err != nil {
+			return err
+		}
+		for _, shard := range shards {
+			engine := se.engines[shard.Engine]
+			tableName := engine.tbName(rValue(bean)) + shard.Suffix
+			exist, err := engine.IsTableExist(tableName)
+			if err != nil {
+				return err
+			}
+			if exist {
+				continue
+			}
+
+			session := engine.NewSession()
+			err = session.Table(tableName).CreateTable(bean)
+			if err == nil {
+				err = session.Table(tableName).CreateIndexes(bean)
+			}
+			if err == nil {
+				err = session.Table(tableName).CreateUniques(bean)
+			}
+			session.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Insert inserts beans to their shards, a bean could be a struct pointer
+// or a slice of structs or struct pointers
+func (se *ShardEngine) Insert(beans ...interface{}) (int64, error) {
+	var affected int64
+	for _, bean := range beans {
+		sliceValue := reflect.Indirect(reflect.ValueOf(bean))
+		if sliceValue.Kind() != reflect.Slice {
+			cnt, err := se.insertOne(bean)
+			if err != nil {
+				return affected, err
+			}
+			affected += cnt
+			continue
+		}
+
+		for i := 0; i < sliceValue.Len(); i++ {
+			elem := sliceValue.Index(i)
+			if elem.Kind() != reflect.Ptr {
+				elem = elem.Addr()
+			}
+			cnt, err := se.insertOne(elem.Interface())
+			if err != nil {
+				return affected, err
+			}
+			affected += cnt
+		}
+	}
+	return affected, nil
+}
+
+func (se *ShardEngine) insertOne(bean interface{}) (int64, error) {
+	shard, hasKey, err := se.shardOf(bean)
+	if err != nil {
+		return 0, err
+	}
+	if !hasKey {
+		return 0, ErrShardKeyMissing
+	}
+
+	session := se.newSession(bean, shard)
+	defer session.Close()
+	return session.InsertOne(bean)
+}
+
+// Get retrieves one record from the shard of bean's shard key. When the
+// shard key is not set, the shards are queried one by one until a record
+// is found.
+func (se *ShardEngine) Get(bean interface{}) (bool, error) {
+	shard, hasKey, err := se.shardOf(bean)
+	if err != nil {
+		return false, err
+	}
+
+	shards := []Shard{shard}
+	if !hasKey {
+		shards, err = se.shards(bean)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for _, shard := range shards {
+		session := se.newSession(bean, shard)
+		has, err := session.Get(bean)
+		session.Close()
+		if err != nil || has {
+			return has, err
+		}
+	}
+	return false, nil
+}
+
+// Update updates the records of the shard of the shard key, which is
+// taken from the first condiBean or the bean
+func (se *ShardEngine) Update(bean interface{}, condiBean ...interface{}) (int64, error) {
+	keyBean := bean
+	if len(condiBean) > 0 {
+		keyBean = condiBean[0]
+	}
+	shard, hasKey, err := se.shardOf(keyBean)
+	if err != nil {
+		return 0, err
+	}
+	if !hasKey {
+		return 0, ErrShardKeyMissing
+	}
+
+	session := se.newSession(bean, shard)
+	defer session.Close()
+	return session.Update(bean, condiBean...)
+}
+
+// Delete deletes the records of the shard of bean's shard key
+func (se *ShardEngine) Delete(bean interface{}) (int64, error) {
+	shard, hasKey, err := se.shardOf(bean)
+	if err != nil {
+		return 0, err
+	}
+	if !hasKey {
+		return 0, ErrShardKeyMissing
+	}
+
+	session := se.newSession(bean, shard)
+	defer session.Close()
+	return session.Delete(bean)
+}
+
+// Count counts the records of bean's table on all the shards
+func (se *ShardEngine) Count(bean interface{}, query ShardQuery) (int64, error) {
+	shards, err := se.shards(bean)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, shard := range shards {
+		session := se.newSession(bean, shard)
+		if query != nil {
+			query(session)
+		}
+		cnt, err := session.Count(bean)
+		session.Close()
+		if err != nil {
+			return 0, err
+		}
+		total += cnt
+	}
+	return total, nil
+}
+
+// Find queries all the shards and merges the results into rowsSlicePtr.
+// The orders and limits set by query are applied on the merged results,
+// orders could only refer to the columns of the table.
+func (se *ShardEngine) Find(rowsSlicePtr interface{}, query ShardQuery, condiBean ...interface{}) error {
+	return se.FindContext(context.Background(), rowsSlicePtr, query, condiBean...)
+}
+
+// FindContext is Find querying the shards concurrently, at most
+// SetScatterWorkers at once, the first error or the end of ctx cancels the
+// queries of the other shards
+func (se *ShardEngine) FindContext(ctx context.Context, rowsSlicePtr interface{}, query ShardQuery, condiBean ...interface{}) error {
+	bean, err := sliceElemBean(rowsSlicePtr)
+	if err != nil {
+		return err
+	}
+	shards, err := se.shards(bean)
+	if err != nil {
+		return err
+	}
+
+	sessions := make([]*Session, len(shards))
+	for i, shard := range shards {
+		sessions[i] = se.newSession(bean, shard)
+	}
+	return scatterFind(ctx, se.scatterWorkers, sessions, rowsSlicePtr, query, condiBean)
+}
+
+// SetScatterWorkers sets the max number of the shards queried at once by
+// FindContext, all of them when 0, the default
+func (se *ShardEngine) SetScatterWorkers(workers int) {
+	se.scatterWorkers = workers
+}
+
+type shardOrder struct {
+	col  *core.Column
+	desc bool
+}
+
+func parseShardOrders(table *core.Table, orderStr string) ([]shardOrder, error) {
+	if len(orderStr) == 0 {
+		return nil, nil
+	}
+
+	var orders []shardOrder
+	for _, part := range strings.Split(orderStr, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+
+		colName := strings.Trim(fields[0], "`\"[]")
+		if idx := strings.LastIndex(colName, "."); idx > -1 {
+			colName = strings.Trim(colName[idx+1:], "`\"[]")
+		}
+		col := table.GetColumn(colName)
+		if col == nil {
+			return nil, fmt.Errorf("Unsupported order %s for sharded tables", part)
+		}
+		orders = append(orders, shardOrder{
+			col:  col,
+			desc: len(fields) > 1 && strings.EqualFold(fields[1], "desc"),
+		})
+	}
+	return orders, nil
+}
+
+type shardSorter struct {
+	rows      reflect.Value
+	orders    []shardOrder
+	isPointer bool
+}
+
+func (s *shardSorter) Len() int {
+	return s.rows.Len()
+}
+
+func (s *shardSorter) Swap(i, j int) {
+	a, b := s.rows.Index(i), s.rows.Index(j)
+	tmp := reflect.New(a.Type()).Elem()
+	tmp.Set(a)
+	a.Set(b)
+	b.Set(tmp)
+}
+
+func (s *shardSorter) Less(i, j int) bool {
+	a, b := s.rows.Index(i), s.rows.Index(j)
+	if !s.isPointer {
+		a, b = a.Addr(), b.Addr()
+	}
+	for _, order := range s.orders {
+		va, err := order.col.ValueOf(a.Interface())
+		if err != nil {
+			return false
+		}
+		vb, err := order.col.ValueOf(b.Interface())
+		if err != nil {
+			return false
+		}
+		c := compareValues(*va, *vb)
+		if c == 0 {
+			continue
+		}
+		if order.desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+// compareValues returns -1, 0 or 1 when a is less than, equal to or
+// greater than b, nil pointers are less than any other values
+func compareValues(a, b reflect.Value) int {
+	if a.Kind() == reflect.Ptr {
+		switch {
+		case a.IsNil() && b.IsNil():
+			return 0
+		case a.IsNil():
+			return -1
+		case b.IsNil():
+			return 1
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch {
+		case a.Int() < b.Int():
+			return -1
+		case a.Int() > b.Int():
+			return 1
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch {
+		case a.Uint() < b.Uint():
+			return -1
+		case a.Uint() > b.Uint():
+			return 1
+		}
+	case reflect.Float32, reflect.Float64:
+		switch {
+		case a.Float() < b.Float():
+			return -1
+		case a.Float() > b.Float():
+			return 1
+		}
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Bool:
+		if a.Bool() != b.Bool() {
+			if b.Bool() {
+				return -1
+			}
+			return 1
+		}
+	case reflect.Struct:
+		if a.Type().ConvertibleTo(core.TimeType) {
+			ta := a.Convert(core.TimeType).Interface().(time.Time)
+			tb := b.Convert(core.TimeType).Interface().(time.Time)
+			switch {
+			case ta.Before(tb):
+				return -1
+			case ta.After(tb):
+				return 1
+			}
+		}
+	}
+	return 0
+}
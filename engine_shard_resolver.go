@@ -0,0 +1,76 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"hash/crc32"
+	"reflect"
+)
+
+// Shard is where the records of a shard key are stored
+type Shard struct {
+	Engine int    // the index of the engine in ShardEngine
+	Suffix string // appended to the table name
+}
+
+// ShardResolver maps a shard key to one of the shards
+type ShardResolver interface {
+	// Resolve returns the shard of a shard key value
+	Resolve(key interface{}) (Shard, error)
+	// Shards returns all the shards which scatter queries go to
+	Shards() []Shard
+}
+
+type modShardResolver struct {
+	engines int
+	tables  int
+}
+
+// ModShardResolver distributes the shard keys to engines*tables shards by
+// modulo. Integer keys are used as is and string keys are hashed. Tables
+// are suffixed with "_0", "_1"... when tables is greater than 1.
+func ModShardResolver(engines, tables int) ShardResolver {
+	if engines < 1 {
+		engines = 1
+	}
+	if tables < 1 {
+		tables = 1
+	}
+	return &modShardResolver{engines, tables}
+}
+
+func (r *modShardResolver) shard(n uint64) Shard {
+	var shard = Shard{Engine: int(n % uint64(r.engines))}
+	if r.tables > 1 {
+		shard.Suffix = fmt.Sprintf("_%d", n/uint64(r.engines)%uint64(r.tables))
+	}
+	return shard
+}
+
+func (r *modShardResolver) Resolve(key interface{}) (Shard, error) {
+	v := reflect.ValueOf(key)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := v.Int()
+		if n < 0 {
+			n = -n
+		}
+		return r.shard(uint64(n)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return r.shard(v.Uint()), nil
+	case reflect.String:
+		return r.shard(uint64(crc32.ChecksumIEEE([]byte(v.String())))), nil
+	}
+	return Shard{}, fmt.Errorf("Unsupported shard key type %v", v.Type())
+}
+
+func (r *modShardResolver) Shards() []Shard {
+	var shards = make([]Shard, 0, r.engines*r.tables)
+	for i := 0; i < r.engines*r.tables; i++ {
+		shards = append(shards, r.shard(uint64(i)))
+	}
+	return shards
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModShardResolver(t *testing.T) {
+	resolver := ModShardResolver(2, 2)
+	assert.EqualValues(t, 4, len(resolver.Shards()))
+
+	shard, err := resolver.Resolve(int64(5))
+	assert.NoError(t, err)
+	assert.EqualValues(t, Shard{1, "_0"}, shard)
+
+	shard, err = resolver.Resolve(uint(7))
+	assert.NoError(t, err)
+	assert.EqualValues(t, Shard{1, "_1"}, shard)
+
+	_, err = resolver.Resolve("tenant")
+	assert.NoError(t, err)
+
+	_, err = resolver.Resolve(1.5)
+	assert.Error(t, err)
+
+	shards := ModShardResolver(1, 1).Shards()
+	assert.EqualValues(t, []Shard{{0, ""}}, shards)
+}
+
+func TestShardEngine(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	type ShardOrder struct {
+		Id     int64
+		UserId int64 `xorm:"index"`
+		Amount int
+	}
+
+	se, err := NewShardEngine(testEngine)
+	assert.NoError(t, err)
+
+	_, err = se.Insert(&ShardOrder{UserId: 1})
+	assert.EqualValues(t, ErrShardRuleNotFound, err)
+
+	assert.Error(t, se.SetShardRule(new(ShardOrder), "unknown", ModShardResolver(1, 2)))
+	assert.NoError(t, se.SetShardRule(new(ShardOrder), "user_id", ModShardResolver(1, 2)))
+	assert.NoError(t, se.CreateTables(new(ShardOrder)))
+	// creating existing tables is a no-op
+	assert.NoError(t, se.CreateTables(new(ShardOrder)))
+
+	_, err = se.Insert(&ShardOrder{Amount: 1})
+	assert.EqualValues(t, ErrShardKeyMissing, err)
+
+	cnt, err := se.Insert([]ShardOrder{
+		{UserId: 1, Amount: 10},
+		{UserId: 2, Amount: 20},
+		{UserId: 3, Amount: 30},
+	}, &ShardOrder{UserId: 4, Amount: 40})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, cnt)
+
+	// every table only contains its own shard keys
+	total, err := testEngine.Table("shard_order_0").Count(new(ShardOrder))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+
+	total, err = se.Count(new(ShardOrder), nil)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, total)
+
+	var order = ShardOrder{UserId: 3}
+	has, err := se.Get(&order)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 30, order.Amount)
+
+	order = ShardOrder{Amount: 40}
+	has, err = se.Get(&order)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 4, order.UserId)
+
+	cnt, err = se.Update(&ShardOrder{Amount: 31}, &ShardOrder{UserId: 3})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var orders []ShardOrder
+	err = se.Find(&orders, func(session *Session) *Session {
+		return session.Desc("amount").Limit(2, 1)
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, len(orders))
+	assert.EqualValues(t, 31, orders[0].Amount)
+	assert.EqualValues(t, 20, orders[1].Amount)
+
+	var orderPtrs []*ShardOrder
+	err = se.Find(&orderPtrs, func(session *Session) *Session {
+		return session.Where("amount > ?", 15).Asc("user_id")
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, len(orderPtrs))
+	assert.EqualValues(t, 2, orderPtrs[0].UserId)
+	assert.EqualValues(t, 4, orderPtrs[2].UserId)
+
+	cnt, err = se.Delete(&ShardOrder{UserId: 1})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	total, err = se.Count(new(ShardOrder), nil)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, total)
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/go-xorm/core"
+)
+
+// RegisterEnum registers the value set of an enum type, a named integer or
+// string type, as RegisterEnum(StatusActive, StatusDisabled). The fields of
+// the type are stored in an ENUM column on MySQL and in a VARCHAR on the
+// other databases, as the String of the values or their string. The values
+// out of the set are refused on write and on read.
+func (engine *Engine) RegisterEnum(values ...interface{}) error {
+	if len(values) == 0 {
+		return errors.New("no enum value")
+	}
+
+	t := reflect.TypeOf(values[0])
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.String:
+	default:
+		return fmt.Errorf("enum type %v isn't an integer or a string type", t)
+	}
+
+	converter := &enumConverter{
+		dbType:  engine.dialect.DBType(),
+		typ:     t,
+		labels:  make(map[interface{}]string, len(values)),
+		values:  make(map[string]interface{}, len(values)),
+		options: make(map[string]int, len(values)),
+	}
+	for i, v := range values {
+		if reflect.TypeOf(v) != t {
+			return fmt.Errorf("enum value %v is a %T, not a %v", v, v, t)
+		}
+		label := enumLabel(v)
+		if _, ok := converter.values[label]; ok {
+			return fmt.Errorf("duplicate enum value %v of %v", label, t)
+		}
+		converter.labels[v] = label
+		converter.values[label] = v
+		converter.options[label] = i
+		if len(label) > converter.length {
+			converter.length = len(label)
+		}
+	}
+	engine.RegisterConverter(t, converter)
+	return nil
+}
+
+// enumLabel returns the stored value of an enum value
+func enumLabel(v interface{}) string {
+	if s, ok := v.(fmt.Stringer); ok {
+		return s.String()
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.String {
+		return rv.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// enumOptions returns the options of the ENUM columns of the fields of type
+// t, nil if it isn't a registered enum type
+func (engine *Engine) enumOptions(t reflect.Type) map[string]int {
+	converter, _, ok := engine.converter(nil, t)
+	if !ok {
+		return nil
+	}
+	if c, ok := converter.(*enumConverter); ok {
+		return c.options
+	}
+	return nil
+}
+
+// enumConverter converts the values of an enum type to their labels
+type enumConverter struct {
+	dbType  core.DbType
+	typ     reflect.Type
+	labels  map[interface{}]string
+	values  map[string]interface{}
+	options map[string]int
+	length  int
+}
+
+func (c *enumConverter) SQLType() core.SQLType {
+	if c.dbType == core.MYSQL {
+		return core.SQLType{Name: core.Enum}
+	}
+	return core.SQLType{Name: core.Varchar, DefaultLength: c.length}
+}
+
+func (c *enumConverter) ToDB(v interface{}) (interface{}, error) {
+	label, ok := c.labels[v]
+	if !ok {
+		return nil, fmt.Errorf("%v isn't a value of the enum %T", v, v)
+	}
+	return label, nil
+}
+
+func (c *enumConverter) FromDB(src interface{}) (interface{}, error) {
+	var label string
+	switch v := src.(type) {
+	case []byte:
+		label = string(v)
+	case string:
+		label = v
+	default:
+		return nil, fmt.Errorf("unsupported enum %T", src)
+	}
+	v, ok := c.values[label]
+	if !ok {
+		return nil, fmt.Errorf("%q isn't a value of the enum %v", label, c.typ)
+	}
+	return v, nil
+}
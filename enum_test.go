@@ -0,0 +1,97 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type testStatus int
+
+const (
+	testStatusActive testStatus = iota + 1
+	testStatusDisabled
+)
+
+func (s testStatus) String() string {
+	switch s {
+	case testStatusActive:
+		return "active"
+	case testStatusDisabled:
+		return "disabled"
+	}
+	return "unknown"
+}
+
+type testColor string
+
+const (
+	testColorRed  testColor = "red"
+	testColorBlue testColor = "blue"
+)
+
+type EnumStruct struct {
+	Id     int64
+	Status testStatus
+	Color  *testColor
+}
+
+func TestRegisterEnum(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	assert.Error(t, testEngine.RegisterEnum())
+	assert.Error(t, testEngine.RegisterEnum(1.5))
+	assert.Error(t, testEngine.RegisterEnum(testColorRed, "green"))
+	assert.Error(t, testEngine.RegisterEnum(testColorRed, testColorRed))
+}
+
+func TestEnumColumns(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.RegisterEnum(testStatusActive, testStatusDisabled))
+	assert.NoError(t, testEngine.RegisterEnum(testColorRed, testColorBlue))
+	assertSync(t, new(EnumStruct))
+
+	table := testEngine.TableInfo(new(EnumStruct))
+	col := table.GetColumn(testEngine.ColumnMapper.Obj2Table("Status"))
+	assert.NotNil(t, col)
+	if testEngine.Dialect().DBType() == core.MYSQL {
+		assert.EqualValues(t, core.Enum, col.SQLType.Name)
+		assert.EqualValues(t, map[string]int{"active": 0, "disabled": 1}, col.EnumOptions)
+	} else {
+		assert.EqualValues(t, core.Varchar, col.SQLType.Name)
+	}
+
+	blue := testColorBlue
+	e := EnumStruct{Status: testStatusDisabled, Color: &blue}
+	cnt, err := testEngine.Insert(&e)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var status string
+	has, err := testEngine.Table(new(EnumStruct)).ID(e.Id).Cols("status").Get(&status)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "disabled", status)
+
+	var got EnumStruct
+	has, err = testEngine.Where("status = ?", testStatusDisabled).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, testStatusDisabled, got.Status)
+	assert.NotNil(t, got.Color)
+	assert.EqualValues(t, testColorBlue, *got.Color)
+
+	_, err = testEngine.Insert(&EnumStruct{Status: testStatus(7)})
+	assert.Error(t, err)
+
+	_, err = testEngine.Exec("UPDATE "+testEngine.Quote(table.Name)+" SET "+
+		testEngine.Quote("status")+" = ?", "archived")
+	assert.NoError(t, err)
+	_, err = testEngine.ID(e.Id).NoAutoCondition().Get(new(EnumStruct))
+	assert.Error(t, err)
+}
@@ -23,4 +23,36 @@ var (
 	ErrNeedDeletedCond = errors.New("Delete need at least one condition")
 	// ErrNotImplemented not implemented
 	ErrNotImplemented = errors.New("Not implemented")
+	// ErrConcurrentSessionUse a session is used by multiple goroutines at the same time
+	ErrConcurrentSessionUse = errors.New("Session is used by multiple goroutines concurrently")
+	// ErrShardRuleNotFound the bean's table has no shard rule
+	ErrShardRuleNotFound = errors.New("Shard rule not found")
+	// ErrShardKeyMissing the bean has no value for the shard key
+	ErrShardKeyMissing = errors.New("Shard key is missing")
+	// ErrNoHealthySlave all the slaves of the engine group are down
+	ErrNoHealthySlave = errors.New("No healthy slave")
+	// ErrNoDeletedColumn the table has no column tagged deleted
+	ErrNoDeletedColumn = errors.New("No deleted column")
+	// ErrNoSnapshot the bean was not loaded by GetForUpdate
+	ErrNoSnapshot = errors.New("No snapshot of the bean")
+	// ErrDispatcherClosed the dispatcher is closed
+	ErrDispatcherClosed = errors.New("Dispatcher is closed")
+	// ErrRecordNotFound the query returned no row
+	ErrRecordNotFound = errors.New("Record not found")
+	// ErrDuplicateKey the record violates a primary key or a unique index
+	ErrDuplicateKey = errors.New("Duplicate key")
+	// ErrForeignKeyViolation the record violates a foreign key
+	ErrForeignKeyViolation = errors.New("Foreign key violation")
+	// ErrLockTimeout the lock of a record or a table couldn't be acquired in time
+	ErrLockTimeout = errors.New("Lock timeout")
+	// ErrSerialization the transaction failed to serialize or deadlocked, it may be retried
+	ErrSerialization = errors.New("Serialization failure")
+	// ErrNoPrimaryKey the bean has no primary key to find its record
+	ErrNoPrimaryKey = errors.New("No primary key")
+	// ErrOptimisticLock the version of the updated bean is stale
+	ErrOptimisticLock = errors.New("Optimistic lock conflict")
+	// ErrNeedTransaction the operation needs the session in a transaction
+	ErrNeedTransaction = errors.New("Need a transaction")
+	// ErrInvalidTenant the tenant can't be put in the table names
+	ErrInvalidTenant = errors.New("Invalid tenant")
 )
@@ -0,0 +1,164 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// DBError is an error of the database driver classified by its code, so
+// errors.Is(err, ErrDuplicateKey) tells the kind of the error whatever the
+// database, and errors.As still finds the error of the driver
+type DBError struct {
+	// Kind is ErrRecordNotFound, ErrDuplicateKey, ErrForeignKeyViolation,
+	// ErrLockTimeout or ErrSerialization
+	Kind error
+	// Err is the error returned by the driver
+	Err error
+}
+
+func (e *DBError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the driver
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true if target is the kind of the error
+func (e *DBError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// the codes of the errors of the drivers by kind
+var (
+	mysqlErrorKinds = map[int64]error{
+		1062: ErrDuplicateKey,
+		1451: ErrForeignKeyViolation,
+		1452: ErrForeignKeyViolation,
+		1205: ErrLockTimeout,
+		1213: ErrSerialization, // deadlock
+	}
+	mssqlErrorKinds = map[int64]error{
+		2601: ErrDuplicateKey,
+		2627: ErrDuplicateKey,
+		547:  ErrForeignKeyViolation,
+		1222: ErrLockTimeout,
+		1205: ErrSerialization, // deadlock
+	}
+	postgresErrorKinds = map[string]error{
+		"23505": ErrDuplicateKey,
+		"23503": ErrForeignKeyViolation,
+		"55P03": ErrLockTimeout,
+		"40001": ErrSerialization,
+		"40P01": ErrSerialization, // deadlock
+	}
+	// the extended result codes, and the primary ones of the locks
+	sqliteErrorKinds = map[int64]error{
+		1555: ErrDuplicateKey, // SQLITE_CONSTRAINT_PRIMARYKEY
+		2067: ErrDuplicateKey, // SQLITE_CONSTRAINT_UNIQUE
+		787:  ErrForeignKeyViolation,
+		5:    ErrLockTimeout, // SQLITE_BUSY
+		6:    ErrLockTimeout, // SQLITE_LOCKED
+	}
+	oracleErrorKinds = map[string]error{
+		"ORA-00001": ErrDuplicateKey,
+		"ORA-02291": ErrForeignKeyViolation,
+		"ORA-02292": ErrForeignKeyViolation,
+		"ORA-00054": ErrLockTimeout,
+		"ORA-30006": ErrLockTimeout,
+		"ORA-08177": ErrSerialization,
+		"ORA-00060": ErrSerialization, // deadlock
+	}
+)
+
+// classifyError wraps err in a DBError when its code is known for the
+// dialect of the engine. The drivers aren't imported, their errors are
+// read by reflection: the Number of the mysql and mssql errors, the Code
+// or SQLState of the postgres ones and the codes of the sqlite ones.
+func (engine *Engine) classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*DBError); ok {
+		return err
+	}
+	if err == sql.ErrNoRows {
+		return &DBError{ErrRecordNotFound, err}
+	}
+
+	var kind error
+	switch engine.dialect.DBType() {
+	case core.MYSQL:
+		if code, ok := errorIntField(err, "Number"); ok {
+			kind = mysqlErrorKinds[code]
+		}
+	case core.MSSQL:
+		if code, ok := errorIntField(err, "Number"); ok {
+			kind = mssqlErrorKinds[code]
+		}
+	case core.POSTGRES:
+		if s, ok := err.(interface {
+			SQLState() string
+		}); ok {
+			kind = postgresErrorKinds[s.SQLState()]
+		} else if code, ok := errorStringField(err, "Code"); ok {
+			kind = postgresErrorKinds[code]
+		}
+	case core.SQLITE:
+		if code, ok := errorIntField(err, "ExtendedCode"); ok {
+			kind = sqliteErrorKinds[code]
+		}
+		if code, ok := errorIntField(err, "Code"); ok && kind == nil {
+			kind = sqliteErrorKinds[code]
+		}
+	case core.ORACLE:
+		msg := err.Error()
+		if i := strings.Index(msg, "ORA-"); i >= 0 && len(msg) >= i+9 {
+			kind = oracleErrorKinds[msg[i:i+9]]
+		}
+	}
+	if kind == nil {
+		return err
+	}
+	return &DBError{kind, err}
+}
+
+// errorField returns the field name of the struct of err
+func errorField(err error, name string) (reflect.Value, bool) {
+	v := reflect.Indirect(reflect.ValueOf(err))
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	f := v.FieldByName(name)
+	return f, f.IsValid()
+}
+
+func errorIntField(err error, name string) (int64, bool) {
+	f, ok := errorField(err, name)
+	if !ok {
+		return 0, false
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(f.Uint()), true
+	}
+	return 0, false
+}
+
+func errorStringField(err error, name string) (string, bool) {
+	f, ok := errorField(err, name)
+	if !ok || f.Kind() != reflect.String {
+		return "", false
+	}
+	return f.String(), true
+}
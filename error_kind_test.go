@@ -0,0 +1,105 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMySQLError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *fakeMySQLError) Error() string {
+	return e.Message
+}
+
+type fakePqError struct {
+	Code string
+}
+
+func (e fakePqError) Error() string {
+	return "pq: " + e.Code
+}
+
+type fakeSqliteError struct {
+	Code         int
+	ExtendedCode int
+}
+
+func (e fakeSqliteError) Error() string {
+	return "sqlite error"
+}
+
+func TestClassifyError(t *testing.T) {
+	engineOf := func(dialect core.Dialect, dbType core.DbType) *Engine {
+		assert.NoError(t, dialect.Init(nil, &core.Uri{DbType: dbType}, "", ""))
+		return &Engine{dialect: dialect}
+	}
+	mysqlEngine := engineOf(&mysql{}, core.MYSQL)
+	pqEngine := engineOf(&postgres{}, core.POSTGRES)
+	sqliteEngine := engineOf(&sqlite3{}, core.SQLITE)
+	oracleEngine := engineOf(&oracle{}, core.ORACLE)
+
+	var kases = []struct {
+		engine *Engine
+		err    error
+		kind   error
+	}{
+		{mysqlEngine, &fakeMySQLError{1062, "Duplicate entry"}, ErrDuplicateKey},
+		{mysqlEngine, &fakeMySQLError{1452, "Cannot add a child row"}, ErrForeignKeyViolation},
+		{mysqlEngine, &fakeMySQLError{1205, "Lock wait timeout"}, ErrLockTimeout},
+		{mysqlEngine, &fakeMySQLError{1213, "Deadlock"}, ErrSerialization},
+		{mysqlEngine, &fakeMySQLError{1146, "Table doesn't exist"}, nil},
+		{pqEngine, fakePqError{"23505"}, ErrDuplicateKey},
+		{pqEngine, fakePqError{"40001"}, ErrSerialization},
+		{sqliteEngine, fakeSqliteError{19, 2067}, ErrDuplicateKey},
+		{sqliteEngine, fakeSqliteError{5, 261}, ErrLockTimeout},
+		{oracleEngine, errors.New("ORA-00001: unique constraint violated"), ErrDuplicateKey},
+		{mysqlEngine, sql.ErrNoRows, ErrRecordNotFound},
+	}
+	for _, kase := range kases {
+		err := kase.engine.classifyError(kase.err)
+		if kase.kind == nil {
+			assert.True(t, err == kase.err)
+			continue
+		}
+		assert.True(t, errors.Is(err, kase.kind), kase.err.Error())
+		assert.EqualValues(t, kase.err.Error(), err.Error())
+		assert.True(t, errors.Unwrap(err) == kase.err)
+	}
+
+	var myErr *fakeMySQLError
+	err := mysqlEngine.classifyError(&fakeMySQLError{1062, "Duplicate entry"})
+	assert.True(t, errors.As(err, &myErr))
+	assert.EqualValues(t, 1062, myErr.Number)
+	assert.False(t, errors.Is(err, ErrForeignKeyViolation))
+}
+
+type UniqueKeyUser struct {
+	Id   int64
+	Name string `xorm:"unique"`
+}
+
+func TestDuplicateKeyError(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(UniqueKeyUser)))
+
+	_, err := testEngine.Insert(&UniqueKeyUser{Name: "lunny"})
+	assert.NoError(t, err)
+	_, err = testEngine.Insert(&UniqueKeyUser{Name: "lunny"})
+	assert.True(t, errors.Is(err, ErrDuplicateKey), err)
+
+	// no rows is still no error for the aggregations
+	total, err := testEngine.Count(new(UniqueKeyUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+}
@@ -0,0 +1,122 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// ExecStatement is a statement of ExecMulti
+type ExecStatement struct {
+	SQL  string
+	Args []interface{}
+}
+
+// ExecResult is the outcome of a statement of ExecMulti
+type ExecResult struct {
+	Result sql.Result
+	Err    error
+}
+
+// ExecMulti executes the statements in their order over one connection,
+// the one of the transaction of the session or a connection of the pool
+// kept until the last statement, so they share its session variables and
+// temporary tables. The statements after a failed one are executed too,
+// the error is the one of the connection.
+// When the driver runs several statements in one Exec, MySQL with
+// multiStatements in the DSN and PostgreSQL with lib/pq, the consecutive
+// statements without args are sent in one batch. database/sql only returns
+// the result of the whole batch, the statements of a batch share its
+// result or error and the statements of a failed batch after the failed one
+// are not executed. The other statements are sent one by one.
+func (session *Session) ExecMulti(statements []ExecStatement) ([]ExecResult, error) {
+	if err := session.enterOperation(); err != nil {
+		return nil, err
+	}
+	defer session.leaveOperation()
+
+	defer session.resetStatement()
+	if session.IsAutoClose {
+		defer session.Close()
+	}
+
+	if session.IsAutoCommit {
+		session.Engine.checkPool(session.DB())
+		conn, err := session.DB().Conn(session.Ctx())
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		session.conn = conn
+		defer func() {
+			session.conn = nil
+		}()
+	}
+
+	var results = make([]ExecResult, len(statements))
+	var tables []string
+	batches := !session.prepareStmt && batchesExec(session.Engine.dialect.DBType(), session.Engine.DriverName(), session.Engine.DataSourceName())
+	for i := 0; i < len(statements); {
+		end := i + 1
+		sqlStr := statements[i].SQL
+		if batches && len(statements[i].Args) == 0 {
+			for end < len(statements) && len(statements[end].Args) == 0 {
+				end++
+			}
+		}
+		if end > i+1 {
+			var sqls = make([]string, 0, end-i)
+			for _, statement := range statements[i:end] {
+				sqls = append(sqls, strings.TrimRight(statement.SQL, "; \t\r\n"))
+			}
+			sqlStr = strings.Join(sqls, ";\n")
+		}
+
+		result, err := session.exec(sqlStr, statements[i].Args...)
+		for j := i; j < end; j++ {
+			results[j] = ExecResult{result, err}
+			if err == nil {
+				tables = append(tables, writeTables(statements[j].SQL)...)
+			}
+		}
+		i = end
+	}
+	// raw writes are not tracked by the cachers
+	session.Engine.ClearCacheTables(tables...)
+	return results, nil
+}
+
+// batchesExec returns whether the driver runs all the statements of an Exec
+// without args, MySQL needs multiStatements in the DSN and lib/pq runs the
+// Exec without args by the simple query protocol
+func batchesExec(dbType core.DbType, driverName, dsn string) bool {
+	switch dbType {
+	case core.MYSQL:
+		i := strings.IndexByte(dsn, '?')
+		if i < 0 {
+			return false
+		}
+		params, err := url.ParseQuery(dsn[i+1:])
+		if err != nil {
+			return false
+		}
+		multi := params.Get("multiStatements")
+		return multi == "true" || multi == "1"
+	case core.POSTGRES:
+		return driverName == "postgres"
+	}
+	return false
+}
+
+// ExecMulti executes the statements in their order over one connection
+func (engine *Engine) ExecMulti(statements []ExecStatement) ([]ExecResult, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.ExecMulti(statements)
+}
@@ -0,0 +1,106 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type MultiTask struct {
+	Id   int64
+	Name string
+	Done bool
+}
+
+func TestExecMulti(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(MultiTask))
+
+	_, err := testEngine.Insert(&[]MultiTask{{Name: "a"}, {Name: "b"}, {Name: "c"}})
+	assert.NoError(t, err)
+
+	table := testEngine.Quote("multi_task")
+	results, err := testEngine.ExecMulti([]ExecStatement{
+		{SQL: "UPDATE " + table + " SET done = ? WHERE name = ?", Args: []interface{}{true, "a"}},
+		{SQL: "UPDATE " + table + " SET missing = 1"},
+		{SQL: "DELETE FROM " + table + " WHERE name IN (?, ?)", Args: []interface{}{"b", "c"}},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, results, 3) {
+		assert.NoError(t, results[0].Err)
+		assert.Error(t, results[1].Err)
+		assert.NoError(t, results[2].Err)
+		affected, err := results[2].Result.RowsAffected()
+		assert.NoError(t, err)
+		assert.EqualValues(t, 2, affected)
+	}
+
+	var tasks []MultiTask
+	assert.NoError(t, testEngine.Find(&tasks))
+	if assert.Len(t, tasks, 1) {
+		assert.True(t, tasks[0].Done)
+	}
+
+	// the statements without args are batched where the driver allows it
+	results, err = testEngine.ExecMulti([]ExecStatement{
+		{SQL: "INSERT INTO " + table + " (name) VALUES ('d');"},
+		{SQL: "UPDATE " + table + " SET name = 'e' WHERE name = 'd'"},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, results, 2) {
+		assert.NoError(t, results[0].Err)
+		assert.NoError(t, results[1].Err)
+	}
+	total, err := testEngine.Where("name = ?", "e").Count(new(MultiTask))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+
+	if testEngine.Dialect().DBType() != core.SQLITE {
+		return
+	}
+	// the temporary tables are the ones of the connection
+	results, err = testEngine.ExecMulti([]ExecStatement{
+		{SQL: "CREATE TEMP TABLE multi_tmp (id INTEGER)"},
+		{SQL: "INSERT INTO multi_tmp VALUES (1)"},
+		{SQL: "DROP TABLE multi_tmp"},
+	})
+	assert.NoError(t, err)
+	for _, result := range results {
+		assert.NoError(t, result.Err)
+	}
+}
+
+func TestBatchesExec(t *testing.T) {
+	assert.True(t, batchesExec(core.MYSQL, "mysql", "root:@/xorm_test?charset=utf8&multiStatements=true"))
+	assert.True(t, batchesExec(core.MYSQL, "mysql", "root:@/xorm_test?multiStatements=1"))
+	assert.False(t, batchesExec(core.MYSQL, "mysql", "root:@/xorm_test?charset=utf8"))
+	assert.False(t, batchesExec(core.MYSQL, "mysql", "root:@/xorm_test"))
+	assert.True(t, batchesExec(core.POSTGRES, "postgres", "dbname=xorm_test sslmode=disable"))
+	assert.False(t, batchesExec(core.POSTGRES, "pgx", "dbname=xorm_test sslmode=disable"))
+	assert.False(t, batchesExec(core.SQLITE, "sqlite3", "./test.db"))
+}
+
+func TestExecMultiConn(t *testing.T) {
+	mock, err := NewMockEngineOf(core.MYSQL)
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	session := mock.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+	results, err := session.ExecMulti([]ExecStatement{{SQL: "SET @a = 1"}, {SQL: "SET @b = 2"}})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.NoError(t, session.Commit())
+
+	var sqls []string
+	for _, record := range mock.Records() {
+		sqls = append(sqls, record.SQL)
+	}
+	assert.EqualValues(t, []string{"BEGIN", "SET @a = 1", "SET @b = 2", "COMMIT"}, sqls)
+}
@@ -0,0 +1,185 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// errExplained stops the query of Find or Get once its SQL is captured
+var errExplained = errors.New("explained")
+
+// QueryPlan is the plan of a query returned by the database
+type QueryPlan struct {
+	// SQL and Args are the query explained
+	SQL  string
+	Args []interface{}
+	// Columns and Rows are the plan as returned by the database, the
+	// EXPLAIN rows or the XML of the plan on mssql
+	Columns []string
+	Rows    []map[string]string
+}
+
+// String returns the rows of the plan, a line per row
+func (plan *QueryPlan) String() string {
+	lines := make([]string, len(plan.Rows))
+	for i, row := range plan.Rows {
+		values := make([]string, len(plan.Columns))
+		for j, col := range plan.Columns {
+			values[j] = row[col]
+		}
+		lines[i] = strings.Join(values, "\t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Contains returns true if s is in the plan, as the name of an index
+func (plan *QueryPlan) Contains(s string) bool {
+	for _, row := range plan.Rows {
+		for _, value := range row {
+			if strings.Contains(value, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Explain returns the plan of the query which Find, or Get when bean is
+// not a slice or a map, would execute with the conditions of the session
+func (session *Session) Explain(bean interface{}) (*QueryPlan, error) {
+	return session.explain(bean, false)
+}
+
+// ExplainAnalyze executes the query which Find or Get would execute and
+// returns its plan with the actual costs, it's not supported by sqlite,
+// mssql and oracle
+func (session *Session) ExplainAnalyze(bean interface{}) (*QueryPlan, error) {
+	return session.explain(bean, true)
+}
+
+// Explain returns the plan of the query which Find or Get would execute
+func (engine *Engine) Explain(bean interface{}) (*QueryPlan, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.Explain(bean)
+}
+
+// ExplainAnalyze executes the query which Find or Get would execute and
+// returns its plan with the actual costs
+func (engine *Engine) ExplainAnalyze(bean interface{}) (*QueryPlan, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.ExplainAnalyze(bean)
+}
+
+func (session *Session) explain(bean interface{}, analyze bool) (*QueryPlan, error) {
+	if err := session.enterOperation(); err != nil {
+		return nil, err
+	}
+	defer session.leaveOperation()
+
+	// the session runs the EXPLAIN after the query is captured
+	if session.IsAutoClose {
+		session.IsAutoClose = false
+		defer session.Close()
+	}
+
+	plan := &QueryPlan{}
+	session.explaining = plan
+	var err error
+	switch reflect.Indirect(reflect.ValueOf(bean)).Kind() {
+	case reflect.Slice, reflect.Map:
+		err = session.NoCache().Find(bean)
+	default:
+		_, err = session.NoCache().Get(bean)
+	}
+	session.explaining = nil
+	if err != errExplained {
+		if err == nil {
+			err = errors.New("no query to explain")
+		}
+		return nil, err
+	}
+
+	switch session.Engine.dialect.DBType() {
+	case core.MYSQL, core.POSTGRES:
+		prefix := "EXPLAIN "
+		if analyze {
+			prefix = "EXPLAIN ANALYZE "
+		}
+		err = session.queryPlan(plan, prefix+plan.SQL, plan.Args...)
+	case core.SQLITE:
+		if analyze {
+			return nil, ErrNotImplemented
+		}
+		err = session.queryPlan(plan, "EXPLAIN QUERY PLAN "+plan.SQL, plan.Args...)
+	case core.MSSQL:
+		if analyze {
+			return nil, ErrNotImplemented
+		}
+		err = session.onOneConn(func() error {
+			if _, err := session.exec("SET SHOWPLAN_XML ON"); err != nil {
+				return err
+			}
+			defer session.exec("SET SHOWPLAN_XML OFF")
+			return session.queryPlan(plan, plan.SQL, plan.Args...)
+		})
+	case core.ORACLE:
+		if analyze {
+			return nil, ErrNotImplemented
+		}
+		err = session.onOneConn(func() error {
+			if _, err := session.exec("EXPLAIN PLAN FOR "+plan.SQL, plan.Args...); err != nil {
+				return err
+			}
+			return session.queryPlan(plan, "SELECT PLAN_TABLE_OUTPUT FROM TABLE(DBMS_XPLAN.DISPLAY())")
+		})
+	default:
+		return nil, ErrNotImplemented
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// queryPlan reads the rows of the plan returned by sqlStr
+func (session *Session) queryPlan(plan *QueryPlan, sqlStr string, args ...interface{}) error {
+	rows, err := session.queryRows(sqlStr, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if plan.Columns, err = rows.Columns(); err != nil {
+		return err
+	}
+	for rows.Next() {
+		row, err := row2mapStr(rows, plan.Columns)
+		if err != nil {
+			return err
+		}
+		plan.Rows = append(plan.Rows, row)
+	}
+	return rows.Err()
+}
+
+// onOneConn runs f in the transaction of the session, or in a transaction
+// rolled back after f, so the statements of f share the connection
+func (session *Session) onOneConn(f func() error) error {
+	if !session.IsAutoCommit {
+		return f()
+	}
+	if err := session.Begin(); err != nil {
+		return err
+	}
+	defer session.Rollback()
+	return f()
+}
@@ -0,0 +1,55 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type ExplainedUser struct {
+	Id   int64
+	Name string `xorm:"index"`
+	Age  int
+}
+
+func TestExplain(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(ExplainedUser)))
+
+	_, err := testEngine.Insert(&ExplainedUser{Name: "lunny", Age: 20})
+	assert.NoError(t, err)
+
+	nameCol := testEngine.ColumnMapper.Obj2Table("Name")
+	var users []ExplainedUser
+	plan, err := testEngine.Where(nameCol+" = ?", "lunny").Explain(&users)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, len(users))
+	assert.True(t, strings.HasPrefix(strings.ToUpper(plan.SQL), "SELECT"))
+	assert.EqualValues(t, []interface{}{"lunny"}, plan.Args)
+	assert.True(t, len(plan.Rows) > 0)
+	assert.NotEmpty(t, plan.String())
+	switch testEngine.dialect.DBType() {
+	case core.SQLITE, core.MYSQL:
+		assert.True(t, plan.Contains("IDX_"), plan.String())
+	}
+
+	var user ExplainedUser
+	plan, err = testEngine.Id(1).Explain(&user)
+	assert.NoError(t, err)
+	assert.True(t, len(plan.Rows) > 0)
+	assert.EqualValues(t, 0, user.Id)
+
+	_, err = testEngine.ExplainAnalyze(&users)
+	switch testEngine.dialect.DBType() {
+	case core.POSTGRES:
+		assert.NoError(t, err)
+	case core.SQLITE, core.MSSQL:
+		assert.EqualValues(t, ErrNotImplemented, err)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+// Package factory builds and inserts the beans of the tests from the
+// defaults of their models:
+//
+//	factory.Define(func(n int) Role {
+//		return Role{Name: fmt.Sprintf("role%d", n)}
+//	})
+//	factory.Define(func(n int) User {
+//		return User{Name: fmt.Sprintf("user%d", n)}
+//	})
+//
+//	admin, err := factory.Create[Role](session, factory.WithField("Name", "admin"))
+//	user, err := factory.Create[User](session, factory.WithField("Role", admin))
+//
+// The associations of the beans, their fields of the structs of the defined
+// models which are stored as their ids, are created by their factories
+// when they are nil or zero. The associations of a model to itself, as the
+// parents of the trees, are left to the callers.
+package factory
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Inserter inserts the beans, as an *xorm.Engine or an *xorm.Session
+type Inserter interface {
+	Insert(beans ...interface{}) (int64, error)
+}
+
+// definition is the factory of a model
+type definition struct {
+	build func(n int) reflect.Value // a pointer to a new bean
+	seq   int64
+}
+
+var definitions sync.Map // the types of the models to their *definition
+
+// Define sets the builder of the default beans of the model T, n is the
+// sequence of the beans of T to make their unique fields
+func Define[T any](build func(n int) T) {
+	definitions.Store(typeOf[T](), &definition{build: func(n int) reflect.Value {
+		bean := build(n)
+		return reflect.ValueOf(&bean)
+	}})
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Option overrides the defaults of a bean
+type Option func(v reflect.Value) error
+
+// WithField sets the field name of the bean to value
+func WithField(name string, value interface{}) Option {
+	return func(v reflect.Value) error {
+		field := v.FieldByName(name)
+		if !field.IsValid() || !field.CanSet() {
+			return fmt.Errorf("factory: %v has no field %v", v.Type(), name)
+		}
+		if value == nil {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		val := reflect.ValueOf(value)
+		switch {
+		case val.Type().AssignableTo(field.Type()):
+		case val.Kind() == reflect.Ptr && val.Type().Elem().AssignableTo(field.Type()):
+			// an association created by Create
+			val = val.Elem()
+		case val.Type().ConvertibleTo(field.Type()):
+			val = val.Convert(field.Type())
+		default:
+			return fmt.Errorf("factory: %T is not a value of %v.%v", value, v.Type(), name)
+		}
+		field.Set(val)
+		return nil
+	}
+}
+
+// With changes the bean of T
+func With[T any](f func(bean *T)) Option {
+	return func(v reflect.Value) error {
+		bean, ok := v.Addr().Interface().(*T)
+		if !ok {
+			return fmt.Errorf("factory: the option of %v changes a %v", typeOf[T](), v.Type())
+		}
+		f(bean)
+		return nil
+	}
+}
+
+// Build returns a bean of T with the defaults of its factory and options,
+// without inserting it nor its associations
+func Build[T any](options ...Option) (*T, error) {
+	v, err := build(typeOf[T](), options)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface().(*T), nil
+}
+
+// Create builds a bean of T, creates its missing associations and inserts
+// them by db
+func Create[T any](db Inserter, options ...Option) (*T, error) {
+	v, err := create(db, typeOf[T](), options)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface().(*T), nil
+}
+
+// CreateList creates n beans of T with the same options
+func CreateList[T any](db Inserter, n int, options ...Option) ([]*T, error) {
+	var beans = make([]*T, 0, n)
+	for i := 0; i < n; i++ {
+		bean, err := Create[T](db, options...)
+		if err != nil {
+			return nil, err
+		}
+		beans = append(beans, bean)
+	}
+	return beans, nil
+}
+
+// build returns a pointer to a new bean of t
+func build(t reflect.Type, options []Option) (reflect.Value, error) {
+	d, ok := definitions.Load(t)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("factory: %v is not defined", t)
+	}
+	def := d.(*definition)
+	v := def.build(int(atomic.AddInt64(&def.seq, 1)))
+	for _, option := range options {
+		if err := option(v.Elem()); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return v, nil
+}
+
+func create(db Inserter, t reflect.Type, options []Option) (reflect.Value, error) {
+	v, err := build(t, options)
+	if err != nil {
+		return v, err
+	}
+	if err := createAssociations(db, v.Elem()); err != nil {
+		return v, err
+	}
+	if _, err := db.Insert(v.Interface()); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// createAssociations creates the zero associations of the bean v
+func createAssociations(db Inserter, v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanSet() || !field.IsZero() {
+			continue
+		}
+		t := field.Type()
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == v.Type() {
+			continue
+		}
+		if _, ok := definitions.Load(t); !ok {
+			continue
+		}
+
+		assoc, err := create(db, t, nil)
+		if err != nil {
+			return err
+		}
+		if field.Kind() == reflect.Ptr {
+			field.Set(assoc)
+		} else {
+			field.Set(assoc.Elem())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package factory
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-xorm/xorm"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+type Role struct {
+	Id   int64
+	Name string `xorm:"unique"`
+}
+
+type User struct {
+	Id    int64
+	Name  string `xorm:"unique"`
+	Age   int
+	Role  *Role `xorm:"role_id"`
+	Admin bool
+}
+
+func init() {
+	Define(func(n int) Role {
+		return Role{Name: fmt.Sprintf("role%d", n)}
+	})
+	Define(func(n int) User {
+		return User{Name: fmt.Sprintf("user%d", n), Age: 20}
+	})
+}
+
+func TestCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "factory")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	engine, err := xorm.NewEngine("sqlite3", filepath.Join(dir, "test.db"))
+	assert.NoError(t, err)
+	defer engine.Close()
+	assert.NoError(t, engine.Sync2(new(Role), new(User)))
+
+	session := engine.NewSession()
+	defer session.Close()
+
+	admin, err := Create[Role](session, WithField("Name", "admin"))
+	assert.NoError(t, err)
+	assert.True(t, admin.Id > 0)
+
+	user, err := Create[User](session, WithField("Role", admin), With(func(user *User) {
+		user.Admin = true
+	}))
+	assert.NoError(t, err)
+	assert.EqualValues(t, admin, user.Role)
+	assert.True(t, user.Admin)
+
+	// the missing associations are created
+	users, err := CreateList[User](engine, 2, WithField("Age", 30))
+	assert.NoError(t, err)
+	if assert.Len(t, users, 2) {
+		assert.NotEqual(t, users[0].Name, users[1].Name)
+		assert.NotEqual(t, users[0].Role.Id, users[1].Role.Id)
+		assert.EqualValues(t, 30, users[1].Age)
+	}
+
+	cnt, err := engine.Count(new(Role))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, cnt)
+
+	var got User
+	has, err := engine.ID(users[0].Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	if assert.NotNil(t, got.Role) {
+		assert.EqualValues(t, users[0].Role.Id, got.Role.Id)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	user, err := Build[User]()
+	assert.NoError(t, err)
+	assert.Nil(t, user.Role)
+	assert.EqualValues(t, 20, user.Age)
+
+	_, err = Build[User](WithField("Nickname", "lunny"))
+	assert.Error(t, err)
+	_, err = Build[User](WithField("Age", "old"))
+	assert.Error(t, err)
+	_, err = Build[struct{ Id int64 }]()
+	assert.Error(t, err)
+}
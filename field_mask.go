@@ -0,0 +1,182 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// FieldMask is the paths of the fields written by Update, even if they are
+// empty, as the update masks of the APIs. A path is the name of a field or
+// of its column, the fields of the extends fields are named by their paths
+// as Address.City or address.city, and the path of an extends field selects
+// all its fields.
+type FieldMask []string
+
+// ParseFieldMask returns the FieldMask of comma separated paths, as
+// "name,address.city"
+func ParseFieldMask(s string) FieldMask {
+	var mask FieldMask
+	for _, path := range strings.Split(s, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			mask = append(mask, path)
+		}
+	}
+	return mask
+}
+
+// Fields only updates the columns of the fields of mask, update use only
+func (statement *Statement) Fields(mask FieldMask) *Statement {
+	statement.fieldMask = append(statement.fieldMask, mask...)
+	return statement
+}
+
+// SelectFields only selects the columns of the fields of paths, as the
+// fields of a partial response, the paths are named as the ones of Fields.
+// The nested fields of Nested are only fetched when selected, as Orders,
+// or with some of their fields, as Orders.item. The unknown fields are
+// errors of Find, Get and Rows.
+func (statement *Statement) SelectFields(paths []string) *Statement {
+	statement.selectFields = append(statement.selectFields, paths...)
+	return statement
+}
+
+// fieldMaskColumns returns the names of the columns of table selected by
+// the field mask
+func (statement *Statement) fieldMaskColumns(table *core.Table) ([]string, error) {
+	return statement.fieldPathColumns(table, statement.fieldMask, core.ONLYFROMDB)
+}
+
+// applySelectFields selects the columns of the paths of SelectFields from
+// the table of the statement, the nested fields without a path are dropped
+func (statement *Statement) applySelectFields() error {
+	table := statement.RefTable
+	if len(statement.selectFields) == 0 || table == nil {
+		return nil
+	}
+
+	var paths []string
+	var nestedPaths = make(map[int][]string)
+	for _, path := range statement.selectFields {
+		if i, rest, ok := statement.nestedPath(path); ok {
+			nestedPaths[i] = append(nestedPaths[i], rest)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	colNames, err := statement.fieldPathColumns(table, paths, core.ONLYTODB)
+	if err != nil {
+		return err
+	}
+
+	var nested []nestedParam
+	for i, param := range statement.nested {
+		childPaths, ok := nestedPaths[i]
+		if !ok {
+			continue
+		}
+		var selectAll bool
+		var fields []string
+		for _, path := range childPaths {
+			if path == "" {
+				selectAll = true
+			} else {
+				fields = append(fields, path)
+			}
+		}
+		if !selectAll {
+			child, _, err := statement.nestedTable(param.field)
+			if err != nil {
+				return err
+			}
+			if param.columns, err = statement.fieldPathColumns(child, fields, core.ONLYTODB); err != nil {
+				return err
+			}
+		}
+		nested = append(nested, param)
+	}
+	statement.nested = nested
+
+	// the nested fields alone need a column of their own records
+	if len(colNames) == 0 {
+		colNames = table.PrimaryKeys
+	}
+	statement.selectFields = nil
+	statement.Cols(colNames...)
+	return nil
+}
+
+// nestedPath returns the index of the nested field of path and the rest of
+// the path, which is empty when path is the field
+func (statement *Statement) nestedPath(path string) (int, string, bool) {
+	var rest string
+	name := path
+	if i := strings.IndexByte(path, '.'); i >= 0 {
+		name, rest = path[:i], path[i+1:]
+	}
+	_, columnMapper := statement.Engine.mappers(statement.RefTable.Type)
+	for i, param := range statement.nested {
+		if strings.EqualFold(param.field, name) || strings.EqualFold(columnMapper.Obj2Table(param.field), name) {
+			return i, rest, true
+		}
+	}
+	return 0, "", false
+}
+
+// fieldPathColumns returns the names of the columns of table selected by
+// the field paths, the columns of the map type refused are errors
+func (statement *Statement) fieldPathColumns(table *core.Table, paths []string, refused core.MapType) ([]string, error) {
+	_, columnMapper := statement.Engine.mappers(table.Type)
+
+	var colNames []string
+	var selected = make(map[*core.Column]bool)
+	for _, path := range paths {
+		var found bool
+		for _, col := range table.Columns() {
+			if !fieldPathMatch(columnMapper, col, path) {
+				continue
+			}
+			found = true
+			if col.MapType == refused {
+				if refused == core.ONLYFROMDB {
+					return nil, fmt.Errorf("column %v of the table %v is read only", col.Name, table.Name)
+				}
+				return nil, fmt.Errorf("column %v of the table %v is write only", col.Name, table.Name)
+			}
+			if !selected[col] {
+				selected[col] = true
+				colNames = append(colNames, col.Name)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %v of the table %v", path, table.Name)
+		}
+	}
+	return colNames, nil
+}
+
+// fieldPathMatch returns true if path is the name of col, the path of its
+// field or of an extends field of it, case-insensitively
+func fieldPathMatch(mapper core.IMapper, col *core.Column, path string) bool {
+	if strings.EqualFold(col.Name, path) {
+		return true
+	}
+
+	var names = strings.Split(col.FieldName, ".")
+	var elems = strings.Split(path, ".")
+	if len(elems) > len(names) {
+		return false
+	}
+	for i, elem := range elems {
+		if !strings.EqualFold(names[i], elem) && !strings.EqualFold(mapper.Obj2Table(names[i]), elem) {
+			return false
+		}
+	}
+	// a path shorter than the field path is an extends field
+	return true
+}
@@ -0,0 +1,119 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type FieldMaskAddress struct {
+	City   string
+	Street string
+}
+
+type FieldMaskUser struct {
+	Id      int64
+	Name    string
+	Age     int
+	Active  bool
+	Address FieldMaskAddress `xorm:"extends"`
+}
+
+func TestParseFieldMask(t *testing.T) {
+	assert.EqualValues(t, FieldMask{"name", "address.city"}, ParseFieldMask("name, address.city,"))
+	assert.EqualValues(t, 0, len(ParseFieldMask("")))
+}
+
+func TestUpdateFields(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(FieldMaskUser))
+
+	var user = FieldMaskUser{
+		Name:    "alice",
+		Age:     30,
+		Active:  true,
+		Address: FieldMaskAddress{City: "Paris", Street: "Rivoli"},
+	}
+	_, err := testEngine.Insert(&user)
+	assert.NoError(t, err)
+
+	// the empty fields of the mask are written, the others aren't
+	cnt, err := testEngine.ID(user.Id).Fields(ParseFieldMask("age,Active,address.city")).
+		Update(&FieldMaskUser{Name: "bob"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var got FieldMaskUser
+	has, err := testEngine.ID(user.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "alice", got.Name)
+	assert.EqualValues(t, 0, got.Age)
+	assert.False(t, got.Active)
+	assert.EqualValues(t, "", got.Address.City)
+	assert.EqualValues(t, "Rivoli", got.Address.Street)
+
+	// the path of an extends field selects all its fields
+	_, err = testEngine.ID(user.Id).Fields(FieldMask{"Address"}).
+		Update(&FieldMaskUser{Name: "bob", Address: FieldMaskAddress{City: "Lyon"}})
+	assert.NoError(t, err)
+	got = FieldMaskUser{}
+	has, err = testEngine.ID(user.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "alice", got.Name)
+	assert.EqualValues(t, "Lyon", got.Address.City)
+	assert.EqualValues(t, "", got.Address.Street)
+
+	_, err = testEngine.ID(user.Id).Fields(FieldMask{"unknown"}).Update(&FieldMaskUser{})
+	assert.Error(t, err)
+}
+
+func TestSelectFields(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(FieldMaskUser))
+
+	_, err := testEngine.Insert(&FieldMaskUser{
+		Name:    "alice",
+		Age:     30,
+		Active:  true,
+		Address: FieldMaskAddress{City: "Paris", Street: "Rivoli"},
+	})
+	assert.NoError(t, err)
+
+	var users []FieldMaskUser
+	assert.NoError(t, testEngine.SelectFields(ParseFieldMask("name,address.city")).Find(&users))
+	assert.EqualValues(t, []FieldMaskUser{{Name: "alice", Address: FieldMaskAddress{City: "Paris"}}}, users)
+
+	var user FieldMaskUser
+	has, err := testEngine.SelectFields([]string{"Age", "Address"}).Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, FieldMaskUser{Age: 30, Address: FieldMaskAddress{City: "Paris", Street: "Rivoli"}}, user)
+
+	_, err = testEngine.SelectFields([]string{"name", "unknown"}).Get(&user)
+	assert.Error(t, err)
+}
+
+func TestSelectFieldsNested(t *testing.T) {
+	mock, err := NewMockEngineOf(core.POSTGRES)
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	var users []NestedUser
+	assert.NoError(t, mock.Nested("Orders", "user_id").SelectFields([]string{"name", "orders.item"}).Find(&users))
+	assert.EqualValues(t, `SELECT "name", (SELECT COALESCE(json_agg(json_build_object('item', "c"."item")), '[]')`+
+		` FROM "nested_order" "c" WHERE "c"."user_id" = "nested_user"."id") AS "xorm_nested_0"`+
+		` FROM "nested_user"`, mock.LastRecord().SQL)
+
+	// the nested fields without path aren't fetched
+	assert.NoError(t, mock.Nested("Orders", "user_id").SelectFields([]string{"name"}).Find(&users))
+	assert.EqualValues(t, `SELECT "name" FROM "nested_user"`, mock.LastRecord().SQL)
+
+	assert.Error(t, mock.Nested("Orders", "user_id").SelectFields([]string{"orders.unknown"}).Find(&users))
+}
@@ -0,0 +1,328 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fixtures loads the records of the YAML or JSON fixture files into
+// the tables of an engine, as the fixtures of rails. A file holds the
+// records of the table of its name, users.yml the ones of users, as a list
+// or as a map of labeled records:
+//
+//   - id: 1
+//     name: lunny
+//     created: {{now}}
+//
+// The files are templates of text/template run before their parsing. The
+// tables are loaded after the ones they reference, a column user_id
+// referencing the table user.
+//
+//	loader, err := fixtures.New(engine, fixtures.Options{Dir: "testdata/fixtures"})
+//	err = loader.Load()
+//	defer loader.Clean()
+package fixtures
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-xorm/core"
+	"github.com/go-xorm/xorm"
+	"gopkg.in/yaml.v2"
+)
+
+// TimeFormat is the format of the times of the funcs of the templates
+const TimeFormat = "2006-01-02 15:04:05"
+
+// ErrCycle is returned for the tables referencing each other
+var ErrCycle = errors.New("fixtures: the tables reference each other")
+
+// Options are the files of a Loader and their templating
+type Options struct {
+	// Files are the fixture files, .yml, .yaml or .json
+	Files []string
+	// Dir is a directory of fixture files, added to Files
+	Dir string
+	// Funcs are added to the funcs of the templates, now and ago
+	Funcs template.FuncMap
+	// Data is the dot of the templates
+	Data interface{}
+	// Depends are the tables loaded before a table, added to the ones of
+	// its columns
+	Depends map[string][]string
+}
+
+// Loader loads the fixtures of its files into the tables of an engine
+type Loader struct {
+	engine *xorm.Engine
+	tables []*table // in the order of their loads
+}
+
+// table is the fixtures of a table
+type table struct {
+	name    string
+	file    string
+	records []map[string]interface{}
+}
+
+// New parses the fixture files of options into the tables of engine, whose
+// columns are checked
+func New(engine *xorm.Engine, options Options) (*Loader, error) {
+	files := append([]string(nil), options.Files...)
+	if options.Dir != "" {
+		for _, pattern := range []string{"*.yml", "*.yaml", "*.json"} {
+			matches, err := filepath.Glob(filepath.Join(options.Dir, pattern))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+	}
+
+	funcs := template.FuncMap{
+		"now": func() string {
+			return time.Now().In(engine.TZLocation).Format(TimeFormat)
+		},
+		"ago": func(d string) (string, error) {
+			duration, err := time.ParseDuration(d)
+			if err != nil {
+				return "", err
+			}
+			return time.Now().Add(-duration).In(engine.TZLocation).Format(TimeFormat), nil
+		},
+	}
+	for name, f := range options.Funcs {
+		funcs[name] = f
+	}
+
+	metas, err := engine.DBMetas()
+	if err != nil {
+		return nil, err
+	}
+	var dbTables = make(map[string]*core.Table, len(metas))
+	for _, meta := range metas {
+		dbTables[strings.ToLower(meta.Name)] = meta
+	}
+
+	var tables = make(map[string]*table, len(files))
+	var names []string
+	for _, file := range files {
+		t, err := parseFile(file, funcs, options.Data)
+		if err != nil {
+			return nil, err
+		}
+		meta, ok := dbTables[strings.ToLower(t.name)]
+		if !ok {
+			return nil, fmt.Errorf("fixtures %v: no table %v", file, t.name)
+		}
+		t.name = meta.Name
+		for _, record := range t.records {
+			for col := range record {
+				if meta.GetColumn(col) == nil {
+					return nil, fmt.Errorf("fixtures %v: no column %v in table %v", file, col, t.name)
+				}
+			}
+		}
+		if _, ok := tables[t.name]; ok {
+			return nil, fmt.Errorf("fixtures %v: table %v has another file", file, t.name)
+		}
+		tables[t.name] = t
+		names = append(names, t.name)
+	}
+	sort.Strings(names)
+
+	order, err := loadOrder(names, dbTables, options.Depends)
+	if err != nil {
+		return nil, err
+	}
+	loader := &Loader{engine: engine}
+	for _, name := range order {
+		loader.tables = append(loader.tables, tables[name])
+	}
+	return loader, nil
+}
+
+// parseFile runs the template of a file and parses its records
+func parseFile(file string, funcs template.FuncMap, data interface{}) (*table, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(filepath.Base(file)).Funcs(funcs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("fixtures %v: %v", file, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("fixtures %v: %v", file, err)
+	}
+
+	t := &table{
+		name: strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)),
+		file: file,
+	}
+
+	// the JSON files are YAML too
+	var list []map[string]interface{}
+	if err := yaml.Unmarshal(buf.Bytes(), &list); err == nil {
+		t.records = list
+	} else {
+		var labeled yaml.MapSlice
+		if err := yaml.Unmarshal(buf.Bytes(), &labeled); err != nil {
+			return nil, fmt.Errorf("fixtures %v: %v", file, err)
+		}
+		for _, item := range labeled {
+			fields, ok := item.Value.(yaml.MapSlice)
+			if !ok {
+				return nil, fmt.Errorf("fixtures %v: record %v is not a map", file, item.Key)
+			}
+			record := make(map[string]interface{}, len(fields))
+			for _, field := range fields {
+				record[fmt.Sprint(field.Key)] = field.Value
+			}
+			t.records = append(t.records, record)
+		}
+	}
+
+	for _, record := range t.records {
+		for col, v := range record {
+			switch v.(type) {
+			case map[interface{}]interface{}, yaml.MapSlice, []interface{}:
+				return nil, fmt.Errorf("fixtures %v: the value of column %v is not a scalar", file, col)
+			}
+		}
+	}
+	return t, nil
+}
+
+// loadOrder sorts the tables after the tables they depend on, the tables
+// named by the columns <table>_id and by depends
+func loadOrder(names []string, dbTables map[string]*core.Table, depends map[string][]string) ([]string, error) {
+	var loaded = make(map[string]bool, len(names))
+	for _, name := range names {
+		loaded[strings.ToLower(name)] = false
+	}
+	deps := func(name string) []string {
+		var result []string
+		for _, col := range dbTables[strings.ToLower(name)].ColumnsSeq() {
+			ref := strings.TrimSuffix(strings.ToLower(col), "_id")
+			if ref != strings.ToLower(col) && ref != strings.ToLower(name) {
+				if _, ok := loaded[ref]; ok {
+					result = append(result, ref)
+				}
+			}
+		}
+		for _, dep := range depends[name] {
+			if _, ok := loaded[strings.ToLower(dep)]; ok {
+				result = append(result, strings.ToLower(dep))
+			}
+		}
+		return result
+	}
+
+	var order []string
+	var visiting = make(map[string]bool)
+	var visit func(name string) error
+	visit = func(name string) error {
+		key := strings.ToLower(name)
+		if done, ok := loaded[key]; !ok || done {
+			return nil
+		}
+		if visiting[key] {
+			return ErrCycle
+		}
+		visiting[key] = true
+		for _, dep := range deps(name) {
+			if err := visit(dbTables[dep].Name); err != nil {
+				return err
+			}
+		}
+		visiting[key] = false
+		loaded[key] = true
+		order = append(order, dbTables[key].Name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Tables returns the tables of the fixtures in the order of their loads
+func (loader *Loader) Tables() []string {
+	var names = make([]string, len(loader.tables))
+	for i, t := range loader.tables {
+		names[i] = t.name
+	}
+	return names
+}
+
+// Load deletes the records of the tables of the fixtures and inserts the
+// fixtures, in a transaction
+func (loader *Loader) Load() error {
+	session := loader.engine.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		return err
+	}
+	if err := loader.clean(session); err != nil {
+		session.Rollback()
+		return err
+	}
+
+	quote := loader.engine.QuoteStr()
+	for _, t := range loader.tables {
+		for i, record := range t.records {
+			var cols = make([]string, 0, len(record))
+			for col := range record {
+				cols = append(cols, col)
+			}
+			sort.Strings(cols)
+
+			var args = make([]interface{}, len(cols))
+			for j, col := range cols {
+				args[j] = record[col]
+			}
+			sqlStr := fmt.Sprintf("INSERT INTO %s (%s%s%s) VALUES (%s)",
+				loader.engine.Quote(t.name),
+				quote, strings.Join(cols, quote+", "+quote), quote,
+				strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", "))
+			if _, err := session.Exec(sqlStr, args...); err != nil {
+				session.Rollback()
+				return fmt.Errorf("fixtures %v: record %d: %v", t.file, i, err)
+			}
+		}
+	}
+	return session.Commit()
+}
+
+// Clean deletes the records of the tables of the fixtures
+func (loader *Loader) Clean() error {
+	session := loader.engine.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		return err
+	}
+	if err := loader.clean(session); err != nil {
+		session.Rollback()
+		return err
+	}
+	return session.Commit()
+}
+
+// clean deletes the records of the tables, the referencing ones first
+func (loader *Loader) clean(session *xorm.Session) error {
+	for i := len(loader.tables) - 1; i >= 0; i-- {
+		if _, err := session.Exec("DELETE FROM " + loader.engine.Quote(loader.tables[i].name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
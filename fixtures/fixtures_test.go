@@ -0,0 +1,145 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fixtures
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/go-xorm/xorm"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+type User struct {
+	Id      int64
+	Name    string
+	Created time.Time
+}
+
+type Post struct {
+	Id     int64
+	UserId int64
+	Title  string
+}
+
+type Comment struct {
+	Id     int64
+	PostId int64
+	Body   string
+}
+
+const (
+	usersYAML = `
+lunny:
+  id: 1
+  name: lunny
+  created: {{now}}
+xlw:
+  id: 2
+  name: {{.Name}}
+  created: {{ago "48h"}}
+`
+	postsJSON = `[
+  {"id": 1, "user_id": 1, "title": "hello"},
+  {"id": 2, "user_id": 2, "title": "{{upper "world"}}"}
+]`
+	commentsYAML = `
+- id: 1
+  post_id: 1
+  body: first
+- id: 2
+  post_id: 2
+  body: ~
+`
+)
+
+func writeFixtures(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "fixtures")
+	assert.NoError(t, err)
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func newEngine(t *testing.T, dir string) *xorm.Engine {
+	engine, err := xorm.NewEngine("sqlite3", filepath.Join(dir, "test.db"))
+	assert.NoError(t, err)
+	assert.NoError(t, engine.Sync2(new(User), new(Post), new(Comment)))
+	return engine
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"user.yml":     usersYAML,
+		"post.json":    postsJSON,
+		"comment.yaml": commentsYAML,
+	})
+	defer os.RemoveAll(dir)
+	engine := newEngine(t, dir)
+	defer engine.Close()
+
+	loader, err := New(engine, Options{
+		Dir:   dir,
+		Funcs: template.FuncMap{"upper": strings.ToUpper},
+		Data:  map[string]string{"Name": "xlw"},
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"user", "post", "comment"}, loader.Tables())
+
+	// the records of a previous load are deleted
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, loader.Load())
+	}
+
+	var users []User
+	assert.NoError(t, engine.Asc("id").Find(&users))
+	if assert.Len(t, users, 2) {
+		assert.EqualValues(t, "xlw", users[1].Name)
+		assert.True(t, users[0].Created.After(users[1].Created))
+	}
+
+	var posts []Post
+	assert.NoError(t, engine.Asc("id").Find(&posts))
+	assert.EqualValues(t, []Post{{1, 1, "hello"}, {2, 2, "WORLD"}}, posts)
+
+	var comment Comment
+	has, err := engine.Id(2).Get(&comment)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "", comment.Body)
+
+	assert.NoError(t, loader.Clean())
+	cnt, err := engine.Count(new(Post))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"user.yml": usersYAML,
+		"post.yml": "- id: 1\n  author: lunny\n",
+	})
+	defer os.RemoveAll(dir)
+	engine := newEngine(t, dir)
+	defer engine.Close()
+
+	_, err := New(engine, Options{Files: []string{filepath.Join(dir, "post.yml")}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no column author")
+
+	_, err = New(engine, Options{
+		Files:   []string{filepath.Join(dir, "user.yml")},
+		Data:    map[string]string{"Name": "xlw"},
+		Depends: map[string][]string{"user": {"user"}},
+	})
+	assert.Equal(t, ErrCycle, err)
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package xorm
+
+import "github.com/go-xorm/builder"
+
+// Querier runs the typed queries of Find, Get and Insert: an *Engine, an
+// *EngineGroup or a *Session with its conditions, orders and limits
+type Querier interface {
+	typedSession() *Session
+}
+
+func (engine *Engine) typedSession() *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session
+}
+
+func (session *Session) typedSession() *Session {
+	return session
+}
+
+// whereSession returns the session of q with the conditions conds
+func whereSession(q Querier, conds []builder.Cond) *Session {
+	session := q.typedSession()
+	if len(conds) > 0 {
+		session.And(builder.And(conds...))
+	}
+	return session
+}
+
+// Find returns the records of T matching conds, as
+// xorm.Find[User](engine, builder.Eq{"name": name})
+func Find[T any](q Querier, conds ...builder.Cond) ([]T, error) {
+	var beans []T
+	if err := whereSession(q, conds).Find(&beans); err != nil {
+		return nil, err
+	}
+	return beans, nil
+}
+
+// Get returns the first record of T matching conds and false when there's
+// none, as xorm.Get[User](session.ID(id))
+func Get[T any](q Querier, conds ...builder.Cond) (T, bool, error) {
+	var bean T
+	has, err := whereSession(q, conds).Get(&bean)
+	return bean, has, err
+}
+
+// Insert inserts the records of beans, their ids are set
+func Insert[T any](q Querier, beans ...*T) (int64, error) {
+	session := q.typedSession()
+	if len(beans) == 1 {
+		return session.Insert(beans[0])
+	}
+	return session.Insert(beans)
+}
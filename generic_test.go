@@ -0,0 +1,69 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/builder"
+	"github.com/stretchr/testify/assert"
+)
+
+type GenericUser struct {
+	Id   int64
+	Name string
+	Age  int
+}
+
+func TestGenericAPI(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(GenericUser))
+
+	cnt, err := Insert(testEngine, &GenericUser{Name: "a", Age: 10})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	users := []*GenericUser{{Name: "b", Age: 20}, {Name: "c", Age: 30}}
+	cnt, err = Insert(testEngine, users...)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cnt)
+
+	all, err := Find[GenericUser](testEngine)
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	older, err := Find[GenericUser](testEngine.Desc("age"), builder.Gt{"age": 15})
+	assert.NoError(t, err)
+	assert.Len(t, older, 2)
+	assert.EqualValues(t, "c", older[0].Name)
+	assert.EqualValues(t, "b", older[1].Name)
+
+	user, has, err := Get[GenericUser](testEngine, builder.Eq{"name": "b"})
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 20, user.Age)
+
+	_, has, err = Get[GenericUser](testEngine, builder.Eq{"name": "d"})
+	assert.NoError(t, err)
+	assert.False(t, has)
+
+	session := testEngine.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+	_, err = Insert(session, &GenericUser{Name: "d", Age: 40})
+	assert.NoError(t, err)
+	user, has, err = Get[GenericUser](session.Where("age > ?", 35))
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "d", user.Name)
+	assert.NoError(t, session.Rollback())
+
+	all, err = Find[GenericUser](testEngine)
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+}
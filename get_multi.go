@@ -0,0 +1,198 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+)
+
+// MultiGetCacher is implemented by the cachers getting several beans in a
+// round trip, the beans of GetMulti are got one by one from the others
+type MultiGetCacher interface {
+	// GetBeans returns the cached beans of the ids, nil for the missing ones
+	GetBeans(tableName string, ids []string) []interface{}
+}
+
+var _ MultiGetCacher = &RedisCacher{}
+
+// getBeans returns the beans of ids cached by cacher
+func getBeans(cacher core.Cacher, tableName string, ids []string) []interface{} {
+	if c, ok := cacher.(MultiGetCacher); ok {
+		return c.GetBeans(tableName, ids)
+	}
+	var beans = make([]interface{}, len(ids))
+	for i, id := range ids {
+		beans[i] = cacher.GetBean(tableName, id)
+	}
+	return beans
+}
+
+// GetMulti gets the records of ids into rowsSlicePtr, a pointer to a slice
+// of structs or of pointers to structs, in the order of ids and returns the
+// ids without record. An id is the value of the primary key, or a core.PK
+// for a composite key. The cached beans are got from the cache, the others
+// by a single In query and put into the cache. The sessions of a tenant
+// column or with query filters and the soft deleted tables always query.
+//
+//	var users []User
+//	missing, err := engine.GetMulti(&users, []int64{3, 1, 2})
+func (session *Session) GetMulti(rowsSlicePtr interface{}, ids interface{}) ([]interface{}, error) {
+	fail := func(err error) ([]interface{}, error) {
+		session.resetStatement()
+		if session.IsAutoClose {
+			session.Close()
+		}
+		return nil, err
+	}
+
+	sliceValue := reflect.Indirect(reflect.ValueOf(rowsSlicePtr))
+	idsValue := reflect.ValueOf(ids)
+	if sliceValue.Kind() != reflect.Slice || idsValue.Kind() != reflect.Slice {
+		return fail(errors.New("needs a pointer to a slice and a slice of ids"))
+	}
+	elemType := sliceValue.Type().Elem()
+	isPointer := elemType.Kind() == reflect.Ptr
+	if isPointer {
+		elemType = elemType.Elem()
+	}
+
+	table, err := session.Engine.autoMapType(reflect.New(elemType).Elem())
+	if err == nil && len(table.PrimaryKeys) == 0 {
+		err = ErrNoPrimaryKey
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if err := session.Statement.setRefValue(reflect.New(elemType).Elem()); err != nil {
+		return fail(err)
+	}
+	tableName := session.Statement.TableName()
+
+	// the keys of the ids, the ones of the cache when they're cacheable
+	var pks = make([]core.PK, idsValue.Len())
+	var keys = make([]string, idsValue.Len())
+	var cacheable = make(map[string]bool, len(keys))
+	for i := range pks {
+		id := idsValue.Index(i).Interface()
+		pk, ok := id.(core.PK)
+		if !ok {
+			pk = core.PK{id}
+		}
+		if len(pk) != len(table.PrimaryKeys) {
+			return fail(&PKArityError{Table: tableName, Expected: len(table.PrimaryKeys), Actual: len(pk)})
+		}
+		var cached bool
+		pks[i] = pk
+		if keys[i], cached = pkKey(table, pk); cached {
+			cacheable[keys[i]] = true
+		}
+	}
+
+	// the cached beans aren't checked against the tenant, the query filters
+	// and the soft deletes, the ids are queried with their conditions
+	var found = make(map[string]reflect.Value, len(keys))
+	var cacher core.Cacher
+	if session.canCache() && !session.Statement.unscoped &&
+		session.Statement.tenantColumn() == nil && !session.Statement.filterCond().IsValid() &&
+		table.DeletedColumn() == nil {
+		cacher = session.readCacher(table)
+	}
+	if cacher != nil && len(cacheable) > 0 {
+		var sids = make([]string, 0, len(cacheable))
+		for sid := range cacheable {
+			sids = append(sids, sid)
+		}
+		beans := getBeans(cacher, tableName, sids)
+		for i, bean := range beans {
+			session.Engine.cacheMonitor.lookup(tableName, bean != nil)
+			if bean == nil {
+				continue
+			}
+			bv := reflect.ValueOf(bean)
+			if bv.Kind() != reflect.Ptr || bv.Elem().Type() != elemType {
+				continue
+			}
+			found[sids[i]] = bv
+		}
+	}
+
+	// the missing ids are queried at once
+	var cond = builder.NewCond()
+	var queried = make(map[string]bool)
+	for i, pk := range pks {
+		if _, ok := found[keys[i]]; ok || queried[keys[i]] {
+			continue
+		}
+		queried[keys[i]] = true
+		var eq = builder.Eq{}
+		for j, name := range table.PrimaryKeys {
+			eq[session.Engine.Quote(name)] = pk[j]
+		}
+		cond = cond.Or(eq)
+	}
+	if len(queried) > 0 {
+		beans := reflect.New(reflect.SliceOf(reflect.PtrTo(elemType)))
+		if err := session.Where(cond).NoCache().Find(beans.Interface()); err != nil {
+			return nil, err
+		}
+		beans = beans.Elem()
+		for i := 0; i < beans.Len(); i++ {
+			bv := beans.Index(i)
+			pk, err := session.Engine.idOfV(bv)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := pkKey(table, pk)
+			found[key] = bv
+			if cacher != nil && ok {
+				cacher.PutBean(tableName, key, bv.Interface())
+			}
+		}
+	} else {
+		session.resetStatement()
+		if session.IsAutoClose {
+			session.Close()
+		}
+	}
+
+	var missing []interface{}
+	for i, key := range keys {
+		bv, ok := found[key]
+		if !ok {
+			missing = append(missing, idsValue.Index(i).Interface())
+			continue
+		}
+		if isPointer {
+			sliceValue.Set(reflect.Append(sliceValue, bv))
+		} else {
+			sliceValue.Set(reflect.Append(sliceValue, bv.Elem()))
+		}
+	}
+	return missing, nil
+}
+
+// GetMulti gets the records of ids into rowsSlicePtr in the order of ids
+// and returns the ids without record
+func (engine *Engine) GetMulti(rowsSlicePtr interface{}, ids interface{}) ([]interface{}, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.GetMulti(rowsSlicePtr, ids)
+}
+
+// pkKey returns the key of pk in the cache and true, or its values and
+// false when it can't be cached
+func pkKey(table *core.Table, pk core.PK) (string, bool) {
+	if cpk, ok := cachePK(table, pk); ok {
+		if sid, err := cpk.ToString(); err == nil {
+			return sid, true
+		}
+	}
+	return fmt.Sprintf("%v", []interface{}(pk)), false
+}
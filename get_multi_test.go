@@ -0,0 +1,117 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type MultiGetUser struct {
+	Id   int64
+	Name string
+}
+
+func TestGetMulti(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(MultiGetUser))
+
+	for _, name := range []string{"lunny", "xlw", "xiangxiao"} {
+		_, err := testEngine.Insert(&MultiGetUser{Name: name})
+		assert.NoError(t, err)
+	}
+
+	var users []MultiGetUser
+	missing, err := testEngine.GetMulti(&users, []int64{3, 5, 1, 3})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []interface{}{int64(5)}, missing)
+	assert.EqualValues(t, []MultiGetUser{{3, "xiangxiao"}, {1, "lunny"}, {3, "xiangxiao"}}, users)
+
+	var ptrs []*MultiGetUser
+	missing, err = testEngine.GetMulti(&ptrs, []interface{}{2, core.PK{int64(1)}})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, len(missing))
+	assert.EqualValues(t, []*MultiGetUser{{2, "xlw"}, {1, "lunny"}}, ptrs)
+
+	_, err = testEngine.GetMulti(&users, []core.PK{{1, 2}})
+	assert.IsType(t, &PKArityError{}, err)
+}
+
+func TestGetMultiCache(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(MultiGetUser))
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.MapCacher(new(MultiGetUser), cacher)
+	defer testEngine.MapCacher(new(MultiGetUser), nil)
+
+	for _, name := range []string{"lunny", "xlw", "xiangxiao"} {
+		_, err := testEngine.Insert(&MultiGetUser{Name: name})
+		assert.NoError(t, err)
+	}
+
+	var user MultiGetUser
+	has, err := testEngine.Id(2).Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	tableName := testEngine.TableMapper.Obj2Table("MultiGetUser")
+	before := testEngine.CacheStats()[tableName]
+
+	var users []MultiGetUser
+	missing, err := testEngine.GetMulti(&users, []int64{3, 2, 1})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, len(missing))
+	assert.EqualValues(t, []MultiGetUser{{3, "xiangxiao"}, {2, "xlw"}, {1, "lunny"}}, users)
+
+	// the bean 2 hits the cache, 1 and 3 are loaded into it
+	stats := testEngine.CacheStats()[tableName]
+	assert.EqualValues(t, 1, stats.Hits-before.Hits)
+	assert.EqualValues(t, 2, stats.Misses-before.Misses)
+	for _, id := range []int64{1, 3} {
+		pk := core.PK{id}
+		sid, err := pk.ToString()
+		assert.NoError(t, err)
+		assert.NotNil(t, cacher.GetBean(tableName, sid))
+	}
+}
+
+func TestGetMultiCacheFiltered(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(MultiGetUser))
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.MapCacher(new(MultiGetUser), cacher)
+	defer testEngine.MapCacher(new(MultiGetUser), nil)
+
+	for _, name := range []string{"lunny", "xlw"} {
+		_, err := testEngine.Insert(&MultiGetUser{Name: name})
+		assert.NoError(t, err)
+	}
+
+	// the bean 2 is cached by an unfiltered session
+	var users []MultiGetUser
+	_, err := testEngine.GetMulti(&users, []int64{1, 2})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, len(users))
+
+	nameCol := testEngine.ColumnMapper.Obj2Table("Name")
+	testEngine.AddQueryFilter(func(table *core.Table, session *Session) builder.Cond {
+		if table.GetColumn(nameCol) == nil {
+			return nil
+		}
+		return builder.Neq{nameCol: "xlw"}
+	})
+	defer func() { testEngine.queryFilters = nil }()
+
+	users = nil
+	missing, err := testEngine.GetMulti(&users, []int64{1, 2})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []interface{}{int64(2)}, missing)
+	assert.EqualValues(t, []MultiGetUser{{1, "lunny"}}, users)
+}
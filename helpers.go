@@ -418,7 +418,7 @@ func genCols(table *core.Table, session *Session, bean interface{}, useCol bool,
 
 		if (col.IsCreated || col.IsUpdated) && session.Statement.UseAutoTime /*&& isZero(fieldValue.Interface())*/ {
 			// if time is non-empty, then set to auto time
-			val, t := session.Engine.NowTime2(col.SQLType.Name)
+			val, t := session.Engine.nowTime(col)
 			args = append(args, val)
 
 			var colName = col.Name
@@ -426,7 +426,7 @@ func genCols(table *core.Table, session *Session, bean interface{}, useCol bool,
 				col := table.GetColumn(colName)
 				setColumnTime(bean, col, t)
 			})
-		} else if col.IsVersion && session.Statement.checkVersion {
+		} else if col.IsVersion && session.Statement.checkVersion && isCounterVersion(fieldValue.Type()) {
 			args = append(args, 1)
 		} else {
 			arg, err := session.value2Interface(col, fieldValue)
@@ -0,0 +1,91 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-xorm/core"
+)
+
+// IDGenerator generates the ids of the columns with the IDGEN tag, as
+// `xorm:"pk idgen(ulid)"`
+type IDGenerator interface {
+	// NewID returns a new id for a field of type t
+	NewID(t reflect.Type) (interface{}, error)
+}
+
+var (
+	idGeneratorsMu sync.RWMutex
+	idGenerators   = map[string]IDGenerator{
+		"ulid": ulidGenerator{},
+	}
+)
+
+// RegisterIDGenerator registers a generator for the IDGEN(name) tags, ulid
+// is registered by default
+func RegisterIDGenerator(name string, generator IDGenerator) {
+	idGeneratorsMu.Lock()
+	idGenerators[strings.ToLower(name)] = generator
+	idGeneratorsMu.Unlock()
+}
+
+func getIDGenerator(name string) (IDGenerator, bool) {
+	idGeneratorsMu.RLock()
+	generator, ok := idGenerators[strings.ToLower(name)]
+	idGeneratorsMu.RUnlock()
+	return generator, ok
+}
+
+// IDGenTagHandler generates the ids of the column with the generator of
+// its param, ulid by default, when the inserted fields are empty
+func IDGenTagHandler(ctx *tagContext) error {
+	name := "ulid"
+	if len(ctx.params) > 0 {
+		name = strings.Trim(strings.TrimSpace(ctx.params[0]), "'")
+	}
+	generator, ok := getIDGenerator(name)
+	if !ok {
+		return fmt.Errorf("unknown id generator %v of the field %v", name, ctx.col.FieldName)
+	}
+	ctx.engine.columnIDGens.Store(ctx.col, generator)
+	return nil
+}
+
+// setGeneratedIDs sets the empty fields of the columns having an id
+// generator of the bean v
+func (engine *Engine) setGeneratedIDs(table *core.Table, v reflect.Value) error {
+	for _, col := range table.Columns() {
+		generator, ok := engine.columnIDGens.Load(col)
+		if !ok {
+			continue
+		}
+
+		fieldValue, err := col.ValueOfV(&v)
+		if err != nil {
+			return err
+		}
+		if !fieldValue.IsZero() {
+			continue
+		}
+		if !fieldValue.CanSet() {
+			return fmt.Errorf("id column %v is not settable, use a pointer of the bean", col.Name)
+		}
+
+		id, err := generator.(IDGenerator).NewID(fieldValue.Type())
+		if err != nil {
+			return err
+		}
+		rv := reflect.ValueOf(id)
+		if !rv.IsValid() || !rv.Type().ConvertibleTo(fieldValue.Type()) {
+			return fmt.Errorf("id generator of the column %v returned %T", col.Name, id)
+		}
+		fieldValue.Set(rv.Convert(fieldValue.Type()))
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/go-xorm/core"
+)
+
+// ImportOptions are the options of ImportWith
+type ImportOptions struct {
+	// Delimiter ends the statements, ; if empty. The DELIMITER lines of
+	// the scripts of the mysql client change it.
+	Delimiter string
+	// Transaction runs the statements in a transaction rolled back on the
+	// first error, the DDLs of MySQL and Oracle commit it implicitly
+	Transaction bool
+	// Progress is called after each statement with its number from 1, the
+	// line of its start and its SQL
+	Progress func(n, line int, sqlStr string)
+}
+
+// ImportError is the error of the statement of a script starting at Line
+type ImportError struct {
+	Line int
+	SQL  string
+	Err  error
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the error of the statement
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+// ImportFileWith runs the SQL script of the file ddlPath with options
+func (engine *Engine) ImportFileWith(ddlPath string, options ImportOptions) ([]sql.Result, error) {
+	file, err := os.Open(ddlPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return engine.ImportWith(file, options)
+}
+
+// ImportWith runs the statements of the SQL script r, the errors are
+// *ImportError. The delimiters of the quoted strings and names, of the
+// comments, of the dollar quoted bodies of PostgreSQL and of the BEGIN END
+// bodies of the triggers don't end the statements. The routines of MSSQL
+// end with the GO lines and the PL/SQL blocks of Oracle with the / lines.
+func (engine *Engine) ImportWith(r io.Reader, options ImportOptions) ([]sql.Result, error) {
+	scanner := newScriptScanner(r, engine.dialect.DBType(), options.Delimiter)
+
+	var tx *core.Tx
+	exec := engine.DB().Exec
+	if options.Transaction {
+		var err error
+		if tx, err = engine.DB().Begin(); err != nil {
+			return nil, err
+		}
+		exec = tx.Exec
+	}
+
+	var results []sql.Result
+	for n := 1; ; n++ {
+		query, line, err := scanner.next()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			engine.logSQL(query)
+			var result sql.Result
+			if result, err = exec(query); err == nil {
+				results = append(results, result)
+				if options.Progress != nil {
+					options.Progress(n, line, query)
+				}
+				continue
+			}
+			err = &ImportError{Line: line, SQL: query, Err: engine.classifyError(err)}
+		}
+		if tx != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		return results, err
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+var (
+	dollarQuoteRegexp = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+	// the statements of the triggers, whose bodies are BEGIN END blocks
+	triggerRegexp = regexp.MustCompile(`(?is)^CREATE\s+(\S+\s+)*?TRIGGER\b`)
+	// the PL/SQL blocks of Oracle ended by the / lines
+	plsqlRegexp = regexp.MustCompile(`(?is)^(BEGIN|DECLARE|CREATE\s+(OR\s+REPLACE\s+)?(EDITIONABLE\s+)?(PROCEDURE|FUNCTION|PACKAGE|TRIGGER|TYPE\s+BODY))\b`)
+	// the routines of MSSQL ended by the GO lines
+	routineRegexp = regexp.MustCompile(`(?is)^(CREATE|ALTER|CREATE\s+OR\s+ALTER)\s+(PROCEDURE|PROC|FUNCTION|TRIGGER)\b`)
+)
+
+// scriptScanner splits a SQL script into its statements
+type scriptScanner struct {
+	reader    *bufio.Reader
+	dbType    core.DbType
+	delimiter string
+	line      int    // the number of the current line
+	rest      string // the unread rest of the current line
+
+	stmt         strings.Builder
+	start        int    // the line of the statement
+	quote        byte   // the quote of the current string or name
+	dollar       string // the tag of the current dollar quoted body
+	blockComment bool
+	depth        int // the BEGIN and CASE blocks of a trigger
+	word         strings.Builder
+}
+
+func newScriptScanner(r io.Reader, dbType core.DbType, delimiter string) *scriptScanner {
+	if delimiter == "" {
+		delimiter = ";"
+	}
+	return &scriptScanner{
+		reader:    bufio.NewReader(r),
+		dbType:    dbType,
+		delimiter: delimiter,
+	}
+}
+
+// next returns the next statement and the line of its start, io.EOF at
+// the end of the script
+func (s *scriptScanner) next() (string, int, error) {
+	for {
+		if s.rest == "" {
+			line, err := s.reader.ReadString('\n')
+			if line == "" && err != nil {
+				if err != io.EOF {
+					return "", 0, err
+				}
+				if s.quote != 0 || s.dollar != "" {
+					return "", s.start, &ImportError{Line: s.start, SQL: s.stmt.String(), Err: fmt.Errorf("unterminated string")}
+				}
+				if stmt, start, ok := s.flush(); ok {
+					return stmt, start, nil
+				}
+				return "", 0, io.EOF
+			}
+			s.line++
+			s.rest = line
+
+			if s.inText() {
+				// the statement goes on
+			} else if s.isEmpty() && s.directive(line) {
+				s.rest = ""
+				continue
+			} else if s.isBatchEnd(line) {
+				s.rest = ""
+				if stmt, start, ok := s.flush(); ok {
+					return stmt, start, nil
+				}
+				continue
+			}
+		}
+
+		if stmt, start, ok := s.scan(); ok {
+			return stmt, start, nil
+		}
+	}
+}
+
+func (s *scriptScanner) inText() bool {
+	return s.quote != 0 || s.dollar != "" || s.blockComment
+}
+
+func (s *scriptScanner) isEmpty() bool {
+	return strings.TrimSpace(s.stmt.String()) == ""
+}
+
+// directive changes the delimiter for a DELIMITER line
+func (s *scriptScanner) directive(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) != 2 || !strings.EqualFold(fields[0], "DELIMITER") {
+		return false
+	}
+	s.delimiter = fields[1]
+	return true
+}
+
+// isBatchEnd returns true for the GO lines of MSSQL and the / lines of
+// Oracle
+func (s *scriptScanner) isBatchEnd(line string) bool {
+	line = strings.TrimSpace(line)
+	switch s.dbType {
+	case core.MSSQL:
+		return strings.EqualFold(line, "GO")
+	case core.ORACLE:
+		return line == "/"
+	}
+	return false
+}
+
+// scan scans the rest of the current line until the end of a statement
+func (s *scriptScanner) scan() (string, int, bool) {
+	rest := s.rest
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		if !s.inText() && !isWordByte(c) {
+			s.endWord()
+		}
+		switch {
+		case s.blockComment:
+			if strings.HasPrefix(rest[i:], "*/") {
+				s.blockComment = false
+				s.stmt.WriteString("*/")
+				i++
+				continue
+			}
+		case s.quote != 0:
+			if c == '\\' && s.dbType == core.MYSQL && s.quote != '`' && i+1 < len(rest) {
+				s.stmt.WriteString(rest[i : i+2])
+				i++
+				continue
+			}
+			if c == s.quote {
+				if i+1 < len(rest) && rest[i+1] == c {
+					s.stmt.WriteString(rest[i : i+2])
+					i++
+					continue
+				}
+				s.quote = 0
+			}
+		case s.dollar != "":
+			if strings.HasPrefix(rest[i:], s.dollar) {
+				s.stmt.WriteString(s.dollar)
+				i += len(s.dollar) - 1
+				s.dollar = ""
+				continue
+			}
+		case strings.HasPrefix(rest[i:], s.delimiter) && !s.inBlock():
+			s.rest = rest[i+len(s.delimiter):]
+			if stmt, start, ok := s.flush(); ok {
+				return stmt, start, true
+			}
+			return s.scan()
+		case strings.HasPrefix(rest[i:], "--") || c == '#' && s.dbType == core.MYSQL:
+			// the line comments are dropped
+			s.stmt.WriteByte('\n')
+			s.rest = ""
+			return "", 0, false
+		case strings.HasPrefix(rest[i:], "/*"):
+			s.blockComment = true
+			s.stmt.WriteString("/*")
+			i++
+			continue
+		case c == '\'' || c == '"' || c == '`' || c == '[' && s.dbType == core.MSSQL:
+			s.startStmt()
+			s.quote = c
+			if c == '[' {
+				s.quote = ']'
+			}
+		case c == '$' && s.dbType == core.POSTGRES && (i == 0 || !isWordByte(rest[i-1])):
+			if tag := dollarQuoteRegexp.FindString(rest[i:]); tag != "" {
+				s.startStmt()
+				s.dollar = tag
+				s.stmt.WriteString(tag)
+				i += len(tag) - 1
+				continue
+			}
+		case isWordByte(c):
+			s.startStmt()
+			s.word.WriteByte(c)
+		default:
+			if !unicode.IsSpace(rune(c)) {
+				s.startStmt()
+			}
+		}
+		s.stmt.WriteByte(c)
+	}
+	s.endWord()
+	s.rest = ""
+	return "", 0, false
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// startStmt records the line of the first token of the statement
+func (s *scriptScanner) startStmt() {
+	if s.start == 0 {
+		s.start = s.line
+	}
+}
+
+// endWord counts the BEGIN END blocks of the bodies of the triggers
+func (s *scriptScanner) endWord() {
+	if s.word.Len() == 0 {
+		return
+	}
+	word := strings.ToUpper(s.word.String())
+	s.word.Reset()
+	if !triggerRegexp.MatchString(strings.TrimSpace(s.stmt.String())) {
+		return
+	}
+	switch word {
+	case "BEGIN", "CASE":
+		s.depth++
+	case "END":
+		if s.depth > 0 {
+			s.depth--
+		}
+	}
+}
+
+// inBlock returns true in the body of a trigger, in a PL/SQL block or in a
+// routine of MSSQL
+func (s *scriptScanner) inBlock() bool {
+	if s.depth > 0 {
+		return true
+	}
+	if s.delimiter != ";" {
+		return false
+	}
+	switch s.dbType {
+	case core.ORACLE:
+		return plsqlRegexp.MatchString(strings.TrimSpace(s.stmt.String()))
+	case core.MSSQL:
+		return routineRegexp.MatchString(strings.TrimSpace(s.stmt.String()))
+	}
+	return false
+}
+
+// flush returns the scanned statement, if it's not empty
+func (s *scriptScanner) flush() (string, int, bool) {
+	stmt, start := strings.TrimSpace(s.stmt.String()), s.start
+	s.stmt.Reset()
+	s.start, s.depth = 0, 0
+	if stmt == "" || start == 0 {
+		return "", 0, false
+	}
+	return stmt, start, true
+}
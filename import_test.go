@@ -0,0 +1,123 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// scanScript returns the statements of a script with their lines
+func scanScript(t *testing.T, dbType core.DbType, script string) ([]string, []int) {
+	scanner := newScriptScanner(strings.NewReader(script), dbType, "")
+	var stmts []string
+	var lines []int
+	for {
+		stmt, line, err := scanner.next()
+		if err == io.EOF {
+			return stmts, lines
+		}
+		assert.NoError(t, err)
+		if err != nil {
+			return stmts, lines
+		}
+		stmts = append(stmts, stmt)
+		lines = append(lines, line)
+	}
+}
+
+func TestScriptScanner(t *testing.T) {
+	stmts, lines := scanScript(t, core.SQLITE, `-- the users
+CREATE TABLE user (id INTEGER, name TEXT); INSERT INTO user VALUES (1, 'a;b''c');
+
+/* a comment; */
+INSERT INTO user
+VALUES (2, "x;y");
+CREATE TRIGGER user_deleted AFTER DELETE ON user
+BEGIN
+  UPDATE counts SET n = CASE WHEN n > 0 THEN n - 1 ELSE 0 END;
+  DELETE FROM posts WHERE user_id = OLD.id;
+END;
+SELECT 1`)
+	assert.EqualValues(t, []string{
+		"CREATE TABLE user (id INTEGER, name TEXT)",
+		"INSERT INTO user VALUES (1, 'a;b''c')",
+		"/* a comment; */\nINSERT INTO user\nVALUES (2, \"x;y\")",
+		"CREATE TRIGGER user_deleted AFTER DELETE ON user\nBEGIN\n  UPDATE counts SET n = CASE WHEN n > 0 THEN n - 1 ELSE 0 END;\n  DELETE FROM posts WHERE user_id = OLD.id;\nEND",
+		"SELECT 1",
+	}, stmts)
+	assert.EqualValues(t, []int{2, 2, 5, 7, 12}, lines)
+
+	stmts, _ = scanScript(t, core.MYSQL, `DELIMITER //
+CREATE PROCEDURE p()
+BEGIN
+  SELECT 'it\'s;'; # a comment;
+END //
+DELIMITER ;
+CALL p();`)
+	assert.EqualValues(t, []string{
+		"CREATE PROCEDURE p()\nBEGIN\n  SELECT 'it\\'s;'; \nEND",
+		"CALL p()",
+	}, stmts)
+
+	stmts, _ = scanScript(t, core.POSTGRES, `CREATE FUNCTION f() RETURNS int AS $body$
+BEGIN
+  RETURN 1;
+END;
+$body$ LANGUAGE plpgsql;
+SELECT $1::text;`)
+	assert.Len(t, stmts, 2)
+	assert.True(t, strings.HasSuffix(stmts[0], "$body$ LANGUAGE plpgsql"))
+
+	stmts, _ = scanScript(t, core.MSSQL, "CREATE PROCEDURE p AS\nSELECT 1;\nSELECT [a;b] FROM t;\nGO\nSELECT 2;")
+	assert.EqualValues(t, []string{"CREATE PROCEDURE p AS\nSELECT 1;\nSELECT [a;b] FROM t;", "SELECT 2"}, stmts)
+
+	stmts, _ = scanScript(t, core.ORACLE, "BEGIN\n  NULL;\nEND;\n/\nSELECT 1 FROM DUAL;")
+	assert.EqualValues(t, []string{"BEGIN\n  NULL;\nEND;", "SELECT 1 FROM DUAL"}, stmts)
+}
+
+func TestImportWith(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	if testEngine.Dialect().DBType() != core.SQLITE {
+		t.Skip("the script is the one of sqlite")
+	}
+
+	script := `DROP TABLE IF EXISTS import_user;
+CREATE TABLE import_user (id INTEGER PRIMARY KEY, name TEXT);
+INSERT INTO import_user VALUES (1, 'lunny; xlw');
+`
+	var progress []int
+	results, err := testEngine.ImportWith(strings.NewReader(script), ImportOptions{
+		Progress: func(n, line int, sqlStr string) {
+			progress = append(progress, n, line)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.EqualValues(t, []int{1, 1, 2, 2, 3, 3}, progress)
+
+	// the statements of a failed transaction are rolled back
+	_, err = testEngine.ImportWith(strings.NewReader(`INSERT INTO import_user VALUES (2, 'gopher')$
+INSERT INTO import_user VALUES (3, 'tux')$
+
+INSERT INTO import_users VALUES (4, 'none')$`), ImportOptions{Delimiter: "$", Transaction: true})
+	if assert.Error(t, err) {
+		importErr, ok := err.(*ImportError)
+		if assert.True(t, ok) {
+			assert.EqualValues(t, 4, importErr.Line)
+		}
+	}
+	results, err = testEngine.Import(strings.NewReader("SELECT count(*) FROM import_user"))
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	var cnt int64
+	_, err = testEngine.SQL("SELECT count(*) FROM import_user").Get(&cnt)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+}
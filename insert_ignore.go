@@ -0,0 +1,120 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// InsertIgnore inserts a record of bean unless it conflicts with a record
+// by its primary key or a unique index, and returns true if it's inserted.
+// It's an INSERT IGNORE on MySQL, an ON CONFLICT DO NOTHING on PostgreSQL
+// and SQLite and a MERGE on MSSQL, it's not supported by Oracle. The
+// hooks after the insert only run for an inserted record.
+func (session *Session) InsertIgnore(bean interface{}) (bool, error) {
+	if err := session.enterOperation(); err != nil {
+		return false, err
+	}
+	defer session.leaveOperation()
+
+	defer session.resetStatement()
+	if session.IsAutoClose {
+		defer session.Close()
+	}
+
+	session.Statement.insertIgnore = true
+	affected, err := session.innerInsert(bean)
+	return affected > 0, err
+}
+
+// InsertIgnore inserts a record of bean unless it conflicts with a record
+func (engine *Engine) InsertIgnore(bean interface{}) (bool, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.InsertIgnore(bean)
+}
+
+// insertIgnoreSQL returns the insert sqlStr of the columns colNames ignoring
+// the conflicts, the last columns are the ones of exprs
+func (session *Session) insertIgnoreSQL(sqlStr string, colNames, exprs []string) (string, error) {
+	switch session.Engine.dialect.DBType() {
+	case core.MYSQL:
+		return "INSERT IGNORE" + strings.TrimPrefix(sqlStr, "INSERT"), nil
+	case core.POSTGRES, core.SQLITE:
+		return sqlStr + " ON CONFLICT DO NOTHING", nil
+	case core.MSSQL:
+		return session.mergeInsertSQL(sqlStr, colNames, exprs), nil
+	}
+	return "", ErrNotImplemented
+}
+
+// mergeInsertSQL returns a MERGE inserting the columns colNames when no
+// record has the same primary key or unique index
+func (session *Session) mergeInsertSQL(sqlStr string, colNames, exprs []string) string {
+	var inserted = make(map[string]bool, len(colNames))
+	for _, colName := range colNames {
+		inserted[strings.ToLower(colName)] = true
+	}
+
+	quote := session.Engine.Quote
+	target, source := quote("target"), quote("source")
+	keys := conflictKeys(session.Statement.RefTable, func(col string) bool {
+		return inserted[strings.ToLower(col)]
+	})
+	var conds []string
+	for _, cols := range keys {
+		var eqs = make([]string, len(cols))
+		for i, col := range cols {
+			eqs[i] = target + "." + quote(col) + " = " + source + "." + quote(col)
+		}
+		conds = append(conds, "("+strings.Join(eqs, " AND ")+")")
+	}
+	if len(conds) == 0 {
+		return sqlStr
+	}
+
+	var sources = make([]string, len(colNames))
+	var targets = make([]string, len(colNames))
+	var values = make([]string, len(colNames))
+	for i, colName := range colNames {
+		place := "?"
+		if j := i - (len(colNames) - len(exprs)); j >= 0 {
+			place = exprs[j]
+		}
+		sources[i] = place + " AS " + quote(colName)
+		targets[i] = quote(colName)
+		values[i] = source + "." + quote(colName)
+	}
+	return "MERGE INTO " + quote(session.Statement.TableName()) + " WITH (HOLDLOCK) AS " + target +
+		" USING (SELECT " + strings.Join(sources, ", ") + ") AS " + source +
+		" ON " + strings.Join(conds, " OR ") +
+		" WHEN NOT MATCHED THEN INSERT (" + strings.Join(targets, ", ") + ")" +
+		" VALUES (" + strings.Join(values, ", ") + ");"
+}
+
+// conflictKeys returns the columns of the primary key and of the unique
+// indexes of table whose columns are all inserted
+func conflictKeys(table *core.Table, inserted func(col string) bool) [][]string {
+	var keys = [][]string{table.PrimaryKeys}
+	for _, index := range table.Indexes {
+		if index.Type == core.UniqueType {
+			keys = append(keys, index.Cols)
+		}
+	}
+
+	var result [][]string
+	for _, cols := range keys {
+		var ok = len(cols) > 0
+		for _, col := range cols {
+			ok = ok && inserted(col)
+		}
+		if ok {
+			result = append(result, cols)
+		}
+	}
+	return result
+}
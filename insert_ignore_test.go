@@ -0,0 +1,71 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type IgnoredUser struct {
+	Id    int64
+	Email string `xorm:"varchar(64) unique"`
+	Name  string
+}
+
+func TestInsertIgnore(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(IgnoredUser))
+
+	user := IgnoredUser{Email: "lunny@example.com", Name: "lunny"}
+	inserted, err := testEngine.InsertIgnore(&user)
+	assert.NoError(t, err)
+	assert.True(t, inserted)
+	assert.True(t, user.Id > 0)
+
+	dup := IgnoredUser{Email: "lunny@example.com", Name: "xlw"}
+	inserted, err = testEngine.InsertIgnore(&dup)
+	assert.NoError(t, err)
+	assert.False(t, inserted)
+	assert.EqualValues(t, 0, dup.Id)
+
+	other := IgnoredUser{Email: "xlw@example.com", Name: "xlw"}
+	inserted, err = testEngine.InsertIgnore(&other)
+	assert.NoError(t, err)
+	assert.True(t, inserted)
+	assert.True(t, other.Id > user.Id)
+
+	var got IgnoredUser
+	has, err := testEngine.Where("email = ?", "lunny@example.com").Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", got.Name)
+
+	// the next inserts don't ignore the conflicts
+	session := testEngine.NewSession()
+	defer session.Close()
+	_, err = session.InsertIgnore(&IgnoredUser{Email: "xlw@example.com"})
+	assert.NoError(t, err)
+	_, err = session.Insert(&IgnoredUser{Email: "xlw@example.com"})
+	assert.Error(t, err)
+}
+
+func TestInsertIgnoreMSSQL(t *testing.T) {
+	mock, err := NewMockEngineOf(core.MSSQL)
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.On("^MERGE").Affected(0)
+	inserted, err := mock.InsertIgnore(&IgnoredUser{Email: "lunny@example.com", Name: "lunny"})
+	assert.NoError(t, err)
+	assert.False(t, inserted)
+	assert.EqualValues(t, `MERGE INTO "ignored_user" WITH (HOLDLOCK) AS "target"`+
+		` USING (SELECT ? AS "email", ? AS "name") AS "source"`+
+		` ON ("target"."email" = "source"."email")`+
+		` WHEN NOT MATCHED THEN INSERT ("email", "name") VALUES ("source"."email", "source"."name");`,
+		mock.LastRecord().SQL)
+}
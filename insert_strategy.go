@@ -0,0 +1,334 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+
+	"github.com/go-xorm/core"
+)
+
+// InsertMultiStrategy is how InsertMulti writes its beans
+type InsertMultiStrategy int
+
+const (
+	// InsertMultiAuto writes the beans in a multi-values insert when they
+	// fit in one, else copies them into PostgreSQL with lib/pq, inserts
+	// them row by row into SQLite and in multi-values inserts of the most
+	// args allowed into the other databases
+	InsertMultiAuto InsertMultiStrategy = iota
+	// InsertMultiValues writes the beans in multi-values inserts of the
+	// most args allowed by the database
+	InsertMultiValues
+	// InsertMultiRows executes a prepared single row insert per bean
+	InsertMultiRows
+	// InsertMultiCopy streams the beans by COPY FROM STDIN, PostgreSQL with
+	// the lib/pq driver only
+	InsertMultiCopy
+)
+
+// ErrInsertCopyUnsupported is returned by InsertMulti with InsertMultiCopy
+// on the databases or the drivers without COPY FROM STDIN
+var ErrInsertCopyUnsupported = errors.New("COPY is only supported by PostgreSQL with lib/pq")
+
+// SetInsertMultiStrategy sets how InsertMulti writes its beans,
+// InsertMultiAuto by default
+func (engine *Engine) SetInsertMultiStrategy(strategy InsertMultiStrategy) {
+	engine.insertStrategy = strategy
+}
+
+// InsertMultiStrategy sets how the next InsertMulti writes its beans,
+// overriding the strategy of the engine
+func (session *Session) InsertMultiStrategy(strategy InsertMultiStrategy) *Session {
+	session.Statement.insertStrategy = strategy
+	return session
+}
+
+// insertLimits returns the most args of a statement and the most rows of a
+// multi-values insert of a database
+func insertLimits(dbType core.DbType) (maxArgs, maxRows int) {
+	switch dbType {
+	case core.SQLITE:
+		// the limit of sqlite before 3.32
+		return 999, 0
+	case core.MSSQL:
+		return 2100, 1000
+	case core.ORACLE:
+		return 65535, 1000
+	}
+	return 65535, 0
+}
+
+// insertChunkRows returns the most rows of the multi-values inserts of
+// cols columns
+func (session *Session) insertChunkRows(cols int) int {
+	maxArgs, maxRows := insertLimits(session.Engine.dialect.DBType())
+	rows := maxArgs
+	if cols > 0 {
+		rows = maxArgs / cols
+	}
+	if maxRows > 0 && rows > maxRows {
+		rows = maxRows
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	return rows
+}
+
+// supportsCopy returns true if the database and its driver are PostgreSQL
+// and lib/pq, which prepares COPY FROM STDIN
+func (session *Session) supportsCopy() bool {
+	return session.Engine.dialect.DBType() == core.POSTGRES &&
+		session.Engine.DriverName() == "postgres"
+}
+
+// insertMultiStrategy returns the strategy of an insert of rows of cols
+// columns
+func (session *Session) insertMultiStrategy(rows, cols int) (InsertMultiStrategy, error) {
+	strategy := session.Statement.insertStrategy
+	if strategy == InsertMultiAuto {
+		strategy = session.Engine.insertStrategy
+	}
+	switch strategy {
+	case InsertMultiAuto:
+	case InsertMultiValues:
+		if !session.Engine.SupportInsertMany() {
+			return InsertMultiRows, nil
+		}
+		return strategy, nil
+	case InsertMultiCopy:
+		if !session.supportsCopy() {
+			return strategy, ErrInsertCopyUnsupported
+		}
+		return strategy, nil
+	default:
+		return strategy, nil
+	}
+
+	switch {
+	case !session.Engine.SupportInsertMany():
+		return InsertMultiRows, nil
+	case rows <= session.insertChunkRows(cols):
+		return InsertMultiValues, nil
+	case session.supportsCopy():
+		return InsertMultiCopy, nil
+	case session.Engine.dialect.DBType() == core.SQLITE:
+		// no round trips to save, a prepared insert skips the parsing of
+		// the large statements
+		return InsertMultiRows, nil
+	}
+	return InsertMultiValues, nil
+}
+
+// execInsertMulti inserts rows of the columns colNames, args being the
+// values of the rows one after the other, and returns the number of the
+// inserted records. The statements of more than a row are executed in the
+// transaction of the session, or in one of their own.
+func (session *Session) execInsertMulti(colNames []string, rows int, args []interface{}) (int64, error) {
+	cols := len(colNames)
+	if len(args) != rows*cols {
+		return 0, errors.New("the beans have different columns to insert")
+	}
+	strategy, err := session.insertMultiStrategy(rows, cols)
+	if err != nil {
+		return 0, err
+	}
+
+	chunk := session.insertChunkRows(cols)
+	if strategy == InsertMultiValues && rows <= chunk {
+		res, err := session.exec(session.insertValuesSQL(colNames, rows), args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	}
+
+	var affected int64
+	err = session.inInsertTx(func() error {
+		switch strategy {
+		case InsertMultiRows:
+			affected, err = session.insertRows(colNames, rows, args)
+		case InsertMultiCopy:
+			affected, err = session.copyRows(colNames, rows, args)
+		default:
+			for start := 0; start < rows; start += chunk {
+				end := start + chunk
+				if end > rows {
+					end = rows
+				}
+				var res sql.Result
+				res, err = session.exec(session.insertValuesSQL(colNames, end-start), args[start*cols:end*cols]...)
+				if err != nil {
+					return err
+				}
+				var n int64
+				if n, err = res.RowsAffected(); err != nil {
+					return err
+				}
+				affected += n
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+// inInsertTx runs f in the transaction of the session, or in a transaction
+// of its own committed after f
+func (session *Session) inInsertTx(f func() error) error {
+	if !session.IsAutoCommit {
+		return f()
+	}
+
+	session.Engine.checkPool(session.DB())
+	tx, err := session.DB().BeginTx(session.Ctx(), nil)
+	if err != nil {
+		return err
+	}
+	session.Tx, session.IsAutoCommit = tx, false
+	err = f()
+	session.Tx, session.IsAutoCommit = nil, true
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertValuesSQL returns the insert of rows of the columns colNames
+func (session *Session) insertValuesSQL(colNames []string, rows int) string {
+	var quote = session.Engine.QuoteStr()
+	var oracle = session.Engine.dialect.DBType() == core.ORACLE
+	buf := getSQLBuffer()
+	defer putSQLBuffer(buf)
+	if oracle {
+		buf.WriteString("INSERT ALL")
+	} else {
+		buf.WriteString("INSERT")
+	}
+	into := func() {
+		buf.WriteString(" INTO ")
+		buf.WriteString(session.Engine.Quote(session.Statement.TableName()))
+		buf.WriteString(" (")
+		buf.WriteString(quote)
+		writeJoin(buf, colNames, quote+", "+quote)
+		buf.WriteString(quote)
+		buf.WriteString(") VALUES (")
+	}
+	into()
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			if oracle {
+				buf.WriteString(")")
+				into()
+			} else {
+				buf.WriteString("),(")
+			}
+		}
+		writePlaces(buf, len(colNames))
+	}
+	buf.WriteString(")")
+	if oracle {
+		buf.WriteString(" SELECT 1 FROM DUAL")
+	}
+	return buf.String()
+}
+
+func writePlaces(buf *bytes.Buffer, n int) {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString("?")
+	}
+}
+
+// insertRows executes the single row insert of the columns colNames once
+// per row, prepared in the transaction of the session
+func (session *Session) insertRows(colNames []string, rows int, args []interface{}) (int64, error) {
+	sqlStr := session.insertValuesSQL(colNames, 1)
+	for _, filter := range session.Engine.dialect.Filters() {
+		sqlStr = filter.Do(sqlStr, session.Engine.dialect, session.Statement.RefTable)
+	}
+	stmt, err := session.Tx.PrepareContext(session.Ctx(), sqlStr)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	cols := len(colNames)
+	var affected int64
+	for i := 0; i < rows; i++ {
+		rowArgs := args[i*cols : (i+1)*cols]
+		session.saveLastSQL(sqlStr, rowArgs...)
+		res, _, err := session.interceptExec(sqlStr, rowArgs, func(s string, a []interface{}) (sql.Result, error) {
+			return session.logSQLExecutionTime(s, a, func() (sql.Result, error) {
+				if s != sqlStr {
+					// rewritten by an interceptor
+					return session.Tx.Exec(s, a...)
+				}
+				return stmt.Exec(a...)
+			})
+		})
+		if err != nil {
+			return affected, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return affected, err
+		}
+		affected += n
+	}
+	session.markWrite()
+	session.invalidateQueryCache(sqlStr)
+	return affected, nil
+}
+
+// copyRows streams the rows of the columns colNames by COPY FROM STDIN,
+// which lib/pq prepares in a transaction, a row per exec and an exec
+// without args to end the copy
+func (session *Session) copyRows(colNames []string, rows int, args []interface{}) (int64, error) {
+	quote := session.Engine.QuoteStr()
+	buf := getSQLBuffer()
+	buf.WriteString("COPY ")
+	buf.WriteString(session.Engine.Quote(session.Statement.TableName()))
+	buf.WriteString(" (")
+	buf.WriteString(quote)
+	writeJoin(buf, colNames, quote+", "+quote)
+	buf.WriteString(quote)
+	buf.WriteString(") FROM STDIN")
+	sqlStr := buf.String()
+	putSQLBuffer(buf)
+
+	session.saveLastSQL(sqlStr)
+	_, err := session.logSQLExecutionTime(sqlStr, nil, func() (sql.Result, error) {
+		stmt, err := session.Tx.PrepareContext(session.Ctx(), sqlStr)
+		if err != nil {
+			return nil, err
+		}
+		defer stmt.Close()
+
+		cols := len(colNames)
+		for i := 0; i < rows; i++ {
+			if _, err := stmt.Exec(args[i*cols : (i+1)*cols]...); err != nil {
+				return nil, err
+			}
+		}
+		return stmt.Exec()
+	})
+	if err != nil {
+		return 0, err
+	}
+	session.markWrite()
+	// the tables of the copy are the ones of an insert
+	session.invalidateQueryCache(session.insertValuesSQL(colNames, 1))
+	return int64(rows), nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type InsertStrategyUser struct {
+	Id   int64
+	Name string `xorm:"unique"`
+	Age  int
+}
+
+func insertStrategyUsers(prefix string, n int) []InsertStrategyUser {
+	var users = make([]InsertStrategyUser, n)
+	for i := range users {
+		users[i] = InsertStrategyUser{Name: fmt.Sprintf("%s%d", prefix, i), Age: i}
+	}
+	return users
+}
+
+func TestInsertMultiStrategy(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(InsertStrategyUser))
+
+	// more rows than the args of a statement allow
+	const n = 1200
+	for _, strategy := range []InsertMultiStrategy{InsertMultiAuto, InsertMultiValues, InsertMultiRows} {
+		prefix := fmt.Sprintf("s%d-", strategy)
+		users := insertStrategyUsers(prefix, n)
+		affected, err := testEngine.NewSession().InsertMultiStrategy(strategy).InsertMulti(&users)
+		assert.NoError(t, err)
+		assert.EqualValues(t, n, affected)
+
+		cnt, err := testEngine.Where("name LIKE ?", prefix+"%").Count(new(InsertStrategyUser))
+		assert.NoError(t, err)
+		assert.EqualValues(t, n, cnt)
+	}
+
+	testEngine.SetInsertMultiStrategy(InsertMultiRows)
+	defer testEngine.SetInsertMultiStrategy(InsertMultiAuto)
+	users := insertStrategyUsers("engine-", 3)
+	affected, err := testEngine.Insert(&users)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, affected)
+}
+
+func TestInsertMultiStrategyRollback(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(InsertStrategyUser))
+
+	for _, strategy := range []InsertMultiStrategy{InsertMultiValues, InsertMultiRows} {
+		users := insertStrategyUsers("dup", 1200)
+		users[len(users)-1].Name = users[0].Name
+		_, err := testEngine.NewSession().InsertMultiStrategy(strategy).InsertMulti(&users)
+		assert.Error(t, err)
+
+		// the inserts of the first rows are rolled back
+		cnt, err := testEngine.Count(new(InsertStrategyUser))
+		assert.NoError(t, err)
+		assert.EqualValues(t, 0, cnt)
+	}
+
+	// in the transaction of the session, left to the caller
+	session := testEngine.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+	users := insertStrategyUsers("tx", 1200)
+	affected, err := session.InsertMultiStrategy(InsertMultiValues).InsertMulti(&users)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1200, affected)
+	assert.NoError(t, session.Rollback())
+
+	cnt, err := testEngine.Count(new(InsertStrategyUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+}
+
+func TestInsertMultiCopy(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(InsertStrategyUser))
+
+	session := testEngine.NewSession()
+	defer session.Close()
+
+	users := insertStrategyUsers("copy", 10)
+	affected, err := session.InsertMultiStrategy(InsertMultiCopy).InsertMulti(&users)
+	if !session.supportsCopy() {
+		assert.Equal(t, ErrInsertCopyUnsupported, err)
+
+		// the strategy is reset after the insert
+		affected, err = session.InsertMulti(&users)
+	}
+	assert.NoError(t, err)
+	assert.EqualValues(t, 10, affected)
+
+	cnt, err := testEngine.Count(new(InsertStrategyUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 10, cnt)
+}
+
+func TestInsertMultiAutoStrategy(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	session := testEngine.NewSession()
+	defer session.Close()
+
+	strategy, err := session.insertMultiStrategy(10, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, InsertMultiValues, strategy)
+
+	strategy, err = session.insertMultiStrategy(100000, 2)
+	assert.NoError(t, err)
+	switch {
+	case session.supportsCopy():
+		assert.Equal(t, InsertMultiCopy, strategy)
+	case testEngine.Dialect().DBType() == core.SQLITE:
+		assert.Equal(t, InsertMultiRows, strategy)
+	default:
+		assert.Equal(t, InsertMultiValues, strategy)
+	}
+
+	session.InsertMultiStrategy(InsertMultiRows)
+	strategy, err = session.insertMultiStrategy(10, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, InsertMultiRows, strategy)
+
+	if testEngine.Dialect().DBType() == core.SQLITE {
+		assert.EqualValues(t, 499, session.insertChunkRows(2))
+	}
+	maxArgs, maxRows := insertLimits(core.MSSQL)
+	assert.EqualValues(t, 2100, maxArgs)
+	assert.EqualValues(t, 1000, maxRows)
+}
@@ -0,0 +1,145 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+)
+
+// ErrStatementSkipped is returned by a query which an interceptor
+// short-circuited without returning an error
+var ErrStatementSkipped = errors.New("Statement skipped by an interceptor")
+
+// Operation is the kind of a statement seen by the interceptors
+type Operation int
+
+// the operations of the statements
+const (
+	OperationQuery Operation = iota
+	OperationExec
+)
+
+func (op Operation) String() string {
+	if op == OperationExec {
+		return "exec"
+	}
+	return "query"
+}
+
+// Invocation is a statement about to be executed. The interceptors may
+// rewrite the SQL and the args before calling the next handler.
+type Invocation struct {
+	Session *Session
+	Op      Operation
+	SQL     string
+	Args    []interface{}
+	// Bean is the bean the statement is mapped to, nil for the raw SQLs
+	Bean interface{}
+	// Result is the result of an exec, an interceptor short-circuiting an
+	// exec may set it
+	Result sql.Result
+
+	executed bool
+}
+
+// Handler executes an invocation
+type Handler func(inv *Invocation) error
+
+// Interceptor wraps the execution of every statement of an engine. It calls
+// next to go on, or returns without calling it to short-circuit the
+// statement.
+type Interceptor interface {
+	Intercept(inv *Invocation, next Handler) error
+}
+
+// InterceptorFunc is an Interceptor as a function
+type InterceptorFunc func(inv *Invocation, next Handler) error
+
+// Intercept implements Interceptor
+func (f InterceptorFunc) Intercept(inv *Invocation, next Handler) error {
+	return f(inv, next)
+}
+
+// Use appends interceptors to the engine, the first one is the outermost.
+// It should be called before the engine is used.
+func (engine *Engine) Use(interceptors ...Interceptor) {
+	engine.interceptors = append(engine.interceptors, interceptors...)
+}
+
+// intercept runs do through the interceptors of the engine, wrapped by the
+// SQL hooks, and classifies its errors. The query is not executed but
+// captured while explaining or converting to SQL.
+func (session *Session) intercept(inv *Invocation, do Handler) error {
+	if err := session.Statement.checkTenant(); err != nil {
+		return err
+	}
+
+	args, err := session.Engine.convertArgs(inv.Args)
+	if err != nil {
+		return err
+	}
+	inv.Args = args
+
+	if plan := session.explaining; plan != nil {
+		plan.SQL, plan.Args = inv.SQL, inv.Args
+		return errExplained
+	}
+
+	inv.Session = session
+	inv.Bean = session.Statement.bean
+	execute := do
+	do = session.hookSQL(func(inv *Invocation) error {
+		return session.Engine.classifyError(execute(inv))
+	})
+
+	interceptors := session.Engine.interceptors
+	if len(interceptors) == 0 {
+		return do(inv)
+	}
+
+	var handler = func(inv *Invocation) error {
+		inv.executed = true
+		return do(inv)
+	}
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(inv *Invocation) error {
+			return interceptor.Intercept(inv, next)
+		}
+	}
+	return handler(inv)
+}
+
+// interceptQuery runs the query do through the interceptors, with the SQL
+// and the args they may have rewritten
+func (session *Session) interceptQuery(sqlStr string, args []interface{}, do func(sqlStr string, args []interface{}) error) error {
+	inv := &Invocation{Op: OperationQuery, SQL: sqlStr, Args: args}
+	err := session.intercept(inv, func(inv *Invocation) error {
+		return do(inv.SQL, inv.Args)
+	})
+	if err == nil && !inv.executed && len(session.Engine.interceptors) > 0 {
+		return ErrStatementSkipped
+	}
+	return err
+}
+
+// interceptExec runs the exec do through the interceptors, a short-circuited
+// exec without result affects no rows
+func (session *Session) interceptExec(sqlStr string, args []interface{}, do func(sqlStr string, args []interface{}) (sql.Result, error)) (sql.Result, string, error) {
+	inv := &Invocation{Op: OperationExec, SQL: sqlStr, Args: args}
+	err := session.intercept(inv, func(inv *Invocation) (err error) {
+		inv.Result, err = do(inv.SQL, inv.Args)
+		return err
+	})
+	if err != nil {
+		return nil, inv.SQL, err
+	}
+	if inv.Result == nil {
+		inv.Result = driver.RowsAffected(0)
+	}
+	return inv.Result, inv.SQL, nil
+}
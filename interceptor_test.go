@@ -0,0 +1,130 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type InterceptedUser struct {
+	Id   int64
+	Name string
+}
+
+func TestInterceptorObserve(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(InterceptedUser)))
+
+	var invs []Invocation
+	testEngine.Use(InterceptorFunc(func(inv *Invocation, next Handler) error {
+		err := next(inv)
+		invs = append(invs, *inv)
+		return err
+	}))
+	defer func() { testEngine.interceptors = nil }()
+
+	user := InterceptedUser{Name: "lunny"}
+	_, err := testEngine.Insert(&user)
+	assert.NoError(t, err)
+	if assert.EqualValues(t, 1, len(invs)) {
+		assert.EqualValues(t, OperationExec, invs[0].Op)
+		assert.True(t, strings.HasPrefix(strings.ToUpper(invs[0].SQL), "INSERT"))
+		assert.True(t, invs[0].Bean == &user)
+		assert.NotNil(t, invs[0].Result)
+	}
+
+	invs = nil
+	var users []InterceptedUser
+	assert.NoError(t, testEngine.Find(&users))
+	assert.EqualValues(t, 1, len(users))
+	if assert.EqualValues(t, 1, len(invs)) {
+		assert.EqualValues(t, OperationQuery, invs[0].Op)
+	}
+
+	invs = nil
+	total, err := testEngine.Count(new(InterceptedUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.EqualValues(t, 1, len(invs))
+}
+
+func TestInterceptorRewrite(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(InterceptedUser)))
+
+	_, err := testEngine.Insert(&InterceptedUser{Name: "lunny"}, &InterceptedUser{Name: "xlw"})
+	assert.NoError(t, err)
+	tableName := testEngine.TableMapper.Obj2Table("InterceptedUser")
+
+	// the interceptors run in the order they are used
+	var order []string
+	testEngine.Use(
+		InterceptorFunc(func(inv *Invocation, next Handler) error {
+			order = append(order, "first")
+			return next(inv)
+		}),
+		InterceptorFunc(func(inv *Invocation, next Handler) error {
+			order = append(order, "second")
+			if inv.Op == OperationQuery {
+				inv.SQL = "SELECT * FROM " + testEngine.Quote(tableName) + " WHERE name = ?"
+				inv.Args = []interface{}{"xlw"}
+			}
+			return next(inv)
+		}),
+	)
+	defer func() { testEngine.interceptors = nil }()
+
+	var users []InterceptedUser
+	assert.NoError(t, testEngine.Find(&users))
+	assert.EqualValues(t, []string{"first", "second"}, order)
+	if assert.EqualValues(t, 1, len(users)) {
+		assert.EqualValues(t, "xlw", users[0].Name)
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(InterceptedUser)))
+
+	_, err := testEngine.Insert(&InterceptedUser{Name: "lunny"})
+	assert.NoError(t, err)
+	tableName := testEngine.TableMapper.Obj2Table("InterceptedUser")
+
+	errDenied := errors.New("denied")
+	testEngine.Use(InterceptorFunc(func(inv *Invocation, next Handler) error {
+		switch {
+		case strings.HasPrefix(strings.ToUpper(inv.SQL), "DELETE"):
+			return errDenied
+		case strings.HasPrefix(strings.ToUpper(inv.SQL), "UPDATE"):
+			return nil
+		case inv.Op == OperationQuery && inv.Bean == nil:
+			return nil
+		}
+		return next(inv)
+	}))
+	defer func() { testEngine.interceptors = nil }()
+
+	_, err = testEngine.Id(1).Delete(new(InterceptedUser))
+	assert.EqualValues(t, errDenied, err)
+
+	// a skipped exec affects no rows
+	cnt, err := testEngine.Id(1).Update(&InterceptedUser{Name: "xlw"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+
+	// a skipped query has no rows to return
+	_, err = testEngine.Query("SELECT * FROM " + testEngine.Quote(tableName))
+	assert.EqualValues(t, ErrStatementSkipped, err)
+
+	var user InterceptedUser
+	has, err := testEngine.Id(1).Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", user.Name)
+}
@@ -0,0 +1,136 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// joinTable is a table joined by Join and its alias
+type joinTable struct {
+	name  string
+	alias string
+}
+
+// genJoinColumnStr returns the columns selected by a join into a struct
+// whose extends fields are the joined tables, as
+//
+//	type OrderUser struct {
+//		Order `xorm:"extends"`
+//		User  `xorm:"extends"`
+//	}
+//
+// each column is qualified by the alias of the table of its field, so the
+// columns of the same name are scanned into their own fields whatever the
+// order of the tables. It returns "" to select * when no field is a joined
+// table.
+func (statement *Statement) genJoinColumnStr() string {
+	table := statement.RefTable
+	if table == nil || table.Type == nil || table.Type.Kind() != reflect.Struct {
+		return ""
+	}
+
+	var mainAlias = statement.TableAlias
+	if mainAlias == "" {
+		mainAlias = statement.TableName()
+	}
+	aliases, joined := statement.joinFieldAliases(table.Type)
+	if !joined {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	for _, col := range table.Columns() {
+		if statement.OmitStr != "" {
+			if _, ok := getFlagForColumn(statement.columnMap, col); ok {
+				continue
+			}
+		}
+		if col.MapType == core.ONLYTODB || !statement.inScope(col) || statement.isLazy(col) {
+			continue
+		}
+
+		// the columns of the other fields are the ones of the main table
+		var alias = mainAlias
+		if i := strings.IndexByte(col.FieldName, '.'); i > 0 {
+			if a, ok := aliases[col.FieldName[:i]]; ok {
+				alias = a
+			}
+		}
+
+		if buf.Len() != 0 {
+			buf.WriteString(", ")
+		}
+		statement.Engine.QuoteTo(&buf, alias)
+		buf.WriteString(".")
+		statement.Engine.QuoteTo(&buf, col.Name)
+	}
+	return buf.String()
+}
+
+// joinFieldAliases returns the aliases of the tables of the extends fields
+// of t by field name. A field is the table aliased as its name, or else the
+// first table of its type not taken, the main table first. joined is true
+// if a field is a joined table.
+func (statement *Statement) joinFieldAliases(t reflect.Type) (aliases map[string]string, joined bool) {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		tags := splitTag(t.Field(i).Tag.Get(statement.Engine.TagIdentifier))
+		if len(tags) > 0 && strings.ToUpper(tags[0]) == "EXTENDS" {
+			fields = append(fields, t.Field(i))
+		}
+	}
+
+	var tables = append([]joinTable{{statement.TableName(), statement.TableAlias}}, statement.joinTables...)
+	var taken = make([]bool, len(tables))
+	var take = func(f reflect.StructField, i int) {
+		taken[i] = true
+		if tables[i].alias != "" {
+			aliases[f.Name] = tables[i].alias
+		} else {
+			aliases[f.Name] = tables[i].name
+		}
+		if i > 0 {
+			joined = true
+		}
+	}
+
+	_, columnMapper := statement.Engine.mappers(t)
+	aliases = make(map[string]string, len(fields))
+	for _, f := range fields {
+		var name = columnMapper.Obj2Table(f.Name)
+		for i, tb := range tables {
+			if !taken[i] && tb.alias != "" && (strings.EqualFold(tb.alias, name) || strings.EqualFold(tb.alias, f.Name)) {
+				take(f, i)
+				break
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if _, ok := aliases[f.Name]; ok {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct {
+			continue
+		}
+		tbName := statement.Engine.tbName(reflect.New(ft).Elem())
+		for i, tb := range tables {
+			if !taken[i] && strings.EqualFold(tb.name, tbName) {
+				take(f, i)
+				break
+			}
+		}
+	}
+	return aliases, joined
+}
@@ -0,0 +1,171 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// ErrJSONPathNotSupported is returned by the updates of the JSON paths of
+// SetJSON on the dialects without JSON functions
+var ErrJSONPathNotSupported = errors.New("JSON paths are not supported by the dialect")
+
+// the JSON columns of PostgreSQL, which can be updated by jsonb_set
+const jsonb = "JSONB"
+
+// EmbeddedJSONTagHandler stores an embedded or a nested struct in one JSON
+// column instead of the columns of its fields as extends does
+func EmbeddedJSONTagHandler(ctx *tagContext) error {
+	t := ctx.fieldValue.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("embedded_json tag of the field %v which isn't a struct", ctx.col.FieldName)
+	}
+
+	converter, err := ctx.engine.serializeConverter("json", t)
+	if err != nil {
+		return err
+	}
+	if ctx.col.SQLType.Name == "" {
+		if ctx.engine.dialect.DBType() == core.POSTGRES {
+			ctx.col.SQLType = core.SQLType{Name: jsonb}
+		} else {
+			ctx.col.SQLType = core.SQLType{Name: core.Json}
+		}
+	}
+	ctx.engine.setColumnConverter(ctx.col, converter)
+	return nil
+}
+
+type jsonPathParam struct {
+	colName string
+	path    string
+	arg     interface{}
+}
+
+// SetJSON generates "Update ... Set column = JSON_SET(column, path, arg)",
+// arg is stored as JSON at path, as $.address.city or $.tags[0], in the
+// JSON document of column. The paths are updated on MySQL, PostgreSQL and
+// SQLite.
+func (statement *Statement) SetJSON(column, path string, arg interface{}) *Statement {
+	statement.jsonPaths = append(statement.jsonPaths, jsonPathParam{column, path, arg})
+	return statement
+}
+
+// genJSONPathSets returns the "column = JSON_SET(...)" of the JSON paths set
+// by SetJSON and their args
+func (statement *Statement) genJSONPathSets() ([]string, []interface{}, error) {
+	if len(statement.jsonPaths) == 0 {
+		return nil, nil, nil
+	}
+
+	var dbType = statement.Engine.dialect.DBType()
+	switch dbType {
+	case core.MYSQL, core.POSTGRES, core.SQLITE:
+	default:
+		return nil, nil, ErrJSONPathNotSupported
+	}
+
+	var colNames []string
+	var exprs = make(map[string]string)
+	var args = make(map[string][]interface{})
+	for _, p := range statement.jsonPaths {
+		data, err := json.Marshal(p.arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		path, err := parseJSONPath(p.path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		k := strings.ToLower(p.colName)
+		expr, ok := exprs[k]
+		if !ok {
+			colNames = append(colNames, p.colName)
+			expr = statement.Engine.Quote(p.colName)
+		}
+		switch dbType {
+		case core.MYSQL:
+			expr = "JSON_SET(" + expr + ", ?, CAST(? AS JSON))"
+			args[k] = append(args[k], p.path, string(data))
+		case core.POSTGRES:
+			expr = "jsonb_set(" + expr + ", ?::text[], ?::jsonb)"
+			args[k] = append(args[k], pgTextArray(path), string(data))
+		case core.SQLITE:
+			expr = "json_set(" + expr + ", ?, json(?))"
+			args[k] = append(args[k], p.path, string(data))
+		}
+		exprs[k] = expr
+	}
+
+	var sets = make([]string, 0, len(colNames))
+	var setArgs []interface{}
+	for _, colName := range colNames {
+		k := strings.ToLower(colName)
+		sets = append(sets, statement.Engine.Quote(colName)+" = "+exprs[k])
+		setArgs = append(setArgs, args[k]...)
+	}
+	return sets, setArgs, nil
+}
+
+// pgTextArray returns the text[] literal of the keys of a path
+func pgTextArray(elems []string) string {
+	quoted := make([]string, len(elems))
+	for i, elem := range elems {
+		elem = strings.Replace(elem, `\`, `\\`, -1)
+		quoted[i] = `"` + strings.Replace(elem, `"`, `\"`, -1) + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}"
+}
+
+// parseJSONPath returns the keys and the indexes of a path as $.a.b[0]
+func parseJSONPath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSON path %q doesn't start with $", path)
+	}
+
+	var elems []string
+	for rest := path[1:]; rest != ""; {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("invalid JSON path %q", path)
+			}
+			elems = append(elems, key)
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q", path)
+			}
+			if _, err := strconv.Atoi(rest[1:end]); err != nil {
+				return nil, fmt.Errorf("invalid index of JSON path %q", path)
+			}
+			elems = append(elems, rest[1:end])
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSON path %q", path)
+		}
+	}
+	if len(elems) == 0 {
+		return nil, fmt.Errorf("JSON path %q has no key", path)
+	}
+	return elems, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	var kases = []struct {
+		path     string
+		expected []string
+	}{
+		{"$.a", []string{"a"}},
+		{"$.a.b", []string{"a", "b"}},
+		{"$.tags[1]", []string{"tags", "1"}},
+		{"$[0].a", []string{"0", "a"}},
+	}
+	for _, kase := range kases {
+		elems, err := parseJSONPath(kase.path)
+		assert.NoError(t, err)
+		assert.EqualValues(t, kase.expected, elems, kase.path)
+	}
+
+	for _, path := range []string{"a.b", "$", "$.", "$.a..b", "$.a[x]", "$.a[1"} {
+		_, err := parseJSONPath(path)
+		assert.Error(t, err, path)
+	}
+
+	assert.EqualValues(t, `{"a","b\"c","0"}`, pgTextArray([]string{"a", `b"c`, "0"}))
+}
+
+type JSONAddress struct {
+	City   string
+	Street string
+	Tags   []string
+}
+
+type JSONMeta struct {
+	Source string
+	Rank   int
+}
+
+type EmbeddedJSONStruct struct {
+	Id       int64
+	JSONMeta `xorm:"embedded_json"`
+	Address  JSONAddress  `xorm:"embedded_json"`
+	Previous *JSONAddress `xorm:"embedded_json"`
+}
+
+func TestEmbeddedJSON(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(EmbeddedJSONStruct))
+
+	table := testEngine.TableInfo(new(EmbeddedJSONStruct))
+	col := table.GetColumn(testEngine.ColumnMapper.Obj2Table("Address"))
+	assert.NotNil(t, col)
+	assert.True(t, col.SQLType.IsJson())
+	assert.NotNil(t, table.GetColumn(testEngine.ColumnMapper.Obj2Table("JSONMeta")))
+	assert.Nil(t, table.GetColumn(testEngine.ColumnMapper.Obj2Table("City")))
+
+	s := EmbeddedJSONStruct{
+		JSONMeta: JSONMeta{Source: "import", Rank: 2},
+		Address:  JSONAddress{City: "Lyon", Street: "Rue", Tags: []string{"home"}},
+	}
+	cnt, err := testEngine.Insert(&s)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var got EmbeddedJSONStruct
+	has, err := testEngine.ID(s.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, s, got)
+
+	switch testEngine.Dialect().DBType() {
+	case core.MYSQL, core.POSTGRES, core.SQLITE:
+	default:
+		_, err = testEngine.ID(s.Id).SetJSON("address", "$.City", "Paris").Update(new(EmbeddedJSONStruct))
+		assert.EqualValues(t, ErrJSONPathNotSupported, err)
+		return
+	}
+
+	cnt, err = testEngine.ID(s.Id).
+		SetJSON("address", "$.City", "Paris").
+		SetJSON("address", "$.Tags[0]", "work").
+		SetJSON(testEngine.ColumnMapper.Obj2Table("JSONMeta"), "$.Rank", 3).
+		Update(new(EmbeddedJSONStruct))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	got = EmbeddedJSONStruct{}
+	has, err = testEngine.ID(s.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "Paris", got.Address.City)
+	assert.EqualValues(t, "Rue", got.Address.Street)
+	assert.EqualValues(t, []string{"work"}, got.Address.Tags)
+	assert.EqualValues(t, JSONMeta{Source: "import", Rank: 3}, got.JSONMeta)
+	assert.Nil(t, got.Previous)
+
+	_, err = testEngine.ID(s.Id).SetJSON("address", "City", "Nice").Update(new(EmbeddedJSONStruct))
+	assert.Error(t, err)
+}
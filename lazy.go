@@ -0,0 +1,88 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+
+	"github.com/go-xorm/core"
+)
+
+// LazyTagHandler makes the column lazy, as `xorm:"text lazy"`: it isn't
+// selected by Find and Get unless given by Cols, Eager or a scope of its
+// groups and it's loaded on demand by LoadColumn
+func LazyTagHandler(ctx *tagContext) error {
+	if ctx.col.IsPrimaryKey {
+		return fmt.Errorf("primary key %v can't be lazy", ctx.col.FieldName)
+	}
+	ctx.engine.lazyColumns.Store(ctx.col, true)
+	return nil
+}
+
+// Eager selects the lazy columns too
+func (statement *Statement) Eager() *Statement {
+	statement.eager = true
+	return statement
+}
+
+// isLazy returns true if the lazy column col isn't selected by the
+// statement, the scopes select the lazy columns of their groups
+func (statement *Statement) isLazy(col *core.Column) bool {
+	if statement.eager || len(statement.scopes) > 0 {
+		return false
+	}
+	_, ok := statement.Engine.lazyColumns.Load(col)
+	return ok
+}
+
+// lazyColumnNames returns the names of the lazy columns of table
+func (engine *Engine) lazyColumnNames(table *core.Table) []string {
+	var names []string
+	for _, col := range table.Columns() {
+		if _, ok := engine.lazyColumns.Load(col); ok {
+			names = append(names, col.Name)
+		}
+	}
+	return names
+}
+
+// LoadColumn loads the columns, the lazy ones when none is given, of the
+// record of the primary key of bean into its fields, the other fields are
+// left as is
+func (session *Session) LoadColumn(bean interface{}, columns ...string) error {
+	v := rValue(bean)
+	table, err := session.Engine.autoMapType(v)
+	if err != nil {
+		if session.IsAutoClose {
+			session.Close()
+		}
+		return err
+	}
+	pk, err := session.Engine.idOfV(v)
+	if err == nil && (len(table.PrimaryKeys) == 0 || len(pk) != len(table.PrimaryKeys) || isPKZero(pk)) {
+		err = ErrNoPrimaryKey
+	}
+	if len(columns) == 0 {
+		columns = session.Engine.lazyColumnNames(table)
+	}
+	if err == nil && len(columns) == 0 {
+		err = fmt.Errorf("table %v has no lazy column", table.Name)
+	}
+	if err != nil {
+		if session.IsAutoClose {
+			session.Close()
+		}
+		return err
+	}
+
+	has, err := session.Id(pk).NoAutoCondition().NoCache().Cols(columns...).Get(bean)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return ErrNotExist
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type LazyArticle struct {
+	Id    int64
+	Title string
+	Body  string `xorm:"text lazy groups('detail')"`
+	Notes string `xorm:"text lazy"`
+}
+
+func TestLazyColumns(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(LazyArticle))
+
+	_, err := testEngine.Insert(&LazyArticle{Title: "first", Body: "body", Notes: "notes"})
+	assert.NoError(t, err)
+
+	var articles []LazyArticle
+	assert.NoError(t, testEngine.Find(&articles))
+	if assert.EqualValues(t, 1, len(articles)) {
+		assert.EqualValues(t, "first", articles[0].Title)
+		assert.EqualValues(t, "", articles[0].Body)
+		assert.EqualValues(t, "", articles[0].Notes)
+	}
+
+	var article LazyArticle
+	has, err := testEngine.Id(1).Get(&article)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "", article.Body)
+
+	// the other fields are left as is
+	article.Title = "changed"
+	assert.NoError(t, testEngine.LoadColumn(&article, "body"))
+	assert.EqualValues(t, "body", article.Body)
+	assert.EqualValues(t, "", article.Notes)
+	assert.EqualValues(t, "changed", article.Title)
+
+	assert.NoError(t, testEngine.LoadColumn(&article))
+	assert.EqualValues(t, "notes", article.Notes)
+
+	article = LazyArticle{}
+	has, err = testEngine.Eager().Id(1).Get(&article)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "body", article.Body)
+	assert.EqualValues(t, "notes", article.Notes)
+
+	// the lazy columns of the scopes are selected
+	articles = nil
+	assert.NoError(t, testEngine.Scope("detail").Find(&articles))
+	if assert.EqualValues(t, 1, len(articles)) {
+		assert.EqualValues(t, "body", articles[0].Body)
+		assert.EqualValues(t, "", articles[0].Notes)
+	}
+
+	articles = nil
+	assert.NoError(t, testEngine.Cols("id", "notes").Find(&articles))
+	if assert.EqualValues(t, 1, len(articles)) {
+		assert.EqualValues(t, "notes", articles[0].Notes)
+	}
+
+	assert.EqualValues(t, ErrNoPrimaryKey, testEngine.LoadColumn(&LazyArticle{}))
+	assert.EqualValues(t, ErrNotExist, testEngine.LoadColumn(&LazyArticle{Id: 2}))
+}
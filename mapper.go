@@ -0,0 +1,57 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+
+	"github.com/go-xorm/core"
+)
+
+// SetTableMapperFor maps the table name and the column names of the struct
+// t, or of the struct pointed by t, with mapper instead of TableMapper and
+// ColumnMapper. It should be set before the table is used.
+func (engine *Engine) SetTableMapperFor(t reflect.Type, mapper core.IMapper) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	if engine.typeMappers == nil {
+		engine.typeMappers = make(map[reflect.Type]core.IMapper)
+	}
+	engine.typeMappers[t] = mapper
+	delete(engine.Tables, t)
+}
+
+// SetTableMapperForPackage maps the table names and the column names of the
+// structs of the package of import path pkgPath with mapper, the mapper of
+// SetTableMapperFor wins. It should be set before the tables are used.
+func (engine *Engine) SetTableMapperForPackage(pkgPath string, mapper core.IMapper) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	if engine.pkgMappers == nil {
+		engine.pkgMappers = make(map[string]core.IMapper)
+	}
+	engine.pkgMappers[pkgPath] = mapper
+	for t := range engine.Tables {
+		if t.PkgPath() == pkgPath {
+			delete(engine.Tables, t)
+		}
+	}
+}
+
+// mappers returns the mappers of the table name and of the column names of
+// the struct t
+func (engine *Engine) mappers(t reflect.Type) (tableMapper, columnMapper core.IMapper) {
+	if mapper, ok := engine.typeMappers[t]; ok {
+		return mapper, mapper
+	}
+	if mapper, ok := engine.pkgMappers[t.PkgPath()]; ok {
+		return mapper, mapper
+	}
+	return engine.TableMapper, engine.ColumnMapper
+}
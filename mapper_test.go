@@ -0,0 +1,68 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type LegacyAccount struct {
+	Id          int64
+	AccountName string
+}
+
+// resetTableMappers removes the mappers of the types and of the packages
+func resetTableMappers() {
+	testEngine.mutex.Lock()
+	defer testEngine.mutex.Unlock()
+	testEngine.typeMappers = nil
+	testEngine.pkgMappers = nil
+	testEngine.Tables = make(map[reflect.Type]*core.Table)
+}
+
+func TestSetTableMapperFor(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	testEngine.SetTableMapperFor(reflect.TypeOf(new(LegacyAccount)), core.SameMapper{})
+	defer resetTableMappers()
+
+	table := testEngine.TableInfo(new(LegacyAccount))
+	assert.EqualValues(t, "LegacyAccount", table.Name)
+	assert.NotNil(t, table.GetColumn("AccountName"))
+	assert.EqualValues(t, "LegacyAccount", testEngine.tbName(reflect.ValueOf(LegacyAccount{})))
+
+	// the other tables keep the mappers of the engine
+	assert.EqualValues(t, testEngine.TableMapper.Obj2Table("Userinfo"), testEngine.TableInfo(new(Userinfo)).Name)
+
+	assertSync(t, new(LegacyAccount))
+	_, err := testEngine.Insert(&LegacyAccount{AccountName: "legacy"})
+	assert.NoError(t, err)
+
+	var account LegacyAccount
+	has, err := testEngine.Where(testEngine.Quote("AccountName")+" = ?", "legacy").Get(&account)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "legacy", account.AccountName)
+}
+
+func TestSetTableMapperForPackage(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	pkgPath := reflect.TypeOf(LegacyAccount{}).PkgPath()
+	testEngine.SetTableMapperForPackage(pkgPath, core.SameMapper{})
+	defer resetTableMappers()
+
+	table := testEngine.TableInfo(new(Userinfo))
+	assert.EqualValues(t, "Userinfo", table.Name)
+	assert.NotNil(t, table.GetColumn("Username"))
+
+	// the mapper of the type wins
+	testEngine.SetTableMapperFor(reflect.TypeOf(LegacyAccount{}), core.GonicMapper{})
+	assert.EqualValues(t, "legacy_account", testEngine.TableInfo(new(LegacyAccount)).Name)
+}
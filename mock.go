@@ -0,0 +1,372 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-xorm/core"
+)
+
+const mockDriverName = "xorm_mock"
+
+var (
+	mockOnce sync.Once
+	mockSeq  int64
+	mocks    sync.Map // the data source names to their *MockEngine
+)
+
+// MockEngine is an engine without database for the unit tests, it records
+// the SQLs with their args and returns the canned results of the patterns
+// they match. The SQLs matching no pattern return no rows and affect a
+// record, with the next id of the mock as its insert id.
+type MockEngine struct {
+	*Engine
+
+	dsn     string
+	mutex   sync.Mutex
+	records []MockRecord
+	results []*MockResult
+	lastID  int64
+}
+
+// MockRecord is a SQL executed by a MockEngine, the transactions are
+// recorded as BEGIN, COMMIT and ROLLBACK
+type MockRecord struct {
+	SQL  string
+	Args []interface{}
+}
+
+// MockResult is the canned result of the SQLs matching a pattern
+type MockResult struct {
+	engine   *MockEngine
+	pattern  *regexp.Regexp
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+	insertID int64
+	err      error
+	once     bool
+}
+
+// NewMockEngine returns a mock of an engine of SQLite
+func NewMockEngine() (*MockEngine, error) {
+	return NewMockEngineOf(core.SQLITE)
+}
+
+// NewMockEngineOf returns a mock of an engine of the database dbType, whose
+// dialect generates the SQLs
+func NewMockEngineOf(dbType core.DbType) (*MockEngine, error) {
+	mockOnce.Do(func() {
+		sql.Register(mockDriverName, mockDriver{})
+		core.RegisterDriver(mockDriverName, mockDriver{})
+	})
+
+	m := &MockEngine{dsn: fmt.Sprintf("%s:%d", dbType, atomic.AddInt64(&mockSeq, 1))}
+	mocks.Store(m.dsn, m)
+	engine, err := NewEngine(mockDriverName, m.dsn)
+	if err != nil {
+		mocks.Delete(m.dsn)
+		return nil, err
+	}
+	m.Engine = engine
+	return m, nil
+}
+
+// Close closes the engine and forgets the mock
+func (m *MockEngine) Close() error {
+	mocks.Delete(m.dsn)
+	return m.Engine.Close()
+}
+
+// On returns the result of the SQLs matching the regular expression
+// pattern, the patterns are matched in the order of their results
+func (m *MockEngine) On(pattern string) *MockResult {
+	result := &MockResult{
+		engine:   m,
+		pattern:  regexp.MustCompile(pattern),
+		affected: 1,
+	}
+	m.mutex.Lock()
+	m.results = append(m.results, result)
+	m.mutex.Unlock()
+	return result
+}
+
+// Records returns the SQLs executed since the creation or the last reset
+func (m *MockEngine) Records() []MockRecord {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]MockRecord(nil), m.records...)
+}
+
+// LastRecord returns the last executed SQL
+func (m *MockEngine) LastRecord() MockRecord {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.records) == 0 {
+		return MockRecord{}
+	}
+	return m.records[len(m.records)-1]
+}
+
+// Reset forgets the records and the results
+func (m *MockEngine) Reset() {
+	m.mutex.Lock()
+	m.records = nil
+	m.results = nil
+	m.mutex.Unlock()
+}
+
+// Rows makes the result return rows of the columns
+func (r *MockResult) Rows(columns []string, rows ...[]interface{}) *MockResult {
+	r.columns = columns
+	r.rows = r.rows[:0]
+	for _, row := range rows {
+		values := make([]driver.Value, len(row))
+		for i, v := range row {
+			value, err := driver.DefaultParameterConverter.ConvertValue(v)
+			if err != nil {
+				panic(fmt.Sprintf("mock row %v: %v", row, err))
+			}
+			values[i] = value
+		}
+		r.rows = append(r.rows, values)
+	}
+	return r
+}
+
+// Beans makes the result return the records of beans, a slice or pointers
+// to the structs of a table, as they are inserted
+func (r *MockResult) Beans(beans interface{}) *MockResult {
+	v := reflect.Indirect(reflect.ValueOf(beans))
+	if v.Kind() != reflect.Slice {
+		v = reflect.ValueOf([]interface{}{beans})
+	}
+
+	session := r.engine.NewSession()
+	defer session.Close()
+	var columns []string
+	var rows [][]interface{}
+	for i := 0; i < v.Len(); i++ {
+		bean := reflect.Indirect(reflect.ValueOf(v.Index(i).Interface()))
+		table, err := r.engine.autoMapType(bean)
+		if err != nil {
+			panic(fmt.Sprintf("mock bean %v: %v", bean.Type(), err))
+		}
+		var row []interface{}
+		for _, col := range table.Columns() {
+			if col.MapType == core.ONLYTODB {
+				continue
+			}
+			fieldValue, err := col.ValueOfV(&bean)
+			if err != nil {
+				panic(fmt.Sprintf("mock bean %v: %v", bean.Type(), err))
+			}
+			arg, err := session.value2Interface(col, *fieldValue)
+			if err != nil {
+				panic(fmt.Sprintf("mock bean %v: %v", bean.Type(), err))
+			}
+			if i == 0 {
+				columns = append(columns, col.Name)
+			}
+			row = append(row, arg)
+		}
+		rows = append(rows, row)
+	}
+	return r.Rows(columns, rows...)
+}
+
+// Affected makes the result affect n records
+func (r *MockResult) Affected(n int64) *MockResult {
+	r.affected = n
+	return r
+}
+
+// InsertID makes the result return the insert id
+func (r *MockResult) InsertID(id int64) *MockResult {
+	r.insertID = id
+	return r
+}
+
+// Error makes the result fail with err
+func (r *MockResult) Error(err error) *MockResult {
+	r.err = err
+	return r
+}
+
+// Once makes the result return once, to the next SQL matching it
+func (r *MockResult) Once() *MockResult {
+	r.once = true
+	return r
+}
+
+// record records a SQL and returns its result, nil for the SQLs matching
+// no pattern
+func (m *MockEngine) record(sqlStr string, args []driver.Value) *MockResult {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	record := MockRecord{SQL: sqlStr}
+	for _, arg := range args {
+		record.Args = append(record.Args, arg)
+	}
+	m.records = append(m.records, record)
+
+	for i, result := range m.results {
+		if result.pattern.MatchString(sqlStr) {
+			if result.once {
+				m.results = append(m.results[:i:i], m.results[i+1:]...)
+			}
+			return result
+		}
+	}
+	return nil
+}
+
+func (m *MockEngine) exec(sqlStr string, args []driver.Value) (driver.Result, error) {
+	result := m.record(sqlStr, args)
+	if result == nil {
+		return mockExecResult{id: atomic.AddInt64(&m.lastID, 1), affected: 1}, nil
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	return mockExecResult{id: result.insertID, affected: result.affected}, nil
+}
+
+func (m *MockEngine) query(sqlStr string, args []driver.Value) (driver.Rows, error) {
+	result := m.record(sqlStr, args)
+	if result == nil {
+		return &mockRows{}, nil
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	return &mockRows{columns: result.columns, rows: result.rows}, nil
+}
+
+// mockDriver is the sql and the core driver of the mocks, whose data
+// source names are their database types and their sequences
+type mockDriver struct{}
+
+func (mockDriver) Parse(driverName, dataSourceName string) (*core.Uri, error) {
+	idx := strings.Index(dataSourceName, ":")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid mock data source %v", dataSourceName)
+	}
+	return &core.Uri{DbType: core.DbType(dataSourceName[:idx]), DbName: "mock"}, nil
+}
+
+func (mockDriver) Open(name string) (driver.Conn, error) {
+	m, ok := mocks.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("mock %v is closed", name)
+	}
+	return &mockConn{m.(*MockEngine)}, nil
+}
+
+type mockConn struct {
+	engine *MockEngine
+}
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+	return &mockStmt{c.engine, query}, nil
+}
+
+func (c *mockConn) Close() error {
+	return nil
+}
+
+func (c *mockConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx ignores the options, as the isolations and the read only ones
+func (c *mockConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.engine.exec("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return &mockTx{c.engine}, nil
+}
+
+type mockTx struct {
+	engine *MockEngine
+}
+
+func (tx *mockTx) Commit() error {
+	_, err := tx.engine.exec("COMMIT", nil)
+	return err
+}
+
+func (tx *mockTx) Rollback() error {
+	_, err := tx.engine.exec("ROLLBACK", nil)
+	return err
+}
+
+type mockStmt struct {
+	engine *MockEngine
+	query  string
+}
+
+func (s *mockStmt) Close() error {
+	return nil
+}
+
+func (s *mockStmt) NumInput() int {
+	return -1
+}
+
+func (s *mockStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.engine.exec(s.query, args)
+}
+
+func (s *mockStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.engine.query(s.query, args)
+}
+
+type mockExecResult struct {
+	id       int64
+	affected int64
+}
+
+func (r mockExecResult) LastInsertId() (int64, error) {
+	return r.id, nil
+}
+
+func (r mockExecResult) RowsAffected() (int64, error) {
+	return r.affected, nil
+}
+
+type mockRows struct {
+	columns []string
+	rows    [][]driver.Value
+	idx     int
+}
+
+func (r *mockRows) Columns() []string {
+	return r.columns
+}
+
+func (r *mockRows) Close() error {
+	return nil
+}
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if r.idx >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.idx])
+	r.idx++
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockUser struct {
+	Id   int64
+	Name string
+	Age  int
+}
+
+func TestMockEngine(t *testing.T) {
+	m, err := NewMockEngine()
+	assert.NoError(t, err)
+	defer m.Close()
+	m.ShowSQL(false)
+
+	user := MockUser{Name: "lunny", Age: 30}
+	affected, err := m.Insert(&user)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, affected)
+	assert.EqualValues(t, 1, user.Id)
+	assert.EqualValues(t, MockRecord{
+		SQL:  "INSERT INTO `mock_user` (`name`,`age`) VALUES (?, ?)",
+		Args: []interface{}{"lunny", int64(30)},
+	}, m.LastRecord())
+
+	m.On(`^SELECT .* FROM .mock_user.`).Beans([]MockUser{{Id: 1, Name: "lunny", Age: 30}, {Id: 2, Name: "xlw", Age: 20}}).Once()
+	var users []MockUser
+	assert.NoError(t, m.Where("age > ?", 10).Find(&users))
+	assert.EqualValues(t, []MockUser{{1, "lunny", 30}, {2, "xlw", 20}}, users)
+	assert.EqualValues(t, []interface{}{int64(10)}, m.LastRecord().Args)
+
+	m.On(`count\(\*\)`).Rows([]string{"count(*)"}, []interface{}{42})
+	cnt, err := m.Count(new(MockUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 42, cnt)
+
+	errDown := errors.New("down")
+	m.On(`^UPDATE`).Error(errDown).Once()
+	_, err = m.Id(1).Update(&MockUser{Age: 31})
+	assert.Equal(t, errDown, err)
+	affected, err = m.Id(1).Update(&MockUser{Age: 31})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, affected)
+
+	m.Reset()
+	var user2 MockUser
+	has, err := m.Id(3).Get(&user2)
+	assert.NoError(t, err)
+	assert.False(t, has)
+
+	m.Reset()
+	session := m.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+	m.On(`^DELETE`).Affected(2)
+	affected, err = session.Where("age < ?", 18).Delete(new(MockUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, affected)
+	assert.NoError(t, session.Commit())
+
+	var sqls []string
+	for _, record := range m.Records() {
+		sqls = append(sqls, record.SQL)
+	}
+	assert.EqualValues(t, []string{"BEGIN", "DELETE FROM `mock_user` WHERE (age < ?)", "COMMIT"}, sqls)
+}
+
+func TestMockEngineOf(t *testing.T) {
+	m, err := NewMockEngineOf(core.POSTGRES)
+	assert.NoError(t, err)
+	defer m.Close()
+	m.ShowSQL(false)
+
+	_, err = m.Where("name = ?", "lunny").Get(new(MockUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, `SELECT "id", "name", "age" FROM "mock_user" WHERE (name = $1) LIMIT 1`, m.LastRecord().SQL)
+}
@@ -0,0 +1,39 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package msgpackadapter registers the msgpack serializer of the
+// `xorm:"serialize(msgpack)"` tags and of engine.SetSerializer("msgpack"),
+// with github.com/vmihailenco/msgpack. It's registered by importing it:
+//
+//	import _ "github.com/go-xorm/xorm/msgpackadapter"
+package msgpackadapter
+
+import (
+	"github.com/go-xorm/xorm"
+	"github.com/vmihailenco/msgpack"
+)
+
+// Serializer stores the values as msgpack in BLOBs
+type Serializer struct{}
+
+var _ xorm.Serializer = Serializer{}
+
+func init() {
+	xorm.RegisterSerializer("msgpack", Serializer{})
+}
+
+// Marshal implements xorm.Serializer
+func (Serializer) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// Unmarshal implements xorm.Serializer
+func (Serializer) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+// Binary implements xorm.Serializer
+func (Serializer) Binary() bool {
+	return true
+}
@@ -0,0 +1,27 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package msgpackadapter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerializer(t *testing.T) {
+	var s Serializer
+	assert.True(t, s.Binary())
+
+	tags := map[string][]int{"a": {1, 2}, "b": nil}
+	data, err := s.Marshal(tags)
+	assert.NoError(t, err)
+
+	var got map[string][]int
+	assert.NoError(t, s.Unmarshal(data, &got))
+	assert.EqualValues(t, []int{1, 2}, got["a"])
+	assert.Empty(t, got["b"])
+
+	assert.Error(t, s.Unmarshal([]byte{0xc1}, &got))
+}
@@ -0,0 +1,143 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+)
+
+// MultiTxStage is the stage of MultiTx a failure happened at
+type MultiTxStage int
+
+// the stages of MultiTx
+const (
+	MultiTxBegin MultiTxStage = iota
+	MultiTxRun
+	MultiTxCommit
+)
+
+func (stage MultiTxStage) String() string {
+	switch stage {
+	case MultiTxBegin:
+		return "begin"
+	case MultiTxRun:
+		return "run"
+	default:
+		return "commit"
+	}
+}
+
+// MultiTxError is returned by MultiTx when a transaction failed to begin or
+// to commit or when the callback failed. The engines are given by their
+// indexes in the engines of MultiTx.
+type MultiTxError struct {
+	Stage  MultiTxStage
+	Engine int   // index of the failed engine, -1 when the callback failed
+	Err    error // error of the failed engine or of the callback
+	// the engines whose transactions were committed before the failure,
+	// their writes are kept
+	Committed []int
+	// the engines whose transactions were rolled back
+	RolledBack []int
+	// errors of the rollbacks which failed, keyed by engine index
+	RollbackErrs map[int]error
+}
+
+func (e *MultiTxError) Error() string {
+	var msg string
+	if e.Engine < 0 {
+		msg = fmt.Sprintf("multi tx %v failed: %v", e.Stage, e.Err)
+	} else {
+		msg = fmt.Sprintf("multi tx %v of engine %d failed: %v", e.Stage, e.Engine, e.Err)
+	}
+	if len(e.Committed) > 0 {
+		msg += fmt.Sprintf(", engines %v committed", e.Committed)
+	}
+	if len(e.RollbackErrs) > 0 {
+		msg += fmt.Sprintf(", %d rollbacks failed", len(e.RollbackErrs))
+	}
+	return msg
+}
+
+// Unwrap returns the error of the failed engine or of the callback
+func (e *MultiTxError) Unwrap() error {
+	return e.Err
+}
+
+// Partial returns true if some transactions were committed while the others
+// weren't, the committed writes need to be reconciled by the application
+func (e *MultiTxError) Partial() bool {
+	return len(e.Committed) > 0
+}
+
+// MultiTx begins a transaction on every engine in order, runs f with their
+// sessions, of the same order, and commits them in order. It's a best
+// effort for the writes to several databases without XA: when a begin or
+// f failed, all the transactions are rolled back, and when a commit
+// failed, the next transactions are rolled back while the previous ones
+// stay committed. The failures are returned as a *MultiTxError telling
+// which engines were committed. The engine whose commit is the most likely
+// to fail, or whose writes are the hardest to reconcile, should be first.
+//
+//	err := xorm.MultiTx([]*xorm.Engine{orders, billing}, func(sessions []*xorm.Session) error {
+//		if _, err := sessions[0].Insert(&order); err != nil {
+//			return err
+//		}
+//		_, err := sessions[1].Insert(&invoice)
+//		return err
+//	})
+func MultiTx(engines []*Engine, f func(sessions []*Session) error) error {
+	sessions := make([]*Session, 0, len(engines))
+	defer func() {
+		for _, session := range sessions {
+			session.Close()
+		}
+	}()
+
+	for i, engine := range engines {
+		session := engine.NewSession()
+		sessions = append(sessions, session)
+		if err := session.Begin(); err != nil {
+			engine.logger.Errorf("multi tx begin of engine %d failed: %v", i, err)
+			return rollbackMultiTx(sessions[:i], &MultiTxError{Stage: MultiTxBegin, Engine: i, Err: err})
+		}
+	}
+
+	if err := f(sessions); err != nil {
+		return rollbackMultiTx(sessions, &MultiTxError{Stage: MultiTxRun, Engine: -1, Err: err})
+	}
+
+	for i, session := range sessions {
+		if err := session.Commit(); err != nil {
+			session.Engine.logger.Errorf("multi tx commit of engine %d failed: %v", i, err)
+			txErr := &MultiTxError{Stage: MultiTxCommit, Engine: i, Err: err}
+			for j := 0; j < i; j++ {
+				txErr.Committed = append(txErr.Committed, j)
+			}
+			// the failed commit has been rolled back by the database
+			txErr.RolledBack = append(txErr.RolledBack, i)
+			return rollbackMultiTx(sessions[i+1:], txErr)
+		}
+	}
+	return nil
+}
+
+// rollbackMultiTx rolls back the transactions of sessions, the last ones of
+// MultiTx, and records them in txErr
+func rollbackMultiTx(sessions []*Session, txErr *MultiTxError) error {
+	offset := len(txErr.Committed) + len(txErr.RolledBack)
+	for i, session := range sessions {
+		if err := session.Rollback(); err != nil {
+			session.Engine.logger.Errorf("multi tx rollback of engine %d failed: %v", offset+i, err)
+			if txErr.RollbackErrs == nil {
+				txErr.RollbackErrs = make(map[int]error)
+			}
+			txErr.RollbackErrs[offset+i] = err
+			continue
+		}
+		txErr.RolledBack = append(txErr.RolledBack, offset+i)
+	}
+	return txErr
+}
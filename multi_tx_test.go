@@ -0,0 +1,92 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MultiTxOrder struct {
+	Id   int64
+	Name string
+}
+
+func TestMultiTx(t *testing.T) {
+	var engines []*Engine
+	var mocks []*MockEngine
+	for i := 0; i < 3; i++ {
+		mock, err := NewMockEngine()
+		assert.NoError(t, err)
+		defer mock.Close()
+		mocks = append(mocks, mock)
+		engines = append(engines, mock.Engine)
+	}
+	insert := func(sessions []*Session) error {
+		for _, session := range sessions {
+			if _, err := session.Insert(&MultiTxOrder{Name: "lunny"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	lastSQLs := func() []string {
+		var sqls []string
+		for _, mock := range mocks {
+			sqls = append(sqls, mock.LastRecord().SQL)
+		}
+		return sqls
+	}
+
+	assert.NoError(t, MultiTx(engines, insert))
+	assert.EqualValues(t, []string{"COMMIT", "COMMIT", "COMMIT"}, lastSQLs())
+
+	// the callback failed, all are rolled back
+	errRun := errors.New("run failed")
+	err := MultiTx(engines, func(sessions []*Session) error {
+		if err := insert(sessions); err != nil {
+			return err
+		}
+		return errRun
+	})
+	txErr, ok := err.(*MultiTxError)
+	if assert.True(t, ok) {
+		assert.EqualValues(t, MultiTxRun, txErr.Stage)
+		assert.EqualValues(t, -1, txErr.Engine)
+		assert.Equal(t, errRun, txErr.Err)
+		assert.False(t, txErr.Partial())
+		assert.EqualValues(t, []int{0, 1, 2}, txErr.RolledBack)
+	}
+	assert.EqualValues(t, []string{"ROLLBACK", "ROLLBACK", "ROLLBACK"}, lastSQLs())
+
+	// the second commit failed, the first stays committed
+	errDown := errors.New("down")
+	mocks[1].On(`^COMMIT$`).Error(errDown).Once()
+	err = MultiTx(engines, insert)
+	txErr, ok = err.(*MultiTxError)
+	if assert.True(t, ok) {
+		assert.EqualValues(t, MultiTxCommit, txErr.Stage)
+		assert.EqualValues(t, 1, txErr.Engine)
+		assert.Equal(t, errDown, txErr.Err)
+		assert.True(t, txErr.Partial())
+		assert.EqualValues(t, []int{0}, txErr.Committed)
+		assert.EqualValues(t, []int{1, 2}, txErr.RolledBack)
+		assert.EqualValues(t, "multi tx commit of engine 1 failed: down, engines [0] committed", txErr.Error())
+	}
+	assert.EqualValues(t, []string{"COMMIT", "COMMIT", "ROLLBACK"}, lastSQLs())
+
+	// the third begin failed, the others are rolled back before any write
+	mocks[2].On(`^BEGIN$`).Error(errDown).Once()
+	err = MultiTx(engines, insert)
+	txErr, ok = err.(*MultiTxError)
+	if assert.True(t, ok) {
+		assert.EqualValues(t, MultiTxBegin, txErr.Stage)
+		assert.EqualValues(t, 2, txErr.Engine)
+		assert.EqualValues(t, []int{0, 1}, txErr.RolledBack)
+	}
+	assert.EqualValues(t, []string{"ROLLBACK", "ROLLBACK", "BEGIN"}, lastSQLs())
+}
@@ -0,0 +1,229 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+)
+
+// nestedParam is a has-many field fetched by Nested
+type nestedParam struct {
+	field      string
+	foreignKey string
+	columns    []string // the selected columns of the children, all when empty
+}
+
+// nestedPrefix is the prefix of the aliases of the aggregated children
+const nestedPrefix = "xorm_nested_"
+
+// Nested makes Find fetch the records of the has-many field of its beans in
+// the same query, instead of a query per field, on PostgreSQL, MySQL
+// 5.7.22+ and SQLite. The children of a bean are aggregated as a JSON
+// array by a subquery on the column foreignKey of their table referencing
+// the primary key of the bean. The field is a slice of structs or of
+// pointers to structs which is ignored by the mapping, as `xorm:"-"`.
+//
+//	type User struct {
+//		Id     int64
+//		Name   string
+//		Orders []Order `xorm:"-"`
+//	}
+//
+//	err := engine.Nested("Orders", "user_id").Find(&users)
+func (statement *Statement) Nested(field, foreignKey string) *Statement {
+	statement.nested = append(statement.nested, nestedParam{field: field, foreignKey: foreignKey})
+	return statement
+}
+
+// nestedColumnStr returns columnStr with a subquery aggregating the
+// children of each nested field, and the args of the subqueries
+func (statement *Statement) nestedColumnStr(columnStr string) (string, []interface{}, error) {
+	var jsonObject, jsonArray string
+	switch statement.Engine.dialect.DBType() {
+	case core.POSTGRES:
+		jsonObject, jsonArray = "json_build_object(%s)", "COALESCE(json_agg(%s), '[]')"
+	case core.MYSQL:
+		jsonObject, jsonArray = "JSON_OBJECT(%s)", "COALESCE(JSON_ARRAYAGG(%s), JSON_ARRAY())"
+	case core.SQLITE:
+		jsonObject, jsonArray = "json_object(%s)", "json_group_array(%s)"
+	default:
+		return "", nil, ErrNotImplemented
+	}
+
+	table := statement.RefTable
+	if len(table.PrimaryKeys) != 1 {
+		return "", nil, fmt.Errorf("table %v needs a single primary key to be nested", table.Name)
+	}
+	quote := statement.Engine.Quote
+	parent := statement.TableName()
+	if statement.TableAlias != "" {
+		parent = statement.TableAlias
+	}
+	pkName := quote(parent) + "." + quote(table.PrimaryKeys[0])
+
+	var args []interface{}
+	for i, param := range statement.nested {
+		child, childName, err := statement.nestedTable(param.field)
+		if err != nil {
+			return "", nil, err
+		}
+		fk := child.GetColumn(param.foreignKey)
+		if fk == nil {
+			return "", nil, fmt.Errorf("unknown column %v of the table %v", param.foreignKey, child.Name)
+		}
+
+		var selected = make(map[string]bool, len(param.columns))
+		for _, name := range param.columns {
+			selected[name] = true
+		}
+		alias := quote("c")
+		var pairs []string
+		for _, col := range child.Columns() {
+			if col.MapType == core.ONLYTODB || (len(selected) > 0 && !selected[col.Name]) {
+				continue
+			}
+			pairs = append(pairs, "'"+col.Name+"', "+alias+"."+quote(col.Name))
+		}
+
+		var cond builder.Cond = builder.Expr(alias + "." + quote(fk.Name) + " = " + pkName)
+		if col := child.DeletedColumn(); col != nil && !statement.unscoped {
+			cond = cond.And(statement.Engine.notDeletedCond(child, col, alias+"."+quote(col.Name)))
+		}
+		condSQL, condArgs, err := builder.ToSQL(cond)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, condArgs...)
+
+		columnStr += ", (SELECT " +
+			fmt.Sprintf(jsonArray, fmt.Sprintf(jsonObject, strings.Join(pairs, ", "))) +
+			" FROM " + quote(childName) + " " + alias + " WHERE " + condSQL + ") AS " +
+			quote(fmt.Sprintf("%s%d", nestedPrefix, i))
+	}
+	return columnStr, args, nil
+}
+
+// nestedTable returns the table and the table name of the elements of the
+// nested field of the bean of the statement
+func (statement *Statement) nestedTable(field string) (*core.Table, string, error) {
+	elemType, err := nestedElemType(statement.RefTable.Type, field)
+	if err != nil {
+		return nil, "", err
+	}
+	v := reflect.New(elemType).Elem()
+	table, err := statement.Engine.autoMapType(v)
+	if err != nil {
+		return nil, "", err
+	}
+	return table, statement.tbName(v), nil
+}
+
+// nestedElemType returns the struct type of the elements of the slice
+// field of the struct type t
+func nestedElemType(t reflect.Type, field string) (reflect.Type, error) {
+	f, ok := t.FieldByName(field)
+	if !ok || f.Type.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("field %v of %v isn't a slice", field, t.Name())
+	}
+	elemType := f.Type.Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("field %v of %v isn't a slice of structs", field, t.Name())
+	}
+	return elemType, nil
+}
+
+// setNested sets the nested fields of the bean v from the aggregated
+// children of the result columns fields
+func (session *Session) setNested(v reflect.Value, fields []string, scanResults []interface{}) error {
+	for i, key := range fields {
+		if !strings.HasPrefix(strings.ToLower(key), nestedPrefix) {
+			continue
+		}
+		idx, err := strconv.Atoi(key[len(nestedPrefix):])
+		if err != nil || idx >= len(session.Statement.nested) {
+			continue
+		}
+		rawValue := reflect.Indirect(reflect.ValueOf(scanResults[i]))
+		if rawValue.Interface() == nil {
+			continue
+		}
+		data, err := value2Bytes(&rawValue)
+		if err != nil {
+			return err
+		}
+		if err := session.setNestedField(v.FieldByName(session.Statement.nested[idx].field), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setNestedField sets the slice fieldValue from the JSON array of the
+// children data, their columns are converted as the scanned ones
+func (session *Session) setNestedField(fieldValue reflect.Value, data []byte) error {
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return err
+	}
+
+	sliceType := fieldValue.Type()
+	elemType := sliceType.Elem()
+	isPointer := elemType.Kind() == reflect.Ptr
+	if isPointer {
+		elemType = elemType.Elem()
+	}
+	table, err := session.Engine.autoMapType(reflect.New(elemType).Elem())
+	if err != nil {
+		return err
+	}
+
+	slice := reflect.MakeSlice(sliceType, 0, len(objects))
+	for _, object := range objects {
+		elem := reflect.New(elemType)
+		for key, raw := range object {
+			col := table.GetColumn(key)
+			if col == nil {
+				continue
+			}
+			childField, err := col.ValueOf(elem.Interface())
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(raw, []byte("null")) {
+				session.setNull(childField)
+				continue
+			}
+			value := []byte(raw)
+			if len(raw) > 0 && raw[0] == '"' {
+				var s string
+				if err := json.Unmarshal(raw, &s); err != nil {
+					return err
+				}
+				value = []byte(s)
+			}
+			if err := session.bytes2Value(col, childField, value); err != nil {
+				return err
+			}
+		}
+		if isPointer {
+			slice = reflect.Append(slice, elem)
+		} else {
+			slice = reflect.Append(slice, elem.Elem())
+		}
+	}
+	fieldValue.Set(slice)
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type NestedOrder struct {
+	Id     int64
+	UserId int64 `xorm:"index"`
+	Item   string
+}
+
+type NestedUser struct {
+	Id     int64
+	Name   string
+	Orders []*NestedOrder `xorm:"-"`
+}
+
+func TestNested(t *testing.T) {
+	mock, err := NewMockEngineOf(core.POSTGRES)
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.On(`^SELECT`).Rows([]string{"id", "name", "xorm_nested_0"},
+		[]interface{}{1, "lunny", `[{"id": 1, "user_id": 1, "item": "book"}, {"id": 2, "user_id": 1, "item": null}]`},
+		[]interface{}{2, "xlw", `[]`})
+	var users []NestedUser
+	assert.NoError(t, mock.Nested("Orders", "user_id").Where("name <> ?", "").Find(&users))
+	assert.EqualValues(t, `SELECT "id", "name", (SELECT COALESCE(json_agg(json_build_object(`+
+		`'id', "c"."id", 'user_id', "c"."user_id", 'item', "c"."item")), '[]')`+
+		` FROM "nested_order" "c" WHERE "c"."user_id" = "nested_user"."id") AS "xorm_nested_0"`+
+		` FROM "nested_user" WHERE (name <> $1)`, mock.LastRecord().SQL)
+	assert.EqualValues(t, []NestedUser{
+		{Id: 1, Name: "lunny", Orders: []*NestedOrder{{1, 1, "book"}, {2, 1, ""}}},
+		{Id: 2, Name: "xlw", Orders: []*NestedOrder{}},
+	}, users)
+
+	err = mock.Nested("Name", "user_id").Find(&users)
+	assert.Error(t, err)
+
+	oracle, err := NewMockEngineOf(core.ORACLE)
+	assert.NoError(t, err)
+	defer oracle.Close()
+	assert.Equal(t, ErrNotImplemented, oracle.Nested("Orders", "user_id").Find(&users))
+}
+
+func TestNestedFind(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	switch testEngine.Dialect().DBType() {
+	case core.POSTGRES, core.MYSQL, core.SQLITE:
+	default:
+		t.Skip("json aggregation isn't supported")
+	}
+	assertSync(t, new(NestedUser), new(NestedOrder))
+
+	lunny, xlw := NestedUser{Name: "lunny"}, NestedUser{Name: "xlw"}
+	_, err := testEngine.Insert(&lunny, &xlw)
+	assert.NoError(t, err)
+	_, err = testEngine.Insert(&NestedOrder{UserId: lunny.Id, Item: "book"}, &NestedOrder{UserId: lunny.Id, Item: "pen"})
+	assert.NoError(t, err)
+
+	var found []NestedUser
+	assert.NoError(t, testEngine.Nested("Orders", "user_id").Asc("id").Find(&found))
+	assert.EqualValues(t, 2, len(found))
+	assert.EqualValues(t, 2, len(found[0].Orders))
+	items := []string{found[0].Orders[0].Item, found[0].Orders[1].Item}
+	sort.Strings(items)
+	assert.EqualValues(t, []string{"book", "pen"}, items)
+	assert.EqualValues(t, 0, len(found[1].Orders))
+}
@@ -0,0 +1,142 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+)
+
+// the SQL types of the network addresses of PostgreSQL
+const (
+	Inet    = "INET"
+	Cidr    = "CIDR"
+	Macaddr = "MACADDR"
+)
+
+func init() {
+	builtinConverters[reflect.TypeOf(net.IP(nil))] = func(engine *Engine, col *core.Column) Converter {
+		return ConverterFuncs{
+			Type: engine.networkSQLType(Inet, 45),
+			To: func(v interface{}) (interface{}, error) {
+				ip := v.(net.IP)
+				if len(ip) == 0 {
+					return nil, nil
+				}
+				return ip.String(), nil
+			},
+			From: func(src interface{}) (interface{}, error) {
+				s, err := networkString(src)
+				if err != nil {
+					return nil, err
+				}
+				ip := net.ParseIP(trimMask(s))
+				if ip == nil {
+					return nil, fmt.Errorf("invalid IP %q", s)
+				}
+				return ip, nil
+			},
+		}
+	}
+	builtinConverters[reflect.TypeOf(net.IPNet{})] = func(engine *Engine, col *core.Column) Converter {
+		return ConverterFuncs{
+			Type: engine.networkSQLType(Cidr, 49),
+			To: func(v interface{}) (interface{}, error) {
+				ipNet := v.(net.IPNet)
+				if len(ipNet.IP) == 0 {
+					return nil, nil
+				}
+				return ipNet.String(), nil
+			},
+			From: func(src interface{}) (interface{}, error) {
+				s, err := networkString(src)
+				if err != nil {
+					return nil, err
+				}
+				_, ipNet, err := net.ParseCIDR(s)
+				if err != nil {
+					return nil, err
+				}
+				return *ipNet, nil
+			},
+		}
+	}
+	builtinConverters[reflect.TypeOf(net.HardwareAddr(nil))] = func(engine *Engine, col *core.Column) Converter {
+		return ConverterFuncs{
+			Type: engine.networkSQLType(Macaddr, 23),
+			To: func(v interface{}) (interface{}, error) {
+				mac := v.(net.HardwareAddr)
+				if len(mac) == 0 {
+					return nil, nil
+				}
+				return mac.String(), nil
+			},
+			From: func(src interface{}) (interface{}, error) {
+				s, err := networkString(src)
+				if err != nil {
+					return nil, err
+				}
+				return net.ParseMAC(s)
+			},
+		}
+	}
+}
+
+// networkSQLType returns the type of PostgreSQL of the network addresses or
+// a VARCHAR of their text on the other databases
+func (engine *Engine) networkSQLType(pgType string, length int) core.SQLType {
+	if engine.dialect.DBType() == core.POSTGRES {
+		return core.SQLType{Name: pgType}
+	}
+	return core.SQLType{Name: core.Varchar, DefaultLength: length}
+}
+
+func networkString(src interface{}) (string, error) {
+	switch v := src.(type) {
+	case []byte:
+		return string(v), nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("unsupported network address %T", src)
+}
+
+// trimMask removes the mask of an address, the INET of PostgreSQL may have
+// one
+func trimMask(s string) string {
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// NetContainedBy is the condition "column << network" of PostgreSQL, the
+// address or the network of column is strictly in network
+func NetContainedBy(column string, network interface{}) builder.Cond {
+	return builder.Expr(column+" << ?", network)
+}
+
+// NetContainedByOrEquals is the condition "column <<= network" of
+// PostgreSQL, the address or the network of column is in or is network
+func NetContainedByOrEquals(column string, network interface{}) builder.Cond {
+	return builder.Expr(column+" <<= ?", network)
+}
+
+// NetContains is the condition "column >> addr" of PostgreSQL, the network
+// of column strictly contains the address or the network addr
+func NetContains(column string, addr interface{}) builder.Cond {
+	return builder.Expr(column+" >> ?", addr)
+}
+
+// NetContainsOrEquals is the condition "column >>= addr" of PostgreSQL, the
+// network of column contains or is addr
+func NetContainsOrEquals(column string, addr interface{}) builder.Cond {
+	return builder.Expr(column+" >>= ?", addr)
+}
@@ -0,0 +1,56 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package xorm
+
+import (
+	"net/netip"
+	"reflect"
+
+	"github.com/go-xorm/core"
+)
+
+func init() {
+	builtinConverters[reflect.TypeOf(netip.Addr{})] = func(engine *Engine, col *core.Column) Converter {
+		return ConverterFuncs{
+			Type: engine.networkSQLType(Inet, 45),
+			To: func(v interface{}) (interface{}, error) {
+				addr := v.(netip.Addr)
+				if !addr.IsValid() {
+					return nil, nil
+				}
+				return addr.String(), nil
+			},
+			From: func(src interface{}) (interface{}, error) {
+				s, err := networkString(src)
+				if err != nil {
+					return nil, err
+				}
+				return netip.ParseAddr(trimMask(s))
+			},
+		}
+	}
+	builtinConverters[reflect.TypeOf(netip.Prefix{})] = func(engine *Engine, col *core.Column) Converter {
+		return ConverterFuncs{
+			Type: engine.networkSQLType(Cidr, 49),
+			To: func(v interface{}) (interface{}, error) {
+				prefix := v.(netip.Prefix)
+				if !prefix.IsValid() {
+					return nil, nil
+				}
+				return prefix.String(), nil
+			},
+			From: func(src interface{}) (interface{}, error) {
+				s, err := networkString(src)
+				if err != nil {
+					return nil, err
+				}
+				return netip.ParsePrefix(s)
+			},
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package xorm
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type NetipStruct struct {
+	Id      int64
+	Addr    netip.Addr
+	Prefix  netip.Prefix
+	Gateway *netip.Addr
+}
+
+func TestNetipColumns(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(NetipStruct))
+
+	n := NetipStruct{
+		Addr:   netip.MustParseAddr("fe80::1"),
+		Prefix: netip.MustParsePrefix("192.168.0.0/24"),
+	}
+	cnt, err := testEngine.Insert(&n)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var got NetipStruct
+	has, err := testEngine.ID(n.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, n.Addr, got.Addr)
+	assert.EqualValues(t, n.Prefix, got.Prefix)
+	assert.Nil(t, got.Gateway)
+
+	cnt, err = testEngine.Where("addr = ?", netip.MustParseAddr("fe80::1")).Count(new(NetipStruct))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"net"
+	"testing"
+
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type NetworkStruct struct {
+	Id      int64
+	Ip      net.IP
+	Ip6     net.IP
+	Network *net.IPNet
+	Mac     net.HardwareAddr
+	Missing net.IP
+}
+
+func TestNetworkColumns(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(NetworkStruct))
+
+	table := testEngine.TableInfo(new(NetworkStruct))
+	col := table.GetColumn(testEngine.ColumnMapper.Obj2Table("Network"))
+	assert.NotNil(t, col)
+	if testEngine.Dialect().DBType() == core.POSTGRES {
+		assert.EqualValues(t, Cidr, col.SQLType.Name)
+	} else {
+		assert.EqualValues(t, core.Varchar, col.SQLType.Name)
+	}
+
+	_, network, err := net.ParseCIDR("10.1.0.0/16")
+	assert.NoError(t, err)
+	mac, err := net.ParseMAC("00:1a:2b:3c:4d:5e")
+	assert.NoError(t, err)
+	n := NetworkStruct{
+		Ip:      net.ParseIP("10.1.2.3"),
+		Ip6:     net.ParseIP("2001:db8::1"),
+		Network: network,
+		Mac:     mac,
+	}
+	cnt, err := testEngine.Insert(&n)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var ip string
+	has, err := testEngine.Table(new(NetworkStruct)).Cols("ip").Get(&ip)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "10.1.2.3", ip)
+
+	var got NetworkStruct
+	has, err = testEngine.Where("ip = ?", net.ParseIP("10.1.2.3")).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.True(t, n.Ip.Equal(got.Ip))
+	assert.True(t, n.Ip6.Equal(got.Ip6))
+	assert.NotNil(t, got.Network)
+	assert.EqualValues(t, "10.1.0.0/16", got.Network.String())
+	assert.EqualValues(t, mac, got.Mac)
+	assert.Nil(t, got.Missing)
+
+	if testEngine.Dialect().DBType() == core.POSTGRES {
+		cnt, err = testEngine.Where(NetContainedBy("ip", network)).Count(new(NetworkStruct))
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, cnt)
+		cnt, err = testEngine.Where(NetContainsOrEquals("network", net.ParseIP("10.2.0.1"))).Count(new(NetworkStruct))
+		assert.NoError(t, err)
+		assert.EqualValues(t, 0, cnt)
+	}
+}
+
+func TestNetConds(t *testing.T) {
+	var kases = []struct {
+		cond     builder.Cond
+		expected string
+	}{
+		{NetContainedBy("ip", "10.0.0.0/8"), "ip << ?"},
+		{NetContainedByOrEquals("ip", "10.0.0.0/8"), "ip <<= ?"},
+		{NetContains("network", "10.1.2.3"), "network >> ?"},
+		{NetContainsOrEquals("network", "10.1.2.3"), "network >>= ?"},
+	}
+	for _, kase := range kases {
+		sql, args, err := builder.ToSQL(kase.cond)
+		assert.NoError(t, err)
+		assert.EqualValues(t, kase.expected, sql)
+		assert.Len(t, args, 1)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package xorm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+)
+
+// Null is a nullable value of any type, as the sql.Null* types. The column
+// of a Null[T] field has the type of T and is NULL when Valid is false.
+type Null[T any] struct {
+	V     T
+	Valid bool // Valid is true if V is not NULL
+}
+
+// NewNull returns a valid Null of v
+func NewNull[T any](v T) Null[T] {
+	return Null[T]{V: v, Valid: true}
+}
+
+// Ptr returns a pointer to the value, nil when it's NULL
+func (n Null[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	v := n.V
+	return &v
+}
+
+// Scan implements sql.Scanner
+func (n *Null[T]) Scan(value interface{}) error {
+	var zero T
+	n.V = zero
+	if value == nil {
+		n.Valid = false
+		return nil
+	}
+
+	var err error
+	if scanner, ok := interface{}(&n.V).(sql.Scanner); ok {
+		err = scanner.Scan(value)
+	} else {
+		err = convertAssign(&n.V, value)
+	}
+	n.Valid = err == nil
+	return err
+}
+
+// Value implements driver.Valuer
+func (n Null[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	if valuer, ok := interface{}(n.V).(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return driver.DefaultParameterConverter.ConvertValue(n.V)
+}
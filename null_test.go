@@ -0,0 +1,116 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package xorm
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNullScanValue(t *testing.T) {
+	var n Null[int64]
+	assert.NoError(t, n.Scan(int64(3)))
+	assert.True(t, n.Valid)
+	assert.EqualValues(t, 3, n.V)
+
+	v, err := n.Value()
+	assert.NoError(t, err)
+	assert.EqualValues(t, int64(3), v)
+
+	assert.NoError(t, n.Scan(nil))
+	assert.False(t, n.Valid)
+	assert.EqualValues(t, 0, n.V)
+	assert.Nil(t, n.Ptr())
+
+	v, err = n.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	var s Null[string]
+	assert.NoError(t, s.Scan([]byte("xorm")))
+	assert.EqualValues(t, "xorm", *s.Ptr())
+
+	// the value of a valuer is its own one
+	c, err := NewNull(CustomStruct{2017, 1, 2}).Value()
+	assert.NoError(t, err)
+	assert.EqualValues(t, "2017/1/2", c)
+}
+
+func TestNullSQLType(t *testing.T) {
+	assert.EqualValues(t, core.BigInt, type2SQLType(reflect.TypeOf(Null[int64]{})).Name)
+	assert.EqualValues(t, core.Varchar, type2SQLType(reflect.TypeOf(&Null[string]{})).Name)
+	assert.EqualValues(t, core.DateTime, type2SQLType(reflect.TypeOf(Null[time.Time]{})).Name)
+	assert.EqualValues(t, core.BigInt, type2SQLType(reflect.TypeOf(sql.NullInt64{})).Name)
+	assert.EqualValues(t, core.Bool, type2SQLType(reflect.TypeOf(sql.NullBool{})).Name)
+}
+
+type NullGenericExtend struct {
+	Score Null[float64]
+}
+
+type NullGeneric struct {
+	Id     int64 `xorm:"pk autoincr"`
+	Name   Null[string]
+	Age    Null[int]
+	Born   Null[time.Time]
+	Nick   *Null[string]
+	Extend NullGenericExtend `xorm:"extends"`
+}
+
+func TestNullGenericRoundTrip(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(NullGeneric))
+
+	born := time.Date(2017, 1, 2, 3, 4, 5, 0, time.Local)
+	nick := NewNull("lun")
+	item := NullGeneric{
+		Name:   NewNull("lunny"),
+		Age:    NewNull(34),
+		Born:   NewNull(born),
+		Nick:   &nick,
+		Extend: NullGenericExtend{Score: NewNull(9.5)},
+	}
+	cnt, err := testEngine.Insert(&item)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var got NullGeneric
+	has, err := testEngine.Id(item.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, item.Name, got.Name)
+	assert.EqualValues(t, item.Age, got.Age)
+	assert.True(t, got.Born.Valid)
+	assert.EqualValues(t, born.Unix(), got.Born.V.Unix())
+	assert.NotNil(t, got.Nick)
+	assert.EqualValues(t, "lun", got.Nick.V)
+	assert.EqualValues(t, 9.5, got.Extend.Score.V)
+
+	cnt, err = testEngine.Id(item.Id).Cols("name", "age", "born", "nick",
+		testEngine.ColumnMapper.Obj2Table("Score")).Update(&NullGeneric{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	has, err = testEngine.Id(item.Id).NoAutoCondition().Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.False(t, got.Name.Valid)
+	assert.False(t, got.Age.Valid)
+	assert.False(t, got.Born.Valid)
+	assert.Nil(t, got.Nick)
+	assert.False(t, got.Extend.Score.Valid)
+
+	var items []NullGeneric
+	assert.NoError(t, testEngine.Where("age IS NULL").Find(&items))
+	assert.EqualValues(t, 1, len(items))
+}
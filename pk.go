@@ -0,0 +1,48 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+
+	"github.com/go-xorm/core"
+)
+
+// PK is the values of a primary key, in the order of its columns, as
+// ID(xorm.PK{orgID, userID}) for a composite key
+type PK = core.PK
+
+// PKArityError is returned when the values of ID don't match the columns of
+// the primary key of the table
+type PKArityError struct {
+	Table    string
+	Expected int // the columns of the primary key
+	Actual   int // the values of ID
+}
+
+func (e *PKArityError) Error() string {
+	return fmt.Sprintf("table %v has %d primary key columns but ID has %d values",
+		e.Table, e.Expected, e.Actual)
+}
+
+// checkIDParam checks the values of ID against the primary key of the
+// table, ID needs the struct of the table to know its key. The ID of the
+// tables without primary key is ignored.
+func (statement *Statement) checkIDParam() error {
+	if statement.idParam == nil {
+		return nil
+	}
+	if statement.RefTable == nil {
+		return ErrNoPrimaryKey
+	}
+	if n := len(statement.RefTable.PrimaryKeys); n > 0 && len(*statement.idParam) != n {
+		return &PKArityError{
+			Table:    statement.TableName(),
+			Expected: len(statement.RefTable.PrimaryKeys),
+			Actual:   len(*statement.idParam),
+		}
+	}
+	return nil
+}
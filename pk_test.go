@@ -0,0 +1,87 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type OrgMember struct {
+	OrgId  int64 `xorm:"pk"`
+	UserId int64 `xorm:"pk"`
+	Role   string
+}
+
+func TestCompositePK(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(OrgMember))
+
+	cacher := NewLRUCacher2(NewMemoryStore(), time.Hour, 10000)
+	testEngine.MapCacher(new(OrgMember), cacher)
+	defer testEngine.MapCacher(new(OrgMember), nil)
+
+	_, err := testEngine.Insert(&OrgMember{1, 1, "owner"}, &OrgMember{1, 2, "member"}, &OrgMember{2, 1, "member"})
+	assert.NoError(t, err)
+
+	var member OrgMember
+	has, err := testEngine.ID(PK{1, 2}).Get(&member)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, OrgMember{1, 2, "member"}, member)
+
+	has, err = testEngine.ID(PK{1, 2}).Exist(new(OrgMember))
+	assert.NoError(t, err)
+	assert.True(t, has)
+	has, err = testEngine.Table(new(OrgMember)).ID(PK{2, 2}).Exist()
+	assert.NoError(t, err)
+	assert.False(t, has)
+
+	// the cached record is refreshed by the update
+	cnt, err := testEngine.ID(PK{1, 2}).Update(&OrgMember{Role: "admin"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	member = OrgMember{}
+	has, err = testEngine.ID(PK{int32(1), uint(2)}).Get(&member)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "admin", member.Role)
+
+	cnt, err = testEngine.ID(PK{1, 2}).Delete(new(OrgMember))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	has, err = testEngine.ID(PK{1, 2}).Get(new(OrgMember))
+	assert.NoError(t, err)
+	assert.False(t, has)
+
+	total, err := testEngine.Count(new(OrgMember))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+}
+
+func TestCompositePKArity(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(OrgMember))
+
+	var arityErr *PKArityError
+	_, err := testEngine.ID(1).Get(new(OrgMember))
+	if assert.True(t, errors.As(err, &arityErr)) {
+		assert.EqualValues(t, 2, arityErr.Expected)
+		assert.EqualValues(t, 1, arityErr.Actual)
+	}
+
+	_, err = testEngine.ID(PK{1, 2, 3}).Update(&OrgMember{Role: "admin"})
+	assert.True(t, errors.As(err, &arityErr))
+	_, err = testEngine.ID(PK{1}).Delete(new(OrgMember))
+	assert.True(t, errors.As(err, &arityErr))
+	_, err = testEngine.ID(PK{1}).Exist(new(OrgMember))
+	assert.True(t, errors.As(err, &arityErr))
+
+	_, err = testEngine.Table("org_member").ID(PK{1, 2}).Exist()
+	assert.EqualValues(t, ErrNoPrimaryKey, err)
+}
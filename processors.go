@@ -4,6 +4,8 @@
 
 package xorm
 
+import "context"
+
 // BeforeInsertProcessor executed before an object is initially persisted to the database
 type BeforeInsertProcessor interface {
 	BeforeInsert()
@@ -50,3 +52,152 @@ type AfterUpdateProcessor interface {
 type AfterDeleteProcessor interface {
 	AfterDelete()
 }
+
+// BeforeInsertContextProcessor is BeforeInsertProcessor with the context and
+// the session of the insert, so it can query in the same transaction. An
+// error aborts the insert.
+type BeforeInsertContextProcessor interface {
+	BeforeInsert(ctx context.Context, session *Session) error
+}
+
+// BeforeUpdateContextProcessor is BeforeUpdateProcessor with the context and
+// the session of the update. An error aborts the update.
+type BeforeUpdateContextProcessor interface {
+	BeforeUpdate(ctx context.Context, session *Session) error
+}
+
+// BeforeDeleteContextProcessor is BeforeDeleteProcessor with the context and
+// the session of the delete. An error aborts the delete.
+type BeforeDeleteContextProcessor interface {
+	BeforeDelete(ctx context.Context, session *Session) error
+}
+
+// AfterInsertContextProcessor is AfterInsertProcessor with the context and
+// the session of the insert, it's called after the commit in a transaction
+type AfterInsertContextProcessor interface {
+	AfterInsert(ctx context.Context, session *Session)
+}
+
+// AfterUpdateContextProcessor is AfterUpdateProcessor with the context and
+// the session of the update, it's called after the commit in a transaction
+type AfterUpdateContextProcessor interface {
+	AfterUpdate(ctx context.Context, session *Session)
+}
+
+// AfterDeleteContextProcessor is AfterDeleteProcessor with the context and
+// the session of the delete, it's called after the commit in a transaction
+type AfterDeleteContextProcessor interface {
+	AfterDelete(ctx context.Context, session *Session)
+}
+
+// AfterLoadProcessor executed after an object is loaded from the database,
+// once all its fields are set. The rows of the query are still open, so in a
+// transaction the driver has to support several open results to query on
+// the session.
+type AfterLoadProcessor interface {
+	AfterLoad(ctx context.Context, session *Session)
+}
+
+// runHook runs a processor with a statement of its own, so the queries of
+// the processor on the session don't reset the statement being executed
+func (session *Session) runHook(hook func() error) error {
+	saved, autoClose := session.Statement, session.IsAutoClose
+	session.Statement = Statement{Engine: session.Engine, tenant: saved.tenant, session: session}
+	session.Statement.Init()
+	session.IsAutoClose = false
+	defer func() {
+		session.Statement, session.IsAutoClose = saved, autoClose
+	}()
+	return hook()
+}
+
+func (session *Session) beforeInsert(bean interface{}) error {
+	if processor, ok := bean.(BeforeInsertProcessor); ok {
+		processor.BeforeInsert()
+	}
+	if processor, ok := bean.(BeforeInsertContextProcessor); ok {
+		return session.runHook(func() error {
+			return processor.BeforeInsert(session.Ctx(), session)
+		})
+	}
+	return nil
+}
+
+func (session *Session) beforeUpdate(bean interface{}) error {
+	if processor, ok := bean.(BeforeUpdateProcessor); ok {
+		processor.BeforeUpdate()
+	}
+	if processor, ok := bean.(BeforeUpdateContextProcessor); ok {
+		return session.runHook(func() error {
+			return processor.BeforeUpdate(session.Ctx(), session)
+		})
+	}
+	return nil
+}
+
+func (session *Session) beforeDelete(bean interface{}) error {
+	if processor, ok := bean.(BeforeDeleteProcessor); ok {
+		processor.BeforeDelete()
+	}
+	if processor, ok := bean.(BeforeDeleteContextProcessor); ok {
+		return session.runHook(func() error {
+			return processor.BeforeDelete(session.Ctx(), session)
+		})
+	}
+	return nil
+}
+
+// hasAfterInsert reports whether bean has an after insert processor
+func hasAfterInsert(bean interface{}) bool {
+	_, ok := bean.(AfterInsertProcessor)
+	_, ctxOk := bean.(AfterInsertContextProcessor)
+	return ok || ctxOk
+}
+
+func hasAfterUpdate(bean interface{}) bool {
+	_, ok := bean.(AfterUpdateProcessor)
+	_, ctxOk := bean.(AfterUpdateContextProcessor)
+	return ok || ctxOk
+}
+
+func hasAfterDelete(bean interface{}) bool {
+	_, ok := bean.(AfterDeleteProcessor)
+	_, ctxOk := bean.(AfterDeleteContextProcessor)
+	return ok || ctxOk
+}
+
+func (session *Session) afterInsert(bean interface{}) {
+	if processor, ok := bean.(AfterInsertProcessor); ok {
+		processor.AfterInsert()
+	}
+	if processor, ok := bean.(AfterInsertContextProcessor); ok {
+		session.runHook(func() error {
+			processor.AfterInsert(session.Ctx(), session)
+			return nil
+		})
+	}
+}
+
+func (session *Session) afterUpdate(bean interface{}) {
+	if processor, ok := bean.(AfterUpdateProcessor); ok {
+		processor.AfterUpdate()
+	}
+	if processor, ok := bean.(AfterUpdateContextProcessor); ok {
+		session.runHook(func() error {
+			processor.AfterUpdate(session.Ctx(), session)
+			return nil
+		})
+	}
+}
+
+func (session *Session) afterDelete(bean interface{}) {
+	if processor, ok := bean.(AfterDeleteProcessor); ok {
+		processor.AfterDelete()
+	}
+	if processor, ok := bean.(AfterDeleteContextProcessor); ok {
+		session.runHook(func() error {
+			processor.AfterDelete(session.Ctx(), session)
+			return nil
+		})
+	}
+}
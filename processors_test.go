@@ -5,6 +5,7 @@
 package xorm
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
@@ -964,3 +965,56 @@ func TestProcessorsTx(t *testing.T) {
 	session.Close()
 	// --
 }
+
+type ctxKey struct{}
+
+type ContextProcessorsStruct struct {
+	Id     int64
+	Name   string
+	Tenant string `xorm:"-"`
+	Loaded int    `xorm:"-"`
+	Count  int64  `xorm:"-"`
+}
+
+func (p *ContextProcessorsStruct) BeforeInsert(ctx context.Context, session *Session) error {
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	p.Tenant, _ = ctx.Value(ctxKey{}).(string)
+	// the session can query in the same transaction
+	var err error
+	p.Count, err = session.Count(new(ContextProcessorsStruct))
+	return err
+}
+
+func (p *ContextProcessorsStruct) AfterLoad(ctx context.Context, session *Session) {
+	p.Tenant, _ = ctx.Value(ctxKey{}).(string)
+	p.Loaded++
+}
+
+func TestContextProcessors(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(ContextProcessorsStruct)))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "tenant1")
+
+	_, err := testEngine.Context(ctx).Insert(&ContextProcessorsStruct{})
+	assert.Error(t, err)
+
+	sess := testEngine.NewSession()
+	defer sess.Close()
+	assert.NoError(t, sess.Begin())
+	var p = &ContextProcessorsStruct{Name: "lunny"}
+	_, err = sess.Context(ctx).Insert(p)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "tenant1", p.Tenant)
+	assert.EqualValues(t, 0, p.Count)
+	assert.NoError(t, sess.Commit())
+
+	var p2 ContextProcessorsStruct
+	has, err := testEngine.Context(ctx).Id(p.Id).Get(&p2)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "tenant1", p2.Tenant)
+	assert.EqualValues(t, 1, p2.Loaded)
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+)
+
+// QueryFilter returns a mandatory condition of the statements of session on
+// table, as a row level security rule. It returns nil when table is not
+// filtered.
+type QueryFilter func(table *core.Table, session *Session) builder.Cond
+
+// AddQueryFilter adds filters whose conditions are added to every query,
+// update and delete of the beans. The raw SQLs and the statements without
+// bean are not filtered. As the tenant, the filters are not conditions of a
+// Delete, which still needs one. It should be called before the engine is
+// used.
+func (engine *Engine) AddQueryFilter(filters ...QueryFilter) {
+	engine.queryFilters = append(engine.queryFilters, filters...)
+}
+
+// BypassQueryFilters disables the query filters of the engine for the
+// statements of the session until it's closed, as for an admin session
+func (session *Session) BypassQueryFilters() *Session {
+	session.bypassFilters = true
+	return session
+}
+
+// filterCond returns the conditions of the query filters of the engine
+func (statement *Statement) filterCond() builder.Cond {
+	cond := builder.NewCond()
+	if statement.session == nil || statement.session.bypassFilters || statement.RefTable == nil {
+		return cond
+	}
+	for _, filter := range statement.Engine.queryFilters {
+		if c := filter(statement.RefTable, statement.session); c != nil {
+			cond = cond.And(c)
+		}
+	}
+	return cond
+}
+
+// mandatoryCond returns the conditions every statement on the table of the
+// bean has, whatever the conditions set by the caller
+func (statement *Statement) mandatoryCond() builder.Cond {
+	return builder.And(statement.tenantCond(), statement.filterCond())
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type FilteredDoc struct {
+	Id    int64
+	OrgId int64
+	Title string
+}
+
+type filterOrgKey struct{}
+
+func TestQueryFilter(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(FilteredDoc)))
+
+	_, err := testEngine.Insert(&FilteredDoc{OrgId: 1, Title: "a"}, &FilteredDoc{OrgId: 1, Title: "b"},
+		&FilteredDoc{OrgId: 2, Title: "c"})
+	assert.NoError(t, err)
+
+	orgCol := testEngine.ColumnMapper.Obj2Table("OrgId")
+	testEngine.AddQueryFilter(func(table *core.Table, session *Session) builder.Cond {
+		if table.GetColumn(orgCol) == nil {
+			return nil
+		}
+		org, _ := session.Ctx().Value(filterOrgKey{}).(int64)
+		return builder.Eq{orgCol: org}
+	})
+	defer func() { testEngine.queryFilters = nil }()
+
+	ctx := context.WithValue(context.Background(), filterOrgKey{}, int64(1))
+
+	var docs []FilteredDoc
+	assert.NoError(t, testEngine.Context(ctx).Find(&docs))
+	assert.EqualValues(t, 2, len(docs))
+
+	total, err := testEngine.Context(ctx).Count(new(FilteredDoc))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+
+	has, err := testEngine.Context(ctx).Id(3).Get(new(FilteredDoc))
+	assert.NoError(t, err)
+	assert.False(t, has)
+
+	cnt, err := testEngine.Context(ctx).Id(3).Update(&FilteredDoc{Title: "d"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+
+	cnt, err = testEngine.Context(ctx).Id(3).Delete(new(FilteredDoc))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+
+	// the filter is not a condition of the caller, the rows of the org stay
+	_, err = testEngine.Context(ctx).Delete(new(FilteredDoc))
+	assert.EqualValues(t, ErrNeedDeletedCond, err)
+	total, err = testEngine.Context(ctx).Count(new(FilteredDoc))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+
+	// the filter applies to every statement of a session
+	session := testEngine.NewSession()
+	defer session.Close()
+	session.Context(ctx)
+	docs = nil
+	assert.NoError(t, session.Find(&docs))
+	assert.EqualValues(t, 2, len(docs))
+
+	// an admin session sees all the records
+	docs = nil
+	assert.NoError(t, session.BypassQueryFilters().Find(&docs))
+	assert.EqualValues(t, 3, len(docs))
+	cnt, err = session.Id(3).Update(&FilteredDoc{Title: "d"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+}
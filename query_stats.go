@@ -0,0 +1,278 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bytes"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the number of latencies kept by fingerprint to compute the percentiles
+const queryStatSamples = 1024
+
+// QueryStat is the statistics of the statements of a fingerprint
+type QueryStat struct {
+	Fingerprint string
+	Calls       int64
+	Errors      int64
+	Total       time.Duration
+	Min         time.Duration
+	Max         time.Duration
+	Mean        time.Duration
+	// the percentiles are estimated from a random sample of the latencies
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+}
+
+// queryStat is the running statistics of a fingerprint
+type queryStat struct {
+	calls, errors   int64
+	total, min, max time.Duration
+	samples         []time.Duration
+}
+
+// queryStats is the statistics of the fingerprints of an engine
+type queryStats struct {
+	mutex sync.Mutex
+	stats map[string]*queryStat
+}
+
+func newQueryStats() *queryStats {
+	return &queryStats{stats: make(map[string]*queryStat)}
+}
+
+func (qs *queryStats) add(sqlStr string, d time.Duration, err error) {
+	fp := Fingerprint(sqlStr)
+
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+	stat, ok := qs.stats[fp]
+	if !ok {
+		stat = &queryStat{min: d}
+		qs.stats[fp] = stat
+	}
+	stat.calls++
+	if err != nil {
+		stat.errors++
+	}
+	stat.total += d
+	if d < stat.min {
+		stat.min = d
+	}
+	if d > stat.max {
+		stat.max = d
+	}
+
+	// reservoir sampling keeps a uniform sample of all the latencies
+	if len(stat.samples) < queryStatSamples {
+		stat.samples = append(stat.samples, d)
+	} else if i := rand.Int63n(stat.calls); i < queryStatSamples {
+		stat.samples[i] = d
+	}
+}
+
+func (qs *queryStats) snapshot() []QueryStat {
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+
+	res := make([]QueryStat, 0, len(qs.stats))
+	for fp, stat := range qs.stats {
+		samples := make([]time.Duration, len(stat.samples))
+		copy(samples, stat.samples)
+		sort.Slice(samples, func(i, j int) bool {
+			return samples[i] < samples[j]
+		})
+		res = append(res, QueryStat{
+			Fingerprint: fp,
+			Calls:       stat.calls,
+			Errors:      stat.errors,
+			Total:       stat.total,
+			Min:         stat.min,
+			Max:         stat.max,
+			Mean:        stat.total / time.Duration(stat.calls),
+			P50:         percentile(samples, 50),
+			P95:         percentile(samples, 95),
+			P99:         percentile(samples, 99),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Total > res[j].Total
+	})
+	return res
+}
+
+// percentile returns the p-th percentile of the sorted samples
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p + 99) / 100
+	if i > 0 {
+		i--
+	}
+	return sorted[i]
+}
+
+// SetQueryStats enables or disables the statistics of the statements by
+// fingerprint, disabling them drops the collected ones
+func (engine *Engine) SetQueryStats(enabled bool) {
+	if enabled {
+		if engine.queryStats == nil {
+			engine.queryStats = newQueryStats()
+		}
+		return
+	}
+	engine.queryStats = nil
+}
+
+// QueryStats returns the statistics of the statements executed since they
+// were enabled or reset, by fingerprint, the longest total time first
+func (engine *Engine) QueryStats() []QueryStat {
+	if engine.queryStats == nil {
+		return nil
+	}
+	return engine.queryStats.snapshot()
+}
+
+// ResetQueryStats drops the collected statistics
+func (engine *Engine) ResetQueryStats() {
+	if qs := engine.queryStats; qs != nil {
+		qs.mutex.Lock()
+		qs.stats = make(map[string]*queryStat)
+		qs.mutex.Unlock()
+	}
+}
+
+// queryStatsHook is the SQL hook collecting the statistics of an engine
+type queryStatsHook struct {
+	stats *queryStats
+}
+
+func (h queryStatsHook) BeforeSQL(event *SQLEvent) {}
+
+func (h queryStatsHook) AfterSQL(event *SQLEvent) {
+	h.stats.add(event.SQL, event.Duration, event.Err)
+}
+
+var (
+	valuesListRegexp = regexp.MustCompile(`\(\?(, ?\?)*\)`)
+	valuesRunRegexp  = regexp.MustCompile(`\(\.\.\.\)(, ?\(\.\.\.\))+`)
+)
+
+// Fingerprint normalizes sqlStr so the statements differing only by their
+// values have the same fingerprint. The literals and the placeholders are
+// replaced by ?, the lists of values, as the IN lists and the rows of a
+// multiple insert, are collapsed into (...), the comments are dropped and
+// the spaces are collapsed.
+func Fingerprint(sqlStr string) string {
+	var buf bytes.Buffer
+	var last byte
+	write := func(c byte) {
+		buf.WriteByte(c)
+		last = c
+	}
+
+	n := len(sqlStr)
+	for i := 0; i < n; {
+		c := sqlStr[i]
+		switch {
+		case c == '\'':
+			// a string literal, quotes are escaped by doubling them or by a
+			// backslash
+			j := i + 1
+			for j < n {
+				if sqlStr[j] == '\\' {
+					j += 2
+					continue
+				}
+				if sqlStr[j] == '\'' {
+					if j+1 < n && sqlStr[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			write('?')
+			i = j + 1
+		case c == '/' && i+1 < n && sqlStr[i+1] == '*':
+			// the comments are dropped, as the ones of the SQL commenter
+			end := strings.Index(sqlStr[i+2:], "*/")
+			if end < 0 {
+				i = n
+			} else {
+				i += end + 4
+			}
+		case c == '-' && i+1 < n && sqlStr[i+1] == '-':
+			end := strings.IndexByte(sqlStr[i:], '\n')
+			if end < 0 {
+				i = n
+			} else {
+				i += end
+			}
+		case c == '"' || c == '`' || c == '[':
+			// a quoted identifier is kept
+			end := c
+			if c == '[' {
+				end = ']'
+			}
+			j := i + 1
+			for j < n && sqlStr[j] != end {
+				j++
+			}
+			if j < n {
+				j++
+			}
+			buf.WriteString(sqlStr[i:j])
+			last = end
+			i = j
+		case (c == '$' || c == ':' || c == '@') && i+1 < n && isIdentByte(sqlStr[i+1]) && !isIdentByte(last) && last != ':':
+			// the placeholders $1, :1, :name and @p1
+			j := i + 1
+			for j < n && isIdentByte(sqlStr[j]) {
+				j++
+			}
+			write('?')
+			i = j
+		case c >= '0' && c <= '9' && !isIdentByte(last):
+			j := i + 1
+			for j < n && (sqlStr[j] >= '0' && sqlStr[j] <= '9' || sqlStr[j] == '.') {
+				j++
+			}
+			write('?')
+			i = j
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if last != ' ' && last != 0 && last != '(' {
+				write(' ')
+			}
+			i++
+		case c == ')' || c == ',':
+			if last == ' ' {
+				buf.Truncate(buf.Len() - 1)
+			}
+			write(c)
+			i++
+		default:
+			write(c)
+			i++
+		}
+	}
+
+	fp := bytes.TrimRight(buf.Bytes(), " ")
+	fp = valuesListRegexp.ReplaceAll(fp, []byte("(...)"))
+	fp = valuesRunRegexp.ReplaceAll(fp, []byte("(...)"))
+	return string(fp)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
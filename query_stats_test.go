@@ -0,0 +1,78 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	var kases = []struct {
+		sql         string
+		fingerprint string
+	}{
+		{"SELECT `id` FROM `user` WHERE (id IN (1, 2, 3)) AND name = 'it''s'  LIMIT 10",
+			"SELECT `id` FROM `user` WHERE (id IN (...)) AND name = ? LIMIT ?"},
+		{`INSERT INTO "user" ("name","age") VALUES ($1,$2),($3,$4)`,
+			`INSERT INTO "user" ("name","age") VALUES (...)`},
+		{"SELECT * FROM t1 WHERE x::int = :1 AND y = @p2 AND z IN (?,?)",
+			"SELECT * FROM t1 WHERE x::int = ? AND y = ? AND z IN (...)"},
+		{"  UPDATE t SET a = a + 1.5\n WHERE id=? ",
+			"UPDATE t SET a = a + ? WHERE id=?"},
+		{"SELECT 1 -- one\nFROM t /*route='%2Fusers'*/",
+			"SELECT ? FROM t"},
+	}
+	for _, kase := range kases {
+		assert.EqualValues(t, kase.fingerprint, Fingerprint(kase.sql))
+	}
+}
+
+type StatUser struct {
+	Id   int64
+	Name string
+}
+
+func TestQueryStats(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(StatUser)))
+
+	assert.Nil(t, testEngine.QueryStats())
+	testEngine.SetQueryStats(true)
+	defer testEngine.SetQueryStats(false)
+
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := testEngine.Insert(&StatUser{Name: name})
+		assert.NoError(t, err)
+	}
+	_, err := testEngine.In("id", 1, 2).Count(new(StatUser))
+	assert.NoError(t, err)
+	_, err = testEngine.In("id", 1, 2, 3).Count(new(StatUser))
+	assert.NoError(t, err)
+	_, err = testEngine.Exec("DELETE FROM not_exist_table")
+	assert.Error(t, err)
+
+	stats := testEngine.QueryStats()
+	if assert.EqualValues(t, 3, len(stats)) {
+		byCalls := make(map[int64]QueryStat)
+		for _, stat := range stats {
+			byCalls[stat.Calls] = stat
+			assert.True(t, stat.Min <= stat.P50 && stat.P50 <= stat.P95 && stat.P95 <= stat.P99 && stat.P99 <= stat.Max)
+			assert.EqualValues(t, stat.Total/time.Duration(stat.Calls), stat.Mean)
+		}
+		assert.EqualValues(t, 0, byCalls[3].Errors)
+		assert.EqualValues(t, 0, byCalls[2].Errors)
+		assert.EqualValues(t, 1, byCalls[1].Errors)
+		assert.EqualValues(t, "DELETE FROM not_exist_table", byCalls[1].Fingerprint)
+	}
+	for i := 1; i < len(stats); i++ {
+		assert.True(t, stats[i-1].Total >= stats[i].Total)
+	}
+
+	testEngine.ResetQueryStats()
+	assert.EqualValues(t, 0, len(testEngine.QueryStats()))
+}
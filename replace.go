@@ -0,0 +1,123 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// Replace inserts a record of bean after deleting the records it conflicts
+// with by its primary key or a unique index. It's a REPLACE INTO on MySQL
+// and SQLite, the other databases delete the records of the same keys and
+// insert the record in the transaction of the session, or in their own.
+// The affected records count the deleted ones, except on SQLite. The
+// hooks and the soft deletes of the deleted records are skipped.
+func (session *Session) Replace(bean interface{}) (int64, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
+	defer session.resetStatement()
+	if session.IsAutoClose {
+		defer session.Close()
+	}
+
+	switch session.Engine.dialect.DBType() {
+	case core.MYSQL, core.SQLITE:
+		session.Statement.insertReplace = true
+		affected, err := session.innerInsert(bean)
+		if err != nil {
+			return affected, err
+		}
+		// the replaced records may be cached
+		table := session.Statement.RefTable
+		if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
+			tableName := session.Statement.TableName()
+			cacher.ClearIds(tableName)
+			cacher.ClearBeans(tableName)
+		}
+		return affected, nil
+	}
+
+	var affected int64
+	err := session.inInsertTx(func() error {
+		deleted, err := session.deleteConflicts(bean)
+		if err != nil {
+			return err
+		}
+		inserted, err := session.innerInsert(bean)
+		affected = deleted + inserted
+		return err
+	})
+	return affected, err
+}
+
+// Replace inserts a record of bean after deleting the records it conflicts
+// with
+func (engine *Engine) Replace(bean interface{}) (int64, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.Replace(bean)
+}
+
+// deleteConflicts deletes the records of the keys of bean, the keys with
+// an autoincrement column not set by bean are skipped
+func (session *Session) deleteConflicts(bean interface{}) (int64, error) {
+	if err := session.Statement.setRefValue(rValue(bean)); err != nil {
+		return 0, err
+	}
+	table := session.Statement.RefTable
+
+	var values = make(map[string]interface{})
+	for _, col := range table.Columns() {
+		if col.MapType == core.ONLYFROMDB || col.IsCreated || col.IsUpdated || col.IsDeleted || col.IsVersion {
+			continue
+		}
+		fieldValue, err := col.ValueOf(bean)
+		if err != nil {
+			return 0, err
+		}
+		if col.IsAutoIncrement && isZero(fieldValue.Interface()) {
+			continue
+		}
+		arg, err := session.value2Interface(col, *fieldValue)
+		if err != nil {
+			return 0, err
+		}
+		values[strings.ToLower(col.Name)] = arg
+	}
+
+	keys := conflictKeys(table, func(col string) bool {
+		_, ok := values[strings.ToLower(col)]
+		return ok
+	})
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	var conds []string
+	var args []interface{}
+	for _, cols := range keys {
+		var eqs = make([]string, len(cols))
+		for i, col := range cols {
+			eqs[i] = session.Engine.Quote(col) + " = ?"
+			args = append(args, values[strings.ToLower(col)])
+		}
+		conds = append(conds, "("+strings.Join(eqs, " AND ")+")")
+	}
+	tableName := session.Statement.TableName()
+	res, err := session.exec("DELETE FROM "+session.Engine.Quote(tableName)+" WHERE "+strings.Join(conds, " OR "), args...)
+	if err != nil {
+		return 0, err
+	}
+	if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
+		cacher.ClearIds(tableName)
+		cacher.ClearBeans(tableName)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type ReplacedSetting struct {
+	Id    int64
+	Key   string `xorm:"varchar(64) unique"`
+	Value string
+}
+
+func TestReplace(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(ReplacedSetting))
+
+	_, err := testEngine.Replace(&ReplacedSetting{Key: "theme", Value: "dark"})
+	assert.NoError(t, err)
+	_, err = testEngine.Replace(&ReplacedSetting{Key: "lang", Value: "en"})
+	assert.NoError(t, err)
+
+	affected, err := testEngine.Replace(&ReplacedSetting{Key: "theme", Value: "light"})
+	assert.NoError(t, err)
+	assert.True(t, affected >= 1)
+
+	var settings []ReplacedSetting
+	assert.NoError(t, testEngine.Asc("key").Find(&settings))
+	if assert.Len(t, settings, 2) {
+		assert.EqualValues(t, "lang", settings[0].Key)
+		assert.EqualValues(t, "light", settings[1].Value)
+	}
+
+	// by the primary key
+	_, err = testEngine.Replace(&ReplacedSetting{Id: settings[0].Id, Key: "language", Value: "fr"})
+	assert.NoError(t, err)
+	var setting ReplacedSetting
+	has, err := testEngine.ID(settings[0].Id).Get(&setting)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "language", setting.Key)
+	cnt, err := testEngine.Count(new(ReplacedSetting))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cnt)
+}
+
+func TestReplaceEmulated(t *testing.T) {
+	mock, err := NewMockEngineOf(core.MSSQL)
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.On("^DELETE").Affected(1)
+	affected, err := mock.Replace(&ReplacedSetting{Key: "theme", Value: "light"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, affected)
+
+	var sqls []string
+	for _, record := range mock.Records() {
+		sqls = append(sqls, record.SQL)
+	}
+	if assert.Len(t, sqls, 4) {
+		assert.EqualValues(t, "BEGIN", sqls[0])
+		assert.EqualValues(t, `DELETE FROM "replaced_setting" WHERE ("key" = ?)`, sqls[1])
+		assert.Contains(t, sqls[2], "INSERT INTO")
+		assert.EqualValues(t, "COMMIT", sqls[3])
+	}
+}
+
+func TestReplaceCached(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(ReplacedSetting))
+
+	testEngine.MapCacher(new(ReplacedSetting), NewShardedCacher(ShardedCacherOptions{}))
+	defer testEngine.MapCacher(new(ReplacedSetting), nil)
+
+	setting := ReplacedSetting{Key: "theme", Value: "dark"}
+	_, err := testEngine.Insert(&setting)
+	assert.NoError(t, err)
+
+	var got ReplacedSetting
+	has, err := testEngine.ID(setting.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	// the cached bean of the replaced record is cleared
+	_, err = testEngine.Replace(&ReplacedSetting{Id: setting.Id, Key: "theme", Value: "light"})
+	assert.NoError(t, err)
+	got = ReplacedSetting{}
+	has, err = testEngine.ID(setting.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "light", got.Value)
+}
@@ -0,0 +1,128 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql/driver"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RetryPolicy defines how the idempotent reads of an engine are retried
+// when they fail with transient errors. Writes and the statements in
+// transactions are never retried, nor the raw queries which aren't plain
+// reads, as an INSERT RETURNING, a SELECT FOR UPDATE or a CALL.
+type RetryPolicy struct {
+	// MaxAttempts is the max number of executions including the first one
+	MaxAttempts int
+	// Backoff returns the time to wait before the next attempt, attempt
+	// starts from 1. No wait if it's nil.
+	Backoff func(attempt int) time.Duration
+	// Retryable returns true if the error is transient, IsTransientError is
+	// used if it's nil
+	Retryable func(err error) bool
+}
+
+// ExponentialBackoff doubles the wait from base for every attempt, the wait
+// never exceeds max
+func ExponentialBackoff(base, max time.Duration) func(int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// IsTransientError returns true if the error is likely caused by a dropped
+// or broken connection, so the statement could succeed on another one
+func IsTransientError(err error) bool {
+	switch err {
+	case nil:
+		return false
+	case driver.ErrBadConn, io.EOF, io.ErrUnexpectedEOF:
+		return true
+	}
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return true
+	}
+	if _, ok := err.(*net.OpError); ok {
+		return true
+	}
+
+	msg := err.Error()
+	for _, s := range []string{"connection reset", "broken pipe", "connection refused", "bad connection"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRetryPolicy set the retry policy of the reads, nil disables retrying
+func (engine *Engine) SetRetryPolicy(policy *RetryPolicy) {
+	engine.retryPolicy = policy
+}
+
+// retryRead runs the read f again on transient errors according to the
+// engine's retry policy. f should choose the database itself so a retry may
+// go to another slave of an engine group.
+func (session *Session) retryRead(f func() error) error {
+	policy := session.Engine.retryPolicy
+	if policy == nil || !session.IsAutoCommit {
+		return f()
+	}
+
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsTransientError
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		session.getLogger().Warnf("read failed on attempt %d, retrying: %v", attempt, err)
+		if policy.Backoff != nil {
+			time.Sleep(policy.Backoff(attempt))
+		}
+	}
+}
+
+// retryRawRead runs the raw query sqlStr as retryRead when it only reads,
+// otherwise once as a transient error may come after it was applied
+func (session *Session) retryRawRead(sqlStr string, f func() error) error {
+	if !isReadSQL(sqlStr) {
+		return f()
+	}
+	return session.retryRead(f)
+}
+
+// the words of the raw queries which may write
+var writeWordsRegexp = regexp.MustCompile(`(?i)\b(?:INSERT|UPDATE|DELETE|REPLACE|MERGE|INTO|CALL|EXEC|EXECUTE|RETURNING|NEXTVAL|SETVAL|LOCK)\b`)
+
+// isReadSQL returns true if the raw sqlStr is a SELECT, a WITH, a SHOW or
+// an EXPLAIN without any word of a write, the others may write
+func isReadSQL(sqlStr string) bool {
+	fields := strings.Fields(strings.TrimLeft(sqlStr, " \t\r\n("))
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH", "SHOW", "EXPLAIN", "DESCRIBE", "DESC":
+		return !writeWordsRegexp.MatchString(sqlStr)
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Millisecond, 5*time.Millisecond)
+	assert.EqualValues(t, time.Millisecond, backoff(1))
+	assert.EqualValues(t, 2*time.Millisecond, backoff(2))
+	assert.EqualValues(t, 4*time.Millisecond, backoff(3))
+	assert.EqualValues(t, 5*time.Millisecond, backoff(4))
+	assert.EqualValues(t, 5*time.Millisecond, backoff(10))
+}
+
+func TestIsTransientError(t *testing.T) {
+	assert.False(t, IsTransientError(nil))
+	assert.True(t, IsTransientError(driver.ErrBadConn))
+	assert.True(t, IsTransientError(io.EOF))
+	assert.True(t, IsTransientError(errors.New("read tcp: connection reset by peer")))
+	assert.False(t, IsTransientError(errors.New("syntax error")))
+}
+
+func TestIsReadSQL(t *testing.T) {
+	assert.True(t, isReadSQL("SELECT id FROM user WHERE name = ?"))
+	assert.True(t, isReadSQL(" (select 1) union (select 2)"))
+	assert.True(t, isReadSQL("WITH t AS (SELECT 1) SELECT * FROM t"))
+	assert.False(t, isReadSQL("INSERT INTO user (name) VALUES (?) RETURNING id"))
+	assert.False(t, isReadSQL("WITH t AS (DELETE FROM user RETURNING id) SELECT * FROM t"))
+	assert.False(t, isReadSQL("SELECT id FROM user FOR UPDATE"))
+	assert.False(t, isReadSQL("SELECT * INTO backup FROM user"))
+	assert.False(t, isReadSQL("SELECT nextval('user_id_seq')"))
+	assert.False(t, isReadSQL("CALL transfer(1, 2)"))
+}
+
+func TestRetryRawQuery(t *testing.T) {
+	mock, err := NewMockEngine()
+	assert.NoError(t, err)
+	defer mock.Close()
+	mock.SetRetryPolicy(&RetryPolicy{MaxAttempts: 3})
+
+	count := func(prefix string) int {
+		var n int
+		for _, record := range mock.Records() {
+			if strings.HasPrefix(record.SQL, prefix) {
+				n++
+			}
+		}
+		return n
+	}
+
+	mock.On(`^SELECT`).Error(io.EOF)
+	_, err = mock.QueryString("SELECT 1")
+	assert.Error(t, err)
+	assert.EqualValues(t, 3, count("SELECT"))
+
+	// a write may have been applied before the error, it's not retried
+	mock.On(`^INSERT`).Error(io.EOF)
+	_, err = mock.Query("INSERT INTO mock_user (name) VALUES (?) RETURNING id", "lunny")
+	assert.Error(t, err)
+	assert.EqualValues(t, 1, count("INSERT"))
+}
+
+func TestRetryRead(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	testEngine.SetRetryPolicy(&RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     ExponentialBackoff(time.Millisecond, time.Millisecond),
+	})
+	defer testEngine.SetRetryPolicy(nil)
+
+	sess := testEngine.NewSession()
+	defer sess.Close()
+
+	var attempts int
+	err := sess.retryRead(func() error {
+		attempts++
+		if attempts < 3 {
+			return driver.ErrBadConn
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, attempts)
+
+	// gives up after MaxAttempts
+	attempts = 0
+	err = sess.retryRead(func() error {
+		attempts++
+		return driver.ErrBadConn
+	})
+	assert.EqualValues(t, driver.ErrBadConn, err)
+	assert.EqualValues(t, 3, attempts)
+
+	// not transient
+	attempts = 0
+	err = sess.retryRead(func() error {
+		attempts++
+		return errors.New("syntax error")
+	})
+	assert.Error(t, err)
+	assert.EqualValues(t, 1, attempts)
+
+	// no retry in transaction
+	assert.NoError(t, sess.Begin())
+	attempts = 0
+	err = sess.retryRead(func() error {
+		attempts++
+		return driver.ErrBadConn
+	})
+	assert.Error(t, err)
+	assert.EqualValues(t, 1, attempts)
+	assert.NoError(t, sess.Rollback())
+
+	results, err := testEngine.QueryString("select 1 as a")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "1", results[0]["a"])
+}
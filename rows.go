@@ -21,6 +21,8 @@ type Rows struct {
 	rows      *core.Rows
 	fields    []string
 	beanType  reflect.Type
+	plan      *scanPlan
+	cursor    *rowsCursor // nil unless the rows are fetched from a cursor
 	lastError error
 }
 
@@ -43,6 +45,9 @@ func newRows(session *Session, bean interface{}) (*Rows, error) {
 	}
 
 	if rows.session.Statement.RawSQL == "" {
+		if err := rows.session.Statement.applySelectFields(); err != nil {
+			return nil, err
+		}
 		sqlStr, args = rows.session.Statement.genGetSQL(bean)
 	} else {
 		sqlStr = rows.session.Statement.RawSQL
@@ -54,28 +59,33 @@ func newRows(session *Session, bean interface{}) (*Rows, error) {
 	}
 
 	rows.session.saveLastSQL(sqlStr, args...)
-	var err error
-	if rows.session.prepareStmt {
-		rows.stmt, err = rows.session.DB().Prepare(sqlStr)
-		if err != nil {
-			rows.lastError = err
-			rows.Close()
-			return nil, err
-		}
-
-		rows.rows, err = rows.stmt.Query(args...)
-		if err != nil {
-			rows.lastError = err
-			rows.Close()
-			return nil, err
+	err := rows.session.interceptQuery(sqlStr, args, func(sqlStr string, args []interface{}) (err error) {
+		if size := rows.session.Statement.cursorSize; size > 0 && session.Engine.dialect.DBType() == core.POSTGRES {
+			return rows.declareCursor(sqlStr, args, size)
 		}
-	} else {
-		rows.rows, err = rows.session.DB().Query(sqlStr, args...)
-		if err != nil {
-			rows.lastError = err
-			rows.Close()
-			return nil, err
+		if rows.session.prepareStmt {
+			rows.session.Engine.checkPool(rows.session.DB())
+			rows.stmt, err = rows.session.DB().Prepare(sqlStr)
+			if err != nil {
+				return err
+			}
+			rows.rows, err = rows.stmt.QueryContext(rows.session.Ctx(), args...)
+			return err
 		}
+		return rows.session.retryRead(func() error {
+			db, err := rows.session.readDB()
+			if err != nil {
+				return err
+			}
+			rows.session.Engine.checkPool(db)
+			rows.rows, err = db.QueryContext(rows.session.Ctx(), sqlStr, args...)
+			return err
+		})
+	})
+	if err != nil {
+		rows.lastError = err
+		rows.Close()
+		return nil, err
 	}
 
 	rows.fields, err = rows.rows.Columns()
@@ -92,7 +102,15 @@ func newRows(session *Session, bean interface{}) (*Rows, error) {
 func (rows *Rows) Next() bool {
 	if rows.lastError == nil && rows.rows != nil {
 		hasNext := rows.rows.Next()
-		if !hasNext {
+		if rows.cursor != nil {
+			if !hasNext && rows.cursor.read == rows.cursor.size {
+				hasNext = rows.fetchNext()
+			}
+			if hasNext {
+				rows.cursor.read++
+			}
+		}
+		if !hasNext && rows.lastError == nil {
 			rows.lastError = sql.ErrNoRows
 		}
 		return hasNext
@@ -119,7 +137,11 @@ func (rows *Rows) Scan(bean interface{}) error {
 	if err := rows.session.Statement.setRefValue(dataStruct); err != nil {
 		return err
 	}
-	_, err := rows.session.row2Bean(rows.rows, rows.fields, len(rows.fields), bean, &dataStruct, rows.session.Statement.RefTable)
+	table := rows.session.Statement.RefTable
+	if rows.plan == nil || rows.plan.table != table || rows.plan.beanType != dataStruct.Type() {
+		rows.plan = rows.session.scanPlan(table, dataStruct.Type(), rows.fields)
+	}
+	_, err := rows.session.row2Bean(rows.rows, rows.fields, len(rows.fields), bean, &dataStruct, table, rows.plan)
 
 	return err
 }
@@ -129,6 +151,10 @@ func (rows *Rows) Close() error {
 	if rows.session.IsAutoClose {
 		defer rows.session.Close()
 	}
+	if rows.cursor != nil {
+		// after the rows of the last batch are closed
+		defer rows.closeCursor()
+	}
 
 	if rows.lastError == nil {
 		if rows.rows != nil {
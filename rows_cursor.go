@@ -0,0 +1,113 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/go-xorm/core"
+)
+
+// the sequence of the names of the cursors
+var cursorSeq int64
+
+// Cursor makes Rows and Iterate read the records of PostgreSQL by batches
+// of size from a server-side cursor, instead of the whole result at once.
+// The cursor is declared in the transaction of the session, or in a read
+// only transaction of its own ended by the close of the rows. It's ignored
+// by the other databases.
+func (session *Session) Cursor(size int) *Session {
+	session.Statement.cursorSize = size
+	return session
+}
+
+// rowsCursor is the server-side cursor the rows are fetched from
+type rowsCursor struct {
+	tx    *core.Tx
+	ownTx bool // the transaction is the one of the cursor
+	name  string
+	size  int
+	read  int // the rows read from the current batch
+}
+
+// declareCursor declares a cursor of the query sqlStr and fetches its first
+// batch
+func (rows *Rows) declareCursor(sqlStr string, args []interface{}, size int) error {
+	session := rows.session
+	cursor := &rowsCursor{
+		name: fmt.Sprintf("xorm_cursor_%d", atomic.AddInt64(&cursorSeq, 1)),
+		size: size,
+	}
+	if session.IsAutoCommit {
+		session.Engine.checkPool(session.DB())
+		tx, err := session.DB().BeginTx(session.Ctx(), &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return err
+		}
+		cursor.tx, cursor.ownTx = tx, true
+	} else {
+		cursor.tx = session.Tx
+	}
+
+	declare := "DECLARE " + cursor.name + " NO SCROLL CURSOR FOR " + sqlStr
+	if _, err := cursor.tx.Exec(declare, args...); err != nil {
+		if cursor.ownTx {
+			cursor.tx.Rollback()
+		}
+		return err
+	}
+	rows.cursor = cursor
+	return rows.fetch()
+}
+
+// fetch fetches the next batch of the cursor
+func (rows *Rows) fetch() error {
+	cursor := rows.cursor
+	sqlStr := fmt.Sprintf("FETCH FORWARD %d FROM %s", cursor.size, cursor.name)
+	rows.session.saveLastSQL(sqlStr)
+	r, err := cursor.tx.Query(sqlStr)
+	if err != nil {
+		return err
+	}
+	rows.rows = r
+	cursor.read = 0
+	return nil
+}
+
+// fetchNext fetches the batch after a full one and moves to its first row
+func (rows *Rows) fetchNext() bool {
+	if err := rows.rows.Err(); err != nil {
+		rows.lastError = err
+		return false
+	}
+	if err := rows.rows.Close(); err != nil {
+		rows.lastError = err
+		return false
+	}
+	rows.rows = nil
+	if err := rows.fetch(); err != nil {
+		rows.lastError = err
+		return false
+	}
+	return rows.rows.Next()
+}
+
+// closeCursor closes the cursor, its own transaction is rolled back as it
+// only reads
+func (rows *Rows) closeCursor() {
+	cursor := rows.cursor
+	rows.cursor = nil
+	var err error
+	if cursor.ownTx {
+		err = cursor.tx.Rollback()
+	} else {
+		_, err = cursor.tx.Exec("CLOSE " + cursor.name)
+	}
+	if err != nil {
+		rows.session.getLogger().Warnf("close cursor %v: %v", cursor.name, err)
+	}
+}
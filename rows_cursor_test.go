@@ -0,0 +1,60 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type CursorRecord struct {
+	Id  int64
+	Seq int
+}
+
+func TestRowsCursor(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(CursorRecord))
+
+	var records = make([]CursorRecord, 10)
+	for i := range records {
+		records[i].Seq = i
+	}
+	_, err := testEngine.Insert(&records)
+	assert.NoError(t, err)
+
+	// the batches of 3 and of 5 end with a partial and a full one
+	for _, size := range []int{3, 5} {
+		var seqs []int
+		err = testEngine.Cursor(size).Asc("seq").Iterate(new(CursorRecord), func(i int, bean interface{}) error {
+			seqs = append(seqs, bean.(*CursorRecord).Seq)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, seqs)
+	}
+
+	// in the transaction of the session
+	session := testEngine.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+
+	rows, err := session.Cursor(4).Where("seq >= ?", 5).Asc("seq").Rows(new(CursorRecord))
+	assert.NoError(t, err)
+	var seqs []int
+	for rows.Next() {
+		var record CursorRecord
+		assert.NoError(t, rows.Scan(&record))
+		seqs = append(seqs, record.Seq)
+	}
+	rows.Close()
+	assert.EqualValues(t, []int{5, 6, 7, 8, 9}, seqs)
+
+	cnt, err := session.Count(new(CursorRecord))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 10, cnt)
+	assert.NoError(t, session.Commit())
+}
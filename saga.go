@@ -0,0 +1,96 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+)
+
+type sagaStep struct {
+	engine     *Engine
+	action     func(*Session) error
+	compensate func(*Session) error
+}
+
+// Saga runs steps against one or more engines, every step is executed in a
+// local transaction of its engine. When a step fails, the compensations of
+// the succeeded steps are executed in reverse order, so a workflow across
+// engines could be undone without a distributed transaction.
+type Saga struct {
+	steps []sagaStep
+}
+
+// SagaError is returned by Saga.Run when a step failed
+type SagaError struct {
+	Step int   // index of the failed step
+	Err  error // error of the failed step
+	// errors of the compensations which failed, keyed by step index
+	CompensateErrs map[int]error
+}
+
+func (e *SagaError) Error() string {
+	if len(e.CompensateErrs) > 0 {
+		return fmt.Sprintf("saga step %d failed: %v, %d compensations failed", e.Step, e.Err, len(e.CompensateErrs))
+	}
+	return fmt.Sprintf("saga step %d failed: %v", e.Step, e.Err)
+}
+
+// NewSaga creates an empty saga
+func NewSaga() *Saga {
+	return &Saga{}
+}
+
+// Step adds a step running action on engine, compensate undoes the action
+// and could be nil if the action needs no compensation
+func (saga *Saga) Step(engine *Engine, action func(*Session) error, compensate func(*Session) error) *Saga {
+	saga.steps = append(saga.steps, sagaStep{engine, action, compensate})
+	return saga
+}
+
+// Run executes the steps in order. If a step failed, the compensations of
+// all the previous steps are executed in reverse order and a *SagaError is
+// returned.
+func (saga *Saga) Run() error {
+	for i, step := range saga.steps {
+		if err := runInTx(step.engine, step.action); err != nil {
+			step.engine.logger.Errorf("saga step %d failed: %v", i, err)
+			return saga.compensate(i, err)
+		}
+	}
+	return nil
+}
+
+func (saga *Saga) compensate(failed int, err error) error {
+	sagaErr := &SagaError{Step: failed, Err: err}
+	for i := failed - 1; i >= 0; i-- {
+		step := saga.steps[i]
+		if step.compensate == nil {
+			continue
+		}
+		if err := runInTx(step.engine, step.compensate); err != nil {
+			step.engine.logger.Errorf("saga compensation of step %d failed: %v", i, err)
+			if sagaErr.CompensateErrs == nil {
+				sagaErr.CompensateErrs = make(map[int]error)
+			}
+			sagaErr.CompensateErrs[i] = err
+		}
+	}
+	return sagaErr
+}
+
+// runInTx runs f in a transaction of engine
+func runInTx(engine *Engine, f func(*Session) error) error {
+	session := engine.NewSession()
+	defer session.Close()
+
+	if err := session.Begin(); err != nil {
+		return err
+	}
+	if err := f(session); err != nil {
+		session.Rollback()
+		return err
+	}
+	return session.Commit()
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaga(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	type SagaAccount struct {
+		Id      int64
+		Balance int
+	}
+	assert.NoError(t, testEngine.Sync2(new(SagaAccount)))
+
+	_, err := testEngine.Insert(&SagaAccount{Id: 1, Balance: 100}, &SagaAccount{Id: 2, Balance: 0})
+	assert.NoError(t, err)
+
+	transfer := func(id int64, amount int) func(*Session) error {
+		return func(session *Session) error {
+			_, err := session.ID(id).Incr("balance", amount).Update(new(SagaAccount))
+			return err
+		}
+	}
+
+	// all steps succeeded
+	err = NewSaga().
+		Step(testEngine, transfer(1, -10), transfer(1, 10)).
+		Step(testEngine, transfer(2, 10), transfer(2, -10)).
+		Run()
+	assert.NoError(t, err)
+
+	var account SagaAccount
+	_, err = testEngine.ID(2).Get(&account)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 10, account.Balance)
+
+	// the third step failed, the first two are compensated
+	var stepErr = errors.New("step failed")
+	var compensated []int
+	err = NewSaga().
+		Step(testEngine, transfer(1, -10), func(session *Session) error {
+			compensated = append(compensated, 0)
+			return transfer(1, 10)(session)
+		}).
+		Step(testEngine, transfer(2, 10), func(session *Session) error {
+			compensated = append(compensated, 1)
+			return transfer(2, -10)(session)
+		}).
+		Step(testEngine, func(session *Session) error {
+			if err := transfer(2, 1000)(session); err != nil {
+				return err
+			}
+			return stepErr
+		}, nil).
+		Run()
+	assert.Error(t, err)
+	sagaErr, ok := err.(*SagaError)
+	assert.True(t, ok)
+	assert.EqualValues(t, 2, sagaErr.Step)
+	assert.EqualValues(t, stepErr, sagaErr.Err)
+	assert.EqualValues(t, 0, len(sagaErr.CompensateErrs))
+	assert.EqualValues(t, []int{1, 0}, compensated)
+
+	account = SagaAccount{}
+	_, err = testEngine.ID(1).Get(&account)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 90, account.Balance)
+
+	account = SagaAccount{}
+	_, err = testEngine.ID(2).Get(&account)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 10, account.Balance)
+
+	// failed compensations are reported
+	err = NewSaga().
+		Step(testEngine, transfer(1, -1), func(session *Session) error {
+			return errors.New("compensation failed")
+		}).
+		Step(testEngine, func(session *Session) error {
+			return stepErr
+		}, nil).
+		Run()
+	sagaErr, ok = err.(*SagaError)
+	assert.True(t, ok)
+	assert.EqualValues(t, 1, len(sagaErr.CompensateErrs))
+	assert.Error(t, sagaErr.CompensateErrs[0])
+}
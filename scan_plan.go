@@ -0,0 +1,139 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// scanPlan is how the columns of a result are scanned into the beans of a
+// type, computed once from the table and reused for all the rows
+type scanPlan struct {
+	table    *core.Table
+	beanType reflect.Type
+	fields   []scanField // by result column
+}
+
+// scanField is how a result column is scanned
+type scanField struct {
+	col *core.Column // nil when the column has no field
+	// the indexes of the field from the bean, the nil pointers of the
+	// embedded structs are allocated on the way
+	index []int
+	// the converter of the field and the type it converts
+	converter Converter
+	valueType reflect.Type
+	// the field or its address implements core.Conversion
+	conversion     bool
+	addrConversion bool
+}
+
+type scanPlanKey struct {
+	table    *core.Table
+	beanType reflect.Type
+	fields   string
+}
+
+// scanPlan returns the plan of the result columns fields scanned into the
+// beans of type beanType mapped to table
+func (session *Session) scanPlan(table *core.Table, beanType reflect.Type, fields []string) *scanPlan {
+	engine := session.Engine
+	key := scanPlanKey{table, beanType, strings.Join(fields, ",")}
+	if plan, ok := engine.scanPlans.Load(key); ok {
+		return plan.(*scanPlan)
+	}
+
+	plan := &scanPlan{
+		table:    table,
+		beanType: beanType,
+		fields:   make([]scanField, len(fields)),
+	}
+	var idxes = make(map[string]int)
+	for i, key := range fields {
+		lKey := strings.ToLower(key)
+		idx, ok := idxes[lKey]
+		if ok {
+			idx++
+		}
+		idxes[lKey] = idx
+
+		col := session.resultColumn(table, key, idx)
+		if col == nil {
+			continue
+		}
+		index, fieldType, ok := fieldIndex(beanType, col.FieldName)
+		if !ok {
+			session.getLogger().Warnf("table %v's column %v is not valid or cannot set", table.Name, key)
+			continue
+		}
+		field := &plan.fields[i]
+		field.col = col
+		field.index = index
+		field.converter, field.valueType, _ = engine.converter(col, fieldType)
+		// the values of the interfaces are checked by the rows
+		field.conversion = fieldType.Kind() == reflect.Interface || fieldType.Implements(conversionType)
+		field.addrConversion = reflect.PtrTo(fieldType).Implements(conversionType)
+	}
+	engine.scanPlans.Store(key, plan)
+	return plan
+}
+
+// fieldIndex returns the indexes and the type of the field of the path
+// fieldName, as A.B for the field B of the field A, of the struct type t
+func fieldIndex(t reflect.Type, fieldName string) ([]int, reflect.Type, bool) {
+	var index []int
+	for _, name := range strings.Split(fieldName, ".") {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, nil, false
+		}
+		f, ok := t.FieldByName(name)
+		if !ok {
+			return nil, nil, false
+		}
+		index = append(index, f.Index...)
+		t = f.Type
+	}
+	return index, t, true
+}
+
+// fieldValue returns the field of the result column i of the bean v, or
+// nil when it has none
+func (plan *scanPlan) fieldValue(v reflect.Value, i int) *reflect.Value {
+	field := &plan.fields[i]
+	if field.col == nil {
+		return nil
+	}
+	for _, idx := range field.index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return nil
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	if !v.CanSet() {
+		return nil
+	}
+	return &v
+}
+
+// resetScanPlans drops the plans after a change of the way the columns are
+// matched or converted
+func (engine *Engine) resetScanPlans() {
+	engine.scanPlans.Range(func(key, _ interface{}) bool {
+		engine.scanPlans.Delete(key)
+		return true
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ScanPlanInfo struct {
+	Email string
+	Age   int
+}
+
+type ScanPlanUser struct {
+	Id            int64
+	Name          string
+	*ScanPlanInfo `xorm:"extends"`
+}
+
+func TestScanPlan(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(ScanPlanUser))
+
+	_, err := testEngine.Insert(&ScanPlanUser{Name: "alice", ScanPlanInfo: &ScanPlanInfo{Email: "alice@example.com", Age: 30}},
+		&ScanPlanUser{Name: "bob", ScanPlanInfo: &ScanPlanInfo{Email: "bob@example.com", Age: 40}})
+	assert.NoError(t, err)
+
+	// the nil embedded pointers are allocated
+	var users []ScanPlanUser
+	assert.NoError(t, testEngine.Asc("id").Find(&users))
+	if assert.EqualValues(t, 2, len(users)) {
+		assert.EqualValues(t, "alice", users[0].Name)
+		if assert.NotNil(t, users[0].ScanPlanInfo) {
+			assert.EqualValues(t, "alice@example.com", users[0].Email)
+			assert.EqualValues(t, 30, users[0].Age)
+		}
+		assert.EqualValues(t, 40, users[1].Age)
+	}
+
+	// the plan is reused for the same columns
+	table := testEngine.TableInfo(new(ScanPlanUser)).Table
+	session := testEngine.NewSession()
+	defer session.Close()
+	fields := []string{"id", "name", "unknown", "age"}
+	beanType := reflect.TypeOf(ScanPlanUser{})
+	plan := session.scanPlan(table, beanType, fields)
+	assert.True(t, plan == session.scanPlan(table, beanType, fields))
+	assert.Nil(t, plan.fields[2].col)
+	assert.EqualValues(t, []int{2, 1}, plan.fields[3].index)
+}
+
+func BenchmarkFindScanPlan(b *testing.B) {
+	b.StopTimer()
+	if err := prepareEngine(); err != nil {
+		b.Fatal(err)
+	}
+	if err := testEngine.Sync2(new(ScanPlanUser)); err != nil {
+		b.Fatal(err)
+	}
+	var users = make([]ScanPlanUser, 100)
+	for i := range users {
+		users[i] = ScanPlanUser{Name: "user", ScanPlanInfo: &ScanPlanInfo{Age: i}}
+	}
+	if _, err := testEngine.Insert(&users); err != nil {
+		b.Fatal(err)
+	}
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		var found []ScanPlanUser
+		if err := testEngine.Limit(100).Find(&found); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// findPrimitives appends the rows of a result of a single column to the
+// slices of int64, string and time.Time, as the ids of
+// Cols("id").Find(&ids), without the reflection of the other slices. It
+// returns false for the other slices.
+func (session *Session) findPrimitives(rows *core.Rows, field string, rowsSlicePtr interface{}) (bool, error) {
+	switch ids := rowsSlicePtr.(type) {
+	case *[]int64:
+		var v int64
+		for rows.Next() {
+			if err := rows.Scan(&v); err != nil {
+				return true, err
+			}
+			*ids = append(*ids, v)
+		}
+	case *[]string:
+		var v string
+		for rows.Next() {
+			if err := rows.Scan(&v); err != nil {
+				return true, err
+			}
+			*ids = append(*ids, v)
+		}
+	case *[]time.Time:
+		var scanner = session.newTimeScanner(field)
+		for rows.Next() {
+			if err := rows.Scan(scanner); err != nil {
+				return true, err
+			}
+			*ids = append(*ids, scanner.t)
+		}
+	default:
+		return false, nil
+	}
+	return true, rows.Err()
+}
+
+// scanTime scans the time of the current row of a result of a single
+// column, as the times of the time fields
+func (session *Session) scanTime(rows *core.Rows, t *time.Time) error {
+	fields, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(fields) != 1 {
+		return rows.Scan(t)
+	}
+	scanner := session.newTimeScanner(fields[0])
+	if err := rows.Scan(scanner); err != nil {
+		return err
+	}
+	*t = scanner.t
+	return nil
+}
+
+// timeScanner scans the times of the drivers returning times, texts or
+// unix timestamps as the time fields, in the timezone of the application
+type timeScanner struct {
+	session *Session
+	col     *core.Column
+	t       time.Time
+}
+
+func (session *Session) newTimeScanner(field string) *timeScanner {
+	return &timeScanner{
+		session: session,
+		col:     &core.Column{Name: field, FieldName: field},
+	}
+}
+
+// Scan implements sql.Scanner, a NULL is the zero time
+func (s *timeScanner) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case nil:
+		s.t = time.Time{}
+	case time.Time:
+		s.t = s.session.Engine.timeFromDB(s.col, v)
+	case int64:
+		s.t = time.Unix(v, 0).In(s.session.Engine.TZLocation)
+	case []byte:
+		s.t, err = s.session.byte2Time(s.col, v)
+	case string:
+		s.t, err = s.session.str2Time(s.col, v)
+	default:
+		err = fmt.Errorf("unsupported time %T of %v", src, s.col.Name)
+	}
+	return err
+}
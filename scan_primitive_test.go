@@ -0,0 +1,80 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ScanPrimitive struct {
+	Id      int64
+	Name    string
+	Created time.Time `xorm:"created"`
+}
+
+func TestFindPrimitives(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(ScanPrimitive))
+
+	_, err := testEngine.Insert(&ScanPrimitive{Name: "a"}, &ScanPrimitive{Name: "b"}, &ScanPrimitive{Name: "c"})
+	assert.NoError(t, err)
+
+	var ids []int64
+	assert.NoError(t, testEngine.Table(new(ScanPrimitive)).Cols("id").Asc("id").Find(&ids))
+	assert.EqualValues(t, []int64{1, 2, 3}, ids)
+
+	var names []string
+	assert.NoError(t, testEngine.Table(new(ScanPrimitive)).Cols("name").Asc("id").Find(&names))
+	assert.EqualValues(t, []string{"a", "b", "c"}, names)
+
+	var times []time.Time
+	assert.NoError(t, testEngine.Table(new(ScanPrimitive)).Cols("created").Find(&times))
+	if assert.EqualValues(t, 3, len(times)) {
+		assert.False(t, times[0].IsZero())
+		assert.EqualValues(t, testEngine.TZLocation.String(), times[0].Location().String())
+	}
+
+	// the slices are appended to
+	assert.NoError(t, testEngine.Table(new(ScanPrimitive)).Cols("id").Where("id > ?", 2).Find(&ids))
+	assert.EqualValues(t, []int64{1, 2, 3, 3}, ids)
+
+	var created time.Time
+	has, err := testEngine.SQL("SELECT MAX(" + testEngine.Quote("created") + ") FROM " +
+		testEngine.Quote(testEngine.TableMapper.Obj2Table("ScanPrimitive"))).Get(&created)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.False(t, created.IsZero())
+
+	var count int64
+	has, err = testEngine.Table(new(ScanPrimitive)).Select("COUNT(*)").Get(&count)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 3, count)
+}
+
+func BenchmarkFindPrimitives(b *testing.B) {
+	b.StopTimer()
+	if err := prepareEngine(); err != nil {
+		b.Fatal(err)
+	}
+	if err := testEngine.Sync2(new(ScanPrimitive)); err != nil {
+		b.Fatal(err)
+	}
+	var beans = make([]ScanPrimitive, 100)
+	if _, err := testEngine.Insert(&beans); err != nil {
+		b.Fatal(err)
+	}
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		var ids []int64
+		if err := testEngine.Table(new(ScanPrimitive)).Cols("id").Limit(100).Find(&ids); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
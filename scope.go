@@ -0,0 +1,57 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// GroupsTagHandler puts the column in the groups of its params, as
+// `xorm:"groups('list','detail')"`, the groups selected by Scope
+func GroupsTagHandler(ctx *tagContext) error {
+	var groups = make(map[string]bool, len(ctx.params))
+	for _, param := range ctx.params {
+		group := strings.ToLower(strings.Trim(strings.TrimSpace(param), "'"))
+		if group == "" {
+			return fmt.Errorf("empty group of the field %v", ctx.col.FieldName)
+		}
+		groups[group] = true
+	}
+	if len(groups) == 0 {
+		return fmt.Errorf("groups tag of the field %v without group", ctx.col.FieldName)
+	}
+	ctx.engine.columnGroups.Store(ctx.col, groups)
+	return nil
+}
+
+// Scope only selects the primary keys and the columns of the groups, the
+// columns with `xorm:"groups('list')"` for Scope("list"), when the columns
+// aren't given by Cols or Select
+func (statement *Statement) Scope(groups ...string) *Statement {
+	for _, group := range groups {
+		statement.scopes = append(statement.scopes, strings.ToLower(group))
+	}
+	return statement
+}
+
+// inScope returns true if col is selected by the scopes of the statement
+func (statement *Statement) inScope(col *core.Column) bool {
+	if len(statement.scopes) == 0 || col.IsPrimaryKey {
+		return true
+	}
+	groups, ok := statement.Engine.columnGroups.Load(col)
+	if !ok {
+		return false
+	}
+	for _, scope := range statement.scopes {
+		if groups.(map[string]bool)[scope] {
+			return true
+		}
+	}
+	return false
+}
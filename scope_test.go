@@ -0,0 +1,60 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ScopeArticle struct {
+	Id      int64
+	Title   string `xorm:"groups('list','detail')"`
+	Summary string `xorm:"groups(list)"`
+	Content string `xorm:"text groups('detail')"`
+	Views   int
+}
+
+func TestScope(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(ScopeArticle))
+
+	_, err := testEngine.Insert(&ScopeArticle{Title: "title", Summary: "summary", Content: "content", Views: 3})
+	assert.NoError(t, err)
+
+	var articles []ScopeArticle
+	assert.NoError(t, testEngine.Scope("list").Find(&articles))
+	assert.EqualValues(t, 1, len(articles))
+	assert.True(t, articles[0].Id > 0)
+	assert.EqualValues(t, "title", articles[0].Title)
+	assert.EqualValues(t, "summary", articles[0].Summary)
+	assert.EqualValues(t, "", articles[0].Content)
+	assert.EqualValues(t, 0, articles[0].Views)
+
+	var article ScopeArticle
+	has, err := testEngine.Scope("DETAIL").Get(&article)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "title", article.Title)
+	assert.EqualValues(t, "", article.Summary)
+	assert.EqualValues(t, "content", article.Content)
+
+	// the scopes are merged
+	article = ScopeArticle{}
+	has, err = testEngine.Scope("list", "detail").Get(&article)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "summary", article.Summary)
+	assert.EqualValues(t, "content", article.Content)
+	assert.EqualValues(t, 0, article.Views)
+
+	// without scope all the columns are selected
+	article = ScopeArticle{}
+	has, err = testEngine.Get(&article)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 3, article.Views)
+}
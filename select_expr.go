@@ -0,0 +1,77 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+	"strings"
+)
+
+// SelectExpr selects the expression expr after the columns of the table,
+// as "count(c.id) AS comment_count". The value of its alias is scanned
+// into the field of the same name which isn't a column, as CommentCount
+// tagged `xorm:"-"`.
+//
+//	engine.Table("post").Join("LEFT", "comment c", "c.post_id = post.id").
+//		GroupBy("post.id").Cols("post.id", "post.title").
+//		SelectExpr("count(c.id) AS comment_count").Find(&posts)
+func (statement *Statement) SelectExpr(expr string) *Statement {
+	statement.selectExprs = append(statement.selectExprs, expr)
+	return statement
+}
+
+// exprColumnStr returns columnStr with the expressions of SelectExpr
+func (statement *Statement) exprColumnStr(columnStr string) string {
+	if len(statement.selectExprs) == 0 {
+		return columnStr
+	}
+	exprs := strings.Join(statement.selectExprs, ", ")
+	if columnStr == "" {
+		return exprs
+	}
+	return columnStr + ", " + exprs
+}
+
+// setExprFields sets the fields of the bean v which aren't columns from
+// the result columns of the same names
+func (session *Session) setExprFields(v reflect.Value, plan *scanPlan, fields []string, scanResults []interface{}) error {
+	_, columnMapper := session.Engine.mappers(v.Type())
+	for i, key := range fields {
+		if plan.fields[i].col != nil {
+			continue
+		}
+		fieldValue := exprField(v, columnMapper.Obj2Table, key)
+		if !fieldValue.IsValid() {
+			continue
+		}
+		rawValue := reflect.Indirect(reflect.ValueOf(scanResults[i]))
+		if rawValue.Interface() == nil {
+			session.setNull(&fieldValue)
+			continue
+		}
+		if err := convertAssign(fieldValue.Addr().Interface(), rawValue.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exprField returns the settable field of the struct v named key, or whose
+// name is mapped to key, the invalid value when it has none
+func exprField(v reflect.Value, obj2Table func(string) string, key string) reflect.Value {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Anonymous {
+			continue
+		}
+		if strings.EqualFold(f.Name, key) || strings.EqualFold(obj2Table(f.Name), key) {
+			if fieldValue := v.Field(i); fieldValue.CanSet() {
+				return fieldValue
+			}
+		}
+	}
+	return reflect.Value{}
+}
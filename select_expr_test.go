@@ -0,0 +1,65 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ExprPost struct {
+	Id           int64
+	Title        string
+	CommentCount int `xorm:"-"`
+}
+
+type ExprComment struct {
+	Id         int64
+	ExprPostId int64
+}
+
+func TestSelectExprMock(t *testing.T) {
+	mock, err := NewMockEngine()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.On(`^SELECT`).Rows([]string{"id", "title", "comment_count"}, []interface{}{1, "xorm", 2})
+	var posts []ExprPost
+	assert.NoError(t, mock.SelectExpr("(SELECT count(*) FROM expr_comment c WHERE c.expr_post_id = expr_post.id) AS comment_count").
+		Find(&posts))
+	assert.EqualValues(t, "SELECT `id`, `title`, (SELECT count(*) FROM expr_comment c WHERE c.expr_post_id = expr_post.id)"+
+		" AS comment_count FROM `expr_post`", mock.LastRecord().SQL)
+	assert.EqualValues(t, []ExprPost{{1, "xorm", 2}}, posts)
+
+	var post ExprPost
+	has, err := mock.SelectExpr("1 AS CommentCount").Get(&post)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "SELECT `id`, `title`, 1 AS CommentCount FROM `expr_post` LIMIT 1", mock.LastRecord().SQL)
+}
+
+func TestSelectExpr(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(ExprPost), new(ExprComment))
+
+	post1, post2 := ExprPost{Title: "xorm"}, ExprPost{Title: "builder"}
+	_, err := testEngine.Insert(&post1, &post2)
+	assert.NoError(t, err)
+	_, err = testEngine.Insert(&ExprComment{ExprPostId: post1.Id}, &ExprComment{ExprPostId: post1.Id})
+	assert.NoError(t, err)
+
+	quote := testEngine.Quote
+	var posts []ExprPost
+	err = testEngine.Table("expr_post").
+		Join("LEFT", []string{"expr_comment", "c"}, "c.expr_post_id = "+quote("expr_post")+".id").
+		GroupBy(quote("expr_post")+".id, "+quote("expr_post")+".title").
+		Cols("expr_post.id", "expr_post.title").
+		SelectExpr("count(c.id) AS comment_count").
+		Asc("expr_post.id").
+		Find(&posts)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []ExprPost{{post1.Id, "xorm", 2}, {post2.Id, "builder", 0}}, posts)
+}
@@ -0,0 +1,343 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-xorm/core"
+)
+
+// Serializer persists the composite values, as the slices and the maps, of
+// the columns of the SERIALIZE tag and of the engine default
+type Serializer interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	// Binary is true if the values are stored in BLOBs, else in TEXTs
+	Binary() bool
+}
+
+var (
+	serializersMu sync.RWMutex
+	serializers   = map[string]Serializer{
+		"json":  jsonSerializer{},
+		"gob":   gobSerializer{},
+		"array": arraySerializer{},
+	}
+)
+
+// RegisterSerializer registers a serialization for the SERIALIZE(name)
+// tags and SetSerializer, as msgpack by the msgpackadapter package. json,
+// gob and array are registered by default.
+func RegisterSerializer(name string, serializer Serializer) {
+	serializersMu.Lock()
+	serializers[strings.ToLower(name)] = serializer
+	serializersMu.Unlock()
+}
+
+func getSerializer(name string) (Serializer, bool) {
+	serializersMu.RLock()
+	serializer, ok := serializers[strings.ToLower(name)]
+	serializersMu.RUnlock()
+	return serializer, ok
+}
+
+// SetSerializer sets the serialization of the slice and map fields without
+// a SERIALIZE tag, the fields are stored as JSON when it's not set or is
+// set to "". It should be called before the tables are mapped.
+func (engine *Engine) SetSerializer(name string) error {
+	if _, ok := getSerializer(name); !ok && name != "" {
+		return fmt.Errorf("unknown serializer %v", name)
+	}
+	engine.serializer = strings.ToLower(name)
+	return nil
+}
+
+// SerializeTagHandler stores a field with a serializer,
+// `xorm:"serialize(gob)"`, the default serializer of the engine or json
+// without a name
+func SerializeTagHandler(ctx *tagContext) error {
+	name := ctx.engine.serializer
+	if len(ctx.params) > 0 {
+		name = strings.TrimSpace(ctx.params[0])
+	}
+	if name == "" {
+		name = "json"
+	}
+	converter, err := ctx.engine.serializeConverter(name, ctx.fieldValue.Type())
+	if err != nil {
+		return fmt.Errorf("serialize tag of the field %v: %v", ctx.col.FieldName, err)
+	}
+	if ctx.col.SQLType.Name == "" {
+		ctx.col.SQLType = converter.SQLType()
+	}
+	ctx.engine.setColumnConverter(ctx.col, converter)
+	return nil
+}
+
+// serializeByDefault stores col with the default serializer of the engine
+// if its field is a slice or a map without a conversion, it returns the SQL
+// type of the serializer
+func (engine *Engine) serializeByDefault(col *core.Column, t reflect.Type) (core.SQLType, bool) {
+	if engine.serializer == "" {
+		return core.SQLType{}, false
+	}
+	if _, ok := engine.columnConverters.Load(col); ok {
+		return core.SQLType{}, false
+	}
+	if _, _, ok := engine.converter(nil, t); ok {
+		return core.SQLType{}, false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Map ||
+		t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return core.SQLType{}, false
+	}
+	pt := reflect.PtrTo(t)
+	if pt.Implements(conversionType) || pt.Implements(scannerType) || t.Implements(valuerType) {
+		return core.SQLType{}, false
+	}
+
+	converter, err := engine.serializeConverter(engine.serializer, t)
+	if err != nil {
+		return core.SQLType{}, false
+	}
+	engine.setColumnConverter(col, converter)
+	return converter.SQLType(), true
+}
+
+var conversionType = reflect.TypeOf((*core.Conversion)(nil)).Elem()
+
+func (engine *Engine) serializeConverter(name string, t reflect.Type) (*serializeConverter, error) {
+	serializer, ok := getSerializer(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown serializer %v", name)
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if _, ok := serializer.(arraySerializer); ok && !isScalarSlice(t) {
+		return nil, fmt.Errorf("%v isn't a slice of numbers, strings or bools", t)
+	}
+	return &serializeConverter{
+		dbType:     engine.dialect.DBType(),
+		typ:        t,
+		serializer: serializer,
+	}, nil
+}
+
+// serializeConverter converts the values of a column with a serializer
+type serializeConverter struct {
+	dbType     core.DbType
+	typ        reflect.Type
+	serializer Serializer
+}
+
+func (c *serializeConverter) SQLType() core.SQLType {
+	if _, ok := c.serializer.(arraySerializer); ok && c.dbType == core.POSTGRES {
+		return core.SQLType{Name: pgArrayType(c.typ.Elem()) + "[]"}
+	}
+	if c.serializer.Binary() {
+		return core.SQLType{Name: core.Blob}
+	}
+	return core.SQLType{Name: core.Text}
+}
+
+// ToDB stores the nil slices and maps as NULLs
+func (c *serializeConverter) ToDB(v interface{}) (interface{}, error) {
+	if rv := reflect.ValueOf(v); (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map) && rv.IsNil() {
+		return nil, nil
+	}
+	data, err := c.serializer.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if c.serializer.Binary() {
+		return data, nil
+	}
+	return string(data), nil
+}
+
+func (c *serializeConverter) FromDB(src interface{}) (interface{}, error) {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil, fmt.Errorf("unsupported serialized %v %T", c.typ, src)
+	}
+	ptr := reflect.New(c.typ)
+	if err := c.serializer.Unmarshal(data, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonSerializer) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonSerializer) Binary() bool                               { return false }
+
+type gobSerializer struct{}
+
+func (gobSerializer) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobSerializer) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (gobSerializer) Binary() bool { return true }
+
+// arraySerializer stores the slices of numbers, strings and bools as the
+// array literals of PostgreSQL, as {1,2,3} or {"a","b"}, which are the
+// values of its array columns
+type arraySerializer struct{}
+
+func isScalarSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return false
+	}
+	switch t.Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Bool, reflect.String:
+		return true
+	}
+	return false
+}
+
+// pgArrayType returns the type of the elements of the array columns
+func pgArrayType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return core.BigInt
+	case reflect.Float32, reflect.Float64:
+		return "DOUBLE PRECISION"
+	case reflect.Bool:
+		return core.Bool
+	}
+	return core.Text
+}
+
+func (arraySerializer) Marshal(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if !isScalarSlice(rv.Type()) {
+		return nil, fmt.Errorf("%T isn't a slice of numbers, strings or bools", v)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		elem := rv.Index(i)
+		switch elem.Kind() {
+		case reflect.String:
+			buf.WriteByte('"')
+			s := strings.Replace(elem.String(), `\`, `\\`, -1)
+			buf.WriteString(strings.Replace(s, `"`, `\"`, -1))
+			buf.WriteByte('"')
+		case reflect.Bool:
+			buf.WriteString(strconv.FormatBool(elem.Bool()))
+		default:
+			fmt.Fprint(&buf, elem.Interface())
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (arraySerializer) Unmarshal(data []byte, v interface{}) error {
+	elems, err := parseArray(string(data))
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() == reflect.Slice {
+		rv.Set(reflect.MakeSlice(rv.Type(), len(elems), len(elems)))
+	} else if rv.Len() < len(elems) {
+		return fmt.Errorf("array of %d elements for a %v", len(elems), rv.Type())
+	}
+	for i, elem := range elems {
+		var src interface{} = elem
+		if rv.Type().Elem().Kind() == reflect.Bool {
+			// the booleans of PostgreSQL are t and f
+			src = elem == "t" || elem == "true"
+		}
+		if err := convertAssign(rv.Index(i).Addr().Interface(), src); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (arraySerializer) Binary() bool { return false }
+
+// parseArray returns the elements of a one-dimensional array literal
+func parseArray(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, fmt.Errorf("invalid array %q", s)
+	}
+	s = s[1 : len(s)-1]
+	if s == "" {
+		return []string{}, nil
+	}
+
+	var elems []string
+	for i := 0; i <= len(s); {
+		if i < len(s) && s[i] == '"' {
+			var elem []byte
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+				if i < len(s) {
+					elem = append(elem, s[i])
+				}
+			}
+			if i >= len(s) {
+				return nil, errors.New("unterminated string of array")
+			}
+			elems = append(elems, string(elem))
+			i++
+		} else {
+			end := strings.IndexByte(s[i:], ',')
+			if end < 0 {
+				end = len(s) - i
+			}
+			elems = append(elems, strings.TrimSpace(s[i:i+end]))
+			i += end
+		}
+		if i < len(s) && s[i] != ',' {
+			return nil, fmt.Errorf("invalid array %q", s)
+		}
+		i++
+	}
+	return elems, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArraySerializer(t *testing.T) {
+	var s arraySerializer
+
+	data, err := s.Marshal([]string{"a", `b "c"`, `d\e`, ""})
+	assert.NoError(t, err)
+	assert.EqualValues(t, `{"a","b \"c\"","d\\e",""}`, string(data))
+
+	var strs []string
+	assert.NoError(t, s.Unmarshal(data, &strs))
+	assert.EqualValues(t, []string{"a", `b "c"`, `d\e`, ""}, strs)
+
+	var ints []int64
+	assert.NoError(t, s.Unmarshal([]byte("{1, 2,3}"), &ints))
+	assert.EqualValues(t, []int64{1, 2, 3}, ints)
+	assert.NoError(t, s.Unmarshal([]byte("{}"), &ints))
+	assert.Empty(t, ints)
+
+	var bools []bool
+	assert.NoError(t, s.Unmarshal([]byte("{t,f,true}"), &bools))
+	assert.EqualValues(t, []bool{true, false, true}, bools)
+
+	assert.Error(t, s.Unmarshal([]byte("1,2"), &ints))
+	assert.Error(t, s.Unmarshal([]byte(`{"a}`), &strs))
+	_, err = s.Marshal([]struct{}{})
+	assert.Error(t, err)
+}
+
+type SerializeStruct struct {
+	Id     int64
+	Json   map[string]int
+	Gob    []string         `xorm:"serialize(gob)"`
+	Array  []int64          `xorm:"serialize(array)"`
+	Named  map[string]int   `xorm:"text serialize(json)"`
+	Ptr    *map[string]bool `xorm:"serialize(gob)"`
+	Absent []string         `xorm:"serialize(gob)"`
+}
+
+func TestSerializeTag(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(SerializeStruct))
+
+	table := testEngine.TableInfo(new(SerializeStruct))
+	col := table.GetColumn(testEngine.ColumnMapper.Obj2Table("Gob"))
+	assert.NotNil(t, col)
+	assert.True(t, col.SQLType.IsBlob())
+
+	ptr := map[string]bool{"on": true}
+	s := SerializeStruct{
+		Json:  map[string]int{"a": 1},
+		Gob:   []string{"x", "y"},
+		Array: []int64{3, 2, 1},
+		Named: map[string]int{"b": 2},
+		Ptr:   &ptr,
+	}
+	cnt, err := testEngine.Insert(&s)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var array string
+	has, err := testEngine.Table(new(SerializeStruct)).Cols("array").Get(&array)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "{3,2,1}", array)
+
+	var absent int64
+	absent, err = testEngine.Where("absent IS NULL").Count(new(SerializeStruct))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, absent)
+
+	var got SerializeStruct
+	has, err = testEngine.ID(s.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, s.Json, got.Json)
+	assert.EqualValues(t, s.Gob, got.Gob)
+	assert.EqualValues(t, s.Array, got.Array)
+	assert.EqualValues(t, s.Named, got.Named)
+	assert.NotNil(t, got.Ptr)
+	assert.EqualValues(t, ptr, *got.Ptr)
+	assert.Nil(t, got.Absent)
+
+	cnt, err = testEngine.ID(s.Id).Update(&SerializeStruct{Gob: []string{"z"}})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	got = SerializeStruct{}
+	has, err = testEngine.ID(s.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, []string{"z"}, got.Gob)
+}
+
+type SerializeDefaultStruct struct {
+	Id    int64
+	Tags  []string
+	Attrs map[string]string
+	Data  []byte
+}
+
+func TestSetSerializer(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	assert.Error(t, testEngine.SetSerializer("yaml"))
+	assert.NoError(t, testEngine.SetSerializer("gob"))
+	defer testEngine.SetSerializer("")
+
+	assertSync(t, new(SerializeDefaultStruct))
+	table := testEngine.TableInfo(new(SerializeDefaultStruct))
+	for _, name := range []string{"Tags", "Attrs"} {
+		col := table.GetColumn(testEngine.ColumnMapper.Obj2Table(name))
+		assert.NotNil(t, col)
+		assert.True(t, col.SQLType.IsBlob(), name)
+		_, ok := testEngine.columnConverters.Load(col)
+		assert.True(t, ok, name)
+	}
+	col := table.GetColumn(testEngine.ColumnMapper.Obj2Table("Data"))
+	_, ok := testEngine.columnConverters.Load(col)
+	assert.False(t, ok)
+	if testEngine.Dialect().DBType() == core.POSTGRES {
+		assert.EqualValues(t, core.Bytea, col.SQLType.Name)
+	}
+
+	s := SerializeDefaultStruct{
+		Tags:  []string{"a", "b"},
+		Attrs: map[string]string{"k": "v"},
+		Data:  []byte("data"),
+	}
+	_, err := testEngine.Insert(&s)
+	assert.NoError(t, err)
+
+	var got SerializeDefaultStruct
+	has, err := testEngine.ID(s.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, s, got)
+}
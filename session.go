@@ -5,13 +5,13 @@
 package xorm
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"hash/crc32"
 	"reflect"
-	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-xorm/core"
@@ -41,18 +41,62 @@ type Session struct {
 	beforeClosures []func(interface{})
 	afterClosures  []func(interface{})
 
+	// callbacks waiting for the outcome of the current transaction
+	beforeCommitCallbacks []func() error
+	commitCallbacks       []func()
+	rollbackCallbacks     []func()
+
 	prepareStmt bool
-	stmtCache   map[uint32]*core.Stmt //key: hash.Hash32 of (queryStr, len(queryStr))
+	useMaster   bool
+	lastWrite   time.Time
+	stmtCache   *stmtCache
 
 	// !evalphobia! stored the last executed query on this session
 	//beforeSQLExec func(string, ...interface{})
 	lastSQL     string
 	lastSQLArgs []interface{}
+
+	// not nil when engine enabled session concurrency detection
+	guard *sessionGuard
+
+	// true when the session is closed and put back to the engine's pool
+	pooled bool
+	// the operations running on the session, a session closed by one of
+	// them is put back to the pool when the last one has left
+	running    int
+	closeDefer bool
+
+	ctx context.Context
+
+	// the values of the beans loaded by GetForUpdate
+	snapshots map[interface{}]map[string]interface{}
+
+	// the SQL hooks of the session, called after the ones of the engine
+	sqlHooks []SQLHook
+
+	// true when the query filters of the engine are not applied
+	bypassFilters bool
+
+	id uint64 // the id of the session in the structured logs
+
+	// not nil while Explain or ToSQL captures the statement of the session
+	explaining *QueryPlan
+
+	// the connection of ExecMulti out of a transaction
+	conn *sql.Conn
+
+	// the temporary tables of the structs mapped by TempTable
+	tempTables map[reflect.Type]string
+
+	// the logger and the SQL logging of the session, the engine's ones when nil
+	logger  core.ILogger
+	showSQL *bool
 }
 
 // Clone copy all the session's content and return a new session
 func (session *Session) Clone() *Session {
 	var sess = *session
+	sess.Statement.session = &sess
 	return &sess
 }
 
@@ -60,18 +104,40 @@ func (session *Session) Clone() *Session {
 func (session *Session) Init() {
 	session.Statement.Init()
 	session.Statement.Engine = session.Engine
+	session.Statement.tenant = ""
+	session.Statement.session = session
 	session.IsAutoCommit = true
 	session.IsCommitedOrRollbacked = false
 	session.IsAutoClose = false
 	session.AutoResetStatement = true
 	session.prepareStmt = false
+	session.useMaster = false
+	session.lastWrite = time.Time{}
+	session.ctx = nil
+	session.snapshots = nil
+	session.sqlHooks = nil
+	session.bypassFilters = false
+	session.explaining = nil
+	session.tempTables = nil
+	session.logger = nil
+	session.showSQL = nil
 
 	// !nashtsai! is lazy init better?
-	session.afterInsertBeans = make(map[interface{}]*[]func(interface{}), 0)
-	session.afterUpdateBeans = make(map[interface{}]*[]func(interface{}), 0)
-	session.afterDeleteBeans = make(map[interface{}]*[]func(interface{}), 0)
+	// reuse the empty maps of a reused session
+	if session.afterInsertBeans == nil || len(session.afterInsertBeans) > 0 {
+		session.afterInsertBeans = make(map[interface{}]*[]func(interface{}), 0)
+	}
+	if session.afterUpdateBeans == nil || len(session.afterUpdateBeans) > 0 {
+		session.afterUpdateBeans = make(map[interface{}]*[]func(interface{}), 0)
+	}
+	if session.afterDeleteBeans == nil || len(session.afterDeleteBeans) > 0 {
+		session.afterDeleteBeans = make(map[interface{}]*[]func(interface{}), 0)
+	}
 	session.beforeClosures = make([]func(interface{}), 0)
 	session.afterClosures = make([]func(interface{}), 0)
+	session.beforeCommitCallbacks = nil
+	session.commitCallbacks = nil
+	session.rollbackCallbacks = nil
 
 	session.lastSQL = ""
 	session.lastSQLArgs = []interface{}{}
@@ -79,8 +145,8 @@ func (session *Session) Init() {
 
 // Close release the connection from pool
 func (session *Session) Close() {
-	for _, v := range session.stmtCache {
-		v.Close()
+	if session.stmtCache != nil {
+		session.stmtCache.close()
 	}
 
 	if session.db != nil {
@@ -94,6 +160,12 @@ func (session *Session) Close() {
 		session.Init()
 		session.db = nil
 	}
+
+	if session.running > 0 {
+		session.closeDefer = true
+		return
+	}
+	session.Engine.putPooledSession(session)
 }
 
 func (session *Session) resetStatement() {
@@ -102,6 +174,58 @@ func (session *Session) resetStatement() {
 	}
 }
 
+// Tenant applies the tenant to all the statements built in the session with
+// the engine's tenant strategy until the session is closed. Raw SQLs are
+// not changed. When the strategy puts the tenant in the table names, the
+// statements of a tenant which isn't a plain identifier fail with
+// ErrInvalidTenant.
+func (session *Session) Tenant(tenant string) *Session {
+	session.Statement.tenant = tenant
+	return session
+}
+
+// Context set the context passed to the processors of the beans until the
+// session is closed
+func (session *Session) Context(ctx context.Context) *Session {
+	session.ctx = ctx
+	return session
+}
+
+// Ctx returns the context of the session, context.Background() when none is set
+func (session *Session) Ctx() context.Context {
+	if session.ctx == nil {
+		return context.Background()
+	}
+	return session.ctx
+}
+
+// ForceMaster sends the next query to the master of the engine group
+func (session *Session) ForceMaster() *Session {
+	session.Statement.route = routeMaster
+	return session
+}
+
+// PreferReplica sends the next query to a slave of the engine group even
+// if the session uses master or is in the sticky window after a write
+func (session *Session) PreferReplica() *Session {
+	session.Statement.route = routeReplica
+	return session
+}
+
+// markWrite records the time of a write for the sticky window
+func (session *Session) markWrite() {
+	if session.Engine.group != nil && session.Engine.group.stickyWindow > 0 {
+		session.lastWrite = time.Now()
+	}
+}
+
+// UseMaster forces all the reads of the session to go to the master of the
+// engine group until the session is closed
+func (session *Session) UseMaster() *Session {
+	session.useMaster = true
+	return session
+}
+
 // Prepare set a flag to session that should be prepare statement before execute query
 func (session *Session) Prepare() *Session {
 	session.prepareStmt = true
@@ -185,6 +309,18 @@ func (session *Session) StoreEngine(storeEngine string) *Session {
 	return session
 }
 
+// Temporary makes CreateTable create a temporary table
+func (session *Session) Temporary() *Session {
+	session.Statement.Temporary()
+	return session
+}
+
+// Unlogged makes CreateTable create an unlogged table, PostgreSQL only
+func (session *Session) Unlogged() *Session {
+	session.Statement.Unlogged()
+	return session
+}
+
 // Charset is only avialble mysql dialect currently
 func (session *Session) Charset(charset string) *Session {
 	session.Statement.Charset = charset
@@ -228,11 +364,42 @@ func (session *Session) Having(conditions string) *Session {
 func (session *Session) DB() *core.DB {
 	if session.db == nil {
 		session.db = session.Engine.db
-		session.stmtCache = make(map[uint32]*core.Stmt, 0)
+		session.stmtCache = newStmtCache(session.Engine)
 	}
 	return session.db
 }
 
+// readDB returns the database which autocommit reads are sent to. For a
+// session of an engine group it's a slave chosen by the group's policy,
+// unless the session uses master, transaction or prepared statements, or
+// the routing hint of the statement or the sticky window after a write
+// requires the master.
+func (session *Session) readDB() (*core.DB, error) {
+	group := session.Engine.group
+	if group == nil || session.prepareStmt || !session.IsAutoCommit {
+		return session.DB(), nil
+	}
+
+	switch session.Statement.route {
+	case routeMaster:
+		return session.DB(), nil
+	case routeDefault:
+		if session.useMaster {
+			return session.DB(), nil
+		}
+		if group.stickyWindow > 0 && !session.lastWrite.IsZero() &&
+			time.Since(session.lastWrite) < group.stickyWindow {
+			return session.DB(), nil
+		}
+	}
+
+	slave := group.Slave()
+	if slave == nil {
+		return nil, ErrNoHealthySlave
+	}
+	return slave.DB(), nil
+}
+
 func cleanupProcessorsClosures(slices *[]func(interface{})) {
 	if len(*slices) > 0 {
 		*slices = make([]func(interface{}), 0)
@@ -246,58 +413,44 @@ func (session *Session) canCache() bool {
 		!session.Statement.UseCache ||
 		session.Statement.IsForUpdate ||
 		session.Tx != nil ||
-		len(session.Statement.selectStr) > 0 {
+		len(session.Statement.selectStr) > 0 ||
+		len(session.Statement.selectExprs) > 0 {
 		return false
 	}
 	return true
 }
 
 func (session *Session) doPrepare(sqlStr string) (stmt *core.Stmt, err error) {
-	crc := crc32.ChecksumIEEE([]byte(sqlStr))
-	// TODO try hash(sqlStr+len(sqlStr))
-	var has bool
-	stmt, has = session.stmtCache[crc]
-	if !has {
-		stmt, err = session.DB().Prepare(sqlStr)
+	db := session.DB()
+	stmt = session.stmtCache.get(sqlStr)
+	if stmt == nil {
+		// get the version before preparing, a DDL executed meanwhile makes
+		// the statement stale at once
+		schemaVersion := atomic.LoadInt64(&session.Engine.stmtCounters.schemaVersion)
+		stmt, err = db.Prepare(sqlStr)
 		if err != nil {
 			return nil, err
 		}
-		session.stmtCache[crc] = stmt
+		session.stmtCache.put(sqlStr, stmt, schemaVersion)
 	}
 	return
 }
 
-func (session *Session) getField(dataStruct *reflect.Value, key string, table *core.Table, idx int) *reflect.Value {
-	var col *core.Column
-	if col = table.GetColumnIdx(key, idx); col == nil {
-		//session.Engine.logger.Warnf("table %v has no column %v. %v", table.Name, key, table.ColumnsSeq())
-		return nil
-	}
-
-	fieldValue, err := col.ValueOfV(dataStruct)
-	if err != nil {
-		session.Engine.logger.Error(err)
-		return nil
-	}
-
-	if !fieldValue.IsValid() || !fieldValue.CanSet() {
-		session.Engine.logger.Warnf("table %v's column %v is not valid or cannot set", table.Name, key)
-		return nil
-	}
-	return fieldValue
-}
-
 // Cell cell is a result of one column field
 type Cell *interface{}
 
 func (session *Session) rows2Beans(rows *core.Rows, fields []string, fieldsCount int,
 	table *core.Table, newElemFunc func([]string) reflect.Value,
 	sliceValueSetFunc func(*reflect.Value, core.PK) error) error {
+	var plan *scanPlan
 	for rows.Next() {
 		var newValue = newElemFunc(fields)
 		bean := newValue.Interface()
 		dataStruct := rValue(bean)
-		pk, err := session.row2Bean(rows, fields, fieldsCount, bean, &dataStruct, table)
+		if plan == nil {
+			plan = session.scanPlan(table, dataStruct.Type(), fields)
+		}
+		pk, err := session.row2Bean(rows, fields, fieldsCount, bean, &dataStruct, table, plan)
 		if err != nil {
 			return err
 		}
@@ -310,7 +463,7 @@ func (session *Session) rows2Beans(rows *core.Rows, fields []string, fieldsCount
 	return nil
 }
 
-func (session *Session) row2Bean(rows *core.Rows, fields []string, fieldsCount int, bean interface{}, dataStruct *reflect.Value, table *core.Table) (core.PK, error) {
+func (session *Session) row2Bean(rows *core.Rows, fields []string, fieldsCount int, bean interface{}, dataStruct *reflect.Value, table *core.Table, plan *scanPlan) (core.PK, error) {
 	// handle beforeClosures
 	for _, closure := range session.beforeClosures {
 		closure(bean)
@@ -338,61 +491,66 @@ func (session *Session) row2Bean(rows *core.Rows, fields []string, fieldsCount i
 			}
 		}
 
+		if b, hasAfterLoad := bean.(AfterLoadProcessor); hasAfterLoad {
+			session.runHook(func() error {
+				b.AfterLoad(session.Ctx(), session)
+				return nil
+			})
+		}
+
 		// handle afterClosures
 		for _, closure := range session.afterClosures {
 			closure(bean)
 		}
 	}()
 
-	var tempMap = make(map[string]int)
 	var pk core.PK
 	for ii, key := range fields {
-		var idx int
-		var ok bool
-		var lKey = strings.ToLower(key)
-		if idx, ok = tempMap[lKey]; !ok {
-			idx = 0
-		} else {
-			idx = idx + 1
-		}
-		tempMap[lKey] = idx
-
-		if fieldValue := session.getField(dataStruct, key, table, idx); fieldValue != nil {
+		if fieldValue := plan.fieldValue(*dataStruct, ii); fieldValue != nil {
+			field := &plan.fields[ii]
 			rawValue := reflect.Indirect(reflect.ValueOf(scanResults[ii]))
 
-			// if row is null then ignore
+			// a NULL resets the pointers and the nullable fields
 			if rawValue.Interface() == nil {
+				session.setNull(fieldValue)
 				continue
 			}
 
-			if fieldValue.CanAddr() {
-				if structConvert, ok := fieldValue.Addr().Interface().(core.Conversion); ok {
-					if data, err := value2Bytes(&rawValue); err == nil {
-						if err := structConvert.FromDB(data); err != nil {
-							return nil, err
-						}
-					} else {
-						return nil, err
-					}
-					continue
+			if field.converter != nil {
+				if err := setConverted(field.converter, field.valueType, fieldValue, rawValue.Interface()); err != nil {
+					return nil, err
 				}
+				continue
 			}
 
-			if _, ok := fieldValue.Interface().(core.Conversion); ok {
+			if field.addrConversion {
 				if data, err := value2Bytes(&rawValue); err == nil {
-					if fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() {
-						fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
+					if err := fieldValue.Addr().Interface().(core.Conversion).FromDB(data); err != nil {
+						return nil, err
 					}
-					fieldValue.Interface().(core.Conversion).FromDB(data)
 				} else {
 					return nil, err
 				}
 				continue
 			}
 
+			if field.conversion {
+				if _, ok := fieldValue.Interface().(core.Conversion); ok {
+					if data, err := value2Bytes(&rawValue); err == nil {
+						if fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() {
+							fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
+						}
+						fieldValue.Interface().(core.Conversion).FromDB(data)
+					} else {
+						return nil, err
+					}
+					continue
+				}
+			}
+
 			rawValueType := reflect.TypeOf(rawValue.Interface())
 			vv := reflect.ValueOf(rawValue.Interface())
-			col := table.GetColumnIdx(key, idx)
+			col := field.col
 			if col.IsPrimaryKey {
 				pk = append(pk, rawValue.Interface())
 			}
@@ -519,25 +677,10 @@ func (session *Session) row2Bean(rows *core.Rows, fields []string, fieldsCount i
 				}
 			case reflect.Struct:
 				if fieldType.ConvertibleTo(core.TimeType) {
-					dbTZ := session.Engine.DatabaseTZ
-					if col.TimeZone != nil {
-						dbTZ = col.TimeZone
-					}
-
 					if rawValueType == core.TimeType {
 						hasAssigned = true
 
-						t := vv.Convert(core.TimeType).Interface().(time.Time)
-
-						z, _ := t.Zone()
-						// set new location if database don't save timezone or give an incorrect timezone
-						if len(z) == 0 || t.Year() == 0 || t.Location().String() != dbTZ.String() { // !nashtsai! HACK tmp work around for lib/pq doesn't properly time with location
-							session.Engine.logger.Debugf("empty zone key[%v] : %v | zone: %v | location: %+v\n", key, t, z, *t.Location())
-							t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(),
-								t.Minute(), t.Second(), t.Nanosecond(), dbTZ)
-						}
-
-						t = t.In(session.Engine.TZLocation)
+						t := session.Engine.timeFromDB(col, vv.Convert(core.TimeType).Interface().(time.Time))
 						fieldValue.Set(reflect.ValueOf(t).Convert(fieldType))
 					} else if rawValueType == core.IntType || rawValueType == core.Int64Type ||
 						rawValueType == core.Int32Type {
@@ -550,7 +693,7 @@ func (session *Session) row2Bean(rows *core.Rows, fields []string, fieldsCount i
 							hasAssigned = true
 							t, err := session.byte2Time(col, d)
 							if err != nil {
-								session.Engine.logger.Error("byte2Time error:", err.Error())
+								session.getLogger().Error("byte2Time error:", err.Error())
 								hasAssigned = false
 							} else {
 								fieldValue.Set(reflect.ValueOf(t).Convert(fieldType))
@@ -559,7 +702,7 @@ func (session *Session) row2Bean(rows *core.Rows, fields []string, fieldsCount i
 							hasAssigned = true
 							t, err := session.str2Time(col, d)
 							if err != nil {
-								session.Engine.logger.Error("byte2Time error:", err.Error())
+								session.getLogger().Error("byte2Time error:", err.Error())
 								hasAssigned = false
 							} else {
 								fieldValue.Set(reflect.ValueOf(t).Convert(fieldType))
@@ -572,7 +715,7 @@ func (session *Session) row2Bean(rows *core.Rows, fields []string, fieldsCount i
 					// !<winxxp>! 增加支持sql.Scanner接口的结构，如sql.NullString
 					hasAssigned = true
 					if err := nulVal.Scan(vv.Interface()); err != nil {
-						session.Engine.logger.Error("sql.Sanner error:", err.Error())
+						session.getLogger().Error("sql.Sanner error:", err.Error())
 						hasAssigned = false
 					}
 				} else if col.SQLType.IsJson() {
@@ -745,6 +888,17 @@ func (session *Session) row2Bean(rows *core.Rows, fields []string, fieldsCount i
 						fieldValue.Set(reflect.ValueOf(&x))
 					}
 					hasAssigned = true
+				default:
+					// a pointer to a scanner, as *sql.NullString or *Null[T]
+					if fieldType.Implements(scannerType) {
+						x := reflect.New(fieldType.Elem())
+						if err := x.Interface().(sql.Scanner).Scan(vv.Interface()); err != nil {
+							session.getLogger().Error("sql.Scanner error:", err.Error())
+						} else {
+							hasAssigned = true
+							fieldValue.Set(x)
+						}
+					}
 				} // switch fieldType
 			} // switch fieldType.Kind()
 
@@ -761,6 +915,16 @@ func (session *Session) row2Bean(rows *core.Rows, fields []string, fieldsCount i
 			}
 		}
 	}
+	if len(session.Statement.nested) > 0 {
+		if err := session.setNested(*dataStruct, fields, scanResults); err != nil {
+			return nil, err
+		}
+	}
+	if len(session.Statement.selectExprs) > 0 {
+		if err := session.setExprFields(*dataStruct, plan, fields, scanResults); err != nil {
+			return nil, err
+		}
+	}
 	return pk, nil
 }
 
@@ -776,7 +940,7 @@ func (session *Session) queryPreprocess(sqlStr *string, paramStr ...interface{})
 func (session *Session) saveLastSQL(sql string, args ...interface{}) {
 	session.lastSQL = sql
 	session.lastSQLArgs = args
-	session.Engine.logSQL(sql, args...)
+	session.logSQL(sql, args...)
 }
 
 // LastSQL returns last query information
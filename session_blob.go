@@ -0,0 +1,404 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+)
+
+// Oid is the SQL type of the columns of PostgreSQL referencing a large
+// object of pg_largeobject, `xorm:"oid"`
+const Oid = "OID"
+
+// the size of the chunks of the blobs when SetBlobChunkSize isn't called
+const defaultBlobChunkSize = 1 << 20
+
+// SetBlobChunkSize sets the size of the chunks read and written by
+// BlobReader and WriteBlob, 1MB by default
+func (engine *Engine) SetBlobChunkSize(size int) {
+	engine.blobChunkSize = size
+}
+
+func (engine *Engine) getBlobChunkSize() int {
+	if engine.blobChunkSize <= 0 {
+		return defaultBlobChunkSize
+	}
+	return engine.blobChunkSize
+}
+
+// blobColumn is a blob column of the record of a bean
+type blobColumn struct {
+	table     *core.Table
+	col       *core.Column
+	tableName string
+	cond      string
+	condArgs  []interface{}
+}
+
+// isLargeObject returns true if the column references a large object
+func (blob *blobColumn) isLargeObject(dbType core.DbType) bool {
+	return dbType == core.POSTGRES && strings.EqualFold(blob.col.SQLType.Name, Oid)
+}
+
+// blobColumn returns the column of the record of bean, found by its primary
+// key
+func (session *Session) blobColumn(bean interface{}, column string) (*blobColumn, error) {
+	if err := session.Statement.setRefValue(rValue(bean)); err != nil {
+		return nil, err
+	}
+	table := session.Statement.RefTable
+	col := table.GetColumn(column)
+	if col == nil {
+		return nil, fmt.Errorf("unknown column %v of the table %v", column, table.Name)
+	}
+
+	pk, err := session.Engine.idOfV(rValue(bean))
+	if err != nil {
+		return nil, err
+	}
+	pkCols := table.PKColumns()
+	if len(pkCols) == 0 || len(pk) != len(pkCols) {
+		return nil, ErrNoPrimaryKey
+	}
+	var cond = builder.NewCond()
+	for i, pkCol := range pkCols {
+		cond = cond.And(builder.Eq{session.Engine.Quote(pkCol.Name): pk[i]})
+	}
+	condSQL, condArgs, err := builder.ToSQL(cond)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blobColumn{
+		table:     table,
+		col:       col,
+		tableName: session.Statement.TableName(),
+		cond:      condSQL,
+		condArgs:  condArgs,
+	}, nil
+}
+
+// BlobReader returns a reader of the binary column of the record of bean,
+// found by its primary key. The column is read by chunks, the large
+// objects of the OID columns of PostgreSQL too, so it doesn't have to fit
+// in memory. The reader uses the session until it's closed.
+func (session *Session) BlobReader(bean interface{}, column string) (io.ReadCloser, error) {
+	if err := session.enterOperation(); err != nil {
+		return nil, err
+	}
+	defer session.leaveOperation()
+
+	defer session.resetStatement()
+
+	blob, err := session.blobColumn(bean, column)
+	if err != nil {
+		if session.IsAutoClose {
+			session.Close()
+		}
+		return nil, err
+	}
+
+	var colName = session.Engine.Quote(blob.col.Name)
+	var chunk string
+	var offset int64 = 1
+	switch dbType := session.Engine.dialect.DBType(); {
+	case blob.isLargeObject(dbType):
+		chunk = "lo_get(" + colName + ", ?, ?)"
+		offset = 0
+	case dbType == core.POSTGRES:
+		chunk = "substring(" + colName + " from ? for ?)"
+	case dbType == core.SQLITE:
+		chunk = "substr(" + colName + ", ?, ?)"
+	case dbType == core.ORACLE:
+		// the length is before the offset
+		chunk = "DBMS_LOB.SUBSTR(" + colName + ", ?, ?)"
+	default:
+		chunk = "SUBSTRING(" + colName + ", ?, ?)"
+	}
+	sqlStr := fmt.Sprintf("SELECT %v FROM %v WHERE %v", chunk,
+		session.Engine.Quote(blob.tableName), blob.cond)
+	session.queryPreprocess(&sqlStr, blob.condArgs...)
+
+	r := &blobReader{
+		session: session,
+		sqlStr:  sqlStr,
+		blob:    blob,
+		offset:  offset,
+		size:    session.Engine.getBlobChunkSize(),
+	}
+	// the record is looked up by the first chunk
+	if err := r.fill(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// BlobReader returns a reader of the binary column of the record of bean,
+// see Session.BlobReader
+func (engine *Engine) BlobReader(bean interface{}, column string) (io.ReadCloser, error) {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.BlobReader(bean, column)
+}
+
+// blobReader reads a blob by chunks
+type blobReader struct {
+	session *Session
+	sqlStr  string
+	blob    *blobColumn
+	offset  int64
+	size    int
+	buf     []byte
+	eof     bool
+	closed  bool
+}
+
+// fill reads the next chunk of the blob
+func (r *blobReader) fill() error {
+	if err := r.session.enterOperation(); err != nil {
+		return err
+	}
+	defer r.session.leaveOperation()
+
+	var args []interface{}
+	if r.session.Engine.dialect.DBType() == core.ORACLE {
+		args = append(args, r.size, r.offset)
+	} else {
+		args = append(args, r.offset, r.size)
+	}
+	rows, err := r.session.queryRows(r.sqlStr, append(args, r.blob.condArgs...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return ErrNotExist
+	}
+	var chunk []byte
+	if err := rows.Scan(&chunk); err != nil {
+		return err
+	}
+	r.buf = chunk
+	r.offset += int64(len(chunk))
+	r.eof = len(chunk) < r.size
+	return nil
+}
+
+func (r *blobReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, io.ErrClosedPipe
+	}
+	for len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close closes the session of the reader if it's auto closed
+func (r *blobReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if r.session.IsAutoClose {
+		r.session.Close()
+	}
+	return nil
+}
+
+// WriteBlob replaces the binary column of the record of bean, found by its
+// primary key, by the content of reader. It's written by chunks in a
+// transaction, the one of the session if it's begun, so it doesn't have to
+// fit in memory. It returns the number of bytes written.
+func (session *Session) WriteBlob(bean interface{}, column string, reader io.Reader) (int64, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
+	defer session.resetStatement()
+	if session.IsAutoClose {
+		defer session.Close()
+	}
+
+	blob, err := session.blobColumn(bean, column)
+	if err != nil {
+		return 0, err
+	}
+	return session.writeBlob(blob, reader)
+}
+
+// WriteBlob replaces the binary column of the record of bean by the content
+// of reader, see Session.WriteBlob
+func (engine *Engine) WriteBlob(bean interface{}, column string, reader io.Reader) (int64, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.WriteBlob(bean, column, reader)
+}
+
+// InsertBlob inserts bean and writes the content of reader to its binary
+// column, in a transaction, see WriteBlob
+func (session *Session) InsertBlob(bean interface{}, column string, reader io.Reader) (int64, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
+	// the session is closed after the blob, not by Insert
+	if session.IsAutoClose {
+		session.IsAutoClose = false
+		defer session.Close()
+	}
+
+	var autoCommit = session.IsAutoCommit
+	if autoCommit {
+		if err := session.Begin(); err != nil {
+			return 0, err
+		}
+		defer session.Rollback()
+	}
+
+	if err := session.Statement.setRefValue(rValue(bean)); err != nil {
+		return 0, err
+	}
+	var tableName = session.Statement.TableName()
+	if _, err := session.Insert(bean); err != nil {
+		return 0, err
+	}
+
+	session.Table(tableName)
+	blob, err := session.blobColumn(bean, column)
+	session.resetStatement()
+	if err != nil {
+		return 0, err
+	}
+	n, err := session.writeBlob(blob, reader)
+	if err != nil {
+		return n, err
+	}
+
+	if autoCommit {
+		if err := session.Commit(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// InsertBlob inserts bean and writes the content of reader to its binary
+// column, see Session.InsertBlob
+func (engine *Engine) InsertBlob(bean interface{}, column string, reader io.Reader) (int64, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.InsertBlob(bean, column, reader)
+}
+
+// writeBlob writes the chunks of reader to a blob column, the first one
+// replaces the blob and the others are appended to it
+func (session *Session) writeBlob(blob *blobColumn, reader io.Reader) (int64, error) {
+	var dbType = session.Engine.dialect.DBType()
+	var colName = session.Engine.Quote(blob.col.Name)
+	var tableName = session.Engine.Quote(blob.tableName)
+
+	var set, appendSQL string
+	switch {
+	case blob.isLargeObject(dbType):
+		set = "lo_from_bytea(0, ?)"
+		appendSQL = fmt.Sprintf("SELECT lo_put(%v, ?, ?) FROM %v WHERE %v", colName, tableName, blob.cond)
+	case dbType == core.MYSQL:
+		set = "?"
+		appendSQL = fmt.Sprintf("UPDATE %v SET %v = CONCAT(%v, ?) WHERE %v", tableName, colName, colName, blob.cond)
+	case dbType == core.POSTGRES:
+		set = "?"
+		appendSQL = fmt.Sprintf("UPDATE %v SET %v = %v || ? WHERE %v", tableName, colName, colName, blob.cond)
+	case dbType == core.SQLITE:
+		set = "?"
+		appendSQL = fmt.Sprintf("UPDATE %v SET %v = CAST(%v || ? AS BLOB) WHERE %v", tableName, colName, colName, blob.cond)
+	case dbType == core.MSSQL:
+		set = "?"
+		appendSQL = fmt.Sprintf("UPDATE %v SET %v = %v + ? WHERE %v", tableName, colName, colName, blob.cond)
+	default:
+		return 0, ErrNotImplemented
+	}
+
+	var autoCommit = session.IsAutoCommit
+	if autoCommit {
+		if err := session.Begin(); err != nil {
+			return 0, err
+		}
+		defer session.Rollback()
+	}
+
+	if blob.isLargeObject(dbType) {
+		// the large object replaced is unlinked
+		sqlStr := fmt.Sprintf("SELECT lo_unlink(%v) FROM %v WHERE %v AND %v IS NOT NULL", colName, tableName, blob.cond, colName)
+		if _, err := session.exec(sqlStr, blob.condArgs...); err != nil {
+			return 0, err
+		}
+	}
+
+	var buf = make([]byte, session.Engine.getBlobChunkSize())
+	var written int64
+	for {
+		n, err := io.ReadFull(reader, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return written, err
+		}
+		if n == 0 && written > 0 {
+			break
+		}
+
+		var sqlStr string
+		var args []interface{}
+		if written == 0 {
+			sqlStr = fmt.Sprintf("UPDATE %v SET %v = %v WHERE %v", tableName, colName, set, blob.cond)
+			args = append(args, buf[:n])
+		} else if blob.isLargeObject(dbType) {
+			sqlStr = appendSQL
+			args = append(args, written, buf[:n])
+		} else {
+			sqlStr = appendSQL
+			args = append(args, buf[:n])
+		}
+		res, execErr := session.exec(sqlStr, append(args, blob.condArgs...)...)
+		if execErr != nil {
+			return written, execErr
+		}
+		if written == 0 {
+			if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+				return 0, ErrNotExist
+			}
+		}
+		written += int64(n)
+		if err != nil {
+			break
+		}
+	}
+
+	if cacher := session.Engine.getCacher2(blob.table); cacher != nil {
+		cacher.ClearBeans(blob.tableName)
+	}
+
+	if autoCommit {
+		if err := session.Commit(); err != nil {
+			return 0, err
+		}
+	}
+	return written, nil
+}
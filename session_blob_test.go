@@ -0,0 +1,106 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type BlobStruct struct {
+	Id   int64
+	Name string
+	Data []byte
+}
+
+func TestBlobStreaming(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(BlobStruct))
+
+	testEngine.SetBlobChunkSize(7)
+	defer testEngine.SetBlobChunkSize(0)
+
+	var data = make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i * 37)
+	}
+	data[0], data[50] = 0, 0xff
+
+	b := BlobStruct{Name: "blob"}
+	n, err := testEngine.InsertBlob(&b, "data", bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.EqualValues(t, len(data), n)
+	assert.True(t, b.Id > 0)
+
+	r, err := testEngine.BlobReader(&BlobStruct{Id: b.Id}, "data")
+	assert.NoError(t, err)
+	got, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	assert.EqualValues(t, data, got)
+
+	var stored BlobStruct
+	has, err := testEngine.ID(b.Id).Get(&stored)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "blob", stored.Name)
+	assert.EqualValues(t, data, stored.Data)
+
+	// a multiple of the chunks
+	n, err = testEngine.WriteBlob(&b, "data", bytes.NewReader(data[:14]))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 14, n)
+	r, err = testEngine.BlobReader(&b, "data")
+	assert.NoError(t, err)
+	got, err = ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	r.Close()
+	assert.EqualValues(t, data[:14], got)
+
+	n, err = testEngine.WriteBlob(&b, "data", bytes.NewReader(nil))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+	r, err = testEngine.BlobReader(&b, "data")
+	assert.NoError(t, err)
+	got, err = ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	r.Close()
+	assert.Empty(t, got)
+
+	_, err = testEngine.BlobReader(&BlobStruct{Id: b.Id + 100}, "data")
+	assert.EqualValues(t, ErrNotExist, err)
+	_, err = testEngine.WriteBlob(&BlobStruct{Id: b.Id + 100}, "data", bytes.NewReader(data))
+	assert.EqualValues(t, ErrNotExist, err)
+	_, err = testEngine.BlobReader(&b, "content")
+	assert.Error(t, err)
+}
+
+func TestBlobInTransaction(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(BlobStruct))
+
+	session := testEngine.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+
+	b := BlobStruct{Name: "rolled back"}
+	_, err := session.InsertBlob(&b, "data", bytes.NewReader([]byte("data")))
+	assert.NoError(t, err)
+
+	r, err := session.BlobReader(&b, "data")
+	assert.NoError(t, err)
+	got, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	assert.EqualValues(t, "data", string(got))
+	assert.NoError(t, session.Rollback())
+
+	cnt, err := testEngine.Count(new(BlobStruct))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+}
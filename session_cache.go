@@ -0,0 +1,67 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// TTLCacher is implemented by the cachers which support a TTL per entry, as
+// the sharded, the Redis and the memcached cachers. The TTL of Session.Cache
+// is ignored by the other cachers, as LRUCacher whose entries expire after
+// its Expired.
+type TTLCacher interface {
+	core.Cacher
+	PutIdsTTL(tableName, sql string, ids interface{}, ttl time.Duration)
+	PutBeanTTL(tableName string, id string, obj interface{}, ttl time.Duration)
+}
+
+// ttlCacher puts the ids and beans with the TTL of a statement
+type ttlCacher struct {
+	TTLCacher
+	ttl time.Duration
+}
+
+func (c ttlCacher) PutIds(tableName, sql string, ids interface{}) {
+	c.PutIdsTTL(tableName, sql, ids, c.ttl)
+}
+
+func (c ttlCacher) PutBean(tableName string, id string, obj interface{}) {
+	c.PutBeanTTL(tableName, id, obj, c.ttl)
+}
+
+// Cache caches the result of the next Get or Find even if the table is
+// tagged NOCACHE, with the default cacher in this case, which the writes of
+// the table clear from then on. The optional ttl overrides the cacher's TTL
+// for the cached ids and beans when the cacher is a TTLCacher, it's logged
+// and ignored otherwise.
+func (session *Session) Cache(ttl ...time.Duration) *Session {
+	session.Statement.forceCache = true
+	if len(ttl) > 0 {
+		session.Statement.cacheTTL = ttl[0]
+	}
+	return session
+}
+
+// readCacher returns the cacher used by Get and Find
+func (session *Session) readCacher(table *core.Table) core.Cacher {
+	cacher := session.Engine.tableCacher(table)
+	if cacher == nil && session.Statement.forceCache && session.Engine.Cacher != nil {
+		// the writes of the table clear the default cacher from now on
+		session.Engine.forcedCaches.Store(table, true)
+		cacher = session.Engine.Cacher
+	}
+	if cacher == nil || session.Statement.cacheTTL <= 0 {
+		return cacher
+	}
+	if c, ok := cacher.(TTLCacher); ok {
+		return ttlCacher{c, session.Statement.cacheTTL}
+	}
+	session.getLogger().Warnf("the cacher %T of %v has no TTL per entry, the TTL %v is ignored",
+		cacher, table.Name, session.Statement.cacheTTL)
+	return cacher
+}
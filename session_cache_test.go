@@ -0,0 +1,96 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type SessionCacheUser struct {
+	Id   int64 `xorm:"pk autoincr nocache"`
+	Name string
+}
+
+func TestSessionCache(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(SessionCacheUser)))
+
+	cacher := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.SetDefaultCacher(cacher)
+	defer testEngine.SetDefaultCacher(nil)
+
+	_, err := testEngine.Insert(&SessionCacheUser{Name: "lunny"})
+	assert.NoError(t, err)
+
+	// the table is not cached by default
+	var user SessionCacheUser
+	has, err := testEngine.Id(1).Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 0, cacher.Len())
+
+	var user1 SessionCacheUser
+	has, err = testEngine.Cache(100 * time.Millisecond).Id(1).Get(&user1)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", user1.Name)
+	assert.True(t, cacher.Len() > 0)
+
+	_, err = testEngine.Id(1).Update(&SessionCacheUser{Name: "xlw"})
+	assert.NoError(t, err)
+
+	// the writes clear the default cacher of the force cached table
+	var user2 SessionCacheUser
+	has, err = testEngine.Cache().Id(1).Get(&user2)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "xlw", user2.Name)
+
+	tableName := testEngine.TableMapper.Obj2Table("SessionCacheUser")
+	_, err = testEngine.Exec("UPDATE "+testEngine.Quote(tableName)+" SET name = ? WHERE id = ?", "lunny", 1)
+	assert.NoError(t, err)
+	var user3 SessionCacheUser
+	has, err = testEngine.Cache().Id(1).Get(&user3)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", user3.Name)
+
+	// NoCache wins
+	var user4 SessionCacheUser
+	has, err = testEngine.Cache().NoCache().Id(1).Get(&user4)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", user4.Name)
+
+	var users []SessionCacheUser
+	assert.NoError(t, testEngine.Cache(time.Hour).Find(&users))
+	assert.EqualValues(t, 1, len(users))
+}
+
+func TestReadCacher(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	sess := testEngine.NewSession()
+	defer sess.Close()
+
+	table := testEngine.TableInfo(new(SessionCacheUser)).Table
+	assert.Nil(t, sess.readCacher(table))
+
+	lru := NewLRUCacher2(NewMemoryStore(), time.Hour, 100)
+	testEngine.SetDefaultCacher(lru)
+	defer testEngine.SetDefaultCacher(nil)
+
+	assert.Nil(t, sess.readCacher(table))
+	sess.Cache(time.Minute)
+	// the ttl is ignored by the cachers which don't support it
+	assert.True(t, sess.readCacher(table) == lru)
+
+	sharded := NewShardedCacher(ShardedCacherOptions{})
+	testEngine.SetDefaultCacher(sharded)
+	assert.EqualValues(t, ttlCacher{sharded, time.Minute}, sess.readCacher(table))
+}
@@ -22,6 +22,12 @@ func (session *Session) SetExpr(column string, expression string) *Session {
 	return session
 }
 
+// SetJSON provides a update string like "column = JSON_SET(column, path, ?)"
+func (session *Session) SetJSON(column, path string, arg interface{}) *Session {
+	session.Statement.SetJSON(column, path, arg)
+	return session
+}
+
 // Select provides some columns to special
 func (session *Session) Select(str string) *Session {
 	session.Statement.Select(str)
@@ -46,6 +52,46 @@ func (session *Session) MustCols(columns ...string) *Session {
 	return session
 }
 
+// Fields only updates the columns of the fields of mask, even if they are
+// empty
+func (session *Session) Fields(mask FieldMask) *Session {
+	session.Statement.Fields(mask)
+	return session
+}
+
+// Scope only selects the primary keys and the columns of the groups
+func (session *Session) Scope(groups ...string) *Session {
+	session.Statement.Scope(groups...)
+	return session
+}
+
+// Eager selects the lazy columns too
+func (session *Session) Eager() *Session {
+	session.Statement.Eager()
+	return session
+}
+
+// SelectExpr selects the expression expr, as "count(c.id) AS comment_count",
+// scanned into the field of its alias which isn't a column
+func (session *Session) SelectExpr(expr string) *Session {
+	session.Statement.SelectExpr(expr)
+	return session
+}
+
+// SelectFields only selects the columns of the fields of paths, the unknown
+// fields are errors
+func (session *Session) SelectFields(paths []string) *Session {
+	session.Statement.SelectFields(paths)
+	return session
+}
+
+// Nested makes Find fetch the records of the has-many field of its beans,
+// whose column foreignKey references their primary key, in the same query
+func (session *Session) Nested(field, foreignKey string) *Session {
+	session.Statement.Nested(field, foreignKey)
+	return session
+}
+
 // UseBool automatically retrieve condition according struct, but
 // if struct has bool field, it will ignore them. So use UseBool
 // to tell system to do not ignore them.
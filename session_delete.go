@@ -64,20 +64,25 @@ func (session *Session) cacheDelete(sqlStr string, args ...interface{}) error {
 	}*/
 
 	for _, id := range ids {
-		session.Engine.logger.Debug("[cacheDelete] delete cache obj", tableName, id)
+		session.getLogger().Debug("[cacheDelete] delete cache obj", tableName, id)
 		sid, err := id.ToString()
 		if err != nil {
 			return err
 		}
 		cacher.DelBean(tableName, sid)
 	}
-	session.Engine.logger.Debug("[cacheDelete] clear cache sql", tableName)
+	session.getLogger().Debug("[cacheDelete] clear cache sql", tableName)
 	cacher.ClearIds(tableName)
 	return nil
 }
 
 // Delete records, bean's non-empty fields are conditions
 func (session *Session) Delete(bean interface{}) (int64, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
@@ -87,6 +92,9 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 		return 0, err
 	}
 	var table = session.Statement.RefTable
+	if err := session.Statement.checkIDParam(); err != nil {
+		return 0, err
+	}
 
 	// handle before delete processors
 	for _, closure := range session.beforeClosures {
@@ -94,13 +102,13 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 	}
 	cleanupProcessorsClosures(&session.beforeClosures)
 
-	if processor, ok := interface{}(bean).(BeforeDeleteProcessor); ok {
-		processor.BeforeDelete()
+	if err := session.beforeDelete(bean); err != nil {
+		return 0, err
 	}
 
 	// --
 	condSQL, condArgs, _ := session.Statement.genConds(bean)
-	if len(condSQL) == 0 && session.Statement.LimitN == 0 {
+	if !session.Statement.callerConds && session.Statement.LimitN == 0 {
 		return 0, ErrNeedDeletedCond
 	}
 
@@ -144,6 +152,15 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 		}
 	}
 
+	endAudit, err := session.beginAudit(table)
+	if err != nil {
+		return 0, err
+	}
+	before, err := session.beforeChange(table, "SELECT * FROM"+deleteSQL[len("DELETE FROM"):], condArgs)
+	if err != nil {
+		return 0, endAudit(err)
+	}
+
 	var realSQL string
 	argsForCache := make([]interface{}, 0, len(condArgs)*2)
 	if session.Statement.unscoped || table.DeletedColumn() == nil { // tag "deleted" is disabled
@@ -179,7 +196,7 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 				}
 			// TODO: how to handle delete limit on mssql?
 			case core.MSSQL:
-				return 0, ErrNotImplemented
+				return 0, endAudit(ErrNotImplemented)
 			default:
 				realSQL += orderSQL
 			}
@@ -190,14 +207,9 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 		paramsLen := len(condArgs)
 		copy(condArgs[1:paramsLen], condArgs[0:paramsLen-1])
 
-		val, t := session.Engine.NowTime2(deletedColumn.SQLType.Name)
+		val, setDeleted := session.Engine.deletedValue(table, deletedColumn)
 		condArgs[0] = val
-
-		var colName = deletedColumn.Name
-		session.afterClosures = append(session.afterClosures, func(bean interface{}) {
-			col := table.GetColumn(colName)
-			setColumnTime(bean, col, t)
-		})
+		session.afterClosures = append(session.afterClosures, setDeleted)
 	}
 
 	if cacher := session.Engine.getCacher2(session.Statement.RefTable); cacher != nil && session.Statement.UseCache {
@@ -206,6 +218,9 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 
 	res, err := session.exec(realSQL, condArgs...)
 	if err != nil {
+		return 0, endAudit(err)
+	}
+	if err := endAudit(session.captureChange(ChangeDelete, table, before)); err != nil {
 		return 0, err
 	}
 
@@ -214,9 +229,7 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 		for _, closure := range session.afterClosures {
 			closure(bean)
 		}
-		if processor, ok := interface{}(bean).(AfterDeleteProcessor); ok {
-			processor.AfterDelete()
-		}
+		session.afterDelete(bean)
 	} else {
 		lenAfterClosures := len(session.afterClosures)
 		if lenAfterClosures > 0 {
@@ -228,7 +241,7 @@ func (session *Session) Delete(bean interface{}) (int64, error) {
 				session.afterDeleteBeans[bean] = &afterClosures
 			}
 		} else {
-			if _, ok := interface{}(bean).(AfterDeleteProcessor); ok {
+			if hasAfterDelete(bean) {
 				session.afterDeleteBeans[bean] = nil
 			}
 		}
@@ -0,0 +1,144 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+
+	"github.com/go-xorm/core"
+)
+
+// ColumnChange is a column changed since the bean was loaded
+type ColumnChange struct {
+	Column string
+	Old    interface{}
+	New    interface{}
+}
+
+// ChangeSet is the columns changed by SaveChanges, in the order of the
+// table columns
+type ChangeSet []ColumnChange
+
+// Columns returns the names of the changed columns
+func (changes ChangeSet) Columns() []string {
+	cols := make([]string, len(changes))
+	for i, change := range changes {
+		cols[i] = change.Column
+	}
+	return cols
+}
+
+// GetForUpdate gets the record as Get, locked by SELECT ... FOR UPDATE in a
+// transaction, and records the loaded values of bean so SaveChanges only
+// updates the columns changed since
+func (session *Session) GetForUpdate(bean interface{}) (bool, error) {
+	if err := session.enterOperation(); err != nil {
+		return false, err
+	}
+	defer session.leaveOperation()
+
+	has, err := session.ForUpdate().NoCache().Get(bean)
+	if err != nil || !has {
+		return has, err
+	}
+
+	table, err := session.Engine.autoMapType(rValue(bean))
+	if err != nil {
+		return has, err
+	}
+	if session.snapshots == nil {
+		session.snapshots = make(map[interface{}]map[string]interface{})
+	}
+	session.snapshots[bean] = snapshotColumns(table, bean)
+	return true, nil
+}
+
+// SaveChanges updates the columns of bean changed since it was loaded by
+// GetForUpdate on the session, whatever their values are zero or not. It
+// returns the changed columns, no SQL is executed when there is none.
+func (session *Session) SaveChanges(bean interface{}) (ChangeSet, error) {
+	if err := session.enterOperation(); err != nil {
+		return nil, err
+	}
+	defer session.leaveOperation()
+
+	snapshot, ok := session.snapshots[bean]
+	if !ok {
+		session.resetStatement()
+		return nil, ErrNoSnapshot
+	}
+	table, err := session.Engine.autoMapType(rValue(bean))
+	if err != nil {
+		session.resetStatement()
+		return nil, err
+	}
+
+	current := snapshotColumns(table, bean)
+	var changes ChangeSet
+	for _, col := range table.Columns() {
+		if !trackedColumn(col) {
+			continue
+		}
+		if !reflect.DeepEqual(snapshot[col.Name], current[col.Name]) {
+			changes = append(changes, ColumnChange{col.Name, snapshot[col.Name], current[col.Name]})
+		}
+	}
+	if len(changes) == 0 {
+		session.resetStatement()
+		return nil, nil
+	}
+
+	pk, err := session.Engine.idOfV(reflect.ValueOf(bean))
+	if err != nil {
+		session.resetStatement()
+		return nil, err
+	}
+	if _, err := session.Id(pk).Cols(changes.Columns()...).Update(bean); err != nil {
+		return nil, err
+	}
+	session.snapshots[bean] = snapshotColumns(table, bean)
+	return changes, nil
+}
+
+// Forget stops tracking the changes of bean loaded by GetForUpdate
+func (session *Session) Forget(bean interface{}) *Session {
+	delete(session.snapshots, bean)
+	return session
+}
+
+// trackedColumn returns false for the columns which are not updated from the
+// fields of the beans
+func trackedColumn(col *core.Column) bool {
+	return !col.IsPrimaryKey && !col.IsCreated && !col.IsUpdated && !col.IsVersion &&
+		!col.IsDeleted && col.MapType != core.ONLYFROMDB
+}
+
+// snapshotColumns returns the values of the columns of bean, the slices and
+// the maps are copied so they can be compared to the modified fields
+func snapshotColumns(table *core.Table, bean interface{}) map[string]interface{} {
+	values := beanColumns(table, bean)
+	for name, value := range values {
+		v := reflect.ValueOf(value)
+		switch v.Kind() {
+		case reflect.Slice:
+			if v.IsNil() {
+				continue
+			}
+			c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+			reflect.Copy(c, v)
+			values[name] = c.Interface()
+		case reflect.Map:
+			if v.IsNil() {
+				continue
+			}
+			c := reflect.MakeMap(v.Type())
+			for _, k := range v.MapKeys() {
+				c.SetMapIndex(k, v.MapIndex(k))
+			}
+			values[name] = c.Interface()
+		}
+	}
+	return values
+}
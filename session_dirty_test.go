@@ -0,0 +1,76 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type DirtyUser struct {
+	Id     int64
+	Name   string
+	Age    int
+	Active bool
+	Tags   []string
+}
+
+func TestSaveChanges(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(DirtyUser)))
+
+	_, err := testEngine.Insert(&DirtyUser{Name: "lunny", Age: 20, Active: true, Tags: []string{"a"}})
+	assert.NoError(t, err)
+
+	session := testEngine.NewSession()
+	defer session.Close()
+
+	_, err = session.SaveChanges(&DirtyUser{Id: 1})
+	assert.EqualValues(t, ErrNoSnapshot, err)
+
+	assert.NoError(t, session.Begin())
+	var user DirtyUser
+	has, err := session.Id(1).GetForUpdate(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	changes, err := session.SaveChanges(&user)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, len(changes))
+
+	// the zero values are updated
+	user.Age = 0
+	user.Active = false
+	user.Tags[0] = "b"
+	changes, err = session.SaveChanges(&user)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{
+		testEngine.ColumnMapper.Obj2Table("Age"),
+		testEngine.ColumnMapper.Obj2Table("Active"),
+		testEngine.ColumnMapper.Obj2Table("Tags"),
+	}, changes.Columns())
+	assert.EqualValues(t, 20, changes[0].Old)
+	assert.EqualValues(t, 0, changes[0].New)
+	assert.NoError(t, session.Commit())
+
+	var user2 DirtyUser
+	has, err = testEngine.Id(1).Get(&user2)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", user2.Name)
+	assert.EqualValues(t, 0, user2.Age)
+	assert.False(t, user2.Active)
+	assert.EqualValues(t, []string{"b"}, user2.Tags)
+
+	// the saved values are the new snapshot
+	changes, err = session.SaveChanges(&user)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, len(changes))
+
+	session.Forget(&user)
+	_, err = session.SaveChanges(&user)
+	assert.EqualValues(t, ErrNoSnapshot, err)
+}
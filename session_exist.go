@@ -0,0 +1,79 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/go-xorm/builder"
+)
+
+// Exist returns true if a record matches the conditions, the non-empty
+// fields of bean and the ones of the session, without reading it
+func (session *Session) Exist(bean ...interface{}) (bool, error) {
+	if err := session.enterOperation(); err != nil {
+		return false, err
+	}
+	defer session.leaveOperation()
+
+	defer session.resetStatement()
+	if session.IsAutoClose {
+		defer session.Close()
+	}
+
+	var sqlStr string
+	var args []interface{}
+
+	if session.Statement.RawSQL == "" {
+		if len(bean) > 0 {
+			beanValue := reflect.ValueOf(bean[0])
+			if beanValue.Kind() != reflect.Ptr {
+				return false, errors.New("needs a pointer")
+			}
+			if beanValue.Elem().Kind() == reflect.Struct {
+				if err := session.Statement.setRefValue(beanValue.Elem()); err != nil {
+					return false, err
+				}
+			}
+		}
+		if len(session.Statement.TableName()) <= 0 {
+			return false, ErrTableNotFound
+		}
+		if err := session.Statement.checkIDParam(); err != nil {
+			return false, err
+		}
+		session.Statement.Limit(1)
+		if len(bean) > 0 {
+			sqlStr, args = session.Statement.genGetSQL(bean[0])
+		} else {
+			if session.Statement.idParam != nil {
+				session.Statement.processIDParam()
+			}
+			condSQL, condArgs, err := builder.ToSQL(session.Statement.cond.And(session.Statement.mandatoryCond()))
+			if err != nil {
+				return false, err
+			}
+			sqlStr = session.Statement.genSelectSQL("*", condSQL)
+			args = append(session.Statement.joinArgs, condArgs...)
+		}
+	} else {
+		sqlStr = session.Statement.RawSQL
+		args = session.Statement.RawParams
+	}
+
+	session.queryPreprocess(&sqlStr, args...)
+
+	rows, err := session.queryRows(sqlStr, args...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		return true, nil
+	}
+	return false, rows.Err()
+}
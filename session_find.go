@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-xorm/builder"
 	"github.com/go-xorm/core"
@@ -24,6 +25,11 @@ const (
 // are conditions. beans could be []Struct, []*Struct, map[int64]Struct
 // map[int64]*Struct
 func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{}) error {
+	if err := session.enterOperation(); err != nil {
+		return err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
@@ -80,11 +86,7 @@ func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{})
 					}
 					colName = session.Engine.Quote(nm) + "." + colName
 				}
-				if session.Engine.dialect.DBType() == core.MSSQL {
-					autoCond = builder.IsNull{colName}
-				} else {
-					autoCond = builder.IsNull{colName}.Or(builder.Eq{colName: "0001-01-01 00:00:00"})
-				}
+				autoCond = session.Engine.notDeletedCond(table, col, colName)
 			}
 		}
 	}
@@ -95,6 +97,11 @@ func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{})
 		if len(session.Statement.TableName()) <= 0 {
 			return ErrTableNotFound
 		}
+		if tp == tpStruct {
+			if err := session.Statement.applySelectFields(); err != nil {
+				return err
+			}
+		}
 
 		var columnStr = session.Statement.ColumnStr
 		if len(session.Statement.selectStr) > 0 {
@@ -112,22 +119,37 @@ func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{})
 				if columnStr == "" {
 					if session.Statement.GroupByStr != "" {
 						columnStr = session.Statement.Engine.Quote(strings.Replace(session.Statement.GroupByStr, ",", session.Engine.Quote(","), -1))
-					} else {
-						columnStr = "*"
+					} else if tp == tpStruct {
+						columnStr = session.Statement.genJoinColumnStr()
 					}
 				}
 			}
 			if columnStr == "" {
 				columnStr = "*"
 			}
+			columnStr = session.Statement.exprColumnStr(columnStr)
+		}
+
+		var nestedArgs []interface{}
+		if len(session.Statement.nested) > 0 {
+			if tp != tpStruct {
+				return errors.New("nested fields need a slice of structs")
+			}
+			var err error
+			if columnStr, nestedArgs, err = session.Statement.nestedColumnStr(columnStr); err != nil {
+				return err
+			}
 		}
 
-		condSQL, condArgs, err := builder.ToSQL(session.Statement.cond.And(autoCond))
+		condSQL, condArgs, err := builder.ToSQL(session.Statement.cond.And(autoCond, session.Statement.mandatoryCond()))
 		if err != nil {
 			return err
 		}
 
 		args = append(session.Statement.joinArgs, condArgs...)
+		if len(nestedArgs) > 0 {
+			args = append(nestedArgs, args...)
+		}
 		sqlStr = session.Statement.genSelectSQL(columnStr, condSQL)
 		// for mssql and use limit
 		qs := strings.Count(sqlStr, "?")
@@ -139,9 +161,24 @@ func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{})
 		args = session.Statement.RawParams
 	}
 
+	// the children aren't kept by the caches
+	if len(session.Statement.nested) > 0 {
+		return session.noCacheFind(table, sliceValue, sqlStr, args...)
+	}
+
+	if qc := session.queryCache(sliceValue); qc != nil {
+		return qc.find(session, sliceValue, sqlStr, args, func() error {
+			return session.find(table, sliceElementType, sliceValue, rowsSlicePtr, sqlStr, args)
+		})
+	}
+	return session.find(table, sliceElementType, sliceValue, rowsSlicePtr, sqlStr, args)
+}
+
+// find runs the query of Find, with the bean cache when possible
+func (session *Session) find(table *core.Table, sliceElementType reflect.Type, sliceValue reflect.Value, rowsSlicePtr interface{}, sqlStr string, args []interface{}) error {
 	var err error
 	if session.canCache() {
-		if cacher := session.Engine.getCacher2(table); cacher != nil &&
+		if cacher := session.readCacher(table); cacher != nil &&
 			!session.Statement.IsDistinct &&
 			!session.Statement.unscoped {
 			err = session.cacheFind(sliceElementType, sqlStr, rowsSlicePtr, args...)
@@ -149,7 +186,7 @@ func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{})
 				return err
 			}
 			err = nil // !nashtsai! reset err to nil for ErrCacheFailed
-			session.Engine.logger.Warn("Cache Find Failed")
+			session.getLogger().Warn("Cache Find Failed")
 		}
 	}
 
@@ -157,15 +194,8 @@ func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{})
 }
 
 func (session *Session) noCacheFind(table *core.Table, containerValue reflect.Value, sqlStr string, args ...interface{}) error {
-	var rawRows *core.Rows
-	var err error
-
 	session.queryPreprocess(&sqlStr, args...)
-	if session.IsAutoCommit {
-		_, rawRows, err = session.innerQuery(sqlStr, args...)
-	} else {
-		rawRows, err = session.Tx.Query(sqlStr, args...)
-	}
+	rawRows, err := session.queryRows(sqlStr, args...)
 	if err != nil {
 		return err
 	}
@@ -176,6 +206,12 @@ func (session *Session) noCacheFind(table *core.Table, containerValue reflect.Va
 		return err
 	}
 
+	if containerValue.Kind() == reflect.Slice && len(fields) == 1 && containerValue.CanAddr() {
+		if ok, err := session.findPrimitives(rawRows, fields[0], containerValue.Addr().Interface()); ok {
+			return err
+		}
+	}
+
 	var newElemFunc func(fields []string) reflect.Value
 	elemType := containerValue.Type().Elem()
 	var isPointer bool
@@ -301,54 +337,66 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 	tableName := session.Statement.TableName()
 
 	table := session.Statement.RefTable
-	cacher := session.Engine.getCacher2(table)
-	ids, err := core.GetCacheSql(cacher, tableName, newsql, args)
-	if err != nil {
-		rows, err := session.DB().Query(newsql, args...)
-		if err != nil {
-			return err
-		}
-		defer rows.Close()
-
-		var i int
-		ids = make([]core.PK, 0)
-		for rows.Next() {
-			i++
-			if i > 500 {
-				session.Engine.logger.Debug("[cacheFind] ids length > 500, no cache")
-				return ErrCacheFailed
-			}
-			var res = make([]string, len(table.PrimaryKeys))
-			err = rows.ScanSlice(&res)
+	cacher := session.readCacher(table)
+	// the ids of an In of the primary key are known without querying them
+	ids, derived := session.Statement.pkInIds(table)
+	if !derived {
+		ids, err = core.GetCacheSql(cacher, tableName, newsql, args)
+		session.Engine.cacheMonitor.lookup(tableName, err == nil)
+	}
+	if derived {
+		session.getLogger().Debug("[cacheFind] ids of In:", tableName, ids)
+	} else if err != nil {
+		key := fmt.Sprintf("ids:%s:%s-%v", tableName, newsql, args)
+		v, err, _ := session.loadOnce(key, func() (interface{}, error) {
+			defer session.Engine.cacheMonitor.loaded(tableName, time.Now())
+			rows, err := session.dbQuery(newsql, args...)
 			if err != nil {
-				return err
+				return nil, err
 			}
+			defer rows.Close()
+
+			var i int
+			ids := make([]core.PK, 0)
+			for rows.Next() {
+				i++
+				if i > 500 {
+					session.getLogger().Debug("[cacheFind] ids length > 500, no cache")
+					return nil, ErrCacheFailed
+				}
+				var res = make([]string, len(table.PrimaryKeys))
+				err = rows.ScanSlice(&res)
+				if err != nil {
+					return nil, err
+				}
 
-			var pk core.PK = make([]interface{}, len(table.PrimaryKeys))
-			for i, col := range table.PKColumns() {
-				if col.SQLType.IsNumeric() {
-					n, err := strconv.ParseInt(res[i], 10, 64)
-					if err != nil {
-						return err
+				var pk core.PK = make([]interface{}, len(table.PrimaryKeys))
+				for i, col := range table.PKColumns() {
+					if col.SQLType.IsNumeric() {
+						n, err := strconv.ParseInt(res[i], 10, 64)
+						if err != nil {
+							return nil, err
+						}
+						pk[i] = n
+					} else if col.SQLType.IsText() {
+						pk[i] = res[i]
+					} else {
+						return nil, errors.New("not supported")
 					}
-					pk[i] = n
-				} else if col.SQLType.IsText() {
-					pk[i] = res[i]
-				} else {
-					return errors.New("not supported")
 				}
-			}
 
-			ids = append(ids, pk)
-		}
+				ids = append(ids, pk)
+			}
 
-		session.Engine.logger.Debug("[cacheFind] cache sql:", ids, tableName, newsql, args)
-		err = core.PutCacheSql(cacher, ids, tableName, newsql, args)
+			session.getLogger().Debug("[cacheFind] cache sql:", ids, tableName, newsql, args)
+			return ids, core.PutCacheSql(cacher, ids, tableName, newsql, args)
+		})
 		if err != nil {
 			return err
 		}
+		ids = v.([]core.PK)
 	} else {
-		session.Engine.logger.Debug("[cacheFind] cache hit sql:", newsql, args)
+		session.getLogger().Debug("[cacheFind] cache hit sql:", newsql, args)
 	}
 
 	sliceValue := reflect.Indirect(reflect.ValueOf(rowsSlicePtr))
@@ -363,11 +411,12 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 			return err
 		}
 		bean := cacher.GetBean(tableName, sid)
+		session.Engine.cacheMonitor.lookup(tableName, bean != nil)
 		if bean == nil {
 			ides = append(ides, id)
 			ididxes[sid] = idx
 		} else {
-			session.Engine.logger.Debug("[cacheFind] cache hit bean:", tableName, id, bean)
+			session.getLogger().Debug("[cacheFind] cache hit bean:", tableName, id, bean)
 
 			pk := session.Engine.IdOf(bean)
 			xid, err := pk.ToString()
@@ -376,7 +425,7 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 			}
 
 			if sid != xid {
-				session.Engine.logger.Error("[cacheFind] error cache", xid, sid, bean)
+				session.getLogger().Error("[cacheFind] error cache", xid, sid, bean)
 				return ErrCacheFailed
 			}
 			temps[idx] = bean
@@ -384,7 +433,7 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 	}
 
 	if len(ides) > 0 {
-		newSession := session.Engine.NewSession()
+		newSession := session.cacheLoadSession()
 		defer newSession.Close()
 
 		slices := reflect.New(reflect.SliceOf(t))
@@ -407,7 +456,9 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 			}
 		}
 
+		start := time.Now()
 		err = newSession.NoCache().Find(beans)
+		session.Engine.cacheMonitor.loaded(tableName, start)
 		if err != nil {
 			return err
 		}
@@ -429,7 +480,7 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 
 			bean := rv.Interface()
 			temps[ididxes[sid]] = bean
-			session.Engine.logger.Debug("[cacheFind] cache bean:", tableName, id, bean, temps)
+			session.getLogger().Debug("[cacheFind] cache bean:", tableName, id, bean, temps)
 			cacher.PutBean(tableName, sid, bean)
 		}
 	}
@@ -437,7 +488,10 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 	for j := 0; j < len(temps); j++ {
 		bean := temps[j]
 		if bean == nil {
-			session.Engine.logger.Warn("[cacheFind] cache no hit:", tableName, ids[j], temps)
+			// the ids of an In may not exist
+			if !derived {
+				session.getLogger().Warn("[cacheFind] cache no hit:", tableName, ids[j], temps)
+			}
 			// return errors.New("cache error") // !nashtsai! no need to return error, but continue instead
 			continue
 		}
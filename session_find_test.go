@@ -464,3 +464,73 @@ func TestFindMapPtrString(t *testing.T) {
 		fmt.Println(record)
 	}
 }
+
+type FindJoinUser struct {
+	Id   int64
+	Name string
+}
+
+type FindJoinOrder struct {
+	Id     int64
+	UserId int64
+	Name   string
+}
+
+func TestFindJoinExtends(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(FindJoinUser), new(FindJoinOrder))
+
+	var users = []FindJoinUser{{Name: "alice"}, {Name: "bob"}}
+	_, err := testEngine.Insert(&users)
+	assert.NoError(t, err)
+	var bob FindJoinUser
+	has, err := testEngine.Where("name = ?", "bob").Get(&bob)
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	// the order ids aren't the user ids
+	_, err = testEngine.Insert(&FindJoinOrder{UserId: bob.Id, Name: "first"}, &FindJoinOrder{UserId: bob.Id, Name: "second"})
+	assert.NoError(t, err)
+
+	var mapper = testEngine.TableMapper.Obj2Table
+	var quote = testEngine.Quote
+	orderTable := mapper("FindJoinOrder")
+	userTable := mapper("FindJoinUser")
+
+	// the fields aren't in the order of the tables
+	type OrderUser struct {
+		FindJoinUser  `xorm:"extends"`
+		FindJoinOrder `xorm:"extends"`
+	}
+	var rows []OrderUser
+	err = testEngine.Table(orderTable).
+		Join("INNER", userTable, quote(userTable)+"."+quote("id")+" = "+quote(orderTable)+"."+quote(mapper("UserId"))).
+		Asc(orderTable + ".id").
+		Find(&rows)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, len(rows))
+	assert.EqualValues(t, "first", rows[0].FindJoinOrder.Name)
+	assert.EqualValues(t, "second", rows[1].FindJoinOrder.Name)
+	assert.True(t, rows[0].FindJoinOrder.Id != rows[1].FindJoinOrder.Id)
+	for _, row := range rows {
+		assert.EqualValues(t, bob.Id, row.FindJoinUser.Id)
+		assert.EqualValues(t, "bob", row.FindJoinUser.Name)
+		assert.EqualValues(t, bob.Id, row.UserId)
+	}
+
+	// the fields are the tables aliased as their names
+	type OrderBuyer struct {
+		Buyer FindJoinUser  `xorm:"extends"`
+		Order FindJoinOrder `xorm:"extends"`
+	}
+	var row OrderBuyer
+	has, err = testEngine.Table(orderTable).Alias("o").
+		Join("INNER", []string{userTable, "buyer"}, quote("buyer")+"."+quote("id")+" = "+quote("o")+"."+quote(mapper("UserId"))).
+		Where(quote("o")+"."+quote("name")+" = ?", "second").
+		Get(&row)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "second", row.Order.Name)
+	assert.EqualValues(t, "bob", row.Buyer.Name)
+	assert.EqualValues(t, bob.Id, row.Buyer.Id)
+}
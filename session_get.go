@@ -6,8 +6,10 @@ package xorm
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/go-xorm/core"
 )
@@ -15,6 +17,11 @@ import (
 // Get retrieve one record from database, bean's non-empty fields
 // will be as conditions
 func (session *Session) Get(bean interface{}) (bool, error) {
+	if err := session.enterOperation(); err != nil {
+		return false, err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
@@ -25,7 +32,13 @@ func (session *Session) Get(bean interface{}) (bool, error) {
 		return false, errors.New("needs a pointer")
 	}
 
-	if beanValue.Elem().Kind() == reflect.Struct {
+	// a time is a single value, not a table
+	beanKind := beanValue.Elem().Kind()
+	if _, ok := bean.(*time.Time); ok {
+		beanKind = reflect.Invalid
+	}
+
+	if beanKind == reflect.Struct {
 		if err := session.Statement.setRefValue(beanValue.Elem()); err != nil {
 			return false, err
 		}
@@ -38,6 +51,12 @@ func (session *Session) Get(bean interface{}) (bool, error) {
 		if len(session.Statement.TableName()) <= 0 {
 			return false, ErrTableNotFound
 		}
+		if err := session.Statement.checkIDParam(); err != nil {
+			return false, err
+		}
+		if err := session.Statement.applySelectFields(); err != nil {
+			return false, err
+		}
 		session.Statement.Limit(1)
 		sqlStr, args = session.Statement.genGetSQL(bean)
 	} else {
@@ -45,8 +64,8 @@ func (session *Session) Get(bean interface{}) (bool, error) {
 		args = session.Statement.RawParams
 	}
 
-	if session.canCache() && beanValue.Elem().Kind() == reflect.Struct {
-		if cacher := session.Engine.getCacher2(session.Statement.RefTable); cacher != nil &&
+	if session.canCache() && beanKind == reflect.Struct {
+		if cacher := session.readCacher(session.Statement.RefTable); cacher != nil &&
 			!session.Statement.unscoped {
 			has, err := session.cacheGet(bean, sqlStr, args...)
 			if err != ErrCacheFailed {
@@ -55,19 +74,13 @@ func (session *Session) Get(bean interface{}) (bool, error) {
 		}
 	}
 
-	return session.nocacheGet(beanValue.Elem().Kind(), bean, sqlStr, args...)
+	return session.nocacheGet(beanKind, bean, sqlStr, args...)
 }
 
 func (session *Session) nocacheGet(beanKind reflect.Kind, bean interface{}, sqlStr string, args ...interface{}) (bool, error) {
 	session.queryPreprocess(&sqlStr, args...)
 
-	var rawRows *core.Rows
-	var err error
-	if session.IsAutoCommit {
-		_, rawRows, err = session.innerQuery(sqlStr, args...)
-	} else {
-		rawRows, err = session.Tx.Query(sqlStr, args...)
-	}
+	rawRows, err := session.queryRows(sqlStr, args...)
 	if err != nil {
 		return false, err
 	}
@@ -77,7 +90,8 @@ func (session *Session) nocacheGet(beanKind reflect.Kind, bean interface{}, sqlS
 	if rawRows.Next() {
 		switch beanKind {
 		case reflect.Struct:
-			fields, err := rawRows.Columns()
+			var fields []string
+			fields, err = rawRows.Columns()
 			if err != nil {
 				// WARN: Alougth rawRows return true, but get fields failed
 				return true, err
@@ -86,13 +100,18 @@ func (session *Session) nocacheGet(beanKind reflect.Kind, bean interface{}, sqlS
 			if err := session.Statement.setRefValue(dataStruct); err != nil {
 				return false, err
 			}
-			_, err = session.row2Bean(rawRows, fields, len(fields), bean, &dataStruct, session.Statement.RefTable)
+			table := session.Statement.RefTable
+			_, err = session.row2Bean(rawRows, fields, len(fields), bean, &dataStruct, table, session.scanPlan(table, dataStruct.Type(), fields))
 		case reflect.Slice:
 			err = rawRows.ScanSlice(bean)
 		case reflect.Map:
 			err = rawRows.ScanMap(bean)
 		default:
-			err = rawRows.Scan(bean)
+			if t, ok := bean.(*time.Time); ok {
+				err = session.scanTime(rawRows, t)
+			} else {
+				err = rawRows.Scan(bean)
+			}
 		}
 
 		return true, err
@@ -114,73 +133,91 @@ func (session *Session) cacheGet(bean interface{}, sqlStr string, args ...interf
 		return false, ErrCacheFailed
 	}
 
-	cacher := session.Engine.getCacher2(session.Statement.RefTable)
+	cacher := session.readCacher(session.Statement.RefTable)
 	tableName := session.Statement.TableName()
-	session.Engine.logger.Debug("[cacheGet] find sql:", newsql, args)
+	session.getLogger().Debug("[cacheGet] find sql:", newsql, args)
 	ids, err := core.GetCacheSql(cacher, tableName, newsql, args)
+	session.Engine.cacheMonitor.lookup(tableName, err == nil)
 	table := session.Statement.RefTable
 	if err != nil {
-		var res = make([]string, len(table.PrimaryKeys))
-		rows, err := session.DB().Query(newsql, args...)
-		if err != nil {
-			return false, err
-		}
-		defer rows.Close()
-
-		if rows.Next() {
-			err = rows.ScanSlice(&res)
+		key := fmt.Sprintf("ids:%s:%s-%v", tableName, newsql, args)
+		v, err, _ := session.loadOnce(key, func() (interface{}, error) {
+			defer session.Engine.cacheMonitor.loaded(tableName, time.Now())
+			var res = make([]string, len(table.PrimaryKeys))
+			rows, err := session.dbQuery(newsql, args...)
 			if err != nil {
-				return false, err
+				return nil, err
 			}
-		} else {
-			return false, ErrCacheFailed
-		}
 
-		var pk core.PK = make([]interface{}, len(table.PrimaryKeys))
-		for i, col := range table.PKColumns() {
-			if col.SQLType.IsText() {
-				pk[i] = res[i]
-			} else if col.SQLType.IsNumeric() {
-				n, err := strconv.ParseInt(res[i], 10, 64)
+			if rows.Next() {
+				err = rows.ScanSlice(&res)
+				rows.Close()
 				if err != nil {
-					return false, err
+					return nil, err
 				}
-				pk[i] = n
 			} else {
-				return false, errors.New("unsupported")
+				rows.Close()
+				return nil, ErrCacheFailed
 			}
-		}
 
-		ids = []core.PK{pk}
-		session.Engine.logger.Debug("[cacheGet] cache ids:", newsql, ids)
-		err = core.PutCacheSql(cacher, ids, tableName, newsql, args)
+			var pk core.PK = make([]interface{}, len(table.PrimaryKeys))
+			for i, col := range table.PKColumns() {
+				if col.SQLType.IsText() {
+					pk[i] = res[i]
+				} else if col.SQLType.IsNumeric() {
+					n, err := strconv.ParseInt(res[i], 10, 64)
+					if err != nil {
+						return nil, err
+					}
+					pk[i] = n
+				} else {
+					return nil, errors.New("unsupported")
+				}
+			}
+
+			ids := []core.PK{pk}
+			session.getLogger().Debug("[cacheGet] cache ids:", newsql, ids)
+			return ids, core.PutCacheSql(cacher, ids, tableName, newsql, args)
+		})
 		if err != nil {
 			return false, err
 		}
+		ids = v.([]core.PK)
 	} else {
-		session.Engine.logger.Debug("[cacheGet] cache hit sql:", newsql)
+		session.getLogger().Debug("[cacheGet] cache hit sql:", newsql)
 	}
 
 	if len(ids) > 0 {
 		structValue := reflect.Indirect(reflect.ValueOf(bean))
 		id := ids[0]
-		session.Engine.logger.Debug("[cacheGet] get bean:", tableName, id)
+		session.getLogger().Debug("[cacheGet] get bean:", tableName, id)
 		sid, err := id.ToString()
 		if err != nil {
 			return false, err
 		}
 		cacheBean := cacher.GetBean(tableName, sid)
+		session.Engine.cacheMonitor.lookup(tableName, cacheBean != nil)
 		if cacheBean == nil {
-			cacheBean = bean
-			has, err = session.nocacheGet(reflect.Struct, cacheBean, sqlStr, args...)
-			if err != nil || !has {
-				return has, err
-			}
+			v, err, _ := session.loadOnce("bean:"+tableName+":"+sid, func() (interface{}, error) {
+				defer session.Engine.cacheMonitor.loaded(tableName, time.Now())
+				// the bean is shared by the sessions waiting for the load
+				cacheBean := reflect.New(structValue.Type()).Interface()
+				has, err := session.nocacheGet(reflect.Struct, cacheBean, sqlStr, args...)
+				if err != nil || !has {
+					return nil, err
+				}
 
-			session.Engine.logger.Debug("[cacheGet] cache bean:", tableName, id, cacheBean)
-			cacher.PutBean(tableName, sid, cacheBean)
+				session.getLogger().Debug("[cacheGet] cache bean:", tableName, id, cacheBean)
+				cacher.PutBean(tableName, sid, cacheBean)
+				return cacheBean, nil
+			})
+			if err != nil || v == nil {
+				return false, err
+			}
+			cacheBean = v
+			has = true
 		} else {
-			session.Engine.logger.Debug("[cacheGet] cache hit bean:", tableName, id, cacheBean)
+			session.getLogger().Debug("[cacheGet] cache hit bean:", tableName, id, cacheBean)
 			has = true
 		}
 		structValue.Set(reflect.Indirect(reflect.ValueOf(cacheBean)))
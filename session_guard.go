@@ -0,0 +1,85 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// sessionGuard tracks the goroutine which is running an operation on a session
+// so that concurrent use of one session could be reported as an error instead
+// of corrupting the statement.
+type sessionGuard struct {
+	mutex     sync.Mutex
+	goroutine uint64
+	depth     int
+}
+
+func (guard *sessionGuard) enter() error {
+	gid := curGoroutineID()
+
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	if guard.depth > 0 && guard.goroutine != gid {
+		return ErrConcurrentSessionUse
+	}
+	guard.goroutine = gid
+	guard.depth++
+	return nil
+}
+
+func (guard *sessionGuard) leave() {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	if guard.depth > 0 {
+		guard.depth--
+	}
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+// curGoroutineID parses the current goroutine's id from the stack header, it's
+// slow and only used when session concurrency detection is enabled.
+func curGoroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// enterOperation marks the session as used by current goroutine. It returns
+// ErrConcurrentSessionUse if another goroutine is running an operation on the
+// same session. Every successful call should be paired with leaveOperation.
+func (session *Session) enterOperation() error {
+	if session.guard != nil {
+		if err := session.guard.enter(); err != nil {
+			return err
+		}
+	}
+	session.running++
+	return nil
+}
+
+// leaveOperation ends the operation, the session closed automatically by the
+// last operation is put back to the pool once its deferred resets are done
+func (session *Session) leaveOperation() {
+	if session.running > 0 {
+		session.running--
+	}
+	if session.guard != nil {
+		session.guard.leave()
+	}
+	if session.running == 0 && session.closeDefer {
+		session.closeDefer = false
+		session.Engine.putPooledSession(session)
+	}
+}
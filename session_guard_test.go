@@ -0,0 +1,57 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionRaceDetect(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	type SessionRace struct {
+		Id   int64
+		Name string
+	}
+
+	assert.NoError(t, testEngine.Sync2(new(SessionRace)))
+	_, err := testEngine.Insert(&SessionRace{Name: "race"})
+	assert.NoError(t, err)
+
+	testEngine.SetSessionRaceDetect(true)
+	defer testEngine.SetSessionRaceDetect(false)
+
+	session := testEngine.NewSession()
+	defer session.Close()
+
+	err = session.Iterate(new(SessionRace), func(i int, bean interface{}) error {
+		errs := make(chan error)
+		go func() {
+			_, err := session.Count(new(SessionRace))
+			errs <- err
+		}()
+		return <-errs
+	})
+	assert.EqualValues(t, ErrConcurrentSessionUse, err)
+
+	// the session could be used again once the operation finished
+	cnt, err := session.Count(new(SessionRace))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+}
+
+func TestCurGoroutineID(t *testing.T) {
+	id := curGoroutineID()
+	assert.True(t, id > 0)
+	assert.EqualValues(t, id, curGoroutineID())
+
+	ids := make(chan uint64)
+	go func() {
+		ids <- curGoroutineID()
+	}()
+	assert.NotEqual(t, id, <-ids)
+}
@@ -16,6 +16,11 @@ import (
 
 // Insert insert one or more beans
 func (session *Session) Insert(beans ...interface{}) (int64, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
 	var affected int64
 	var err error
 
@@ -26,7 +31,21 @@ func (session *Session) Insert(beans ...interface{}) (int64, error) {
 
 	for _, bean := range beans {
 		sliceValue := reflect.Indirect(reflect.ValueOf(bean))
-		if sliceValue.Kind() == reflect.Slice {
+		if sliceValue.Kind() == reflect.Map {
+			cnt, err := session.insertMap(sliceValue)
+			if err != nil {
+				return affected, err
+			}
+			affected += cnt
+		} else if sliceValue.Kind() == reflect.Slice && sliceValue.Type().Elem().Kind() == reflect.Map {
+			for i := 0; i < sliceValue.Len(); i++ {
+				cnt, err := session.insertMap(sliceValue.Index(i))
+				if err != nil {
+					return affected, err
+				}
+				affected += cnt
+			}
+		} else if sliceValue.Kind() == reflect.Slice {
 			size := sliceValue.Len()
 			if size > 0 {
 				if session.Engine.SupportInsertMany() {
@@ -79,7 +98,6 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 	size := sliceValue.Len()
 
 	var colNames []string
-	var colMultiPlaces []string
 	var args []interface{}
 	var cols []*core.Column
 
@@ -87,7 +105,6 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 		v := sliceValue.Index(i)
 		vv := reflect.Indirect(v)
 		elemValue := v.Interface()
-		var colPlaces []string
 
 		// handle BeforeInsertProcessor
 		// !nashtsai! does user expect it's same slice to passed closure when using Before()/After() when insert multi??
@@ -95,11 +112,26 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 			closure(elemValue)
 		}
 
-		if processor, ok := interface{}(elemValue).(BeforeInsertProcessor); ok {
-			processor.BeforeInsert()
+		if err := session.beforeInsert(elemValue); err != nil {
+			return 0, err
 		}
 		// --
 
+		if err := session.Engine.setGeneratedIDs(table, vv); err != nil {
+			return 0, err
+		}
+		if err := session.setInitialVersion(table, vv); err != nil {
+			return 0, err
+		}
+
+		if err := session.validate(vv.Addr().Interface()); err != nil {
+			return 0, err
+		}
+
+		if err := session.Statement.setTenantValue(vv); err != nil {
+			return 0, err
+		}
+
 		if i == 0 {
 			for _, col := range table.Columns() {
 				ptrFieldValue, err := col.ValueOfV(&vv)
@@ -127,7 +159,7 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 					}
 				}
 				if (col.IsCreated || col.IsUpdated) && session.Statement.UseAutoTime {
-					val, t := session.Engine.NowTime2(col.SQLType.Name)
+					val, t := session.Engine.nowTime(col)
 					args = append(args, val)
 
 					var colName = col.Name
@@ -135,7 +167,7 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 						col := table.GetColumn(colName)
 						setColumnTime(bean, col, t)
 					})
-				} else if col.IsVersion && session.Statement.checkVersion {
+				} else if col.IsVersion && session.Statement.checkVersion && isCounterVersion(fieldValue.Type()) {
 					args = append(args, 1)
 					var colName = col.Name
 					session.afterClosures = append(session.afterClosures, func(bean interface{}) {
@@ -152,7 +184,6 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 
 				colNames = append(colNames, col.Name)
 				cols = append(cols, col)
-				colPlaces = append(colPlaces, "?")
 			}
 		} else {
 			for _, col := range cols {
@@ -182,7 +213,7 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 					}
 				}
 				if (col.IsCreated || col.IsUpdated) && session.Statement.UseAutoTime {
-					val, t := session.Engine.NowTime2(col.SQLType.Name)
+					val, t := session.Engine.nowTime(col)
 					args = append(args, val)
 
 					var colName = col.Name
@@ -190,7 +221,7 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 						col := table.GetColumn(colName)
 						setColumnTime(bean, col, t)
 					})
-				} else if col.IsVersion && session.Statement.checkVersion {
+				} else if col.IsVersion && session.Statement.checkVersion && isCounterVersion(fieldValue.Type()) {
 					args = append(args, 1)
 					var colName = col.Name
 					session.afterClosures = append(session.afterClosures, func(bean interface{}) {
@@ -204,46 +235,37 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 					}
 					args = append(args, arg)
 				}
-
-				colPlaces = append(colPlaces, "?")
 			}
 		}
-		colMultiPlaces = append(colMultiPlaces, strings.Join(colPlaces, ", "))
 	}
 	cleanupProcessorsClosures(&session.beforeClosures)
 
-	var sql = "INSERT INTO %s (%v%v%v) VALUES (%v)"
-	var statement string
-	if session.Engine.dialect.DBType() == core.ORACLE {
-		sql = "INSERT ALL INTO %s (%v%v%v) VALUES (%v) SELECT 1 FROM DUAL"
-		temp := fmt.Sprintf(") INTO %s (%v%v%v) VALUES (",
-			session.Engine.Quote(session.Statement.TableName()),
-			session.Engine.QuoteStr(),
-			strings.Join(colNames, session.Engine.QuoteStr()+", "+session.Engine.QuoteStr()),
-			session.Engine.QuoteStr())
-		statement = fmt.Sprintf(sql,
-			session.Engine.Quote(session.Statement.TableName()),
-			session.Engine.QuoteStr(),
-			strings.Join(colNames, session.Engine.QuoteStr()+", "+session.Engine.QuoteStr()),
-			session.Engine.QuoteStr(),
-			strings.Join(colMultiPlaces, temp))
-	} else {
-		statement = fmt.Sprintf(sql,
-			session.Engine.Quote(session.Statement.TableName()),
-			session.Engine.QuoteStr(),
-			strings.Join(colNames, session.Engine.QuoteStr()+", "+session.Engine.QuoteStr()),
-			session.Engine.QuoteStr(),
-			strings.Join(colMultiPlaces, "),("))
-	}
-	res, err := session.exec(statement, args...)
+	endAudit, err := session.beginAudit(table)
 	if err != nil {
 		return 0, err
 	}
+	affected, err := session.execInsertMulti(colNames, size, args)
+	if err != nil {
+		return 0, endAudit(err)
+	}
 
 	if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
 		session.cacheInsert(session.Statement.TableName())
 	}
 
+	if session.capturesChanges(table) {
+		beans := make([]interface{}, size)
+		for i := range beans {
+			beans[i] = reflect.Indirect(sliceValue.Index(i)).Addr().Interface()
+		}
+		if err := session.captureInsert(table, beans...); err != nil {
+			return 0, endAudit(err)
+		}
+	}
+	if err := endAudit(nil); err != nil {
+		return 0, err
+	}
+
 	lenAfterClosures := len(session.afterClosures)
 	for i := 0; i < size; i++ {
 		elemValue := reflect.Indirect(sliceValue.Index(i)).Addr().Interface()
@@ -254,9 +276,7 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 			for _, closure := range session.afterClosures {
 				closure(elemValue)
 			}
-			if processor, ok := interface{}(elemValue).(AfterInsertProcessor); ok {
-				processor.AfterInsert()
-			}
+			session.afterInsert(elemValue)
 		} else {
 			if lenAfterClosures > 0 {
 				if value, has := session.afterInsertBeans[elemValue]; has && value != nil {
@@ -267,7 +287,7 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 					session.afterInsertBeans[elemValue] = &afterClosures
 				}
 			} else {
-				if _, ok := interface{}(elemValue).(AfterInsertProcessor); ok {
+				if hasAfterInsert(elemValue) {
 					session.afterInsertBeans[elemValue] = nil
 				}
 			}
@@ -275,11 +295,16 @@ func (session *Session) innerInsertMulti(rowsSlicePtr interface{}) (int64, error
 	}
 
 	cleanupProcessorsClosures(&session.afterClosures)
-	return res.RowsAffected()
+	return affected, nil
 }
 
 // InsertMulti insert multiple records
 func (session *Session) InsertMulti(rowsSlicePtr interface{}) (int64, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
@@ -299,6 +324,29 @@ func (session *Session) InsertMulti(rowsSlicePtr interface{}) (int64, error) {
 }
 
 func (session *Session) innerInsert(bean interface{}) (int64, error) {
+	if err := session.Statement.setRefValue(rValue(bean)); err != nil {
+		return 0, err
+	}
+	endAudit, err := session.beginAudit(session.Statement.RefTable)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := session.insertBean(bean)
+	inserted := err == nil && (affected > 0 || !session.Statement.insertIgnore)
+	if inserted {
+		err = session.captureInsert(session.Statement.RefTable, bean)
+	}
+	if err = endAudit(err); err == nil && inserted {
+		table := session.Statement.RefTable
+		if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
+			session.writeThrough(table, session.Engine.IdOf(bean))
+		}
+	}
+	return affected, err
+}
+
+func (session *Session) insertBean(bean interface{}) (int64, error) {
 	if err := session.Statement.setRefValue(rValue(bean)); err != nil {
 		return 0, err
 	}
@@ -314,10 +362,25 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 	}
 	cleanupProcessorsClosures(&session.beforeClosures) // cleanup after used
 
-	if processor, ok := interface{}(bean).(BeforeInsertProcessor); ok {
-		processor.BeforeInsert()
+	if err := session.beforeInsert(bean); err != nil {
+		return 0, err
 	}
 	// --
+
+	if err := session.Engine.setGeneratedIDs(table, rValue(bean)); err != nil {
+		return 0, err
+	}
+	if err := session.setInitialVersion(table, rValue(bean)); err != nil {
+		return 0, err
+	}
+
+	if err := session.validate(bean); err != nil {
+		return 0, err
+	}
+
+	if err := session.Statement.setTenantValue(rValue(bean)); err != nil {
+		return 0, err
+	}
 	colNames, args, err := genCols(session.Statement.RefTable, session, bean, false, false)
 	if err != nil {
 		return 0, err
@@ -350,12 +413,18 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 
 	var sqlStr string
 	if len(colPlaces) > 0 {
-		sqlStr = fmt.Sprintf("INSERT INTO %s (%v%v%v) VALUES (%v)",
-			session.Engine.Quote(session.Statement.TableName()),
-			session.Engine.QuoteStr(),
-			strings.Join(colNames, session.Engine.Quote(", ")),
-			session.Engine.QuoteStr(),
-			colPlaces)
+		buf := getSQLBuffer()
+		buf.WriteString("INSERT INTO ")
+		buf.WriteString(session.Engine.Quote(session.Statement.TableName()))
+		buf.WriteString(" (")
+		buf.WriteString(session.Engine.QuoteStr())
+		writeJoin(buf, colNames, session.Engine.Quote(", "))
+		buf.WriteString(session.Engine.QuoteStr())
+		buf.WriteString(") VALUES (")
+		buf.WriteString(colPlaces)
+		buf.WriteString(")")
+		sqlStr = buf.String()
+		putSQLBuffer(buf)
 	} else {
 		if session.Engine.dialect.DBType() == core.MYSQL {
 			sqlStr = fmt.Sprintf("INSERT INTO %s VALUES ()", session.Engine.Quote(session.Statement.TableName()))
@@ -363,15 +432,24 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 			sqlStr = fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", session.Engine.Quote(session.Statement.TableName()))
 		}
 	}
+	if session.Statement.insertIgnore {
+		if sqlStr, err = session.insertIgnoreSQL(sqlStr, colNames, exprColVals); err != nil {
+			return 0, err
+		}
+	} else if session.Statement.insertReplace {
+		sqlStr = "REPLACE" + strings.TrimPrefix(sqlStr, "INSERT")
+	} else if session.Statement.upsert.enabled {
+		if sqlStr, err = session.upsertSQL(sqlStr, colNames); err != nil {
+			return 0, err
+		}
+	}
 
 	handleAfterInsertProcessorFunc := func(bean interface{}) {
 		if session.IsAutoCommit {
 			for _, closure := range session.afterClosures {
 				closure(bean)
 			}
-			if processor, ok := interface{}(bean).(AfterInsertProcessor); ok {
-				processor.AfterInsert()
-			}
+			session.afterInsert(bean)
 		} else {
 			lenAfterClosures := len(session.afterClosures)
 			if lenAfterClosures > 0 {
@@ -384,7 +462,7 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 				}
 
 			} else {
-				if _, ok := interface{}(bean).(AfterInsertProcessor); ok {
+				if hasAfterInsert(bean) {
 					session.afterInsertBeans[bean] = nil
 				}
 			}
@@ -409,8 +487,8 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 		if table.Version != "" && session.Statement.checkVersion {
 			verValue, err := table.VersionColumn().ValueOf(bean)
 			if err != nil {
-				session.Engine.logger.Error(err)
-			} else if verValue.IsValid() && verValue.CanSet() {
+				session.getLogger().Error(err)
+			} else if verValue.IsValid() && verValue.CanSet() && isCounterVersion(verValue.Type()) {
 				verValue.SetInt(1)
 			}
 		}
@@ -427,7 +505,7 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 
 		aiValue, err := table.AutoIncrColumn().ValueOf(bean)
 		if err != nil {
-			session.Engine.logger.Error(err)
+			session.getLogger().Error(err)
 		}
 
 		if aiValue == nil || !aiValue.IsValid() || !aiValue.CanSet() {
@@ -445,6 +523,12 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 		if err != nil {
 			return 0, err
 		}
+		// nothing is returned when the conflict is ignored
+		if len(res) < 1 && (session.Statement.insertIgnore || session.Statement.upsert.enabled) {
+			return 0, nil
+		}
+		session.markWrite()
+		session.invalidateQueryCache(sqlStr)
 		handleAfterInsertProcessorFunc(bean)
 
 		if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
@@ -454,8 +538,8 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 		if table.Version != "" && session.Statement.checkVersion {
 			verValue, err := table.VersionColumn().ValueOf(bean)
 			if err != nil {
-				session.Engine.logger.Error(err)
-			} else if verValue.IsValid() && verValue.CanSet() {
+				session.getLogger().Error(err)
+			} else if verValue.IsValid() && verValue.CanSet() && isCounterVersion(verValue.Type()) {
 				verValue.SetInt(1)
 			}
 		}
@@ -472,7 +556,7 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 
 		aiValue, err := table.AutoIncrColumn().ValueOf(bean)
 		if err != nil {
-			session.Engine.logger.Error(err)
+			session.getLogger().Error(err)
 		}
 
 		if aiValue == nil || !aiValue.IsValid() || !aiValue.CanSet() {
@@ -487,6 +571,11 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 		if err != nil {
 			return 0, err
 		}
+		if session.Statement.insertIgnore {
+			if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+				return 0, err
+			}
+		}
 
 		defer handleAfterInsertProcessorFunc(bean)
 
@@ -497,8 +586,8 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 		if table.Version != "" && session.Statement.checkVersion {
 			verValue, err := table.VersionColumn().ValueOf(bean)
 			if err != nil {
-				session.Engine.logger.Error(err)
-			} else if verValue.IsValid() && verValue.CanSet() {
+				session.getLogger().Error(err)
+			} else if verValue.IsValid() && verValue.CanSet() && isCounterVersion(verValue.Type()) {
 				verValue.SetInt(1)
 			}
 		}
@@ -515,7 +604,7 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 
 		aiValue, err := table.AutoIncrColumn().ValueOf(bean)
 		if err != nil {
-			session.Engine.logger.Error(err)
+			session.getLogger().Error(err)
 		}
 
 		if aiValue == nil || !aiValue.IsValid() || !aiValue.CanSet() {
@@ -532,6 +621,11 @@ func (session *Session) innerInsert(bean interface{}) (int64, error) {
 // The in parameter bean must a struct or a point to struct. The return
 // parameter is inserted and error
 func (session *Session) InsertOne(bean interface{}) (int64, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
@@ -549,7 +643,7 @@ func (session *Session) cacheInsert(tables ...string) error {
 	cacher := session.Engine.getCacher2(table)
 
 	for _, t := range tables {
-		session.Engine.logger.Debug("[cache] clear sql:", t)
+		session.getLogger().Debug("[cache] clear sql:", t)
 		cacher.ClearIds(t)
 	}
 
@@ -0,0 +1,97 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// SetLogger logs the statements and the messages of the session with
+// logger until the session is closed, instead of the logger of the engine.
+// A logger of a lower level debugs a single session verbosely.
+func (session *Session) SetLogger(logger core.ILogger) *Session {
+	session.logger = logger
+	return session
+}
+
+// ShowSQL shows or hides the SQL statements of the session until it's
+// closed, whatever the engine shows
+func (session *Session) ShowSQL(show ...bool) *Session {
+	var s = true
+	if len(show) > 0 {
+		s = show[0]
+	}
+	session.showSQL = &s
+	return session
+}
+
+// getLogger returns the logger of the session, the engine's one by default
+func (session *Session) getLogger() core.ILogger {
+	if session.logger != nil {
+		return session.logger
+	}
+	return session.Engine.logger
+}
+
+// isShowSQL returns true if the statements of the session are logged
+func (session *Session) isShowSQL() bool {
+	if session.showSQL != nil {
+		return *session.showSQL
+	}
+	return session.Engine.showSQL
+}
+
+// structuredLogger returns the structured logger of the engine, nil when
+// the session has its own logger
+func (session *Session) structuredLogger() StructuredLogger {
+	if session.logger != nil {
+		return nil
+	}
+	return session.Engine.structuredLogger
+}
+
+// logSQL logs the statement, when the execution time is not shown
+func (session *Session) logSQL(sqlStr string, sqlArgs ...interface{}) {
+	if session.isShowSQL() && !session.Engine.showExecTime && session.structuredLogger() == nil {
+		if len(sqlArgs) > 0 {
+			session.getLogger().Infof("[SQL] %v %v", sqlStr, sqlArgs)
+		} else {
+			session.getLogger().Infof("[SQL] %v", sqlStr)
+		}
+	}
+}
+
+func (session *Session) logSQLQueryTime(sqlStr string, args []interface{}, executionBlock func() (*core.Stmt, *core.Rows, error)) (*core.Stmt, *core.Rows, error) {
+	if session.isShowSQL() && session.Engine.showExecTime && session.structuredLogger() == nil {
+		b4ExecTime := time.Now()
+		stmt, res, err := executionBlock()
+		execDuration := time.Since(b4ExecTime)
+		if len(args) > 0 {
+			session.getLogger().Infof("[SQL] %s %v - took: %v", sqlStr, args, execDuration)
+		} else {
+			session.getLogger().Infof("[SQL] %s - took: %v", sqlStr, execDuration)
+		}
+		return stmt, res, err
+	}
+	return executionBlock()
+}
+
+func (session *Session) logSQLExecutionTime(sqlStr string, args []interface{}, executionBlock func() (sql.Result, error)) (sql.Result, error) {
+	if session.isShowSQL() && session.Engine.showExecTime && session.structuredLogger() == nil {
+		b4ExecTime := time.Now()
+		res, err := executionBlock()
+		execDuration := time.Since(b4ExecTime)
+		if len(args) > 0 {
+			session.getLogger().Infof("[sql] %s [args] %v - took: %v", sqlStr, args, execDuration)
+		} else {
+			session.getLogger().Infof("[sql] %s - took: %v", sqlStr, execDuration)
+		}
+		return res, err
+	}
+	return executionBlock()
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type SessionLoggedUser struct {
+	Id   int64
+	Name string
+}
+
+func TestSessionLogger(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(SessionLoggedUser)))
+
+	showSQL := testEngine.showSQL
+	testEngine.ShowSQL(false)
+	defer testEngine.ShowSQL(showSQL)
+
+	var buf bytes.Buffer
+	logger := NewSimpleLogger3(&buf, DEFAULT_LOG_PREFIX, 0, core.LOG_DEBUG)
+
+	session := testEngine.NewSession()
+	defer session.Close()
+	session.SetLogger(logger).ShowSQL(true)
+
+	_, err := session.Insert(&SessionLoggedUser{Name: "lunny"})
+	assert.NoError(t, err)
+	var users []SessionLoggedUser
+	assert.NoError(t, session.Find(&users))
+	assert.True(t, strings.Count(strings.ToUpper(buf.String()), "[SQL]") >= 2, buf.String())
+
+	// the other sessions don't log
+	buf.Reset()
+	assert.NoError(t, testEngine.Find(&users))
+	assert.EqualValues(t, 0, buf.Len())
+
+	// the session can hide its statements
+	session.ShowSQL(false)
+	assert.NoError(t, session.Find(&users))
+	assert.EqualValues(t, 0, buf.Len())
+}
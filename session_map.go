@@ -0,0 +1,144 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// mapValues returns the columns and the values of a map of column names to
+// values, as the map[string]interface{} of Insert and Update. When the
+// table is mapped, the keys are checked against its columns and the values
+// are converted as the values of the fields of the columns.
+func (session *Session) mapValues(table *core.Table, m reflect.Value) ([]string, []interface{}, error) {
+	if m.Type().Key().Kind() != reflect.String {
+		return nil, nil, ErrParamsType
+	}
+
+	keys := make([]string, 0, m.Len())
+	for _, k := range m.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+
+	var colNames = make([]string, 0, len(keys))
+	var args = make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		value := m.MapIndex(reflect.ValueOf(key).Convert(m.Type().Key()))
+		if value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+
+		if table == nil {
+			colNames = append(colNames, key)
+			if value.IsValid() {
+				args = append(args, value.Interface())
+			} else {
+				args = append(args, nil)
+			}
+			continue
+		}
+
+		col := table.GetColumn(key)
+		if col == nil {
+			return nil, nil, fmt.Errorf("unknown column %v of the table %v", key, table.Name)
+		}
+		if col.MapType == core.ONLYFROMDB {
+			return nil, nil, fmt.Errorf("column %v of the table %v is read only", key, table.Name)
+		}
+
+		var arg interface{}
+		if value.IsValid() && !(value.Kind() == reflect.Ptr && value.IsNil()) {
+			var err error
+			if arg, err = session.value2Interface(col, value); err != nil {
+				return nil, nil, err
+			}
+		}
+		colNames = append(colNames, col.Name)
+		args = append(args, arg)
+	}
+	return colNames, args, nil
+}
+
+// insertMap inserts a map of column names to values in the table set by
+// Table, the created, updated and version columns of a mapped table which
+// are not in the map are set as for the beans
+func (session *Session) insertMap(m reflect.Value) (int64, error) {
+	tableName := session.Statement.TableName()
+	if len(tableName) <= 0 {
+		return 0, ErrTableNotFound
+	}
+
+	table := session.Statement.RefTable
+	colNames, args, err := session.mapValues(table, m)
+	if err != nil {
+		return 0, err
+	}
+	// the map is inserted for the tenant of the session
+	colNames, args, err = session.Statement.tenantMapValues(colNames, args)
+	if err != nil {
+		return 0, err
+	}
+
+	if table != nil {
+		var has = make(map[string]bool, len(colNames))
+		for _, colName := range colNames {
+			has[strings.ToLower(colName)] = true
+		}
+		var autoTimes []string
+		if session.Statement.UseAutoTime {
+			for name := range table.Created {
+				autoTimes = append(autoTimes, name)
+			}
+			if table.Updated != "" && !table.Created[table.Updated] {
+				autoTimes = append(autoTimes, table.Updated)
+			}
+		}
+		sort.Strings(autoTimes)
+		for _, name := range autoTimes {
+			if has[strings.ToLower(name)] {
+				continue
+			}
+			col := table.GetColumn(name)
+			val, _ := session.Engine.nowTime(col)
+			colNames = append(colNames, col.Name)
+			args = append(args, val)
+		}
+		if table.Version != "" && !has[strings.ToLower(table.Version)] {
+			colNames = append(colNames, table.Version)
+			args = append(args, 1)
+		}
+	}
+
+	if len(colNames) == 0 {
+		return 0, errors.New("No content found to be inserted")
+	}
+
+	var quoted = make([]string, len(colNames))
+	for i, colName := range colNames {
+		quoted[i] = session.Engine.Quote(colName)
+	}
+	sqlStr := fmt.Sprintf("INSERT INTO %v (%v) VALUES (%v)",
+		session.Engine.Quote(tableName),
+		strings.Join(quoted, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(colNames)), ", "))
+	res, err := session.exec(sqlStr, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	if table != nil {
+		if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
+			session.cacheInsert(tableName)
+		}
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,114 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MapWriteStruct struct {
+	Id      int64
+	Name    string
+	Tags    []string
+	Created time.Time `xorm:"created"`
+	Updated time.Time `xorm:"updated"`
+	Version int       `xorm:"version"`
+	Count   int       `xorm:"<-"`
+}
+
+func TestInsertMapColumns(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(MapWriteStruct))
+
+	cnt, err := testEngine.Table(new(MapWriteStruct)).Insert(map[string]interface{}{
+		"name": "a",
+		"tags": []string{"x", "y"},
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	cnt, err = testEngine.Table(new(MapWriteStruct)).Insert([]map[string]interface{}{
+		{"name": "b"},
+		{"name": "c", "version": 5},
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cnt)
+
+	var got MapWriteStruct
+	has, err := testEngine.Where("name = ?", "a").Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, []string{"x", "y"}, got.Tags)
+	assert.False(t, got.Created.IsZero())
+	assert.False(t, got.Updated.IsZero())
+	assert.EqualValues(t, 1, got.Version)
+
+	got = MapWriteStruct{}
+	has, err = testEngine.Where("name = ?", "c").Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 5, got.Version)
+
+	_, err = testEngine.Table(new(MapWriteStruct)).Insert(map[string]interface{}{"nmae": "d"})
+	assert.Error(t, err)
+	_, err = testEngine.Table(new(MapWriteStruct)).Insert(map[string]interface{}{"count": 1})
+	assert.Error(t, err)
+
+	cnt, err = testEngine.Count(new(MapWriteStruct))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, cnt)
+}
+
+func TestUpdateMapColumns(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(MapWriteStruct))
+
+	m := MapWriteStruct{Name: "a"}
+	_, err := testEngine.Insert(&m)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, m.Version)
+
+	cnt, err := testEngine.Table(new(MapWriteStruct)).ID(m.Id).Update(map[string]interface{}{
+		"name":    "b",
+		"tags":    []string{"z"},
+		"version": 1,
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var got MapWriteStruct
+	has, err := testEngine.ID(m.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "b", got.Name)
+	assert.EqualValues(t, []string{"z"}, got.Tags)
+	assert.EqualValues(t, 2, got.Version)
+	assert.False(t, got.Updated.IsZero())
+
+	// a stale version updates nothing
+	cnt, err = testEngine.Table(new(MapWriteStruct)).ID(m.Id).Update(map[string]interface{}{
+		"name":    "c",
+		"version": 1,
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+
+	cnt, err = testEngine.Table(new(MapWriteStruct)).ID(m.Id).Update(map[string]interface{}{"name": "d"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	got = MapWriteStruct{}
+	has, err = testEngine.ID(m.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "d", got.Name)
+	assert.EqualValues(t, 3, got.Version)
+
+	_, err = testEngine.Table(new(MapWriteStruct)).ID(m.Id).Update(map[string]interface{}{"nmae": "e"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,50 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"sync"
+)
+
+// SetSessionPool enables or disables reusing the closed sessions and their
+// statements for the new sessions, so less memory is allocated per request.
+// When it's enabled, a session MUST NOT be used after Close, including the
+// sessions closed automatically by the chainable methods of Engine.
+func (engine *Engine) SetSessionPool(enabled bool) {
+	if !enabled {
+		engine.sessionPool = nil
+		return
+	}
+
+	engine.sessionPool = &sync.Pool{
+		New: func() interface{} {
+			session := &Session{Engine: engine}
+			session.Init()
+			return session
+		},
+	}
+}
+
+// getPooledSession returns a reset session from the pool
+func (engine *Engine) getPooledSession() *Session {
+	session := engine.sessionPool.Get().(*Session)
+	session.pooled = false
+	return session
+}
+
+// putPooledSession resets the closed session and puts it back to the pool,
+// a session closed twice is only put once
+func (engine *Engine) putPooledSession(session *Session) {
+	pool := engine.sessionPool
+	if pool == nil || session.pooled {
+		return
+	}
+
+	session.Init()
+	session.guard = nil
+	session.closeDefer = false
+	session.pooled = true
+	pool.Put(session)
+}
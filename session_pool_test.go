@@ -0,0 +1,123 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionPool(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	type PoolSessionUser struct {
+		Id   int64
+		Name string
+	}
+	assert.NoError(t, testEngine.Sync2(new(PoolSessionUser)))
+
+	testEngine.SetSessionPool(true)
+	defer testEngine.SetSessionPool(false)
+
+	sess := testEngine.NewSession()
+	sess.Where("name = ?", "a").Cols("name").Prepare()
+	_, err := sess.Insert(&PoolSessionUser{Name: "a"})
+	assert.NoError(t, err)
+	sess.Close()
+	assert.True(t, sess.pooled)
+
+	// closing twice doesn't put the session into the pool twice
+	sess.Close()
+	assert.True(t, sess.pooled)
+
+	sess = testEngine.NewSession()
+	defer sess.Close()
+	assert.False(t, sess.pooled)
+	assert.False(t, sess.prepareStmt)
+	assert.True(t, sess.IsAutoCommit)
+	assert.EqualValues(t, 0, len(sess.Statement.columnMap))
+	assert.EqualValues(t, "", sess.Statement.ColumnStr)
+
+	total, err := sess.Count(new(PoolSessionUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+
+	// chainable methods of engine close their sessions automatically
+	for i := 0; i < 10; i++ {
+		total, err = testEngine.Where("name = ?", "a").Count(new(PoolSessionUser))
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, total)
+	}
+}
+
+func TestSessionPoolAutoClose(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	type PoolAutoCloseUser struct {
+		Id   int64
+		Name string
+	}
+	assert.NoError(t, testEngine.Sync2(new(PoolAutoCloseUser)))
+	_, err := testEngine.Insert(&PoolAutoCloseUser{Name: "a"})
+	assert.NoError(t, err)
+
+	testEngine.SetSessionPool(true)
+	defer testEngine.SetSessionPool(false)
+
+	// a session closed automatically is only reused when its operation has
+	// reset its statement, run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				total, err := testEngine.Where("name = ?", "a").Count(new(PoolAutoCloseUser))
+				assert.NoError(t, err)
+				assert.EqualValues(t, 1, total)
+
+				var users []PoolAutoCloseUser
+				assert.NoError(t, testEngine.Where("name = ?", "a").Find(&users))
+				assert.EqualValues(t, 1, len(users))
+			}
+		}()
+	}
+	wg.Wait()
+
+	sess := testEngine.NewSession()
+	sess.IsAutoClose = true
+	_, err = sess.Count(new(PoolAutoCloseUser))
+	assert.NoError(t, err)
+	assert.True(t, sess.pooled)
+	assert.EqualValues(t, 0, sess.running)
+}
+
+func benchmarkNewSession(b *testing.B, pooled bool) {
+	b.StopTimer()
+	if err := prepareEngine(); err != nil {
+		b.Fatal(err)
+	}
+	testEngine.SetSessionPool(pooled)
+	defer testEngine.SetSessionPool(false)
+
+	b.ReportAllocs()
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		sess := testEngine.NewSession()
+		sess.Where("id = ?", i).Cols("name").Limit(10)
+		sess.resetStatement()
+		sess.Close()
+	}
+}
+
+func BenchmarkNewSession(b *testing.B) {
+	benchmarkNewSession(b, false)
+}
+
+func BenchmarkNewSessionPooled(b *testing.B) {
+	benchmarkNewSession(b, true)
+}
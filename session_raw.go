@@ -5,6 +5,7 @@
 package xorm
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
@@ -15,16 +16,28 @@ import (
 )
 
 func (session *Session) query(sqlStr string, paramStr ...interface{}) ([]map[string][]byte, error) {
+	return session.queryDB(session.DB(), sqlStr, paramStr...)
+}
+
+// queryDB runs the query on db when session is autocommit, otherwise in the
+// transaction
+func (session *Session) queryDB(db *core.DB, sqlStr string, paramStr ...interface{}) ([]map[string][]byte, error) {
 	session.queryPreprocess(&sqlStr, paramStr...)
 
-	if session.IsAutoCommit {
-		return session.innerQuery2(sqlStr, paramStr...)
-	}
-	return session.txQuery(session.Tx, sqlStr, paramStr...)
+	var results []map[string][]byte
+	err := session.interceptQuery(sqlStr, paramStr, func(sqlStr string, args []interface{}) (err error) {
+		if session.IsAutoCommit {
+			results, err = session.innerQuery2(db, sqlStr, args...)
+		} else {
+			results, err = session.txQuery(session.Tx, sqlStr, args...)
+		}
+		return err
+	})
+	return results, err
 }
 
 func (session *Session) txQuery(tx *core.Tx, sqlStr string, params ...interface{}) ([]map[string][]byte, error) {
-	rows, err := tx.Query(sqlStr, params...)
+	rows, err := tx.QueryContext(session.Ctx(), sqlStr, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +46,9 @@ func (session *Session) txQuery(tx *core.Tx, sqlStr string, params ...interface{
 	return rows2maps(rows)
 }
 
-func (session *Session) innerQuery(sqlStr string, params ...interface{}) (*core.Stmt, *core.Rows, error) {
+func (session *Session) innerQuery(db *core.DB, sqlStr string, params ...interface{}) (*core.Stmt, *core.Rows, error) {
+	session.Engine.checkPool(db)
+
 	var callback func() (*core.Stmt, *core.Rows, error)
 	if session.prepareStmt {
 		callback = func() (*core.Stmt, *core.Rows, error) {
@@ -41,7 +56,7 @@ func (session *Session) innerQuery(sqlStr string, params ...interface{}) (*core.
 			if err != nil {
 				return nil, nil, err
 			}
-			rows, err := stmt.Query(params...)
+			rows, err := stmt.QueryContext(session.Ctx(), params...)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -49,20 +64,61 @@ func (session *Session) innerQuery(sqlStr string, params ...interface{}) (*core.
 		}
 	} else {
 		callback = func() (*core.Stmt, *core.Rows, error) {
-			rows, err := session.DB().Query(sqlStr, params...)
+			rows, err := db.QueryContext(session.Ctx(), sqlStr, params...)
 			if err != nil {
 				return nil, nil, err
 			}
 			return nil, rows, err
 		}
 	}
-	stmt, rows, err := session.Engine.logSQLQueryTime(sqlStr, params, callback)
+	stmt, rows, err := session.logSQLQueryTime(sqlStr, params, callback)
 	if err != nil {
 		return nil, nil, err
 	}
 	return stmt, rows, nil
 }
 
+// readQuery runs an autocommit read on the database returned by readDB
+func (session *Session) readQuery(sqlStr string, params ...interface{}) (*core.Rows, error) {
+	var rows *core.Rows
+	err := session.retryRead(func() error {
+		db, err := session.readDB()
+		if err != nil {
+			return err
+		}
+		_, rows, err = session.innerQuery(db, sqlStr, params...)
+		return err
+	})
+	return rows, err
+}
+
+// queryRows runs the query of Find and Get, on the database returned by
+// readDB when session is autocommit, otherwise in the transaction
+func (session *Session) queryRows(sqlStr string, params ...interface{}) (*core.Rows, error) {
+	var rows *core.Rows
+	err := session.interceptQuery(sqlStr, params, func(sqlStr string, args []interface{}) (err error) {
+		if session.IsAutoCommit {
+			rows, err = session.readQuery(sqlStr, args...)
+		} else {
+			rows, err = session.Tx.QueryContext(session.Ctx(), sqlStr, args...)
+		}
+		return err
+	})
+	return rows, err
+}
+
+// dbQuery runs the query on the database of session even in a transaction,
+// as the queries of the cached ids
+func (session *Session) dbQuery(sqlStr string, params ...interface{}) (*core.Rows, error) {
+	var rows *core.Rows
+	err := session.interceptQuery(sqlStr, params, func(sqlStr string, args []interface{}) (err error) {
+		session.Engine.checkPool(session.DB())
+		rows, err = session.DB().QueryContext(session.Ctx(), sqlStr, args...)
+		return err
+	})
+	return rows, err
+}
+
 func rows2maps(rows *core.Rows) (resultsSlice []map[string][]byte, err error) {
 	fields, err := rows.Columns()
 	if err != nil {
@@ -117,8 +173,8 @@ func row2map(rows *core.Rows, fields []string) (resultsMap map[string][]byte, er
 	return result, nil
 }
 
-func (session *Session) innerQuery2(sqlStr string, params ...interface{}) ([]map[string][]byte, error) {
-	_, rows, err := session.innerQuery(sqlStr, params...)
+func (session *Session) innerQuery2(db *core.DB, sqlStr string, params ...interface{}) ([]map[string][]byte, error) {
+	_, rows, err := session.innerQuery(db, sqlStr, params...)
 	if rows != nil {
 		defer rows.Close()
 	}
@@ -130,12 +186,26 @@ func (session *Session) innerQuery2(sqlStr string, params ...interface{}) ([]map
 
 // Query runs a raw sql and return records as []map[string][]byte
 func (session *Session) Query(sqlStr string, paramStr ...interface{}) ([]map[string][]byte, error) {
+	if err := session.enterOperation(); err != nil {
+		return nil, err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
 	}
 
-	return session.query(sqlStr, paramStr...)
+	var results []map[string][]byte
+	err := session.retryRawRead(sqlStr, func() error {
+		db, err := session.readDB()
+		if err != nil {
+			return err
+		}
+		results, err = session.queryDB(db, sqlStr, paramStr...)
+		return err
+	})
+	return results, err
 }
 
 func rows2Strings(rows *core.Rows) (resultsSlice []map[string]string, err error) {
@@ -234,8 +304,8 @@ func row2mapStr(rows *core.Rows, fields []string) (resultsMap map[string]string,
 	return result, nil
 }
 
-func txQuery2(tx *core.Tx, sqlStr string, params ...interface{}) ([]map[string]string, error) {
-	rows, err := tx.Query(sqlStr, params...)
+func txQuery2(ctx context.Context, tx *core.Tx, sqlStr string, params ...interface{}) ([]map[string]string, error) {
+	rows, err := tx.QueryContext(ctx, sqlStr, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -244,8 +314,8 @@ func txQuery2(tx *core.Tx, sqlStr string, params ...interface{}) ([]map[string]s
 	return rows2Strings(rows)
 }
 
-func query2(db *core.DB, sqlStr string, params ...interface{}) ([]map[string]string, error) {
-	rows, err := db.Query(sqlStr, params...)
+func query2(ctx context.Context, db *core.DB, sqlStr string, params ...interface{}) ([]map[string]string, error) {
+	rows, err := db.QueryContext(ctx, sqlStr, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -255,6 +325,11 @@ func query2(db *core.DB, sqlStr string, params ...interface{}) ([]map[string]str
 
 // QueryString runs a raw sql and return records as []map[string]string
 func (session *Session) QueryString(sqlStr string, args ...interface{}) ([]map[string]string, error) {
+	if err := session.enterOperation(); err != nil {
+		return nil, err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
@@ -262,10 +337,23 @@ func (session *Session) QueryString(sqlStr string, args ...interface{}) ([]map[s
 
 	session.queryPreprocess(&sqlStr, args...)
 
-	if session.IsAutoCommit {
-		return query2(session.DB(), sqlStr, args...)
-	}
-	return txQuery2(session.Tx, sqlStr, args...)
+	var results []map[string]string
+	err := session.interceptQuery(sqlStr, args, func(sqlStr string, args []interface{}) (err error) {
+		if !session.IsAutoCommit {
+			results, err = txQuery2(session.Ctx(), session.Tx, sqlStr, args...)
+			return err
+		}
+		return session.retryRawRead(sqlStr, func() error {
+			db, err := session.readDB()
+			if err != nil {
+				return err
+			}
+			session.Engine.checkPool(db)
+			results, err = query2(session.Ctx(), db, sqlStr, args...)
+			return err
+		})
+	})
+	return results, err
 }
 
 // Execute sql
@@ -276,14 +364,14 @@ func (session *Session) innerExec(sqlStr string, args ...interface{}) (sql.Resul
 			return nil, err
 		}
 
-		res, err := stmt.Exec(args...)
+		res, err := stmt.ExecContext(session.Ctx(), args...)
 		if err != nil {
 			return nil, err
 		}
 		return res, nil
 	}
 
-	return session.DB().Exec(sqlStr, args...)
+	return session.DB().ExecContext(session.Ctx(), sqlStr, args...)
 }
 
 func (session *Session) exec(sqlStr string, args ...interface{}) (sql.Result, error) {
@@ -293,28 +381,55 @@ func (session *Session) exec(sqlStr string, args ...interface{}) (sql.Result, er
 	}
 
 	session.saveLastSQL(sqlStr, args...)
+	if session.IsAutoCommit {
+		session.Engine.checkPool(session.DB())
+	}
 
-	return session.Engine.logSQLExecutionTime(sqlStr, args, func() (sql.Result, error) {
-		if session.IsAutoCommit {
-			// FIXME: oci8 can not auto commit (github.com/mattn/go-oci8)
-			if session.Engine.dialect.DBType() == core.ORACLE {
-				session.Begin()
-				r, err := session.Tx.Exec(sqlStr, args...)
-				session.Commit()
-				return r, err
+	res, sqlStr, err := session.interceptExec(sqlStr, args, func(sqlStr string, args []interface{}) (sql.Result, error) {
+		return session.logSQLExecutionTime(sqlStr, args, func() (sql.Result, error) {
+			if session.conn != nil {
+				return session.conn.ExecContext(session.Ctx(), sqlStr, args...)
 			}
-			return session.innerExec(sqlStr, args...)
-		}
-		return session.Tx.Exec(sqlStr, args...)
+			if session.IsAutoCommit {
+				// FIXME: oci8 can not auto commit (github.com/mattn/go-oci8)
+				if session.Engine.dialect.DBType() == core.ORACLE {
+					session.Begin()
+					r, err := session.Tx.Exec(sqlStr, args...)
+					session.Commit()
+					return r, err
+				}
+				return session.innerExec(sqlStr, args...)
+			}
+			return session.Tx.ExecContext(session.Ctx(), sqlStr, args...)
+		})
 	})
+	if err == nil {
+		session.markWrite()
+		session.invalidateQueryCache(sqlStr)
+		if isDDL(sqlStr) {
+			// statements prepared before may refer to the old schema
+			session.Engine.invalidateStmts()
+		}
+	}
+	return res, err
 }
 
 // Exec raw sql
 func (session *Session) Exec(sqlStr string, args ...interface{}) (sql.Result, error) {
+	if err := session.enterOperation(); err != nil {
+		return nil, err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
 	}
 
-	return session.exec(sqlStr, args...)
+	res, err := session.exec(sqlStr, args...)
+	if err == nil {
+		// raw writes are not tracked by the cachers
+		session.Engine.ClearCacheTables(append(writeTables(sqlStr), session.Statement.invalidTables...)...)
+	}
+	return res, err
 }
@@ -84,8 +84,11 @@ func (session *Session) CreateUniques(bean interface{}) error {
 }
 
 func (session *Session) createOneTable() error {
-	sqlStr := session.Statement.genCreateTableSQL()
-	_, err := session.exec(sqlStr)
+	sqlStr, err := session.Statement.createTableSQL()
+	if err != nil {
+		return err
+	}
+	_, err = session.exec(sqlStr)
 	return err
 }
 
@@ -113,7 +116,8 @@ func (session *Session) DropIndexes(bean interface{}) error {
 
 // DropTable drop table will drop table if exist, if drop failed, it will return error
 func (session *Session) DropTable(beanOrTableName interface{}) error {
-	tableName, err := session.Engine.tableName(beanOrTableName)
+	defer session.resetStatement()
+	tableName, err := session.tableName(beanOrTableName)
 	if err != nil {
 		return err
 	}
@@ -138,7 +142,7 @@ func (session *Session) DropTable(beanOrTableName interface{}) error {
 
 // IsTableExist if a table is exist
 func (session *Session) IsTableExist(beanOrTableName interface{}) (bool, error) {
-	tableName, err := session.Engine.tableName(beanOrTableName)
+	tableName, err := session.tableName(beanOrTableName)
 	if err != nil {
 		return false, err
 	}
@@ -420,6 +424,12 @@ func (session *Session) Sync2(beans ...interface{}) error {
 		}
 	}
 
+	for _, table := range structTables {
+		if err := session.syncHistory(table, tables); err != nil {
+			return err
+		}
+	}
+
 	for _, table := range tables {
 		var oriTable *core.Table
 		for _, structTable := range structTables {
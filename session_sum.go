@@ -4,16 +4,34 @@
 
 package xorm
 
-import "database/sql"
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/go-xorm/core"
+)
 
 // Count counts the records. bean's non-empty fields
 // are conditions.
-func (session *Session) Count(bean interface{}) (int64, error) {
+func (session *Session) Count(bean interface{}, options ...CountOption) (int64, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
 	}
 
+	if est := estimatedOption(options); est != nil {
+		total, ok := session.estimateCount(bean)
+		est.Approximate = ok
+		if ok {
+			return total, nil
+		}
+	}
+
 	var sqlStr string
 	var args []interface{}
 	if session.Statement.RawSQL == "" {
@@ -25,15 +43,12 @@ func (session *Session) Count(bean interface{}) (int64, error) {
 
 	session.queryPreprocess(&sqlStr, args...)
 
-	var err error
 	var total int64
-	if session.IsAutoCommit {
-		err = session.DB().QueryRow(sqlStr, args...).Scan(&total)
-	} else {
-		err = session.Tx.QueryRow(sqlStr, args...).Scan(&total)
-	}
+	err := session.queryRow(sqlStr, args, func(row *core.Row) error {
+		return row.Scan(&total)
+	})
 
-	if err == sql.ErrNoRows || err == nil {
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
 		return total, nil
 	}
 
@@ -42,6 +57,11 @@ func (session *Session) Count(bean interface{}) (int64, error) {
 
 // Sum call sum some column. bean's non-empty fields are conditions.
 func (session *Session) Sum(bean interface{}, columnName string) (float64, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
@@ -58,15 +78,12 @@ func (session *Session) Sum(bean interface{}, columnName string) (float64, error
 
 	session.queryPreprocess(&sqlStr, args...)
 
-	var err error
 	var res float64
-	if session.IsAutoCommit {
-		err = session.DB().QueryRow(sqlStr, args...).Scan(&res)
-	} else {
-		err = session.Tx.QueryRow(sqlStr, args...).Scan(&res)
-	}
+	err := session.queryRow(sqlStr, args, func(row *core.Row) error {
+		return row.Scan(&res)
+	})
 
-	if err == sql.ErrNoRows || err == nil {
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
 		return res, nil
 	}
 	return 0, err
@@ -74,6 +91,11 @@ func (session *Session) Sum(bean interface{}, columnName string) (float64, error
 
 // Sums call sum some columns. bean's non-empty fields are conditions.
 func (session *Session) Sums(bean interface{}, columnNames ...string) ([]float64, error) {
+	if err := session.enterOperation(); err != nil {
+		return nil, err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
@@ -90,15 +112,12 @@ func (session *Session) Sums(bean interface{}, columnNames ...string) ([]float64
 
 	session.queryPreprocess(&sqlStr, args...)
 
-	var err error
 	var res = make([]float64, len(columnNames), len(columnNames))
-	if session.IsAutoCommit {
-		err = session.DB().QueryRow(sqlStr, args...).ScanSlice(&res)
-	} else {
-		err = session.Tx.QueryRow(sqlStr, args...).ScanSlice(&res)
-	}
+	err := session.queryRow(sqlStr, args, func(row *core.Row) error {
+		return row.ScanSlice(&res)
+	})
 
-	if err == sql.ErrNoRows || err == nil {
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
 		return res, nil
 	}
 	return nil, err
@@ -106,6 +125,11 @@ func (session *Session) Sums(bean interface{}, columnNames ...string) ([]float64
 
 // SumsInt sum specify columns and return as []int64 instead of []float64
 func (session *Session) SumsInt(bean interface{}, columnNames ...string) ([]int64, error) {
+	if err := session.enterOperation(); err != nil {
+		return nil, err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
@@ -122,16 +146,31 @@ func (session *Session) SumsInt(bean interface{}, columnNames ...string) ([]int6
 
 	session.queryPreprocess(&sqlStr, args...)
 
-	var err error
 	var res = make([]int64, len(columnNames), len(columnNames))
-	if session.IsAutoCommit {
-		err = session.DB().QueryRow(sqlStr, args...).ScanSlice(&res)
-	} else {
-		err = session.Tx.QueryRow(sqlStr, args...).ScanSlice(&res)
-	}
+	err := session.queryRow(sqlStr, args, func(row *core.Row) error {
+		return row.ScanSlice(&res)
+	})
 
-	if err == sql.ErrNoRows || err == nil {
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
 		return res, nil
 	}
 	return nil, err
 }
+
+// queryRow runs the query of a single row on the database returned by readDB
+// when session is autocommit, otherwise in the transaction
+func (session *Session) queryRow(sqlStr string, args []interface{}, scan func(row *core.Row) error) error {
+	return session.interceptQuery(sqlStr, args, func(sqlStr string, args []interface{}) error {
+		if !session.IsAutoCommit {
+			return scan(session.Tx.QueryRowContext(session.Ctx(), sqlStr, args...))
+		}
+		return session.retryRead(func() error {
+			db, err := session.readDB()
+			if err != nil {
+				return err
+			}
+			session.Engine.checkPool(db)
+			return scan(db.QueryRowContext(session.Ctx(), sqlStr, args...))
+		})
+	})
+}
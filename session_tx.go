@@ -6,7 +6,13 @@ package xorm
 
 // Begin a transaction
 func (session *Session) Begin() error {
+	if err := session.enterOperation(); err != nil {
+		return err
+	}
+	defer session.leaveOperation()
+
 	if session.IsAutoCommit {
+		session.Engine.checkPool(session.DB())
 		tx, err := session.DB().Begin()
 		if err != nil {
 			return err
@@ -21,21 +27,48 @@ func (session *Session) Begin() error {
 
 // Rollback When using transaction, you can rollback if any error
 func (session *Session) Rollback() error {
+	if err := session.enterOperation(); err != nil {
+		return err
+	}
+	defer session.leaveOperation()
+
 	if !session.IsAutoCommit && !session.IsCommitedOrRollbacked {
 		session.saveLastSQL(session.Engine.dialect.RollBackStr())
 		session.IsCommitedOrRollbacked = true
-		return session.Tx.Rollback()
+		err := session.Tx.Rollback()
+		session.handleRollbackCallbacks()
+		return err
 	}
 	return nil
 }
 
 // Commit When using transaction, Commit will commit all operations.
 func (session *Session) Commit() error {
+	if err := session.enterOperation(); err != nil {
+		return err
+	}
+	defer session.leaveOperation()
+
 	if !session.IsAutoCommit && !session.IsCommitedOrRollbacked {
+		for _, callback := range session.beforeCommitCallbacks {
+			if err := callback(); err != nil {
+				session.Rollback()
+				return err
+			}
+		}
+
 		session.saveLastSQL("COMMIT")
 		session.IsCommitedOrRollbacked = true
 		var err error
-		if err = session.Tx.Commit(); err == nil {
+		if err = session.Tx.Commit(); err != nil {
+			// the transaction has been aborted by database/sql
+			session.handleRollbackCallbacks()
+		} else {
+			// the writes of the transaction are visible from now on
+			if !session.lastWrite.IsZero() {
+				session.markWrite()
+			}
+
 			// handle processors after tx committed
 
 			closureCallFunc := func(closuresPtr *[]func(interface{}), bean interface{}) {
@@ -50,23 +83,17 @@ func (session *Session) Commit() error {
 			for bean, closuresPtr := range session.afterInsertBeans {
 				closureCallFunc(closuresPtr, bean)
 
-				if processor, ok := interface{}(bean).(AfterInsertProcessor); ok {
-					processor.AfterInsert()
-				}
+				session.afterInsert(bean)
 			}
 			for bean, closuresPtr := range session.afterUpdateBeans {
 				closureCallFunc(closuresPtr, bean)
 
-				if processor, ok := interface{}(bean).(AfterUpdateProcessor); ok {
-					processor.AfterUpdate()
-				}
+				session.afterUpdate(bean)
 			}
 			for bean, closuresPtr := range session.afterDeleteBeans {
 				closureCallFunc(closuresPtr, bean)
 
-				if processor, ok := interface{}(bean).(AfterDeleteProcessor); ok {
-					processor.AfterDelete()
-				}
+				session.afterDelete(bean)
 			}
 			cleanUpFunc := func(slices *map[interface{}]*[]func(interface{})) {
 				if len(*slices) > 0 {
@@ -76,8 +103,65 @@ func (session *Session) Commit() error {
 			cleanUpFunc(&session.afterInsertBeans)
 			cleanUpFunc(&session.afterUpdateBeans)
 			cleanUpFunc(&session.afterDeleteBeans)
+
+			commitCallbacks := session.commitCallbacks
+			session.cleanupTxCallbacks()
+			for _, callback := range commitCallbacks {
+				callback()
+			}
 		}
-		return err
+		return session.Engine.classifyError(err)
 	}
 	return nil
 }
+
+// BeforeCommit registers a callback which will be invoked before the
+// transaction is committed. If the callback returns an error, the transaction
+// will be rolled back and Commit will return that error. If the session is
+// not in a transaction, the callback is ignored.
+func (session *Session) BeforeCommit(callback func() error) *Session {
+	if callback != nil && !session.IsAutoCommit {
+		session.beforeCommitCallbacks = append(session.beforeCommitCallbacks, callback)
+	}
+	return session
+}
+
+// OnCommit registers a callback which will be invoked after the transaction
+// has been committed successfully, so side effects like cache invalidation or
+// event publishing only happen when the changes are visible. If the session
+// is not in a transaction, the callback will be invoked immediately.
+func (session *Session) OnCommit(callback func()) *Session {
+	if callback == nil {
+		return session
+	}
+	if session.IsAutoCommit {
+		callback()
+		return session
+	}
+	session.commitCallbacks = append(session.commitCallbacks, callback)
+	return session
+}
+
+// OnRollback registers a callback which will be invoked after the transaction
+// has been rolled back, explicitly or because Commit or Close failed to finish
+// it. If the session is not in a transaction, the callback is ignored.
+func (session *Session) OnRollback(callback func()) *Session {
+	if callback != nil && !session.IsAutoCommit {
+		session.rollbackCallbacks = append(session.rollbackCallbacks, callback)
+	}
+	return session
+}
+
+func (session *Session) handleRollbackCallbacks() {
+	rollbackCallbacks := session.rollbackCallbacks
+	session.cleanupTxCallbacks()
+	for _, callback := range rollbackCallbacks {
+		callback()
+	}
+}
+
+func (session *Session) cleanupTxCallbacks() {
+	session.beforeCommitCallbacks = nil
+	session.commitCallbacks = nil
+	session.rollbackCallbacks = nil
+}
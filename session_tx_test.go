@@ -5,6 +5,7 @@
 package xorm
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -190,3 +191,73 @@ func TestCombineTransactionSameMapper(t *testing.T) {
 		panic(err)
 	}
 }
+
+func TestTransactionCallbacks(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(Userinfo))
+
+	var committed, rollbacked int
+
+	session := testEngine.NewSession()
+	defer session.Close()
+
+	assert.NoError(t, session.Begin())
+	session.OnCommit(func() {
+		committed++
+	}).OnRollback(func() {
+		rollbacked++
+	})
+
+	_, err := session.Insert(&Userinfo{Username: "callback1"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, committed)
+
+	assert.NoError(t, session.Commit())
+	assert.EqualValues(t, 1, committed)
+	assert.EqualValues(t, 0, rollbacked)
+
+	session2 := testEngine.NewSession()
+	defer session2.Close()
+
+	assert.NoError(t, session2.Begin())
+	session2.OnCommit(func() {
+		committed++
+	}).OnRollback(func() {
+		rollbacked++
+	})
+
+	_, err = session2.Insert(&Userinfo{Username: "callback2"})
+	assert.NoError(t, err)
+	assert.NoError(t, session2.Rollback())
+	assert.EqualValues(t, 1, committed)
+	assert.EqualValues(t, 1, rollbacked)
+
+	var errBeforeCommit = errors.New("before commit failed")
+	session3 := testEngine.NewSession()
+	defer session3.Close()
+
+	assert.NoError(t, session3.Begin())
+	session3.BeforeCommit(func() error {
+		return errBeforeCommit
+	}).OnCommit(func() {
+		committed++
+	}).OnRollback(func() {
+		rollbacked++
+	})
+
+	_, err = session3.Insert(&Userinfo{Username: "callback3"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, errBeforeCommit, session3.Commit())
+	assert.EqualValues(t, 1, committed)
+	assert.EqualValues(t, 2, rollbacked)
+
+	total, err := testEngine.Count(new(Userinfo))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+
+	// not in a transaction, OnCommit will be invoked immediately
+	testEngine.NewSession().OnCommit(func() {
+		committed++
+	}).Close()
+	assert.EqualValues(t, 2, committed)
+}
@@ -28,7 +28,7 @@ func (session *Session) cacheUpdate(sqlStr string, args ...interface{}) error {
 	for _, filter := range session.Engine.dialect.Filters() {
 		newsql = filter.Do(newsql, session.Engine.dialect, session.Statement.RefTable)
 	}
-	session.Engine.logger.Debug("[cacheUpdate] new sql", oldhead, newsql)
+	session.getLogger().Debug("[cacheUpdate] new sql", oldhead, newsql)
 
 	var nStart int
 	if len(args) > 0 {
@@ -42,10 +42,10 @@ func (session *Session) cacheUpdate(sqlStr string, args ...interface{}) error {
 	table := session.Statement.RefTable
 	cacher := session.Engine.getCacher2(table)
 	tableName := session.Statement.TableName()
-	session.Engine.logger.Debug("[cacheUpdate] get cache sql", newsql, args[nStart:])
+	session.getLogger().Debug("[cacheUpdate] get cache sql", newsql, args[nStart:])
 	ids, err := core.GetCacheSql(cacher, tableName, newsql, args[nStart:])
 	if err != nil {
-		rows, err := session.DB().Query(newsql, args[nStart:]...)
+		rows, err := session.dbQuery(newsql, args[nStart:]...)
 		if err != nil {
 			return err
 		}
@@ -75,7 +75,7 @@ func (session *Session) cacheUpdate(sqlStr string, args ...interface{}) error {
 
 			ids = append(ids, pk)
 		}
-		session.Engine.logger.Debug("[cacheUpdate] find updated id", ids)
+		session.getLogger().Debug("[cacheUpdate] find updated id", ids)
 	} /*else {
 	    session.Engine.LogDebug("[xorm:cacheUpdate] del cached sql:", tableName, newsql, args)
 	    cacher.DelIds(tableName, genSqlKey(newsql, args))
@@ -106,16 +106,16 @@ func (session *Session) cacheUpdate(sqlStr string, args ...interface{}) error {
 				} else if strings.Contains(colName, session.Engine.QuoteStr()) {
 					colName = strings.TrimSpace(strings.Replace(colName, session.Engine.QuoteStr(), "", -1))
 				} else {
-					session.Engine.logger.Debug("[cacheUpdate] cannot find column", tableName, colName)
+					session.getLogger().Debug("[cacheUpdate] cannot find column", tableName, colName)
 					return ErrCacheFailed
 				}
 
 				if col := table.GetColumn(colName); col != nil {
 					fieldValue, err := col.ValueOf(bean)
 					if err != nil {
-						session.Engine.logger.Error(err)
+						session.getLogger().Error(err)
 					} else {
-						session.Engine.logger.Debug("[cacheUpdate] set bean field", bean, colName, fieldValue.Interface())
+						session.getLogger().Debug("[cacheUpdate] set bean field", bean, colName, fieldValue.Interface())
 						if col.IsVersion && session.Statement.checkVersion {
 							fieldValue.SetInt(fieldValue.Int() + 1)
 						} else {
@@ -123,16 +123,16 @@ func (session *Session) cacheUpdate(sqlStr string, args ...interface{}) error {
 						}
 					}
 				} else {
-					session.Engine.logger.Errorf("[cacheUpdate] ERROR: column %v is not table %v's",
+					session.getLogger().Errorf("[cacheUpdate] ERROR: column %v is not table %v's",
 						colName, table.Name)
 				}
 			}
 
-			session.Engine.logger.Debug("[cacheUpdate] update cache", tableName, id, bean)
+			session.getLogger().Debug("[cacheUpdate] update cache", tableName, id, bean)
 			cacher.PutBean(tableName, sid, bean)
 		}
 	}
-	session.Engine.logger.Debug("[cacheUpdate] clear cached table sql:", tableName)
+	session.getLogger().Debug("[cacheUpdate] clear cached table sql:", tableName)
 	cacher.ClearIds(tableName)
 	return nil
 }
@@ -144,6 +144,11 @@ func (session *Session) cacheUpdate(sqlStr string, args ...interface{}) error {
 //         You should call UseBool if you have bool to use.
 //        2.float32 & float64 may be not inexact as conditions
 func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int64, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
 	defer session.resetStatement()
 	if session.IsAutoClose {
 		defer session.Close()
@@ -160,14 +165,16 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 		closure(bean)
 	}
 	cleanupProcessorsClosures(&session.beforeClosures) // cleanup after used
-	if processor, ok := interface{}(bean).(BeforeUpdateProcessor); ok {
-		processor.BeforeUpdate()
+	if err := session.beforeUpdate(bean); err != nil {
+		return 0, err
 	}
 	// --
 
 	var err error
 	var isMap = t.Kind() == reflect.Map
 	var isStruct = t.Kind() == reflect.Struct
+	var mapColumns map[string]bool
+	var mapVersion interface{}
 	if isStruct {
 		if err := session.Statement.setRefValue(v); err != nil {
 			return 0, err
@@ -177,6 +184,14 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 			return 0, ErrTableNotFound
 		}
 
+		if len(session.Statement.fieldMask) > 0 {
+			maskCols, err := session.Statement.fieldMaskColumns(session.Statement.RefTable)
+			if err != nil {
+				return 0, err
+			}
+			session.Statement.Cols(maskCols...)
+		}
+
 		if session.Statement.ColumnStr == "" {
 			colNames, args = buildUpdates(session.Engine, session.Statement.RefTable, bean, false, false,
 				false, false, session.Statement.allUseBool, session.Statement.useAllCols,
@@ -188,14 +203,35 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 				return 0, err
 			}
 		}
+
+		// only the columns written are validated
+		if err := session.validateFields(bean, updatedFields(session.Statement.RefTable, colNames)); err != nil {
+			return 0, err
+		}
 	} else if isMap {
-		colNames = make([]string, 0)
-		args = make([]interface{}, 0)
-		bValue := reflect.Indirect(reflect.ValueOf(bean))
+		var names []string
+		var values []interface{}
+		names, values, err = session.mapValues(session.Statement.RefTable, reflect.Indirect(reflect.ValueOf(bean)))
+		if err != nil {
+			return 0, err
+		}
 
-		for _, v := range bValue.MapKeys() {
-			colNames = append(colNames, session.Engine.Quote(v.String())+" = ?")
-			args = append(args, bValue.MapIndex(v).Interface())
+		colNames = make([]string, 0, len(names))
+		args = make([]interface{}, 0, len(values))
+		mapColumns = make(map[string]bool, len(names))
+		for i, name := range names {
+			// a map can't move the records to another tenant
+			if err := session.Statement.checkTenantMapValue(name, values[i]); err != nil {
+				return 0, err
+			}
+			mapColumns[strings.ToLower(name)] = true
+			// the version of the map is a condition
+			if table := session.Statement.RefTable; table != nil && strings.EqualFold(name, table.Version) {
+				mapVersion = values[i]
+				continue
+			}
+			colNames = append(colNames, session.Engine.Quote(name)+" = ?")
+			args = append(args, values[i])
 		}
 	} else {
 		return 0, ErrParamsType
@@ -203,10 +239,10 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 
 	table := session.Statement.RefTable
 
-	if session.Statement.UseAutoTime && table != nil && table.Updated != "" {
+	if session.Statement.UseAutoTime && table != nil && table.Updated != "" && !mapColumns[strings.ToLower(table.Updated)] {
 		colNames = append(colNames, session.Engine.Quote(table.Updated)+" = ?")
 		col := table.UpdatedColumn()
-		val, t := session.Engine.NowTime2(col.SQLType.Name)
+		val, t := session.Engine.nowTime(col)
 		args = append(args, val)
 
 		var colName = col.Name
@@ -235,7 +271,17 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 	for _, v := range exprColumns {
 		colNames = append(colNames, session.Engine.Quote(v.colName)+" = "+v.expr)
 	}
+	//for update action to like "column = JSON_SET(column, path, ?)"
+	jsonSets, jsonArgs, err := session.Statement.genJSONPathSets()
+	if err != nil {
+		return 0, err
+	}
+	colNames = append(colNames, jsonSets...)
+	args = append(args, jsonArgs...)
 
+	if err := session.Statement.checkIDParam(); err != nil {
+		return 0, err
+	}
 	session.Statement.processIDParam()
 
 	var autoCond builder.Cond
@@ -253,18 +299,44 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 	var sqlStr string
 	var condArgs []interface{}
 	var condSQL string
-	cond := session.Statement.cond.And(autoCond)
+	cond := session.Statement.cond.And(autoCond, session.Statement.mandatoryCond())
 
 	var doIncVer = (table != nil && table.Version != "" && session.Statement.checkVersion)
 	var verValue *reflect.Value
-	if doIncVer {
+	var newVersion reflect.Value
+	var versionCond = cond
+	if doIncVer && isMap {
+		if mapVersion != nil {
+			cond = cond.And(builder.Eq{session.Engine.Quote(table.Version): mapVersion})
+		}
+		colNames = append(colNames, session.Engine.Quote(table.Version)+" = "+session.Engine.Quote(table.Version)+" + 1")
+	} else if doIncVer {
 		verValue, err = table.VersionColumn().ValueOf(bean)
 		if err != nil {
 			return 0, err
 		}
 
-		cond = cond.And(builder.Eq{session.Engine.Quote(table.Version): verValue.Interface()})
-		colNames = append(colNames, session.Engine.Quote(table.Version)+" = "+session.Engine.Quote(table.Version)+" + 1")
+		if isCounterVersion(verValue.Type()) {
+			cond = cond.And(builder.Eq{session.Engine.Quote(table.Version): verValue.Interface()})
+			colNames = append(colNames, session.Engine.Quote(table.Version)+" = "+session.Engine.Quote(table.Version)+" + 1")
+		} else {
+			// the timestamps and the tokens are replaced by new ones
+			verCol := table.VersionColumn()
+			oldVersion, err := session.value2Interface(verCol, *verValue)
+			if err != nil {
+				return 0, err
+			}
+			if newVersion, err = session.newVersion(verValue.Type()); err != nil {
+				return 0, err
+			}
+			arg, err := session.value2Interface(verCol, newVersion)
+			if err != nil {
+				return 0, err
+			}
+			cond = cond.And(builder.Eq{session.Engine.Quote(table.Version): oldVersion})
+			colNames = append(colNames, session.Engine.Quote(table.Version)+" = ?")
+			args = append(args, arg)
+		}
 	}
 
 	condSQL, condArgs, _ = builder.ToSQL(cond)
@@ -320,17 +392,39 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 		strings.Join(colNames, ", "),
 		condSQL)
 
-	res, err := session.exec(sqlStr, append(args, condArgs...)...)
+	endAudit, err := session.beginAudit(table)
 	if err != nil {
 		return 0, err
-	} else if doIncVer {
-		if verValue != nil && verValue.IsValid() && verValue.CanSet() {
-			verValue.SetInt(verValue.Int() + 1)
+	}
+	before, err := session.beforeChange(table, fmt.Sprintf("SELECT %v* FROM %v %v",
+		top, session.Engine.Quote(session.Statement.TableName()), condSQL), condArgs)
+	if err != nil {
+		return 0, endAudit(err)
+	}
+
+	res, err := session.exec(sqlStr, append(args, condArgs...)...)
+	if err != nil {
+		return 0, endAudit(err)
+	} else if doIncVer && verValue != nil {
+		if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+			if err := session.versionConflict(table, session.Statement.TableName(), versionCond, verValue.Interface()); err != nil {
+				return 0, endAudit(err)
+			}
+		} else if verValue.IsValid() && verValue.CanSet() {
+			if newVersion.IsValid() {
+				verValue.Set(newVersion)
+			} else {
+				verValue.SetInt(verValue.Int() + 1)
+			}
 		}
 	}
+	if err := endAudit(session.captureChange(ChangeUpdate, table, before)); err != nil {
+		return 0, err
+	}
 
 	if table != nil {
-		if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
+		if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache &&
+			(session.Statement.idParam == nil || !session.writeThrough(table, *session.Statement.idParam)) {
 			cacher.ClearIds(session.Statement.TableName())
 			cacher.ClearBeans(session.Statement.TableName())
 		}
@@ -341,10 +435,7 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 		for _, closure := range session.afterClosures {
 			closure(bean)
 		}
-		if processor, ok := interface{}(bean).(AfterUpdateProcessor); ok {
-			session.Engine.logger.Debug("[event]", session.Statement.TableName(), " has after update processor")
-			processor.AfterUpdate()
-		}
+		session.afterUpdate(bean)
 	} else {
 		lenAfterClosures := len(session.afterClosures)
 		if lenAfterClosures > 0 {
@@ -358,7 +449,7 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 			}
 
 		} else {
-			if _, ok := interface{}(bean).(AfterUpdateProcessor); ok {
+			if hasAfterUpdate(bean) {
 				session.afterUpdateBeans[bean] = nil
 			}
 		}
@@ -0,0 +1,54 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+// Package slogadapter logs the entries of xorm with log/slog
+package slogadapter
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go-xorm/core"
+	"github.com/go-xorm/xorm"
+)
+
+// Logger is a xorm.StructuredLogger writing to a slog.Logger
+type Logger struct {
+	logger *slog.Logger
+}
+
+var _ xorm.StructuredLogger = &Logger{}
+
+// New returns a structured logger writing to logger, slog.Default() is used
+// when it's nil
+func New(logger *slog.Logger) *Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Logger{logger}
+}
+
+// Log implements xorm.StructuredLogger
+func (l *Logger) Log(level core.LogLevel, msg string, fields ...xorm.LogField) {
+	attrs := make([]slog.Attr, len(fields))
+	for i, field := range fields {
+		attrs[i] = slog.Any(field.Key, field.Value)
+	}
+	l.logger.LogAttrs(context.Background(), slogLevel(level), msg, attrs...)
+}
+
+func slogLevel(level core.LogLevel) slog.Level {
+	switch level {
+	case core.LOG_DEBUG:
+		return slog.LevelDebug
+	case core.LOG_WARNING:
+		return slog.LevelWarn
+	case core.LOG_ERR:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
@@ -0,0 +1,44 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package slogadapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/go-xorm/xorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	logger.Log(core.LOG_ERR, "sql",
+		xorm.LogField{Key: xorm.LogFieldSQL, Value: "SELECT 1"},
+		xorm.LogField{Key: xorm.LogFieldSession, Value: uint64(3)},
+		xorm.LogField{Key: xorm.LogFieldError, Value: errors.New("failed")},
+	)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.EqualValues(t, "ERROR", entry["level"])
+	assert.EqualValues(t, "sql", entry["msg"])
+	assert.EqualValues(t, "SELECT 1", entry["sql"])
+	assert.EqualValues(t, 3, entry["session"])
+	assert.EqualValues(t, "failed", entry["error"])
+
+	// the debug entries are below the default level of the handler
+	buf.Reset()
+	logger.Log(core.LOG_DEBUG, "debug")
+	assert.EqualValues(t, 0, buf.Len())
+}
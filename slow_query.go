@@ -0,0 +1,109 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// SlowQuery is a statement which took longer than the slow query threshold
+type SlowQuery struct {
+	SQL      string
+	Args     []interface{}
+	Duration time.Duration
+	Err      error
+	// Caller is the file:line of the code outside of xorm which ran the
+	// statement
+	Caller string
+}
+
+// slowQueryLog is the slow query settings of an engine
+type slowQueryLog struct {
+	threshold time.Duration
+	sampling  float64
+	handler   func(SlowQuery)
+}
+
+// SetSlowQueryThreshold reports the statements taking threshold or longer,
+// 0 disables the slow query log. They are logged as warnings unless a
+// handler is set by SetSlowQueryHandler.
+func (engine *Engine) SetSlowQueryThreshold(threshold time.Duration) {
+	engine.slowQuery.threshold = threshold
+}
+
+// SetSlowQueryHandler sets the function the slow statements are reported
+// to, nil logs them
+func (engine *Engine) SetSlowQueryHandler(handler func(SlowQuery)) {
+	engine.slowQuery.handler = handler
+}
+
+// SetSlowQuerySampling reports only the given fraction of the slow
+// statements, picked randomly, to limit the volume. A rate out of (0, 1)
+// reports all of them.
+func (engine *Engine) SetSlowQuerySampling(rate float64) {
+	engine.slowQuery.sampling = rate
+}
+
+// slowQueryHook is the SQL hook reporting the slow statements of an engine
+type slowQueryHook struct {
+	engine *Engine
+}
+
+func (h slowQueryHook) BeforeSQL(event *SQLEvent) {}
+
+func (h slowQueryHook) AfterSQL(event *SQLEvent) {
+	log := h.engine.slowQuery
+	if event.Duration < log.threshold {
+		return
+	}
+	if log.sampling > 0 && log.sampling < 1 && rand.Float64() >= log.sampling {
+		return
+	}
+
+	slow := SlowQuery{
+		SQL:      event.SQL,
+		Args:     event.Args,
+		Duration: event.Duration,
+		Err:      event.Err,
+		Caller:   callerOutsideXorm(),
+	}
+	if log.handler != nil {
+		log.handler(slow)
+		return
+	}
+	if len(slow.Args) > 0 {
+		h.engine.logger.Warnf("[SLOW SQL] %s %v - took: %v at %s", slow.SQL, slow.Args, slow.Duration, slow.Caller)
+	} else {
+		h.engine.logger.Warnf("[SLOW SQL] %s - took: %v at %s", slow.SQL, slow.Duration, slow.Caller)
+	}
+}
+
+// xormDir is the directory of the sources of xorm
+var xormDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// callerOutsideXorm returns the file:line of the first caller which is not
+// in the sources of xorm, the tests of xorm are callers
+func callerOutsideXorm() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if frame.File != "" && (filepath.Dir(frame.File) != xormDir || strings.HasSuffix(frame.File, "_test.go")) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
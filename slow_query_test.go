@@ -0,0 +1,51 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type SlowUser struct {
+	Id   int64
+	Name string
+}
+
+func TestSlowQuery(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(SlowUser)))
+
+	var slows []SlowQuery
+	testEngine.SetSlowQueryThreshold(time.Nanosecond)
+	testEngine.SetSlowQueryHandler(func(slow SlowQuery) {
+		slows = append(slows, slow)
+	})
+	defer func() { testEngine.slowQuery = slowQueryLog{} }()
+
+	_, err := testEngine.Insert(&SlowUser{Name: "lunny"})
+	assert.NoError(t, err)
+	if assert.EqualValues(t, 1, len(slows)) {
+		assert.True(t, strings.HasPrefix(strings.ToUpper(slows[0].SQL), "INSERT"))
+		assert.EqualValues(t, []interface{}{"lunny"}, slows[0].Args)
+		assert.True(t, slows[0].Duration > 0)
+		assert.NoError(t, slows[0].Err)
+		assert.True(t, strings.Contains(slows[0].Caller, "slow_query_test.go:"), slows[0].Caller)
+	}
+
+	slows = nil
+	testEngine.SetSlowQueryThreshold(time.Hour)
+	var users []SlowUser
+	assert.NoError(t, testEngine.Find(&users))
+	assert.EqualValues(t, 0, len(slows))
+
+	testEngine.SetSlowQueryThreshold(time.Nanosecond)
+	testEngine.SetSlowQuerySampling(1e-12)
+	assert.NoError(t, testEngine.Find(&users))
+	assert.EqualValues(t, 0, len(slows))
+}
@@ -0,0 +1,186 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+)
+
+// deletedMarker is how the column of the tag "deleted" marks the deleted
+// rows, it depends on the type of the field
+type deletedMarker int
+
+const (
+	// the time of the deletion, NULL or the zero time when not deleted
+	deletedTime deletedMarker = iota
+	// a bool, true when deleted
+	deletedFlag
+	// the unix time of the deletion, 0 when not deleted
+	deletedEpoch
+)
+
+func deletedMarkerOf(table *core.Table, col *core.Column) deletedMarker {
+	t := table.Type
+	for _, name := range strings.Split(col.FieldName, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return deletedTime
+		}
+		t = field.Type
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return deletedFlag
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return deletedEpoch
+	}
+	return deletedTime
+}
+
+// notDeletedCond returns the condition on the deleted column col, named
+// colName in the SQL, of the rows which are not deleted
+func (engine *Engine) notDeletedCond(table *core.Table, col *core.Column, colName string) builder.Cond {
+	switch deletedMarkerOf(table, col) {
+	case deletedFlag:
+		return builder.IsNull{colName}.Or(builder.Eq{colName: false})
+	case deletedEpoch:
+		return builder.IsNull{colName}.Or(builder.Eq{colName: 0})
+	}
+	if engine.dialect.DBType() == core.MSSQL {
+		return builder.IsNull{colName}
+	}
+	return builder.IsNull{colName}.Or(builder.Eq{colName: zeroTime1})
+}
+
+// deletedValue returns the value of the deleted column col of the rows
+// deleted now, and sets it to the field of a bean
+func (engine *Engine) deletedValue(table *core.Table, col *core.Column) (interface{}, func(bean interface{})) {
+	switch deletedMarkerOf(table, col) {
+	case deletedFlag:
+		return true, func(bean interface{}) {
+			setColumnValue(bean, col, true)
+		}
+	case deletedEpoch:
+		t := engine.now()
+		return t.Unix(), func(bean interface{}) {
+			setColumnTime(bean, col, t)
+		}
+	}
+	val, t := engine.nowTime(col)
+	return val, func(bean interface{}) {
+		setColumnTime(bean, col, t)
+	}
+}
+
+// restoredValue returns the value of the deleted column col of the rows
+// which are not deleted
+func (engine *Engine) restoredValue(table *core.Table, col *core.Column) interface{} {
+	switch deletedMarkerOf(table, col) {
+	case deletedFlag:
+		return false
+	case deletedEpoch:
+		return 0
+	}
+	if col.Nullable {
+		return nil
+	}
+	return zeroTime1
+}
+
+func setColumnValue(bean interface{}, col *core.Column, value interface{}) {
+	v, err := col.ValueOf(bean)
+	if err != nil || !v.CanSet() {
+		return
+	}
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(reflect.ValueOf(value).Convert(v.Type().Elem()))
+		v.Set(p)
+		return
+	}
+	v.Set(reflect.ValueOf(value).Convert(v.Type()))
+}
+
+// Restore undeletes the soft deleted records, bean's non-empty fields but
+// the deleted one are conditions. The deleted field of bean is reset.
+func (session *Session) Restore(bean interface{}) (int64, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
+	defer session.resetStatement()
+	if session.IsAutoClose {
+		defer session.Close()
+	}
+
+	if err := session.Statement.setRefValue(rValue(bean)); err != nil {
+		return 0, err
+	}
+	table := session.Statement.RefTable
+	col := table.DeletedColumn()
+	if col == nil {
+		return 0, ErrNoDeletedColumn
+	}
+	// the deleted field is not a condition
+	if v, err := col.ValueOf(bean); err == nil && v.CanSet() {
+		v.Set(reflect.Zero(v.Type()))
+	}
+
+	session.Statement.unscoped = true
+	condSQL, condArgs, err := session.Statement.genConds(bean)
+	if err != nil {
+		return 0, err
+	}
+	if !session.Statement.callerConds {
+		return 0, ErrNeedDeletedCond
+	}
+
+	tableName := session.Engine.Quote(session.Statement.TableName())
+	endAudit, err := session.beginAudit(table)
+	if err != nil {
+		return 0, err
+	}
+	before, err := session.beforeChange(table, fmt.Sprintf("SELECT * FROM %v WHERE %v", tableName, condSQL), condArgs)
+	if err != nil {
+		return 0, endAudit(err)
+	}
+
+	value := session.Engine.restoredValue(table, col)
+	sqlStr := fmt.Sprintf("UPDATE %v SET %v = ? WHERE %v", tableName, session.Engine.Quote(col.Name), condSQL)
+	res, err := session.exec(sqlStr, append([]interface{}{value}, condArgs...)...)
+	if err != nil {
+		return 0, endAudit(err)
+	}
+
+	if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
+		cacher.ClearIds(session.Statement.TableName())
+		cacher.ClearBeans(session.Statement.TableName())
+	}
+	if err := endAudit(session.captureChange(ChangeUpdate, table, before)); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Restore undeletes the soft deleted records, see Session.Restore
+func (engine *Engine) Restore(bean interface{}) (int64, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.Restore(bean)
+}
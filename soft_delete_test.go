@@ -0,0 +1,122 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type SoftDeleteTime struct {
+	Id        int64
+	Name      string
+	DeletedAt time.Time `xorm:"deleted"`
+}
+
+type SoftDeleteFlag struct {
+	Id      int64
+	Name    string
+	Deleted bool `xorm:"deleted"`
+}
+
+type SoftDeleteEpoch struct {
+	Id        int64
+	Name      string
+	DeletedAt int64 `xorm:"deleted"`
+}
+
+func TestSoftDeleteRestore(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(SoftDeleteTime)))
+
+	_, err := testEngine.Insert(&SoftDeleteTime{Name: "lunny"})
+	assert.NoError(t, err)
+
+	var deleted SoftDeleteTime
+	cnt, err := testEngine.Id(1).Delete(&deleted)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	assert.False(t, deleted.DeletedAt.IsZero())
+
+	has, err := testEngine.Id(1).Get(new(SoftDeleteTime))
+	assert.NoError(t, err)
+	assert.False(t, has)
+	has, err = testEngine.Id(1).Unscoped().Get(new(SoftDeleteTime))
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	cnt, err = testEngine.Id(1).Restore(&deleted)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	assert.True(t, deleted.DeletedAt.IsZero())
+
+	var user SoftDeleteTime
+	has, err = testEngine.Id(1).Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", user.Name)
+
+	_, err = testEngine.Restore(new(SoftDeleteTime))
+	assert.EqualValues(t, ErrNeedDeletedCond, err)
+	_, err = testEngine.Id(1).Restore(new(Userinfo))
+	assert.EqualValues(t, ErrNoDeletedColumn, err)
+}
+
+func TestSoftDeleteMarkers(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(SoftDeleteFlag), new(SoftDeleteEpoch)))
+
+	_, err := testEngine.Insert(&SoftDeleteFlag{Name: "lunny"}, &SoftDeleteFlag{Name: "xlw"})
+	assert.NoError(t, err)
+
+	var flag SoftDeleteFlag
+	_, err = testEngine.Id(1).Delete(&flag)
+	assert.NoError(t, err)
+	assert.True(t, flag.Deleted)
+
+	var flags []SoftDeleteFlag
+	assert.NoError(t, testEngine.Find(&flags))
+	if assert.EqualValues(t, 1, len(flags)) {
+		assert.EqualValues(t, "xlw", flags[0].Name)
+	}
+	flags = nil
+	assert.NoError(t, testEngine.Unscoped().Asc("id").Find(&flags))
+	if assert.EqualValues(t, 2, len(flags)) {
+		assert.True(t, flags[0].Deleted)
+	}
+
+	_, err = testEngine.Id(1).Restore(&flag)
+	assert.NoError(t, err)
+	total, err := testEngine.Count(new(SoftDeleteFlag))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+
+	_, err = testEngine.Insert(&SoftDeleteEpoch{Name: "lunny"})
+	assert.NoError(t, err)
+
+	start := time.Now().Unix()
+	var epoch SoftDeleteEpoch
+	_, err = testEngine.Id(1).Delete(&epoch)
+	assert.NoError(t, err)
+	assert.True(t, epoch.DeletedAt >= start)
+
+	has, err := testEngine.Id(1).Get(new(SoftDeleteEpoch))
+	assert.NoError(t, err)
+	assert.False(t, has)
+
+	var unscoped SoftDeleteEpoch
+	has, err = testEngine.Id(1).Unscoped().Get(&unscoped)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, epoch.DeletedAt, unscoped.DeletedAt)
+
+	_, err = testEngine.Id(1).Restore(new(SoftDeleteEpoch))
+	assert.NoError(t, err)
+	has, err = testEngine.Id(1).Get(new(SoftDeleteEpoch))
+	assert.NoError(t, err)
+	assert.True(t, has)
+}
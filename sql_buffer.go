@@ -0,0 +1,44 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bytes"
+	"sync"
+)
+
+// the buffers grown over it, by the inserts of many beans, aren't pooled
+const maxPooledSQLBuffer = 64 << 10
+
+// sqlBuffers are the buffers the SQLs are written to
+var sqlBuffers = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// getSQLBuffer returns an empty buffer of the pool, to put back by
+// putSQLBuffer once its content is copied
+func getSQLBuffer() *bytes.Buffer {
+	return sqlBuffers.Get().(*bytes.Buffer)
+}
+
+func putSQLBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledSQLBuffer {
+		return
+	}
+	buf.Reset()
+	sqlBuffers.Put(buf)
+}
+
+// writeJoin writes the strings of a separated by sep, as strings.Join
+func writeJoin(buf *bytes.Buffer, a []string, sep string) {
+	for i, s := range a {
+		if i > 0 {
+			buf.WriteString(sep)
+		}
+		buf.WriteString(s)
+	}
+}
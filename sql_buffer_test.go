@@ -0,0 +1,47 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLBuffer(t *testing.T) {
+	buf := getSQLBuffer()
+	assert.EqualValues(t, 0, buf.Len())
+	writeJoin(buf, []string{"a", "b", "c"}, ", ")
+	assert.EqualValues(t, "a, b, c", buf.String())
+	putSQLBuffer(buf)
+
+	// the big buffers aren't pooled, they're dropped
+	buf = getSQLBuffer()
+	buf.WriteString(strings.Repeat("?", maxPooledSQLBuffer+1))
+	putSQLBuffer(buf)
+	assert.EqualValues(t, maxPooledSQLBuffer+1, buf.Len())
+}
+
+func BenchmarkInsertMultiSQL(b *testing.B) {
+	b.StopTimer()
+	if err := prepareEngine(); err != nil {
+		b.Fatal(err)
+	}
+	if err := testEngine.Sync2(new(ScanPrimitive)); err != nil {
+		b.Fatal(err)
+	}
+	var beans = make([]ScanPrimitive, 50)
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		for j := range beans {
+			beans[j].Id = 0
+		}
+		if _, err := testEngine.Insert(&beans); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
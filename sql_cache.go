@@ -0,0 +1,168 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-xorm/core"
+)
+
+// SQLCacheStats is the statistics of the cache of the generated SQLs
+type SQLCacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Size      int
+}
+
+// sqlCacheKey is the shape of a generated SQL: the table, the columns and
+// the clauses, the conditions being their SQL with the placeholders of
+// their args
+type sqlCacheKey struct {
+	columnStr bool // the key of a column list, else of a select
+	table     *core.Table
+	tableName string
+	alias     string
+	join      string
+	columns   string
+	cond      string
+	groupBy   string
+	having    string
+	orderBy   string
+	limit     int
+	start     int
+	distinct  bool
+	forUpdate bool
+	eager     bool
+}
+
+type sqlCacheEntry struct {
+	key sqlCacheKey
+	sql string
+}
+
+// sqlCache is a LRU cache of the generated SQLs shared by the sessions of
+// an engine
+type sqlCache struct {
+	// keep the int64 fields first for the alignment of atomic operations
+	hits      int64
+	misses    int64
+	evictions int64
+
+	mutex sync.Mutex
+	size  int
+	list  *list.List
+	index map[sqlCacheKey]*list.Element
+}
+
+// SetSQLCacheSize caches the size most recently generated column lists
+// and selects, so the queries of the same shape skip their building and
+// their quoting. It's disabled by default and by a size of 0.
+func (engine *Engine) SetSQLCacheSize(size int) {
+	if size <= 0 {
+		engine.sqlCache = nil
+		return
+	}
+	engine.sqlCache = &sqlCache{
+		size:  size,
+		list:  list.New(),
+		index: make(map[sqlCacheKey]*list.Element),
+	}
+}
+
+// SQLCacheStats returns the statistics of the cache of the generated SQLs
+func (engine *Engine) SQLCacheStats() SQLCacheStats {
+	c := engine.sqlCache
+	if c == nil {
+		return SQLCacheStats{}
+	}
+	c.mutex.Lock()
+	size := c.list.Len()
+	c.mutex.Unlock()
+	return SQLCacheStats{
+		Hits:      atomic.LoadInt64(&c.hits),
+		Misses:    atomic.LoadInt64(&c.misses),
+		Evictions: atomic.LoadInt64(&c.evictions),
+		Size:      size,
+	}
+}
+
+// get returns the SQL generated by gen for the shape key, gen is called on
+// a miss
+func (c *sqlCache) get(key sqlCacheKey, gen func() string) string {
+	c.mutex.Lock()
+	if el, ok := c.index[key]; ok {
+		c.list.MoveToBack(el)
+		c.mutex.Unlock()
+		atomic.AddInt64(&c.hits, 1)
+		return el.Value.(*sqlCacheEntry).sql
+	}
+	c.mutex.Unlock()
+	atomic.AddInt64(&c.misses, 1)
+
+	sqlStr := gen()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.index[key]; ok {
+		return sqlStr
+	}
+	for c.list.Len() >= c.size {
+		entry := c.list.Remove(c.list.Front()).(*sqlCacheEntry)
+		delete(c.index, entry.key)
+		atomic.AddInt64(&c.evictions, 1)
+	}
+	c.index[key] = c.list.PushBack(&sqlCacheEntry{key, sqlStr})
+	return sqlStr
+}
+
+// columnStrKey returns the shape of the columns of genColumnStr
+func (statement *Statement) columnStrKey() sqlCacheKey {
+	key := sqlCacheKey{
+		columnStr: true,
+		table:     statement.RefTable,
+		columns:   strings.Join(statement.scopes, ","),
+		eager:     statement.eager,
+	}
+	if statement.JoinStr != "" {
+		key.tableName = statement.TableName()
+		key.alias = statement.TableAlias
+		key.join = "join"
+	}
+	if statement.OmitStr != "" {
+		// the columns of the map are omitted, the selected ones too
+		var names = make([]string, 0, len(statement.columnMap))
+		for name := range statement.columnMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		key.cond = strings.Join(names, ",")
+	}
+	return key
+}
+
+// selectKey returns the shape of the select of genSelectSQL
+func (statement *Statement) selectKey(columnStr, condSQL string) sqlCacheKey {
+	return sqlCacheKey{
+		table:     statement.RefTable,
+		tableName: statement.TableName(),
+		alias:     statement.TableAlias,
+		join:      statement.JoinStr,
+		columns:   columnStr,
+		cond:      condSQL,
+		groupBy:   statement.GroupByStr,
+		having:    statement.HavingStr,
+		orderBy:   statement.OrderStr,
+		limit:     statement.LimitN,
+		start:     statement.Start,
+		distinct:  statement.IsDistinct,
+		forUpdate: statement.IsForUpdate,
+	}
+}
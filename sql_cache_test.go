@@ -0,0 +1,59 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type SQLCacheUser struct {
+	Id   int64
+	Name string
+	Age  int
+}
+
+func TestSQLCache(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(SQLCacheUser))
+
+	testEngine.SetSQLCacheSize(2)
+	defer testEngine.SetSQLCacheSize(0)
+
+	_, err := testEngine.Insert(&SQLCacheUser{Name: "a", Age: 1}, &SQLCacheUser{Name: "b", Age: 2})
+	assert.NoError(t, err)
+
+	for _, age := range []int{1, 2} {
+		var users []SQLCacheUser
+		assert.NoError(t, testEngine.Where("age = ?", age).Find(&users))
+		if assert.EqualValues(t, 1, len(users)) {
+			assert.EqualValues(t, age, users[0].Age)
+		}
+	}
+	// the column list and the select are cached by the first query
+	stats := testEngine.SQLCacheStats()
+	assert.EqualValues(t, 2, stats.Misses)
+	assert.EqualValues(t, 2, stats.Hits)
+	assert.EqualValues(t, 2, stats.Size)
+
+	// an other shape
+	var users []SQLCacheUser
+	assert.NoError(t, testEngine.Where("age = ?", 1).Omit("name").Find(&users))
+	if assert.EqualValues(t, 1, len(users)) {
+		assert.EqualValues(t, "", users[0].Name)
+	}
+	stats = testEngine.SQLCacheStats()
+	assert.EqualValues(t, 4, stats.Misses)
+	assert.EqualValues(t, 2, stats.Evictions)
+
+	users = nil
+	assert.NoError(t, testEngine.Where("age = ?", 1).Limit(1).Find(&users))
+	if assert.EqualValues(t, 1, len(users)) {
+		assert.EqualValues(t, "a", users[0].Name)
+	}
+	// the column list was evicted too
+	assert.EqualValues(t, 6, testEngine.SQLCacheStats().Misses)
+}
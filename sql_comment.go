@@ -0,0 +1,100 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type sqlCommentKey struct{}
+
+// WithSQLComment returns a context whose statements are commented with key
+// and value by the SQL commenter, as the route or the controller handling
+// a request
+func WithSQLComment(ctx context.Context, key, value string) context.Context {
+	old := sqlCommentTags(ctx)
+	tags := make(map[string]string, len(old)+1)
+	for k, v := range old {
+		tags[k] = v
+	}
+	tags[key] = value
+	return context.WithValue(ctx, sqlCommentKey{}, tags)
+}
+
+func sqlCommentTags(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(sqlCommentKey{}).(map[string]string)
+	return tags
+}
+
+// SQLCommenter returns an interceptor appending to every statement a
+// comment of tags, formatted as specified by sqlcommenter so the database
+// tools can correlate the statements to the application. The tags are the
+// given ones, the ones added to the context of the session by
+// WithSQLComment and the ones fromContext returns, as the traceparent of
+// the span of the context, the later ones winning. fromContext may be nil.
+// The statements already commented are kept as they are. A comment varying
+// by request defeats the prepared statements, Prepare should not be used.
+func SQLCommenter(tags map[string]string, fromContext func(ctx context.Context) map[string]string) Interceptor {
+	return InterceptorFunc(func(inv *Invocation, next Handler) error {
+		if strings.Contains(inv.SQL, "/*") {
+			return next(inv)
+		}
+
+		all := make(map[string]string, len(tags))
+		for k, v := range tags {
+			all[k] = v
+		}
+		ctx := inv.Session.Ctx()
+		for k, v := range sqlCommentTags(ctx) {
+			all[k] = v
+		}
+		if fromContext != nil {
+			for k, v := range fromContext(ctx) {
+				all[k] = v
+			}
+		}
+		inv.SQL = appendSQLComment(inv.SQL, all)
+		return next(inv)
+	})
+}
+
+// appendSQLComment appends the comment of tags to sqlStr, the keys are
+// sorted and the keys and the values are URL encoded
+func appendSQLComment(sqlStr string, tags map[string]string) string {
+	if len(tags) == 0 {
+		return sqlStr
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return sqlStr
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = sqlCommentEscape(k) + "='" + sqlCommentEscape(tags[k]) + "'"
+	}
+	comment := "/*" + strings.Join(pairs, ",") + "*/"
+
+	// the comment goes before the terminating semicolon
+	trimmed := strings.TrimRight(sqlStr, " \t\r\n")
+	if strings.HasSuffix(trimmed, ";") {
+		return trimmed[:len(trimmed)-1] + " " + comment + ";"
+	}
+	return trimmed + " " + comment
+}
+
+func sqlCommentEscape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
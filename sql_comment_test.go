@@ -0,0 +1,60 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type CommentedUser struct {
+	Id   int64
+	Name string
+}
+
+func TestAppendSQLComment(t *testing.T) {
+	assert.EqualValues(t, "SELECT 1", appendSQLComment("SELECT 1", nil))
+	assert.EqualValues(t, "SELECT 1 /*application='app',route='%2Fusers%2F%7Bid%7D'*/;",
+		appendSQLComment("SELECT 1; ", map[string]string{"route": "/users/{id}", "application": "app", "empty": ""}))
+	assert.EqualValues(t, "SELECT 1 /*tracestate='congo%3Dt61rcWkgMzE%2Crojo%3D00f067aa0ba902b7',user='it%27s%20me'*/",
+		appendSQLComment("SELECT 1", map[string]string{"tracestate": "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7", "user": "it's me"}))
+}
+
+func TestSQLCommenter(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(CommentedUser)))
+
+	testEngine.Use(SQLCommenter(map[string]string{"application": "app"}, func(ctx context.Context) map[string]string {
+		return map[string]string{"traceparent": "00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01"}
+	}))
+	defer func() { testEngine.interceptors = nil }()
+
+	var sqls []string
+	testEngine.AddSQLHook(SQLHookFuncs{
+		After: func(event *SQLEvent) {
+			sqls = append(sqls, event.SQL)
+		},
+	})
+	defer func() { testEngine.sqlHooks = nil }()
+
+	ctx := WithSQLComment(context.Background(), "route", "/users")
+	_, err := testEngine.Context(ctx).Insert(&CommentedUser{Name: "lunny"})
+	assert.NoError(t, err)
+	var users []CommentedUser
+	assert.NoError(t, testEngine.Find(&users))
+	assert.EqualValues(t, 1, len(users))
+
+	if assert.EqualValues(t, 2, len(sqls)) {
+		assert.True(t, strings.HasSuffix(sqls[0], " /*application='app',route='%2Fusers',"+
+			"traceparent='00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01'*/"), sqls[0])
+		assert.True(t, strings.HasSuffix(sqls[1], " /*application='app',"+
+			"traceparent='00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01'*/"), sqls[1])
+		// the comments don't change the fingerprints
+		assert.EqualValues(t, Fingerprint(strings.SplitN(sqls[1], " /*", 2)[0]), Fingerprint(sqls[1]))
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SQLEvent is a statement executed by a session, as seen by the SQL hooks
+type SQLEvent struct {
+	Session *Session
+	Op      Operation
+	SQL     string
+	Args    []interface{}
+	// Bean is the bean the statement is mapped to, nil for the raw SQLs
+	Bean interface{}
+
+	// Start, Duration, Result and Err are set once the statement is
+	// executed. Duration is the time the database took to answer, the rows
+	// of a query are not scanned yet.
+	Start    time.Time
+	Duration time.Duration
+	Result   sql.Result
+	// Err is the error of the statement, AfterSQL may replace it to enrich
+	// the error returned to the caller
+	Err error
+}
+
+// SQLHook is called before and after every statement executed by the
+// database. It sees the SQL and the args as rewritten by the interceptors,
+// and is not called for the statements they short-circuited.
+type SQLHook interface {
+	BeforeSQL(event *SQLEvent)
+	AfterSQL(event *SQLEvent)
+}
+
+// SQLHookFuncs is a SQLHook of functions, a nil one is not called
+type SQLHookFuncs struct {
+	Before func(event *SQLEvent)
+	After  func(event *SQLEvent)
+}
+
+// BeforeSQL implements SQLHook
+func (h SQLHookFuncs) BeforeSQL(event *SQLEvent) {
+	if h.Before != nil {
+		h.Before(event)
+	}
+}
+
+// AfterSQL implements SQLHook
+func (h SQLHookFuncs) AfterSQL(event *SQLEvent) {
+	if h.After != nil {
+		h.After(event)
+	}
+}
+
+// AddSQLHook adds hooks called around every statement of the engine. It
+// should be called before the engine is used.
+func (engine *Engine) AddSQLHook(hooks ...SQLHook) {
+	engine.sqlHooks = append(engine.sqlHooks, hooks...)
+}
+
+// AddSQLHook adds hooks called around every statement of the session until
+// it's closed, after the hooks of the engine
+func (session *Session) AddSQLHook(hooks ...SQLHook) *Session {
+	session.sqlHooks = append(session.sqlHooks, hooks...)
+	return session
+}
+
+// hookSQL wraps do with the audit sink, the query statistics, the
+// structured SQL log, the slow query log and the SQL hooks of the engine and
+// of the session. The BeforeSQL are called in order, the AfterSQL in
+// reverse order.
+func (session *Session) hookSQL(do Handler) Handler {
+	engine := session.Engine
+	slow := engine.slowQuery.threshold > 0
+	logged := session.structuredLogger() != nil && session.isShowSQL()
+	stats := engine.queryStats
+	sink := engine.auditSink
+	if len(engine.sqlHooks) == 0 && len(session.sqlHooks) == 0 && !slow && !logged && stats == nil && sink == nil {
+		return do
+	}
+
+	hooks := make([]SQLHook, 0, len(engine.sqlHooks)+len(session.sqlHooks)+4)
+	if sink != nil {
+		hooks = append(hooks, auditSinkHook{sink})
+	}
+	if stats != nil {
+		hooks = append(hooks, queryStatsHook{stats})
+	}
+	if logged {
+		hooks = append(hooks, sqlLogHook{engine})
+	}
+	if slow {
+		hooks = append(hooks, slowQueryHook{engine})
+	}
+	hooks = append(hooks, engine.sqlHooks...)
+	hooks = append(hooks, session.sqlHooks...)
+	return func(inv *Invocation) error {
+		event := &SQLEvent{
+			Session: inv.Session,
+			Op:      inv.Op,
+			SQL:     inv.SQL,
+			Args:    inv.Args,
+			Bean:    inv.Bean,
+		}
+		for _, hook := range hooks {
+			hook.BeforeSQL(event)
+		}
+
+		event.Start = time.Now()
+		event.Err = do(inv)
+		event.Duration = time.Since(event.Start)
+		event.Result = inv.Result
+
+		for i := len(hooks) - 1; i >= 0; i-- {
+			hooks[i].AfterSQL(event)
+		}
+		return event.Err
+	}
+}
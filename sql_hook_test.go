@@ -0,0 +1,105 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type HookedUser struct {
+	Id   int64
+	Name string
+}
+
+func TestSQLHook(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(HookedUser)))
+
+	var calls []string
+	var events []SQLEvent
+	testEngine.AddSQLHook(SQLHookFuncs{
+		Before: func(event *SQLEvent) {
+			calls = append(calls, "engine before")
+		},
+		After: func(event *SQLEvent) {
+			calls = append(calls, "engine after")
+			events = append(events, *event)
+		},
+	})
+	defer func() { testEngine.sqlHooks = nil }()
+
+	user := HookedUser{Name: "lunny"}
+	_, err := testEngine.Insert(&user)
+	assert.NoError(t, err)
+	if assert.EqualValues(t, 1, len(events)) {
+		assert.EqualValues(t, OperationExec, events[0].Op)
+		assert.True(t, strings.HasPrefix(strings.ToUpper(events[0].SQL), "INSERT"))
+		assert.EqualValues(t, []interface{}{"lunny"}, events[0].Args)
+		assert.True(t, events[0].Bean == &user)
+		assert.False(t, events[0].Start.IsZero())
+		assert.True(t, events[0].Duration > 0)
+		assert.NotNil(t, events[0].Result)
+		assert.NoError(t, events[0].Err)
+	}
+
+	session := testEngine.NewSession()
+	defer session.Close()
+	session.AddSQLHook(SQLHookFuncs{
+		Before: func(event *SQLEvent) {
+			calls = append(calls, "session before")
+		},
+		After: func(event *SQLEvent) {
+			calls = append(calls, "session after")
+		},
+	})
+
+	calls = nil
+	events = nil
+	var users []HookedUser
+	assert.NoError(t, session.Find(&users))
+	assert.EqualValues(t, 1, len(users))
+	assert.EqualValues(t, []string{"engine before", "session before", "session after", "engine after"}, calls)
+	if assert.EqualValues(t, 1, len(events)) {
+		assert.EqualValues(t, OperationQuery, events[0].Op)
+	}
+}
+
+func TestSQLHookError(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	errTable := errors.New("no such table")
+	var events []SQLEvent
+	testEngine.AddSQLHook(SQLHookFuncs{
+		After: func(event *SQLEvent) {
+			events = append(events, *event)
+			if event.Err != nil {
+				event.Err = errTable
+			}
+		},
+	})
+	defer func() { testEngine.sqlHooks = nil }()
+
+	_, err := testEngine.Exec("DELETE FROM not_exist_table")
+	assert.EqualValues(t, errTable, err)
+	if assert.EqualValues(t, 1, len(events)) {
+		assert.Error(t, events[0].Err)
+		assert.Nil(t, events[0].Bean)
+	}
+
+	// the short-circuited statements are not hooked
+	events = nil
+	testEngine.Use(InterceptorFunc(func(inv *Invocation, next Handler) error {
+		return nil
+	}))
+	defer func() { testEngine.interceptors = nil }()
+
+	_, err = testEngine.Exec("DELETE FROM not_exist_table")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, len(events))
+}
@@ -0,0 +1,33 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+)
+
+// Rewriter rewrites the final SQL and args of a statement, after the
+// dialect conversions, and returns the ones to execute. An error aborts
+// the statement.
+type Rewriter func(ctx context.Context, op Operation, sqlStr string, args []interface{}) (string, []interface{}, error)
+
+// SQLRewriter returns an interceptor executing every statement as rewritten
+// by rewriter with the context of its session, to route it with a shard
+// comment or append a predicate to it centrally. The rewriters run in the
+// order they are used, as the other interceptors.
+//
+//	engine.Use(xorm.SQLRewriter(func(ctx context.Context, op xorm.Operation, sqlStr string, args []interface{}) (string, []interface{}, error) {
+//		return "/* shard=" + shardOf(ctx) + " */ " + sqlStr, args, nil
+//	}))
+func SQLRewriter(rewriter Rewriter) Interceptor {
+	return InterceptorFunc(func(inv *Invocation, next Handler) error {
+		sqlStr, args, err := rewriter(inv.Session.Ctx(), inv.Op, inv.SQL, inv.Args)
+		if err != nil {
+			return err
+		}
+		inv.SQL, inv.Args = sqlStr, args
+		return next(inv)
+	})
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type RewrittenUser struct {
+	Id        int64
+	CompanyId int64
+	Name      string
+}
+
+type companyKey struct{}
+
+func TestSQLRewriter(t *testing.T) {
+	mock, err := NewMockEngine()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	errNoCompany := errors.New("no company")
+	mock.Use(
+		SQLRewriter(func(ctx context.Context, op Operation, sqlStr string, args []interface{}) (string, []interface{}, error) {
+			if op != OperationQuery {
+				return sqlStr, args, nil
+			}
+			company, ok := ctx.Value(companyKey{}).(int64)
+			if !ok {
+				return "", nil, errNoCompany
+			}
+			return "SELECT * FROM (" + sqlStr + ") t WHERE company_id = ?", append(args, company), nil
+		}),
+		SQLRewriter(func(ctx context.Context, op Operation, sqlStr string, args []interface{}) (string, []interface{}, error) {
+			return "/* shard=1 */ " + sqlStr, args, nil
+		}),
+	)
+
+	ctx := context.WithValue(context.Background(), companyKey{}, int64(3))
+	var users []RewrittenUser
+	assert.NoError(t, mock.Context(ctx).Where("name = ?", "lunny").Find(&users))
+	record := mock.LastRecord()
+	assert.EqualValues(t, "/* shard=1 */ SELECT * FROM (SELECT `id`, `company_id`, `name` FROM `rewritten_user`"+
+		" WHERE (name = ?)) t WHERE company_id = ?", record.SQL)
+	assert.EqualValues(t, []interface{}{"lunny", int64(3)}, record.Args)
+
+	_, err = mock.Insert(&RewrittenUser{CompanyId: 3, Name: "lunny"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "/* shard=1 */ INSERT INTO `rewritten_user` (`company_id`,`name`) VALUES (?, ?)",
+		mock.LastRecord().SQL)
+
+	// an error aborts the statement
+	assert.Equal(t, errNoCompany, mock.Find(&users))
+	assert.EqualValues(t, "/* shard=1 */ INSERT INTO `rewritten_user` (`company_id`,`name`) VALUES (?, ?)",
+		mock.LastRecord().SQL)
+}
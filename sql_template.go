@@ -0,0 +1,240 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// the marker of the statements of the SQL files
+const sqlNameMarker = "-- name:"
+
+// LoadSQLMap loads the templates of the statements of the .sql files of dir
+// and of its subdirectories. A file holds the statements following their
+// -- name: markers, or a statement named by its path relative to dir
+// without its extension:
+//
+//	-- name: find_active_users
+//	SELECT * FROM user WHERE active = :active
+//	{{if .Name}}AND name = :name{{end}}
+//
+// The statements are text/template templates of their params, whose named
+// args :name are bound to the params. The statements replace the loaded
+// ones of the same names.
+func (engine *Engine) LoadSQLMap(dir string) error {
+	var templates = make(map[string]*template.Template)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".sql" {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(strings.TrimSuffix(rel, ".sql"))
+		statements, err := splitSQLFile(name, content)
+		if err != nil {
+			return fmt.Errorf("%v: %v", path, err)
+		}
+		for name, text := range statements {
+			if _, ok := templates[name]; ok {
+				return fmt.Errorf("%v: statement %v is loaded twice", path, name)
+			}
+			tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+			if err != nil {
+				return fmt.Errorf("%v: %v", path, err)
+			}
+			templates[name] = tmpl
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for name, tmpl := range templates {
+		engine.sqlTemplates.Store(name, tmpl)
+	}
+	return nil
+}
+
+// splitSQLFile returns the statements of a file by their names
+func splitSQLFile(fileName string, content []byte) (map[string]string, error) {
+	var statements = make(map[string]string)
+	var name string
+	var lines []string
+	flush := func() error {
+		text := strings.TrimSpace(strings.Join(lines, "\n"))
+		lines = nil
+		if text == "" {
+			return nil
+		}
+		if name == "" {
+			name = fileName
+		}
+		if _, ok := statements[name]; ok {
+			return fmt.Errorf("statement %v is loaded twice", name)
+		}
+		statements[name] = text
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), sqlNameMarker) {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), sqlNameMarker))
+			if name == "" {
+				return nil, fmt.Errorf("a statement has no name")
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return statements, flush()
+}
+
+// RenderSQL returns the statement name of the SQL map run with params, a
+// map or a struct, and the args of its named args
+func (engine *Engine) RenderSQL(name string, params interface{}) (string, []interface{}, error) {
+	tmpl, ok := engine.sqlTemplates.Load(name)
+	if !ok {
+		return "", nil, fmt.Errorf("no statement %v in the SQL map", name)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.(*template.Template).Execute(&buf, params); err != nil {
+		return "", nil, err
+	}
+	return engine.bindNamedArgs(buf.String(), params)
+}
+
+// SQLTemplate sets the statement name of the SQL map run with params as the
+// raw SQL of the session, its errors are logged as the ones of SQL
+func (session *Session) SQLTemplate(name string, params interface{}) *Session {
+	sqlStr, args, err := session.Engine.RenderSQL(name, params)
+	if err != nil {
+		session.Engine.logger.Error(err)
+		return session
+	}
+	return session.SQL(sqlStr, args...)
+}
+
+// SQLTemplate sets the statement name of the SQL map run with params as the
+// raw SQL of a new session
+func (engine *Engine) SQLTemplate(name string, params interface{}) *Session {
+	session := engine.NewSession()
+	session.IsAutoClose = true
+	return session.SQLTemplate(name, params)
+}
+
+// bindNamedArgs replaces the named args :name of sqlStr by placeholders of
+// the params of the names, the slices by lists of placeholders. The quoted
+// strings and names and the casts :: are kept.
+func (engine *Engine) bindNamedArgs(sqlStr string, params interface{}) (string, []interface{}, error) {
+	var buf strings.Builder
+	var args []interface{}
+	for i := 0; i < len(sqlStr); i++ {
+		c := sqlStr[i]
+		switch c {
+		case '\'', '"', '`':
+			end := strings.IndexByte(sqlStr[i+1:], c)
+			if end < 0 {
+				buf.WriteString(sqlStr[i:])
+				i = len(sqlStr)
+				continue
+			}
+			buf.WriteString(sqlStr[i : i+end+2])
+			i += end + 1
+			continue
+		case ':':
+			if i+1 < len(sqlStr) && sqlStr[i+1] == ':' {
+				buf.WriteString("::")
+				i++
+				continue
+			}
+			j := i + 1
+			for j < len(sqlStr) && isNameByte(sqlStr[j], j == i+1) {
+				j++
+			}
+			if j == i+1 {
+				break
+			}
+			name := sqlStr[i+1 : j]
+			value, ok := engine.namedParam(params, name)
+			if !ok {
+				return "", nil, fmt.Errorf("no param %v", name)
+			}
+			v := reflect.ValueOf(value)
+			if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+				if v.Len() == 0 {
+					return "", nil, fmt.Errorf("param %v is empty", name)
+				}
+				for k := 0; k < v.Len(); k++ {
+					if k > 0 {
+						buf.WriteString(", ")
+					}
+					buf.WriteByte('?')
+					args = append(args, v.Index(k).Interface())
+				}
+			} else {
+				buf.WriteByte('?')
+				args = append(args, value)
+			}
+			i = j - 1
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String(), args, nil
+}
+
+func isNameByte(c byte, first bool) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || !first && '0' <= c && c <= '9'
+}
+
+// namedParam returns the param name of a map, or of a struct by its field
+// or its column name
+func (engine *Engine) namedParam(params interface{}, name string) (interface{}, bool) {
+	v := reflect.Indirect(reflect.ValueOf(params))
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		value := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		if !value.IsValid() {
+			return nil, false
+		}
+		return value.Interface(), true
+	case reflect.Struct:
+		if field := v.FieldByName(name); field.IsValid() && field.CanInterface() {
+			return field.Interface(), true
+		}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath == "" && engine.ColumnMapper.Obj2Table(field.Name) == name {
+				return v.Field(i).Interface(), true
+			}
+		}
+	}
+	return nil, false
+}
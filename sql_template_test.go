@@ -0,0 +1,91 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TemplateUser struct {
+	Id     int64
+	Name   string
+	Active bool
+	Age    int
+}
+
+const templateUsersSQL = `
+-- name: find_active_users
+SELECT * FROM template_user WHERE active = :active
+{{if .Name}}AND name = :Name{{end}}
+ORDER BY id
+
+-- name: find_users_by_ids
+SELECT * FROM template_user WHERE id IN (:ids) AND name <> ':ids'
+`
+
+func TestSQLTemplate(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(TemplateUser))
+
+	dir, err := ioutil.TempDir("", "xorm")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "users"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "users.sql"), []byte(templateUsersSQL), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "users", "count.sql"),
+		[]byte("SELECT count(*) FROM template_user WHERE age > :age"), 0644))
+	assert.NoError(t, testEngine.LoadSQLMap(dir))
+
+	_, err = testEngine.Insert(&[]TemplateUser{
+		{Name: "lunny", Active: true, Age: 30},
+		{Name: "xlw", Active: true, Age: 20},
+		{Name: "gopher", Age: 10},
+	})
+	assert.NoError(t, err)
+
+	var users []TemplateUser
+	assert.NoError(t, testEngine.SQLTemplate("find_active_users", map[string]interface{}{"active": true}).Find(&users))
+	assert.Len(t, users, 2)
+
+	users = nil
+	params := struct {
+		Name   string
+		Active bool
+	}{"xlw", true}
+	assert.NoError(t, testEngine.SQLTemplate("find_active_users", &params).Find(&users))
+	if assert.Len(t, users, 1) {
+		assert.EqualValues(t, "xlw", users[0].Name)
+	}
+
+	sqlStr, args, err := testEngine.RenderSQL("find_users_by_ids", map[string]interface{}{"ids": []int64{1, 3}})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "SELECT * FROM template_user WHERE id IN (?, ?) AND name <> ':ids'", sqlStr)
+	assert.EqualValues(t, []interface{}{int64(1), int64(3)}, args)
+
+	var cnt int64
+	has, err := testEngine.SQLTemplate("users/count", map[string]int{"age": 15}).Get(&cnt)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 2, cnt)
+
+	_, _, err = testEngine.RenderSQL("find_active_users", map[string]interface{}{})
+	assert.Error(t, err)
+	_, _, err = testEngine.RenderSQL("missing", nil)
+	assert.Error(t, err)
+}
+
+func TestSplitSQLFile(t *testing.T) {
+	statements, err := splitSQLFile("users", []byte("SELECT 1"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, map[string]string{"users": "SELECT 1"}, statements)
+
+	_, err = splitSQLFile("users", []byte("-- name: a\nSELECT 1\n-- name: a\nSELECT 2"))
+	assert.Error(t, err)
+}
@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -43,10 +44,12 @@ type Statement struct {
 	OrderStr        string
 	JoinStr         string
 	joinArgs        []interface{}
+	joinTables      []joinTable
 	GroupByStr      string
 	HavingStr       string
 	ColumnStr       string
 	selectStr       string
+	selectExprs     []string
 	columnMap       map[string]bool
 	useAllCols      bool
 	OmitStr         string
@@ -72,7 +75,30 @@ type Statement struct {
 	incrColumns     map[string]incrParam
 	decrColumns     map[string]decrParam
 	exprColumns     map[string]exprParam
+	jsonPaths       []jsonPathParam
+	fieldMask       FieldMask
+	selectFields    []string
+	scopes          []string
+	eager           bool
+	insertStrategy  InsertMultiStrategy
+	cursorSize      int
+	insertIgnore    bool
+	insertReplace   bool
+	upsert          upsertParam
+	tableKind       tableKind
+	nested          []nestedParam
 	cond            builder.Cond
+	route           routeHint
+	invalidTables   []string
+	forceCache      bool
+	cacheTTL        time.Duration
+	lastIn          *inParam    // the last In, to find the ids without querying
+	bean            interface{} // the bean of RefTable, seen by the interceptors
+	noCapture       bool        // the changes are not captured, as the audit records
+	tenant          string      // kept across statements, reset with the session
+	tableSuffix     string      // appended to the table names, as the months of the sharded tables
+	session         *Session    // the session of the statement, seen by the query filters
+	callerConds     bool        // set by genConds, the conditions are not only the mandatory ones
 }
 
 // Init reset all the statement's fields
@@ -83,15 +109,18 @@ func (statement *Statement) Init() {
 	statement.OrderStr = ""
 	statement.UseCascade = true
 	statement.JoinStr = ""
+	statement.joinTables = nil
 	statement.joinArgs = make([]interface{}, 0)
 	statement.GroupByStr = ""
 	statement.HavingStr = ""
 	statement.ColumnStr = ""
 	statement.OmitStr = ""
-	statement.columnMap = make(map[string]bool)
+	statement.columnMap = reuseBoolMap(statement.columnMap)
 	statement.AltTableName = ""
 	statement.tableName = ""
+	statement.tableSuffix = ""
 	statement.idParam = nil
+	statement.callerConds = false
 	statement.RawSQL = ""
 	statement.RawParams = make([]interface{}, 0)
 	statement.UseCache = true
@@ -101,16 +130,51 @@ func (statement *Statement) Init() {
 	statement.IsForUpdate = false
 	statement.TableAlias = ""
 	statement.selectStr = ""
+	statement.selectExprs = nil
 	statement.allUseBool = false
 	statement.useAllCols = false
-	statement.mustColumnMap = make(map[string]bool)
-	statement.nullableMap = make(map[string]bool)
+	statement.mustColumnMap = reuseBoolMap(statement.mustColumnMap)
+	statement.nullableMap = reuseBoolMap(statement.nullableMap)
 	statement.checkVersion = true
 	statement.unscoped = false
-	statement.incrColumns = make(map[string]incrParam)
-	statement.decrColumns = make(map[string]decrParam)
-	statement.exprColumns = make(map[string]exprParam)
+	if statement.incrColumns == nil || len(statement.incrColumns) > 0 {
+		statement.incrColumns = make(map[string]incrParam)
+	}
+	if statement.decrColumns == nil || len(statement.decrColumns) > 0 {
+		statement.decrColumns = make(map[string]decrParam)
+	}
+	if statement.exprColumns == nil || len(statement.exprColumns) > 0 {
+		statement.exprColumns = make(map[string]exprParam)
+	}
+	statement.jsonPaths = nil
+	statement.fieldMask = nil
+	statement.selectFields = nil
+	statement.scopes = nil
+	statement.eager = false
+	statement.insertStrategy = InsertMultiAuto
+	statement.cursorSize = 0
+	statement.insertIgnore = false
+	statement.insertReplace = false
+	statement.upsert = upsertParam{}
+	statement.tableKind = tableRegular
+	statement.nested = nil
 	statement.cond = builder.NewCond()
+	statement.route = routeDefault
+	statement.invalidTables = nil
+	statement.forceCache = false
+	statement.cacheTTL = 0
+	statement.lastIn = nil
+	statement.bean = nil
+	statement.noCapture = false
+}
+
+// reuseBoolMap returns m if it's empty, otherwise a new map. The maps are
+// reused since a statement is reset after every operation.
+func reuseBoolMap(m map[string]bool) map[string]bool {
+	if m == nil || len(m) > 0 {
+		return make(map[string]bool)
+	}
+	return m
 }
 
 // NoAutoCondition if you do not want convert bean's field as query condition, then use this function
@@ -197,6 +261,7 @@ func (statement *Statement) Or(query interface{}, args ...interface{}) *Statemen
 func (statement *Statement) In(column string, args ...interface{}) *Statement {
 	in := builder.In(statement.Engine.Quote(column), args...)
 	statement.cond = statement.cond.And(in)
+	statement.lastIn = &inParam{column, args, in}
 	return statement
 }
 
@@ -213,7 +278,12 @@ func (statement *Statement) setRefValue(v reflect.Value) error {
 	if err != nil {
 		return err
 	}
-	statement.tableName = statement.Engine.tbName(v)
+	statement.tableName = statement.tbName(v)
+	if v.CanAddr() {
+		statement.bean = v.Addr().Interface()
+	} else {
+		statement.bean = v.Interface()
+	}
 	return nil
 }
 
@@ -230,7 +300,7 @@ func (statement *Statement) Table(tableNameOrBean interface{}) *Statement {
 			statement.Engine.logger.Error(err)
 			return statement
 		}
-		statement.AltTableName = statement.Engine.tbName(v)
+		statement.AltTableName = statement.tbName(v)
 	}
 	return statement
 }
@@ -296,6 +366,18 @@ func buildUpdates(engine *Engine, table *core.Table, bean interface{},
 
 		var val interface{}
 
+		if v, ok, err := engine.convertToDB(col, fieldValue); ok {
+			if !requiredField && fieldValue.IsZero() && (fieldType.Kind() != reflect.Ptr || !includeNil) {
+				continue
+			}
+			if err != nil {
+				engine.logger.Error(err)
+			} else {
+				val = v
+			}
+			goto APPEND
+		}
+
 		if fieldValue.CanAddr() {
 			if structConvert, ok := fieldValue.Addr().Interface().(core.Conversion); ok {
 				data, err := structConvert.ToDB()
@@ -320,7 +402,8 @@ func buildUpdates(engine *Engine, table *core.Table, bean interface{},
 
 		if fieldType.Kind() == reflect.Ptr {
 			if fieldValue.IsNil() {
-				if includeNil {
+				// a nil pointer of a required column sets it to NULL
+				if includeNil || requiredField {
 					args = append(args, nil)
 					colNames = append(colNames, fmt.Sprintf("%v=?", engine.Quote(col.Name)))
 				}
@@ -531,11 +614,7 @@ func buildConds(engine *Engine, table *core.Table, bean interface{},
 		}
 
 		if col.IsDeleted && !unscoped { // tag "deleted" is enabled
-			if engine.dialect.DBType() == core.MSSQL {
-				conds = append(conds, builder.IsNull{colName})
-			} else {
-				conds = append(conds, builder.IsNull{colName}.Or(builder.Eq{colName: "0001-01-01 00:00:00"}))
-			}
+			conds = append(conds, engine.notDeletedCond(table, col, colName))
 		}
 
 		fieldValue := *fieldValuePtr
@@ -554,6 +633,24 @@ func buildConds(engine *Engine, table *core.Table, bean interface{},
 			}
 		}
 
+		if v, ok, err := engine.convertToDB(col, fieldValue); ok {
+			if err != nil {
+				engine.logger.Error(err)
+				continue
+			}
+			if fieldType.Kind() == reflect.Ptr && fieldValue.IsNil() {
+				if includeNil {
+					conds = append(conds, builder.Eq{colName: nil})
+				}
+				continue
+			}
+			if !requiredField && fieldType.Kind() != reflect.Ptr && fieldValue.IsZero() {
+				continue
+			}
+			conds = append(conds, builder.Eq{colName: v})
+			continue
+		}
+
 		if fieldType.Kind() == reflect.Ptr {
 			if fieldValue.IsNil() {
 				if includeNil {
@@ -710,11 +807,129 @@ func buildConds(engine *Engine, table *core.Table, bean interface{},
 
 // TableName return current tableName
 func (statement *Statement) TableName() string {
+	var tableName = statement.tableName
 	if statement.AltTableName != "" {
-		return statement.AltTableName
+		tableName = statement.AltTableName
+	}
+	if statement.tableSuffix != "" && tableName != "" {
+		tableName += statement.tableSuffix
+	}
+
+	if statement.tenant != "" && statement.Engine.tenantStrategy != nil && tableName != "" {
+		return statement.Engine.tenantStrategy.TableName(statement.tenant, tableName)
+	}
+	return tableName
+}
+
+// checkTenant returns ErrInvalidTenant when the tenant strategy puts the
+// tenant in the table names and the tenant isn't a plain identifier
+func (statement *Statement) checkTenant() error {
+	if statement.tenant == "" || statement.Engine.tenantStrategy == nil ||
+		statement.Engine.tenantStrategy.Column() != "" {
+		return nil
+	}
+	if !tenantIdentifier.MatchString(statement.tenant) {
+		return ErrInvalidTenant
+	}
+	return nil
+}
+
+// tenantColumn returns the column storing the tenant, or nil when the
+// statement isn't filtered by tenant
+func (statement *Statement) tenantColumn() *core.Column {
+	if statement.tenant == "" || statement.Engine.tenantStrategy == nil || statement.RefTable == nil {
+		return nil
+	}
+	colName := statement.Engine.tenantStrategy.Column()
+	if colName == "" {
+		return nil
+	}
+	return statement.RefTable.GetColumn(colName)
+}
+
+// tenantCond returns the condition limiting the records to the tenant
+func (statement *Statement) tenantCond() builder.Cond {
+	col := statement.tenantColumn()
+	if col == nil {
+		return builder.NewCond()
+	}
+	return builder.Eq{statement.colName(col, statement.TableName()): statement.tenant}
+}
+
+// setTenantValue fills the tenant column of a bean to be inserted
+func (statement *Statement) setTenantValue(v reflect.Value) error {
+	col := statement.tenantColumn()
+	if col == nil {
+		return nil
 	}
 
-	return statement.tableName
+	fieldValue, err := col.ValueOfV(&v)
+	if err != nil {
+		return err
+	}
+	if !fieldValue.CanSet() {
+		return errors.New("tenant column is not settable, use a pointer of the bean")
+	}
+	switch fieldValue.Kind() {
+	case reflect.String:
+		fieldValue.SetString(statement.tenant)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(statement.tenant, 10, 64)
+		if err != nil {
+			return err
+		}
+		fieldValue.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(statement.tenant, 10, 64)
+		if err != nil {
+			return err
+		}
+		fieldValue.SetUint(n)
+	default:
+		return fmt.Errorf("Unsupported tenant column type %v", fieldValue.Type())
+	}
+	return nil
+}
+
+// tenantMapValues sets the tenant column of the columns colNames and their
+// values args of a map to be inserted
+func (statement *Statement) tenantMapValues(colNames []string, args []interface{}) ([]string, []interface{}, error) {
+	col := statement.tenantColumn()
+	if col == nil {
+		return colNames, args, nil
+	}
+
+	var tenant interface{} = statement.tenant
+	if col.SQLType.IsNumeric() {
+		n, err := strconv.ParseInt(statement.tenant, 10, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		tenant = n
+	}
+	for i, colName := range colNames {
+		if strings.EqualFold(colName, col.Name) {
+			if err := statement.checkTenantMapValue(colName, args[i]); err != nil {
+				return nil, nil, err
+			}
+			args[i] = tenant
+			return colNames, args, nil
+		}
+	}
+	return append(colNames, col.Name), append(args, tenant), nil
+}
+
+// checkTenantMapValue refuses the value of the column colName of a map when
+// it's the tenant column and the value is another tenant's
+func (statement *Statement) checkTenantMapValue(colName string, value interface{}) error {
+	col := statement.tenantColumn()
+	if col == nil || !strings.EqualFold(colName, col.Name) || value == nil {
+		return nil
+	}
+	if fmt.Sprint(value) != statement.tenant {
+		return fmt.Errorf("tenant column %v is %v, not the tenant %v of the session", col.Name, value, statement.tenant)
+	}
+	return nil
 }
 
 // ID generate "where id = ? " statement or for composite key "where key1 = ? and key2 = ?"
@@ -948,8 +1163,10 @@ func (statement *Statement) Join(joinOP string, tablename interface{}, condition
 		t := tablename.([]string)
 		if len(t) > 1 {
 			fmt.Fprintf(&buf, "%v AS %v", statement.Engine.Quote(t[0]), statement.Engine.Quote(t[1]))
+			statement.joinTables = append(statement.joinTables, joinTable{t[0], t[1]})
 		} else if len(t) == 1 {
 			fmt.Fprintf(&buf, statement.Engine.Quote(t[0]))
+			statement.joinTables = append(statement.joinTables, joinTable{t[0], ""})
 		}
 	case []interface{}:
 		t := tablename.([]interface{})
@@ -968,11 +1185,14 @@ func (statement *Statement) Join(joinOP string, tablename interface{}, condition
 		if l > 1 {
 			fmt.Fprintf(&buf, "%v AS %v", statement.Engine.Quote(table),
 				statement.Engine.Quote(fmt.Sprintf("%v", t[1])))
+			statement.joinTables = append(statement.joinTables, joinTable{table, fmt.Sprintf("%v", t[1])})
 		} else if l == 1 {
 			fmt.Fprintf(&buf, statement.Engine.Quote(table))
+			statement.joinTables = append(statement.joinTables, joinTable{table, ""})
 		}
 	default:
 		fmt.Fprintf(&buf, statement.Engine.Quote(fmt.Sprintf("%v", tablename)))
+		statement.joinTables = append(statement.joinTables, joinTable{fmt.Sprintf("%v", tablename), ""})
 	}
 
 	fmt.Fprintf(&buf, " ON %v", condition)
@@ -1000,10 +1220,18 @@ func (statement *Statement) Unscoped() *Statement {
 }
 
 func (statement *Statement) genColumnStr() string {
-	var buf bytes.Buffer
+	if c := statement.Engine.sqlCache; c != nil && statement.RefTable != nil {
+		return c.get(statement.columnStrKey(), statement.buildColumnStr)
+	}
+	return statement.buildColumnStr()
+}
+
+func (statement *Statement) buildColumnStr() string {
 	if statement.RefTable == nil {
 		return ""
 	}
+	buf := getSQLBuffer()
+	defer putSQLBuffer(buf)
 
 	columns := statement.RefTable.Columns()
 
@@ -1014,7 +1242,7 @@ func (statement *Statement) genColumnStr() string {
 			}
 		}
 
-		if col.MapType == core.ONLYTODB {
+		if col.MapType == core.ONLYTODB || !statement.inScope(col) || statement.isLazy(col) {
 			continue
 		}
 
@@ -1036,7 +1264,7 @@ func (statement *Statement) genColumnStr() string {
 			buf.WriteString(".")
 		}
 
-		statement.Engine.QuoteTo(&buf, col.Name)
+		statement.Engine.QuoteTo(buf, col.Name)
 	}
 
 	return buf.String()
@@ -1117,6 +1345,9 @@ func (statement *Statement) genConds(bean interface{}) (string, []interface{}, e
 		}
 		statement.cond = statement.cond.And(autoCond)
 	}
+	// the tenant and the filters don't make a delete of all the rows valid
+	statement.callerConds = statement.cond.IsValid() || statement.idParam != nil
+	statement.cond = statement.cond.And(statement.mandatoryCond())
 
 	statement.processIDParam()
 
@@ -1125,7 +1356,8 @@ func (statement *Statement) genConds(bean interface{}) (string, []interface{}, e
 
 func (statement *Statement) genGetSQL(bean interface{}) (string, []interface{}) {
 	v := rValue(bean)
-	isStruct := v.Kind() == reflect.Struct
+	_, isTime := bean.(*time.Time)
+	isStruct := v.Kind() == reflect.Struct && !isTime
 	if isStruct {
 		statement.setRefValue(v)
 	}
@@ -1147,6 +1379,8 @@ func (statement *Statement) genGetSQL(bean interface{}) (string, []interface{})
 			if len(columnStr) == 0 {
 				if len(statement.GroupByStr) > 0 {
 					columnStr = statement.Engine.Quote(strings.Replace(statement.GroupByStr, ",", statement.Engine.Quote(","), -1))
+				} else if isStruct {
+					columnStr = statement.genJoinColumnStr()
 				}
 			}
 		}
@@ -1155,13 +1389,16 @@ func (statement *Statement) genGetSQL(bean interface{}) (string, []interface{})
 	if len(columnStr) == 0 {
 		columnStr = "*"
 	}
+	if len(statement.selectStr) == 0 {
+		columnStr = statement.exprColumnStr(columnStr)
+	}
 
 	var condSQL string
 	var condArgs []interface{}
 	if isStruct {
 		condSQL, condArgs, _ = statement.genConds(bean)
 	} else {
-		condSQL, condArgs, _ = builder.ToSQL(statement.cond)
+		condSQL, condArgs, _ = builder.ToSQL(statement.cond.And(statement.mandatoryCond()))
 	}
 
 	return statement.genSelectSQL(columnStr, condSQL), append(statement.joinArgs, condArgs...)
@@ -1200,7 +1437,17 @@ func (statement *Statement) genSumSQL(bean interface{}, columns ...string) (stri
 	return statement.genSelectSQL(sumSelect, condSQL), append(statement.joinArgs, condArgs...)
 }
 
-func (statement *Statement) genSelectSQL(columnStr, condSQL string) (a string) {
+func (statement *Statement) genSelectSQL(columnStr, condSQL string) string {
+	statement.processIDParam()
+	if c := statement.Engine.sqlCache; c != nil {
+		return c.get(statement.selectKey(columnStr, condSQL), func() string {
+			return statement.buildSelectSQL(columnStr, condSQL)
+		})
+	}
+	return statement.buildSelectSQL(columnStr, condSQL)
+}
+
+func (statement *Statement) buildSelectSQL(columnStr, condSQL string) (a string) {
 	var distinct string
 	if statement.IsDistinct && !strings.HasPrefix(columnStr, "count") {
 		distinct = "DISTINCT "
@@ -1211,13 +1458,10 @@ func (statement *Statement) genSelectSQL(columnStr, condSQL string) (a string) {
 	var top string
 	var mssqlCondi string
 
-	statement.processIDParam()
-
-	var buf bytes.Buffer
+	var whereStr string
 	if len(condSQL) > 0 {
-		fmt.Fprintf(&buf, " WHERE %v", condSQL)
+		whereStr = " WHERE " + condSQL
 	}
-	var whereStr = buf.String()
 	var fromStr = " FROM "
 
 	if dialect.DBType() == core.MSSQL && strings.Contains(statement.TableName(), "..") {
@@ -1277,32 +1521,48 @@ func (statement *Statement) genSelectSQL(columnStr, condSQL string) (a string) {
 		}
 	}
 
-	// !nashtsai! REVIEW Sprintf is considered slowest mean of string concatnation, better to work with builder pattern
-	a = fmt.Sprintf("SELECT %v%v%v%v%v", distinct, top, columnStr, fromStr, whereStr)
+	buf := getSQLBuffer()
+	defer putSQLBuffer(buf)
+	buf.WriteString("SELECT ")
+	buf.WriteString(distinct)
+	buf.WriteString(top)
+	buf.WriteString(columnStr)
+	buf.WriteString(fromStr)
+	buf.WriteString(whereStr)
 	if len(mssqlCondi) > 0 {
 		if len(whereStr) > 0 {
-			a += " AND " + mssqlCondi
+			buf.WriteString(" AND ")
 		} else {
-			a += " WHERE " + mssqlCondi
+			buf.WriteString(" WHERE ")
 		}
+		buf.WriteString(mssqlCondi)
 	}
 
 	if statement.GroupByStr != "" {
-		a = fmt.Sprintf("%v GROUP BY %v", a, statement.GroupByStr)
+		buf.WriteString(" GROUP BY ")
+		buf.WriteString(statement.GroupByStr)
 	}
 	if statement.HavingStr != "" {
-		a = fmt.Sprintf("%v %v", a, statement.HavingStr)
+		buf.WriteString(" ")
+		buf.WriteString(statement.HavingStr)
 	}
 	if statement.OrderStr != "" {
-		a = fmt.Sprintf("%v ORDER BY %v", a, statement.OrderStr)
+		buf.WriteString(" ORDER BY ")
+		buf.WriteString(statement.OrderStr)
 	}
 	if dialect.DBType() != core.MSSQL && dialect.DBType() != core.ORACLE {
 		if statement.Start > 0 {
-			a = fmt.Sprintf("%v LIMIT %v OFFSET %v", a, statement.LimitN, statement.Start)
+			buf.WriteString(" LIMIT ")
+			buf.WriteString(strconv.Itoa(statement.LimitN))
+			buf.WriteString(" OFFSET ")
+			buf.WriteString(strconv.Itoa(statement.Start))
 		} else if statement.LimitN > 0 {
-			a = fmt.Sprintf("%v LIMIT %v", a, statement.LimitN)
+			buf.WriteString(" LIMIT ")
+			buf.WriteString(strconv.Itoa(statement.LimitN))
 		}
-	} else if dialect.DBType() == core.ORACLE {
+	}
+	a = buf.String()
+	if dialect.DBType() == core.ORACLE {
 		if statement.Start != 0 || statement.LimitN != 0 {
 			a = fmt.Sprintf("SELECT %v FROM (SELECT %v,ROWNUM RN FROM (%v) at WHERE ROWNUM <= %d) aat WHERE RN > %d", columnStr, columnStr, a, statement.Start+statement.LimitN, statement.Start)
 		}
@@ -1322,7 +1582,14 @@ func (statement *Statement) processIDParam() {
 	for i, col := range statement.RefTable.PKColumns() {
 		var colName = statement.colName(col, statement.TableName())
 		if i < len(*(statement.idParam)) {
-			statement.cond = statement.cond.And(builder.Eq{colName: (*(statement.idParam))[i]})
+			id := (*(statement.idParam))[i]
+			if id != nil {
+				// the errors are returned by the conversion of the args
+				if v, ok, err := statement.Engine.convertToDB(col, reflect.ValueOf(id)); ok && err == nil {
+					id = v
+				}
+			}
+			statement.cond = statement.cond.And(builder.Eq{colName: id})
 		} else {
 			statement.cond = statement.cond.And(builder.Eq{colName: ""})
 		}
@@ -0,0 +1,147 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"container/list"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-xorm/core"
+)
+
+// DefaultStmtCacheSize is the default max number of prepared statements
+// cached by a session
+const DefaultStmtCacheSize = 128
+
+// StmtCacheStats is the statistics of the prepared statement caches of
+// all the sessions of an engine
+type StmtCacheStats struct {
+	Hits          int64
+	Misses        int64
+	Evictions     int64
+	Invalidations int64 // statements re-prepared because of DDL
+}
+
+type stmtCacheCounters struct {
+	// keep the int64 fields first for the alignment of atomic operations
+	hits          int64
+	misses        int64
+	evictions     int64
+	invalidations int64
+	schemaVersion int64 // increased by every DDL executed through the engine
+	size          int
+}
+
+type stmtCacheEntry struct {
+	sqlStr        string
+	stmt          *core.Stmt
+	schemaVersion int64
+}
+
+// stmtCache is a LRU cache of the prepared statements of a session
+type stmtCache struct {
+	engine *Engine
+	list   *list.List
+	index  map[string]*list.Element
+}
+
+func newStmtCache(engine *Engine) *stmtCache {
+	return &stmtCache{
+		engine: engine,
+		list:   list.New(),
+		index:  make(map[string]*list.Element),
+	}
+}
+
+// get returns the prepared statement of sqlStr, it's nil when not cached or
+// prepared before a DDL
+func (c *stmtCache) get(sqlStr string) *core.Stmt {
+	counters := c.engine.stmtCounters
+	el, ok := c.index[sqlStr]
+	if !ok {
+		atomic.AddInt64(&counters.misses, 1)
+		return nil
+	}
+
+	entry := el.Value.(*stmtCacheEntry)
+	if entry.schemaVersion != atomic.LoadInt64(&counters.schemaVersion) {
+		c.remove(el)
+		atomic.AddInt64(&counters.invalidations, 1)
+		atomic.AddInt64(&counters.misses, 1)
+		return nil
+	}
+
+	c.list.MoveToBack(el)
+	atomic.AddInt64(&counters.hits, 1)
+	return entry.stmt
+}
+
+// put caches a statement prepared when the schema version was
+// schemaVersion, the least recently used statements are closed when the
+// cache is full
+func (c *stmtCache) put(sqlStr string, stmt *core.Stmt, schemaVersion int64) {
+	counters := c.engine.stmtCounters
+	size := counters.size
+	if size <= 0 {
+		size = DefaultStmtCacheSize
+	}
+	for c.list.Len() >= size {
+		c.remove(c.list.Front())
+		atomic.AddInt64(&counters.evictions, 1)
+	}
+
+	c.index[sqlStr] = c.list.PushBack(&stmtCacheEntry{sqlStr, stmt, schemaVersion})
+}
+
+func (c *stmtCache) remove(el *list.Element) {
+	entry := c.list.Remove(el).(*stmtCacheEntry)
+	delete(c.index, entry.sqlStr)
+	entry.stmt.Close()
+}
+
+// close closes all the cached statements
+func (c *stmtCache) close() {
+	for el := c.list.Front(); el != nil; el = el.Next() {
+		el.Value.(*stmtCacheEntry).stmt.Close()
+	}
+	c.list.Init()
+	c.index = make(map[string]*list.Element)
+}
+
+// SetStmtCacheSize set the max number of prepared statements cached by
+// every session, default is DefaultStmtCacheSize
+func (engine *Engine) SetStmtCacheSize(size int) {
+	engine.stmtCounters.size = size
+}
+
+// StmtCacheStats returns the statistics of the prepared statement caches
+func (engine *Engine) StmtCacheStats() StmtCacheStats {
+	counters := engine.stmtCounters
+	return StmtCacheStats{
+		Hits:          atomic.LoadInt64(&counters.hits),
+		Misses:        atomic.LoadInt64(&counters.misses),
+		Evictions:     atomic.LoadInt64(&counters.evictions),
+		Invalidations: atomic.LoadInt64(&counters.invalidations),
+	}
+}
+
+// invalidateStmts makes all the statements prepared before stale
+func (engine *Engine) invalidateStmts() {
+	atomic.AddInt64(&engine.stmtCounters.schemaVersion, 1)
+}
+
+// isDDL returns true if the sql changes the schema
+func isDDL(sqlStr string) bool {
+	fields := strings.Fields(sqlStr)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME", "COMMENT":
+		return true
+	}
+	return false
+}
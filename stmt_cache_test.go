@@ -0,0 +1,66 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsDDL(t *testing.T) {
+	assert.True(t, isDDL("CREATE TABLE a (id int)"))
+	assert.True(t, isDDL("  alter table a add b int"))
+	assert.True(t, isDDL("DROP INDEX idx"))
+	assert.False(t, isDDL("SELECT * FROM a"))
+	assert.False(t, isDDL("update a set b = 1"))
+	assert.False(t, isDDL(""))
+}
+
+func TestStmtCache(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	type StmtCacheUser struct {
+		Id   int64
+		Name string
+	}
+	assert.NoError(t, testEngine.Sync2(new(StmtCacheUser)))
+
+	testEngine.SetStmtCacheSize(2)
+	defer testEngine.SetStmtCacheSize(0)
+
+	sess := testEngine.NewSession()
+	defer sess.Close()
+	sess.Prepare()
+
+	before := testEngine.StmtCacheStats()
+
+	for _, name := range []string{"a", "b", "a"} {
+		_, err := sess.Query("select * from stmt_cache_user where name = ?", name)
+		assert.NoError(t, err)
+		_, err = sess.Query("select * from stmt_cache_user where id = ?", 1)
+		assert.NoError(t, err)
+	}
+
+	stats := testEngine.StmtCacheStats()
+	assert.EqualValues(t, 0, stats.Evictions-before.Evictions)
+	assert.EqualValues(t, 2, stats.Misses-before.Misses)
+	assert.EqualValues(t, 4, stats.Hits-before.Hits)
+
+	_, err := sess.Query("select * from stmt_cache_user where name = ? and id = ?", "a", 1)
+	assert.NoError(t, err)
+	stats = testEngine.StmtCacheStats()
+	assert.EqualValues(t, 1, stats.Evictions-before.Evictions)
+	assert.EqualValues(t, 2, sess.stmtCache.list.Len())
+
+	// DDL makes the cached statements stale
+	_, err = testEngine.Exec("CREATE INDEX IDX_stmt_cache_user_name ON stmt_cache_user (name)")
+	assert.NoError(t, err)
+
+	_, err = sess.Query("select * from stmt_cache_user where name = ? and id = ?", "a", 1)
+	assert.NoError(t, err)
+	stats = testEngine.StmtCacheStats()
+	assert.EqualValues(t, 1, stats.Invalidations-before.Invalidations)
+}
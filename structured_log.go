@@ -0,0 +1,169 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/go-xorm/core"
+)
+
+// LogField is a key and a value of a structured log entry
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// StructuredLogger logs entries made of a message and fields, the
+// adapters of log/slog and zap are in the slogadapter and zapadapter
+// packages
+type StructuredLogger interface {
+	Log(level core.LogLevel, msg string, fields ...LogField)
+}
+
+// the keys of the fields of the SQL entries
+const (
+	LogFieldSQL      = "sql"
+	LogFieldArgs     = "args"
+	LogFieldDuration = "duration"
+	LogFieldSession  = "session"
+	LogFieldTx       = "tx"
+	LogFieldOp       = "op"
+	LogFieldError    = "error"
+)
+
+// SetStructuredLogger logs with logger instead of the logger of the engine.
+// The statements shown by ShowSQL are logged as an entry "sql" with their
+// SQL, args, duration, session id and whether the session is in a
+// transaction, the failed ones at the error level.
+func (engine *Engine) SetStructuredLogger(logger StructuredLogger) {
+	engine.structuredLogger = logger
+	engine.SetLogger(&structuredILogger{
+		logger:  logger,
+		level:   engine.logger.Level(),
+		showSQL: engine.logger.IsShowSQL(),
+	})
+}
+
+// sqlLogHook is the SQL hook logging the statements to the structured
+// logger of an engine
+type sqlLogHook struct {
+	engine *Engine
+}
+
+func (h sqlLogHook) BeforeSQL(event *SQLEvent) {}
+
+func (h sqlLogHook) AfterSQL(event *SQLEvent) {
+	fields := []LogField{
+		{LogFieldSQL, event.SQL},
+		{LogFieldArgs, event.Args},
+		{LogFieldDuration, event.Duration},
+		{LogFieldSession, event.Session.SessionID()},
+		{LogFieldTx, !event.Session.IsAutoCommit},
+		{LogFieldOp, event.Op.String()},
+	}
+	if event.Err != nil {
+		fields = append(fields, LogField{LogFieldError, event.Err})
+		h.engine.structuredLogger.Log(core.LOG_ERR, "sql", fields...)
+		return
+	}
+	if h.engine.logger.Level() <= core.LOG_INFO {
+		h.engine.structuredLogger.Log(core.LOG_INFO, "sql", fields...)
+	}
+}
+
+// lastSessionID is the id of the last session created
+var lastSessionID uint64
+
+// SessionID returns the id of the session in the structured logs, unique
+// in the process
+func (session *Session) SessionID() uint64 {
+	return session.id
+}
+
+func nextSessionID() uint64 {
+	return atomic.AddUint64(&lastSessionID, 1)
+}
+
+// structuredILogger is the core.ILogger of an engine with a structured
+// logger, the messages are logged without fields
+type structuredILogger struct {
+	logger  StructuredLogger
+	level   core.LogLevel
+	showSQL bool
+}
+
+var _ core.ILogger = &structuredILogger{}
+
+func (l *structuredILogger) log(level core.LogLevel, msg string) {
+	if l.level <= level {
+		l.logger.Log(level, msg)
+	}
+}
+
+// Debug implement core.ILogger
+func (l *structuredILogger) Debug(v ...interface{}) {
+	l.log(core.LOG_DEBUG, fmt.Sprint(v...))
+}
+
+// Debugf implement core.ILogger
+func (l *structuredILogger) Debugf(format string, v ...interface{}) {
+	l.log(core.LOG_DEBUG, fmt.Sprintf(format, v...))
+}
+
+// Error implement core.ILogger
+func (l *structuredILogger) Error(v ...interface{}) {
+	l.log(core.LOG_ERR, fmt.Sprint(v...))
+}
+
+// Errorf implement core.ILogger
+func (l *structuredILogger) Errorf(format string, v ...interface{}) {
+	l.log(core.LOG_ERR, fmt.Sprintf(format, v...))
+}
+
+// Info implement core.ILogger
+func (l *structuredILogger) Info(v ...interface{}) {
+	l.log(core.LOG_INFO, fmt.Sprint(v...))
+}
+
+// Infof implement core.ILogger
+func (l *structuredILogger) Infof(format string, v ...interface{}) {
+	l.log(core.LOG_INFO, fmt.Sprintf(format, v...))
+}
+
+// Warn implement core.ILogger
+func (l *structuredILogger) Warn(v ...interface{}) {
+	l.log(core.LOG_WARNING, fmt.Sprint(v...))
+}
+
+// Warnf implement core.ILogger
+func (l *structuredILogger) Warnf(format string, v ...interface{}) {
+	l.log(core.LOG_WARNING, fmt.Sprintf(format, v...))
+}
+
+// Level implement core.ILogger
+func (l *structuredILogger) Level() core.LogLevel {
+	return l.level
+}
+
+// SetLevel implement core.ILogger
+func (l *structuredILogger) SetLevel(level core.LogLevel) {
+	l.level = level
+}
+
+// ShowSQL implement core.ILogger
+func (l *structuredILogger) ShowSQL(show ...bool) {
+	if len(show) == 0 {
+		l.showSQL = true
+		return
+	}
+	l.showSQL = show[0]
+}
+
+// IsShowSQL implement core.ILogger
+func (l *structuredILogger) IsShowSQL() bool {
+	return l.showSQL
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type LoggedUser struct {
+	Id   int64
+	Name string
+}
+
+type logEntry struct {
+	level  core.LogLevel
+	msg    string
+	fields map[string]interface{}
+}
+
+type testStructuredLogger struct {
+	entries []logEntry
+}
+
+func (l *testStructuredLogger) Log(level core.LogLevel, msg string, fields ...LogField) {
+	entry := logEntry{level, msg, make(map[string]interface{})}
+	for _, field := range fields {
+		entry.fields[field.Key] = field.Value
+	}
+	l.entries = append(l.entries, entry)
+}
+
+func TestStructuredLogger(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(LoggedUser)))
+
+	logger, showSQL := testEngine.logger, testEngine.showSQL
+	defer func() {
+		testEngine.structuredLogger = nil
+		testEngine.SetLogger(logger)
+		testEngine.ShowSQL(showSQL)
+	}()
+
+	var l testStructuredLogger
+	testEngine.SetStructuredLogger(&l)
+	testEngine.logger.SetLevel(core.LOG_INFO)
+	testEngine.ShowSQL(true)
+
+	session := testEngine.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+	_, err := session.Insert(&LoggedUser{Name: "lunny"})
+	assert.NoError(t, err)
+	assert.NoError(t, session.Commit())
+
+	if assert.EqualValues(t, 1, len(l.entries)) {
+		entry := l.entries[0]
+		assert.EqualValues(t, core.LOG_INFO, entry.level)
+		assert.EqualValues(t, "sql", entry.msg)
+		assert.True(t, strings.HasPrefix(strings.ToUpper(entry.fields[LogFieldSQL].(string)), "INSERT"))
+		assert.EqualValues(t, []interface{}{"lunny"}, entry.fields[LogFieldArgs])
+		assert.EqualValues(t, session.SessionID(), entry.fields[LogFieldSession])
+		assert.EqualValues(t, true, entry.fields[LogFieldTx])
+		assert.EqualValues(t, "exec", entry.fields[LogFieldOp])
+		assert.NotNil(t, entry.fields[LogFieldDuration])
+	}
+
+	l.entries = nil
+	_, err = testEngine.Exec("DELETE FROM not_exist_table")
+	assert.Error(t, err)
+	if assert.EqualValues(t, 1, len(l.entries)) {
+		assert.EqualValues(t, core.LOG_ERR, l.entries[0].level)
+		assert.EqualValues(t, false, l.entries[0].fields[LogFieldTx])
+		assert.EqualValues(t, err, l.entries[0].fields[LogFieldError])
+	}
+
+	// the messages of the engine are logged without fields
+	l.entries = nil
+	testEngine.logger.Warnf("cache %s", "failed")
+	if assert.EqualValues(t, 1, len(l.entries)) {
+		assert.EqualValues(t, core.LOG_WARNING, l.entries[0].level)
+		assert.EqualValues(t, "cache failed", l.entries[0].msg)
+		assert.EqualValues(t, 0, len(l.entries[0].fields))
+	}
+	testEngine.logger.Debugf("not logged")
+	assert.EqualValues(t, 1, len(l.entries))
+
+	session2 := testEngine.NewSession()
+	defer session2.Close()
+	assert.NotEqual(t, session.SessionID(), session2.SessionID())
+}
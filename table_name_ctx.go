@@ -0,0 +1,74 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"reflect"
+)
+
+// TableNameCtx is implemented by the beans whose tables depend on the
+// context of their sessions, as the tables sharded by month or by tenant
+// sharing a struct. It has precedence over TableName in the statements.
+//
+//	func (Log) TableNameCtx(ctx context.Context) string {
+//		return "log_" + ctx.Value(monthKey).(string)
+//	}
+type TableNameCtx interface {
+	TableNameCtx(ctx context.Context) string
+}
+
+// TableSuffix appends suffix to the table of the next statement, to select
+// a physical table of a struct sharded by time or by tenant
+//
+//	engine.TableSuffix("_2024_05").Insert(&log)
+func (session *Session) TableSuffix(suffix string) *Session {
+	session.Statement.tableSuffix = suffix
+	return session
+}
+
+// tbName returns the table of the bean v, the temporary table of TempTable
+// or the one of TableNameCtx with the context of the session
+func (statement *Statement) tbName(v reflect.Value) string {
+	if tableName, ok := statement.session.tempTable(reflect.Indirect(v).Type()); ok {
+		return tableName
+	}
+	if tb, ok := tableNameCtxOf(v); ok {
+		ctx := context.Background()
+		if statement.session != nil {
+			ctx = statement.session.Ctx()
+		}
+		return tb.TableNameCtx(ctx)
+	}
+	return statement.Engine.tbName(v)
+}
+
+// tableName returns the table of a bean or a table name, with the context
+// and the suffix of the statement
+func (session *Session) tableName(beanOrTableName interface{}) (string, error) {
+	tableName, err := session.Engine.tableName(beanOrTableName)
+	if err != nil {
+		return "", err
+	}
+	if v := rValue(beanOrTableName); v.Kind() == reflect.Struct {
+		tableName = session.Statement.tbName(v)
+	}
+	return tableName + session.Statement.tableSuffix, nil
+}
+
+func tableNameCtxOf(v reflect.Value) (TableNameCtx, bool) {
+	if tb, ok := v.Interface().(TableNameCtx); ok {
+		return tb, true
+	}
+	if v.Kind() == reflect.Ptr {
+		tb, ok := reflect.Indirect(v).Interface().(TableNameCtx)
+		return tb, ok
+	}
+	if v.CanAddr() {
+		tb, ok := v.Addr().Interface().(TableNameCtx)
+		return tb, ok
+	}
+	return nil, false
+}
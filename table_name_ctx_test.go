@@ -0,0 +1,98 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type shardMonthKey struct{}
+
+type ShardedLog struct {
+	Id  int64
+	Msg string
+}
+
+func (ShardedLog) TableNameCtx(ctx context.Context) string {
+	if month, ok := ctx.Value(shardMonthKey{}).(string); ok {
+		return "sharded_log_" + month
+	}
+	return "sharded_log"
+}
+
+type SuffixedLog struct {
+	Id  int64
+	Msg string
+}
+
+func TestTableNameCtx(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	may := context.WithValue(context.Background(), shardMonthKey{}, "2024_05")
+	june := context.WithValue(context.Background(), shardMonthKey{}, "2024_06")
+	for _, ctx := range []context.Context{may, june} {
+		session := testEngine.NewSession().Context(ctx)
+		assert.NoError(t, session.DropTable(new(ShardedLog)))
+		assert.NoError(t, session.CreateTable(new(ShardedLog)))
+		session.Close()
+	}
+
+	_, err := testEngine.Context(may).Insert(&ShardedLog{Msg: "may"})
+	assert.NoError(t, err)
+	_, err = testEngine.Context(june).Insert(&[]ShardedLog{{Msg: "june"}, {Msg: "june"}})
+	assert.NoError(t, err)
+
+	cnt, err := testEngine.Context(may).Count(new(ShardedLog))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var logs []ShardedLog
+	assert.NoError(t, testEngine.Context(june).Find(&logs))
+	assert.Len(t, logs, 2)
+
+	exist, err := testEngine.IsTableExist("sharded_log_2024_06")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+	exist, err = testEngine.Context(june).IsTableExist(new(ShardedLog))
+	assert.NoError(t, err)
+	assert.True(t, exist)
+}
+
+func TestTableSuffix(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	for _, suffix := range []string{"_2024_05", "_2024_06"} {
+		assert.NoError(t, testEngine.DropTables("suffixed_log"+suffix))
+		assert.NoError(t, testEngine.TableSuffix(suffix).CreateTable(new(SuffixedLog)))
+	}
+
+	_, err := testEngine.TableSuffix("_2024_05").Insert(&SuffixedLog{Msg: "may"})
+	assert.NoError(t, err)
+
+	var log SuffixedLog
+	has, err := testEngine.TableSuffix("_2024_05").Get(&log)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "may", log.Msg)
+
+	// the suffix is reset after a statement
+	session := testEngine.NewSession()
+	defer session.Close()
+	cnt, err := session.TableSuffix("_2024_06").Count(new(SuffixedLog))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+	_, err = session.Count(new(SuffixedLog))
+	assert.Error(t, err)
+
+	_, err = testEngine.Table("suffixed_log").TableSuffix("_2024_05").Where("msg = ?", "may").
+		Cols("msg").Update(&SuffixedLog{Msg: "june"})
+	assert.NoError(t, err)
+	cnt, err = testEngine.TableSuffix("_2024_05").Where("msg = ?", "june").Count(new(SuffixedLog))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+}
@@ -27,6 +27,7 @@ type tagContext struct {
 	engine          *Engine
 	hasCacheTag     bool
 	hasNoCacheTag   bool
+	hasAuditedTag   bool
 	ignoreNext      bool
 }
 
@@ -36,30 +37,46 @@ type tagHandler func(ctx *tagContext) error
 var (
 	// defaultTagHandlers enumerates all the default tag handler
 	defaultTagHandlers = map[string]tagHandler{
-		"<-":       OnlyFromDBTagHandler,
-		"->":       OnlyToDBTagHandler,
-		"PK":       PKTagHandler,
-		"NULL":     NULLTagHandler,
-		"NOT":      IgnoreTagHandler,
-		"AUTOINCR": AutoIncrTagHandler,
-		"DEFAULT":  DefaultTagHandler,
-		"CREATED":  CreatedTagHandler,
-		"UPDATED":  UpdatedTagHandler,
-		"DELETED":  DeletedTagHandler,
-		"VERSION":  VersionTagHandler,
-		"UTC":      UTCTagHandler,
-		"LOCAL":    LocalTagHandler,
-		"NOTNULL":  NotNullTagHandler,
-		"INDEX":    IndexTagHandler,
-		"UNIQUE":   UniqueTagHandler,
-		"CACHE":    CacheTagHandler,
-		"NOCACHE":  NoCacheTagHandler,
+		"<-":        OnlyFromDBTagHandler,
+		"->":        OnlyToDBTagHandler,
+		"PK":        PKTagHandler,
+		"NULL":      NULLTagHandler,
+		"NOT":       IgnoreTagHandler,
+		"AUTOINCR":  AutoIncrTagHandler,
+		"DEFAULT":   DefaultTagHandler,
+		"CREATED":   CreatedTagHandler,
+		"UPDATED":   UpdatedTagHandler,
+		"DELETED":   DeletedTagHandler,
+		"VERSION":   VersionTagHandler,
+		"UTC":       UTCTagHandler,
+		"LOCAL":     LocalTagHandler,
+		"NOTNULL":   NotNullTagHandler,
+		"INDEX":     IndexTagHandler,
+		"UNIQUE":    UniqueTagHandler,
+		"CACHE":     CacheTagHandler,
+		"NOCACHE":   NoCacheTagHandler,
+		"AUDITED":   AuditedTagHandler,
+		"DURATION":  DurationTagHandler,
+		"INTERVAL":  IntervalTagHandler,
+		"UUID":      UUIDTagHandler,
+		"SERIALIZE": SerializeTagHandler,
+
+		"EMBEDDED_JSON": EmbeddedJSONTagHandler,
+		"OID":           SQLTypeTagHandler,
+		"INET":          SQLTypeTagHandler,
+		"CIDR":          SQLTypeTagHandler,
+		"MACADDR":       SQLTypeTagHandler,
+		"GROUPS":        GroupsTagHandler,
+		"IDGEN":         IDGenTagHandler,
+		"LAZY":          LazyTagHandler,
 	}
 )
 
 func init() {
 	for k := range core.SqlTypes {
-		defaultTagHandlers[k] = SQLTypeTagHandler
+		if _, ok := defaultTagHandlers[k]; !ok {
+			defaultTagHandlers[k] = SQLTypeTagHandler
+		}
 	}
 }
 
@@ -136,7 +153,9 @@ func CreatedTagHandler(ctx *tagContext) error {
 // VersionTagHandler describes version tag handler
 func VersionTagHandler(ctx *tagContext) error {
 	ctx.col.IsVersion = true
-	ctx.col.Default = "1"
+	if isCounterVersion(ctx.fieldValue.Type()) {
+		ctx.col.Default = "1"
+	}
 	return nil
 }
 
@@ -126,3 +126,102 @@ func TestVersion2(t *testing.T) {
 		}
 	}
 }
+
+type VersionTime struct {
+	Id   int64
+	Name string
+	Ver  time.Time `xorm:"version"`
+}
+
+type VersionToken struct {
+	Id   int64
+	Name string
+	Ver  string `xorm:"version varchar(36)"`
+}
+
+func TestVersionTime(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(VersionTime))
+
+	ver := &VersionTime{Name: "first"}
+	_, err := testEngine.Insert(ver)
+	assert.NoError(t, err)
+	assert.False(t, ver.Ver.IsZero())
+
+	// a stale version is updated by no record
+	stale := *ver
+	stale.Ver = ver.Ver.Add(-time.Hour)
+	cnt, err := testEngine.Id(ver.Id).Update(&VersionTime{Name: "second", Ver: stale.Ver})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+
+	cnt, err = testEngine.Id(ver.Id).Update(&VersionTime{Name: "second", Ver: ver.Ver})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+}
+
+func TestVersionToken(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(VersionToken))
+
+	ver := &VersionToken{Name: "first"}
+	_, err := testEngine.Insert(ver)
+	assert.NoError(t, err)
+	assert.Len(t, ver.Ver, 36)
+
+	var got VersionToken
+	has, err := testEngine.Id(ver.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, ver.Ver, got.Ver)
+
+	old := ver.Ver
+	ver.Name = "second"
+	cnt, err := testEngine.Id(ver.Id).Update(ver)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	assert.NotEqual(t, old, ver.Ver)
+
+	stale := VersionToken{Name: "third", Ver: old}
+	cnt, err = testEngine.Id(ver.Id).Update(&stale)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+}
+
+func TestVersionConflict(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(VersionS))
+	defer testEngine.SetVersionConflict(VersionConflictSilent)
+
+	ver := &VersionS{Name: "first"}
+	_, err := testEngine.Insert(ver)
+	assert.NoError(t, err)
+
+	cnt, err := testEngine.Id(ver.Id).Update(&VersionS{Name: "second", Ver: ver.Ver})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	testEngine.SetVersionConflict(VersionConflictError)
+	_, err = testEngine.Id(ver.Id).Update(&VersionS{Name: "third", Ver: ver.Ver})
+	assert.True(t, errors.Is(err, ErrOptimisticLock))
+	lockErr, ok := err.(*OptimisticLockError)
+	if assert.True(t, ok) {
+		assert.Nil(t, lockErr.Current)
+	}
+
+	testEngine.SetVersionConflict(VersionConflictRefetch)
+	_, err = testEngine.Id(ver.Id).Update(&VersionS{Name: "third", Ver: ver.Ver})
+	lockErr, ok = err.(*OptimisticLockError)
+	if assert.True(t, ok) {
+		current, ok := lockErr.Current.(*VersionS)
+		if assert.True(t, ok) {
+			assert.EqualValues(t, "second", current.Name)
+			assert.EqualValues(t, 2, current.Ver)
+		}
+	}
+
+	// a missing record is no conflict
+	cnt, err = testEngine.Id(ver.Id + 1).Update(&VersionS{Name: "third", Ver: ver.Ver})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+}
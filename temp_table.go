@@ -0,0 +1,141 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// tableKind is the kind of the tables created by CreateTable
+type tableKind int
+
+const (
+	tableRegular tableKind = iota
+	tableTemporary
+	tableUnlogged
+)
+
+// Temporary makes CreateTable create a temporary table, which only lives
+// on the connection creating it, on MySQL, PostgreSQL and SQLite
+func (statement *Statement) Temporary() *Statement {
+	statement.tableKind = tableTemporary
+	return statement
+}
+
+// Unlogged makes CreateTable create an unlogged table on PostgreSQL, which
+// is faster to write but isn't crash safe nor replicated
+func (statement *Statement) Unlogged() *Statement {
+	statement.tableKind = tableUnlogged
+	return statement
+}
+
+// createTableSQL returns the CREATE TABLE of the statement, of the kind of
+// table given by Temporary or Unlogged
+func (statement *Statement) createTableSQL() (string, error) {
+	sqlStr := statement.genCreateTableSQL()
+	if statement.tableKind == tableRegular {
+		return sqlStr, nil
+	}
+
+	var kind string
+	switch dbType := statement.Engine.dialect.DBType(); {
+	case statement.tableKind == tableTemporary && (dbType == core.MYSQL || dbType == core.SQLITE || dbType == core.POSTGRES):
+		kind = "TEMPORARY"
+	case statement.tableKind == tableUnlogged && dbType == core.POSTGRES:
+		kind = "UNLOGGED"
+	default:
+		return "", ErrNotImplemented
+	}
+	return strings.Replace(sqlStr, "CREATE TABLE", "CREATE "+kind+" TABLE", 1), nil
+}
+
+// TempTable creates the temporary table tableName with the columns of the
+// struct of bean and maps the struct to it in the next statements of the
+// transaction of the session, to stage records before merging them into
+// their table:
+//
+//	session.Begin()
+//	session.TempTable(new(User), "user_staging")
+//	session.Insert(&users) // into user_staging
+//	session.Exec("INSERT INTO user SELECT * FROM user_staging WHERE ...")
+//	session.Commit()
+//
+// A temporary table is seen by its connection only, the session needs a
+// transaction to keep it. The table is dropped at the end of the
+// transaction on PostgreSQL, and with the connection or by the next
+// TempTable of the same name on MySQL and SQLite.
+func (session *Session) TempTable(bean interface{}, tableName string) error {
+	if err := session.enterOperation(); err != nil {
+		return err
+	}
+	defer session.leaveOperation()
+
+	defer session.resetStatement()
+	if session.IsAutoClose {
+		defer session.Close()
+	}
+
+	if session.IsAutoCommit {
+		return ErrNeedTransaction
+	}
+
+	v := rValue(bean)
+	if err := session.Statement.setRefValue(v); err != nil {
+		return err
+	}
+	session.Statement.AltTableName = tableName
+
+	var dropSQL string
+	switch session.Engine.dialect.DBType() {
+	case core.MYSQL:
+		dropSQL = "DROP TEMPORARY TABLE IF EXISTS " + session.Engine.Quote(tableName)
+	case core.SQLITE:
+		dropSQL = "DROP TABLE IF EXISTS temp." + session.Engine.Quote(tableName)
+	case core.POSTGRES:
+	default:
+		return ErrNotImplemented
+	}
+	if dropSQL != "" {
+		if _, err := session.exec(dropSQL); err != nil {
+			return err
+		}
+	}
+
+	session.Statement.Temporary()
+	sqlStr, err := session.Statement.createTableSQL()
+	if err != nil {
+		return err
+	}
+	if session.Engine.dialect.DBType() == core.POSTGRES {
+		sqlStr += " ON COMMIT DROP"
+	}
+	if _, err := session.exec(sqlStr); err != nil {
+		return err
+	}
+
+	if session.tempTables == nil {
+		session.tempTables = make(map[reflect.Type]string)
+	}
+	t := v.Type()
+	session.tempTables[t] = tableName
+	forget := func() {
+		delete(session.tempTables, t)
+	}
+	session.OnCommit(forget).OnRollback(forget)
+	return nil
+}
+
+// tempTable returns the temporary table the struct type t is mapped to by
+// the session
+func (session *Session) tempTable(t reflect.Type) (string, bool) {
+	if session == nil || session.tempTables == nil {
+		return "", false
+	}
+	tableName, ok := session.tempTables[t]
+	return tableName, ok
+}
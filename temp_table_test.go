@@ -0,0 +1,95 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type StagedUser struct {
+	Id   int64
+	Name string
+}
+
+func TestCreateTableKind(t *testing.T) {
+	mock, err := NewMockEngineOf(core.POSTGRES)
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	assert.NoError(t, mock.Unlogged().CreateTable(new(StagedUser)))
+	assert.True(t, strings.HasPrefix(mock.LastRecord().SQL, `CREATE UNLOGGED TABLE IF NOT EXISTS "staged_user"`))
+	assert.NoError(t, mock.Temporary().CreateTable(new(StagedUser)))
+	assert.True(t, strings.HasPrefix(mock.LastRecord().SQL, `CREATE TEMPORARY TABLE IF NOT EXISTS "staged_user"`))
+	assert.NoError(t, mock.CreateTable(new(StagedUser)))
+	assert.True(t, strings.HasPrefix(mock.LastRecord().SQL, `CREATE TABLE IF NOT EXISTS "staged_user"`))
+
+	sqlite, err := NewMockEngine()
+	assert.NoError(t, err)
+	defer sqlite.Close()
+	assert.Equal(t, ErrNotImplemented, sqlite.Unlogged().CreateTable(new(StagedUser)))
+}
+
+func TestTempTableMock(t *testing.T) {
+	mock, err := NewMockEngineOf(core.POSTGRES)
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	session := mock.NewSession()
+	defer session.Close()
+	assert.Equal(t, ErrNeedTransaction, session.TempTable(new(StagedUser), "user_staging"))
+
+	assert.NoError(t, session.Begin())
+	assert.NoError(t, session.TempTable(new(StagedUser), "user_staging"))
+	assert.True(t, strings.HasPrefix(mock.LastRecord().SQL, `CREATE TEMPORARY TABLE IF NOT EXISTS "user_staging"`))
+	assert.True(t, strings.HasSuffix(mock.LastRecord().SQL, " ON COMMIT DROP"))
+
+	mock.On(`^INSERT`).Rows([]string{"id"}, []interface{}{1})
+	_, err = session.Insert(&StagedUser{Name: "lunny"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(mock.LastRecord().SQL, `INSERT INTO "user_staging"`))
+	assert.NoError(t, session.Commit())
+
+	// the struct is mapped to its table after the transaction
+	assert.NoError(t, session.Begin())
+	_, err = session.Insert(&StagedUser{Name: "xlw"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(mock.LastRecord().SQL, `INSERT INTO "staged_user"`))
+	assert.NoError(t, session.Commit())
+}
+
+func TestTempTable(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	switch testEngine.Dialect().DBType() {
+	case core.POSTGRES, core.MYSQL, core.SQLITE:
+	default:
+		t.Skip("temporary tables aren't supported")
+	}
+	assertSync(t, new(StagedUser))
+
+	session := testEngine.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+	assert.NoError(t, session.TempTable(new(StagedUser), "staged_user_tmp"))
+	_, err := session.Insert(&StagedUser{Id: 1, Name: "lunny"}, &StagedUser{Id: 2, Name: "xlw"})
+	assert.NoError(t, err)
+
+	cnt, err := session.Count(new(StagedUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cnt)
+
+	quote := testEngine.Quote
+	_, err = session.Exec("INSERT INTO "+quote("staged_user")+" SELECT * FROM "+quote("staged_user_tmp")+
+		" WHERE "+quote("name")+" = ?", "xlw")
+	assert.NoError(t, err)
+	assert.NoError(t, session.Commit())
+
+	var users []StagedUser
+	assert.NoError(t, testEngine.Find(&users))
+	assert.EqualValues(t, []StagedUser{{2, "xlw"}}, users)
+}
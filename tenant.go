@@ -0,0 +1,75 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import "regexp"
+
+// tenantIdentifier matches the tenants which can be put in the names of the
+// tables and schemas, the dialects quote the names without escaping them
+var tenantIdentifier = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// TenantStrategy decides how the tenant of a session is applied to the
+// statements of the session
+type TenantStrategy interface {
+	// TableName returns the table name used by the tenant
+	TableName(tenant, tableName string) string
+	// Column returns the column storing the tenant, records are filtered
+	// on it. An empty string means no column is used.
+	Column() string
+}
+
+type tablePrefixTenant struct {
+	separator string
+}
+
+// TablePrefixTenantStrategy uses a table per tenant which is named as the
+// tenant, the separator and the table name. The tenants may only contain
+// letters, digits and underscores.
+func TablePrefixTenantStrategy(separator string) TenantStrategy {
+	return tablePrefixTenant{separator}
+}
+
+func (s tablePrefixTenant) TableName(tenant, tableName string) string {
+	return tenant + s.separator + tableName
+}
+
+func (s tablePrefixTenant) Column() string {
+	return ""
+}
+
+type schemaTenant struct{}
+
+// SchemaTenantStrategy uses a schema per tenant which is named as the tenant.
+// The tenants may only contain letters, digits and underscores.
+func SchemaTenantStrategy() TenantStrategy {
+	return schemaTenant{}
+}
+
+func (schemaTenant) TableName(tenant, tableName string) string {
+	return tenant + "." + tableName
+}
+
+func (schemaTenant) Column() string {
+	return ""
+}
+
+type columnTenant struct {
+	column string
+}
+
+// ColumnTenantStrategy stores the records of all the tenants in the same
+// table, every query, update and delete has a mandatory condition on the
+// column and inserts fill it. Tables without the column are not filtered.
+func ColumnTenantStrategy(column string) TenantStrategy {
+	return columnTenant{column}
+}
+
+func (s columnTenant) TableName(tenant, tableName string) string {
+	return tableName
+}
+
+func (s columnTenant) Column() string {
+	return s.column
+}
@@ -0,0 +1,181 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantStrategies(t *testing.T) {
+	assert.EqualValues(t, "acme_user", TablePrefixTenantStrategy("_").TableName("acme", "user"))
+	assert.EqualValues(t, "", TablePrefixTenantStrategy("_").Column())
+	assert.EqualValues(t, "acme.user", SchemaTenantStrategy().TableName("acme", "user"))
+	assert.EqualValues(t, "user", ColumnTenantStrategy("tenant").TableName("acme", "user"))
+	assert.EqualValues(t, "tenant", ColumnTenantStrategy("tenant").Column())
+}
+
+func TestTablePrefixTenant(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	type TenantPrefixUser struct {
+		Id   int64
+		Name string
+	}
+
+	testEngine.SetTenantStrategy(TablePrefixTenantStrategy("_"))
+	defer testEngine.SetTenantStrategy(nil)
+
+	for _, tenant := range []string{"acme", "globex"} {
+		assert.NoError(t, testEngine.Tenant(tenant).CreateTable(new(TenantPrefixUser)))
+	}
+
+	session := testEngine.NewSession()
+	defer session.Close()
+
+	session.Tenant("acme")
+	cnt, err := session.Insert(&TenantPrefixUser{Name: "a"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	// the tenant is kept by the following statements of the session
+	total, err := session.Count(new(TenantPrefixUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+
+	total, err = testEngine.Tenant("globex").Count(new(TenantPrefixUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, total)
+
+	total, err = testEngine.Table("acme_tenant_prefix_user").Count(new(TenantPrefixUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+}
+
+func TestInvalidTenant(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	type TenantInvalidUser struct {
+		Id   int64
+		Name string
+	}
+
+	for _, strategy := range []TenantStrategy{TablePrefixTenantStrategy("_"), SchemaTenantStrategy()} {
+		testEngine.SetTenantStrategy(strategy)
+		for _, tenant := range []string{"acme`; DROP TABLE user; --", `acme"`, "acme.globex", "acme-1"} {
+			err := testEngine.Tenant(tenant).CreateTable(new(TenantInvalidUser))
+			assert.EqualValues(t, ErrInvalidTenant, err)
+
+			_, err = testEngine.Tenant(tenant).Count(new(TenantInvalidUser))
+			assert.EqualValues(t, ErrInvalidTenant, err)
+		}
+	}
+
+	// the tenants of a column are values, they are not checked
+	testEngine.SetTenantStrategy(ColumnTenantStrategy("tenant"))
+	defer testEngine.SetTenantStrategy(nil)
+	assert.NoError(t, testEngine.Tenant("acme-1").Sync2(new(TenantInvalidUser)))
+	_, err := testEngine.Tenant("acme-1").Count(new(TenantInvalidUser))
+	assert.NoError(t, err)
+}
+
+func TestColumnTenant(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	type TenantColumnUser struct {
+		Id     int64
+		Tenant string
+		Name   string
+	}
+
+	assert.NoError(t, testEngine.Sync2(new(TenantColumnUser)))
+
+	testEngine.SetTenantStrategy(ColumnTenantStrategy("tenant"))
+	defer testEngine.SetTenantStrategy(nil)
+
+	cnt, err := testEngine.Tenant("acme").Insert(&TenantColumnUser{Name: "a1"}, &TenantColumnUser{Name: "a2"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cnt)
+
+	cnt, err = testEngine.Tenant("globex").Insert(&[]TenantColumnUser{{Name: "g1"}})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var users []TenantColumnUser
+	assert.NoError(t, testEngine.Tenant("acme").Find(&users))
+	assert.EqualValues(t, 2, len(users))
+	assert.EqualValues(t, "acme", users[0].Tenant)
+
+	total, err := testEngine.Tenant("globex").Count(new(TenantColumnUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+
+	var user TenantColumnUser
+	has, err := testEngine.Tenant("globex").Where("name = ?", "a1").Get(&user)
+	assert.NoError(t, err)
+	assert.False(t, has)
+
+	cnt, err = testEngine.Tenant("globex").Where("name = ?", "a1").Update(&TenantColumnUser{Name: "x"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+
+	cnt, err = testEngine.Tenant("globex").Where("name = ?", "a2").Delete(new(TenantColumnUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+
+	cnt, err = testEngine.Tenant("acme").Where("name = ?", "a2").Delete(new(TenantColumnUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	// the tenant is not a condition of the caller
+	_, err = testEngine.Tenant("acme").Delete(new(TenantColumnUser))
+	assert.EqualValues(t, ErrNeedDeletedCond, err)
+
+	total, err = testEngine.Count(new(TenantColumnUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+}
+
+func TestColumnTenantMap(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	type TenantMapUser struct {
+		Id     int64
+		Tenant string
+		Name   string
+	}
+
+	assert.NoError(t, testEngine.Sync2(new(TenantMapUser)))
+
+	testEngine.SetTenantStrategy(ColumnTenantStrategy("tenant"))
+	defer testEngine.SetTenantStrategy(nil)
+
+	// the maps are inserted for the tenant of the session
+	cnt, err := testEngine.Tenant("acme").Table(new(TenantMapUser)).Insert(map[string]interface{}{"name": "a1"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	_, err = testEngine.Tenant("acme").Table(new(TenantMapUser)).Insert(map[string]interface{}{"name": "a2", "tenant": "globex"})
+	assert.Error(t, err)
+
+	var user TenantMapUser
+	has, err := testEngine.Tenant("acme").Where("name = ?", "a1").Get(&user)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "acme", user.Tenant)
+
+	// nor are they moved to another tenant
+	_, err = testEngine.Tenant("acme").Table(new(TenantMapUser)).ID(user.Id).
+		Update(map[string]interface{}{"tenant": "globex"})
+	assert.Error(t, err)
+	cnt, err = testEngine.Tenant("acme").Table(new(TenantMapUser)).ID(user.Id).
+		Update(map[string]interface{}{"name": "x", "tenant": "acme"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	total, err := testEngine.Tenant("globex").Count(new(TenantMapUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, total)
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// SetTZLocation sets the timezone of the application, the times scanned
+// and the times of the created, updated and deleted columns are in it
+func (engine *Engine) SetTZLocation(tz *time.Location) {
+	engine.TZLocation = tz
+}
+
+// SetTZDatabase sets the timezone the times are stored in, the times of the
+// columns are bound in it and the times read without zone are in it. The
+// utc and local tags of the columns win. The time args of the conditions
+// and of the raw SQLs are bound as they are.
+func (engine *Engine) SetTZDatabase(tz *time.Location) {
+	engine.DatabaseTZ = tz
+}
+
+// SetClock sets the clock of the created, updated and deleted columns and
+// of the time versions, as a frozen time of the tests. nil is time.Now.
+func (engine *Engine) SetClock(clock func() time.Time) {
+	engine.clock = clock
+}
+
+// now returns the current time of the clock of the engine
+func (engine *Engine) now() time.Time {
+	if engine.clock != nil {
+		return engine.clock()
+	}
+	return time.Now()
+}
+
+// columnTZ returns the timezone the times of col are stored in, col is nil
+// for an arg
+func (engine *Engine) columnTZ(col *core.Column) *time.Location {
+	if col != nil && col.TimeZone != nil {
+		return col.TimeZone
+	}
+	return engine.DatabaseTZ
+}
+
+// nowTime returns the current time bound for the created, updated or
+// deleted column col and the time in the timezone of the application
+func (engine *Engine) nowTime(col *core.Column) (interface{}, time.Time) {
+	t := engine.now()
+	return engine.formatTime(col.SQLType.Name, t.In(engine.columnTZ(col))), t.In(engine.TZLocation)
+}
+
+// timeFromDB returns the time scanned from col in the timezone of the
+// application, a time without zone is in the timezone of the column
+func (engine *Engine) timeFromDB(col *core.Column, t time.Time) time.Time {
+	dbTZ := engine.columnTZ(col)
+	z, _ := t.Zone()
+	// set new location if database don't save timezone or give an incorrect timezone
+	if len(z) == 0 || t.Year() == 0 || t.Location().String() != dbTZ.String() { // !nashtsai! HACK tmp work around for lib/pq doesn't properly time with location
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(),
+			t.Minute(), t.Second(), t.Nanosecond(), dbTZ)
+	}
+	return t.In(engine.TZLocation)
+}
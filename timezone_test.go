@@ -0,0 +1,114 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TimezoneEvent struct {
+	Id        int64
+	Name      string
+	At        time.Time
+	CreatedAt time.Time `xorm:"created utc"`
+	UpdatedAt time.Time `xorm:"updated"`
+}
+
+func TestTimezones(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	appTZ, dbTZ := testEngine.TZLocation, testEngine.DatabaseTZ
+	defer func() {
+		testEngine.SetTZLocation(appTZ)
+		testEngine.SetTZDatabase(dbTZ)
+	}()
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	assert.NoError(t, err)
+	testEngine.SetTZLocation(loc)
+	dbLoc, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+	testEngine.SetTZDatabase(dbLoc)
+
+	assertSync(t, new(TimezoneEvent))
+
+	var at = time.Date(2017, 6, 1, 12, 30, 0, 0, time.UTC)
+	var event = TimezoneEvent{Name: "launch", At: at}
+	_, err = testEngine.Insert(&event)
+	assert.NoError(t, err)
+	assert.EqualValues(t, loc, event.CreatedAt.Location())
+	assert.EqualValues(t, loc, event.UpdatedAt.Location())
+
+	// the columns are stored in the timezone of the database or of their tags
+	tableName := testEngine.TableInfo(new(TimezoneEvent)).Name
+	results, err := testEngine.QueryString("SELECT " + testEngine.Quote("at") + ", " +
+		testEngine.Quote("created_at") + ", " + testEngine.Quote("updated_at") + " FROM " + testEngine.Quote(tableName))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, len(results))
+	// the drivers may return the DATETIME columns as times
+	var stored = func(name string) string {
+		return strings.Replace(results[0][name], "T", " ", 1)
+	}
+	assert.Contains(t, stored("at"), at.In(dbLoc).Format("2006-01-02 15:04:05"))
+	assert.Contains(t, stored("created_at"), event.CreatedAt.In(time.UTC).Format("2006-01-02 15:04:05"))
+	assert.Contains(t, stored("updated_at"), event.UpdatedAt.In(dbLoc).Format("2006-01-02 15:04:05"))
+
+	// the times are scanned in the timezone of the application
+	var got TimezoneEvent
+	has, err := testEngine.ID(event.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, at.Unix(), got.At.Unix())
+	assert.EqualValues(t, loc, got.At.Location())
+	assert.EqualValues(t, event.CreatedAt.Unix(), got.CreatedAt.Unix())
+	assert.EqualValues(t, loc, got.CreatedAt.Location())
+	assert.EqualValues(t, event.UpdatedAt.Unix(), got.UpdatedAt.Unix())
+
+	// the times of the columns of the condition beans are bound in the
+	// timezone of the database
+	cnt, err := testEngine.Count(&TimezoneEvent{At: at})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+}
+
+type ClockEvent struct {
+	Id        int64
+	Name      string
+	CreatedAt time.Time `xorm:"created"`
+	UpdatedAt time.Time `xorm:"updated"`
+	DeletedAt time.Time `xorm:"deleted"`
+}
+
+func TestSetClock(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(ClockEvent))
+
+	now := time.Date(2017, 3, 4, 5, 6, 7, 0, time.UTC)
+	testEngine.SetClock(func() time.Time { return now })
+	defer testEngine.SetClock(nil)
+
+	event := ClockEvent{Name: "start"}
+	_, err := testEngine.Insert(&event)
+	assert.NoError(t, err)
+	assert.EqualValues(t, now.Unix(), event.CreatedAt.Unix())
+	assert.EqualValues(t, now.Unix(), event.UpdatedAt.Unix())
+
+	now = now.Add(time.Hour)
+	_, err = testEngine.ID(event.Id).Update(&ClockEvent{Name: "stop"})
+	assert.NoError(t, err)
+	_, err = testEngine.ID(event.Id).Delete(new(ClockEvent))
+	assert.NoError(t, err)
+
+	var got ClockEvent
+	has, err := testEngine.ID(event.Id).Unscoped().Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, now.Add(-time.Hour).Unix(), got.CreatedAt.Unix())
+	assert.EqualValues(t, now.Unix(), got.UpdatedAt.Unix())
+	assert.EqualValues(t, now.Unix(), got.DeletedAt.Unix())
+}
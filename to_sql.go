@@ -0,0 +1,183 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// ToSQL returns the statement and the args which the operation run, as a
+// Find, an Update, a Delete or an Insert of the session, would execute
+// with the conditions of the session, without executing it. The hooks of
+// the beans run before the statement is generated.
+//
+//	sqlStr, args, err := engine.Where("age > ?", 10).ToSQL(func(session *xorm.Session) error {
+//		return session.Find(&users)
+//	})
+func (session *Session) ToSQL(run func(*Session) error) (string, []interface{}, error) {
+	if err := session.enterOperation(); err != nil {
+		return "", nil, err
+	}
+	defer session.leaveOperation()
+
+	if session.IsAutoClose {
+		session.IsAutoClose = false
+		defer session.Close()
+	}
+
+	captured := &QueryPlan{}
+	session.explaining = captured
+	err := run(session.NoCache())
+	session.explaining = nil
+	if err != errExplained {
+		if err == nil {
+			err = errors.New("no statement to convert")
+		}
+		return "", nil, err
+	}
+	return captured.SQL, captured.Args, nil
+}
+
+// ToBoundSQL returns the statement of ToSQL with its args written as the
+// literals of the dialect, for the logs and the debugging. The statements
+// with args should be executed instead of the bound ones.
+func (session *Session) ToBoundSQL(run func(*Session) error) (string, error) {
+	sqlStr, args, err := session.ToSQL(run)
+	if err != nil {
+		return "", err
+	}
+	return bindSQL(session.Engine.dialect, sqlStr, args)
+}
+
+// ToSQL returns the statement and the args which the operation run would
+// execute
+func (engine *Engine) ToSQL(run func(*Session) error) (string, []interface{}, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.ToSQL(run)
+}
+
+// ToBoundSQL returns the statement which the operation run would execute
+// with its args written as literals
+func (engine *Engine) ToBoundSQL(run func(*Session) error) (string, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.ToBoundSQL(run)
+}
+
+// bindSQL replaces the placeholders of sqlStr, the ? and the numbered
+// ones of postgres and oracle, by the literals of args. The quoted strings
+// and names are kept.
+func bindSQL(dialect core.Dialect, sqlStr string, args []interface{}) (string, error) {
+	var buf strings.Builder
+	var next int
+	for i := 0; i < len(sqlStr); i++ {
+		c := sqlStr[i]
+		switch c {
+		case '\'', '"', '`':
+			end := strings.IndexByte(sqlStr[i+1:], c)
+			if end < 0 {
+				buf.WriteString(sqlStr[i:])
+				i = len(sqlStr)
+				continue
+			}
+			buf.WriteString(sqlStr[i : i+end+2])
+			i += end + 1
+			continue
+		case '?', '$', ':':
+			idx := next
+			j := i + 1
+			if c != '?' {
+				for j < len(sqlStr) && sqlStr[j] >= '0' && sqlStr[j] <= '9' {
+					j++
+				}
+				if j == i+1 {
+					break
+				}
+				n, _ := strconv.Atoi(sqlStr[i+1 : j])
+				idx = n - 1
+			}
+			if idx < 0 || idx >= len(args) {
+				return "", fmt.Errorf("no arg of the placeholder %v", sqlStr[i:j])
+			}
+			literal, err := sqlLiteral(dialect, args[idx])
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(literal)
+			next++
+			i = j - 1
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String(), nil
+}
+
+// sqlLiteral returns the literal of the dialect of an arg
+func sqlLiteral(dialect core.Dialect, arg interface{}) (string, error) {
+	if valuer, ok := arg.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", err
+		}
+		arg = v
+	}
+	if arg == nil {
+		return "NULL", nil
+	}
+
+	switch v := arg.(type) {
+	case string:
+		return quoteLiteral(dialect, v), nil
+	case []byte:
+		return dialect.FormatBytes(v), nil
+	case bool:
+		switch dialect.DBType() {
+		case core.MYSQL, core.POSTGRES:
+			return strings.ToUpper(strconv.FormatBool(v)), nil
+		}
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case time.Time:
+		return quoteLiteral(dialect, v.Format("2006-01-02 15:04:05.999999999")), nil
+	}
+
+	val := reflect.ValueOf(arg)
+	switch val.Kind() {
+	case reflect.Ptr:
+		if val.IsNil() {
+			return "NULL", nil
+		}
+		return sqlLiteral(dialect, val.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(val.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(val.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(val.Float(), 'g', -1, val.Type().Bits()), nil
+	case reflect.String:
+		return quoteLiteral(dialect, val.String()), nil
+	}
+	return quoteLiteral(dialect, fmt.Sprint(arg)), nil
+}
+
+// quoteLiteral quotes a string, the backslashes are escapes of mysql
+func quoteLiteral(dialect core.Dialect, s string) string {
+	if dialect.DBType() == core.MYSQL {
+		s = strings.Replace(s, `\`, `\\`, -1)
+	}
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
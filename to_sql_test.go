@@ -0,0 +1,88 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type ToSQLUser struct {
+	Id   int64
+	Name string
+	Age  int
+}
+
+func TestToSQL(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(ToSQLUser))
+
+	var users []ToSQLUser
+	sqlStr, args, err := testEngine.Where("age > ?", 10).Desc("id").ToSQL(func(session *Session) error {
+		return session.Find(&users)
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, sqlStr, "FROM "+testEngine.Quote("to_s_q_l_user"))
+	assert.Contains(t, sqlStr, "age >")
+	assert.EqualValues(t, []interface{}{10}, args)
+
+	sqlStr, args, err = testEngine.ID(1).ToSQL(func(session *Session) error {
+		_, err := session.Cols("name").Update(&ToSQLUser{Name: "lunny"})
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, sqlStr, "UPDATE")
+	assert.EqualValues(t, []interface{}{"lunny", 1}, args)
+
+	_, args, err = testEngine.ToSQL(func(session *Session) error {
+		_, err := session.Insert(&ToSQLUser{Name: "xlw", Age: 20})
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Len(t, args, 2)
+
+	// nothing is executed
+	cnt, err := testEngine.Count(new(ToSQLUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+
+	session := testEngine.NewSession()
+	defer session.Close()
+	_, _, err = session.ToSQL(func(session *Session) error {
+		return nil
+	})
+	assert.Error(t, err)
+
+	bound, err := session.Where("name = ?", "o'neil").ToBoundSQL(func(session *Session) error {
+		_, err := session.Delete(new(ToSQLUser))
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, bound, "name = 'o''neil'")
+	assert.NotContains(t, bound, "?")
+}
+
+func TestBindSQL(t *testing.T) {
+	dialectOf := func(dialect core.Dialect, dbType core.DbType) core.Dialect {
+		assert.NoError(t, dialect.Init(nil, &core.Uri{DbType: dbType}, "", ""))
+		return dialect
+	}
+	pg := dialectOf(&postgres{}, core.POSTGRES)
+	when := time.Date(2017, 4, 5, 6, 7, 8, 0, time.UTC)
+	bound, err := bindSQL(pg, `SELECT '$1', "a?" FROM t WHERE a = $2 AND b = $1 AND c::text = $3 AND d IN ($4, $5)`,
+		[]interface{}{"x", 1.5, true, nil, when})
+	assert.NoError(t, err)
+	assert.EqualValues(t, `SELECT '$1', "a?" FROM t WHERE a = 1.5 AND b = 'x' AND c::text = TRUE AND d IN (NULL, '2017-04-05 06:07:08')`, bound)
+
+	_, err = bindSQL(pg, "SELECT ? , ?", []interface{}{1})
+	assert.Error(t, err)
+
+	bound, err = bindSQL(dialectOf(&mysql{}, core.MYSQL), "SELECT ?, ?", []interface{}{`a\'b`, uint8(3)})
+	assert.NoError(t, err)
+	assert.EqualValues(t, `SELECT 'a\\''b', 3`, bound)
+}
@@ -0,0 +1,65 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+
+	"github.com/go-xorm/core"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// nullableValueType returns the type of the value of a nullable type, as
+// sql.NullString, sql.Null[T] or Null[T]: a scanner and valuer struct of
+// the value and of a Valid bool
+func nullableValueType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Struct || t.NumField() != 2 {
+		return nil, false
+	}
+	if valid := t.Field(1); valid.Name != "Valid" || valid.Type.Kind() != reflect.Bool {
+		return nil, false
+	}
+	if !reflect.PtrTo(t).Implements(scannerType) || !t.Implements(valuerType) {
+		return nil, false
+	}
+	return t.Field(0).Type, true
+}
+
+// type2SQLType returns the SQL type of a field, the nullable types and the
+// pointers have the type of their value
+func type2SQLType(t reflect.Type) core.SQLType {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if vt, ok := nullableValueType(t); ok {
+		return type2SQLType(vt)
+	}
+	return core.Type2SQLType(t)
+}
+
+// setNull sets a field to a NULL of the database: nil for the pointers and
+// an invalid value for the scanners, the other fields are kept
+func (session *Session) setNull(fieldValue *reflect.Value) {
+	if fieldValue.Kind() == reflect.Ptr {
+		if !fieldValue.IsNil() {
+			fieldValue.Set(reflect.Zero(fieldValue.Type()))
+		}
+		return
+	}
+	if !fieldValue.CanAddr() {
+		return
+	}
+	if scanner, ok := fieldValue.Addr().Interface().(sql.Scanner); ok {
+		if err := scanner.Scan(nil); err != nil {
+			session.getLogger().Error("sql.Scanner error:", err.Error())
+		}
+	}
+}
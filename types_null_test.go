@@ -402,3 +402,103 @@ func TestNullStructDelete(t *testing.T) {
 		panic(err)
 	}
 }
+
+type NullPtrExtend struct {
+	Nick *string
+	Rank sql.NullInt64
+}
+
+type NullPtrType struct {
+	Id     int64 `xorm:"pk autoincr"`
+	Name   *string
+	Age    *int
+	Height *sql.NullFloat64
+	Extend NullPtrExtend `xorm:"extends"`
+}
+
+func TestNullPtrRoundTrip(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(NullPtrType))
+
+	name, age, nick := "lunny", 34, "lun"
+	item := NullPtrType{
+		Name:   &name,
+		Age:    &age,
+		Height: &sql.NullFloat64{Float64: 1.72, Valid: true},
+		Extend: NullPtrExtend{
+			Nick: &nick,
+			Rank: sql.NullInt64{Int64: 3, Valid: true},
+		},
+	}
+	cnt, err := testEngine.Insert(&item)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var got NullPtrType
+	has, err := testEngine.Id(item.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, name, *got.Name)
+	assert.EqualValues(t, age, *got.Age)
+	assert.NotNil(t, got.Height)
+	assert.EqualValues(t, 1.72, got.Height.Float64)
+	assert.EqualValues(t, nick, *got.Extend.Nick)
+	assert.EqualValues(t, 3, got.Extend.Rank.Int64)
+
+	// the nil pointers and the invalid values update the columns to NULL
+	cnt, err = testEngine.Id(item.Id).Cols("name", "age", "height",
+		testEngine.ColumnMapper.Obj2Table("Nick"), testEngine.ColumnMapper.Obj2Table("Rank")).
+		Update(&NullPtrType{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	// a NULL resets the fields of the bean
+	has, err = testEngine.Id(item.Id).NoAutoCondition().Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.Nil(t, got.Name)
+	assert.Nil(t, got.Age)
+	assert.Nil(t, got.Height)
+	assert.Nil(t, got.Extend.Nick)
+	assert.False(t, got.Extend.Rank.Valid)
+
+	cnt, err = testEngine.Insert(&NullPtrType{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var items []NullPtrType
+	assert.NoError(t, testEngine.Asc("id").Find(&items))
+	assert.EqualValues(t, 2, len(items))
+	for _, it := range items {
+		assert.Nil(t, it.Name)
+		assert.Nil(t, it.Age)
+		assert.Nil(t, it.Height)
+		assert.Nil(t, it.Extend.Nick)
+		assert.False(t, it.Extend.Rank.Valid)
+	}
+
+	total, err := testEngine.Where("age IS NULL").Count(new(NullPtrType))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+}
+
+func TestNullPtrMustCols(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(NullPtrType))
+
+	age := 20
+	item := NullPtrType{Age: &age}
+	_, err := testEngine.Insert(&item)
+	assert.NoError(t, err)
+
+	// a nil pointer of a MustCols column is set to NULL
+	cnt, err := testEngine.Id(item.Id).MustCols("age").Update(&NullPtrType{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var got NullPtrType
+	has, err := testEngine.Id(item.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.Nil(t, got.Age)
+}
@@ -0,0 +1,177 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-xorm/core"
+)
+
+// ULID is a ULID, an id of 128 bits sorted by its creation time: 48 bits
+// of Unix milliseconds then 80 random bits. It's stored as its 26
+// characters in the text columns and as its bytes in the binary ones.
+type ULID [16]byte
+
+// the Crockford's base32 of the ULIDs
+const ulidEncoding = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var ulidState struct {
+	sync.Mutex
+	last ULID
+}
+
+// NewULID returns a new ULID, greater than the ones returned before by the
+// process: in the same millisecond the random bits of the last ULID are
+// incremented
+func NewULID() ULID {
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+
+	ulidState.Lock()
+	defer ulidState.Unlock()
+
+	var id ULID
+	if last := ulidState.last; ms <= last.ms() {
+		id = last
+		if !id.incrRandom() {
+			// the random bits overflowed, the next millisecond is taken
+			id.setMs(last.ms() + 1)
+			readULIDRandom(&id)
+		}
+	} else {
+		id.setMs(ms)
+		readULIDRandom(&id)
+	}
+	ulidState.last = id
+	return id
+}
+
+func readULIDRandom(id *ULID) {
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(err)
+	}
+}
+
+func (id ULID) ms() uint64 {
+	return uint64(id[0])<<40 | uint64(id[1])<<32 | uint64(binary.BigEndian.Uint32(id[2:6]))
+}
+
+func (id *ULID) setMs(ms uint64) {
+	id[0], id[1] = byte(ms>>40), byte(ms>>32)
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+}
+
+// incrRandom increments the random bits, it returns false if they
+// overflowed
+func (id *ULID) incrRandom() bool {
+	for i := len(id) - 1; i >= 6; i-- {
+		id[i]++
+		if id[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Time returns the creation time of the ULID
+func (id ULID) Time() time.Time {
+	ms := int64(id.ms())
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond))
+}
+
+// String returns the 26 characters of the ULID
+func (id ULID) String() string {
+	hi, lo := binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+	var buf [26]byte
+	for i := range buf {
+		shift := uint(25-i) * 5
+		var n uint64
+		switch {
+		case shift >= 64:
+			n = hi >> (shift - 64)
+		case shift+5 <= 64:
+			n = lo >> shift
+		default:
+			n = lo>>shift | hi<<(64-shift)
+		}
+		buf[i] = ulidEncoding[n&31]
+	}
+	return string(buf[:])
+}
+
+// ParseULID parses the 26 characters of a ULID, case-insensitively
+func ParseULID(s string) (ULID, error) {
+	var id ULID
+	if len(s) != 26 {
+		return id, fmt.Errorf("invalid ULID %q", s)
+	}
+	var hi, lo uint64
+	for i, c := range strings.ToUpper(s) {
+		n := strings.IndexRune(ulidEncoding, c)
+		if n < 0 || (i == 0 && n > 7) {
+			return id, fmt.Errorf("invalid ULID %q", s)
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(n)
+	}
+	binary.BigEndian.PutUint64(id[:8], hi)
+	binary.BigEndian.PutUint64(id[8:], lo)
+	return id, nil
+}
+
+// ulidGenerator generates the ULIDs of the IDGEN(ulid) tags, as ULIDs, as
+// their text for the strings or as their bytes for the byte slices
+type ulidGenerator struct{}
+
+var ulidType = reflect.TypeOf(ULID{})
+
+func (ulidGenerator) NewID(t reflect.Type) (interface{}, error) {
+	id := NewULID()
+	switch {
+	case t.Kind() == reflect.String:
+		return id.String(), nil
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return id[:], nil
+	case ulidType.ConvertibleTo(t):
+		return id, nil
+	}
+	return nil, fmt.Errorf("unsupported ULID field %v", t)
+}
+
+func init() {
+	builtinConverters[ulidType] = func(engine *Engine, col *core.Column) Converter {
+		binary := col != nil && col.SQLType.IsBlob()
+		return ConverterFuncs{
+			Type: core.SQLType{Name: core.Char, DefaultLength: 26},
+			To: func(v interface{}) (interface{}, error) {
+				id := v.(ULID)
+				if binary {
+					return id[:], nil
+				}
+				return id.String(), nil
+			},
+			From: func(src interface{}) (interface{}, error) {
+				switch v := src.(type) {
+				case []byte:
+					if len(v) == 16 {
+						var id ULID
+						copy(id[:], v)
+						return id, nil
+					}
+					return ParseULID(string(v))
+				case string:
+					return ParseULID(v)
+				}
+				return nil, fmt.Errorf("unsupported ULID %T", src)
+			},
+		}
+	}
+}
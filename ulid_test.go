@@ -0,0 +1,98 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestULID(t *testing.T) {
+	id, err := ParseULID("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "01ARZ3NDEKTSV4RRFFQ69G5FAV", id.String())
+	assert.EqualValues(t, int64(1469922850259), id.Time().UnixNano()/int64(time.Millisecond))
+
+	lower, err := ParseULID("01arz3ndektsv4rrffq69g5fav")
+	assert.NoError(t, err)
+	assert.EqualValues(t, id, lower)
+
+	_, err = ParseULID("81ARZ3NDEKTSV4RRFFQ69G5FAV")
+	assert.Error(t, err)
+	_, err = ParseULID("01ARZ3NDEKTSV4RRFFQ69G5FA")
+	assert.Error(t, err)
+
+	// the ULIDs of the process are increasing, as their texts
+	var ids = make([]ULID, 1000)
+	var texts = make([]string, len(ids))
+	for i := range ids {
+		ids[i] = NewULID()
+		texts[i] = ids[i].String()
+	}
+	for i := 1; i < len(ids); i++ {
+		assert.True(t, bytes.Compare(ids[i-1][:], ids[i][:]) < 0)
+	}
+	assert.True(t, sort.StringsAreSorted(texts))
+	assert.WithinDuration(t, time.Now(), ids[0].Time(), time.Minute)
+}
+
+type ULIDEvent struct {
+	Id   ULID `xorm:"pk idgen(ulid)"`
+	Name string
+}
+
+type ULIDTextEvent struct {
+	Id   string `xorm:"pk varchar(26) idgen"`
+	Name string
+}
+
+type ULIDBinaryEvent struct {
+	Id   ULID `xorm:"pk binary(16) idgen(ulid)"`
+	Name string
+}
+
+func TestInsertULID(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(ULIDEvent), new(ULIDTextEvent), new(ULIDBinaryEvent))
+
+	var events = []ULIDEvent{{Name: "first"}, {Name: "second"}}
+	_, err := testEngine.Insert(&events)
+	assert.NoError(t, err)
+	assert.NotEqual(t, ULID{}, events[0].Id)
+	assert.True(t, events[0].Id.String() < events[1].Id.String())
+
+	var sorted []ULIDEvent
+	assert.NoError(t, testEngine.Asc("id").Find(&sorted))
+	assert.EqualValues(t, events, sorted)
+
+	// the ids set aren't generated
+	var set = ULIDEvent{Id: NewULID(), Name: "set"}
+	_, err = testEngine.Insert(&set)
+	assert.NoError(t, err)
+	var got ULIDEvent
+	has, err := testEngine.ID(set.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, set, got)
+
+	var text = ULIDTextEvent{Name: "text"}
+	_, err = testEngine.Insert(&text)
+	assert.NoError(t, err)
+	_, err = ParseULID(text.Id)
+	assert.NoError(t, err)
+
+	var binary = ULIDBinaryEvent{Name: "binary"}
+	_, err = testEngine.Insert(&binary)
+	assert.NoError(t, err)
+	var gotBinary ULIDBinaryEvent
+	has, err = testEngine.ID(binary.Id).Get(&gotBinary)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, binary, gotBinary)
+}
@@ -0,0 +1,244 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// upsertParam is the conflict target and the updated columns of Upsert
+type upsertParam struct {
+	enabled    bool
+	columns    []string // the columns of the conflicting unique index
+	constraint string   // or the name of the conflicting constraint
+	where      string   // the predicate of the conflicting partial index
+	updates    []string // the updated columns, the inserted ones when empty
+	keeps      []string // the inserted columns which aren't updated
+}
+
+// OnConflict makes Upsert update the record conflicting by the unique index
+// of columns, which the tables of several unique indexes need
+func (statement *Statement) OnConflict(columns ...string) *Statement {
+	statement.upsert.columns = append(statement.upsert.columns, columns...)
+	return statement
+}
+
+// OnConstraint makes Upsert update the record conflicting by the unique
+// constraint name, PostgreSQL only
+func (statement *Statement) OnConstraint(name string) *Statement {
+	statement.upsert.constraint = name
+	return statement
+}
+
+// ConflictWhere is the predicate of the partial unique index of the columns
+// of OnConflict, as "deleted_at IS NULL", on PostgreSQL and SQLite
+func (statement *Statement) ConflictWhere(predicate string) *Statement {
+	statement.upsert.where = predicate
+	return statement
+}
+
+// DoUpdate makes Upsert only update the columns of a conflicting record to
+// their inserted values, as EXCLUDED.col. Without DoUpdate, the inserted
+// columns are updated but the primary keys, the created ones and the ones
+// of the conflict target.
+func (statement *Statement) DoUpdate(columns ...string) *Statement {
+	statement.upsert.updates = append(statement.upsert.updates, columns...)
+	return statement
+}
+
+// Keep makes Upsert keep the columns of a conflicting record as is
+func (statement *Statement) Keep(columns ...string) *Statement {
+	statement.upsert.keeps = append(statement.upsert.keeps, columns...)
+	return statement
+}
+
+// OnConflict makes Upsert update the record conflicting by the unique index
+// of columns
+func (session *Session) OnConflict(columns ...string) *Session {
+	session.Statement.OnConflict(columns...)
+	return session
+}
+
+// OnConstraint makes Upsert update the record conflicting by the unique
+// constraint name, PostgreSQL only
+func (session *Session) OnConstraint(name string) *Session {
+	session.Statement.OnConstraint(name)
+	return session
+}
+
+// ConflictWhere is the predicate of the partial unique index of the columns
+// of OnConflict
+func (session *Session) ConflictWhere(predicate string) *Session {
+	session.Statement.ConflictWhere(predicate)
+	return session
+}
+
+// DoUpdate makes Upsert only update the columns of a conflicting record
+func (session *Session) DoUpdate(columns ...string) *Session {
+	session.Statement.DoUpdate(columns...)
+	return session
+}
+
+// Keep makes Upsert keep the columns of a conflicting record as is
+func (session *Session) Keep(columns ...string) *Session {
+	session.Statement.Keep(columns...)
+	return session
+}
+
+// Upsert inserts a record of bean or updates the record it conflicts with,
+// as given by OnConflict, OnConstraint, DoUpdate and Keep, and returns the
+// affected records as counted by the database. It's an ON CONFLICT DO
+// UPDATE on PostgreSQL and SQLite 3.24+, whose conflict target is the
+// single unique key of the inserted columns unless given, and an ON
+// DUPLICATE KEY UPDATE on MySQL, which updates the record conflicting by
+// any unique key. The other databases aren't supported. The autoincrement
+// field of bean is set to the id of the inserted or updated record.
+//
+//	engine.OnConflict("email").ConflictWhere("deleted_at IS NULL").
+//		DoUpdate("name").Upsert(&user)
+func (session *Session) Upsert(bean interface{}) (int64, error) {
+	if err := session.enterOperation(); err != nil {
+		return 0, err
+	}
+	defer session.leaveOperation()
+
+	defer session.resetStatement()
+	if session.IsAutoClose {
+		defer session.Close()
+	}
+
+	session.Statement.upsert.enabled = true
+	affected, err := session.innerInsert(bean)
+	if err != nil {
+		return affected, err
+	}
+	// the cached bean may have fields the conflicting record kept
+	table := session.Statement.RefTable
+	if cacher := session.Engine.getCacher2(table); cacher != nil && session.Statement.UseCache {
+		tableName := session.Statement.TableName()
+		cacher.ClearIds(tableName)
+		cacher.ClearBeans(tableName)
+	}
+	return affected, nil
+}
+
+// Upsert inserts a record of bean or updates the record it conflicts with
+func (engine *Engine) Upsert(bean interface{}) (int64, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.Upsert(bean)
+}
+
+// upsertSQL returns the insert sqlStr of the columns colNames updating the
+// conflicting record
+func (session *Session) upsertSQL(sqlStr string, colNames []string) (string, error) {
+	param := &session.Statement.upsert
+	table := session.Statement.RefTable
+	quote := session.Engine.Quote
+
+	switch session.Engine.dialect.DBType() {
+	case core.MYSQL:
+		if param.constraint != "" || param.where != "" {
+			return "", ErrNotImplemented
+		}
+		updates, err := param.updateColumns(table, colNames, nil)
+		if err != nil {
+			return "", err
+		}
+		var sets = make([]string, 0, len(updates)+1)
+		for _, col := range updates {
+			sets = append(sets, quote(col)+" = VALUES("+quote(col)+")")
+		}
+		// the id of the updated record is the last insert id
+		if table.AutoIncrement != "" {
+			ai := quote(table.AutoIncrement)
+			sets = append(sets, ai+" = LAST_INSERT_ID("+ai+")")
+		} else if len(sets) == 0 && len(colNames) > 0 {
+			sets = append(sets, quote(colNames[0])+" = "+quote(colNames[0]))
+		}
+		return sqlStr + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", "), nil
+	case core.POSTGRES, core.SQLITE:
+	default:
+		return "", ErrNotImplemented
+	}
+
+	var target []string
+	var targetSQL string
+	if param.constraint != "" {
+		if session.Engine.dialect.DBType() != core.POSTGRES {
+			return "", ErrNotImplemented
+		}
+		targetSQL = " ON CONSTRAINT " + quote(param.constraint)
+	} else {
+		target = param.columns
+		if len(target) == 0 {
+			var inserted = make(map[string]bool, len(colNames))
+			for _, colName := range colNames {
+				inserted[strings.ToLower(colName)] = true
+			}
+			keys := conflictKeys(table, func(col string) bool {
+				return inserted[strings.ToLower(col)]
+			})
+			if len(keys) != 1 {
+				return "", fmt.Errorf("upsert into %v needs a conflict target among %d unique keys", table.Name, len(keys))
+			}
+			target = keys[0]
+		}
+		var quoted = make([]string, len(target))
+		for i, col := range target {
+			quoted[i] = quote(col)
+		}
+		targetSQL = " (" + strings.Join(quoted, ", ") + ")"
+		if param.where != "" {
+			targetSQL += " WHERE " + param.where
+		}
+	}
+
+	updates, err := param.updateColumns(table, colNames, target)
+	if err != nil {
+		return "", err
+	}
+	if len(updates) == 0 {
+		return sqlStr + " ON CONFLICT" + targetSQL + " DO NOTHING", nil
+	}
+	var sets = make([]string, len(updates))
+	for i, col := range updates {
+		sets[i] = quote(col) + " = EXCLUDED." + quote(col)
+	}
+	return sqlStr + " ON CONFLICT" + targetSQL + " DO UPDATE SET " + strings.Join(sets, ", "), nil
+}
+
+// updateColumns returns the columns of table updated by the upsert of the
+// columns colNames conflicting by the columns of target
+func (param *upsertParam) updateColumns(table *core.Table, colNames, target []string) ([]string, error) {
+	if len(param.updates) > 0 {
+		var updates = make([]string, 0, len(param.updates))
+		for _, name := range param.updates {
+			col := table.GetColumn(name)
+			if col == nil {
+				return nil, fmt.Errorf("unknown column %v of the table %v", name, table.Name)
+			}
+			updates = append(updates, col.Name)
+		}
+		return updates, nil
+	}
+
+	var skipped = make(map[string]bool, len(target)+len(param.keeps))
+	for _, name := range append(append([]string{}, target...), param.keeps...) {
+		skipped[strings.ToLower(name)] = true
+	}
+	var updates []string
+	for _, name := range colNames {
+		col := table.GetColumn(name)
+		if skipped[strings.ToLower(name)] || (col != nil && (col.IsPrimaryKey || col.IsCreated)) {
+			continue
+		}
+		updates = append(updates, name)
+	}
+	return updates, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type UpsertUser struct {
+	Id    int64
+	Email string `xorm:"varchar(64) unique"`
+	Name  string
+	Score int
+}
+
+type UpsertAccount struct {
+	Id    int64
+	Email string `xorm:"varchar(64) unique"`
+	Login string `xorm:"varchar(64) unique"`
+	Name  string
+}
+
+func TestUpsertSQL(t *testing.T) {
+	pg, err := NewMockEngineOf(core.POSTGRES)
+	assert.NoError(t, err)
+	defer pg.Close()
+
+	pg.On(`^INSERT`).Rows([]string{"id"}, []interface{}{1})
+	_, err = pg.Upsert(&UpsertUser{Email: "lunny@example.com", Name: "lunny", Score: 1})
+	assert.NoError(t, err)
+	assert.EqualValues(t, `INSERT INTO "upsert_user" ("email","name","score") VALUES ($1, $2, $3)`+
+		` ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name", "score" = EXCLUDED."score" RETURNING "id"`,
+		pg.LastRecord().SQL)
+
+	_, err = pg.OnConflict("email").ConflictWhere(`"score" > 0`).Keep("score").
+		Upsert(&UpsertUser{Email: "lunny@example.com", Name: "lunny", Score: 1})
+	assert.NoError(t, err)
+	assert.EqualValues(t, `INSERT INTO "upsert_user" ("email","name","score") VALUES ($1, $2, $3)`+
+		` ON CONFLICT ("email") WHERE "score" > 0 DO UPDATE SET "name" = EXCLUDED."name" RETURNING "id"`,
+		pg.LastRecord().SQL)
+
+	_, err = pg.OnConstraint("upsert_user_email_key").DoUpdate("score").
+		Upsert(&UpsertUser{Email: "lunny@example.com", Name: "lunny", Score: 1})
+	assert.NoError(t, err)
+	assert.EqualValues(t, `INSERT INTO "upsert_user" ("email","name","score") VALUES ($1, $2, $3)`+
+		` ON CONFLICT ON CONSTRAINT "upsert_user_email_key" DO UPDATE SET "score" = EXCLUDED."score" RETURNING "id"`,
+		pg.LastRecord().SQL)
+
+	// the tables of several unique keys need a conflict target
+	_, err = pg.Upsert(&UpsertAccount{Email: "lunny@example.com", Login: "lunny"})
+	assert.Error(t, err)
+	_, err = pg.OnConflict("login").Upsert(&UpsertAccount{Email: "lunny@example.com", Login: "lunny"})
+	assert.NoError(t, err)
+
+	mysql, err := NewMockEngineOf(core.MYSQL)
+	assert.NoError(t, err)
+	defer mysql.Close()
+
+	_, err = mysql.Keep("name").Upsert(&UpsertUser{Email: "lunny@example.com", Name: "lunny", Score: 1})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "INSERT INTO `upsert_user` (`email`,`name`,`score`) VALUES (?, ?, ?)"+
+		" ON DUPLICATE KEY UPDATE `email` = VALUES(`email`), `score` = VALUES(`score`), `id` = LAST_INSERT_ID(`id`)",
+		mysql.LastRecord().SQL)
+	_, err = mysql.OnConstraint("email").Upsert(&UpsertUser{Email: "lunny@example.com"})
+	assert.Equal(t, ErrNotImplemented, err)
+}
+
+func TestUpsert(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	switch testEngine.Dialect().DBType() {
+	case core.POSTGRES, core.MYSQL, core.SQLITE:
+	default:
+		t.Skip("upsert isn't supported")
+	}
+	assertSync(t, new(UpsertUser))
+
+	user := UpsertUser{Email: "lunny@example.com", Name: "lunny", Score: 1}
+	_, err := testEngine.Upsert(&user)
+	assert.NoError(t, err)
+	assert.True(t, user.Id > 0)
+
+	again := UpsertUser{Email: "lunny@example.com", Name: "xlw", Score: 2}
+	_, err = testEngine.OnConflict("email").Keep("name").Upsert(&again)
+	assert.NoError(t, err)
+
+	var got UpsertUser
+	has, err := testEngine.ID(user.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", got.Name)
+	assert.EqualValues(t, 2, got.Score)
+
+	cnt, err := testEngine.Count(new(UpsertUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+}
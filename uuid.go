@@ -0,0 +1,172 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// the SQL type of the uuids of mssql
+const uniqueIdentifier = "UNIQUEIDENTIFIER"
+
+// isUUIDType returns true if t is a uuid type: an array of 16 bytes named
+// UUID in a uuid package, as uuid.UUID of github.com/google/uuid or of
+// github.com/gofrs/uuid. The fields of the other arrays of 16 bytes are
+// uuids when they are tagged uuid.
+func isUUIDType(t reflect.Type) bool {
+	return isUUIDArray(t) && t.Name() == "UUID" && strings.Contains(strings.ToLower(t.PkgPath()), "uuid")
+}
+
+// isUUIDArray returns true if t is an array of 16 bytes
+func isUUIDArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+// UUIDTagHandler stores an array of 16 bytes field as a uuid, as the
+// uuid.UUID fields are by default, `xorm:"uuid"`. The field of another type
+// has the UUID column type.
+func UUIDTagHandler(ctx *tagContext) error {
+	t := ctx.fieldValue.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if !isUUIDArray(t) {
+		return SQLTypeTagHandler(ctx)
+	}
+
+	// the type of a tag before or after this one wins
+	if ctx.col.SQLType.Name == "" {
+		ctx.col.SQLType = ctx.engine.uuidConverter(nil).SQLType()
+	}
+	ctx.engine.setColumnConverter(ctx.col, uuidColumnConverter{ctx.engine, ctx.col})
+	return nil
+}
+
+// uuidColumnConverter is the uuidConverter of a column tagged uuid, the
+// column type is only known once all the tags are handled
+type uuidColumnConverter struct {
+	engine *Engine
+	col    *core.Column
+}
+
+func (c uuidColumnConverter) SQLType() core.SQLType {
+	return c.engine.uuidConverter(c.col).SQLType()
+}
+
+func (c uuidColumnConverter) ToDB(v interface{}) (interface{}, error) {
+	return c.engine.uuidConverter(c.col).ToDB(v)
+}
+
+func (c uuidColumnConverter) FromDB(src interface{}) (interface{}, error) {
+	return c.engine.uuidConverter(c.col).FromDB(src)
+}
+
+// uuidConverter converts the uuids to the columns of the dialect: UUID on
+// postgres, UNIQUEIDENTIFIER on mssql and CHAR(36) on the other databases.
+// The binary columns, as BINARY(16) set by the tag, store the 16 bytes.
+type uuidConverter struct {
+	dbType core.DbType
+	binary bool
+}
+
+func (engine *Engine) uuidConverter(col *core.Column) uuidConverter {
+	binary := col != nil && col.SQLType.IsBlob() &&
+		!strings.EqualFold(col.SQLType.Name, uniqueIdentifier)
+	return uuidConverter{engine.dialect.DBType(), binary}
+}
+
+func (c uuidConverter) SQLType() core.SQLType {
+	switch c.dbType {
+	case core.POSTGRES:
+		return core.SQLType{Name: core.Uuid}
+	case core.MSSQL:
+		return core.SQLType{Name: uniqueIdentifier}
+	}
+	return core.SQLType{Name: core.Char, DefaultLength: 36}
+}
+
+// ToDB binds the bytes to the binary columns and the text of the uuid to
+// the others, the String of a fmt.Stringer uuid
+func (c uuidConverter) ToDB(v interface{}) (interface{}, error) {
+	u := uuidBytes(reflect.ValueOf(v))
+	if c.binary {
+		return u[:], nil
+	}
+	if s, ok := v.(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+	return formatUUID(u), nil
+}
+
+// FromDB scans the bytes of the binary columns and the text of the others
+func (c uuidConverter) FromDB(src interface{}) (interface{}, error) {
+	switch v := src.(type) {
+	case []byte:
+		if len(v) == 16 {
+			var u [16]byte
+			copy(u[:], v)
+			if c.dbType == core.MSSQL && !c.binary {
+				// the first groups of a UNIQUEIDENTIFIER are little endian
+				u = mssqlUUIDOrder(u)
+			}
+			return u, nil
+		}
+		return parseUUID(string(v))
+	case string:
+		return parseUUID(v)
+	}
+	return nil, fmt.Errorf("unsupported uuid %T", src)
+}
+
+func uuidBytes(v reflect.Value) [16]byte {
+	var u [16]byte
+	reflect.Copy(reflect.ValueOf(&u).Elem(), v)
+	return u
+}
+
+// formatUUID formats u as xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+func formatUUID(u [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// parseUUID parses the standard form of a uuid, with or without the
+// hyphens, the braces or the urn:uuid: prefix
+func parseUUID(s string) ([16]byte, error) {
+	var u [16]byte
+	h := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "urn:uuid:")
+	h = strings.TrimSuffix(strings.TrimPrefix(h, "{"), "}")
+	h = strings.Replace(h, "-", "", -1)
+	if len(h) != 32 {
+		return u, fmt.Errorf("invalid uuid %q", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(h)); err != nil {
+		return u, fmt.Errorf("invalid uuid %q", s)
+	}
+	return u, nil
+}
+
+// mssqlUUIDOrder swaps the bytes of the first three groups of a uuid, they
+// are little endian in the UNIQUEIDENTIFIERs
+func mssqlUUIDOrder(u [16]byte) [16]byte {
+	u[0], u[1], u[2], u[3] = u[3], u[2], u[1], u[0]
+	u[4], u[5] = u[5], u[4]
+	u[6], u[7] = u[7], u[6]
+	return u
+}
@@ -0,0 +1,141 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUUID(t *testing.T) {
+	var expected = [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1,
+		0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+	for _, s := range []string{
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"6BA7B810-9DAD-11D1-80B4-00C04FD430C8",
+		"6ba7b8109dad11d180b400c04fd430c8",
+		"{6ba7b810-9dad-11d1-80b4-00c04fd430c8}",
+		"urn:uuid:6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+	} {
+		u, err := parseUUID(s)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, u, s)
+	}
+	assert.EqualValues(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", formatUUID(expected))
+
+	_, err := parseUUID("6ba7b810-9dad-11d1-80b4")
+	assert.Error(t, err)
+	_, err = parseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430cz")
+	assert.Error(t, err)
+
+	mssql := [16]byte{0x10, 0xb8, 0xa7, 0x6b, 0xad, 0x9d, 0xd1, 0x11,
+		0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	assert.EqualValues(t, expected, mssqlUUIDOrder(mssql))
+}
+
+type testUUID [16]byte
+
+func (u testUUID) String() string {
+	return formatUUID(u)
+}
+
+type UUIDStruct struct {
+	Id     testUUID  `xorm:"pk uuid"`
+	Ref    [16]byte  `xorm:"binary(16) uuid"`
+	Parent *testUUID `xorm:"uuid"`
+	Name   string
+}
+
+type UUID [16]byte
+
+func TestIsUUIDType(t *testing.T) {
+	// only the UUID types of the uuid packages are uuids by default
+	assert.False(t, isUUIDType(reflect.TypeOf([16]byte{})))
+	assert.False(t, isUUIDType(reflect.TypeOf(testUUID{})))
+	assert.False(t, isUUIDType(reflect.TypeOf(UUID{})))
+	assert.True(t, isUUIDArray(reflect.TypeOf(testUUID{})))
+	assert.False(t, isUUIDArray(reflect.TypeOf([8]byte{})))
+}
+
+type UUIDUntagged struct {
+	Id   int64
+	Hash [16]byte
+}
+
+func TestUUIDUntagged(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+
+	// an array of 16 bytes without the uuid tag is not a uuid
+	col := testEngine.TableInfo(new(UUIDUntagged)).GetColumn("hash")
+	if assert.NotNil(t, col) {
+		_, ok := testEngine.columnConverters.Load(col)
+		assert.False(t, ok)
+		_, _, ok = testEngine.converter(col, reflect.TypeOf([16]byte{}))
+		assert.False(t, ok)
+	}
+}
+
+func TestUUIDColumns(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assertSync(t, new(UUIDStruct))
+
+	table := testEngine.TableInfo(new(UUIDStruct))
+	col := table.GetColumn(testEngine.ColumnMapper.Obj2Table("Id"))
+	assert.NotNil(t, col)
+	if testEngine.Dialect().DBType() == core.POSTGRES {
+		assert.EqualValues(t, core.Uuid, col.SQLType.Name)
+	} else if testEngine.Dialect().DBType() != core.MSSQL {
+		assert.EqualValues(t, core.Char, col.SQLType.Name)
+	}
+
+	id, err := parseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	assert.NoError(t, err)
+	ref, err := parseUUID("6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+	assert.NoError(t, err)
+	parent := testUUID(ref)
+
+	cnt, err := testEngine.Insert(&UUIDStruct{
+		Id:     testUUID(id),
+		Ref:    ref,
+		Parent: &parent,
+		Name:   "uuid",
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var got UUIDStruct
+	has, err := testEngine.ID(testUUID(id)).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, id, got.Id)
+	assert.EqualValues(t, ref, got.Ref)
+	assert.NotNil(t, got.Parent)
+	assert.EqualValues(t, parent, *got.Parent)
+	assert.EqualValues(t, "uuid", got.Name)
+
+	pk := testEngine.IDOf(&got)
+	assert.EqualValues(t, 1, len(pk))
+	assert.EqualValues(t, id, pk[0])
+
+	var byRef UUIDStruct
+	has, err = testEngine.Where("name = ?", "uuid").And("parent = ?", parent.String()).Get(&byRef)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, id, byRef.Id)
+
+	cnt, err = testEngine.ID(testUUID(id)).Cols("parent").Update(&UUIDStruct{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var updated UUIDStruct
+	has, err = testEngine.ID(testUUID(id)).Get(&updated)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.Nil(t, updated.Parent)
+}
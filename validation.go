@@ -0,0 +1,189 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/go-xorm/core"
+)
+
+// Validatable is a bean validated before it's inserted or updated, the
+// write is aborted when Validate returns an error. Validate can return a
+// *FieldError or ValidationErrors to report the invalid fields.
+type Validatable interface {
+	Validate(ctx context.Context) error
+}
+
+// StructValidator validates the beans by their struct tags, as the Validate
+// of github.com/go-playground/validator
+type StructValidator interface {
+	Struct(bean interface{}) error
+}
+
+// PartialValidator is implemented by the struct validators which can
+// validate some fields of the beans, as the StructPartial of
+// github.com/go-playground/validator. The updated beans are validated with
+// it, the errors of the other fields are dropped otherwise.
+type PartialValidator interface {
+	StructPartial(bean interface{}, fields ...string) error
+}
+
+// FieldError is the validation error of a field, Field is empty when the
+// error is not about a field
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Err.Error()
+	}
+	return e.Field + ": " + e.Err.Error()
+}
+
+// ValidationErrors is the validation errors of a bean
+type ValidationErrors []*FieldError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ByField returns the errors grouped by field
+func (errs ValidationErrors) ByField() map[string][]error {
+	res := make(map[string][]error)
+	for _, err := range errs {
+		res[err.Field] = append(res[err.Field], err.Err)
+	}
+	return res
+}
+
+// structFieldError is the error of a field reported by a struct validator
+type structFieldError interface {
+	error
+	StructField() string
+}
+
+func (errs ValidationErrors) add(err error) ValidationErrors {
+	switch e := err.(type) {
+	case ValidationErrors:
+		return append(errs, e...)
+	case *FieldError:
+		return append(errs, e)
+	case structFieldError:
+		return append(errs, &FieldError{e.StructField(), e})
+	}
+
+	// the errors of the struct validators are usually a slice of field errors
+	v := reflect.ValueOf(err)
+	if v.Kind() == reflect.Slice {
+		var fieldErrs ValidationErrors
+		for i := 0; i < v.Len(); i++ {
+			e, ok := v.Index(i).Interface().(structFieldError)
+			if !ok {
+				return append(errs, &FieldError{Err: err})
+			}
+			fieldErrs = append(fieldErrs, &FieldError{e.StructField(), e})
+		}
+		return append(errs, fieldErrs...)
+	}
+	return append(errs, &FieldError{Err: err})
+}
+
+// only keeps the errors of the fields and the errors not about a field
+func (errs ValidationErrors) only(fields []string) ValidationErrors {
+	var res ValidationErrors
+	for _, err := range errs {
+		if err.Field == "" || containsField(fields, err.Field) {
+			res = append(res, err)
+		}
+	}
+	return res
+}
+
+// containsField reports whether fields contains field or, for a field of
+// a nested struct, its name
+func containsField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field || f[strings.LastIndex(f, ".")+1:] == field {
+			return true
+		}
+	}
+	return false
+}
+
+// SetValidator sets the struct validator of the beans inserted or updated
+func (engine *Engine) SetValidator(validator StructValidator) {
+	engine.validator = validator
+}
+
+// validate validates bean by the struct validator of the engine and by its
+// Validate method, it returns the ValidationErrors of both
+func (session *Session) validate(bean interface{}) error {
+	var errs ValidationErrors
+	if validator := session.Engine.validator; validator != nil {
+		if err := validator.Struct(bean); err != nil {
+			errs = errs.add(err)
+		}
+	}
+	if validatable, ok := bean.(Validatable); ok {
+		if err := validatable.Validate(session.Ctx()); err != nil {
+			errs = errs.add(err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateFields validates the fields of bean written by an update
+func (session *Session) validateFields(bean interface{}, fields []string) error {
+	var errs ValidationErrors
+	if validator := session.Engine.validator; validator != nil {
+		var err error
+		if partial, ok := validator.(PartialValidator); ok {
+			err = partial.StructPartial(bean, fields...)
+		} else {
+			err = validator.Struct(bean)
+		}
+		if err != nil {
+			errs = errs.add(err)
+		}
+	}
+	if validatable, ok := bean.(Validatable); ok {
+		if err := validatable.Validate(session.Ctx()); err != nil {
+			errs = errs.add(err)
+		}
+	}
+	if errs = errs.only(fields); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// updatedFields returns the fields of the columns set by colNames, which
+// are "column = ?" assignments
+func updatedFields(table *core.Table, colNames []string) []string {
+	var fields []string
+	for _, colName := range colNames {
+		idx := strings.Index(colName, "=")
+		if idx < 0 {
+			continue
+		}
+		name := strings.Trim(strings.TrimSpace(colName[:idx]), "`\"[]")
+		if col := table.GetColumn(name); col != nil {
+			fields = append(fields, col.FieldName)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,132 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ValidatedUser struct {
+	Id   int64
+	Name string
+	Age  int
+}
+
+func (u *ValidatedUser) Validate(ctx context.Context) error {
+	var errs ValidationErrors
+	if u.Name == "" {
+		errs = append(errs, &FieldError{"Name", errors.New("is required")})
+	}
+	if u.Age < 0 {
+		errs = append(errs, &FieldError{"Age", errors.New("is negative")})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type testFieldError struct {
+	field string
+}
+
+func (e testFieldError) Error() string {
+	return e.field + " is invalid"
+}
+
+func (e testFieldError) StructField() string {
+	return e.field
+}
+
+type testFieldErrors []testFieldError
+
+func (errs testFieldErrors) Error() string {
+	return "invalid"
+}
+
+type testStructValidator struct{}
+
+func (testStructValidator) Struct(bean interface{}) error {
+	if u, ok := bean.(*ValidatedUser); ok && len(u.Name) > 10 {
+		return testFieldErrors{{"Name"}}
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(ValidatedUser)))
+
+	_, err := testEngine.Insert(&ValidatedUser{Age: -1})
+	if assert.Error(t, err) {
+		errs, ok := err.(ValidationErrors)
+		assert.True(t, ok)
+		assert.EqualValues(t, 2, len(errs))
+		assert.EqualValues(t, 1, len(errs.ByField()["Age"]))
+	}
+
+	_, err = testEngine.Insert([]ValidatedUser{{Name: "lunny"}, {Name: ""}})
+	assert.Error(t, err)
+	total, err := testEngine.Count(new(ValidatedUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, total)
+
+	user := ValidatedUser{Name: "lunny"}
+	_, err = testEngine.Insert(&user)
+	assert.NoError(t, err)
+
+	_, err = testEngine.Id(user.Id).Update(&ValidatedUser{Age: -1})
+	assert.Error(t, err)
+
+	// only the columns written are validated
+	_, err = testEngine.Id(user.Id).Update(&ValidatedUser{Age: 3})
+	assert.NoError(t, err)
+	_, err = testEngine.Id(user.Id).Cols("age").Update(&ValidatedUser{Age: 4})
+	assert.NoError(t, err)
+
+	testEngine.SetValidator(testStructValidator{})
+	defer testEngine.SetValidator(nil)
+
+	_, err = testEngine.Id(user.Id).Update(&ValidatedUser{Name: "a very long name", Age: -1})
+	if assert.Error(t, err) {
+		errs := err.(ValidationErrors)
+		assert.EqualValues(t, []error{testFieldError{"Name"}}, errs.ByField()["Name"])
+		assert.EqualValues(t, 1, len(errs.ByField()["Age"]))
+	}
+}
+
+type testPartialValidator struct {
+	fields []string
+}
+
+func (v *testPartialValidator) Struct(bean interface{}) error {
+	return errors.New("validated the whole struct")
+}
+
+func (v *testPartialValidator) StructPartial(bean interface{}, fields ...string) error {
+	v.fields = fields
+	return nil
+}
+
+func TestValidatePartial(t *testing.T) {
+	assert.NoError(t, prepareEngine())
+	assert.NoError(t, testEngine.Sync2(new(ValidatedUser)))
+
+	user := ValidatedUser{Name: "lunny"}
+	_, err := testEngine.Insert(&user)
+	assert.NoError(t, err)
+
+	validator := new(testPartialValidator)
+	testEngine.SetValidator(validator)
+	defer testEngine.SetValidator(nil)
+
+	_, err = testEngine.Id(user.Id).Cols("age").Update(&ValidatedUser{Age: 4})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"Age"}, validator.fields)
+}
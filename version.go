@@ -0,0 +1,159 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"crypto/rand"
+	"fmt"
+	"reflect"
+
+	"github.com/go-xorm/builder"
+	"github.com/go-xorm/core"
+)
+
+// VersionConflictMode is how Update reports the beans whose version column
+// is stale, their record having been updated since they were read
+type VersionConflictMode int
+
+const (
+	// VersionConflictSilent updates no record, Update returns 0
+	VersionConflictSilent VersionConflictMode = iota
+	// VersionConflictError returns an *OptimisticLockError
+	VersionConflictError
+	// VersionConflictRefetch returns an *OptimisticLockError with the
+	// record which won
+	VersionConflictRefetch
+)
+
+// OptimisticLockError is returned by Update for the beans with a stale
+// version when SetVersionConflict is VersionConflictError or
+// VersionConflictRefetch, errors.Is(err, ErrOptimisticLock) is true
+type OptimisticLockError struct {
+	Table string
+	// Version is the stale version of the bean
+	Version interface{}
+	// Current is a pointer to a new bean of the record which won, nil
+	// unless VersionConflictRefetch
+	Current interface{}
+}
+
+func (e *OptimisticLockError) Error() string {
+	return fmt.Sprintf("optimistic lock conflict on %v: version %v is stale", e.Table, e.Version)
+}
+
+// Is returns true for ErrOptimisticLock
+func (e *OptimisticLockError) Is(target error) bool {
+	return target == ErrOptimisticLock
+}
+
+// SetVersionConflict sets how Update reports the beans with a stale
+// version, VersionConflictSilent by default
+func (engine *Engine) SetVersionConflict(mode VersionConflictMode) {
+	engine.versionConflict = mode
+}
+
+// isCounterVersion returns true if the version fields of type t are
+// counters incremented by the updates. The versions of the times are the
+// times of the updates and the ones of the strings and the arrays of 16
+// bytes are random uuid tokens.
+func isCounterVersion(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct && t.ConvertibleTo(core.TimeType) {
+		return false
+	}
+	return t.Kind() != reflect.String && !isUUIDArray(t)
+}
+
+// newVersion returns the next version of a timestamp or a token version
+// field of type t
+func (session *Session) newVersion(t reflect.Type) (reflect.Value, error) {
+	elemType := t
+	if t.Kind() == reflect.Ptr {
+		elemType = t.Elem()
+	}
+
+	var v reflect.Value
+	switch {
+	case elemType.Kind() == reflect.Struct && elemType.ConvertibleTo(core.TimeType):
+		v = reflect.ValueOf(session.Engine.now().In(session.Engine.TZLocation)).Convert(elemType)
+	default:
+		var u [16]byte
+		if _, err := rand.Read(u[:]); err != nil {
+			return v, err
+		}
+		u[6] = u[6]&0x0f | 0x40 // version 4
+		u[8] = u[8]&0x3f | 0x80 // variant 10
+		if elemType.Kind() == reflect.String {
+			v = reflect.ValueOf(formatUUID(u)).Convert(elemType)
+		} else {
+			v = reflect.New(elemType).Elem()
+			reflect.Copy(v, reflect.ValueOf(u[:]))
+		}
+	}
+	if t.Kind() == reflect.Ptr {
+		ptr := reflect.New(elemType)
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	return v, nil
+}
+
+// setInitialVersion sets the timestamp or the token version of an inserted
+// bean v, the counters are set to 1 by the insert
+func (session *Session) setInitialVersion(table *core.Table, v reflect.Value) error {
+	if table.Version == "" || !session.Statement.checkVersion {
+		return nil
+	}
+	fieldValue, err := table.VersionColumn().ValueOfV(&v)
+	if err != nil {
+		return err
+	}
+	if isCounterVersion(fieldValue.Type()) || !fieldValue.CanSet() {
+		return nil
+	}
+	version, err := session.newVersion(fieldValue.Type())
+	if err != nil {
+		return err
+	}
+	fieldValue.Set(version)
+	return nil
+}
+
+// versionConflict returns the error of an update of a bean with a stale
+// version when the record of cond, without the version, still exists
+func (session *Session) versionConflict(table *core.Table, tableName string, cond builder.Cond, version interface{}) error {
+	mode := session.Engine.versionConflict
+	if mode == VersionConflictSilent {
+		return nil
+	}
+
+	condSQL, condArgs, err := builder.ToSQL(cond)
+	if err != nil {
+		return err
+	}
+	sqlStr := "SELECT * FROM " + session.Engine.Quote(tableName)
+	if condSQL != "" {
+		sqlStr += " WHERE " + condSQL
+	}
+
+	// the session is closed by Update
+	if session.IsAutoClose {
+		session.IsAutoClose = false
+		defer func() { session.IsAutoClose = true }()
+	}
+	current := reflect.New(table.Type)
+	has, err := session.SQL(sqlStr, condArgs...).Get(current.Interface())
+	if err != nil || !has {
+		return err
+	}
+
+	lockErr := &OptimisticLockError{Table: tableName, Version: version}
+	if mode == VersionConflictRefetch {
+		lockErr.Current = current.Interface()
+	}
+	return lockErr
+}
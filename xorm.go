@@ -46,7 +46,7 @@ func regDrvsNDialects() bool {
 	return true
 }
 
-func close(engine *Engine) {
+func closeEngine(engine *Engine) {
 	engine.Close()
 }
 
@@ -87,6 +87,8 @@ func NewEngine(driverName string, dataSourceName string) (*Engine, error) {
 		TagIdentifier: "xorm",
 		TZLocation:    time.Local,
 		tagHandlers:   defaultTagHandlers,
+		stmtCounters:  new(stmtCacheCounters),
+		cacheMonitor:  new(cacheMonitor),
 	}
 
 	if uri.DbType == core.SQLITE {
@@ -100,7 +102,7 @@ func NewEngine(driverName string, dataSourceName string) (*Engine, error) {
 	engine.SetLogger(logger)
 	engine.SetMapper(core.NewCacheMapper(new(core.SnakeMapper)))
 
-	runtime.SetFinalizer(engine, close)
+	runtime.SetFinalizer(engine, closeEngine)
 
 	return engine, nil
 }
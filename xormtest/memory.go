@@ -0,0 +1,41 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xormtest
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-xorm/xorm"
+	// the driver of the memory engines
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// the sequence of the names of the memory databases
+var memorySeq int64
+
+// NewMemoryEngine returns an engine of a new in-memory SQLite database with
+// the tables of beans synced and the foreign keys enforced, closed at the
+// end of the test. The connections of the engine share the database, which
+// is not shared with the other engines.
+func NewMemoryEngine(t testing.TB, beans ...interface{}) *xorm.Engine {
+	t.Helper()
+	dsn := fmt.Sprintf("file:xormtest_memory_%d?mode=memory&cache=shared&_foreign_keys=1",
+		atomic.AddInt64(&memorySeq, 1))
+	engine, err := xorm.NewEngine("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("xormtest: memory engine: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := engine.Close(); err != nil {
+			t.Errorf("xormtest: close memory engine: %v", err)
+		}
+	})
+	if err := engine.Sync2(beans...); err != nil {
+		t.Fatalf("xormtest: sync: %v", err)
+	}
+	return engine
+}
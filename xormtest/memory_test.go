@@ -0,0 +1,40 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xormtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMemoryEngine(t *testing.T) {
+	engine := NewMemoryEngine(t, new(User))
+
+	// the connections share the database
+	session := engine.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+	_, err := session.Insert(&User{Name: "lunny"})
+	assert.NoError(t, err)
+	assert.NoError(t, session.Commit())
+
+	cnt, err := engine.Count(new(User))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	// the databases of the engines are not shared
+	other := NewMemoryEngine(t, new(User))
+	cnt, err = other.Count(new(User))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+
+	_, err = engine.Exec("CREATE TABLE post (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES user(id))")
+	assert.NoError(t, err)
+	_, err = engine.Exec("INSERT INTO post (id, user_id) VALUES (1, 1)")
+	assert.NoError(t, err)
+	_, err = engine.Exec("INSERT INTO post (id, user_id) VALUES (2, 2)")
+	assert.Error(t, err)
+}
@@ -0,0 +1,244 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xormtest
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-xorm/xorm"
+)
+
+var update = flag.Bool("xormtest.update", false, "write the statements of the recorders to their golden files")
+
+var (
+	spacesRegexp = regexp.MustCompile(`\s+`)
+	// the placeholders of postgres, oracle and mssql
+	placeholderRegexp = regexp.MustCompile(`\$\d+|:\d+|@p\d+`)
+	// the lists of placeholders of the INs and of the values
+	placesRegexp = regexp.MustCompile(`\(\s*\?(\s*,\s*\?)*\s*\)`)
+	tuplesRegexp = regexp.MustCompile(`\(\?, \.\.\.\)(\s*,\s*\(\?, \.\.\.\))+`)
+	// the generated names of the savepoints and of the cursors
+	seqNameRegexp = regexp.MustCompile(`\b(xormtest|xorm_cursor)_\d+\b`)
+	// the quotes of the names of the dialects
+	quotesReplacer = strings.NewReplacer("`", "", `"`, "", "[", "", "]", "")
+)
+
+// Normalize returns the shape of a statement, without the spacing, the
+// numbering of the placeholders and the lengths of the lists of
+// placeholders, which vary with the args
+func Normalize(sqlStr string) string {
+	sqlStr = strings.TrimSpace(spacesRegexp.ReplaceAllString(sqlStr, " "))
+	sqlStr = placeholderRegexp.ReplaceAllString(sqlStr, "?")
+	sqlStr = placesRegexp.ReplaceAllString(sqlStr, "(?, ...)")
+	sqlStr = tuplesRegexp.ReplaceAllString(sqlStr, "(?, ...), ...")
+	return seqNameRegexp.ReplaceAllString(sqlStr, "${1}_N")
+}
+
+// Recorder is an interceptor recording the normalized statements executed
+// by an engine while it's started, to compare them to golden files or to
+// assert their executions. The golden files are written by the tests run
+// with -xormtest.update.
+//
+//	recorder := xormtest.NewRecorder(engine)
+//	recorder.Start()
+//	// the code under test
+//	recorder.AssertGolden(t, "testdata/create_user.golden")
+type Recorder struct {
+	mutex      sync.Mutex
+	recording  bool
+	statements []string
+}
+
+// NewRecorder returns a stopped recorder of the statements of engine
+func NewRecorder(engine *xorm.Engine) *Recorder {
+	r := new(Recorder)
+	engine.Use(r)
+	return r
+}
+
+// Intercept implements xorm.Interceptor
+func (r *Recorder) Intercept(inv *xorm.Invocation, next xorm.Handler) error {
+	r.mutex.Lock()
+	if r.recording {
+		r.statements = append(r.statements, Normalize(inv.SQL))
+	}
+	r.mutex.Unlock()
+	return next(inv)
+}
+
+// Start forgets the recorded statements and records the next ones
+func (r *Recorder) Start() {
+	r.mutex.Lock()
+	r.recording = true
+	r.statements = nil
+	r.mutex.Unlock()
+}
+
+// Stop stops the recording and returns the recorded statements
+func (r *Recorder) Stop() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.recording = false
+	return append([]string(nil), r.statements...)
+}
+
+// Statements returns the statements recorded since the start
+func (r *Recorder) Statements() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.statements...)
+}
+
+// Count returns the number of the recorded statements matching pattern, a
+// pattern of LIKE whose % matches any text, compared to the statements
+// without the quotes of their names and ignoring the case
+func (r *Recorder) Count(pattern string) int {
+	re := likeRegexp(pattern)
+	var cnt int
+	for _, statement := range r.Statements() {
+		if re.MatchString(quotesReplacer.Replace(statement)) {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+func likeRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "%")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("(?is)^" + strings.Join(parts, ".*") + "$")
+}
+
+// AssertExecuted fails the test if no recorded statement matches pattern,
+// as the ones of Count
+//
+//	recorder.AssertExecuted(t, "INSERT INTO user%")
+func (r *Recorder) AssertExecuted(t testing.TB, pattern string) bool {
+	t.Helper()
+	if r.Count(pattern) > 0 {
+		return true
+	}
+	t.Errorf("xormtest: no statement matches %v in:\n%s", pattern, strings.Join(r.Statements(), "\n"))
+	return false
+}
+
+// AssertNotExecuted fails the test if a recorded statement matches pattern
+func (r *Recorder) AssertNotExecuted(t testing.TB, pattern string) bool {
+	t.Helper()
+	if cnt := r.Count(pattern); cnt > 0 {
+		t.Errorf("xormtest: %d statements match %v", cnt, pattern)
+		return false
+	}
+	return true
+}
+
+// AssertCount fails the test if the number of the recorded statements is
+// not n
+func (r *Recorder) AssertCount(t testing.TB, n int) bool {
+	t.Helper()
+	statements := r.Statements()
+	if len(statements) == n {
+		return true
+	}
+	t.Errorf("xormtest: %d statements executed instead of %d:\n%s", len(statements), n, strings.Join(statements, "\n"))
+	return false
+}
+
+// RepeatedSelects returns the recorded SELECTs executed more than max
+// times, with their numbers of executions
+func (r *Recorder) RepeatedSelects(max int) map[string]int {
+	var counts = make(map[string]int)
+	for _, statement := range r.Statements() {
+		if len(statement) >= 6 && strings.EqualFold(statement[:6], "SELECT") {
+			counts[statement]++
+		}
+	}
+	for statement, cnt := range counts {
+		if cnt <= max {
+			delete(counts, statement)
+		}
+	}
+	return counts
+}
+
+// AssertNoRepeatedSelects fails the test for the SELECTs executed more than
+// max times, as the N+1 queries of the loops loading their records one by
+// one instead of with an IN
+func (r *Recorder) AssertNoRepeatedSelects(t testing.TB, max int) bool {
+	t.Helper()
+	repeated := r.RepeatedSelects(max)
+	if len(repeated) == 0 {
+		return true
+	}
+	var lines = make([]string, 0, len(repeated))
+	for statement, cnt := range repeated {
+		lines = append(lines, fmt.Sprintf("%d times: %s", cnt, statement))
+	}
+	sort.Strings(lines)
+	t.Errorf("xormtest: the SELECTs are repeated more than %d times:\n%s", max, strings.Join(lines, "\n"))
+	return false
+}
+
+// AssertGolden stops the recording and fails the test if the recorded
+// statements are not the lines of the golden file path
+func (r *Recorder) AssertGolden(t testing.TB, path string) bool {
+	t.Helper()
+	statements := r.Stop()
+	actual := strings.Join(statements, "\n")
+	if len(statements) > 0 {
+		actual += "\n"
+	}
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("xormtest: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(actual), 0644); err != nil {
+			t.Fatalf("xormtest: %v", err)
+		}
+		return true
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("xormtest: %v, run the test with -xormtest.update to write it", err)
+		return false
+	}
+	if string(expected) == actual {
+		return true
+	}
+	t.Errorf("xormtest: the statements differ from %v:\n%s", path, diffLines(string(expected), actual))
+	return false
+}
+
+// diffLines returns the lines of expected and of actual from the first
+// different one, prefixed by - and +
+func diffLines(expected, actual string) string {
+	exp := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	act := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+	var i int
+	for i < len(exp) && i < len(act) && exp[i] == act[i] {
+		i++
+	}
+	var lines []string
+	for _, line := range exp[i:] {
+		lines = append(lines, "- "+line)
+	}
+	for _, line := range act[i:] {
+		lines = append(lines, "+ "+line)
+	}
+	return strings.Join(lines, "\n")
+}
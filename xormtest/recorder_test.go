@@ -0,0 +1,114 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xormtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-xorm/xorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.EqualValues(t, "SELECT * FROM user WHERE id IN (?, ...) AND name = ?",
+		Normalize("SELECT *\n  FROM user WHERE id IN ($1, $2,$3) AND name = $4"))
+	assert.EqualValues(t, "INSERT INTO user (id, name) VALUES (?, ...), ...",
+		Normalize("INSERT INTO user (id, name) VALUES (?, ?),(?, ?), (?, ?)"))
+	assert.EqualValues(t, "ROLLBACK TO SAVEPOINT xormtest_N", Normalize("ROLLBACK TO SAVEPOINT xormtest_12"))
+}
+
+// fakeT records the failures of AssertGolden
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failed = true
+}
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xormtest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	engine, err := xorm.NewEngine("sqlite3", filepath.Join(dir, "test.db"))
+	assert.NoError(t, err)
+	defer engine.Close()
+	assert.NoError(t, engine.Sync2(new(User)))
+
+	recorder := NewRecorder(engine)
+	run := func(ids ...int64) {
+		recorder.Start()
+		_, err := engine.Insert(&User{Name: "lunny"})
+		assert.NoError(t, err)
+		var users []User
+		assert.NoError(t, engine.In("id", ids).Find(&users))
+	}
+
+	golden := filepath.Join(dir, "testdata", "user.golden")
+	*update = true
+	run(1)
+	assert.True(t, recorder.AssertGolden(t, golden))
+	*update = false
+	content, err := ioutil.ReadFile(golden)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "INSERT INTO `user` (`name`) VALUES (?, ...)\n"+
+		"SELECT `id`, `name` FROM `user` WHERE `id` IN (?, ...)\n", string(content))
+
+	// the number of the args doesn't change the shape
+	run(1, 2, 3)
+	assert.True(t, recorder.AssertGolden(t, golden))
+
+	// an extra query does
+	run(1)
+	_, err = engine.Count(new(User))
+	assert.NoError(t, err)
+	ft := &fakeT{TB: t}
+	assert.False(t, recorder.AssertGolden(ft, golden))
+	assert.True(t, ft.failed)
+
+	// the statements after the stop are not recorded
+	_, err = engine.Count(new(User))
+	assert.NoError(t, err)
+	assert.Len(t, recorder.Statements(), 3)
+}
+
+func TestRecorderAssertions(t *testing.T) {
+	engine := NewMemoryEngine(t, new(User))
+	recorder := NewRecorder(engine)
+	recorder.Start()
+
+	_, err := engine.Insert(&User{Name: "lunny"}, &User{Name: "xlw"})
+	assert.NoError(t, err)
+	for _, id := range []int64{1, 2} {
+		var user User
+		_, err := engine.ID(id).Get(&user)
+		assert.NoError(t, err)
+	}
+
+	assert.EqualValues(t, 2, recorder.Count("insert into user%"))
+	assert.True(t, recorder.AssertExecuted(t, "INSERT INTO user%"))
+	assert.True(t, recorder.AssertNotExecuted(t, "DELETE%"))
+	assert.True(t, recorder.AssertCount(t, 4))
+	assert.True(t, recorder.AssertNoRepeatedSelects(t, 2))
+
+	ft := &fakeT{TB: t}
+	assert.False(t, recorder.AssertExecuted(ft, "UPDATE%"))
+	assert.False(t, recorder.AssertNotExecuted(ft, "SELECT%FROM user%"))
+	assert.False(t, recorder.AssertCount(ft, 3))
+	assert.True(t, ft.failed)
+
+	// the N+1 queries
+	ft = &fakeT{TB: t}
+	assert.False(t, recorder.AssertNoRepeatedSelects(ft, 1))
+	assert.True(t, ft.failed)
+	assert.Len(t, recorder.RepeatedSelects(1), 1)
+}
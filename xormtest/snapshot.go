@@ -0,0 +1,194 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xormtest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-xorm/core"
+	"github.com/go-xorm/xorm"
+)
+
+// Snapshot is the saved tables of the database of an engine, their schemas
+// and their records, restored by the tests sharing a setup:
+//
+//	func TestMain(m *testing.M) {
+//		engine.Sync2(new(User))
+//		loader.Load()
+//		snapshot, _ = xormtest.TakeSnapshot(engine)
+//		code := m.Run()
+//		snapshot.Close()
+//		os.Exit(code)
+//	}
+//
+//	func TestUser(t *testing.T) {
+//		if err := snapshot.Restore(); err != nil {
+//			t.Fatal(err)
+//		}
+//		...
+//	}
+//
+// The databases of SQLite are copied to a file restored by the SQL of an
+// attached database, the other databases are dumped and imported.
+type Snapshot struct {
+	engine *xorm.Engine
+	dir    string // the directory of the copy of the SQLite database
+	dump   []byte
+}
+
+// the schema of the attached copies of the SQLite databases
+const snapshotSchema = "xormtest_snapshot"
+
+// TakeSnapshot saves the tables of the database of engine
+func TakeSnapshot(engine *xorm.Engine) (*Snapshot, error) {
+	s := &Snapshot{engine: engine}
+	if engine.Dialect().DBType() != core.SQLITE {
+		var buf bytes.Buffer
+		if err := engine.DumpAll(&buf); err != nil {
+			return nil, err
+		}
+		s.dump = buf.Bytes()
+		return s, nil
+	}
+
+	dir, err := ioutil.TempDir("", "xormtest")
+	if err != nil {
+		return nil, err
+	}
+	s.dir = dir
+	if _, err := engine.Exec("VACUUM INTO " + quoteString(s.path())); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Snapshot) path() string {
+	return filepath.Join(s.dir, "snapshot.db")
+}
+
+func quoteString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// Restore drops the tables of the database and restores the ones of the
+// snapshot
+func (s *Snapshot) Restore() error {
+	metas, err := s.engine.DBMetas()
+	if err != nil {
+		return err
+	}
+	var tables = make([]string, 0, len(metas))
+	for _, meta := range metas {
+		tables = append(tables, meta.Name)
+	}
+	defer func() {
+		// the cached records of the tables are stale
+		s.engine.ClearCacheTables(tables...)
+	}()
+
+	if s.dir != "" {
+		return s.restoreSQLite(tables)
+	}
+
+	for _, table := range tables {
+		if _, err := s.engine.Exec(s.engine.Dialect().DropTableSql(table)); err != nil {
+			return err
+		}
+	}
+	_, err = s.engine.Import(bytes.NewReader(s.dump))
+	return err
+}
+
+// restoreSQLite drops the tables and copies the ones of the attached copy of
+// the database, with a connection of its own as an attach is per connection
+func (s *Snapshot) restoreSQLite(tables []string) error {
+	ctx := context.Background()
+	conn, err := s.engine.DB().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE "+quoteString(s.path())+" AS "+snapshotSchema); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "DETACH DATABASE "+snapshotSchema)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := s.copySQLite(ctx, tx, tables); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Snapshot) copySQLite(ctx context.Context, tx *sql.Tx, tables []string) error {
+	for _, table := range tables {
+		if _, err := tx.ExecContext(ctx, "DROP TABLE "+s.engine.Quote(table)); err != nil {
+			return err
+		}
+	}
+
+	// the tables before their indexes
+	rows, err := tx.QueryContext(ctx, "SELECT type, name, sql FROM "+snapshotSchema+".sqlite_master "+
+		"WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' ORDER BY type = 'table' DESC, rowid")
+	if err != nil {
+		return err
+	}
+	type object struct{ typ, name, sql string }
+	var objects []object
+	for rows.Next() {
+		var o object
+		if err := rows.Scan(&o.typ, &o.name, &o.sql); err != nil {
+			rows.Close()
+			return err
+		}
+		objects = append(objects, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, o := range objects {
+		if _, err := tx.ExecContext(ctx, o.sql); err != nil {
+			return err
+		}
+		if o.typ != "table" {
+			continue
+		}
+		name := s.engine.Quote(o.name)
+		if _, err := tx.ExecContext(ctx, "INSERT INTO main."+name+" SELECT * FROM "+snapshotSchema+"."+name); err != nil {
+			return err
+		}
+	}
+
+	// the sequences of the autoincrement tables
+	var hasSequences int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+snapshotSchema+".sqlite_master WHERE name = 'sqlite_sequence'").
+		Scan(&hasSequences); err != nil || hasSequences == 0 {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT OR REPLACE INTO main.sqlite_sequence SELECT * FROM "+snapshotSchema+".sqlite_sequence")
+	return err
+}
+
+// Close removes the copy of the database
+func (s *Snapshot) Close() error {
+	if s.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.dir)
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xormtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-xorm/xorm"
+	"github.com/stretchr/testify/assert"
+)
+
+type Post struct {
+	Id    int64
+	Title string `xorm:"index"`
+}
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xormtest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	engine, err := xorm.NewEngine("sqlite3", filepath.Join(dir, "test.db"))
+	assert.NoError(t, err)
+	defer engine.Close()
+	assert.NoError(t, engine.Sync2(new(User)))
+	_, err = engine.Insert(&User{Name: "lunny"}, &User{Name: "xlw"})
+	assert.NoError(t, err)
+
+	snapshot, err := TakeSnapshot(engine)
+	assert.NoError(t, err)
+	defer snapshot.Close()
+
+	for i := 0; i < 2; i++ {
+		// the changes of a test
+		_, err = engine.Id(1).Delete(new(User))
+		assert.NoError(t, err)
+		_, err = engine.Insert(&User{Name: "gopher"})
+		assert.NoError(t, err)
+		assert.NoError(t, engine.Sync2(new(Post)))
+
+		assert.NoError(t, snapshot.Restore())
+
+		var users []User
+		assert.NoError(t, engine.Asc("id").Find(&users))
+		assert.EqualValues(t, []User{{1, "lunny"}, {2, "xlw"}}, users)
+		exist, err := engine.IsTableExist(new(Post))
+		assert.NoError(t, err)
+		assert.False(t, exist)
+
+		// the ids go on after the ones of the snapshot
+		user := User{Name: "gopher"}
+		_, err = engine.Insert(&user)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 3, user.Id)
+		assert.NoError(t, snapshot.Restore())
+	}
+}
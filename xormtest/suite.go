@@ -0,0 +1,371 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xormtest
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+// SuiteRecord is the table of the CRUD of the dialect suite
+type SuiteRecord struct {
+	Id      int64
+	Name    string `xorm:"varchar(64) index"`
+	Score   int
+	Created time.Time `xorm:"created"`
+	Updated time.Time `xorm:"updated"`
+}
+
+// suiteRecordV2 is SuiteRecord with a new column and a new index, to sync
+type suiteRecordV2 struct {
+	Id      int64
+	Name    string `xorm:"varchar(64) index"`
+	Score   int
+	Created time.Time `xorm:"created"`
+	Updated time.Time `xorm:"updated"`
+	Email   string    `xorm:"varchar(128) unique"`
+}
+
+func (suiteRecordV2) TableName() string {
+	return "suite_record"
+}
+
+// SuiteType is the table of the round trips of the types of the dialect
+// suite
+type SuiteType struct {
+	Id         int64
+	Int8       int8
+	Int16      int16
+	Int32      int32
+	Int64      int64
+	Uint8      uint8
+	Uint16     uint16
+	Uint32     uint32
+	Float32    float32
+	Float64    float64
+	Bool       bool
+	String     string `xorm:"varchar(255)"`
+	Text       string `xorm:"text"`
+	Bytes      []byte
+	Time       time.Time
+	NullString *string
+	NullInt    *int64
+	Map        map[string]string
+}
+
+// RunDialectSuite checks that the dialect of engine supports the syncs,
+// the CRUD, the transactions and the round trips of the types, as the
+// subtests of t. It creates and drops the tables suite_record and
+// suite_type of the database of engine.
+//
+//	func TestDialect(t *testing.T) {
+//		engine, err := xorm.NewEngine("mydb", dsn)
+//		...
+//		xormtest.RunDialectSuite(t, engine)
+//	}
+func RunDialectSuite(t *testing.T, engine *xorm.Engine) {
+	t.Helper()
+	dropSuiteTables(t, engine)
+	defer dropSuiteTables(t, engine)
+
+	t.Run("Sync2", func(t *testing.T) { testSuiteSync(t, engine) })
+	t.Run("CRUD", func(t *testing.T) { testSuiteCRUD(t, engine) })
+	t.Run("Transaction", func(t *testing.T) { testSuiteTransaction(t, engine) })
+	t.Run("Types", func(t *testing.T) { testSuiteTypes(t, engine) })
+}
+
+func dropSuiteTables(t testing.TB, engine *xorm.Engine) {
+	t.Helper()
+	if err := engine.DropTables(new(SuiteRecord), new(SuiteType)); err != nil {
+		t.Fatalf("xormtest: drop the tables of the suite: %v", err)
+	}
+}
+
+// check fails the test if err is not nil
+func check(t testing.TB, err error, what string) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("xormtest: %v: %v", what, err)
+	}
+}
+
+// checkEqual fails the test if actual is not expected
+func checkEqual(t testing.TB, what string, expected, actual interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("xormtest: %v is %#v instead of %#v", what, actual, expected)
+	}
+}
+
+func testSuiteSync(t *testing.T, engine *xorm.Engine) {
+	check(t, engine.Sync2(new(SuiteRecord), new(SuiteType)), "sync")
+	// the tables are synced
+	check(t, engine.Sync2(new(SuiteRecord), new(SuiteType)), "sync again")
+	check(t, engine.Sync2(new(suiteRecordV2)), "sync a new column")
+	// the next tests sync the table without the new column
+	defer func() {
+		check(t, engine.DropTables(new(SuiteRecord)), "drop")
+	}()
+
+	metas, err := engine.DBMetas()
+	check(t, err, "metas")
+	for _, meta := range metas {
+		if !strings.EqualFold(meta.Name, "suite_record") {
+			continue
+		}
+		checkEqual(t, "the column email", true, meta.GetColumn("email") != nil)
+		var indexes []string
+		for _, index := range meta.Indexes {
+			indexes = append(indexes, strings.Join(index.Cols, ","))
+		}
+		checkEqual(t, "the indexes of the columns", 2, len(indexes))
+		return
+	}
+	t.Errorf("xormtest: no table suite_record in the metas")
+}
+
+func testSuiteCRUD(t *testing.T, engine *xorm.Engine) {
+	check(t, engine.Sync2(new(SuiteRecord)), "sync")
+	_, err := engine.Where("1=1").Delete(new(SuiteRecord))
+	check(t, err, "delete all")
+
+	record := SuiteRecord{Name: "lunny", Score: 1}
+	affected, err := engine.Insert(&record)
+	check(t, err, "insert")
+	checkEqual(t, "the affected records of the insert", int64(1), affected)
+	if record.Id == 0 || record.Created.IsZero() {
+		t.Errorf("xormtest: the insert didn't set the id nor the created time")
+	}
+
+	records := []SuiteRecord{{Name: "xlw", Score: 2}, {Name: "gopher", Score: 3}, {Name: "tux", Score: 4}}
+	affected, err = engine.Insert(&records)
+	check(t, err, "insert multi")
+	checkEqual(t, "the affected records of the insert multi", int64(3), affected)
+
+	var got SuiteRecord
+	has, err := engine.ID(record.Id).Get(&got)
+	check(t, err, "get")
+	checkEqual(t, "the record exists", true, has)
+	checkEqual(t, "the name", "lunny", got.Name)
+
+	has, err = engine.ID(record.Id + 1000).Get(new(SuiteRecord))
+	check(t, err, "get a missing record")
+	checkEqual(t, "the missing record exists", false, has)
+
+	var found []SuiteRecord
+	check(t, engine.Where("score > ?", 1).Asc("score").Limit(2, 1).Find(&found), "find")
+	if checkLen(t, "the found records", 2, len(found)) {
+		checkEqual(t, "the names", []string{"gopher", "tux"}, []string{found[0].Name, found[1].Name})
+	}
+
+	cnt, err := engine.Where("score >= ?", 2).Count(new(SuiteRecord))
+	check(t, err, "count")
+	checkEqual(t, "the count", int64(3), cnt)
+	total, err := engine.Sum(new(SuiteRecord), "score")
+	check(t, err, "sum")
+	checkEqual(t, "the sum", float64(10), total)
+
+	affected, err = engine.ID(record.Id).Cols("name").Update(&SuiteRecord{Name: "lunny2"})
+	check(t, err, "update")
+	checkEqual(t, "the affected records of the update", int64(1), affected)
+	got = SuiteRecord{}
+	_, err = engine.ID(record.Id).Get(&got)
+	check(t, err, "get the update")
+	checkEqual(t, "the updated name", "lunny2", got.Name)
+	checkEqual(t, "the score not updated", 1, got.Score)
+
+	affected, err = engine.In("name", "xlw", "tux").Delete(new(SuiteRecord))
+	check(t, err, "delete")
+	checkEqual(t, "the affected records of the delete", int64(2), affected)
+	cnt, err = engine.Count(new(SuiteRecord))
+	check(t, err, "count the rest")
+	checkEqual(t, "the count of the rest", int64(2), cnt)
+}
+
+func checkLen(t testing.TB, what string, expected, actual int) bool {
+	t.Helper()
+	if expected != actual {
+		t.Errorf("xormtest: %v are %d instead of %d", what, actual, expected)
+		return false
+	}
+	return true
+}
+
+func testSuiteTransaction(t *testing.T, engine *xorm.Engine) {
+	check(t, engine.Sync2(new(SuiteRecord)), "sync")
+	before, err := engine.Count(new(SuiteRecord))
+	check(t, err, "count")
+
+	for _, commit := range []bool{false, true} {
+		session := engine.NewSession()
+		check(t, session.Begin(), "begin")
+		_, err := session.Insert(&SuiteRecord{Name: fmt.Sprintf("tx%v", commit)})
+		check(t, err, "insert in the transaction")
+		if commit {
+			check(t, session.Commit(), "commit")
+		} else {
+			check(t, session.Rollback(), "rollback")
+		}
+		session.Close()
+	}
+
+	after, err := engine.Count(new(SuiteRecord))
+	check(t, err, "count after")
+	checkEqual(t, "the committed records", before+1, after)
+}
+
+func testSuiteTypes(t *testing.T, engine *xorm.Engine) {
+	check(t, engine.Sync2(new(SuiteType)), "sync")
+
+	str, n := "nullable", int64(-42)
+	values := []SuiteType{
+		{
+			Int8:       math.MaxInt8,
+			Int16:      math.MinInt16,
+			Int32:      math.MaxInt32,
+			Int64:      math.MinInt64 + 1,
+			Uint8:      math.MaxUint8,
+			Uint16:     math.MaxUint16,
+			Uint32:     1<<31 - 1,
+			Float32:    3.25,
+			Float64:    -1.0 / 3,
+			Bool:       true,
+			String:     "héllo 'quoted' \"world\"",
+			Text:       strings.Repeat("long text ", 500),
+			Bytes:      []byte{0, 1, 2, 0xfe, 0xff},
+			Time:       time.Date(2017, 4, 5, 6, 7, 8, 0, engine.TZLocation),
+			NullString: &str,
+			NullInt:    &n,
+			Map:        map[string]string{"key": "value"},
+		},
+		{},
+	}
+	for i, value := range values {
+		_, err := engine.Insert(&value)
+		check(t, err, "insert")
+
+		var got SuiteType
+		has, err := engine.ID(value.Id).Get(&got)
+		check(t, err, "get")
+		checkEqual(t, "the record exists", true, has)
+
+		what := func(field string) string {
+			return fmt.Sprintf("the %v of the record %d", field, i)
+		}
+		checkEqual(t, what("Int8"), value.Int8, got.Int8)
+		checkEqual(t, what("Int16"), value.Int16, got.Int16)
+		checkEqual(t, what("Int32"), value.Int32, got.Int32)
+		checkEqual(t, what("Int64"), value.Int64, got.Int64)
+		checkEqual(t, what("Uint8"), value.Uint8, got.Uint8)
+		checkEqual(t, what("Uint16"), value.Uint16, got.Uint16)
+		checkEqual(t, what("Uint32"), value.Uint32, got.Uint32)
+		checkEqual(t, what("Float32"), value.Float32, got.Float32)
+		if math.Abs(value.Float64-got.Float64) > 1e-9 {
+			t.Errorf("xormtest: %v is %v instead of %v", what("Float64"), got.Float64, value.Float64)
+		}
+		checkEqual(t, what("Bool"), value.Bool, got.Bool)
+		checkEqual(t, what("String"), value.String, got.String)
+		checkEqual(t, what("Text"), value.Text, got.Text)
+		checkEqual(t, what("Bytes"), len(value.Bytes) > 0, len(got.Bytes) > 0)
+		if len(value.Bytes) > 0 {
+			checkEqual(t, what("Bytes"), value.Bytes, got.Bytes)
+		}
+		checkEqual(t, what("Time"), value.Time.Unix(), got.Time.Unix())
+		checkEqual(t, what("NullString"), value.NullString, got.NullString)
+		checkEqual(t, what("NullInt"), value.NullInt, got.NullInt)
+		checkEqual(t, what("Map"), len(value.Map), len(got.Map))
+		if len(value.Map) > 0 {
+			checkEqual(t, what("Map"), value.Map, got.Map)
+		}
+	}
+}
+
+// RunDialectBenchmarks measures the CRUD, the syncs and the round trips of
+// the types of the dialect of engine, as the sub-benchmarks of b, on the
+// tables of RunDialectSuite
+//
+//	func BenchmarkDialect(b *testing.B) {
+//		xormtest.RunDialectBenchmarks(b, engine)
+//	}
+func RunDialectBenchmarks(b *testing.B, engine *xorm.Engine) {
+	b.Helper()
+	dropSuiteTables(b, engine)
+	defer dropSuiteTables(b, engine)
+	check(b, engine.Sync2(new(SuiteRecord), new(SuiteType)), "sync")
+
+	var id int64
+	b.Run("Insert", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			record := SuiteRecord{Name: "lunny", Score: i}
+			_, err := engine.Insert(&record)
+			check(b, err, "insert")
+			id = record.Id
+		}
+	})
+	b.Run("InsertMulti", func(b *testing.B) {
+		records := make([]SuiteRecord, 100)
+		for i := range records {
+			records[i] = SuiteRecord{Name: "xlw", Score: i}
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, err := engine.Insert(&records)
+			check(b, err, "insert multi")
+		}
+	})
+	b.Run("Get", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := engine.ID(id).Get(new(SuiteRecord))
+			check(b, err, "get")
+		}
+	})
+	b.Run("Find", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var records []SuiteRecord
+			check(b, engine.Limit(100).Find(&records), "find")
+		}
+	})
+	b.Run("Update", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := engine.ID(id).Cols("score").Update(&SuiteRecord{Score: i})
+			check(b, err, "update")
+		}
+	})
+	b.Run("Count", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := engine.Where("score > ?", 10).Count(new(SuiteRecord))
+			check(b, err, "count")
+		}
+	})
+	b.Run("Sync2", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			check(b, engine.Sync2(new(SuiteRecord), new(SuiteType)), "sync")
+		}
+	})
+	b.Run("Types", func(b *testing.B) {
+		str := "nullable"
+		for i := 0; i < b.N; i++ {
+			value := SuiteType{
+				Int64:      int64(i),
+				Float64:    float64(i) / 3,
+				String:     "hello",
+				Bytes:      []byte{1, 2, 3},
+				Time:       time.Now(),
+				NullString: &str,
+				Map:        map[string]string{"key": "value"},
+			}
+			_, err := engine.Insert(&value)
+			check(b, err, "insert")
+			_, err = engine.ID(value.Id).Get(new(SuiteType))
+			check(b, err, "get")
+		}
+	})
+}
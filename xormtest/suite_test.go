@@ -0,0 +1,15 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xormtest
+
+import "testing"
+
+func TestRunDialectSuite(t *testing.T) {
+	RunDialectSuite(t, NewMemoryEngine(t))
+}
+
+func BenchmarkDialect(b *testing.B) {
+	RunDialectBenchmarks(b, NewMemoryEngine(b))
+}
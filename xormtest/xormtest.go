@@ -0,0 +1,72 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package xormtest isolates the tests sharing a database by running them in
+// transactions rolled back at their ends:
+//
+//	func TestCreateUser(t *testing.T) {
+//		xormtest.WithRollback(t, engine, func(session *xorm.Session) {
+//			_, err := session.Insert(&User{Name: "lunny"})
+//			...
+//		})
+//	}
+//
+// The code under test has to write through the session, the writes of the
+// engine and of the other sessions are not rolled back.
+package xormtest
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/go-xorm/xorm"
+)
+
+// the sequence of the names of the savepoints
+var savepointSeq int64
+
+// WithRollback runs f with a session in a transaction rolled back after f,
+// even when f fails the test or panics. A commit of f ends the transaction
+// and its writes are kept.
+func WithRollback(t testing.TB, engine *xorm.Engine, f func(*xorm.Session)) {
+	t.Helper()
+	session := engine.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		t.Fatalf("xormtest: begin: %v", err)
+	}
+	defer func() {
+		if err := session.Rollback(); err != nil {
+			t.Errorf("xormtest: rollback: %v", err)
+		}
+	}()
+	f(session)
+}
+
+// WithSavepoint runs f with session in a savepoint of its transaction, as
+// the one of WithRollback, rolled back to after f, so the subtests share
+// the records of their test but not their own
+func WithSavepoint(t testing.TB, session *xorm.Session, f func(*xorm.Session)) {
+	t.Helper()
+	if session.IsAutoCommit {
+		t.Fatalf("xormtest: the session has no transaction")
+	}
+
+	name := fmt.Sprintf("xormtest_%d", atomic.AddInt64(&savepointSeq, 1))
+	save, rollback := "SAVEPOINT "+name, "ROLLBACK TO SAVEPOINT "+name
+	if session.Engine.Dialect().DBType() == core.MSSQL {
+		save, rollback = "SAVE TRANSACTION "+name, "ROLLBACK TRANSACTION "+name
+	}
+	if _, err := session.Exec(save); err != nil {
+		t.Fatalf("xormtest: savepoint: %v", err)
+	}
+	defer func() {
+		if _, err := session.Exec(rollback); err != nil {
+			t.Errorf("xormtest: rollback to savepoint: %v", err)
+		}
+	}()
+	f(session)
+}
@@ -0,0 +1,55 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xormtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-xorm/xorm"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+type User struct {
+	Id   int64
+	Name string
+}
+
+func TestWithRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xormtest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	engine, err := xorm.NewEngine("sqlite3", filepath.Join(dir, "test.db"))
+	assert.NoError(t, err)
+	defer engine.Close()
+	assert.NoError(t, engine.Sync2(new(User)))
+
+	count := func(session *xorm.Session) int64 {
+		cnt, err := session.Count(new(User))
+		assert.NoError(t, err)
+		return cnt
+	}
+
+	WithRollback(t, engine, func(session *xorm.Session) {
+		_, err := session.Insert(&User{Name: "lunny"})
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, count(session))
+
+		WithSavepoint(t, session, func(session *xorm.Session) {
+			_, err := session.Insert(&User{Name: "xlw"})
+			assert.NoError(t, err)
+			assert.EqualValues(t, 2, count(session))
+		})
+		assert.EqualValues(t, 1, count(session))
+	})
+
+	cnt, err := engine.Count(new(User))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+}
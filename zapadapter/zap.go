@@ -0,0 +1,47 @@
+// Copyright 2017 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package zapadapter logs the entries of xorm with go.uber.org/zap
+package zapadapter
+
+import (
+	"github.com/go-xorm/core"
+	"github.com/go-xorm/xorm"
+	"go.uber.org/zap"
+)
+
+// Logger is a xorm.StructuredLogger writing to a zap.Logger
+type Logger struct {
+	logger *zap.Logger
+}
+
+var _ xorm.StructuredLogger = &Logger{}
+
+// New returns a structured logger writing to logger
+func New(logger *zap.Logger) *Logger {
+	return &Logger{logger}
+}
+
+// Log implements xorm.StructuredLogger
+func (l *Logger) Log(level core.LogLevel, msg string, fields ...xorm.LogField) {
+	zapFields := make([]zap.Field, len(fields))
+	for i, field := range fields {
+		if err, ok := field.Value.(error); ok {
+			zapFields[i] = zap.NamedError(field.Key, err)
+			continue
+		}
+		zapFields[i] = zap.Any(field.Key, field.Value)
+	}
+
+	switch level {
+	case core.LOG_DEBUG:
+		l.logger.Debug(msg, zapFields...)
+	case core.LOG_WARNING:
+		l.logger.Warn(msg, zapFields...)
+	case core.LOG_ERR:
+		l.logger.Error(msg, zapFields...)
+	default:
+		l.logger.Info(msg, zapFields...)
+	}
+}